@@ -0,0 +1,247 @@
+// Package assignment seats players at an event's tables from their ranked
+// choices, for slots that don't sign up first come, first served.
+//
+// Solve seats as many players as the tables' seats allow and, among those
+// seatings, gives the best total of ranks. Ties are broken by a lottery drawn
+// from a seed, so the same choices and seed always give the same seating.
+// It is a pure function of its Problem, so it can be tested on its own.
+package assignment
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+)
+
+// Table is a table players can be seated at.
+type Table struct {
+	ID    int64
+	Seats int // Seats free for assignment
+}
+
+// Player is a player's choices, best first. Tables that aren't in the
+// problem are ignored.
+type Player struct {
+	ID      int64
+	Choices []int64
+}
+
+// Problem is a slot to seat.
+type Problem struct {
+	Tables  []Table
+	Players []Player
+	// Pins are the organizer's tweaks: they seat a player at a table whatever
+	// their choices, or leave them without one if the table is 0. Pinned
+	// players take their seats before anyone else is seated.
+	Pins map[int64]int64
+	Seed int64 // Draws the lottery
+}
+
+// Result is the seating Solve found.
+type Result struct {
+	Tables map[int64]int64 // Player to table, for the seated players
+	Ranks  map[int64]int   // Player to the rank of their table among their choices, from 1; 0 if it wasn't one
+	// Lottery is the players in the order the lottery drew them. Earlier
+	// players get their better choices when the totals are tied.
+	Lottery []int64
+}
+
+// Seated is how many players have a table.
+func (r *Result) Seated() int {
+	return len(r.Tables)
+}
+
+// WithRank is how many seated players got their rank-th choice (from 1).
+func (r *Result) WithRank(rank int) int {
+	n := 0
+	for p := range r.Tables {
+		if r.Ranks[p] == rank {
+			n++
+		}
+	}
+	return n
+}
+
+// Solve seats the problem's players. Pinned players get their pinned table (or
+// none); the others are seated at one of their choices, by a minimum-cost flow
+// from players to table seats. The flow seats as many players as it can, then
+// minimizes the sum of their ranks, then favors players the lottery drew early.
+func Solve(p Problem) *Result {
+	res := &Result{Tables: make(map[int64]int64), Ranks: make(map[int64]int)}
+
+	seats := make(map[int64]int, len(p.Tables))
+	tableIndex := make(map[int64]int, len(p.Tables))
+	for i, t := range p.Tables {
+		seats[t.ID] = max(t.Seats, 0)
+		tableIndex[t.ID] = i
+	}
+
+	// The lottery: players sorted by ID, so the input order doesn't matter,
+	// then shuffled by the seed.
+	players := make([]Player, len(p.Players))
+	copy(players, p.Players)
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+	rand.New(rand.NewSource(p.Seed)).Shuffle(len(players), func(i, j int) { players[i], players[j] = players[j], players[i] })
+	for _, pl := range players {
+		res.Lottery = append(res.Lottery, pl.ID)
+	}
+
+	// Pinned players take their seats first; pinning can overbook a table.
+	var free []Player
+	for _, pl := range players {
+		table, pinned := p.Pins[pl.ID]
+		if !pinned {
+			free = append(free, pl)
+			continue
+		}
+		if _, ok := seats[table]; ok {
+			res.Tables[pl.ID] = table
+			res.Ranks[pl.ID] = rankOf(pl.Choices, table)
+			seats[table]--
+		}
+	}
+
+	// Nodes: source, the free players in lottery order, the tables, sink.
+	n, m := len(free), len(p.Tables)
+	source, sink := 0, n+m+1
+	g := newGraph(n + m + 2)
+	maxChoices := 0
+	for _, pl := range free {
+		maxChoices = max(maxChoices, len(pl.Choices))
+	}
+	// A rank costs more than any spread of lottery costs, so the lottery only
+	// breaks ties between seatings with the same total of ranks.
+	rankCost := int64(n)*int64(n)*int64(maxChoices) + 1
+	type choiceEdge struct{ player, edge, rank int }
+	var choices []choiceEdge
+	for i, pl := range free {
+		g.addEdge(source, 1+i, 1, 0)
+		seen := make(map[int64]bool, len(pl.Choices))
+		for r, table := range pl.Choices {
+			ti, ok := tableIndex[table]
+			if !ok || seen[table] {
+				continue
+			}
+			seen[table] = true
+			cost := int64(r)*rankCost + int64(r)*int64(n-i)
+			choices = append(choices, choiceEdge{i, g.addEdge(1+i, 1+n+ti, 1, cost), r + 1})
+		}
+	}
+	for i, t := range p.Tables {
+		if s := seats[t.ID]; s > 0 {
+			g.addEdge(1+n+i, sink, s, 0)
+		}
+	}
+	g.minCostFlow(source, sink)
+
+	for _, c := range choices {
+		if g.edges[c.edge].cap == 0 { // The player's seat flows through this choice
+			pl := free[c.player]
+			res.Tables[pl.ID] = p.Tables[g.edges[c.edge].to-1-n].ID
+			res.Ranks[pl.ID] = c.rank
+		}
+	}
+	return res
+}
+
+// rankOf is the rank of table among choices, from 1; 0 if it isn't one.
+func rankOf(choices []int64, table int64) int {
+	for i, c := range choices {
+		if c == table {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// graph is a flow network. Each edge is stored next to its reverse, so e^1 is
+// the reverse of edge e.
+type graph struct {
+	adj   [][]int
+	edges []edge
+}
+
+type edge struct {
+	to   int
+	cap  int
+	cost int64
+}
+
+func newGraph(nodes int) *graph {
+	return &graph{adj: make([][]int, nodes)}
+}
+
+// addEdge adds an edge and its reverse, returning the edge's index.
+func (g *graph) addEdge(from, to, capacity int, cost int64) int {
+	g.adj[from] = append(g.adj[from], len(g.edges))
+	g.edges = append(g.edges, edge{to, capacity, cost})
+	g.adj[to] = append(g.adj[to], len(g.edges))
+	g.edges = append(g.edges, edge{from, 0, -cost})
+	return len(g.edges) - 2
+}
+
+// minCostFlow pushes as much flow as it can from source to sink at the least
+// cost, one shortest path at a time. Costs start non-negative, so Dijkstra with
+// potentials finds each path.
+func (g *graph) minCostFlow(source, sink int) {
+	nodes := len(g.adj)
+	potential := make([]int64, nodes)
+	for {
+		dist := make([]int64, nodes)
+		via := make([]int, nodes) // The edge each node was reached through
+		for i := range dist {
+			dist[i], via[i] = -1, -1
+		}
+		dist[source] = 0
+		q := &queue{{source, 0}}
+		for q.Len() > 0 {
+			it := heap.Pop(q).(item)
+			if it.dist > dist[it.node] {
+				continue
+			}
+			for _, e := range g.adj[it.node] {
+				ed := g.edges[e]
+				if ed.cap == 0 {
+					continue
+				}
+				d := it.dist + ed.cost + potential[it.node] - potential[ed.to]
+				if dist[ed.to] < 0 || d < dist[ed.to] {
+					dist[ed.to], via[ed.to] = d, e
+					heap.Push(q, item{ed.to, d})
+				}
+			}
+		}
+		if dist[sink] < 0 {
+			return
+		}
+		for i := range potential {
+			if dist[i] >= 0 {
+				potential[i] += dist[i]
+			}
+		}
+		// Every path from the source starts at a player, so it carries one seat.
+		for v := sink; v != source; v = g.edges[via[v]^1].to {
+			g.edges[via[v]].cap--
+			g.edges[via[v]^1].cap++
+		}
+	}
+}
+
+type item struct {
+	node int
+	dist int64
+}
+
+// queue is a min-heap of items by distance.
+type queue []item
+
+func (q queue) Len() int            { return len(q) }
+func (q queue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(item)) }
+func (q *queue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,117 @@
+package assignment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		problem    Problem
+		wantTables map[int64]int64
+		wantRanks  map[int64]int
+	}{
+		{
+			name: "everyone gets their first choice",
+			problem: Problem{
+				Tables:  []Table{{ID: 10, Seats: 2}, {ID: 20, Seats: 2}},
+				Players: []Player{{ID: 1, Choices: []int64{10}}, {ID: 2, Choices: []int64{20, 10}}, {ID: 3, Choices: []int64{10, 20}}},
+			},
+			wantTables: map[int64]int64{1: 10, 2: 20, 3: 10},
+			wantRanks:  map[int64]int{1: 1, 2: 1, 3: 1},
+		},
+		{
+			name: "seating everyone beats first choices",
+			problem: Problem{
+				Tables: []Table{{ID: 10, Seats: 1}, {ID: 20, Seats: 1}},
+				// Player 1 can only sit at 10, so player 2 moves to their second choice.
+				Players: []Player{{ID: 1, Choices: []int64{10}}, {ID: 2, Choices: []int64{10, 20}}},
+			},
+			wantTables: map[int64]int64{1: 10, 2: 20},
+			wantRanks:  map[int64]int{1: 1, 2: 2},
+		},
+		{
+			name: "best total of ranks",
+			problem: Problem{
+				Tables: []Table{{ID: 10, Seats: 1}, {ID: 20, Seats: 1}, {ID: 30, Seats: 1}},
+				Players: []Player{
+					{ID: 1, Choices: []int64{10, 20, 30}},
+					{ID: 2, Choices: []int64{10, 30}},
+					{ID: 3, Choices: []int64{20, 10}},
+				},
+			},
+			// Whoever sits at 10, the other players' ranks add up to 1+1+2 at best,
+			// which only seating 1 at 10 reaches.
+			wantTables: map[int64]int64{1: 10, 2: 30, 3: 20},
+			wantRanks:  map[int64]int{1: 1, 2: 2, 3: 1},
+		},
+		{
+			name: "too few seats",
+			problem: Problem{
+				Tables:  []Table{{ID: 10, Seats: 1}, {ID: 20, Seats: 0}},
+				Players: []Player{{ID: 1, Choices: []int64{20}}, {ID: 2, Choices: []int64{10, 10, 99}}},
+			},
+			wantTables: map[int64]int64{2: 10},
+			wantRanks:  map[int64]int{2: 1},
+		},
+		{
+			name: "pins",
+			problem: Problem{
+				Tables: []Table{{ID: 10, Seats: 1}, {ID: 20, Seats: 2}},
+				Players: []Player{
+					{ID: 1, Choices: []int64{10}},
+					{ID: 2, Choices: []int64{10, 20}},
+					{ID: 3, Choices: []int64{20}},
+					{ID: 4, Choices: []int64{20}},
+				},
+				// Player 2 takes the seat at 10, and player 4 is kept off the slot.
+				Pins: map[int64]int64{2: 10, 4: 0},
+			},
+			wantTables: map[int64]int64{2: 10, 3: 20},
+			wantRanks:  map[int64]int{2: 1, 3: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Solve(tt.problem)
+			if !reflect.DeepEqual(got.Tables, tt.wantTables) {
+				t.Errorf("Tables = %v, want %v", got.Tables, tt.wantTables)
+			}
+			if !reflect.DeepEqual(got.Ranks, tt.wantRanks) {
+				t.Errorf("Ranks = %v, want %v", got.Ranks, tt.wantRanks)
+			}
+		})
+	}
+}
+
+func TestSolveLottery(t *testing.T) {
+	// Four players want one seat; the lottery decides, and the seed makes it repeatable.
+	problem := Problem{Tables: []Table{{ID: 10, Seats: 1}, {ID: 20, Seats: 3}}, Seed: 42}
+	for id := int64(1); id <= 4; id++ {
+		problem.Players = append(problem.Players, Player{ID: id, Choices: []int64{10, 20}})
+	}
+	first := Solve(problem)
+	if first.Seated() != 4 || first.WithRank(1) != 1 || first.WithRank(2) != 3 {
+		t.Fatalf("Solve() = %v, want one player at 10 and three at 20", first.Tables)
+	}
+	if winner := first.Lottery[0]; first.Tables[winner] != 10 {
+		t.Errorf("lottery winner %d sits at %d, want 10", winner, first.Tables[winner])
+	}
+
+	// The order of the players doesn't matter, only the seed.
+	reversed := problem
+	reversed.Players = []Player{problem.Players[3], problem.Players[2], problem.Players[1], problem.Players[0]}
+	if again := Solve(reversed); !reflect.DeepEqual(again.Tables, first.Tables) || !reflect.DeepEqual(again.Lottery, first.Lottery) {
+		t.Errorf("Solve() with the players reversed = %v, want %v", again.Tables, first.Tables)
+	}
+
+	winners := map[int64]bool{}
+	for seed := int64(0); seed < 20; seed++ {
+		problem.Seed = seed
+		winners[Solve(problem).Lottery[0]] = true
+	}
+	if len(winners) < 2 {
+		t.Errorf("20 seeds drew the same lottery winner, want the seed to change the draw")
+	}
+}
@@ -0,0 +1,146 @@
+// Package availability overlays users' weekly availability and one-off
+// exceptions, each kept in the user's own timezone, onto a shared week of hour
+// slots shown in the viewer's timezone.
+//
+// FreeBetween and Build are pure functions of the profiles and the times asked
+// about, so they can be tested without a database.
+package availability
+
+import (
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// SlotLength is the length of a heatmap slot.
+const SlotLength = time.Hour
+
+const minutesPerDay = 24 * 60
+
+// Heatmap is a week of slots in the viewer's timezone, each with who is free
+// for the whole slot.
+type Heatmap struct {
+	Location *time.Location
+	Members  int
+	Days     []*Day
+}
+
+// Day is a date in the viewer's timezone. It usually has 24 slots, but 23 or
+// 25 when daylight saving time starts or ends.
+type Day struct {
+	Date  time.Time // Midnight
+	Slots []*Slot
+}
+
+// Slot is an hour of the heatmap.
+type Slot struct {
+	Start time.Time // In the viewer's timezone
+	Free  []*models.User
+	// Level grades the share of members who are free, from 0 (nobody) to 4 (everyone).
+	Level int
+}
+
+// WeekStart returns midnight on the Monday of the week containing t, in loc.
+func WeekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	offset := (int(t.Weekday()) + 6) % 7 // Days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// Build lays out the week starting at weekStart (midnight in the viewer's
+// timezone loc) and marks which profiles are free in each slot.
+func Build(profiles []*models.AvailabilityProfile, weekStart time.Time, loc *time.Location) *Heatmap {
+	h := &Heatmap{Location: loc, Members: len(profiles)}
+	weekStart = weekStart.In(loc)
+	// Timezones are loaded, and each user's local dates worked out, only once.
+	locs := make([]*time.Location, len(profiles))
+	caches := make([]map[string]*[minutesPerDay]bool, len(profiles))
+	for i, p := range profiles {
+		locs[i] = p.User.Location()
+		caches[i] = make(map[string]*[minutesPerDay]bool)
+	}
+	for d := 0; d < 7; d++ {
+		date := time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day()+d, 0, 0, 0, 0, loc)
+		next := time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, loc)
+		day := &Day{Date: date}
+		for t := date; t.Before(next); t = t.Add(SlotLength) {
+			slot := &Slot{Start: t}
+			for i, p := range profiles {
+				if freeBetween(p, locs[i], t, t.Add(SlotLength), caches[i]) {
+					slot.Free = append(slot.Free, p.User)
+				}
+			}
+			if h.Members > 0 {
+				// Round up, so a single free member shows.
+				slot.Level = (len(slot.Free)*4 + h.Members - 1) / h.Members
+			}
+			day.Slots = append(day.Slots, slot)
+		}
+		h.Days = append(h.Days, day)
+	}
+	return h
+}
+
+// FreeBetween reports whether the profile's user is free for all of [start, end):
+// within their weekly availability or a "free" exception, and not within a
+// "busy" exception, on each of their own local dates the span touches.
+func FreeBetween(p *models.AvailabilityProfile, start, end time.Time) bool {
+	return freeBetween(p, p.User.Location(), start, end, make(map[string]*[minutesPerDay]bool))
+}
+
+func freeBetween(p *models.AvailabilityProfile, loc *time.Location, start, end time.Time, cache map[string]*[minutesPerDay]bool) bool {
+	if !start.Before(end) {
+		return false
+	}
+	s, e := start.In(loc), end.In(loc)
+	for day := time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, loc); day.Before(e); {
+		next := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+		from, to := 0, minutesPerDay
+		if s.After(day) {
+			from = s.Hour()*60 + s.Minute()
+		}
+		if e.Before(next) {
+			to = e.Hour()*60 + e.Minute()
+		}
+		minutes := cache[day.Format(models.DateLayout)]
+		if minutes == nil {
+			minutes = freeMinutes(p, day)
+			cache[day.Format(models.DateLayout)] = minutes
+		}
+		for m := from; m < to; m++ {
+			if !minutes[m] {
+				return false
+			}
+		}
+		day = next
+	}
+	return true
+}
+
+// freeMinutes marks the minutes of a local date when the profile's user is free.
+func freeMinutes(p *models.AvailabilityProfile, day time.Time) *[minutesPerDay]bool {
+	var free [minutesPerDay]bool
+	mark := func(start, end int, value bool) {
+		for m := max(start, 0); m < min(end, minutesPerDay); m++ {
+			free[m] = value
+		}
+	}
+	for _, w := range p.Weekly {
+		if w.Weekday == day.Weekday() {
+			mark(w.Start, w.End, true)
+		}
+	}
+	date := day.Format(models.DateLayout)
+	// Free exceptions first, so a busy one on the same date wins.
+	for _, e := range p.Exceptions {
+		if e.Date == date && e.Free {
+			mark(e.Start, e.End, true)
+		}
+	}
+	for _, e := range p.Exceptions {
+		if e.Date == date && !e.Free {
+			mark(e.Start, e.End, false)
+		}
+	}
+	return &free
+}
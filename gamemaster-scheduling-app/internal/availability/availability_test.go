@@ -0,0 +1,130 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q) error = %v", name, err)
+	}
+	return loc
+}
+
+func weekly(day time.Weekday, start, end int) *models.WeeklyAvailability {
+	return &models.WeeklyAvailability{AvailabilityWindow: models.AvailabilityWindow{Weekday: day, Start: start * 60, End: end * 60}}
+}
+
+func TestFreeBetween(t *testing.T) {
+	// Berlin is UTC+2 in summer: Tuesday 18:00-22:00 there is 16:00-20:00 UTC.
+	berliner := &models.AvailabilityProfile{
+		User:   &models.User{ID: 1, Timezone: "Europe/Berlin"},
+		Weekly: []*models.WeeklyAvailability{weekly(time.Tuesday, 18, 22), weekly(time.Friday, 20, 24), weekly(time.Saturday, 0, 2)},
+		Exceptions: []*models.AvailabilityException{
+			{Date: "2030-06-11", Start: 19 * 60, End: 20 * 60, Free: false},      // A busy hour on a Tuesday
+			{Date: "2030-06-12", Start: 10 * 60, End: 12 * 60, Free: true},       // Free one Wednesday morning
+			{Date: "2030-06-18", Start: 0, End: 24 * 60, Free: false},            // Away a whole Tuesday
+			{Date: "2030-06-18", Start: 18 * 60, End: 19 * 60, Free: true},       // A busy day beats a free hour
+			{Date: "2030-06-25", Start: 17 * 60, End: 18 * 60, Free: true},       // Free an hour earlier than usual
+			{Date: "2030-06-26", Start: 0, End: 24 * 60, Free: false, Note: "x"}, // Busy on a day with no windows
+		},
+	}
+	utc := func(day, hour int) time.Time { return time.Date(2030, 6, day, hour, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       bool
+	}{
+		{"inside the window", utc(4, 16), utc(4, 20), true},
+		{"starts before the window", utc(4, 15), utc(4, 17), false},
+		{"runs past the window", utc(4, 19), utc(4, 21), false},
+		{"other weekday", utc(5, 16), utc(5, 17), false},
+		{"busy hour", utc(11, 17), utc(11, 18), false},
+		{"around the busy hour", utc(11, 16), utc(11, 17), true},
+		{"free exception", utc(12, 8), utc(12, 10), true},
+		{"whole day away", utc(18, 16), utc(18, 17), false},
+		{"earlier than usual", utc(25, 15), utc(25, 20), true},
+		{"across local midnight", utc(7, 21), utc(7, 23), true},
+		{"past the window after midnight", utc(7, 21), utc(8, 1), false},
+		{"empty span", utc(4, 16), utc(4, 16), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FreeBetween(berliner, tt.start, tt.end); got != tt.want {
+				t.Errorf("FreeBetween(%v, %v) = %v, want %v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+
+	// Without a timezone, windows are in UTC.
+	utcUser := &models.AvailabilityProfile{User: &models.User{ID: 2}, Weekly: []*models.WeeklyAvailability{weekly(time.Tuesday, 18, 22)}}
+	if !FreeBetween(utcUser, utc(4, 18), utc(4, 22)) || FreeBetween(utcUser, utc(4, 16), utc(4, 17)) {
+		t.Errorf("FreeBetween() for a user without a timezone is not in UTC")
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	tokyo := mustLoad(t, "Asia/Tokyo")
+	// Sunday 20:00 UTC is already Monday morning in Tokyo.
+	got := WeekStart(time.Date(2030, 6, 9, 20, 0, 0, 0, time.UTC), tokyo)
+	if want := time.Date(2030, 6, 10, 0, 0, 0, 0, tokyo); !got.Equal(want) {
+		t.Errorf("WeekStart() = %v, want %v", got, want)
+	}
+	if got := WeekStart(time.Date(2030, 6, 9, 20, 0, 0, 0, time.UTC), time.UTC); got.Day() != 3 {
+		t.Errorf("WeekStart() in UTC = %v, want Monday June 3", got)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	alice := &models.AvailabilityProfile{
+		User:   &models.User{ID: 1, Username: "alice", Timezone: "Europe/London"},
+		Weekly: []*models.WeeklyAvailability{weekly(time.Wednesday, 23, 24), weekly(time.Thursday, 0, 2)},
+	}
+	bob := &models.AvailabilityProfile{
+		User:   &models.User{ID: 2, Username: "bob", Timezone: "America/Los_Angeles"},
+		Weekly: []*models.WeeklyAvailability{weekly(time.Wednesday, 15, 17)},
+	}
+	carol := &models.AvailabilityProfile{User: &models.User{ID: 3, Username: "carol"}}
+
+	// London 23:00-02:00 (UTC+1) and Los Angeles 15:00-17:00 (UTC-7) overlap from
+	// 22:00 to 24:00 UTC, which is 18:00-20:00 in New York (UTC-4).
+	h := Build([]*models.AvailabilityProfile{alice, bob, carol}, time.Date(2030, 6, 10, 0, 0, 0, 0, newYork), newYork)
+	if h.Members != 3 || len(h.Days) != 7 {
+		t.Fatalf("Build() = %d members, %d days; want 3 and 7", h.Members, len(h.Days))
+	}
+	wednesday := h.Days[2]
+	if wednesday.Date.Weekday() != time.Wednesday || len(wednesday.Slots) != 24 {
+		t.Fatalf("day 2 = %v with %d slots, want a 24-hour Wednesday", wednesday.Date, len(wednesday.Slots))
+	}
+	for hour, slot := range wednesday.Slots {
+		var want int
+		switch {
+		case hour == 18 || hour == 19:
+			want = 2
+		case hour == 20: // Alice alone until 21:00 in New York
+			want = 1
+		}
+		if len(slot.Free) != want {
+			t.Errorf("Wednesday %02d:00 has %d free, want %d", hour, len(slot.Free), want)
+		}
+	}
+	if slot := wednesday.Slots[18]; slot.Level != 3 || slot.Start.Hour() != 18 || slot.Free[0].Username != "alice" {
+		t.Errorf("Wednesday 18:00 = %+v, want alice and bob at level 3", slot)
+	}
+	if slot := wednesday.Slots[20]; slot.Level != 2 {
+		t.Errorf("Wednesday 20:00 level = %d, want one in three rounded up to 2", slot.Level)
+	}
+
+	// The day clocks go back has 25 slots.
+	h = Build(nil, time.Date(2030, 10, 28, 0, 0, 0, 0, newYork), newYork)
+	if n := len(h.Days[6].Slots); n != 25 || h.Days[6].Date.Day() != 3 {
+		t.Errorf("%v has %d slots, want 25", h.Days[6].Date, n)
+	}
+}
@@ -0,0 +1,159 @@
+// Package calendar lays games out as a month grid, a week or an agenda, in the
+// viewer's timezone. Games are stored in UTC; a game belongs to the day it
+// starts on where the viewer is.
+//
+// Range and New are pure functions of the dates and games given, so they can
+// be tested without a database.
+package calendar
+
+import (
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// The calendar's views.
+const (
+	ViewMonth  = "month"
+	ViewWeek   = "week"
+	ViewAgenda = "agenda"
+)
+
+// Views lists the views in the order they are offered.
+var Views = []string{ViewMonth, ViewWeek, ViewAgenda}
+
+// ValidView reports whether v is one of Views.
+func ValidView(v string) bool {
+	for _, view := range Views {
+		if v == view {
+			return true
+		}
+	}
+	return false
+}
+
+// AgendaDays is how many days the agenda view covers.
+const AgendaDays = 28
+
+// Calendar is one page of a view.
+type Calendar struct {
+	View     string
+	Location *time.Location
+	// Date is midnight on the day the page was asked for; From and To bound the
+	// days shown, To being midnight after the last one.
+	Date     time.Time
+	From, To time.Time
+	// Prev and Next are dates on the pages before and after this one.
+	Prev, Next time.Time
+	Days       []*Day
+}
+
+// Day is a date in the viewer's timezone, with the games starting on it.
+type Day struct {
+	Date time.Time // Midnight
+	// Outside is set on the days of a month grid that belong to the months before and after.
+	Outside bool
+	Today   bool
+	Entries []*Entry
+}
+
+// Entry is a game on the calendar, with the viewer's part in it.
+type Entry struct {
+	Game  *models.Game
+	Start time.Time // The game's start in the viewer's timezone
+	IsGM  bool
+	// RSVPStatus is the viewer's RSVP status, or "" if they haven't RSVP'd.
+	RSVPStatus string
+}
+
+// Weeks splits the days into rows of seven, for the month grid.
+func (c *Calendar) Weeks() [][]*Day {
+	var weeks [][]*Day
+	for i := 0; i+7 <= len(c.Days); i += 7 {
+		weeks = append(weeks, c.Days[i:i+7])
+	}
+	return weeks
+}
+
+// Last returns midnight on the last day shown.
+func (c *Calendar) Last() time.Time {
+	return c.To.AddDate(0, 0, -1)
+}
+
+// BusyDays returns the days with games on them, for the agenda.
+func (c *Calendar) BusyDays() []*Day {
+	var days []*Day
+	for _, d := range c.Days {
+		if len(d.Entries) > 0 {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// midnight returns the start of the day containing t, in loc.
+func midnight(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// weekStart returns midnight on the Monday of the week containing t, in loc.
+func weekStart(t time.Time, loc *time.Location) time.Time {
+	t = midnight(t, loc)
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+// Range returns the days a page of view shows for date, in loc: from midnight
+// on the first day to midnight after the last. A month grid runs from the
+// Monday before the 1st to the Sunday after the month's last day.
+func Range(view string, date time.Time, loc *time.Location) (from, to time.Time) {
+	date = midnight(date, loc)
+	switch view {
+	case ViewWeek:
+		from = weekStart(date, loc)
+		return from, from.AddDate(0, 0, 7)
+	case ViewAgenda:
+		return date, date.AddDate(0, 0, AgendaDays)
+	default:
+		first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, loc)
+		last := first.AddDate(0, 1, -1)
+		return weekStart(first, loc), weekStart(last, loc).AddDate(0, 0, 7)
+	}
+}
+
+// New lays out the page of view for date in loc, placing entries on the day
+// they start, keeping their order. now marks today. Entries outside the page
+// are dropped.
+func New(view string, date, now time.Time, loc *time.Location, entries []*Entry) *Calendar {
+	if !ValidView(view) {
+		view = ViewMonth
+	}
+	date = midnight(date, loc)
+	c := &Calendar{View: view, Location: loc, Date: date}
+	c.From, c.To = Range(view, date, loc)
+	switch view {
+	case ViewWeek:
+		c.Prev, c.Next = date.AddDate(0, 0, -7), date.AddDate(0, 0, 7)
+	case ViewAgenda:
+		c.Prev, c.Next = date.AddDate(0, 0, -AgendaDays), date.AddDate(0, 0, AgendaDays)
+	default:
+		first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, loc)
+		c.Prev, c.Next = first.AddDate(0, -1, 0), first.AddDate(0, 1, 0)
+	}
+
+	today := midnight(now, loc)
+	byDate := make(map[string]*Day)
+	for d := c.From; d.Before(c.To); d = d.AddDate(0, 0, 1) {
+		day := &Day{Date: d, Today: d.Equal(today)}
+		day.Outside = view == ViewMonth && d.Month() != date.Month()
+		byDate[d.Format(models.DateLayout)] = day
+		c.Days = append(c.Days, day)
+	}
+	for _, e := range entries {
+		e.Start = e.Game.GameDateTime.In(loc)
+		if day := byDate[e.Start.Format(models.DateLayout)]; day != nil {
+			day.Entries = append(day.Entries, e)
+		}
+	}
+	return c
+}
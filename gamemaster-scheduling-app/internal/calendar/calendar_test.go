@@ -0,0 +1,79 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestRange(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	date := time.Date(2030, 3, 13, 15, 0, 0, 0, berlin) // A Wednesday
+	day := func(month time.Month, d int) time.Time { return time.Date(2030, month, d, 0, 0, 0, 0, berlin) }
+
+	tests := []struct {
+		view     string
+		from, to time.Time
+	}{
+		// March 2030 starts on a Friday and ends on a Sunday.
+		{ViewMonth, day(2, 25), day(4, 1)},
+		{ViewWeek, day(3, 11), day(3, 18)},
+		{ViewAgenda, day(3, 13), day(4, 10)},
+		{"bogus", day(2, 25), day(4, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.view, func(t *testing.T) {
+			from, to := Range(tt.view, date, berlin)
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("Range(%q) = %v to %v, want %v to %v", tt.view, from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo") // UTC+9
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	game := func(id int64, day, hour int) *Entry {
+		return &Entry{Game: &models.Game{ID: id, GameDateTime: time.Date(2030, 3, day, hour, 0, 0, 0, time.UTC)}}
+	}
+	// Late on the 13th in UTC is already the 14th in Tokyo.
+	entries := []*Entry{game(1, 13, 10), game(2, 13, 20), game(3, 14, 1), game(4, 30, 0)}
+	now := time.Date(2030, 3, 14, 12, 0, 0, 0, tokyo)
+
+	c := New(ViewWeek, time.Date(2030, 3, 13, 0, 0, 0, 0, tokyo), now, tokyo, entries)
+	if len(c.Days) != 7 || c.Days[0].Date.Day() != 11 {
+		t.Fatalf("week days = %d starting %v, want 7 starting Monday the 11th", len(c.Days), c.Days[0].Date)
+	}
+	wed, thu := c.Days[2], c.Days[3]
+	if len(wed.Entries) != 1 || wed.Entries[0].Game.ID != 1 || wed.Entries[0].Start.Hour() != 19 {
+		t.Errorf("Wednesday entries = %v, want game 1 at 19:00", wed.Entries)
+	}
+	if len(thu.Entries) != 2 || thu.Entries[0].Game.ID != 2 || thu.Entries[1].Game.ID != 3 || !thu.Today {
+		t.Errorf("Thursday = %+v, want games 2 and 3, marked today", thu)
+	}
+	if len(c.BusyDays()) != 2 {
+		t.Errorf("busy days = %d, want 2 (game 4 is after the week)", len(c.BusyDays()))
+	}
+	if c.Prev.Day() != 6 || c.Next.Day() != 20 {
+		t.Errorf("prev/next = %v/%v, want the 6th and the 20th", c.Prev, c.Next)
+	}
+
+	c = New(ViewMonth, time.Date(2030, 3, 13, 0, 0, 0, 0, tokyo), now, tokyo, entries)
+	weeks := c.Weeks()
+	if len(weeks) != 5 || !weeks[0][0].Outside || weeks[0][4].Outside || weeks[0][4].Date.Day() != 1 {
+		t.Errorf("month grid = %d weeks, want 5 starting with February's last days", len(weeks))
+	}
+	if c.Prev.Month() != time.February || c.Next.Month() != time.April || c.Next.Day() != 1 {
+		t.Errorf("prev/next = %v/%v, want February and April", c.Prev, c.Next)
+	}
+	if n := len(weeks[4][5].Entries); n != 1 {
+		t.Errorf("Saturday the 30th entries = %d, want 1", n)
+	}
+}
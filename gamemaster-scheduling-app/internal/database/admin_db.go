@@ -0,0 +1,157 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// insertAuditEntry appends to the audit log in the admin action's transaction.
+func insertAuditEntry(tx *sql.Tx, adminID int64, action, targetType string, targetID int64, details string) error {
+	_, err := tx.Exec(`
+		INSERT INTO audit_log (admin_id, action, target_type, target_id, details) VALUES (?, ?, ?, ?, ?)
+	`, adminID, action, targetType, targetID, nullIfEmpty(details))
+	return err
+}
+
+// adminAction runs an admin's change and its audit entry in one transaction. The
+// change must affect exactly one row; otherwise sql.ErrNoRows is returned and
+// nothing is logged.
+func adminAction(db *sql.DB, adminID int64, action, targetType string, targetID int64, details string, query string, args ...interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := insertAuditEntry(tx, adminID, action, targetType, targetID, details); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SuspendUser stops a user from logging in. It returns sql.ErrNoRows if the user
+// doesn't exist or is already suspended.
+func SuspendUser(db *sql.DB, adminID, userID int64, reason string) error {
+	return adminAction(db, adminID, models.AuditSuspendUser, models.AuditTargetUser, userID, reason,
+		"UPDATE users SET suspended_at = CURRENT_TIMESTAMP, suspended_reason = ? WHERE id = ? AND suspended_at IS NULL",
+		nullIfEmpty(reason), userID)
+}
+
+// UnsuspendUser lets a suspended user log in again. It returns sql.ErrNoRows if the
+// user doesn't exist or isn't suspended.
+func UnsuspendUser(db *sql.DB, adminID, userID int64) error {
+	return adminAction(db, adminID, models.AuditUnsuspendUser, models.AuditTargetUser, userID, "",
+		"UPDATE users SET suspended_at = NULL, suspended_reason = NULL WHERE id = ? AND suspended_at IS NOT NULL",
+		userID)
+}
+
+// SetUserRole changes a user's site role. It returns sql.ErrNoRows if the user doesn't
+// exist or already has the role.
+func SetUserRole(db *sql.DB, adminID, userID int64, role string) error {
+	return adminAction(db, adminID, models.AuditSetUserRole, models.AuditTargetUser, userID, role,
+		"UPDATE users SET role = ? WHERE id = ? AND role != ?", role, userID, role)
+}
+
+// ForceCancelGame cancels a game on behalf of the site. It returns sql.ErrNoRows if
+// the game doesn't exist or was already cancelled by an admin.
+func ForceCancelGame(db *sql.DB, adminID, gameID int64, reason string) error {
+	return adminAction(db, adminID, models.AuditCancelGame, models.AuditTargetGame, gameID, reason,
+		"UPDATE games SET cancelled_at = CURRENT_TIMESTAMP, cancel_reason = ? WHERE id = ? AND cancelled_at IS NULL",
+		nullIfEmpty(reason), gameID)
+}
+
+// AdminDeleteChatMessage soft-deletes any chat message as a site admin. It returns
+// sql.ErrNoRows if the message doesn't exist or was already deleted.
+func AdminDeleteChatMessage(db *sql.DB, adminID, messageID int64) error {
+	return adminAction(db, adminID, models.AuditDeleteChatMessage, models.AuditTargetChatMessage, messageID, "",
+		"UPDATE chat_messages SET deleted_at = CURRENT_TIMESTAMP, deleted_by = ? WHERE id = ? AND deleted_at IS NULL",
+		adminID, messageID)
+}
+
+// GetAuditLog retrieves the latest admin actions, newest first.
+func GetAuditLog(db *sql.DB, limit int) ([]*models.AuditEntry, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.admin_id, u.email, a.action, a.target_type, a.target_id, a.details, a.created_at
+		FROM audit_log a JOIN users u ON u.id = a.admin_id
+		ORDER BY a.created_at DESC, a.id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		e := &models.AuditEntry{}
+		var details sql.NullString
+		if err := rows.Scan(&e.ID, &e.AdminID, &e.AdminEmail, &e.Action, &e.TargetType, &e.TargetID, &details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Details = details.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GrantAdminRole makes the users with the given emails site admins, e.g. the operators
+// configured at startup. Emails that aren't registered are skipped.
+func GrantAdminRole(db *sql.DB, emails []string) error {
+	for _, email := range emails {
+		if _, err := db.Exec("UPDATE users SET role = ? WHERE email = ?", models.UserRoleAdmin, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordLogin stores a login attempt.
+func RecordLogin(db *sql.DB, e *models.LoginEvent) error {
+	userID := sql.NullInt64{Int64: e.UserID, Valid: e.UserID != 0}
+	_, err := db.Exec(`
+		INSERT INTO login_events (user_id, email, success, reason, ip_address, user_agent) VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, e.Email, e.Success, nullIfEmpty(e.Reason), nullIfEmpty(e.IPAddress), nullIfEmpty(e.UserAgent))
+	return err
+}
+
+// GetLoginEvents retrieves the latest login attempts, newest first: a user's if userID
+// is set, otherwise everyone's.
+func GetLoginEvents(db *sql.DB, userID int64, limit int) ([]*models.LoginEvent, error) {
+	query := "SELECT id, user_id, email, success, reason, ip_address, user_agent, created_at FROM login_events"
+	args := []interface{}{}
+	if userID != 0 {
+		query += " WHERE user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	rows, err := db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.LoginEvent
+	for rows.Next() {
+		e := &models.LoginEvent{}
+		var eventUserID sql.NullInt64
+		var reason, ip, userAgent sql.NullString
+		if err := rows.Scan(&e.ID, &eventUserID, &e.Email, &e.Success, &reason, &ip, &userAgent, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.UserID = eventUserID.Int64
+		e.Reason, e.IPAddress, e.UserAgent = reason.String, ip.String, userAgent.String
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
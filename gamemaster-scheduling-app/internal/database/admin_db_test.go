@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestAdminActionsAndAuditLog(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	admin := createTestUserForRSVPs(t, db, "admindb_admin@example.com", "password")
+	user := createTestUserForRSVPs(t, db, "admindb_user@example.com", "password")
+	game := createTestGameForRSVPs(t, db, user, "Moderated")
+
+	if err := GrantAdminRole(db, []string{admin.Email, "nobody@example.com"}); err != nil {
+		t.Fatalf("GrantAdminRole() error = %v", err)
+	}
+	if a, _ := GetUserByID(db, admin.ID); !a.IsAdmin() {
+		t.Errorf("admin role = %q, want %q", a.Role, models.UserRoleAdmin)
+	}
+	if u, _ := GetUserByID(db, user.ID); u.IsAdmin() || u.Role != models.UserRoleUser {
+		t.Errorf("new user role = %q, want %q", u.Role, models.UserRoleUser)
+	}
+
+	if err := SuspendUser(db, admin.ID, user.ID, "spam"); err != nil {
+		t.Fatalf("SuspendUser() error = %v", err)
+	}
+	if err := SuspendUser(db, admin.ID, user.ID, "again"); err != sql.ErrNoRows {
+		t.Errorf("suspending twice error = %v, want sql.ErrNoRows", err)
+	}
+	u, _ := GetUserByID(db, user.ID)
+	if !u.IsSuspended() || u.SuspendedReason != "spam" {
+		t.Errorf("suspended user = %+v", u)
+	}
+	if err := UnsuspendUser(db, admin.ID, user.ID); err != nil {
+		t.Fatalf("UnsuspendUser() error = %v", err)
+	}
+	if u, _ := GetUserByID(db, user.ID); u.IsSuspended() || u.SuspendedReason != "" {
+		t.Errorf("unsuspended user = %+v", u)
+	}
+	if err := SetUserRole(db, admin.ID, user.ID, models.UserRoleAdmin); err != nil {
+		t.Fatalf("SetUserRole() error = %v", err)
+	}
+
+	if err := ForceCancelGame(db, admin.ID, game.ID, "Harassment"); err != nil {
+		t.Fatalf("ForceCancelGame() error = %v", err)
+	}
+	if err := ForceCancelGame(db, admin.ID, game.ID, ""); err != sql.ErrNoRows {
+		t.Errorf("cancelling twice error = %v, want sql.ErrNoRows", err)
+	}
+	g, _ := GetGameByID(db, game.ID)
+	if !g.CancelledByAdmin() || !g.IsCancelled() || g.CancelReason != "Harassment" {
+		t.Errorf("cancelled game = %+v", g)
+	}
+
+	msg, err := CreateChatMessage(db, &models.ChatMessage{GameID: game.ID, UserID: user.ID, MessageContent: "buy cheap gold"})
+	if err != nil {
+		t.Fatalf("CreateChatMessage() error = %v", err)
+	}
+	if found, _ := SearchChatMessages(db, "cheap gold", 10); len(found) != 1 || found[0].ID != msg.ID {
+		t.Errorf("SearchChatMessages() = %v, want the message", found)
+	}
+	if err := AdminDeleteChatMessage(db, admin.ID, msg.ID); err != nil {
+		t.Fatalf("AdminDeleteChatMessage() error = %v", err)
+	}
+	if found, _ := SearchChatMessages(db, "cheap gold", 10); len(found) != 1 || !found[0].IsDeleted() || found[0].DeletedBy != admin.ID {
+		t.Errorf("deleted message = %+v", found)
+	}
+
+	entries, err := GetAuditLog(db, 10)
+	if err != nil {
+		t.Fatalf("GetAuditLog() error = %v", err)
+	}
+	want := []string{models.AuditDeleteChatMessage, models.AuditCancelGame, models.AuditSetUserRole, models.AuditUnsuspendUser, models.AuditSuspendUser}
+	if len(entries) != len(want) {
+		t.Fatalf("GetAuditLog() returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.Action != want[i] || e.AdminEmail != admin.Email {
+			t.Errorf("entry %d = %+v, want action %q", i, e, want[i])
+		}
+	}
+	if entries[1].TargetType != models.AuditTargetGame || entries[1].TargetID != game.ID || entries[1].Details != "Harassment" {
+		t.Errorf("cancel entry = %+v", entries[1])
+	}
+}
+
+func TestLoginEvents(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	user := createTestUserForRSVPs(t, db, "logins_user@example.com", "password")
+	events := []*models.LoginEvent{
+		{Email: "stranger@example.com", Reason: models.LoginFailedUnknownEmail, IPAddress: "10.0.0.9"},
+		{UserID: user.ID, Email: user.Email, Reason: models.LoginFailedBadPassword, IPAddress: "10.0.0.1"},
+		{UserID: user.ID, Email: user.Email, Success: true, IPAddress: "10.0.0.1", UserAgent: "TestBrowser"},
+	}
+	for _, e := range events {
+		if err := RecordLogin(db, e); err != nil {
+			t.Fatalf("RecordLogin() error = %v", err)
+		}
+	}
+
+	all, err := GetLoginEvents(db, 0, 10)
+	if err != nil {
+		t.Fatalf("GetLoginEvents() error = %v", err)
+	}
+	if len(all) != 3 || all[2].UserID != 0 || all[2].Reason != models.LoginFailedUnknownEmail {
+		t.Errorf("all login events = %+v", all)
+	}
+	mine, _ := GetLoginEvents(db, user.ID, 10)
+	if len(mine) != 2 || !mine[0].Success || mine[0].UserAgent != "TestBrowser" || mine[1].Success {
+		t.Errorf("user's login events = %+v", mine)
+	}
+}
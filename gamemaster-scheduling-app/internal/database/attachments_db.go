@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// attachmentSelect selects an attachment with the IDs of the players it was
+// revealed to, comma separated. Use with scanAttachment.
+const attachmentSelect = `
+	SELECT a.id, a.game_id, a.uploader_id, a.filename, a.content_type, a.size_bytes,
+		a.storage_key, a.thumbnail_key, a.restricted, a.created_at,
+		(SELECT GROUP_CONCAT(ar.user_id) FROM attachment_reveals ar WHERE ar.attachment_id = a.id)
+	FROM attachments a
+`
+
+// scanAttachment scans a row produced by attachmentSelect.
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	a := &models.Attachment{}
+	var thumbnailKey, reveals sql.NullString
+	err := row.Scan(&a.ID, &a.GameID, &a.UploaderID, &a.Filename, &a.ContentType, &a.SizeBytes,
+		&a.StorageKey, &thumbnailKey, &a.Restricted, &a.CreatedAt, &reveals)
+	if err != nil {
+		return nil, err
+	}
+	a.ThumbnailKey = thumbnailKey.String
+	if reveals.Valid {
+		for _, id := range strings.Split(reveals.String, ",") {
+			userID, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			a.RevealedTo = append(a.RevealedTo, userID)
+		}
+	}
+	return a, nil
+}
+
+// CreateAttachment records an uploaded file, and who it is revealed to if restricted,
+// in one transaction. The file must already be in storage.
+func CreateAttachment(db *sql.DB, a *models.Attachment) (*models.Attachment, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var thumbnailKey interface{}
+	if a.ThumbnailKey != "" {
+		thumbnailKey = a.ThumbnailKey
+	}
+	res, err := tx.Exec(`
+		INSERT INTO attachments (game_id, uploader_id, filename, content_type, size_bytes, storage_key, thumbnail_key, restricted)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.GameID, a.UploaderID, a.Filename, a.ContentType, a.SizeBytes, a.StorageKey, thumbnailKey, a.Restricted)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	if err := replaceAttachmentReveals(tx, id, a.RevealedTo); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetAttachmentByID(db, id)
+}
+
+// GetAttachmentByID retrieves an attachment by its ID.
+func GetAttachmentByID(db *sql.DB, id int64) (*models.Attachment, error) {
+	return scanAttachment(db.QueryRow(attachmentSelect+" WHERE a.id = ?", id))
+}
+
+// GetAttachmentsForGame retrieves all of a game's attachments, newest first.
+// Filtering by what the viewer may see is the caller's responsibility.
+func GetAttachmentsForGame(db *sql.DB, gameID int64) ([]*models.Attachment, error) {
+	rows, err := db.Query(attachmentSelect+" WHERE a.game_id = ? ORDER BY a.created_at DESC, a.id DESC", gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// SetAttachmentReveals changes who may see an attachment: every participant, or
+// (restricted) only the given players.
+func SetAttachmentReveals(db *sql.DB, attachmentID int64, restricted bool, userIDs []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE attachments SET restricted = ? WHERE id = ?", restricted, attachmentID); err != nil {
+		return err
+	}
+	if !restricted {
+		userIDs = nil
+	}
+	if err := replaceAttachmentReveals(tx, attachmentID, userIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceAttachmentReveals(tx *sql.Tx, attachmentID int64, userIDs []int64) error {
+	if _, err := tx.Exec("DELETE FROM attachment_reveals WHERE attachment_id = ?", attachmentID); err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO attachment_reveals (attachment_id, user_id) VALUES (?, ?)", attachmentID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAttachment removes an attachment's record. Removing the stored file is the
+// caller's responsibility.
+func DeleteAttachment(db *sql.DB, attachmentID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM attachment_reveals WHERE attachment_id = ?", attachmentID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM attachments WHERE id = ?", attachmentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
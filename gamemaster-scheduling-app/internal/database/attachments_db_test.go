@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestAttachmentsDB(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+
+	gm, _ := CreateUser(db, "attach_gm@example.com", "password")
+	alice, _ := CreateUser(db, "attach_alice@example.com", "password")
+	bob, _ := CreateUser(db, "attach_bob@example.com", "password")
+	game, _ := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Handouts", GameDateTime: time.Now(), Location: "Table"})
+
+	worldMap, err := CreateAttachment(db, &models.Attachment{
+		GameID: game.ID, UploaderID: gm.ID, Filename: "map.png", ContentType: "image/png",
+		SizeBytes: 2048, StorageKey: "games/1/map", ThumbnailKey: "games/1/map.thumb.jpg",
+	})
+	if err != nil {
+		t.Fatalf("CreateAttachment() error = %v", err)
+	}
+	if worldMap.Restricted || worldMap.ThumbnailKey != "games/1/map.thumb.jpg" || worldMap.SizeLabel() != "2 KB" {
+		t.Errorf("map attachment = %+v", worldMap)
+	}
+
+	letter, err := CreateAttachment(db, &models.Attachment{
+		GameID: game.ID, UploaderID: gm.ID, Filename: "letter.txt", ContentType: "text/plain; charset=utf-8",
+		SizeBytes: 10, StorageKey: "games/1/letter", Restricted: true, RevealedTo: []int64{alice.ID},
+	})
+	if err != nil {
+		t.Fatalf("CreateAttachment() restricted error = %v", err)
+	}
+	if !letter.Restricted || !letter.IsRevealedTo(alice.ID) || letter.IsRevealedTo(bob.ID) || letter.ThumbnailKey != "" {
+		t.Errorf("letter attachment = %+v, want restricted to alice without a thumbnail", letter)
+	}
+
+	if err := SetAttachmentReveals(db, letter.ID, true, []int64{alice.ID, bob.ID}); err != nil {
+		t.Fatalf("SetAttachmentReveals() error = %v", err)
+	}
+	if got, _ := GetAttachmentByID(db, letter.ID); len(got.RevealedTo) != 2 {
+		t.Errorf("RevealedTo = %v, want alice and bob", got.RevealedTo)
+	}
+	if err := SetAttachmentReveals(db, letter.ID, false, []int64{alice.ID}); err != nil {
+		t.Fatalf("SetAttachmentReveals() unrestrict error = %v", err)
+	}
+	if got, _ := GetAttachmentByID(db, letter.ID); got.Restricted || len(got.RevealedTo) != 0 {
+		t.Errorf("after unrestricting = %+v, want no reveal list", got)
+	}
+
+	attachments, err := GetAttachmentsForGame(db, game.ID)
+	if err != nil || len(attachments) != 2 || attachments[0].ID != letter.ID {
+		t.Fatalf("GetAttachmentsForGame() = %v (err %v), want letter then map", attachments, err)
+	}
+
+	if err := DeleteAttachment(db, letter.ID); err != nil {
+		t.Fatalf("DeleteAttachment() error = %v", err)
+	}
+	if attachments, _ := GetAttachmentsForGame(db, game.ID); len(attachments) != 1 || attachments[0].ID != worldMap.ID {
+		t.Errorf("after delete = %v, want only the map", attachments)
+	}
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// SetAttendance records attendance for a game in one transaction. statuses maps user IDs
+// to a models.Attendance* status; an empty status clears that player's record.
+// Validation (GM only, past game, RSVP'd players) is the caller's responsibility.
+func SetAttendance(db *sql.DB, gameID int64, recordedBy int64, statuses map[int64]string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for userID, status := range statuses {
+		if status == "" {
+			_, err = tx.Exec("DELETE FROM attendance WHERE game_id = ? AND user_id = ?", gameID, userID)
+		} else {
+			_, err = tx.Exec(`
+				INSERT INTO attendance (game_id, user_id, status, recorded_by) VALUES (?, ?, ?, ?)
+				ON CONFLICT(game_id, user_id) DO UPDATE SET
+					status = excluded.status,
+					recorded_by = excluded.recorded_by,
+					recorded_at = CURRENT_TIMESTAMP
+			`, gameID, userID, status, recordedBy)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetAttendanceForGame returns the recorded status for each player of a game, by user ID.
+func GetAttendanceForGame(db *sql.DB, gameID int64) (map[int64]string, error) {
+	rows, err := db.Query("SELECT user_id, status FROM attendance WHERE game_id = ?", gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := map[int64]string{}
+	for rows.Next() {
+		var (
+			userID int64
+			status string
+		)
+		if err := rows.Scan(&userID, &status); err != nil {
+			return nil, err
+		}
+		statuses[userID] = status
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// GetPlayerStats aggregates a player's attendance across all games.
+func GetPlayerStats(db *sql.DB, userID int64) (*models.PlayerStats, error) {
+	stats := &models.PlayerStats{UserID: userID}
+	err := db.QueryRow(`
+		SELECT COUNT(*),
+			COALESCE(SUM(status IN ('present', 'late')), 0),
+			COALESCE(SUM(status = 'late'), 0),
+			COALESCE(SUM(status = 'no_show'), 0),
+			COALESCE(100.0 * SUM(status = 'no_show') / COUNT(*), 0)
+		FROM attendance
+		WHERE user_id = ?
+	`, userID).Scan(&stats.SessionsRecorded, &stats.SessionsPlayed, &stats.LateCount, &stats.NoShowCount, &stats.NoShowPercent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the date as a plain column rather than MAX(), so the driver still sees
+	// a TIMESTAMP and scans it into a time.Time.
+	err = db.QueryRow(`
+		SELECT g.game_datetime
+		FROM attendance a
+		JOIN games g ON a.game_id = g.id
+		WHERE a.user_id = ? AND a.status IN ('present', 'late')
+		ORDER BY g.game_datetime DESC
+		LIMIT 1
+	`, userID).Scan(&stats.LastPlayed)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetCampaignAttendanceMatrix builds the attendance matrix for a campaign: every player
+// with recorded attendance against every session, plus per-player totals.
+func GetCampaignAttendanceMatrix(db *sql.DB, campaignID int64) (*models.AttendanceMatrix, error) {
+	games, err := GetGamesForCampaign(db, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	matrix := &models.AttendanceMatrix{Games: games}
+	column := make(map[int64]int, len(games))
+	for i, g := range games {
+		column[g.ID] = i
+	}
+
+	rows, err := db.Query(`
+		SELECT a.user_id, COALESCE(u.username, u.email),
+			SUM(a.status IN ('present', 'late')),
+			SUM(a.status = 'late'),
+			SUM(a.status = 'no_show')
+		FROM attendance a
+		JOIN games g ON a.game_id = g.id
+		JOIN users u ON a.user_id = u.id
+		WHERE g.campaign_id = ?
+		GROUP BY a.user_id
+		ORDER BY 3 DESC, 2 ASC
+	`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byUser := map[int64]*models.AttendanceRow{}
+	for rows.Next() {
+		row := &models.AttendanceRow{Statuses: make([]string, len(games))}
+		if err := rows.Scan(&row.UserID, &row.UserName, &row.Played, &row.Late, &row.NoShows); err != nil {
+			return nil, err
+		}
+		matrix.Rows = append(matrix.Rows, row)
+		byUser[row.UserID] = row
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cells, err := db.Query(`
+		SELECT a.game_id, a.user_id, a.status
+		FROM attendance a
+		JOIN games g ON a.game_id = g.id
+		WHERE g.campaign_id = ?
+	`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer cells.Close()
+
+	for cells.Next() {
+		var (
+			gameID, userID int64
+			status         string
+		)
+		if err := cells.Scan(&gameID, &userID, &status); err != nil {
+			return nil, err
+		}
+		if row, ok := byUser[userID]; ok {
+			row.Statuses[column[gameID]] = status
+		}
+	}
+	if err = cells.Err(); err != nil {
+		return nil, err
+	}
+	return matrix, nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestAttendanceStatsAndMatrix(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+
+	gm, _ := CreateUser(db, "attendance_gm@example.com", "password")
+	alice, _ := CreateUser(db, "attendance_alice@example.com", "password")
+	bob, _ := CreateUser(db, "attendance_bob@example.com", "password")
+	if err := SetUsername(db, alice.ID, "alice"); err != nil {
+		t.Fatalf("SetUsername() error = %v", err)
+	}
+
+	campaign, err := GetOrCreateCampaign(db, gm.ID, "Curse of Strahd")
+	if err != nil {
+		t.Fatalf("GetOrCreateCampaign() error = %v", err)
+	}
+	if again, _ := GetOrCreateCampaign(db, gm.ID, "Curse of Strahd"); again.ID != campaign.ID {
+		t.Errorf("GetOrCreateCampaign() created a duplicate campaign")
+	}
+
+	start := time.Now().Add(-30 * 24 * time.Hour).Truncate(time.Second)
+	var sessions []*models.Game
+	for i := 0; i < 3; i++ {
+		game, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Session", GameDateTime: start.Add(time.Duration(i) * 7 * 24 * time.Hour), Location: "Table", CampaignID: campaign.ID})
+		if err != nil {
+			t.Fatalf("CreateGame() error = %v", err)
+		}
+		sessions = append(sessions, game)
+	}
+	oneShot, _ := CreateGame(db, &models.Game{GMID: gm.ID, Title: "One-shot", GameDateTime: start, Location: "Table"})
+	if oneShot.CampaignID != 0 || sessions[0].CampaignID != campaign.ID {
+		t.Fatalf("CampaignID = %d / %d, want 0 / %d", oneShot.CampaignID, sessions[0].CampaignID, campaign.ID)
+	}
+
+	record := func(game *models.Game, statuses map[int64]string) {
+		t.Helper()
+		if err := SetAttendance(db, game.ID, gm.ID, statuses); err != nil {
+			t.Fatalf("SetAttendance() error = %v", err)
+		}
+	}
+	record(sessions[0], map[int64]string{alice.ID: models.AttendancePresent, bob.ID: models.AttendanceNoShow})
+	record(sessions[1], map[int64]string{alice.ID: models.AttendanceLate, bob.ID: models.AttendancePresent})
+	record(sessions[2], map[int64]string{alice.ID: models.AttendanceNoShow})
+	record(oneShot, map[int64]string{alice.ID: models.AttendancePresent})
+	// Re-recording overwrites, and an empty status clears.
+	record(sessions[2], map[int64]string{alice.ID: models.AttendancePresent, bob.ID: ""})
+
+	stats, err := GetPlayerStats(db, alice.ID)
+	if err != nil {
+		t.Fatalf("GetPlayerStats() error = %v", err)
+	}
+	if stats.SessionsRecorded != 4 || stats.SessionsPlayed != 4 || stats.LateCount != 1 || stats.NoShowCount != 0 {
+		t.Errorf("alice stats = %+v, want 4 recorded, 4 played, 1 late, 0 no-shows", stats)
+	}
+	if !stats.LastPlayed.Equal(sessions[2].GameDateTime) {
+		t.Errorf("alice LastPlayed = %v, want %v", stats.LastPlayed, sessions[2].GameDateTime)
+	}
+
+	stats, _ = GetPlayerStats(db, bob.ID)
+	if stats.SessionsRecorded != 2 || stats.SessionsPlayed != 1 || stats.NoShowCount != 1 || stats.NoShowPercent != 50 {
+		t.Errorf("bob stats = %+v, want 2 recorded, 1 played, 1 no-show (50%%)", stats)
+	}
+
+	stats, _ = GetPlayerStats(db, gm.ID)
+	if stats.SessionsRecorded != 0 || stats.NoShowPercent != 0 || !stats.LastPlayed.IsZero() {
+		t.Errorf("stats with no attendance = %+v, want zeros", stats)
+	}
+
+	matrix, err := GetCampaignAttendanceMatrix(db, campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignAttendanceMatrix() error = %v", err)
+	}
+	if len(matrix.Games) != 3 || matrix.Games[0].ID != sessions[0].ID {
+		t.Fatalf("matrix games = %d, want the 3 campaign sessions oldest first", len(matrix.Games))
+	}
+	if len(matrix.Rows) != 2 {
+		t.Fatalf("matrix rows = %d, want 2", len(matrix.Rows))
+	}
+	a, b := matrix.Rows[0], matrix.Rows[1]
+	if a.UserName != "alice" || a.Played != 3 || a.Late != 1 {
+		t.Errorf("first row = %+v, want alice with 3 played (the one-shot excluded), 1 late", a)
+	}
+	wantAlice := []string{models.AttendancePresent, models.AttendanceLate, models.AttendancePresent}
+	wantBob := []string{models.AttendanceNoShow, models.AttendancePresent, ""}
+	for i := range wantAlice {
+		if a.Statuses[i] != wantAlice[i] || b.Statuses[i] != wantBob[i] {
+			t.Errorf("session %d: statuses %q / %q, want %q / %q", i, a.Statuses[i], b.Statuses[i], wantAlice[i], wantBob[i])
+		}
+	}
+	if b.UserName != "attendance_bob@example.com" || b.NoShows != 1 {
+		t.Errorf("second row = %+v, want bob (by email) with 1 no-show", b)
+	}
+}
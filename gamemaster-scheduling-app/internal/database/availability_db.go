@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// GetWeeklyAvailability retrieves a user's weekly windows, Monday first, then by start time.
+func GetWeeklyAvailability(db *sql.DB, userID int64) ([]*models.WeeklyAvailability, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, weekday, start_minute, end_minute FROM user_availability
+		WHERE user_id = ?
+		ORDER BY (weekday + 6) % 7, start_minute, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []*models.WeeklyAvailability
+	for rows.Next() {
+		w := &models.WeeklyAvailability{}
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Weekday, &w.Start, &w.End); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// AddWeeklyAvailability saves a weekly window for w.UserID and sets its ID.
+func AddWeeklyAvailability(db *sql.DB, w *models.WeeklyAvailability) error {
+	res, err := db.Exec("INSERT INTO user_availability (user_id, weekday, start_minute, end_minute) VALUES (?, ?, ?, ?)",
+		w.UserID, int(w.Weekday), w.Start, w.End)
+	if err != nil {
+		return err
+	}
+	w.ID, err = res.LastInsertId()
+	return err
+}
+
+// DeleteWeeklyAvailability removes one of a user's weekly windows. It reports
+// false if the user has no such window.
+func DeleteWeeklyAvailability(db *sql.DB, userID, id int64) (bool, error) {
+	res, err := db.Exec("DELETE FROM user_availability WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// GetAvailabilityExceptions retrieves a user's exceptions on or after the date
+// from (models.DateLayout), soonest first.
+func GetAvailabilityExceptions(db *sql.DB, userID int64, from string) ([]*models.AvailabilityException, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, date, start_minute, end_minute, free, note FROM user_availability_exceptions
+		WHERE user_id = ? AND date >= ?
+		ORDER BY date, start_minute, id
+	`, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceptions []*models.AvailabilityException
+	for rows.Next() {
+		e := &models.AvailabilityException{}
+		var note sql.NullString
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Date, &e.Start, &e.End, &e.Free, &note); err != nil {
+			return nil, err
+		}
+		e.Note = note.String
+		exceptions = append(exceptions, e)
+	}
+	return exceptions, rows.Err()
+}
+
+// AddAvailabilityException saves an exception for e.UserID and sets its ID.
+func AddAvailabilityException(db *sql.DB, e *models.AvailabilityException) error {
+	res, err := db.Exec(`
+		INSERT INTO user_availability_exceptions (user_id, date, start_minute, end_minute, free, note) VALUES (?, ?, ?, ?, ?, ?)
+	`, e.UserID, e.Date, e.Start, e.End, e.Free, nullIfEmpty(e.Note))
+	if err != nil {
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	return err
+}
+
+// DeleteAvailabilityException removes one of a user's exceptions. It reports
+// false if the user has no such exception.
+func DeleteAvailabilityException(db *sql.DB, userID, id int64) (bool, error) {
+	res, err := db.Exec("DELETE FROM user_availability_exceptions WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// GetAvailabilityProfile retrieves a user's weekly windows and their exceptions
+// on or after the date from (models.DateLayout).
+func GetAvailabilityProfile(db *sql.DB, user *models.User, from string) (*models.AvailabilityProfile, error) {
+	weekly, err := GetWeeklyAvailability(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := GetAvailabilityExceptions(db, user.ID, from)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AvailabilityProfile{User: user, Weekly: weekly, Exceptions: exceptions}, nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestAvailabilityAndCampaignRoster(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "avail_gm@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "avail_alice@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "avail_bob@example.com", "password")
+	dropout := createTestUserForRSVPs(t, db, "avail_dropout@example.com", "password")
+
+	if err := SetTimezone(db, alice.ID, "Europe/Berlin"); err != nil {
+		t.Fatalf("SetTimezone() error = %v", err)
+	}
+	if u, _ := GetUserByID(db, alice.ID); u.Timezone != "Europe/Berlin" || u.Location().String() != "Europe/Berlin" {
+		t.Errorf("timezone = %q, want Europe/Berlin", u.Timezone)
+	}
+	if u, _ := GetUserByID(db, bob.ID); u.Timezone != "" || u.Location() != time.UTC {
+		t.Errorf("unset timezone = %q, want UTC", u.Timezone)
+	}
+
+	// Weekly windows come back Monday first.
+	for _, w := range []models.AvailabilityWindow{
+		{Weekday: time.Sunday, Start: 10 * 60, End: 14 * 60},
+		{Weekday: time.Monday, Start: 19 * 60, End: 22 * 60},
+		{Weekday: time.Monday, Start: 8 * 60, End: 9 * 60},
+	} {
+		if err := AddWeeklyAvailability(db, &models.WeeklyAvailability{UserID: alice.ID, AvailabilityWindow: w}); err != nil {
+			t.Fatalf("AddWeeklyAvailability() error = %v", err)
+		}
+	}
+	weekly, err := GetWeeklyAvailability(db, alice.ID)
+	if err != nil || len(weekly) != 3 {
+		t.Fatalf("GetWeeklyAvailability() = %v, %v; want 3 windows", weekly, err)
+	}
+	if weekly[0].Label() != "Monday 08:00-09:00" || weekly[2].Weekday != time.Sunday {
+		t.Errorf("weekly = %s, %s, %s; want Monday morning first and Sunday last", weekly[0].Label(), weekly[1].Label(), weekly[2].Label())
+	}
+	if ok, _ := DeleteWeeklyAvailability(db, bob.ID, weekly[0].ID); ok {
+		t.Errorf("deleted someone else's window")
+	}
+	if ok, err := DeleteWeeklyAvailability(db, alice.ID, weekly[0].ID); !ok || err != nil {
+		t.Errorf("DeleteWeeklyAvailability() = %v, %v; want true", ok, err)
+	}
+
+	// Exceptions before the date asked about are left out.
+	for _, e := range []*models.AvailabilityException{
+		{UserID: alice.ID, Date: "2030-01-05", Start: 0, End: 24 * 60},
+		{UserID: alice.ID, Date: "2030-02-01", Start: 18 * 60, End: 20 * 60, Free: true, Note: "Day off"},
+	} {
+		if err := AddAvailabilityException(db, e); err != nil {
+			t.Fatalf("AddAvailabilityException() error = %v", err)
+		}
+	}
+	profile, err := GetAvailabilityProfile(db, alice, "2030-01-10")
+	if err != nil {
+		t.Fatalf("GetAvailabilityProfile() error = %v", err)
+	}
+	if len(profile.Weekly) != 2 || len(profile.Exceptions) != 1 || !profile.Exceptions[0].Free || profile.Exceptions[0].Note != "Day off" {
+		t.Errorf("profile = %+v, want 2 windows and the later exception", profile)
+	}
+	if ok, _ := DeleteAvailabilityException(db, alice.ID, profile.Exceptions[0].ID); !ok {
+		t.Errorf("DeleteAvailabilityException() = false, want true")
+	}
+
+	// The roster is the GM and everyone still signed up to a session.
+	campaign, _ := GetOrCreateCampaign(db, gm.ID, "Dragon Heist")
+	session, _ := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Session 1", GameDateTime: time.Now(), Location: "Online", CampaignID: campaign.ID})
+	other, _ := CreateGame(db, &models.Game{GMID: gm.ID, Title: "One-shot", GameDateTime: time.Now(), Location: "Online"})
+	for _, r := range []*models.RSVP{
+		{UserID: bob.ID, GameID: session.ID, Status: models.RSVPStatusMaybe},
+		{UserID: alice.ID, GameID: session.ID, Status: models.RSVPStatusAttending},
+		{UserID: dropout.ID, GameID: session.ID, Status: models.RSVPStatusNotAttending},
+		{UserID: dropout.ID, GameID: other.ID, Status: models.RSVPStatusAttending},
+	} {
+		if err := CreateOrUpdateRSVP(db, r); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+	roster, err := GetCampaignRoster(db, campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignRoster() error = %v", err)
+	}
+	var emails []string
+	for _, u := range roster {
+		emails = append(emails, u.Email)
+	}
+	if len(emails) != 3 || emails[0] != alice.Email || emails[1] != bob.Email || emails[2] != gm.Email {
+		t.Errorf("roster = %v, want alice, bob and the GM", emails)
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import "database/sql"
+
+// BlockUser hides blockedID's chat messages from blockerID. Blocking someone twice
+// is not an error.
+func BlockUser(db *sql.DB, blockerID, blockedID int64) error {
+	_, err := db.Exec(`
+		INSERT INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)
+		ON CONFLICT(blocker_id, blocked_id) DO NOTHING
+	`, blockerID, blockedID)
+	return err
+}
+
+// UnblockUser lifts a block. Unblocking someone who isn't blocked is not an error.
+func UnblockUser(db *sql.DB, blockerID, blockedID int64) error {
+	_, err := db.Exec("DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?", blockerID, blockedID)
+	return err
+}
+
+// GetBlockedUserIDs returns the set of users blockerID has blocked.
+func GetBlockedUserIDs(db *sql.DB, blockerID int64) (map[int64]bool, error) {
+	rows, err := db.Query("SELECT blocked_id FROM user_blocks WHERE blocker_id = ?", blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		blocked[id] = true
+	}
+	return blocked, rows.Err()
+}
+
+// IsUserBlocked reports whether blockerID has blocked blockedID.
+func IsUserBlocked(db *sql.DB, blockerID, blockedID int64) (bool, error) {
+	var exists bool
+	err := db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?)",
+		blockerID, blockedID,
+	).Scan(&exists)
+	return exists, err
+}
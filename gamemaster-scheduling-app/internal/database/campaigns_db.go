@@ -0,0 +1,50 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// GetOrCreateCampaign returns the GM's campaign with the given name, creating it if needed.
+func GetOrCreateCampaign(db *sql.DB, gmID int64, name string) (*models.Campaign, error) {
+	_, err := db.Exec(`
+		INSERT INTO campaigns (gm_id, name) VALUES (?, ?)
+		ON CONFLICT(gm_id, name) DO NOTHING
+	`, gmID, name)
+	if err != nil {
+		return nil, err
+	}
+	campaign := &models.Campaign{}
+	err = db.QueryRow("SELECT id, gm_id, name, created_at FROM campaigns WHERE gm_id = ? AND name = ?", gmID, name).
+		Scan(&campaign.ID, &campaign.GMID, &campaign.Name, &campaign.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// GetCampaignByID retrieves a campaign by its ID.
+func GetCampaignByID(db *sql.DB, id int64) (*models.Campaign, error) {
+	campaign := &models.Campaign{}
+	err := db.QueryRow("SELECT id, gm_id, name, created_at FROM campaigns WHERE id = ?", id).
+		Scan(&campaign.ID, &campaign.GMID, &campaign.Name, &campaign.CreatedAt)
+	if err != nil {
+		return nil, err // This will include sql.ErrNoRows if not found
+	}
+	return campaign, nil
+}
+
+// GetCampaignRoster retrieves the people in a campaign: its GM, the co-GMs of
+// its sessions, and players with an RSVP to any session other than not attending
+// or declined. Suspended users are left out. They are ordered by display name.
+func GetCampaignRoster(db *sql.DB, campaignID int64) ([]*models.User, error) {
+	return queryUsers(db, "SELECT "+userColumns+` FROM users
+		WHERE suspended_at IS NULL AND (
+			id = (SELECT gm_id FROM campaigns WHERE id = ?)
+			OR id IN (SELECT s.user_id FROM game_staff s JOIN games g ON g.id = s.game_id WHERE g.campaign_id = ?)
+			OR id IN (SELECT r.user_id FROM rsvps r JOIN games g ON g.id = r.game_id WHERE g.campaign_id = ? AND r.status NOT IN (?, ?))
+		)
+		ORDER BY COALESCE(username, email) COLLATE NOCASE, id`,
+		campaignID, campaignID, campaignID, models.RSVPStatusNotAttending, models.RSVPStatusDeclined)
+}
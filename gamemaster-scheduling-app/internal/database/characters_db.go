@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// characterColumns are the characters columns read by scanCharacter, for a table aliased "c".
+const characterColumns = "c.id, c.user_id, c.name, c.system, c.class, c.level, c.sheet_url, c.notes, c.created_at"
+
+// scanCharacter scans a row selected with characterColumns.
+func scanCharacter(row rowScanner) (*models.Character, error) {
+	c := &models.Character{}
+	var system, class, sheetURL, notes sql.NullString
+	var level sql.NullInt64
+	if err := row.Scan(&c.ID, &c.UserID, &c.Name, &system, &class, &level, &sheetURL, &notes, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	c.System, c.Class, c.SheetURL, c.Notes = system.String, class.String, sheetURL.String, notes.String
+	c.Level = int(level.Int64)
+	return c, nil
+}
+
+// CreateCharacter inserts a new character for c.UserID.
+func CreateCharacter(db *sql.DB, c *models.Character) (*models.Character, error) {
+	res, err := db.Exec(`
+		INSERT INTO characters (user_id, name, system, class, level, sheet_url, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, c.UserID, c.Name, nullIfEmpty(c.System), nullIfEmpty(c.Class), nullIfZero(c.Level), nullIfEmpty(c.SheetURL), nullIfEmpty(c.Notes))
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return GetCharacterByID(db, id)
+}
+
+// UpdateCharacter saves changes to a character. Ownership checks are the caller's responsibility.
+func UpdateCharacter(db *sql.DB, c *models.Character) error {
+	_, err := db.Exec(`
+		UPDATE characters SET name = ?, system = ?, class = ?, level = ?, sheet_url = ?, notes = ?
+		WHERE id = ?
+	`, c.Name, nullIfEmpty(c.System), nullIfEmpty(c.Class), nullIfZero(c.Level), nullIfEmpty(c.SheetURL), nullIfEmpty(c.Notes), c.ID)
+	return err
+}
+
+// GetCharacterByID retrieves a character by its ID.
+func GetCharacterByID(db *sql.DB, id int64) (*models.Character, error) {
+	return scanCharacter(db.QueryRow("SELECT "+characterColumns+" FROM characters c WHERE c.id = ?", id))
+}
+
+// GetCharactersForUser retrieves a user's characters, by name.
+func GetCharactersForUser(db *sql.DB, userID int64) ([]*models.Character, error) {
+	rows, err := db.Query("SELECT "+characterColumns+" FROM characters c WHERE c.user_id = ? ORDER BY c.name COLLATE NOCASE, c.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var characters []*models.Character
+	for rows.Next() {
+		c, err := scanCharacter(rows)
+		if err != nil {
+			return nil, err
+		}
+		characters = append(characters, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return characters, nil
+}
+
+// DeleteCharacter removes a character, leaving any RSVPs that brought it without one.
+func DeleteCharacter(db *sql.DB, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE rsvps SET character_id = NULL WHERE character_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM characters WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCharactersDB(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+
+	gm, _ := CreateUser(db, "roster_gm@example.com", "password")
+	alice, _ := CreateUser(db, "roster_alice@example.com", "password")
+	game, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Tomb", GameDateTime: time.Now(), Location: "Table", MinLevel: 3, MaxLevel: 5})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+	if game.MinLevel != 3 || game.MaxLevel != 5 || game.LevelRangeLabel() != "3-5" {
+		t.Errorf("game level range = %d-%d (%q), want 3-5", game.MinLevel, game.MaxLevel, game.LevelRangeLabel())
+	}
+
+	wizard, err := CreateCharacter(db, &models.Character{UserID: alice.ID, Name: "Zanna", System: "D&D 5e", Class: "Wizard", Level: 4})
+	if err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+	rogue, _ := CreateCharacter(db, &models.Character{UserID: alice.ID, Name: "ash"})
+	if wizard.Summary() != "Level 4 Wizard" || rogue.Summary() != "" || rogue.Level != 0 {
+		t.Errorf("summaries = %q, %q", wizard.Summary(), rogue.Summary())
+	}
+
+	rogue.Class, rogue.Level, rogue.SheetURL = "Rogue", 7, "https://example.com/ash"
+	if err := UpdateCharacter(db, rogue); err != nil {
+		t.Fatalf("UpdateCharacter() error = %v", err)
+	}
+	roster, err := GetCharactersForUser(db, alice.ID)
+	if err != nil || len(roster) != 2 {
+		t.Fatalf("GetCharactersForUser() = %v, %v; want 2 characters", roster, err)
+	}
+	if roster[0].Name != "ash" || roster[0].Summary() != "Level 7 Rogue" || roster[0].SheetURL != "https://example.com/ash" {
+		t.Errorf("roster[0] = %+v, want the updated rogue sorted first", roster[0])
+	}
+
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending, CharacterID: wizard.ID}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	rsvps, err := GetRSVPsForGame(db, game.ID)
+	if err != nil || len(rsvps) != 1 {
+		t.Fatalf("GetRSVPsForGame() = %v, %v", rsvps, err)
+	}
+	if rsvps[0].Character == nil || rsvps[0].Character.Name != "Zanna" || game.LevelOutOfRange(rsvps[0].Character.Level) {
+		t.Errorf("rsvp character = %+v, want Zanna within the level range", rsvps[0].Character)
+	}
+
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending, CharacterID: rogue.ID}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() switch character error = %v", err)
+	}
+	rsvp, _ := GetRSVPByUserForGame(db, alice.ID, game.ID)
+	if rsvp.CharacterID != rogue.ID || !game.LevelOutOfRange(rsvp.Character.Level) {
+		t.Errorf("rsvp = %+v, want the level 7 rogue flagged as out of range", rsvp)
+	}
+
+	if err := DeleteCharacter(db, rogue.ID); err != nil {
+		t.Fatalf("DeleteCharacter() error = %v", err)
+	}
+	rsvp, err = GetRSVPByUserForGame(db, alice.ID, game.ID)
+	if err != nil || rsvp.CharacterID != 0 || rsvp.Character != nil || rsvp.Status != models.RSVPStatusAttending {
+		t.Errorf("rsvp after deleting its character = %+v, %v; want kept without a character", rsvp, err)
+	}
+	if roster, _ := GetCharactersForUser(db, alice.ID); len(roster) != 1 {
+		t.Errorf("roster after delete has %d characters, want 1", len(roster))
+	}
+}
@@ -2,22 +2,97 @@ package database
 
 import (
 	"database/sql"
-	// "time" // Not strictly needed if not using time.Now() directly here
+	"encoding/json"
+	"strconv"
+	"strings"
 
 	"github.com/gamemaster-scheduling/app/internal/models"
 )
 
+// chatMessageSelect selects a chat message with its author's email, the users it
+// @mentions (as "id:username" pairs) and, for /roll commands, the stored dice roll.
+// Use with scanChatMessage.
+const chatMessageSelect = `
+	SELECT cm.id, cm.game_id, cm.user_id, u.email, cm.message_content, cm.created_at,
+		cm.edited_at, cm.deleted_at, cm.deleted_by,
+		cr.id, cr.expression, cr.label, cr.total, cr.groups_json,
+		(SELECT GROUP_CONCAT(mu.id || ':' || mu.username)
+			FROM chat_mentions mn JOIN users mu ON mn.user_id = mu.id
+			WHERE mn.message_id = cm.id)
+	FROM chat_messages cm
+	JOIN users u ON cm.user_id = u.id
+	LEFT JOIN chat_rolls cr ON cr.message_id = cm.id
+`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanChatMessage scans a row produced by chatMessageSelect.
+func scanChatMessage(row rowScanner) (*models.ChatMessage, error) {
+	msg := &models.ChatMessage{}
+	var (
+		editedAt   sql.NullTime
+		deletedAt  sql.NullTime
+		deletedBy  sql.NullInt64
+		rollID     sql.NullInt64
+		expression sql.NullString
+		label      sql.NullString
+		total      sql.NullInt64
+		groupsJSON sql.NullString
+		mentions   sql.NullString
+	)
+	err := row.Scan(
+		&msg.ID, &msg.GameID, &msg.UserID, &msg.UserEmail, &msg.MessageContent, &msg.CreatedAt,
+		&editedAt, &deletedAt, &deletedBy,
+		&rollID, &expression, &label, &total, &groupsJSON,
+		&mentions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	msg.EditedAt = editedAt.Time
+	msg.DeletedAt = deletedAt.Time
+	msg.DeletedBy = deletedBy.Int64
+	if mentions.Valid {
+		for _, pair := range strings.Split(mentions.String, ",") {
+			id, username, _ := strings.Cut(pair, ":")
+			userID, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			msg.Mentions = append(msg.Mentions, models.Mention{UserID: userID, Username: username})
+		}
+	}
+	if rollID.Valid {
+		msg.Roll = &models.DiceRoll{
+			ID:         rollID.Int64,
+			MessageID:  msg.ID,
+			Expression: expression.String,
+			Label:      label.String,
+			Total:      int(total.Int64),
+		}
+		if err := json.Unmarshal([]byte(groupsJSON.String), &msg.Roll.Groups); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
 // CreateChatMessage inserts a new chat message into the chat_messages table.
+// If message.Roll is set, the dice roll is stored in chat_rolls in the same transaction.
 // The UserEmail field in the passed models.ChatMessage is ignored here,
-// as it's not a column in the chat_messages table. It's populated by GetChatMessagesForGame.
+// as it's not a column in the chat_messages table. It's populated on the returned message.
 func CreateChatMessage(db *sql.DB, message *models.ChatMessage) (*models.ChatMessage, error) {
-	stmt, err := db.Prepare("INSERT INTO chat_messages(game_id, user_id, message_content) VALUES(?, ?, ?)")
+	tx, err := db.Begin()
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
+	defer tx.Rollback() // No-op once committed
 
-	res, err := stmt.Exec(message.GameID, message.UserID, message.MessageContent)
+	res, err := tx.Exec("INSERT INTO chat_messages(game_id, user_id, message_content) VALUES(?, ?, ?)",
+		message.GameID, message.UserID, message.MessageContent)
 	if err != nil {
 		return nil, err
 	}
@@ -27,57 +102,38 @@ func CreateChatMessage(db *sql.DB, message *models.ChatMessage) (*models.ChatMes
 		return nil, err
 	}
 
-	// To get CreatedAt and potentially UserEmail (if we were to fetch it here, but we won't),
-	// we need to retrieve the message.
-	// For this function, we'll construct the returned message partially,
-	// as the schema sets created_at. A full select would be more robust.
-	// Let's do a Get to ensure all fields are consistent.
-	
-	// Minimal approach:
-	// createdMsg := *message // copy
-	// createdMsg.ID = id
-	// // createdMsg.CreatedAt will be zero time.Time, unless schema default is read back,
-	// // which LastInsertId doesn't provide.
-	// return &createdMsg, nil
-
-	// Better approach: Retrieve the message to get all DB-generated fields (like created_at)
-	// and to ensure the UserEmail is correctly associated if we were to fetch it here.
-	// However, to keep this function focused, we will query for the specific message
-	// and populate its UserEmail from the users table.
-	// This is slightly redundant if the calling handler already has user email,
-	// but makes this function more self-contained for returning a "complete" ChatMessage model.
-
-	var createdMessage models.ChatMessage
-	row := db.QueryRow(`
-		SELECT cm.id, cm.game_id, cm.user_id, u.email, cm.message_content, cm.created_at 
-		FROM chat_messages cm
-		JOIN users u ON cm.user_id = u.id
-		WHERE cm.id = ?
-	`, id)
-	err = row.Scan(
-		&createdMessage.ID,
-		&createdMessage.GameID,
-		&createdMessage.UserID,
-		&createdMessage.UserEmail, // Populate UserEmail
-		&createdMessage.MessageContent,
-		&createdMessage.CreatedAt,
-	)
-	if err != nil {
+	if message.Roll != nil {
+		groupsJSON, err := json.Marshal(message.Roll.Groups)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec("INSERT INTO chat_rolls(message_id, expression, label, total, groups_json) VALUES(?, ?, ?, ?, ?)",
+			id, message.Roll.Expression, message.Roll.Label, message.Roll.Total, string(groupsJSON))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	return &createdMessage, nil
+	// Retrieve the message to get all DB-generated fields (like created_at)
+	// and the author's email, so callers get a "complete" ChatMessage model.
+	return GetChatMessageByID(db, id)
+}
+
+// GetChatMessageByID retrieves a single chat message, including the user's email and any dice roll.
+func GetChatMessageByID(db *sql.DB, id int64) (*models.ChatMessage, error) {
+	return scanChatMessage(db.QueryRow(chatMessageSelect+" WHERE cm.id = ?", id))
 }
 
 // GetChatMessagesForGame retrieves all chat messages for a given game,
 // including the user's email, ordered by creation time (oldest first).
 func GetChatMessagesForGame(db *sql.DB, gameID int64) ([]*models.ChatMessage, error) {
-	rows, err := db.Query(`
-		SELECT cm.id, cm.game_id, cm.user_id, u.email, cm.message_content, cm.created_at
-		FROM chat_messages cm
-		JOIN users u ON cm.user_id = u.id
+	rows, err := db.Query(chatMessageSelect+`
 		WHERE cm.game_id = ?
-		ORDER BY cm.created_at ASC
+		ORDER BY cm.created_at ASC, cm.id ASC
 	`, gameID)
 	if err != nil {
 		return nil, err
@@ -86,8 +142,7 @@ func GetChatMessagesForGame(db *sql.DB, gameID int64) ([]*models.ChatMessage, er
 
 	var messages []*models.ChatMessage
 	for rows.Next() {
-		msg := &models.ChatMessage{}
-		err := rows.Scan(&msg.ID, &msg.GameID, &msg.UserID, &msg.UserEmail, &msg.MessageContent, &msg.CreatedAt)
+		msg, err := scanChatMessage(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -99,3 +154,276 @@ func GetChatMessagesForGame(db *sql.DB, gameID int64) ([]*models.ChatMessage, er
 	}
 	return messages, nil
 }
+
+// SearchChatMessages finds messages across all games whose content or author's email
+// contains query (ignoring case), newest first, including deleted ones. It is meant
+// for site admins.
+func SearchChatMessages(db *sql.DB, query string, limit int) ([]*models.ChatMessage, error) {
+	rows, err := db.Query(chatMessageSelect+`
+		WHERE cm.message_content LIKE '%' || ? || '%' OR u.email LIKE '%' || ? || '%'
+		ORDER BY cm.created_at DESC, cm.id DESC LIMIT ?
+	`, query, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*models.ChatMessage
+	for rows.Next() {
+		msg, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// GetChatMessagesPage retrieves up to limit messages for a game that were posted before
+// the message beforeID (or the latest messages if beforeID is 0), returned oldest first
+// for display. hasOlder reports whether there are even older messages to load.
+// The cursor is (created_at, id), served by idx_chat_messages_game_created.
+func GetChatMessagesPage(db *sql.DB, gameID int64, beforeID int64, limit int) (messages []*models.ChatMessage, hasOlder bool, err error) {
+	query := chatMessageSelect + " WHERE cm.game_id = ?"
+	args := []interface{}{gameID}
+	if beforeID > 0 {
+		query += " AND (cm.created_at, cm.id) < (SELECT created_at, id FROM chat_messages WHERE id = ?)"
+		args = append(args, beforeID)
+	}
+	// Fetch one extra row to learn whether an older page exists.
+	query += " ORDER BY cm.created_at DESC, cm.id DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		msg, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		messages = append(messages, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(messages) > limit {
+		hasOlder = true
+		messages = messages[:limit]
+	}
+	// Reverse to oldest first.
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, hasOlder, nil
+}
+
+// GetChatMessagesSince retrieves the messages for a game posted after the message afterID
+// (or from the start if afterID is 0), oldest first, at most limit of them.
+// Used to append new messages without re-rendering the list.
+func GetChatMessagesSince(db *sql.DB, gameID int64, afterID int64, limit int) ([]*models.ChatMessage, error) {
+	query := chatMessageSelect + " WHERE cm.game_id = ?"
+	args := []interface{}{gameID}
+	if afterID > 0 {
+		query += " AND (cm.created_at, cm.id) > (SELECT created_at, id FROM chat_messages WHERE id = ?)"
+		args = append(args, afterID)
+	}
+	query += " ORDER BY cm.created_at ASC, cm.id ASC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*models.ChatMessage
+	for rows.Next() {
+		msg, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// UpdateChatMessageContent replaces a message's content, marks it edited and stores
+// the previous content in chat_message_revisions, all in one transaction.
+// Permission and edit-window checks are the caller's responsibility.
+func UpdateChatMessageContent(db *sql.DB, messageID int64, newContent string) (*models.ChatMessage, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRow("SELECT message_content FROM chat_messages WHERE id = ? AND deleted_at IS NULL", messageID).Scan(&previous)
+	if err != nil {
+		return nil, err // sql.ErrNoRows if missing or deleted
+	}
+
+	if _, err := tx.Exec("INSERT INTO chat_message_revisions(message_id, previous_content) VALUES(?, ?)", messageID, previous); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE chat_messages SET message_content = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ?", newContent, messageID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetChatMessageByID(db, messageID)
+}
+
+// GetChatMessageRevisions retrieves the previous versions of a message, oldest first.
+func GetChatMessageRevisions(db *sql.DB, messageID int64) ([]*models.ChatMessageRevision, error) {
+	rows, err := db.Query(`
+		SELECT id, message_id, previous_content, edited_at
+		FROM chat_message_revisions
+		WHERE message_id = ?
+		ORDER BY edited_at ASC, id ASC
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*models.ChatMessageRevision
+	for rows.Next() {
+		rev := &models.ChatMessageRevision{}
+		if err := rows.Scan(&rev.ID, &rev.MessageID, &rev.PreviousContent, &rev.EditedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// SetChatMentions records which users a message @mentions, replacing any previous set
+// (e.g. after an edit). It returns the user IDs that were not mentioned before, so
+// callers notify each user only once per message.
+func SetChatMentions(db *sql.DB, messageID int64, userIDs []int64) (added []int64, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT user_id FROM chat_mentions WHERE message_id = ?", messageID)
+	if err != nil {
+		return nil, err
+	}
+	previous := map[int64]bool{}
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		previous[userID] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM chat_mentions WHERE message_id = ?", messageID); err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		res, err := tx.Exec("INSERT OR IGNORE INTO chat_mentions(message_id, user_id) VALUES(?, ?)", messageID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 && !previous[userID] {
+			added = append(added, userID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// SoftDeleteChatMessage marks a message deleted by deletedBy (the author or a moderator).
+// The content is kept for moderation. Deleting an already deleted message returns sql.ErrNoRows.
+func SoftDeleteChatMessage(db *sql.DB, messageID int64, deletedBy int64) error {
+	res, err := db.Exec("UPDATE chat_messages SET deleted_at = CURRENT_TIMESTAMP, deleted_by = ? WHERE id = ? AND deleted_at IS NULL", deletedBy, messageID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MuteUserInGame stops a user from posting in a game's chat. Muting twice is a no-op.
+func MuteUserInGame(db *sql.DB, gameID, userID, mutedBy int64) error {
+	_, err := db.Exec(`
+		INSERT INTO chat_mutes (game_id, user_id, muted_by) VALUES (?, ?, ?)
+		ON CONFLICT(game_id, user_id) DO NOTHING
+	`, gameID, userID, mutedBy)
+	return err
+}
+
+// UnmuteUserInGame lets a muted user post in a game's chat again.
+func UnmuteUserInGame(db *sql.DB, gameID, userID int64) error {
+	_, err := db.Exec("DELETE FROM chat_mutes WHERE game_id = ? AND user_id = ?", gameID, userID)
+	return err
+}
+
+// IsUserMutedInGame reports whether a user is muted in a game's chat.
+func IsUserMutedInGame(db *sql.DB, gameID, userID int64) (bool, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM chat_mutes WHERE game_id = ? AND user_id = ?", gameID, userID).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// GetChatMutesForGame retrieves the users muted in a game's chat, including their emails.
+func GetChatMutesForGame(db *sql.DB, gameID int64) ([]*models.ChatMute, error) {
+	rows, err := db.Query(`
+		SELECT m.id, m.game_id, m.user_id, u.email, m.muted_by, m.created_at
+		FROM chat_mutes m
+		JOIN users u ON m.user_id = u.id
+		WHERE m.game_id = ?
+		ORDER BY m.created_at ASC, m.id ASC
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mutes []*models.ChatMute
+	for rows.Next() {
+		mute := &models.ChatMute{}
+		if err := rows.Scan(&mute.ID, &mute.GameID, &mute.UserID, &mute.UserEmail, &mute.MutedBy, &mute.CreatedAt); err != nil {
+			return nil, err
+		}
+		mutes = append(mutes, mute)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return mutes, nil
+}
@@ -138,3 +138,261 @@ func TestCreateChatMessageAndGet(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateChatMessageWithRoll(t *testing.T) {
+	db, teardown := setupTestDBForChat(t)
+	defer teardown()
+
+	user := createTestUserForChat(t, db, "roller@example.com", "pass")
+	game := createTestGameForChat(t, db, user, "Roll Test Game")
+
+	roll := &models.DiceRoll{
+		Expression: "4d6kh3+2",
+		Label:      "Strength",
+		Total:      14,
+		Groups: []models.DiceGroup{
+			{Notation: "4d6kh3", Subtotal: 12, Dice: []models.Die{
+				{Sides: 6, Value: 5}, {Sides: 6, Value: 1, Dropped: true}, {Sides: 6, Value: 4, Rerolls: []int{1}}, {Sides: 6, Value: 3},
+			}},
+			{Notation: "2", Constant: true, Subtotal: 2},
+		},
+	}
+	created, err := CreateChatMessage(db, &models.ChatMessage{
+		GameID:         game.ID,
+		UserID:         user.ID,
+		MessageContent: "/roll 4d6kh3+2 Strength",
+		Roll:           roll,
+	})
+	if err != nil {
+		t.Fatalf("CreateChatMessage() with roll error = %v", err)
+	}
+	if created.Roll == nil {
+		t.Fatalf("CreateChatMessage() returned message without roll")
+	}
+	if created.Roll.MessageID != created.ID || created.Roll.ID == 0 {
+		t.Errorf("Roll IDs = (%d, msg %d), want non-zero ID linked to message %d", created.Roll.ID, created.Roll.MessageID, created.ID)
+	}
+	roll.ID, roll.MessageID = created.Roll.ID, created.Roll.MessageID
+	if !reflect.DeepEqual(created.Roll, roll) {
+		t.Errorf("stored roll = %+v, want %+v", created.Roll, roll)
+	}
+
+	plain, err := CreateChatMessage(db, &models.ChatMessage{GameID: game.ID, UserID: user.ID, MessageContent: "rolled a 20, honest"})
+	if err != nil {
+		t.Fatalf("CreateChatMessage() plain error = %v", err)
+	}
+	if plain.Roll != nil {
+		t.Errorf("plain message has roll %+v, want nil", plain.Roll)
+	}
+
+	messages, err := GetChatMessagesForGame(db, game.ID)
+	if err != nil {
+		t.Fatalf("GetChatMessagesForGame() error = %v", err)
+	}
+	if len(messages) != 2 || messages[0].Roll == nil || messages[1].Roll != nil {
+		t.Fatalf("GetChatMessagesForGame() = %+v, want roll only on first message", messages)
+	}
+}
+
+func TestEditDeleteAndMuteChat(t *testing.T) {
+	db, teardown := setupTestDBForChat(t)
+	defer teardown()
+
+	gm := createTestUserForChat(t, db, "modgm@example.com", "pass")
+	player := createTestUserForChat(t, db, "modplayer@example.com", "pass")
+	game := createTestGameForChat(t, db, gm, "Moderated Game")
+
+	msg, err := CreateChatMessage(db, &models.ChatMessage{GameID: game.ID, UserID: player.ID, MessageContent: "teh first"})
+	if err != nil {
+		t.Fatalf("CreateChatMessage() error = %v", err)
+	}
+	if msg.IsEdited() || msg.IsDeleted() {
+		t.Fatalf("new message should be neither edited nor deleted: %+v", msg)
+	}
+
+	t.Run("edit keeps revisions", func(t *testing.T) {
+		if _, err := UpdateChatMessageContent(db, msg.ID, "the first"); err != nil {
+			t.Fatalf("UpdateChatMessageContent() error = %v", err)
+		}
+		edited, err := UpdateChatMessageContent(db, msg.ID, "the first!")
+		if err != nil {
+			t.Fatalf("UpdateChatMessageContent() second edit error = %v", err)
+		}
+		if edited.MessageContent != "the first!" || !edited.IsEdited() {
+			t.Errorf("edited message = %+v, want new content and EditedAt set", edited)
+		}
+		revisions, err := GetChatMessageRevisions(db, msg.ID)
+		if err != nil {
+			t.Fatalf("GetChatMessageRevisions() error = %v", err)
+		}
+		if len(revisions) != 2 || revisions[0].PreviousContent != "teh first" || revisions[1].PreviousContent != "the first" {
+			t.Errorf("revisions = %+v, want [teh first, the first]", revisions)
+		}
+	})
+
+	t.Run("soft delete by moderator", func(t *testing.T) {
+		if err := SoftDeleteChatMessage(db, msg.ID, gm.ID); err != nil {
+			t.Fatalf("SoftDeleteChatMessage() error = %v", err)
+		}
+		deleted, err := GetChatMessageByID(db, msg.ID)
+		if err != nil {
+			t.Fatalf("GetChatMessageByID() error = %v", err)
+		}
+		if !deleted.IsDeleted() || !deleted.RemovedByModerator() || deleted.DeletedBy != gm.ID {
+			t.Errorf("deleted message = %+v, want deleted by GM %d", deleted, gm.ID)
+		}
+		if err := SoftDeleteChatMessage(db, msg.ID, player.ID); err != sql.ErrNoRows {
+			t.Errorf("deleting twice error = %v, want sql.ErrNoRows", err)
+		}
+		if _, err := UpdateChatMessageContent(db, msg.ID, "sneaky"); err != sql.ErrNoRows {
+			t.Errorf("editing deleted message error = %v, want sql.ErrNoRows", err)
+		}
+	})
+
+	t.Run("mute and unmute", func(t *testing.T) {
+		if err := MuteUserInGame(db, game.ID, player.ID, gm.ID); err != nil {
+			t.Fatalf("MuteUserInGame() error = %v", err)
+		}
+		if err := MuteUserInGame(db, game.ID, player.ID, gm.ID); err != nil {
+			t.Fatalf("MuteUserInGame() twice error = %v", err)
+		}
+		muted, err := IsUserMutedInGame(db, game.ID, player.ID)
+		if err != nil || !muted {
+			t.Fatalf("IsUserMutedInGame() = %v, %v; want true", muted, err)
+		}
+		mutes, err := GetChatMutesForGame(db, game.ID)
+		if err != nil || len(mutes) != 1 || mutes[0].UserEmail != player.Email {
+			t.Fatalf("GetChatMutesForGame() = %+v, %v; want one mute for %s", mutes, err, player.Email)
+		}
+		if err := UnmuteUserInGame(db, game.ID, player.ID); err != nil {
+			t.Fatalf("UnmuteUserInGame() error = %v", err)
+		}
+		if muted, _ := IsUserMutedInGame(db, game.ID, player.ID); muted {
+			t.Errorf("user still muted after UnmuteUserInGame()")
+		}
+	})
+}
+
+func TestChatMessagePagination(t *testing.T) {
+	db, teardown := setupTestDBForChat(t)
+	defer teardown()
+
+	user := createTestUserForChat(t, db, "pager@example.com", "pass")
+	game := createTestGameForChat(t, db, user, "Long Campaign")
+	otherGame := createTestGameForChat(t, db, user, "Other Game")
+
+	// Many messages share a created_at second, so the id tie-break matters.
+	var ids []int64
+	for i := 0; i < 7; i++ {
+		msg, err := CreateChatMessage(db, &models.ChatMessage{GameID: game.ID, UserID: user.ID, MessageContent: "msg"})
+		if err != nil {
+			t.Fatalf("CreateChatMessage() error = %v", err)
+		}
+		ids = append(ids, msg.ID)
+		// Interleave another game's messages; they must never leak into this game's pages.
+		if _, err := CreateChatMessage(db, &models.ChatMessage{GameID: otherGame.ID, UserID: user.ID, MessageContent: "other"}); err != nil {
+			t.Fatalf("CreateChatMessage() other game error = %v", err)
+		}
+	}
+
+	pageIDs := func(messages []*models.ChatMessage) []int64 {
+		var got []int64
+		for _, m := range messages {
+			got = append(got, m.ID)
+		}
+		return got
+	}
+
+	latest, hasOlder, err := GetChatMessagesPage(db, game.ID, 0, 3)
+	if err != nil {
+		t.Fatalf("GetChatMessagesPage() error = %v", err)
+	}
+	if !reflect.DeepEqual(pageIDs(latest), ids[4:7]) || !hasOlder {
+		t.Errorf("latest page = %v (hasOlder %v), want %v (hasOlder true)", pageIDs(latest), hasOlder, ids[4:7])
+	}
+
+	older, hasOlder, err := GetChatMessagesPage(db, game.ID, latest[0].ID, 3)
+	if err != nil {
+		t.Fatalf("GetChatMessagesPage(before) error = %v", err)
+	}
+	if !reflect.DeepEqual(pageIDs(older), ids[1:4]) || !hasOlder {
+		t.Errorf("older page = %v (hasOlder %v), want %v (hasOlder true)", pageIDs(older), hasOlder, ids[1:4])
+	}
+
+	oldest, hasOlder, err := GetChatMessagesPage(db, game.ID, older[0].ID, 3)
+	if err != nil {
+		t.Fatalf("GetChatMessagesPage(oldest) error = %v", err)
+	}
+	if !reflect.DeepEqual(pageIDs(oldest), ids[0:1]) || hasOlder {
+		t.Errorf("oldest page = %v (hasOlder %v), want %v (hasOlder false)", pageIDs(oldest), hasOlder, ids[0:1])
+	}
+
+	since, err := GetChatMessagesSince(db, game.ID, ids[4], 10)
+	if err != nil {
+		t.Fatalf("GetChatMessagesSince() error = %v", err)
+	}
+	if !reflect.DeepEqual(pageIDs(since), ids[5:7]) {
+		t.Errorf("GetChatMessagesSince() = %v, want %v", pageIDs(since), ids[5:7])
+	}
+	fromStart, err := GetChatMessagesSince(db, game.ID, 0, 2)
+	if err != nil {
+		t.Fatalf("GetChatMessagesSince(0) error = %v", err)
+	}
+	if !reflect.DeepEqual(pageIDs(fromStart), ids[0:2]) {
+		t.Errorf("GetChatMessagesSince(0, limit 2) = %v, want %v", pageIDs(fromStart), ids[0:2])
+	}
+}
+
+func TestSetChatMentions(t *testing.T) {
+	db, teardown := setupTestDBForChat(t)
+	defer teardown()
+
+	gm := createTestUserForChat(t, db, "mentions_gm@example.com", "password")
+	alice := createTestUserForChat(t, db, "mentions_alice@example.com", "password")
+	bob := createTestUserForChat(t, db, "mentions_bob@example.com", "password")
+	if err := SetUsername(db, alice.ID, "alice"); err != nil {
+		t.Fatalf("SetUsername() error = %v", err)
+	}
+	if err := SetUsername(db, bob.ID, "bob"); err != nil {
+		t.Fatalf("SetUsername() error = %v", err)
+	}
+	game := createTestGameForChat(t, db, gm, "Mentions Game")
+	msg, err := CreateChatMessage(db, &models.ChatMessage{GameID: game.ID, UserID: gm.ID, MessageContent: "@alice hi"})
+	if err != nil {
+		t.Fatalf("CreateChatMessage() error = %v", err)
+	}
+
+	added, err := SetChatMentions(db, msg.ID, []int64{alice.ID})
+	if err != nil || !reflect.DeepEqual(added, []int64{alice.ID}) {
+		t.Fatalf("SetChatMentions() = %v, %v; want [%d]", added, err, alice.ID)
+	}
+	added, err = SetChatMentions(db, msg.ID, []int64{alice.ID, bob.ID})
+	if err != nil || !reflect.DeepEqual(added, []int64{bob.ID}) {
+		t.Fatalf("SetChatMentions() after edit = %v, %v; want only [%d]", added, err, bob.ID)
+	}
+
+	got, err := GetChatMessageByID(db, msg.ID)
+	if err != nil {
+		t.Fatalf("GetChatMessageByID() error = %v", err)
+	}
+	want := []models.Mention{{UserID: alice.ID, Username: "alice"}, {UserID: bob.ID, Username: "bob"}}
+	if len(got.Mentions) != 2 {
+		t.Fatalf("Mentions = %+v, want %+v", got.Mentions, want)
+	}
+	for _, m := range want {
+		found := false
+		for _, g := range got.Mentions {
+			found = found || g == m
+		}
+		if !found {
+			t.Errorf("Mentions = %+v, missing %+v", got.Mentions, m)
+		}
+	}
+
+	if _, err := SetChatMentions(db, msg.ID, nil); err != nil {
+		t.Fatalf("SetChatMentions(nil) error = %v", err)
+	}
+	if got, _ := GetChatMessageByID(db, msg.ID); len(got.Mentions) != 0 {
+		t.Errorf("Mentions after clearing = %+v, want none", got.Mentions)
+	}
+}
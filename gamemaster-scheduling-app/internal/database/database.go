@@ -2,11 +2,19 @@ package database
 
 import (
 	"database/sql"
-	"os"
+	_ "embed"
+	"fmt"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// schemaSQL is embedded so the schema loads regardless of the working directory
+// (the server binary, `go test` in a package dir, etc.).
+//
+//go:embed schema.sql
+var schemaSQL string
+
 // InitDB initializes and returns a database connection
 func InitDB(dataSourceName string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", dataSourceName)
@@ -14,29 +22,179 @@ func InitDB(dataSourceName string) (*sql.DB, error) {
 		return nil, err
 	}
 
+	// Each connection to ":memory:" opens a separate, empty database, so pin
+	// in-memory databases (used by tests) to a single connection.
+	if dataSourceName == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+
 	if err = db.Ping(); err != nil {
 		return nil, err
 	}
 
 	// Load schema
-	if err = loadSchema(db, "internal/database/schema.sql"); err != nil {
+	if err = loadSchema(db); err != nil {
+		return nil, err
+	}
+
+	if err = migrateColumns(db); err != nil {
+		return nil, err
+	}
+
+	if err = createMigratedIndexes(db); err != nil {
 		return nil, err
 	}
 
 	return db, nil
 }
 
-// loadSchema reads and executes the SQL schema from the given path
-func loadSchema(db *sql.DB, schemaPath string) error {
-	content, err := os.ReadFile(schemaPath)
-	if err != nil {
-		return err
+// columnMigration adds a column that was introduced after its table was first created.
+// schema.sql already has these columns for new databases; CREATE TABLE IF NOT EXISTS
+// leaves older databases untouched, so they are added here instead.
+type columnMigration struct {
+	table, column, definition string
+}
+
+var columnMigrations = []columnMigration{
+	{"chat_messages", "edited_at", "TIMESTAMP"},
+	{"chat_messages", "deleted_at", "TIMESTAMP"},
+	{"chat_messages", "deleted_by", "INTEGER REFERENCES users(id)"},
+	{"users", "username", "TEXT"},
+	{"games", "campaign_id", "INTEGER REFERENCES campaigns(id)"},
+	{"games", "min_level", "INTEGER"},
+	{"games", "max_level", "INTEGER"},
+	{"rsvps", "character_id", "INTEGER REFERENCES characters(id)"},
+	{"games", "requires_approval", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"games", "max_players", "INTEGER"},
+	{"games", "rsvp_deadline", "TIMESTAMP"},
+	{"games", "min_players", "INTEGER"},
+	{"games", "quorum_status", "TEXT"},
+	{"games", "quorum_decided_at", "TIMESTAMP"},
+	{"games", "rsvps_reopened", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"rsvps", "comment", "TEXT"},
+	{"rsvps", "guests", "INTEGER NOT NULL DEFAULT 0"},
+	{"rsvps", "reviewed_by", "INTEGER REFERENCES users(id)"},
+	{"rsvps", "reviewed_at", "TIMESTAMP"},
+	{"rsvps", "review_message", "TEXT"},
+	{"games", "transfer_to_id", "INTEGER REFERENCES users(id)"},
+	{"users", "role", "TEXT NOT NULL DEFAULT 'user'"},
+	{"users", "suspended_at", "TIMESTAMP"},
+	{"users", "suspended_reason", "TEXT"},
+	{"games", "cancelled_at", "TIMESTAMP"},
+	{"games", "cancel_reason", "TEXT"},
+	{"games", "content_notes", "TEXT"},
+	{"games", "system_id", "INTEGER REFERENCES game_systems(id)"},
+	{"games", "experience_level", "TEXT"},
+	{"games", "format", "TEXT"},
+	{"games", "play_mode", "TEXT"},
+	{"users", "timezone", "TEXT"},
+	{"games", "event_slot_id", "INTEGER REFERENCES event_slots(id)"},
+	{"games", "event_venue_id", "INTEGER REFERENCES event_venues(id)"},
+	{"event_slots", "ranked", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"event_slots", "assignment_seed", "INTEGER NOT NULL DEFAULT 0"},
+	{"event_slots", "assignments_published_at", "TIMESTAMP"},
+	{"games", "venue_id", "INTEGER REFERENCES venues(id)"},
+	{"games", "virtual_table_id", "INTEGER REFERENCES virtual_tables(id)"},
+	{"ledger_entries", "reverses_entry_id", "INTEGER REFERENCES ledger_entries(id)"},
+}
+
+// migratedIndexes are indexes on columns from columnMigrations. They can't live in
+// schema.sql, which runs before older databases have the columns.
+var migratedIndexes = []string{
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username COLLATE NOCASE)`,
+	`CREATE INDEX IF NOT EXISTS idx_games_campaign ON games (campaign_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_games_system ON games (system_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_games_event_slot ON games (event_slot_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_games_venue ON games (venue_id)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_entries_reverses ON ledger_entries (reverses_entry_id)`,
+}
+
+// migrateColumns applies columnMigrations that are missing from the database.
+func migrateColumns(db *sql.DB) error {
+	for _, m := range columnMigrations {
+		exists, err := columnExists(db, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)); err != nil {
+			return fmt.Errorf("adding %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
+
+// createMigratedIndexes creates migratedIndexes that don't exist yet.
+func createMigratedIndexes(db *sql.DB) error {
+	for _, stmt := range migratedIndexes {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("creating index: %w", err)
+		}
 	}
+	return nil
+}
 
-	_, err = db.Exec(string(content))
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return err
+		return false, err
 	}
+	defer rows.Close()
 
-	return nil
+	for rows.Next() {
+		var (
+			cid        int
+			name, typ  string
+			notNull    bool
+			dflt       sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &primaryKey); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// loadSchema executes the embedded SQL schema.
+func loadSchema(db *sql.DB) error {
+	_, err := db.Exec(schemaSQL)
+	return err
+}
+
+// nullIfZero stores 0 as NULL, for optional numeric columns.
+func nullIfZero(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// nullIfZero64 stores 0 as NULL, for optional references to other tables.
+func nullIfZero64(n int64) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// nullIfZeroTime stores an unset time as NULL.
+func nullIfZeroTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// nullIfEmpty stores "" as NULL, for optional text columns.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
 }
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestInitDBMigratesOlderDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+
+	// A database created before the chat moderation and username columns existed.
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	_, err = old.Exec(`CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE chat_messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		game_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		message_content TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	old.Close()
+	if err != nil {
+		t.Fatalf("creating old schema error = %v", err)
+	}
+
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB() on older database error = %v", err)
+	}
+	defer db.Close()
+
+	for _, m := range columnMigrations {
+		exists, err := columnExists(db, m.table, m.column)
+		if err != nil {
+			t.Fatalf("columnExists(%s, %s) error = %v", m.table, m.column, err)
+		}
+		if !exists {
+			t.Errorf("column %s.%s was not added", m.table, m.column)
+		}
+	}
+
+	// The unique username index is created once the column exists.
+	_, err = db.Exec("INSERT INTO users (email, password_hash, username) VALUES ('a@example.com', 'x', 'Alice'), ('b@example.com', 'x', 'alice')")
+	if err == nil {
+		t.Errorf("inserting usernames differing only in case succeeded, want unique index violation")
+	}
+
+	// Running InitDB again must be a no-op.
+	db2, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB() second run error = %v", err)
+	}
+	db2.Close()
+}
@@ -0,0 +1,526 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// ErrEventFull is returned by RegisterForEvent when every badge has been handed out.
+var ErrEventFull = errors.New("event has no badges left")
+
+// ErrAssignmentsPublished is returned by PublishEventAssignments when the slot's
+// seating was already published.
+var ErrAssignmentsPublished = errors.New("slot assignments already published")
+
+// eventSelect selects the columns read by scanEvent.
+const eventSelect = `SELECT id, organizer_id, name, description, starts_on, max_attendees,
+	(SELECT COUNT(*) FROM event_registrations WHERE event_registrations.event_id = events.id), created_at
+	FROM events`
+
+// scanEvent scans a row selected with eventSelect.
+func scanEvent(row rowScanner) (*models.Event, error) {
+	e := &models.Event{}
+	var description sql.NullString
+	var maxAttendees sql.NullInt64
+	if err := row.Scan(&e.ID, &e.OrganizerID, &e.Name, &description, &e.StartsOn, &maxAttendees, &e.Attendees, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	e.Description = description.String
+	e.MaxAttendees = int(maxAttendees.Int64)
+	return e, nil
+}
+
+// CreateEvent saves a new event and sets its ID.
+func CreateEvent(db *sql.DB, e *models.Event) error {
+	res, err := db.Exec("INSERT INTO events (organizer_id, name, description, starts_on, max_attendees) VALUES (?, ?, ?, ?, ?)",
+		e.OrganizerID, e.Name, nullIfEmpty(e.Description), e.StartsOn, nullIfZero(e.MaxAttendees))
+	if err != nil {
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetEventByID retrieves an event by its ID.
+func GetEventByID(db *sql.DB, id int64) (*models.Event, error) {
+	return scanEvent(db.QueryRow(eventSelect+" WHERE id = ?", id)) // sql.ErrNoRows if not found
+}
+
+// GetEvents retrieves every event, latest first.
+func GetEvents(db *sql.DB) ([]*models.Event, error) {
+	rows, err := db.Query(eventSelect + " ORDER BY starts_on DESC, id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// AddEventVenue saves a venue of v.EventID and sets its ID.
+func AddEventVenue(db *sql.DB, v *models.EventVenue) error {
+	res, err := db.Exec("INSERT INTO event_venues (event_id, name, details) VALUES (?, ?, ?)", v.EventID, v.Name, nullIfEmpty(v.Details))
+	if err != nil {
+		return err
+	}
+	v.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetEventVenues retrieves an event's venues, in the order they were added.
+func GetEventVenues(db *sql.DB, eventID int64) ([]*models.EventVenue, error) {
+	rows, err := db.Query("SELECT id, event_id, name, details FROM event_venues WHERE event_id = ? ORDER BY id", eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var venues []*models.EventVenue
+	for rows.Next() {
+		v := &models.EventVenue{}
+		var details sql.NullString
+		if err := rows.Scan(&v.ID, &v.EventID, &v.Name, &details); err != nil {
+			return nil, err
+		}
+		v.Details = details.String
+		venues = append(venues, v)
+	}
+	return venues, rows.Err()
+}
+
+// AddEventSlot saves a time slot of s.EventID and sets its ID.
+func AddEventSlot(db *sql.DB, s *models.EventSlot) error {
+	res, err := db.Exec("INSERT INTO event_slots (event_id, name, starts_at, ends_at, ranked, assignment_seed) VALUES (?, ?, ?, ?, ?, ?)",
+		s.EventID, s.Name, s.StartsAt, s.EndsAt, s.Ranked, s.Seed)
+	if err != nil {
+		return err
+	}
+	s.ID, err = res.LastInsertId()
+	return err
+}
+
+// eventSlotSelect selects the columns read by scanEventSlot.
+const eventSlotSelect = "SELECT id, event_id, name, starts_at, ends_at, ranked, assignment_seed, assignments_published_at FROM event_slots"
+
+// scanEventSlot scans a row selected with eventSlotSelect.
+func scanEventSlot(row rowScanner) (*models.EventSlot, error) {
+	s := &models.EventSlot{}
+	var publishedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.EventID, &s.Name, &s.StartsAt, &s.EndsAt, &s.Ranked, &s.Seed, &publishedAt); err != nil {
+		return nil, err
+	}
+	s.PublishedAt = publishedAt.Time
+	return s, nil
+}
+
+// GetEventSlots retrieves an event's time slots, earliest first.
+func GetEventSlots(db *sql.DB, eventID int64) ([]*models.EventSlot, error) {
+	rows, err := db.Query(eventSlotSelect+" WHERE event_id = ? ORDER BY starts_at, id", eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var slots []*models.EventSlot
+	for rows.Next() {
+		s, err := scanEventSlot(rows)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, s)
+	}
+	return slots, rows.Err()
+}
+
+// GetEventSlotByID retrieves a time slot by its ID.
+func GetEventSlotByID(db *sql.DB, id int64) (*models.EventSlot, error) {
+	return scanEventSlot(db.QueryRow(eventSlotSelect+" WHERE id = ?", id)) // sql.ErrNoRows if not found
+}
+
+// RegisterForEvent gives the user the event's next badge, or returns the one
+// they already have. It returns ErrEventFull if no badges are left.
+func RegisterForEvent(db *sql.DB, eventID, userID int64) (*models.EventRegistration, error) {
+	_, err := db.Exec(`
+		INSERT INTO event_registrations (event_id, user_id, badge_number)
+		SELECT e.id, ?, COALESCE((SELECT MAX(badge_number) FROM event_registrations WHERE event_id = e.id), 0) + 1
+		FROM events e
+		WHERE e.id = ? AND (e.max_attendees IS NULL OR (SELECT COUNT(*) FROM event_registrations WHERE event_id = e.id) < e.max_attendees)
+		ON CONFLICT(event_id, user_id) DO NOTHING
+	`, userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := GetEventRegistration(db, eventID, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrEventFull
+	}
+	return reg, err
+}
+
+// GetEventRegistration retrieves a user's badge for an event; sql.ErrNoRows if
+// they haven't registered.
+func GetEventRegistration(db *sql.DB, eventID, userID int64) (*models.EventRegistration, error) {
+	reg := &models.EventRegistration{}
+	err := db.QueryRow("SELECT id, event_id, user_id, badge_number, created_at FROM event_registrations WHERE event_id = ? AND user_id = ?", eventID, userID).
+		Scan(&reg.ID, &reg.EventID, &reg.UserID, &reg.BadgeNumber, &reg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// GetEventTables retrieves the tables of an event, by start time and then title, with
+// the seats taken at each. As everywhere, seats are taken by attending players
+// and their guests, not by the table's GMs.
+func GetEventTables(db *sql.DB, eventID int64) ([]*models.EventTable, error) {
+	games, err := queryGames(db, "SELECT "+gameColumns+` FROM games
+		WHERE event_slot_id IN (SELECT id FROM event_slots WHERE event_id = ?)
+		ORDER BY julianday(game_datetime), event_slot_id, title COLLATE NOCASE, id`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT r.game_id, SUM(1 + r.guests) FROM rsvps r
+		JOIN games g ON g.id = r.game_id
+		JOIN event_slots s ON s.id = g.event_slot_id
+		WHERE s.event_id = ? AND r.status = ? AND r.user_id != g.gm_id
+			AND r.user_id NOT IN (SELECT user_id FROM game_staff WHERE game_id = g.id)
+		GROUP BY r.game_id
+	`, eventID, models.RSVPStatusAttending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taken := make(map[int64]int)
+	for rows.Next() {
+		var gameID int64
+		var n int
+		if err := rows.Scan(&gameID, &n); err != nil {
+			return nil, err
+		}
+		taken[gameID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tables := make([]*models.EventTable, len(games))
+	for i, g := range games {
+		tables[i] = &models.EventTable{Game: g, Taken: taken[g.ID]}
+	}
+	return tables, nil
+}
+
+// GetEventSignups maps the tables of an event the user has an RSVP for to its status.
+func GetEventSignups(db *sql.DB, eventID, userID int64) (map[int64]string, error) {
+	rows, err := db.Query(`
+		SELECT r.game_id, r.status FROM rsvps r
+		JOIN games g ON g.id = r.game_id
+		JOIN event_slots s ON s.id = g.event_slot_id
+		WHERE s.event_id = ? AND r.user_id = ?
+	`, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	signups := make(map[int64]string)
+	for rows.Next() {
+		var gameID int64
+		var status string
+		if err := rows.Scan(&gameID, &status); err != nil {
+			return nil, err
+		}
+		signups[gameID] = status
+	}
+	return signups, rows.Err()
+}
+
+// GetSlotSignup retrieves the table in a slot, other than exceptGameID, that the
+// user is running, attending or waiting for approval at; sql.ErrNoRows if there
+// is none.
+func GetSlotSignup(db *sql.DB, userID, slotID, exceptGameID int64) (*models.Game, error) {
+	return scanGame(db.QueryRow("SELECT "+gameColumns+" FROM games WHERE "+slotSignupWhere+" LIMIT 1",
+		slotSignupArgs(userID, slotID, exceptGameID)...))
+}
+
+// slotSignupWhere matches the games in a slot, but one, that a user is running,
+// attending or waiting for approval at. Its arguments come from slotSignupArgs.
+const slotSignupWhere = `event_slot_id = ? AND id != ? AND (
+	gm_id = ?
+	OR id IN (SELECT game_id FROM game_staff WHERE user_id = ?)
+	OR id IN (SELECT game_id FROM rsvps WHERE user_id = ? AND status IN (?, ?))
+)`
+
+func slotSignupArgs(userID, slotID, exceptGameID int64) []interface{} {
+	return []interface{}{slotID, exceptGameID, userID, userID, userID, models.RSVPStatusAttending, models.RSVPStatusPending}
+}
+
+// GetEventOverview lays out an event's tables by venue and slot, with each
+// slot's totals: its seats, how many are taken, and how many registered
+// players aren't running, attending or waiting for approval at any of its tables.
+func GetEventOverview(db *sql.DB, eventID int64) (*models.EventOverview, error) {
+	slots, err := GetEventSlots(db, eventID)
+	if err != nil {
+		return nil, err
+	}
+	venues, err := GetEventVenues(db, eventID)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := GetEventTables(db, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &models.EventOverview{Slots: slots}
+	column := make(map[int64]int, len(slots))
+	for i, s := range slots {
+		column[s.ID] = i
+		overview.Totals = append(overview.Totals, &models.SlotFill{})
+	}
+	newRow := func(v *models.EventVenue) *models.EventOverviewRow {
+		return &models.EventOverviewRow{Venue: v, Cells: make([][]*models.EventTable, len(slots))}
+	}
+	rowOf := make(map[int64]*models.EventOverviewRow, len(venues))
+	for _, v := range venues {
+		rowOf[v.ID] = newRow(v)
+		overview.Rows = append(overview.Rows, rowOf[v.ID])
+	}
+	for _, t := range tables {
+		row := rowOf[t.Game.EventVenueID]
+		if row == nil { // No venue yet
+			row = newRow(nil)
+			rowOf[t.Game.EventVenueID] = row
+			overview.Rows = append(overview.Rows, row)
+		}
+		i := column[t.Game.EventSlotID]
+		row.Cells[i] = append(row.Cells[i], t)
+		total := overview.Totals[i]
+		total.Tables++
+		total.Taken += t.Taken
+		total.Seats += t.Game.MaxPlayers
+	}
+
+	var registered int
+	if err := db.QueryRow("SELECT COUNT(*) FROM event_registrations WHERE event_id = ?", eventID).Scan(&registered); err != nil {
+		return nil, err
+	}
+	placed := make(map[int64]int)
+	rows, err := db.Query(`
+		SELECT p.slot_id, COUNT(DISTINCT p.user_id) FROM (
+			SELECT g.event_slot_id AS slot_id, r.user_id FROM rsvps r JOIN games g ON g.id = r.game_id WHERE r.status IN (?, ?)
+			UNION SELECT event_slot_id, gm_id FROM games
+			UNION SELECT g.event_slot_id, st.user_id FROM game_staff st JOIN games g ON g.id = st.game_id
+		) p
+		JOIN event_slots s ON s.id = p.slot_id
+		JOIN event_registrations er ON er.event_id = s.event_id AND er.user_id = p.user_id
+		WHERE s.event_id = ?
+		GROUP BY p.slot_id
+	`, models.RSVPStatusAttending, models.RSVPStatusPending, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var slotID int64
+		var n int
+		if err := rows.Scan(&slotID, &n); err != nil {
+			return nil, err
+		}
+		placed[slotID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, s := range slots {
+		overview.Totals[i].Unplaced = registered - placed[s.ID]
+	}
+	return overview, nil
+}
+
+// SetEventSlotSeed changes the seed of the lottery that breaks ties in a ranked
+// slot's seating.
+func SetEventSlotSeed(db *sql.DB, slotID, seed int64) error {
+	_, err := db.Exec("UPDATE event_slots SET assignment_seed = ? WHERE id = ?", seed, slotID)
+	return err
+}
+
+// SetEventChoices replaces a player's ranked choices in a slot with gameIDs,
+// best first. No games withdraws the player from the slot's seating.
+func SetEventChoices(db *sql.DB, slotID, userID int64, gameIDs []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM event_choices WHERE slot_id = ? AND user_id = ?", slotID, userID); err != nil {
+		return err
+	}
+	for i, gameID := range gameIDs {
+		if _, err := tx.Exec("INSERT INTO event_choices (slot_id, user_id, game_id, rank) VALUES (?, ?, ?, ?)", slotID, userID, gameID, i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetEventChoices retrieves the ranked choices of a slot's registered players,
+// by player.
+func GetEventChoices(db *sql.DB, slotID int64) ([]*models.EventChoices, error) {
+	rows, err := db.Query(`
+		SELECT c.user_id, u.email, c.game_id FROM event_choices c
+		JOIN users u ON u.id = c.user_id
+		JOIN event_slots s ON s.id = c.slot_id
+		JOIN event_registrations er ON er.event_id = s.event_id AND er.user_id = c.user_id
+		WHERE c.slot_id = ?
+		ORDER BY c.user_id, c.rank
+	`, slotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var choices []*models.EventChoices
+	for rows.Next() {
+		var userID, gameID int64
+		var email string
+		if err := rows.Scan(&userID, &email, &gameID); err != nil {
+			return nil, err
+		}
+		if len(choices) == 0 || choices[len(choices)-1].UserID != userID {
+			choices = append(choices, &models.EventChoices{UserID: userID, UserEmail: email})
+		}
+		last := choices[len(choices)-1]
+		last.GameIDs = append(last.GameIDs, gameID)
+	}
+	return choices, rows.Err()
+}
+
+// GetUserEventChoices maps the slots of an event to the user's ranked choices
+// in them, best first.
+func GetUserEventChoices(db *sql.DB, eventID, userID int64) (map[int64][]int64, error) {
+	rows, err := db.Query(`
+		SELECT c.slot_id, c.game_id FROM event_choices c
+		JOIN event_slots s ON s.id = c.slot_id
+		WHERE s.event_id = ? AND c.user_id = ?
+		ORDER BY c.slot_id, c.rank
+	`, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	choices := make(map[int64][]int64)
+	for rows.Next() {
+		var slotID, gameID int64
+		if err := rows.Scan(&slotID, &gameID); err != nil {
+			return nil, err
+		}
+		choices[slotID] = append(choices[slotID], gameID)
+	}
+	return choices, rows.Err()
+}
+
+// SetEventAssignmentPin pins a player to a table in a ranked slot's seating,
+// or keeps them from being seated if gameID is 0.
+func SetEventAssignmentPin(db *sql.DB, slotID, userID, gameID int64) error {
+	_, err := db.Exec(`
+		INSERT INTO event_assignment_pins (slot_id, user_id, game_id) VALUES (?, ?, ?)
+		ON CONFLICT(slot_id, user_id) DO UPDATE SET game_id = excluded.game_id
+	`, slotID, userID, nullIfZero64(gameID))
+	return err
+}
+
+// ClearEventAssignmentPin lets the solver seat the player again.
+func ClearEventAssignmentPin(db *sql.DB, slotID, userID int64) error {
+	_, err := db.Exec("DELETE FROM event_assignment_pins WHERE slot_id = ? AND user_id = ?", slotID, userID)
+	return err
+}
+
+// GetEventAssignmentPins maps the pinned players of a slot to their table, or
+// to 0 if they are kept from being seated.
+func GetEventAssignmentPins(db *sql.DB, slotID int64) (map[int64]int64, error) {
+	rows, err := db.Query("SELECT user_id, game_id FROM event_assignment_pins WHERE slot_id = ?", slotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := make(map[int64]int64)
+	for rows.Next() {
+		var userID int64
+		var gameID sql.NullInt64
+		if err := rows.Scan(&userID, &gameID); err != nil {
+			return nil, err
+		}
+		pins[userID] = gameID.Int64
+	}
+	return pins, rows.Err()
+}
+
+// PublishEventAssignments makes a ranked slot's seating final: each player in
+// seats (player to game) gets an attending RSVP for their table, recorded in
+// its history as the organizer's doing. It returns ErrAssignmentsPublished if
+// the seating was already published.
+func PublishEventAssignments(db *sql.DB, slotID, organizerID int64, seats map[int64]int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE event_slots SET assignments_published_at = CURRENT_TIMESTAMP WHERE id = ? AND ranked AND assignments_published_at IS NULL", slotID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAssignmentsPublished
+	}
+
+	for userID, gameID := range seats {
+		var oldStatus sql.NullString
+		err := tx.QueryRow("SELECT status FROM rsvps WHERE user_id = ? AND game_id = ?", userID, gameID).Scan(&oldStatus)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if oldStatus.String == models.RSVPStatusAttending {
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT INTO rsvps (user_id, game_id, status, created_at, updated_at)
+			VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(user_id, game_id) DO UPDATE SET
+				status = excluded.status,
+				reviewed_by = NULL,
+				reviewed_at = NULL,
+				review_message = NULL,
+				updated_at = CURRENT_TIMESTAMP
+		`, userID, gameID, models.RSVPStatusAttending)
+		if err != nil {
+			return err
+		}
+		var rsvpID int64
+		if err := tx.QueryRow("SELECT id FROM rsvps WHERE user_id = ? AND game_id = ?", userID, gameID).Scan(&rsvpID); err != nil {
+			return err
+		}
+		if err := insertRSVPEvent(tx, rsvpID, organizerID, oldStatus.String, models.RSVPStatusAttending, ""); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
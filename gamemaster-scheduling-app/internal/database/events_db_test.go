@@ -0,0 +1,219 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestEventRegistrationsTablesAndOverview(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	organizer := createTestUserForRSVPs(t, db, "event_org@example.com", "password")
+	gm := createTestUserForRSVPs(t, db, "event_gm@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "event_alice@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "event_bob@example.com", "password")
+
+	event := &models.Event{OrganizerID: organizer.ID, Name: "DiceCon", StartsOn: "2030-05-04", MaxAttendees: 3}
+	if err := CreateEvent(db, event); err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+
+	for i, u := range []*models.User{gm, alice, bob} {
+		reg, err := RegisterForEvent(db, event.ID, u.ID)
+		if err != nil {
+			t.Fatalf("RegisterForEvent(%s) error = %v", u.Email, err)
+		}
+		if reg.BadgeNumber != i+1 {
+			t.Errorf("badge of %s = %d, want %d", u.Email, reg.BadgeNumber, i+1)
+		}
+	}
+	if reg, err := RegisterForEvent(db, event.ID, alice.ID); err != nil || reg.BadgeNumber != 2 {
+		t.Errorf("registering again = %+v, %v; want badge #2 back", reg, err)
+	}
+	if _, err := RegisterForEvent(db, event.ID, organizer.ID); err != ErrEventFull {
+		t.Errorf("registering past the cap: error = %v, want ErrEventFull", err)
+	}
+	if got, _ := GetEventByID(db, event.ID); got.Attendees != 3 || !got.IsFull() {
+		t.Errorf("GetEventByID() = %+v, want 3 attendees and full", got)
+	}
+
+	hall := &models.EventVenue{EventID: event.ID, Name: "Main hall"}
+	if err := AddEventVenue(db, hall); err != nil {
+		t.Fatalf("AddEventVenue() error = %v", err)
+	}
+	start := time.Date(2030, 5, 4, 9, 0, 0, 0, time.UTC)
+	morning := &models.EventSlot{EventID: event.ID, Name: "Morning", StartsAt: start, EndsAt: start.Add(4 * time.Hour)}
+	evening := &models.EventSlot{EventID: event.ID, Name: "Evening", StartsAt: start.Add(9 * time.Hour), EndsAt: start.Add(13 * time.Hour)}
+	for _, s := range []*models.EventSlot{evening, morning} {
+		if err := AddEventSlot(db, s); err != nil {
+			t.Fatalf("AddEventSlot(%s) error = %v", s.Name, err)
+		}
+	}
+	newTable := func(title string, slot *models.EventSlot, venueID int64, seats int) *models.Game {
+		t.Helper()
+		g, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: title, GameDateTime: slot.StartsAt, Location: "DiceCon",
+			MaxPlayers: seats, EventSlotID: slot.ID, EventVenueID: venueID})
+		if err != nil {
+			t.Fatalf("CreateGame(%s) error = %v", title, err)
+		}
+		return g
+	}
+	dragons := newTable("Dragons", morning, hall.ID, 4)
+	goblins := newTable("Goblins", morning, 0, 2)
+	newTable("Vampires", evening, hall.ID, 5)
+	for _, r := range []*models.RSVP{
+		{UserID: alice.ID, GameID: dragons.ID, Status: models.RSVPStatusAttending, Guests: 1},
+		{UserID: bob.ID, GameID: goblins.ID, Status: models.RSVPStatusMaybe},
+	} {
+		if err := CreateOrUpdateRSVP(db, r); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+
+	if got, _ := GetGameByID(db, dragons.ID); got.EventID != event.ID || got.EventSlotID != morning.ID || got.EventVenueID != hall.ID {
+		t.Errorf("GetGameByID() event = %d/%d/%d, want %d/%d/%d", got.EventID, got.EventSlotID, got.EventVenueID, event.ID, morning.ID, hall.ID)
+	}
+	tables, err := GetEventTables(db, event.ID)
+	if err != nil {
+		t.Fatalf("GetEventTables() error = %v", err)
+	}
+	if len(tables) != 3 || tables[0].Game.Title != "Dragons" || tables[0].Taken != 2 || tables[0].FillPercent() != 50 || tables[1].Taken != 0 || tables[2].Game.Title != "Vampires" {
+		t.Errorf("GetEventTables() = %v, want Dragons (2 taken), Goblins (0), Vampires", tables)
+	}
+
+	if g, err := GetSlotSignup(db, alice.ID, morning.ID, goblins.ID); err != nil || g.ID != dragons.ID {
+		t.Errorf("GetSlotSignup(alice) = %v, %v; want Dragons", g, err)
+	}
+	if _, err := GetSlotSignup(db, alice.ID, morning.ID, dragons.ID); err == nil {
+		t.Error("GetSlotSignup(alice) excluding Dragons found a game, want none")
+	}
+	if _, err := GetSlotSignup(db, bob.ID, morning.ID, 0); err == nil {
+		t.Error("GetSlotSignup(bob) found a game for a maybe, want none")
+	}
+	if g, err := GetSlotSignup(db, gm.ID, evening.ID, 0); err != nil || g.Title != "Vampires" {
+		t.Errorf("GetSlotSignup(gm) = %v, %v; want the table they run", g, err)
+	}
+	for _, status := range []string{models.RSVPStatusAttending, models.RSVPStatusPending} {
+		if err := CreateOrUpdateRSVP(db, &models.RSVP{UserID: alice.ID, GameID: goblins.ID, Status: status}); err != ErrSlotTaken {
+			t.Errorf("CreateOrUpdateRSVP(alice, Goblins, %s) error = %v, want ErrSlotTaken", status, err)
+		}
+	}
+	signups, err := GetEventSignups(db, event.ID, bob.ID)
+	if err != nil || signups[goblins.ID] != models.RSVPStatusMaybe || len(signups) != 1 {
+		t.Errorf("GetEventSignups(bob) = %v, %v; want Goblins: maybe", signups, err)
+	}
+
+	overview, err := GetEventOverview(db, event.ID)
+	if err != nil {
+		t.Fatalf("GetEventOverview() error = %v", err)
+	}
+	if len(overview.Slots) != 2 || overview.Slots[0].Name != "Morning" {
+		t.Fatalf("overview slots = %v, want Morning then Evening", overview.Slots)
+	}
+	if len(overview.Rows) != 2 || overview.Rows[0].Venue.ID != hall.ID || overview.Rows[1].Venue != nil {
+		t.Fatalf("overview rows = %v, want the hall then no venue", overview.Rows)
+	}
+	if cells := overview.Rows[0].Cells; len(cells[0]) != 1 || cells[0][0].Game.ID != dragons.ID || len(cells[1]) != 1 {
+		t.Errorf("hall cells = %v, want Dragons in the morning and Vampires in the evening", cells)
+	}
+	if cells := overview.Rows[1].Cells; len(cells[0]) != 1 || cells[0][0].Game.ID != goblins.ID || len(cells[1]) != 0 {
+		t.Errorf("no-venue cells = %v, want Goblins in the morning", cells)
+	}
+	// Morning: the GM and Alice are placed; Bob's maybe doesn't count.
+	if got := overview.Totals[0]; got.Tables != 2 || got.Taken != 2 || got.Seats != 6 || got.FillPercent() != 33 || got.Unplaced != 1 {
+		t.Errorf("morning totals = %+v, want 2 tables, 2 of 6 seats, 1 unplaced", got)
+	}
+	if got := overview.Totals[1]; got.Tables != 1 || got.Taken != 0 || got.Seats != 5 || got.Unplaced != 2 {
+		t.Errorf("evening totals = %+v, want 1 table, 0 of 5 seats, 2 unplaced", got)
+	}
+}
+
+func TestEventChoicesAndPublishing(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	organizer := createTestUserForRSVPs(t, db, "ranked_org@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "ranked_alice@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "ranked_bob@example.com", "password")
+	carol := createTestUserForRSVPs(t, db, "ranked_carol@example.com", "password") // Not registered
+
+	event := &models.Event{OrganizerID: organizer.ID, Name: "RankCon", StartsOn: "2030-06-01"}
+	if err := CreateEvent(db, event); err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+	RegisterForEvent(db, event.ID, alice.ID)
+	RegisterForEvent(db, event.ID, bob.ID)
+	start := time.Date(2030, 6, 1, 9, 0, 0, 0, time.UTC)
+	slot := &models.EventSlot{EventID: event.ID, Name: "Morning", StartsAt: start, EndsAt: start.Add(4 * time.Hour), Ranked: true, Seed: 7}
+	if err := AddEventSlot(db, slot); err != nil {
+		t.Fatalf("AddEventSlot() error = %v", err)
+	}
+	if got, _ := GetEventSlotByID(db, slot.ID); !got.Ranked || got.Seed != 7 || !got.TakesChoices() {
+		t.Errorf("GetEventSlotByID() = %+v, want a ranked slot with seed 7 taking choices", got)
+	}
+	if err := SetEventSlotSeed(db, slot.ID, 8); err != nil {
+		t.Fatalf("SetEventSlotSeed() error = %v", err)
+	}
+	dragons, _ := CreateGame(db, &models.Game{GMID: organizer.ID, Title: "Dragons", GameDateTime: start, Location: "RankCon", MaxPlayers: 1, EventSlotID: slot.ID})
+	goblins, _ := CreateGame(db, &models.Game{GMID: organizer.ID, Title: "Goblins", GameDateTime: start, Location: "RankCon", MaxPlayers: 3, EventSlotID: slot.ID})
+
+	for _, c := range []struct {
+		user  *models.User
+		games []int64
+	}{
+		{alice, []int64{goblins.ID}}, // Replaced below
+		{alice, []int64{dragons.ID, goblins.ID}},
+		{bob, []int64{dragons.ID}},
+		{carol, []int64{goblins.ID}},
+	} {
+		if err := SetEventChoices(db, slot.ID, c.user.ID, c.games); err != nil {
+			t.Fatalf("SetEventChoices() error = %v", err)
+		}
+	}
+	choices, err := GetEventChoices(db, slot.ID)
+	if err != nil {
+		t.Fatalf("GetEventChoices() error = %v", err)
+	}
+	if len(choices) != 2 || choices[0].UserID != alice.ID || len(choices[0].GameIDs) != 2 || choices[0].GameIDs[0] != dragons.ID || choices[1].UserEmail != bob.Email {
+		t.Errorf("GetEventChoices() = %+v, want Alice's two choices then Bob's, without unregistered Carol", choices)
+	}
+	if mine, _ := GetUserEventChoices(db, event.ID, alice.ID); len(mine[slot.ID]) != 2 || mine[slot.ID][1] != goblins.ID {
+		t.Errorf("GetUserEventChoices() = %v, want Dragons then Goblins", mine)
+	}
+
+	SetEventAssignmentPin(db, slot.ID, alice.ID, goblins.ID)
+	SetEventAssignmentPin(db, slot.ID, bob.ID, 0)
+	if pins, _ := GetEventAssignmentPins(db, slot.ID); len(pins) != 2 || pins[alice.ID] != goblins.ID || pins[bob.ID] != 0 {
+		t.Errorf("GetEventAssignmentPins() = %v, want Alice at Goblins and Bob at no table", pins)
+	}
+	ClearEventAssignmentPin(db, slot.ID, bob.ID)
+	if pins, _ := GetEventAssignmentPins(db, slot.ID); len(pins) != 1 {
+		t.Errorf("GetEventAssignmentPins() after clearing = %v, want only Alice", pins)
+	}
+
+	// Bob had said maybe to Dragons; publishing seats him there.
+	CreateOrUpdateRSVP(db, &models.RSVP{UserID: bob.ID, GameID: dragons.ID, Status: models.RSVPStatusMaybe})
+	seats := map[int64]int64{alice.ID: goblins.ID, bob.ID: dragons.ID}
+	if err := PublishEventAssignments(db, slot.ID, organizer.ID, seats); err != nil {
+		t.Fatalf("PublishEventAssignments() error = %v", err)
+	}
+	if err := PublishEventAssignments(db, slot.ID, organizer.ID, seats); err != ErrAssignmentsPublished {
+		t.Errorf("publishing again: error = %v, want ErrAssignmentsPublished", err)
+	}
+	if got, _ := GetEventSlotByID(db, slot.ID); got.PublishedAt.IsZero() || got.TakesChoices() {
+		t.Errorf("slot after publishing = %+v, want it published", got)
+	}
+	for userID, gameID := range seats {
+		rsvp, err := GetRSVPByUserForGame(db, userID, gameID)
+		if err != nil || rsvp.Status != models.RSVPStatusAttending {
+			t.Errorf("RSVP of user %d for game %d = %+v, %v; want attending", userID, gameID, rsvp, err)
+		}
+	}
+	events, err := GetRSVPEventsForGame(db, dragons.ID)
+	if err != nil || len(events) != 2 || events[0].ActorID != organizer.ID || events[0].OldStatus != models.RSVPStatusMaybe {
+		t.Errorf("Dragons RSVP history = %+v, %v; want the organizer seating Bob after his maybe", events, err)
+	}
+}
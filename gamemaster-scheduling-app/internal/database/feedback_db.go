@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// ErrFeedbackSubmitted is returned by SubmitSessionFeedback when the attendee
+// already gave feedback on the game.
+var ErrFeedbackSubmitted = errors.New("feedback already submitted")
+
+// SubmitSessionFeedback saves an attendee's feedback on a game, counting it for each
+// of f.GMIDs, and sets its ID. The schema allows one response per attendee per game.
+func SubmitSessionFeedback(db *sql.DB, f *models.SessionFeedback) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO session_feedback (game_id, user_id, fun, pacing, inclusivity, comment)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(game_id, user_id) DO NOTHING
+	`, f.GameID, f.UserID, f.Fun, f.Pacing, f.Inclusivity, nullIfEmpty(f.Comment))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrFeedbackSubmitted
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, gmID := range f.GMIDs {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO session_feedback_gms (feedback_id, gm_id) VALUES (?, ?)", id, gmID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	f.ID = id
+	return nil
+}
+
+// HasSubmittedFeedback reports whether a user gave feedback on a game.
+func HasSubmittedFeedback(db *sql.DB, gameID, userID int64) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM session_feedback WHERE game_id = ? AND user_id = ?)", gameID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetFeedbackForGame retrieves the feedback on a game, oldest first, for its GMs.
+func GetFeedbackForGame(db *sql.DB, gameID int64) ([]*models.SessionFeedback, error) {
+	rows, err := db.Query(`
+		SELECT f.id, f.game_id, f.user_id, COALESCE(u.username, u.email), f.fun, f.pacing, f.inclusivity, COALESCE(f.comment, ''), f.created_at
+		FROM session_feedback f JOIN users u ON u.id = f.user_id
+		WHERE f.game_id = ?
+		ORDER BY f.created_at, f.id
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feedback []*models.SessionFeedback
+	for rows.Next() {
+		f := &models.SessionFeedback{}
+		if err := rows.Scan(&f.ID, &f.GameID, &f.UserID, &f.UserName, &f.Fun, &f.Pacing, &f.Inclusivity, &f.Comment, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		feedback = append(feedback, f)
+	}
+	return feedback, rows.Err()
+}
+
+// GetGMRatings averages the feedback on every game a user ran as GM or co-GM.
+func GetGMRatings(db *sql.DB, gmID int64) (*models.GMRatings, error) {
+	r := &models.GMRatings{}
+	var fun, pacing, inclusivity sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT COUNT(*), AVG(f.fun), AVG(f.pacing), AVG(f.inclusivity)
+		FROM session_feedback f JOIN session_feedback_gms g ON g.feedback_id = f.id
+		WHERE g.gm_id = ?
+	`, gmID).
+		Scan(&r.Responses, &fun, &pacing, &inclusivity)
+	if err != nil {
+		return nil, err
+	}
+	r.Fun, r.Pacing, r.Inclusivity = fun.Float64, pacing.Float64, inclusivity.Float64
+	return r, nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestSessionFeedbackAndGMRatings(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "feedback_gm@example.com", "password")
+	ann := createTestUserForRSVPs(t, db, "feedback_ann@example.com", "password")
+	session, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Feedback Night", GameDateTime: time.Now().Add(-time.Hour), Location: "Online"})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+
+	f := &models.SessionFeedback{GameID: session.ID, UserID: ann.ID, GMIDs: []int64{gm.ID}, Fun: 5, Pacing: 3, Inclusivity: 4, Comment: "Great villain"}
+	if err := SubmitSessionFeedback(db, f); err != nil || f.ID == 0 {
+		t.Fatalf("SubmitSessionFeedback() = %v, ID %d", err, f.ID)
+	}
+	again := &models.SessionFeedback{GameID: session.ID, UserID: ann.ID, GMIDs: []int64{gm.ID}, Fun: 1, Pacing: 1, Inclusivity: 1}
+	if err := SubmitSessionFeedback(db, again); err != ErrFeedbackSubmitted {
+		t.Errorf("second SubmitSessionFeedback() error = %v, want ErrFeedbackSubmitted", err)
+	}
+	if submitted, err := HasSubmittedFeedback(db, session.ID, ann.ID); err != nil || !submitted {
+		t.Errorf("HasSubmittedFeedback() = %v, %v; want true", submitted, err)
+	}
+	if _, err := db.Exec("INSERT INTO session_feedback (game_id, user_id, gm_id, fun, pacing, inclusivity) VALUES (?, ?, ?, 6, 3, 3)", session.ID, gm.ID, gm.ID); err == nil {
+		t.Error("the schema accepted a rating of 6 stars")
+	}
+
+	feedback, err := GetFeedbackForGame(db, session.ID)
+	if err != nil || len(feedback) != 1 || feedback[0].Comment != "Great villain" || feedback[0].UserName == "" {
+		t.Fatalf("GetFeedbackForGame() = %v, %v", feedback, err)
+	}
+
+	for i := 1; i < models.FeedbackPublicThreshold; i++ {
+		player := createTestUserForRSVPs(t, db, fmt.Sprintf("feedback_player%d@example.com", i), "password")
+		if i == models.FeedbackPublicThreshold-1 {
+			ratings, _ := GetGMRatings(db, gm.ID)
+			if ratings.Public() {
+				t.Errorf("ratings from %d responses are public", ratings.Responses)
+			}
+		}
+		if err := SubmitSessionFeedback(db, &models.SessionFeedback{GameID: session.ID, UserID: player.ID, GMIDs: []int64{gm.ID}, Fun: 3, Pacing: 3, Inclusivity: 4}); err != nil {
+			t.Fatalf("SubmitSessionFeedback() error = %v", err)
+		}
+	}
+	ratings, err := GetGMRatings(db, gm.ID)
+	if err != nil {
+		t.Fatalf("GetGMRatings() error = %v", err)
+	}
+	n := float64(models.FeedbackPublicThreshold)
+	if ratings.Responses != models.FeedbackPublicThreshold || !ratings.Public() ||
+		ratings.Fun != (5+3*(n-1))/n || ratings.Pacing != 3 || ratings.Inclusivity != 4 {
+		t.Errorf("GetGMRatings() = %+v", ratings)
+	}
+	if none, err := GetGMRatings(db, ann.ID); err != nil || none.Responses != 0 || none.Fun != 0 {
+		t.Errorf("GetGMRatings() for a player = %+v, %v", none, err)
+	}
+
+	// Feedback counts for every GM of the session when it was given, and only them.
+	coGM := createTestUserForRSVPs(t, db, "feedback_cogm@example.com", "password")
+	late := createTestUserForRSVPs(t, db, "feedback_late@example.com", "password")
+	shared, _ := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Two GMs", GameDateTime: time.Now().Add(-time.Hour), Location: "Online"})
+	if err := SubmitSessionFeedback(db, &models.SessionFeedback{GameID: shared.ID, UserID: ann.ID, GMIDs: []int64{gm.ID, coGM.ID}, Fun: 2, Pacing: 2, Inclusivity: 2}); err != nil {
+		t.Fatalf("SubmitSessionFeedback() error = %v", err)
+	}
+	AddGameStaff(db, shared.ID, late.ID, gm.ID)
+	if r, _ := GetGMRatings(db, coGM.ID); r.Responses != 1 || r.Fun != 2 {
+		t.Errorf("GetGMRatings() for the co-GM = %+v, want the one response", r)
+	}
+	if r, _ := GetGMRatings(db, late.ID); r.Responses != 0 {
+		t.Errorf("GetGMRatings() for a co-GM added later = %+v, want no responses", r)
+	}
+	if r, _ := GetGMRatings(db, gm.ID); r.Responses != models.FeedbackPublicThreshold+1 {
+		t.Errorf("GetGMRatings() for the owner = %d responses, want %d", r.Responses, models.FeedbackPublicThreshold+1)
+	}
+}
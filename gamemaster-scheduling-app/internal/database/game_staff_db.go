@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// ErrAlreadyStaff is returned by AddGameStaff when the user already runs the game.
+var ErrAlreadyStaff = errors.New("user is already a GM of this game")
+
+// ErrNoTransfer is returned by AcceptGameTransfer when the game was not offered to the user.
+var ErrNoTransfer = errors.New("no pending transfer of this game to the user")
+
+// GetGameStaff retrieves a game's co-GMs, in the order they were added.
+func GetGameStaff(db *sql.DB, gameID int64) ([]*models.GameStaff, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.game_id, s.user_id, u.email, s.role, s.added_by, s.created_at
+		FROM game_staff s JOIN users u ON u.id = s.user_id
+		WHERE s.game_id = ?
+		ORDER BY s.created_at ASC, s.id ASC
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var staff []*models.GameStaff
+	for rows.Next() {
+		s := &models.GameStaff{}
+		if err := rows.Scan(&s.ID, &s.GameID, &s.UserID, &s.UserEmail, &s.Role, &s.AddedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		staff = append(staff, s)
+	}
+	return staff, rows.Err()
+}
+
+// LoadGameStaff sets game.Staff, for games that came from a list query.
+func LoadGameStaff(db *sql.DB, game *models.Game) error {
+	staff, err := GetGameStaff(db, game.ID)
+	if err != nil {
+		return err
+	}
+	game.Staff = staff
+	return nil
+}
+
+// AddGameStaff makes a user a co-GM of the game. It returns ErrAlreadyStaff if they
+// already own the game or are on its staff.
+func AddGameStaff(db *sql.DB, gameID, userID, addedBy int64) error {
+	res, err := db.Exec(`
+		INSERT INTO game_staff (game_id, user_id, role, added_by)
+		SELECT ?, ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM games WHERE id = ? AND gm_id = ?)
+		ON CONFLICT(game_id, user_id) DO NOTHING
+	`, gameID, userID, models.GameRoleCoGM, addedBy, gameID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAlreadyStaff
+	}
+	return nil
+}
+
+// RemoveGameStaff takes a co-GM off the game's staff. Removing someone who isn't on
+// it is not an error.
+func RemoveGameStaff(db *sql.DB, gameID, userID int64) error {
+	_, err := db.Exec("DELETE FROM game_staff WHERE game_id = ? AND user_id = ?", gameID, userID)
+	return err
+}
+
+// SetGameTransfer offers the game to another user, replacing any earlier offer, or
+// withdraws the offer when toUserID is 0.
+func SetGameTransfer(db *sql.DB, gameID, toUserID int64) error {
+	transferTo := sql.NullInt64{Int64: toUserID, Valid: toUserID != 0}
+	_, err := db.Exec("UPDATE games SET transfer_to_id = ? WHERE id = ?", transferTo, gameID)
+	return err
+}
+
+// AcceptGameTransfer makes userID the owner of a game that was offered to them. The
+// previous owner stays on as a co-GM so the handoff doesn't lock them out.
+func AcceptGameTransfer(db *sql.DB, gameID, userID int64) (previousOwnerID int64, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var transferTo sql.NullInt64
+	err = tx.QueryRow("SELECT gm_id, transfer_to_id FROM games WHERE id = ?", gameID).Scan(&previousOwnerID, &transferTo)
+	if err != nil {
+		return 0, err
+	}
+	if !transferTo.Valid || transferTo.Int64 != userID {
+		return 0, ErrNoTransfer
+	}
+
+	if _, err := tx.Exec("UPDATE games SET gm_id = ?, transfer_to_id = NULL WHERE id = ?", userID, gameID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM game_staff WHERE game_id = ? AND user_id = ?", gameID, userID); err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO game_staff (game_id, user_id, role, added_by) VALUES (?, ?, ?, ?)
+		ON CONFLICT(game_id, user_id) DO NOTHING
+	`, gameID, previousOwnerID, models.GameRoleCoGM, userID)
+	if err != nil {
+		return 0, err
+	}
+	return previousOwnerID, tx.Commit()
+}
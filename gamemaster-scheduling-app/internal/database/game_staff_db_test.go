@@ -0,0 +1,70 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestGameStaffAndTransfer(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	owner := createTestUserForRSVPs(t, db, "staffdb_owner@example.com", "password")
+	coGM := createTestUserForRSVPs(t, db, "staffdb_co@example.com", "password")
+	player := createTestUserForRSVPs(t, db, "staffdb_player@example.com", "password")
+	game := createTestGameForRSVPs(t, db, owner, "Handoff")
+
+	if err := AddGameStaff(db, game.ID, owner.ID, owner.ID); err != ErrAlreadyStaff {
+		t.Errorf("adding the owner as staff error = %v, want ErrAlreadyStaff", err)
+	}
+	if err := AddGameStaff(db, game.ID, coGM.ID, owner.ID); err != nil {
+		t.Fatalf("AddGameStaff() error = %v", err)
+	}
+	if err := AddGameStaff(db, game.ID, coGM.ID, owner.ID); err != ErrAlreadyStaff {
+		t.Errorf("adding a co-GM twice error = %v, want ErrAlreadyStaff", err)
+	}
+	game, _ = GetGameByID(db, game.ID)
+	if len(game.Staff) != 1 || game.Staff[0].UserEmail != coGM.Email || game.RoleOf(coGM.ID) != models.GameRoleCoGM || game.IsGM(player.ID) {
+		t.Errorf("staff = %+v", game.Staff)
+	}
+	if ok, _ := IsGameParticipant(db, game.ID, coGM.ID); !ok {
+		t.Errorf("co-GM is not a participant")
+	}
+
+	// Co-GMs don't take seats.
+	if _, err := db.Exec("UPDATE games SET max_players = 1 WHERE id = ?", game.ID); err != nil {
+		t.Fatalf("setting seats: %v", err)
+	}
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: coGM.ID, Status: models.RSVPStatusAttending})
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: player.ID, Status: models.RSVPStatusPending})
+	rsvp, _ := GetRSVPByUserForGame(db, player.ID, game.ID)
+	if err := ReviewRSVP(db, rsvp.ID, coGM.ID, true, ""); err != nil {
+		t.Errorf("ReviewRSVP() with a co-GM attending error = %v", err)
+	}
+
+	if _, err := AcceptGameTransfer(db, game.ID, coGM.ID); err != ErrNoTransfer {
+		t.Errorf("accepting without an offer error = %v, want ErrNoTransfer", err)
+	}
+	if err := SetGameTransfer(db, game.ID, coGM.ID); err != nil {
+		t.Fatalf("SetGameTransfer() error = %v", err)
+	}
+	if _, err := AcceptGameTransfer(db, game.ID, player.ID); err != ErrNoTransfer {
+		t.Errorf("accepting someone else's offer error = %v, want ErrNoTransfer", err)
+	}
+	previous, err := AcceptGameTransfer(db, game.ID, coGM.ID)
+	if err != nil || previous != owner.ID {
+		t.Fatalf("AcceptGameTransfer() = %d, %v", previous, err)
+	}
+	game, _ = GetGameByID(db, game.ID)
+	if game.GMID != coGM.ID || game.HasPendingTransfer() || !game.IsOwner(coGM.ID) || game.RoleOf(owner.ID) != models.GameRoleCoGM || len(game.Staff) != 1 {
+		t.Errorf("game after transfer = %+v, staff %+v", game, game.Staff)
+	}
+
+	if err := RemoveGameStaff(db, game.ID, owner.ID); err != nil {
+		t.Fatalf("RemoveGameStaff() error = %v", err)
+	}
+	if staff, _ := GetGameStaff(db, game.ID); len(staff) != 0 {
+		t.Errorf("staff after removal = %+v", staff)
+	}
+}
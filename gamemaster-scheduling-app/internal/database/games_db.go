@@ -2,6 +2,8 @@ package database
 
 import (
 	"database/sql"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gamemaster-scheduling/app/internal/models"
@@ -9,7 +11,7 @@ import (
 
 // CreateGame inserts a new game into the games table.
 func CreateGame(db *sql.DB, game *models.Game) (*models.Game, error) {
-	stmt, err := db.Prepare("INSERT INTO games(gm_id, title, description, game_datetime, location) VALUES(?, ?, ?, ?, ?)")
+	stmt, err := db.Prepare("INSERT INTO games(gm_id, title, description, game_datetime, location, campaign_id, min_level, max_level, requires_approval, max_players, rsvp_deadline, min_players, content_notes, system_id, experience_level, format, play_mode, event_slot_id, event_venue_id, venue_id, virtual_table_id) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return nil, err
 	}
@@ -17,7 +19,13 @@ func CreateGame(db *sql.DB, game *models.Game) (*models.Game, error) {
 
 	// Ensure GameDateTime is in a format SQLite understands, or use Unix timestamp.
 	// SQLite typically handles "YYYY-MM-DD HH:MM:SS" format well.
-	res, err := stmt.Exec(game.GMID, game.Title, game.Description, game.GameDateTime, game.Location)
+	var campaignID sql.NullInt64
+	if game.CampaignID != 0 {
+		campaignID = sql.NullInt64{Int64: game.CampaignID, Valid: true}
+	}
+	res, err := stmt.Exec(game.GMID, game.Title, game.Description, game.GameDateTime, game.Location, campaignID, nullIfZero(game.MinLevel), nullIfZero(game.MaxLevel), game.RequiresApproval, nullIfZero(game.MaxPlayers), nullIfZeroTime(game.RSVPDeadline), nullIfZero(game.MinPlayers), nullIfEmpty(game.ContentNotes),
+		nullIfZero64(game.SystemID), nullIfEmpty(game.ExperienceLevel), nullIfEmpty(game.Format), nullIfEmpty(game.PlayMode), nullIfZero64(game.EventSlotID), nullIfZero64(game.EventVenueID),
+		nullIfZero64(game.VenueID), nullIfZero64(game.VirtualTableID))
 	if err != nil {
 		return nil, err
 	}
@@ -26,26 +34,60 @@ func CreateGame(db *sql.DB, game *models.Game) (*models.Game, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := SetGameContentTags(db, id, game.ContentTags); err != nil {
+		return nil, err
+	}
 
 	// Retrieve the game to get all fields populated, including DB defaults like created_at and the ID.
 	// This ensures the returned Game object is complete.
 	return GetGameByID(db, id)
 }
 
-// GetGameByID retrieves a game by its ID.
-func GetGameByID(db *sql.DB, id int64) (*models.Game, error) {
+// gameColumns are the games columns read by scanGame.
+// The content tags come as a comma-separated list.
+const gameColumns = "id, gm_id, title, description, game_datetime, location, campaign_id, min_level, max_level, requires_approval, max_players, rsvp_deadline, min_players, quorum_status, quorum_decided_at, rsvps_reopened, transfer_to_id, cancelled_at, cancel_reason, content_notes, " +
+	"(SELECT GROUP_CONCAT(topic) FROM game_content_tags WHERE game_content_tags.game_id = games.id), " +
+	"system_id, (SELECT name FROM game_systems WHERE game_systems.id = games.system_id), experience_level, format, play_mode, " +
+	"event_slot_id, event_venue_id, (SELECT event_id FROM event_slots WHERE event_slots.id = games.event_slot_id), " +
+	"venue_id, (SELECT name FROM venues WHERE venues.id = games.venue_id), (SELECT capacity FROM venues WHERE venues.id = games.venue_id), virtual_table_id, created_at"
+
+// scanGame scans a row selected with gameColumns.
+func scanGame(row rowScanner) (*models.Game, error) {
 	game := &models.Game{}
-	row := db.QueryRow("SELECT id, gm_id, title, description, game_datetime, location, created_at FROM games WHERE id = ?", id)
-	err := row.Scan(&game.ID, &game.GMID, &game.Title, &game.Description, &game.GameDateTime, &game.Location, &game.CreatedAt)
+	var campaignID, minLevel, maxLevel, maxPlayers, minPlayers, transferToID, systemID, eventSlotID, eventVenueID, eventID, venueID, venueCapacity, virtualTableID sql.NullInt64
+	var rsvpDeadline, quorumDecidedAt, cancelledAt sql.NullTime
+	var quorumStatus, cancelReason, contentNotes, contentTags, systemName, experienceLevel, format, playMode, venueName sql.NullString
+	err := row.Scan(&game.ID, &game.GMID, &game.Title, &game.Description, &game.GameDateTime, &game.Location, &campaignID, &minLevel, &maxLevel,
+		&game.RequiresApproval, &maxPlayers, &rsvpDeadline, &minPlayers, &quorumStatus, &quorumDecidedAt, &game.RSVPsReopened, &transferToID, &cancelledAt, &cancelReason,
+		&contentNotes, &contentTags, &systemID, &systemName, &experienceLevel, &format, &playMode, &eventSlotID, &eventVenueID, &eventID,
+		&venueID, &venueName, &venueCapacity, &virtualTableID, &game.CreatedAt)
 	if err != nil {
-		return nil, err // This will include sql.ErrNoRows if not found
+		return nil, err
 	}
+	game.CampaignID = campaignID.Int64
+	game.MinLevel = int(minLevel.Int64)
+	game.MaxLevel = int(maxLevel.Int64)
+	game.MaxPlayers = int(maxPlayers.Int64)
+	game.RSVPDeadline, game.MinPlayers = rsvpDeadline.Time, int(minPlayers.Int64)
+	game.QuorumStatus, game.QuorumDecidedAt = quorumStatus.String, quorumDecidedAt.Time
+	game.TransferToID = transferToID.Int64
+	game.CancelledAt, game.CancelReason = cancelledAt.Time, cancelReason.String
+	game.ContentNotes = contentNotes.String
+	if contentTags.Valid {
+		game.ContentTags = strings.Split(contentTags.String, ",")
+		sort.Strings(game.ContentTags)
+	}
+	game.SystemID, game.SystemName = systemID.Int64, systemName.String
+	game.ExperienceLevel, game.Format, game.PlayMode = experienceLevel.String, format.String, playMode.String
+	game.EventSlotID, game.EventVenueID, game.EventID = eventSlotID.Int64, eventVenueID.Int64, eventID.Int64
+	game.VenueID, game.VenueName, game.VenueCapacity = venueID.Int64, venueName.String, int(venueCapacity.Int64)
+	game.VirtualTableID = virtualTableID.Int64
 	return game, nil
 }
 
-// GetAllGames retrieves all games, ordered by game_datetime descending.
-func GetAllGames(db *sql.DB) ([]*models.Game, error) {
-	rows, err := db.Query("SELECT id, gm_id, title, description, game_datetime, location, created_at FROM games ORDER BY game_datetime DESC")
+// queryGames runs a query selecting gameColumns and scans every row.
+func queryGames(db *sql.DB, query string, args ...interface{}) ([]*models.Game, error) {
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,8 +95,7 @@ func GetAllGames(db *sql.DB) ([]*models.Game, error) {
 
 	var games []*models.Game
 	for rows.Next() {
-		game := &models.Game{}
-		err := rows.Scan(&game.ID, &game.GMID, &game.Title, &game.Description, &game.GameDateTime, &game.Location, &game.CreatedAt)
+		game, err := scanGame(rows)
 		if err != nil {
 			return nil, err // Or collect errors and continue
 		}
@@ -67,3 +108,108 @@ func GetAllGames(db *sql.DB) ([]*models.Game, error) {
 
 	return games, nil
 }
+
+// GetGameByID retrieves a game by its ID, with its staff.
+func GetGameByID(db *sql.DB, id int64) (*models.Game, error) {
+	game, err := scanGame(db.QueryRow("SELECT "+gameColumns+" FROM games WHERE id = ?", id)) // sql.ErrNoRows if not found
+	if err != nil {
+		return nil, err
+	}
+	if err := LoadGameStaff(db, game); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// GetAllGames retrieves all games, ordered by game_datetime descending.
+func GetAllGames(db *sql.DB) ([]*models.Game, error) {
+	return queryGames(db, "SELECT "+gameColumns+" FROM games ORDER BY game_datetime DESC")
+}
+
+// FilterGames retrieves the games matching filter, ordered like GetAllGames.
+func FilterGames(db *sql.DB, filter models.GameFilter) ([]*models.Game, error) {
+	var where []string
+	var args []interface{}
+	if filter.SystemID != 0 {
+		where = append(where, "system_id = ?")
+		args = append(args, filter.SystemID)
+	}
+	if filter.ExperienceLevel != "" {
+		where = append(where, "experience_level = ?")
+		args = append(args, filter.ExperienceLevel)
+	}
+	if filter.Format != "" {
+		where = append(where, "format = ?")
+		args = append(args, filter.Format)
+	}
+	if filter.PlayMode != "" {
+		where = append(where, "play_mode = ?")
+		args = append(args, filter.PlayMode)
+	}
+	if filter.PreferredBy != 0 {
+		where = append(where, "system_id IN (SELECT system_id FROM user_game_systems WHERE user_id = ?)")
+		args = append(args, filter.PreferredBy)
+	}
+	if filter.ExcludeLinesOf != 0 {
+		where = append(where, `NOT EXISTS (
+			SELECT 1 FROM game_content_tags t
+			JOIN user_content_limits l ON l.topic = t.topic
+			WHERE t.game_id = games.id AND l.user_id = ? AND l.kind = ?
+		)`)
+		args = append(args, filter.ExcludeLinesOf, models.LimitLine)
+	}
+
+	query := "SELECT " + gameColumns + " FROM games"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	return queryGames(db, query+" ORDER BY game_datetime DESC", args...)
+}
+
+// GetGamesBetween retrieves the games starting at or after from and before to,
+// oldest first. Times are compared as instants, whatever offset they were saved with.
+func GetGamesBetween(db *sql.DB, from, to time.Time) ([]*models.Game, error) {
+	return queryGames(db, "SELECT "+gameColumns+" FROM games WHERE julianday(game_datetime) >= julianday(?) AND julianday(game_datetime) < julianday(?) ORDER BY julianday(game_datetime) ASC, id ASC",
+		from.UTC(), to.UTC())
+}
+
+// GetGamesByGM retrieves the games a user is running, ordered by game_datetime descending.
+func GetGamesByGM(db *sql.DB, gmID int64) ([]*models.Game, error) {
+	return queryGames(db, "SELECT "+gameColumns+" FROM games WHERE gm_id = ? ORDER BY game_datetime DESC", gmID)
+}
+
+// GetGamesForCampaign retrieves a campaign's sessions in play order (oldest first).
+func GetGamesForCampaign(db *sql.DB, campaignID int64) ([]*models.Game, error) {
+	return queryGames(db, "SELECT "+gameColumns+" FROM games WHERE campaign_id = ? ORDER BY game_datetime ASC, id ASC", campaignID)
+}
+
+// GetGamesAwaitingQuorum retrieves games with an RSVP deadline whose quorum hasn't
+// been decided yet, soonest deadline first. Games cancelled by an admin are skipped.
+func GetGamesAwaitingQuorum(db *sql.DB) ([]*models.Game, error) {
+	return queryGames(db, "SELECT "+gameColumns+" FROM games WHERE rsvp_deadline IS NOT NULL AND quorum_status IS NULL AND cancelled_at IS NULL ORDER BY rsvp_deadline ASC, id ASC")
+}
+
+// SearchGames finds games whose title, description or location contains query
+// (ignoring case), newest first. An empty query lists the newest games.
+func SearchGames(db *sql.DB, query string, limit int) ([]*models.Game, error) {
+	return queryGames(db, "SELECT "+gameColumns+` FROM games
+		WHERE title LIKE '%' || ? || '%' OR description LIKE '%' || ? || '%' OR location LIKE '%' || ? || '%'
+		ORDER BY created_at DESC, id DESC LIMIT ?`, query, query, query, limit)
+}
+
+// SetQuorumStatus records the quorum decision for a game. It reports false, without
+// changing anything, if the game was already decided.
+func SetQuorumStatus(db *sql.DB, gameID int64, status string, decidedAt time.Time) (bool, error) {
+	res, err := db.Exec("UPDATE games SET quorum_status = ?, quorum_decided_at = ? WHERE id = ? AND quorum_status IS NULL", status, decidedAt, gameID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// SetRSVPsReopened lets players change their RSVPs after the deadline, or locks them again.
+func SetRSVPsReopened(db *sql.DB, gameID int64, reopened bool) error {
+	_, err := db.Exec("UPDATE games SET rsvps_reopened = ? WHERE id = ?", reopened, gameID)
+	return err
+}
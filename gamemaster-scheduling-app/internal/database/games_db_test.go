@@ -142,3 +142,54 @@ func TestGetAllGames(t *testing.T) {
 		}
 	}
 }
+
+func TestGetGamesBetween(t *testing.T) {
+	db, teardown := setupTestDBForGames(t)
+	defer teardown()
+
+	gm := createTestUserForGames(t, db, "calendar_gm@example.com", "gmpass")
+	coGM := createTestUserForGames(t, db, "calendar_cogm@example.com", "password")
+	player := createTestUserForGames(t, db, "calendar_player@example.com", "password")
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	var games []*models.Game
+	for _, at := range []time.Time{
+		time.Date(2030, 3, 10, 18, 0, 0, 0, time.UTC),
+		time.Date(2030, 3, 11, 2, 0, 0, 0, tokyo), // 17:00 UTC on the 10th, saved with its offset
+		time.Date(2030, 3, 11, 0, 0, 0, 0, time.UTC),
+		time.Date(2030, 3, 9, 23, 59, 0, 0, time.UTC),
+	} {
+		game, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Game", GameDateTime: at, Location: "Online"})
+		if err != nil {
+			t.Fatalf("CreateGame() error = %v", err)
+		}
+		games = append(games, game)
+	}
+
+	from, to := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2030, 3, 11, 0, 0, 0, 0, time.UTC)
+	between, err := GetGamesBetween(db, from, to)
+	if err != nil {
+		t.Fatalf("GetGamesBetween() error = %v", err)
+	}
+	if len(between) != 2 || between[0].ID != games[1].ID || between[1].ID != games[0].ID {
+		t.Errorf("GetGamesBetween() = %d games, want the two on the 10th, earliest first", len(between))
+	}
+
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: games[0].ID, UserID: player.ID, Status: models.RSVPStatusMaybe})
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: games[2].ID, UserID: player.ID, Status: models.RSVPStatusAttending})
+	statuses, err := GetRSVPStatusesBetween(db, player.ID, from, to)
+	if err != nil {
+		t.Fatalf("GetRSVPStatusesBetween() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[games[0].ID] != models.RSVPStatusMaybe {
+		t.Errorf("GetRSVPStatusesBetween() = %v, want only the maybe on the 10th", statuses)
+	}
+
+	if err := AddGameStaff(db, games[1].ID, coGM.ID, gm.ID); err != nil {
+		t.Fatalf("AddGameStaff() error = %v", err)
+	}
+	// Games from list queries come without staff until it is loaded.
+	if err := LoadGameStaff(db, games[1]); err != nil || !games[1].IsGM(coGM.ID) || games[0].IsGM(coGM.ID) {
+		t.Errorf("LoadGameStaff() error = %v; IsGM = %v, %v; want only game %d", err, games[0].IsGM(coGM.ID), games[1].IsGM(coGM.ID), games[1].ID)
+	}
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// ErrNoHostRotation is returned by AssignNextHost when nobody is in the
+// campaign's hosting rotation.
+var ErrNoHostRotation = errors.New("campaign has no hosting rotation")
+
+// ErrNoSessionToHost is returned by AssignNextHost when every upcoming session
+// already has a host.
+var ErrNoSessionToHost = errors.New("no upcoming session without a host")
+
+// ErrInvalidHostSwap is returned by RequestHostSwap unless the requester hosts
+// the first session and someone else hosts the second, both upcoming sessions
+// of the campaign.
+var ErrInvalidHostSwap = errors.New("invalid host swap")
+
+// ErrHostSwapOutdated is returned by RespondToHostSwap when accepting a swap
+// whose sessions have changed hosts since it was asked for. The swap is
+// declined.
+var ErrHostSwapOutdated = errors.New("host swap is outdated")
+
+// upcomingSessionFilter keeps a campaign's sessions that haven't started and
+// weren't cancelled. It takes the campaign ID and the current time.
+const upcomingSessionFilter = "g.campaign_id = ? AND julianday(g.game_datetime) >= julianday(?) AND g.cancelled_at IS NULL AND COALESCE(g.quorum_status, '') <> '" + models.QuorumCancelled + "'"
+
+// GetHostRotation retrieves the players in a campaign's hosting rotation, in
+// the order they take turns.
+func GetHostRotation(db *sql.DB, campaignID int64) ([]*models.User, error) {
+	return queryUsers(db, "SELECT "+userColumns+` FROM users
+		JOIN campaign_host_rotation hr ON hr.user_id = users.id
+		WHERE hr.campaign_id = ?
+		ORDER BY hr.position`, campaignID)
+}
+
+// AddToHostRotation adds a player at the end of a campaign's hosting rotation.
+// Adding someone already in it does nothing.
+func AddToHostRotation(db *sql.DB, campaignID, userID int64) error {
+	_, err := db.Exec(`
+		INSERT INTO campaign_host_rotation (campaign_id, user_id, position)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM campaign_host_rotation WHERE campaign_id = ?))
+		ON CONFLICT(campaign_id, user_id) DO NOTHING
+	`, campaignID, userID, campaignID)
+	return err
+}
+
+// RemoveFromHostRotation takes a player out of a campaign's hosting rotation.
+// Sessions they were already assigned keep them as host.
+func RemoveFromHostRotation(db *sql.DB, campaignID, userID int64) error {
+	_, err := db.Exec("DELETE FROM campaign_host_rotation WHERE campaign_id = ? AND user_id = ?", campaignID, userID)
+	return err
+}
+
+// GetUpcomingSessionHosts retrieves a campaign's upcoming sessions, soonest
+// first, with who hosts each.
+func GetUpcomingSessionHosts(db *sql.DB, campaignID int64, now time.Time) ([]*models.SessionHost, error) {
+	rows, err := db.Query(`
+		SELECT g.id, g.title, g.game_datetime, COALESCE(sh.user_id, 0), COALESCE(u.username, u.email, '')
+		FROM games g
+		LEFT JOIN session_hosts sh ON sh.game_id = g.id
+		LEFT JOIN users u ON u.id = sh.user_id
+		WHERE `+upcomingSessionFilter+`
+		ORDER BY julianday(g.game_datetime), g.id
+	`, campaignID, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.SessionHost
+	for rows.Next() {
+		s := &models.SessionHost{}
+		if err := rows.Scan(&s.GameID, &s.GameTitle, &s.GameDateTime, &s.UserID, &s.UserName); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// GetSessionHost retrieves who hosts a session, or sql.ErrNoRows if nobody does.
+func GetSessionHost(db *sql.DB, gameID int64) (*models.User, error) {
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = (SELECT user_id FROM session_hosts WHERE game_id = ?)", gameID))
+}
+
+// AssignNextHost gives the campaign's soonest upcoming session without a host
+// to the next player in the rotation: the one after whoever hosts the latest
+// session that has a host, or the first player if that host has left the
+// rotation or nobody has hosted yet.
+func AssignNextHost(db *sql.DB, campaignID int64, now time.Time) (*models.SessionHost, error) {
+	rotation, err := GetHostRotation(db, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rotation) == 0 {
+		return nil, ErrNoHostRotation
+	}
+
+	session := &models.SessionHost{}
+	err = db.QueryRow(`
+		SELECT g.id, g.title, g.game_datetime FROM games g
+		WHERE `+upcomingSessionFilter+` AND g.id NOT IN (SELECT game_id FROM session_hosts)
+		ORDER BY julianday(g.game_datetime), g.id LIMIT 1
+	`, campaignID, now.UTC()).Scan(&session.GameID, &session.GameTitle, &session.GameDateTime)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoSessionToHost
+	} else if err != nil {
+		return nil, err
+	}
+
+	var lastHostID int64
+	err = db.QueryRow(`
+		SELECT sh.user_id FROM session_hosts sh JOIN games g ON g.id = sh.game_id
+		WHERE g.campaign_id = ?
+		ORDER BY julianday(g.game_datetime) DESC, g.id DESC LIMIT 1
+	`, campaignID).Scan(&lastHostID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	next := rotation[0]
+	for i, u := range rotation {
+		if u.ID == lastHostID {
+			next = rotation[(i+1)%len(rotation)]
+		}
+	}
+
+	if _, err := db.Exec("INSERT INTO session_hosts (game_id, user_id) VALUES (?, ?)", session.GameID, next.ID); err != nil {
+		return nil, err
+	}
+	session.UserID, session.UserName = next.ID, next.DisplayName()
+	return session, nil
+}
+
+// hostSwapSelect selects the columns read by scanHostSwap.
+const hostSwapSelect = `SELECT s.id, s.campaign_id, s.from_game_id, fg.title, fg.game_datetime, s.to_game_id, tg.title, tg.game_datetime,
+		s.requester_id, COALESCE(ru.username, ru.email), s.responder_id, COALESCE(pu.username, pu.email), s.status, s.created_at
+	FROM host_swaps s
+	JOIN games fg ON fg.id = s.from_game_id
+	JOIN games tg ON tg.id = s.to_game_id
+	JOIN users ru ON ru.id = s.requester_id
+	JOIN users pu ON pu.id = s.responder_id`
+
+// scanHostSwap scans a row selected with hostSwapSelect.
+func scanHostSwap(row rowScanner) (*models.HostSwap, error) {
+	s := &models.HostSwap{}
+	err := row.Scan(&s.ID, &s.CampaignID, &s.FromGameID, &s.FromTitle, &s.FromDateTime, &s.ToGameID, &s.ToTitle, &s.ToDateTime,
+		&s.RequesterID, &s.RequesterName, &s.ResponderID, &s.ResponderName, &s.Status, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RequestHostSwap asks the host of toGameID to trade sessions with the
+// requester, who must host fromGameID. Both must be upcoming sessions of the
+// campaign; ErrInvalidHostSwap otherwise.
+func RequestHostSwap(db *sql.DB, campaignID, fromGameID, toGameID, requesterID int64, now time.Time) (*models.HostSwap, error) {
+	sessions, err := GetUpcomingSessionHosts(db, campaignID, now)
+	if err != nil {
+		return nil, err
+	}
+	var from, to *models.SessionHost
+	for _, s := range sessions {
+		switch s.GameID {
+		case fromGameID:
+			from = s
+		case toGameID:
+			to = s
+		}
+	}
+	if from == nil || to == nil || from.UserID != requesterID || to.UserID == 0 || to.UserID == requesterID {
+		return nil, ErrInvalidHostSwap
+	}
+
+	res, err := db.Exec("INSERT INTO host_swaps (campaign_id, from_game_id, to_game_id, requester_id, responder_id, status) VALUES (?, ?, ?, ?, ?, ?)",
+		campaignID, fromGameID, toGameID, requesterID, to.UserID, models.HostSwapPending)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return GetHostSwapByID(db, id)
+}
+
+// GetHostSwapByID retrieves a host swap by its ID.
+func GetHostSwapByID(db *sql.DB, id int64) (*models.HostSwap, error) {
+	return scanHostSwap(db.QueryRow(hostSwapSelect+" WHERE s.id = ?", id)) // sql.ErrNoRows if not found
+}
+
+// GetPendingHostSwaps retrieves a campaign's swaps awaiting an answer, oldest first.
+func GetPendingHostSwaps(db *sql.DB, campaignID int64) ([]*models.HostSwap, error) {
+	rows, err := db.Query(hostSwapSelect+" WHERE s.campaign_id = ? AND s.status = ? ORDER BY s.created_at, s.id", campaignID, models.HostSwapPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var swaps []*models.HostSwap
+	for rows.Next() {
+		s, err := scanHostSwap(rows)
+		if err != nil {
+			return nil, err
+		}
+		swaps = append(swaps, s)
+	}
+	return swaps, rows.Err()
+}
+
+// RespondToHostSwap accepts or declines a pending swap. Accepting it trades
+// the two sessions' hosts, as long as the requester and the responder still
+// host them; otherwise the swap is declined and ErrHostSwapOutdated returned.
+func RespondToHostSwap(db *sql.DB, swap *models.HostSwap, accept bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status := models.HostSwapDeclined
+	var outdated bool
+	if accept {
+		var fromHost, toHost int64
+		if err := tx.QueryRow("SELECT user_id FROM session_hosts WHERE game_id = ?", swap.FromGameID).Scan(&fromHost); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err := tx.QueryRow("SELECT user_id FROM session_hosts WHERE game_id = ?", swap.ToGameID).Scan(&toHost); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		outdated = fromHost != swap.RequesterID || toHost != swap.ResponderID
+		if !outdated {
+			status = models.HostSwapAccepted
+			_, err := tx.Exec("UPDATE session_hosts SET user_id = CASE game_id WHEN ? THEN ? ELSE ? END, assigned_at = CURRENT_TIMESTAMP WHERE game_id IN (?, ?)",
+				swap.FromGameID, swap.ResponderID, swap.RequesterID, swap.FromGameID, swap.ToGameID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	res, err := tx.Exec("UPDATE host_swaps SET status = ?, responded_at = CURRENT_TIMESTAMP WHERE id = ? AND status = ?", status, swap.ID, models.HostSwapPending)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows // Answered already
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if outdated {
+		return ErrHostSwapOutdated
+	}
+	return nil
+}
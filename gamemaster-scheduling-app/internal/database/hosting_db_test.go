@@ -0,0 +1,108 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestHostRotationAndSwaps(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "host_gm@example.com", "password")
+	ann := createTestUserForRSVPs(t, db, "host_ann@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "host_bob@example.com", "password")
+	campaign, err := GetOrCreateCampaign(db, gm.ID, "Curse of Strahd")
+	if err != nil {
+		t.Fatalf("GetOrCreateCampaign() error = %v", err)
+	}
+	now := time.Date(2030, 5, 1, 12, 0, 0, 0, time.UTC)
+	var sessions []*models.Game
+	for i := -1; i < 4; i++ { // One past session, then four upcoming ones
+		game, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Session", GameDateTime: now.AddDate(0, 0, 7*i+1), Location: "Somewhere", CampaignID: campaign.ID})
+		if err != nil {
+			t.Fatalf("CreateGame() error = %v", err)
+		}
+		sessions = append(sessions, game)
+	}
+
+	if _, err := AssignNextHost(db, campaign.ID, now); err != ErrNoHostRotation {
+		t.Errorf("AssignNextHost() without a rotation: error = %v, want ErrNoHostRotation", err)
+	}
+	for _, u := range []*models.User{ann, bob, gm, ann} {
+		if err := AddToHostRotation(db, campaign.ID, u.ID); err != nil {
+			t.Fatalf("AddToHostRotation() error = %v", err)
+		}
+	}
+	if rotation, _ := GetHostRotation(db, campaign.ID); len(rotation) != 3 || rotation[0].ID != ann.ID || rotation[2].ID != gm.ID {
+		t.Fatalf("GetHostRotation() = %v, want ann, bob, gm", rotation)
+	}
+
+	// Hosts go round the rotation, skipping the past session.
+	for i, want := range []*models.User{ann, bob, gm, ann} {
+		session, err := AssignNextHost(db, campaign.ID, now)
+		if err != nil || session.GameID != sessions[i+1].ID || session.UserID != want.ID {
+			t.Fatalf("AssignNextHost() #%d = %+v, %v; want session %d hosted by %s", i+1, session, err, sessions[i+1].ID, want.Email)
+		}
+	}
+	if _, err := AssignNextHost(db, campaign.ID, now); err != ErrNoSessionToHost {
+		t.Errorf("AssignNextHost() with every session hosted: error = %v, want ErrNoSessionToHost", err)
+	}
+	if host, err := GetSessionHost(db, sessions[2].ID); err != nil || host.ID != bob.ID {
+		t.Errorf("GetSessionHost() = %v, %v; want bob", host, err)
+	}
+	if _, err := GetSessionHost(db, sessions[0].ID); err == nil {
+		t.Error("GetSessionHost() of the past session found a host, want none")
+	}
+
+	// Ann can only offer a session she hosts, for someone else's.
+	if _, err := RequestHostSwap(db, campaign.ID, sessions[2].ID, sessions[1].ID, ann.ID, now); err != ErrInvalidHostSwap {
+		t.Errorf("swapping bob's session: error = %v, want ErrInvalidHostSwap", err)
+	}
+	if _, err := RequestHostSwap(db, campaign.ID, sessions[1].ID, sessions[4].ID, ann.ID, now); err != ErrInvalidHostSwap {
+		t.Errorf("swapping with herself: error = %v, want ErrInvalidHostSwap", err)
+	}
+	swap, err := RequestHostSwap(db, campaign.ID, sessions[1].ID, sessions[2].ID, ann.ID, now)
+	if err != nil || swap.ResponderID != bob.ID || swap.Status != models.HostSwapPending {
+		t.Fatalf("RequestHostSwap() = %+v, %v; want a pending swap for bob", swap, err)
+	}
+	stale, err := RequestHostSwap(db, campaign.ID, sessions[1].ID, sessions[3].ID, ann.ID, now)
+	if err != nil {
+		t.Fatalf("RequestHostSwap() error = %v", err)
+	}
+	if pending, _ := GetPendingHostSwaps(db, campaign.ID); len(pending) != 2 {
+		t.Errorf("GetPendingHostSwaps() = %v, want two", pending)
+	}
+
+	if err := RespondToHostSwap(db, swap, true); err != nil {
+		t.Fatalf("RespondToHostSwap() error = %v", err)
+	}
+	sessionHosts, _ := GetUpcomingSessionHosts(db, campaign.ID, now)
+	if sessionHosts[0].UserID != bob.ID || sessionHosts[1].UserID != ann.ID {
+		t.Errorf("hosts after the swap = %+v, %+v; want bob then ann", sessionHosts[0], sessionHosts[1])
+	}
+	if err := RespondToHostSwap(db, swap, true); err == nil {
+		t.Error("answering a swap twice succeeded, want an error")
+	}
+	// Ann no longer hosts the first session, so her other swap is out of date.
+	if err := RespondToHostSwap(db, stale, true); err != ErrHostSwapOutdated {
+		t.Errorf("accepting an outdated swap: error = %v, want ErrHostSwapOutdated", err)
+	}
+	if got, _ := GetHostSwapByID(db, stale.ID); got.Status != models.HostSwapDeclined {
+		t.Errorf("outdated swap status = %q, want declined", got.Status)
+	}
+	if host, _ := GetSessionHost(db, sessions[3].ID); host.ID != gm.ID {
+		t.Errorf("outdated swap changed a host to %s", host.Email)
+	}
+
+	// Hosts leaving the rotation keep their sessions; the next host starts over.
+	if err := RemoveFromHostRotation(db, campaign.ID, ann.ID); err != nil {
+		t.Fatalf("RemoveFromHostRotation() error = %v", err)
+	}
+	extra, _ := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Finale", GameDateTime: now.AddDate(0, 2, 0), Location: "Somewhere", CampaignID: campaign.ID})
+	if session, err := AssignNextHost(db, campaign.ID, now); err != nil || session.GameID != extra.ID || session.UserID != bob.ID {
+		t.Errorf("AssignNextHost() after ann left = %+v, %v; want bob hosting the finale", session, err)
+	}
+}
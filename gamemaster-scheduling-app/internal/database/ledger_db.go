@@ -0,0 +1,241 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gamemaster-scheduling/app/internal/ledger"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// ErrUnbalancedEntry is returned by AddLedgerEntry for postings that don't add
+// up to zero, or fewer than two of them.
+var ErrUnbalancedEntry = errors.New("ledger entry does not balance")
+
+// AddLedgerEntry saves a ledger entry and its postings in one transaction, and
+// sets its ID. The postings must balance.
+func AddLedgerEntry(db *sql.DB, e *models.LedgerEntry) error {
+	if !ledger.Balanced(e.Postings) {
+		return ErrUnbalancedEntry
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO ledger_entries (campaign_id, game_id, kind, description, created_by) VALUES (?, ?, ?, ?, ?)",
+		e.CampaignID, nullIfZero64(e.GameID), e.Kind, e.Description, e.CreatedBy)
+	if err != nil {
+		return err
+	}
+	entryID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, p := range e.Postings {
+		if _, err := tx.Exec("INSERT INTO ledger_postings (entry_id, user_id, amount_cents) VALUES (?, ?, ?)", entryID, p.UserID, int64(p.Amount)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	e.ID = entryID
+	return nil
+}
+
+// ErrEntryNotReversible is returned by ReverseLedgerEntry for an entry that is
+// itself a reversal, or was reversed already.
+var ErrEntryNotReversible = errors.New("ledger entry cannot be reversed")
+
+// ReverseLedgerEntry undoes a ledger entry by adding a reversal entry with its
+// postings negated, on behalf of reversedBy. The original stays in the ledger.
+// It returns sql.ErrNoRows if there is no such entry.
+func ReverseLedgerEntry(db *sql.DB, entryID, reversedBy int64) (*models.LedgerEntry, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	original := &models.LedgerEntry{ID: entryID}
+	var gameID sql.NullInt64
+	var reversed bool
+	err = tx.QueryRow(`
+		SELECT campaign_id, game_id, kind, description,
+			EXISTS (SELECT 1 FROM ledger_entries r WHERE r.reverses_entry_id = e.id)
+		FROM ledger_entries e WHERE e.id = ?
+	`, entryID).Scan(&original.CampaignID, &gameID, &original.Kind, &original.Description, &reversed)
+	if err != nil {
+		return nil, err
+	}
+	if original.Kind == models.LedgerReversal || reversed {
+		return nil, ErrEntryNotReversible
+	}
+	rows, err := tx.Query("SELECT user_id, amount_cents FROM ledger_postings WHERE entry_id = ? ORDER BY id", entryID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		p := &models.LedgerPosting{}
+		if err := rows.Scan(&p.UserID, &p.Amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		original.Postings = append(original.Postings, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reversal := &models.LedgerEntry{
+		CampaignID:      original.CampaignID,
+		GameID:          gameID.Int64,
+		Kind:            models.LedgerReversal,
+		Description:     original.Description,
+		CreatedBy:       reversedBy,
+		Postings:        ledger.ReversalPostings(original.Postings),
+		ReversesEntryID: entryID,
+	}
+	if !ledger.Balanced(reversal.Postings) {
+		return nil, ErrUnbalancedEntry
+	}
+	res, err := tx.Exec("INSERT INTO ledger_entries (campaign_id, game_id, kind, description, created_by, reverses_entry_id) VALUES (?, ?, ?, ?, ?, ?)",
+		reversal.CampaignID, gameID, reversal.Kind, reversal.Description, reversedBy, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if reversal.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+	for _, p := range reversal.Postings {
+		if _, err := tx.Exec("INSERT INTO ledger_postings (entry_id, user_id, amount_cents) VALUES (?, ?, ?)", reversal.ID, p.UserID, int64(p.Amount)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// GetLedgerEntry retrieves a ledger entry with its postings. It returns
+// sql.ErrNoRows if there is no such entry.
+func GetLedgerEntry(db *sql.DB, entryID int64) (*models.LedgerEntry, error) {
+	entries, err := queryLedgerEntries(db, "e.id = ?", entryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return entries[0], nil
+}
+
+// GetCampaignLedger retrieves every entry in a campaign's ledger, newest first,
+// reversals and the entries they undo included.
+func GetCampaignLedger(db *sql.DB, campaignID int64) ([]*models.LedgerEntry, error) {
+	return queryLedgerEntries(db, "e.campaign_id = ?", campaignID)
+}
+
+// GetSessionExpenses retrieves the expenses recorded for a session, newest first.
+func GetSessionExpenses(db *sql.DB, gameID int64) ([]*models.LedgerEntry, error) {
+	return queryLedgerEntries(db, "e.game_id = ?", gameID)
+}
+
+// queryLedgerEntries retrieves the entries matching where, a fixed filter on
+// the entries e with one argument, with their postings.
+func queryLedgerEntries(db *sql.DB, where string, arg int64) ([]*models.LedgerEntry, error) {
+	rows, err := db.Query(`
+		SELECT e.id, e.campaign_id, COALESCE(e.game_id, 0), COALESCE(g.title, ''), e.kind, e.description, e.created_by, COALESCE(u.username, u.email), e.created_at,
+			COALESCE(e.reverses_entry_id, 0), EXISTS (SELECT 1 FROM ledger_entries r WHERE r.reverses_entry_id = e.id)
+		FROM ledger_entries e
+		JOIN users u ON u.id = e.created_by
+		LEFT JOIN games g ON g.id = e.game_id
+		WHERE `+where+`
+		ORDER BY e.created_at DESC, e.id DESC
+	`, arg)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*models.LedgerEntry
+	byID := make(map[int64]*models.LedgerEntry)
+	for rows.Next() {
+		e := &models.LedgerEntry{}
+		if err := rows.Scan(&e.ID, &e.CampaignID, &e.GameID, &e.GameTitle, &e.Kind, &e.Description, &e.CreatedBy, &e.CreatedByName, &e.CreatedAt, &e.ReversesEntryID, &e.Reversed); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		entries = append(entries, e)
+		byID[e.ID] = e
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(entries) == 0 {
+		return entries, err
+	}
+
+	rows, err = db.Query(`
+		SELECT p.entry_id, p.user_id, COALESCE(u.username, u.email), p.amount_cents
+		FROM ledger_postings p
+		JOIN ledger_entries e ON e.id = p.entry_id
+		JOIN users u ON u.id = p.user_id
+		WHERE `+where+`
+		ORDER BY p.id
+	`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entryID int64
+		p := &models.LedgerPosting{}
+		if err := rows.Scan(&entryID, &p.UserID, &p.UserName, &p.Amount); err != nil {
+			return nil, err
+		}
+		byID[entryID].Postings = append(byID[entryID].Postings, p)
+	}
+	return entries, rows.Err()
+}
+
+// GetLedgerBalances retrieves the running balance of everyone with postings in
+// a campaign's ledger, by name.
+func GetLedgerBalances(db *sql.DB, campaignID int64) ([]*models.LedgerBalance, error) {
+	rows, err := db.Query(`
+		SELECT p.user_id, COALESCE(u.username, u.email), SUM(p.amount_cents)
+		FROM ledger_postings p
+		JOIN ledger_entries e ON e.id = p.entry_id
+		JOIN users u ON u.id = p.user_id
+		WHERE e.campaign_id = ?
+		GROUP BY p.user_id
+		ORDER BY COALESCE(u.username, u.email) COLLATE NOCASE, p.user_id
+	`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []*models.LedgerBalance
+	for rows.Next() {
+		b := &models.LedgerBalance{}
+		if err := rows.Scan(&b.UserID, &b.UserName, &b.Amount); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
+// GetSessionAttendees retrieves who can share a session's expenses: its GM and
+// co-GMs, players attending it, and players the GM marked present or late.
+// They are ordered by display name.
+func GetSessionAttendees(db *sql.DB, gameID int64) ([]*models.User, error) {
+	return queryUsers(db, "SELECT "+userColumns+` FROM users
+		WHERE id = (SELECT gm_id FROM games WHERE id = ?)
+			OR id IN (SELECT user_id FROM game_staff WHERE game_id = ?)
+			OR id IN (SELECT user_id FROM rsvps WHERE game_id = ? AND status = ?)
+			OR id IN (SELECT user_id FROM attendance WHERE game_id = ? AND status IN (?, ?))
+		ORDER BY COALESCE(username, email) COLLATE NOCASE, id`,
+		gameID, gameID, gameID, models.RSVPStatusAttending, gameID, models.AttendancePresent, models.AttendanceLate)
+}
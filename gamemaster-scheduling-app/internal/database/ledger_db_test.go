@@ -0,0 +1,120 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/ledger"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestLedgerEntriesAndBalances(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "ledger_gm@example.com", "password")
+	ann := createTestUserForRSVPs(t, db, "ledger_ann@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "ledger_bob@example.com", "password")
+	carl := createTestUserForRSVPs(t, db, "ledger_carl@example.com", "password")
+	campaign, _ := GetOrCreateCampaign(db, gm.ID, "Ledger Campaign")
+	session, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Pizza Night", GameDateTime: time.Now().Add(-time.Hour), Location: "Ann's", CampaignID: campaign.ID})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{UserID: ann.ID, GameID: session.ID, Status: models.RSVPStatusAttending}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	CreateOrUpdateRSVP(db, &models.RSVP{UserID: carl.ID, GameID: session.ID, Status: models.RSVPStatusMaybe})
+	if err := SetAttendance(db, session.ID, gm.ID, map[int64]string{bob.ID: models.AttendancePresent}); err != nil {
+		t.Fatalf("SetAttendance() error = %v", err)
+	}
+	attendees, err := GetSessionAttendees(db, session.ID)
+	if err != nil || len(attendees) != 3 || userIDs(attendees)[carl.ID] {
+		t.Fatalf("GetSessionAttendees() = %v, %v; want the GM, ann and bob", attendees, err)
+	}
+
+	unbalanced := &models.LedgerEntry{CampaignID: campaign.ID, Kind: models.LedgerExpense, Description: "Typo", CreatedBy: ann.ID,
+		Postings: ledger.ExpensePostings(ann.ID, 1000, []ledger.Share{{UserID: bob.ID, Amount: 900}})}
+	if err := AddLedgerEntry(db, unbalanced); err != ErrUnbalancedEntry {
+		t.Errorf("AddLedgerEntry() of an unbalanced entry: error = %v, want ErrUnbalancedEntry", err)
+	}
+
+	// Ann fronts 30.00 of pizza for all three; Bob pays 5.00 of drinks for himself and Ann.
+	var shares []ledger.Share
+	for i, amount := range ledger.SplitEvenly(3000, 3) {
+		shares = append(shares, ledger.Share{UserID: attendees[i].ID, Amount: amount})
+	}
+	pizza := &models.LedgerEntry{CampaignID: campaign.ID, GameID: session.ID, Kind: models.LedgerExpense, Description: "Pizza", CreatedBy: ann.ID,
+		Postings: ledger.ExpensePostings(ann.ID, 3000, shares)}
+	drinks := &models.LedgerEntry{CampaignID: campaign.ID, GameID: session.ID, Kind: models.LedgerExpense, Description: "Drinks", CreatedBy: bob.ID,
+		Postings: ledger.ExpensePostings(bob.ID, 500, []ledger.Share{{UserID: ann.ID, Amount: 250}, {UserID: bob.ID, Amount: 250}})}
+	payment := &models.LedgerEntry{CampaignID: campaign.ID, Kind: models.LedgerPayment, Description: "Paid back", CreatedBy: gm.ID,
+		Postings: ledger.PaymentPostings(gm.ID, ann.ID, 1000)}
+	for _, e := range []*models.LedgerEntry{pizza, drinks, payment} {
+		if err := AddLedgerEntry(db, e); err != nil || e.ID == 0 {
+			t.Fatalf("AddLedgerEntry(%s) = %v, ID %d", e.Description, err, e.ID)
+		}
+	}
+
+	want := map[int64]models.Cents{ann.ID: 3000 - 1000 - 250 - 1000, bob.ID: 500 - 1000 - 250, gm.ID: -1000 + 1000}
+	balances, err := GetLedgerBalances(db, campaign.ID)
+	if err != nil || len(balances) != 3 {
+		t.Fatalf("GetLedgerBalances() = %v, %v; want three balances", balances, err)
+	}
+	var sum models.Cents
+	for _, b := range balances {
+		if b.Amount != want[b.UserID] {
+			t.Errorf("balance of %s = %v, want %v", b.UserName, b.Amount, want[b.UserID])
+		}
+		sum += b.Amount
+	}
+	if sum != 0 {
+		t.Errorf("balances add up to %v, want 0", sum)
+	}
+
+	expenses, err := GetSessionExpenses(db, session.ID)
+	if err != nil || len(expenses) != 2 || expenses[0].Description != "Drinks" {
+		t.Fatalf("GetSessionExpenses() = %v, %v; want drinks then pizza", expenses, err)
+	}
+	if got := expenses[1]; got.Total() != 3000 || len(got.Owed()) != 3 || got.GameTitle != "Pizza Night" || got.CreatedByName != ann.Email {
+		t.Errorf("pizza = %+v, want 30.00 split three ways", got)
+	}
+	if all, _ := GetCampaignLedger(db, campaign.ID); len(all) != 3 || all[0].Kind != models.LedgerPayment || all[0].Total() != 1000 {
+		t.Errorf("GetCampaignLedger() = %v, want the payment first of three", all)
+	}
+
+	// Reversing the drinks puts Ann and Bob back where they were without them.
+	reversal, err := ReverseLedgerEntry(db, drinks.ID, gm.ID)
+	if err != nil || reversal.Kind != models.LedgerReversal || reversal.ReversesEntryID != drinks.ID || reversal.GameID != session.ID {
+		t.Fatalf("ReverseLedgerEntry() = %+v, %v; want a reversal of the drinks", reversal, err)
+	}
+	want[ann.ID] += 250
+	want[bob.ID] -= 250
+	balances, _ = GetLedgerBalances(db, campaign.ID)
+	for _, b := range balances {
+		if b.Amount != want[b.UserID] {
+			t.Errorf("balance of %s after the reversal = %v, want %v", b.UserName, b.Amount, want[b.UserID])
+		}
+	}
+	if got, err := GetLedgerEntry(db, drinks.ID); err != nil || !got.Reversed || got.Reversible() {
+		t.Errorf("GetLedgerEntry(drinks) = %+v, %v; want it marked reversed", got, err)
+	}
+	if _, err := ReverseLedgerEntry(db, drinks.ID, gm.ID); err != ErrEntryNotReversible {
+		t.Errorf("reversing the drinks twice: error = %v, want ErrEntryNotReversible", err)
+	}
+	if _, err := ReverseLedgerEntry(db, reversal.ID, gm.ID); err != ErrEntryNotReversible {
+		t.Errorf("reversing a reversal: error = %v, want ErrEntryNotReversible", err)
+	}
+	if _, err := ReverseLedgerEntry(db, 999999, gm.ID); err != sql.ErrNoRows {
+		t.Errorf("reversing a missing entry: error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func userIDs(users []*models.User) map[int64]bool {
+	ids := make(map[int64]bool, len(users))
+	for _, u := range users {
+		ids[u.ID] = true
+	}
+	return ids
+}
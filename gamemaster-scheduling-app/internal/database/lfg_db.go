@@ -0,0 +1,255 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// lfgSelect selects a post with its author, system name, game title and
+// availability (as "weekday:start-end" triples). Use with scanLFGPost.
+const lfgSelect = `
+	SELECT p.id, p.user_id, u.email, u.username, p.kind, p.system_id, s.name, p.play_mode, p.experience_level,
+		p.game_id, g.title, p.note, p.closed_at, p.created_at,
+		(SELECT GROUP_CONCAT(a.weekday || ':' || a.start_minute || '-' || a.end_minute)
+			FROM lfg_post_availability a WHERE a.post_id = p.id)
+	FROM lfg_posts p
+	JOIN users u ON u.id = p.user_id
+	LEFT JOIN game_systems s ON s.id = p.system_id
+	LEFT JOIN games g ON g.id = p.game_id
+`
+
+// scanLFGPost scans a row selected by lfgSelect.
+func scanLFGPost(row rowScanner) (*models.LFGPost, error) {
+	p := &models.LFGPost{}
+	var (
+		systemID, gameID                                                sql.NullInt64
+		username, systemName, playMode, level, gameTitle, note, windows sql.NullString
+		closedAt                                                        sql.NullTime
+	)
+	err := row.Scan(&p.ID, &p.UserID, &p.UserEmail, &username, &p.Kind, &systemID, &systemName, &playMode, &level,
+		&gameID, &gameTitle, &note, &closedAt, &p.CreatedAt, &windows)
+	if err != nil {
+		return nil, err
+	}
+	p.Username = username.String
+	p.SystemID, p.SystemName = systemID.Int64, systemName.String
+	p.PlayMode, p.ExperienceLevel = playMode.String, level.String
+	p.GameID, p.GameTitle = gameID.Int64, gameTitle.String
+	p.Note, p.ClosedAt = note.String, closedAt.Time
+	if windows.Valid {
+		for _, triple := range strings.Split(windows.String, ",") {
+			var w models.AvailabilityWindow
+			if _, err := fmt.Sscanf(triple, "%d:%d-%d", &w.Weekday, &w.Start, &w.End); err != nil {
+				return nil, fmt.Errorf("lfg post %d availability %q: %w", p.ID, triple, err)
+			}
+			p.Availability = append(p.Availability, w)
+		}
+		sortAvailability(p.Availability)
+	}
+	return p, nil
+}
+
+// sortAvailability orders windows Monday first, then by start time, as the
+// availability grid shows them.
+func sortAvailability(windows []models.AvailabilityWindow) {
+	day := func(w models.AvailabilityWindow) int { return (int(w.Weekday) + 6) % 7 }
+	sort.Slice(windows, func(i, j int) bool {
+		if day(windows[i]) != day(windows[j]) {
+			return day(windows[i]) < day(windows[j])
+		}
+		return windows[i].Start < windows[j].Start
+	})
+}
+
+// queryLFGPosts runs an lfgSelect query and scans every row.
+func queryLFGPosts(db *sql.DB, query string, args ...interface{}) ([]*models.LFGPost, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*models.LFGPost
+	for rows.Next() {
+		p, err := scanLFGPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// CreateLFGPost adds a post to the board, with its availability, and sets its ID.
+func CreateLFGPost(db *sql.DB, post *models.LFGPost) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO lfg_posts (user_id, kind, system_id, play_mode, experience_level, game_id, note)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, post.UserID, post.Kind, nullIfZero64(post.SystemID), nullIfEmpty(post.PlayMode), nullIfEmpty(post.ExperienceLevel),
+		nullIfZero64(post.GameID), nullIfEmpty(post.Note))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, w := range post.Availability {
+		_, err := tx.Exec(`
+			INSERT INTO lfg_post_availability (post_id, weekday, start_minute, end_minute) VALUES (?, ?, ?, ?)
+			ON CONFLICT DO NOTHING
+		`, id, int(w.Weekday), w.Start, w.End)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	post.ID = id
+	return nil
+}
+
+// GetLFGPostByID retrieves a post, open or closed. It returns sql.ErrNoRows if there is none.
+func GetLFGPostByID(db *sql.DB, id int64) (*models.LFGPost, error) {
+	return scanLFGPost(db.QueryRow(lfgSelect+" WHERE p.id = ?", id))
+}
+
+// GetOpenLFGPosts retrieves the open posts of a kind, newest first. GM posts
+// whose game was cancelled are left out.
+func GetOpenLFGPosts(db *sql.DB, kind string) ([]*models.LFGPost, error) {
+	return queryLFGPosts(db, lfgSelect+`
+		WHERE p.kind = ? AND p.closed_at IS NULL
+			AND (g.id IS NULL OR (g.cancelled_at IS NULL AND COALESCE(g.quorum_status, '') != ?))
+		ORDER BY p.created_at DESC, p.id DESC
+	`, kind, models.QuorumCancelled)
+}
+
+// GetOpenLFGPostsByUser retrieves a user's open posts of either kind, newest first.
+func GetOpenLFGPostsByUser(db *sql.DB, userID int64) ([]*models.LFGPost, error) {
+	return queryLFGPosts(db, lfgSelect+" WHERE p.user_id = ? AND p.closed_at IS NULL ORDER BY p.created_at DESC, p.id DESC", userID)
+}
+
+// CloseLFGPost takes a post off the board. It reports false, without changing
+// anything, if the post was already closed.
+func CloseLFGPost(db *sql.DB, postID int64) (bool, error) {
+	res, err := db.Exec("UPDATE lfg_posts SET closed_at = CURRENT_TIMESTAMP WHERE id = ? AND closed_at IS NULL", postID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// openingFilter keeps the games that could have openings: those that haven't
+// started and weren't cancelled. It takes the current time.
+const openingFilter = "julianday(game_datetime) > julianday(?) AND cancelled_at IS NULL AND COALESCE(quorum_status, '') <> '" + models.QuorumCancelled + "'"
+
+// GetGameOpenings retrieves the games starting after now that still have seats
+// for more players, soonest first. Cancelled games are left out. Seats taken
+// count attending players and their guests, not the game's GMs; everyone with
+// an RSVP other than "not attending" is marked involved, as are the GMs.
+func GetGameOpenings(db *sql.DB, now time.Time) ([]*models.GameOpening, error) {
+	now = now.UTC()
+	games, err := queryGames(db, "SELECT "+gameColumns+" FROM games WHERE "+openingFilter+" ORDER BY julianday(game_datetime), id", now)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*models.GameOpening)
+	var openings []*models.GameOpening
+	for _, g := range games {
+		o := &models.GameOpening{Game: g, Involved: map[int64]bool{g.GMID: true}}
+		byID[g.ID] = o
+		openings = append(openings, o)
+	}
+	if len(openings) == 0 {
+		return nil, nil
+	}
+
+	// A game's GMs take no seats of their own, even if they RSVP'd.
+	gms := make(map[[2]int64]bool)
+	for _, o := range openings {
+		gms[[2]int64{o.Game.ID, o.Game.GMID}] = true
+	}
+	rows, err := db.Query("SELECT game_id, user_id FROM game_staff WHERE game_id IN (SELECT id FROM games WHERE "+openingFilter+")", now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var gameID, userID int64
+		if err := rows.Scan(&gameID, &userID); err != nil {
+			return nil, err
+		}
+		if o := byID[gameID]; o != nil {
+			o.Involved[userID] = true
+			gms[[2]int64{gameID, userID}] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rsvps, err := db.Query("SELECT game_id, user_id, status, guests FROM rsvps WHERE status != ? AND game_id IN (SELECT id FROM games WHERE "+openingFilter+")",
+		models.RSVPStatusNotAttending, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rsvps.Close()
+	for rsvps.Next() {
+		var gameID, userID int64
+		var status string
+		var guests int
+		if err := rsvps.Scan(&gameID, &userID, &status, &guests); err != nil {
+			return nil, err
+		}
+		o := byID[gameID]
+		if o == nil {
+			continue
+		}
+		o.Involved[userID] = true
+		if status == models.RSVPStatusAttending && !gms[[2]int64{gameID, userID}] {
+			o.SeatsTaken += 1 + guests
+		}
+	}
+	if err := rsvps.Err(); err != nil {
+		return nil, err
+	}
+
+	recruiting, err := db.Query("SELECT game_id FROM lfg_posts WHERE kind = ? AND closed_at IS NULL AND game_id IS NOT NULL", models.LFGKindGM)
+	if err != nil {
+		return nil, err
+	}
+	defer recruiting.Close()
+	for recruiting.Next() {
+		var gameID int64
+		if err := recruiting.Scan(&gameID); err != nil {
+			return nil, err
+		}
+		if o := byID[gameID]; o != nil {
+			o.Recruiting = true
+		}
+	}
+	if err := recruiting.Err(); err != nil {
+		return nil, err
+	}
+
+	open := openings[:0]
+	for _, o := range openings {
+		if o.OpenSeats() != 0 {
+			open = append(open, o)
+		}
+	}
+	return open, nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestLFGPostsAndOpenings(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "lfg_gm@example.com", "password")
+	coGM := createTestUserForRSVPs(t, db, "lfg_cogm@example.com", "password")
+	player := createTestUserForRSVPs(t, db, "lfg_player@example.com", "password")
+	seeker := createTestUserForRSVPs(t, db, "lfg_seeker@example.com", "password")
+
+	systems, _ := GetGameSystems(db)
+	post := &models.LFGPost{
+		UserID: seeker.ID, Kind: models.LFGKindPlayer, SystemID: systems[0].ID, PlayMode: models.PlayModeOnline,
+		Availability: []models.AvailabilityWindow{
+			{Weekday: time.Sunday, Start: 12 * 60, End: 17 * 60},
+			{Weekday: time.Friday, Start: 17 * 60, End: 21 * 60},
+		},
+		Note: "Looking for a weekly group",
+	}
+	if err := CreateLFGPost(db, post); err != nil {
+		t.Fatalf("CreateLFGPost() error = %v", err)
+	}
+	got, err := GetLFGPostByID(db, post.ID)
+	if err != nil {
+		t.Fatalf("GetLFGPostByID() error = %v", err)
+	}
+	if got.SystemName != systems[0].Name || got.PlayMode != models.PlayModeOnline || got.ExperienceLevel != "" || got.Note != post.Note {
+		t.Errorf("GetLFGPostByID() = %+v, want the post back", got)
+	}
+	if len(got.Availability) != 2 || got.Availability[0].Weekday != time.Friday || got.Availability[1].Label() != "Sunday afternoon" {
+		t.Errorf("availability = %v, want Friday evening then Sunday afternoon", got.Availability)
+	}
+
+	newGame := func(title string, when time.Time, maxPlayers int) *models.Game {
+		t.Helper()
+		g, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: title, GameDateTime: when, Location: "Online", MaxPlayers: maxPlayers})
+		if err != nil {
+			t.Fatalf("CreateGame(%s) error = %v", title, err)
+		}
+		return g
+	}
+	now := time.Now()
+	past := newGame("Last week", now.Add(-7*24*time.Hour), 0)
+	open := newGame("Two seats", now.Add(48*time.Hour), 2)
+	full := newGame("One seat", now.Add(24*time.Hour), 1)
+	cancelled := newGame("Cancelled", now.Add(24*time.Hour), 3)
+	noQuorum := newGame("No quorum", now.Add(24*time.Hour), 3)
+	if _, err := db.Exec("UPDATE games SET cancelled_at = CURRENT_TIMESTAMP WHERE id = ?", cancelled.ID); err != nil {
+		t.Fatalf("cancelling a game: %v", err)
+	}
+	if _, err := db.Exec("UPDATE games SET quorum_status = ? WHERE id = ?", models.QuorumCancelled, noQuorum.ID); err != nil {
+		t.Fatalf("cancelling a game for quorum: %v", err)
+	}
+	if err := AddGameStaff(db, open.ID, coGM.ID, gm.ID); err != nil {
+		t.Fatalf("AddGameStaff() error = %v", err)
+	}
+	for _, r := range []*models.RSVP{
+		{UserID: coGM.ID, GameID: open.ID, Status: models.RSVPStatusAttending},
+		{UserID: player.ID, GameID: open.ID, Status: models.RSVPStatusMaybe},
+		{UserID: player.ID, GameID: full.ID, Status: models.RSVPStatusAttending},
+		{UserID: seeker.ID, GameID: past.ID, Status: models.RSVPStatusAttending},
+	} {
+		if err := CreateOrUpdateRSVP(db, r); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+	gmPost := &models.LFGPost{UserID: gm.ID, Kind: models.LFGKindGM, GameID: open.ID}
+	if err := CreateLFGPost(db, gmPost); err != nil {
+		t.Fatalf("CreateLFGPost(gm) error = %v", err)
+	}
+
+	// Only the upcoming, uncancelled game with seats is open; the co-GM takes no seat and the
+	// "maybe" player is involved without taking one either.
+	openings, err := GetGameOpenings(db, now)
+	if err != nil {
+		t.Fatalf("GetGameOpenings() error = %v", err)
+	}
+	if len(openings) != 1 || openings[0].Game.ID != open.ID {
+		t.Fatalf("GetGameOpenings() = %v, want only %q", openings, open.Title)
+	}
+	o := openings[0]
+	if o.SeatsTaken != 0 || o.OpenSeats() != 2 || !o.Recruiting {
+		t.Errorf("opening = %+v, want 2 open seats and recruiting", o)
+	}
+	if !o.Involved[gm.ID] || !o.Involved[coGM.ID] || !o.Involved[player.ID] || o.Involved[seeker.ID] {
+		t.Errorf("involved = %v, want the GMs and the player", o.Involved)
+	}
+
+	// Closed posts leave the board.
+	if posts, _ := GetOpenLFGPosts(db, models.LFGKindPlayer); len(posts) != 1 || posts[0].ID != post.ID {
+		t.Errorf("GetOpenLFGPosts(player) = %v, want the seeker's post", posts)
+	}
+	if ok, err := CloseLFGPost(db, post.ID); !ok || err != nil {
+		t.Errorf("CloseLFGPost() = %v, %v; want true", ok, err)
+	}
+	if ok, _ := CloseLFGPost(db, post.ID); ok {
+		t.Errorf("closing twice reported true")
+	}
+	if posts, _ := GetOpenLFGPostsByUser(db, seeker.ID); len(posts) != 0 {
+		t.Errorf("GetOpenLFGPostsByUser() after closing = %v, want none", posts)
+	}
+	if posts, _ := GetOpenLFGPosts(db, models.LFGKindGM); len(posts) != 1 || posts[0].GameTitle != open.Title {
+		t.Errorf("GetOpenLFGPosts(gm) = %v, want the GM's post for %q", posts, open.Title)
+	}
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// CreateNotification stores a new, unread notification for n.UserID.
+func CreateNotification(db *sql.DB, n *models.Notification) (*models.Notification, error) {
+	res, err := db.Exec("INSERT INTO notifications(user_id, kind, message, link) VALUES(?, ?, ?, ?)",
+		n.UserID, n.Kind, n.Message, n.Link)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return GetNotificationByID(db, id)
+}
+
+// GetNotificationByID retrieves a single notification.
+func GetNotificationByID(db *sql.DB, id int64) (*models.Notification, error) {
+	return scanNotification(db.QueryRow(`
+		SELECT id, user_id, kind, message, link, read_at, created_at
+		FROM notifications WHERE id = ?
+	`, id))
+}
+
+// GetNotificationsForUser retrieves a user's most recent notifications, newest first.
+func GetNotificationsForUser(db *sql.DB, userID int64, limit int) ([]*models.Notification, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, kind, message, link, read_at, created_at
+		FROM notifications
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// CountUnreadNotifications returns how many of a user's notifications are unread.
+func CountUnreadNotifications(db *sql.DB, userID int64) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL", userID).Scan(&n)
+	return n, err
+}
+
+// MarkNotificationsRead marks all of a user's notifications as read.
+func MarkNotificationsRead(db *sql.DB, userID int64) error {
+	_, err := db.Exec("UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL", userID)
+	return err
+}
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	n := &models.Notification{}
+	var (
+		link   sql.NullString
+		readAt sql.NullTime
+	)
+	if err := row.Scan(&n.ID, &n.UserID, &n.Kind, &n.Message, &link, &readAt, &n.CreatedAt); err != nil {
+		return nil, err
+	}
+	n.Link = link.String
+	n.ReadAt = readAt.Time
+	return n, nil
+}
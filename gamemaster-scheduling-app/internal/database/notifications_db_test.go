@@ -0,0 +1,50 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestNotifications(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+
+	user, err := CreateUser(db, "notified@example.com", "password")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	other, _ := CreateUser(db, "other@example.com", "password")
+
+	for _, msg := range []string{"first", "second"} {
+		_, err := CreateNotification(db, &models.Notification{UserID: user.ID, Kind: models.NotificationKindMention, Message: msg, Link: "/games/1"})
+		if err != nil {
+			t.Fatalf("CreateNotification() error = %v", err)
+		}
+	}
+	if _, err := CreateNotification(db, &models.Notification{UserID: other.ID, Kind: models.NotificationKindMention, Message: "not yours"}); err != nil {
+		t.Fatalf("CreateNotification() error = %v", err)
+	}
+
+	list, err := GetNotificationsForUser(db, user.ID, 10)
+	if err != nil {
+		t.Fatalf("GetNotificationsForUser() error = %v", err)
+	}
+	if len(list) != 2 || list[0].Message != "second" || list[0].IsRead() {
+		t.Fatalf("GetNotificationsForUser() = %+v, want 2 unread, newest first", list)
+	}
+	if count, _ := CountUnreadNotifications(db, user.ID); count != 2 {
+		t.Errorf("CountUnreadNotifications() = %d, want 2", count)
+	}
+
+	if err := MarkNotificationsRead(db, user.ID); err != nil {
+		t.Fatalf("MarkNotificationsRead() error = %v", err)
+	}
+	if count, _ := CountUnreadNotifications(db, user.ID); count != 0 {
+		t.Errorf("CountUnreadNotifications() after marking read = %d, want 0", count)
+	}
+	if count, _ := CountUnreadNotifications(db, other.ID); count != 1 {
+		t.Errorf("other user's unread count = %d, want 1", count)
+	}
+}
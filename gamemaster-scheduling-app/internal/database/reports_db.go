@@ -0,0 +1,159 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// ErrAlreadyReported is returned by CreateReport when the user already has an open
+// report about the same thing.
+var ErrAlreadyReported = errors.New("user already has an open report about this")
+
+// reportSelect selects a report with the emails, game title and chat message the
+// moderation queue shows. Use with scanReport.
+const reportSelect = `
+	SELECT r.id, r.reporter_id, ru.email, r.target_type, r.target_id, r.game_id, r.reported_user_id,
+		r.reason, r.note, r.status, r.resolved_by, r.resolved_at, r.created_at,
+		tu.email, g.title, cm.message_content
+	FROM reports r
+	JOIN users ru ON ru.id = r.reporter_id
+	LEFT JOIN users tu ON tu.id = r.reported_user_id
+	LEFT JOIN games g ON g.id = r.game_id
+	LEFT JOIN chat_messages cm ON r.target_type = 'chat_message' AND cm.id = r.target_id
+`
+
+// scanReport scans a row selected by reportSelect.
+func scanReport(row rowScanner) (*models.Report, error) {
+	r := &models.Report{}
+	var (
+		gameID, reportedUserID, resolvedBy             sql.NullInt64
+		note, reportedEmail, gameTitle, messageContent sql.NullString
+		resolvedAt                                     sql.NullTime
+	)
+	err := row.Scan(
+		&r.ID, &r.ReporterID, &r.ReporterEmail, &r.TargetType, &r.TargetID, &gameID, &reportedUserID,
+		&r.Reason, &note, &r.Status, &resolvedBy, &resolvedAt, &r.CreatedAt,
+		&reportedEmail, &gameTitle, &messageContent,
+	)
+	if err != nil {
+		return nil, err
+	}
+	r.GameID = gameID.Int64
+	r.ReportedUserID = reportedUserID.Int64
+	r.Note = note.String
+	r.ResolvedBy = resolvedBy.Int64
+	r.ResolvedAt = resolvedAt.Time
+	r.ReportedUserEmail = reportedEmail.String
+	r.GameTitle = gameTitle.String
+	r.MessageContent = messageContent.String
+	return r, nil
+}
+
+// queryReports runs a reportSelect query and scans every row.
+func queryReports(db *sql.DB, query string, args ...interface{}) ([]*models.Report, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*models.Report
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+// CreateReport files a report and sets its ID. It returns ErrAlreadyReported if the
+// reporter already has an open report about the same target.
+func CreateReport(db *sql.DB, report *models.Report) error {
+	res, err := db.Exec(`
+		INSERT INTO reports (reporter_id, target_type, target_id, game_id, reported_user_id, reason, note)
+		SELECT ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM reports WHERE reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?
+		)
+	`, report.ReporterID, report.TargetType, report.TargetID,
+		sql.NullInt64{Int64: report.GameID, Valid: report.GameID != 0},
+		sql.NullInt64{Int64: report.ReportedUserID, Valid: report.ReportedUserID != 0},
+		report.Reason, nullIfEmpty(report.Note),
+		report.ReporterID, report.TargetType, report.TargetID, models.ReportStatusOpen)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAlreadyReported
+	}
+	report.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetReportByID retrieves a single report.
+func GetReportByID(db *sql.DB, id int64) (*models.Report, error) {
+	return scanReport(db.QueryRow(reportSelect+" WHERE r.id = ?", id))
+}
+
+// GetReports retrieves reports with the given status (all of them if status is ""),
+// oldest first so the queue is worked in order.
+func GetReports(db *sql.DB, status string, limit int) ([]*models.Report, error) {
+	return queryReports(db, reportSelect+`
+		WHERE ? = '' OR r.status = ?
+		ORDER BY r.created_at ASC, r.id ASC LIMIT ?`, status, status, limit)
+}
+
+// GetChatReportsForGame retrieves reports about chat messages in a game, for its GMs.
+// Reports about the game listing itself or about users only reach site admins.
+func GetChatReportsForGame(db *sql.DB, gameID int64, status string) ([]*models.Report, error) {
+	return queryReports(db, reportSelect+`
+		WHERE r.game_id = ? AND r.target_type = ? AND (? = '' OR r.status = ?)
+		ORDER BY r.created_at ASC, r.id ASC`, gameID, models.ReportTargetChatMessage, status, status)
+}
+
+// CountOpenReports counts the reports waiting for a site admin.
+func CountOpenReports(db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM reports WHERE status = ?", models.ReportStatusOpen).Scan(&n)
+	return n, err
+}
+
+// closeReportQuery marks an open report resolved or dismissed.
+const closeReportQuery = `
+	UPDATE reports SET status = ?, resolved_by = ?, resolved_at = CURRENT_TIMESTAMP
+	WHERE id = ? AND status = 'open'`
+
+// CloseReport resolves or dismisses an open report as a GM of its game. It returns
+// sql.ErrNoRows if the report doesn't exist or was already closed.
+func CloseReport(db *sql.DB, reportID, userID int64, status string) error {
+	res, err := db.Exec(closeReportQuery, status, userID, reportID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AdminCloseReport is CloseReport for site admins; the decision goes in the audit log.
+func AdminCloseReport(db *sql.DB, adminID, reportID int64, status string) error {
+	action := models.AuditResolveReport
+	if status == models.ReportStatusDismissed {
+		action = models.AuditDismissReport
+	}
+	return adminAction(db, adminID, action, models.AuditTargetReport, reportID, "",
+		closeReportQuery, status, adminID, reportID)
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestReports(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "reports_gm@example.com", "password")
+	troll := createTestUserForRSVPs(t, db, "reports_troll@example.com", "password")
+	reporter := createTestUserForRSVPs(t, db, "reports_reporter@example.com", "password")
+	admin := createTestUserForRSVPs(t, db, "reports_admin@example.com", "password")
+	game := createTestGameForRSVPs(t, db, gm, "Reported Table")
+	msg, err := CreateChatMessage(db, &models.ChatMessage{GameID: game.ID, UserID: troll.ID, MessageContent: "you all suck"})
+	if err != nil {
+		t.Fatalf("CreateChatMessage() error = %v", err)
+	}
+
+	chatReport := &models.Report{
+		ReporterID: reporter.ID, TargetType: models.ReportTargetChatMessage, TargetID: msg.ID,
+		GameID: game.ID, ReportedUserID: troll.ID, Reason: models.ReportReasonHarassment, Note: "Second time tonight",
+	}
+	if err := CreateReport(db, chatReport); err != nil || chatReport.ID == 0 {
+		t.Fatalf("CreateReport() error = %v, ID = %d", err, chatReport.ID)
+	}
+	again := *chatReport
+	if err := CreateReport(db, &again); err != ErrAlreadyReported {
+		t.Errorf("reporting twice error = %v, want ErrAlreadyReported", err)
+	}
+	userReport := &models.Report{ReporterID: reporter.ID, TargetType: models.ReportTargetUser, TargetID: troll.ID, ReportedUserID: troll.ID, Reason: models.ReportReasonSpam}
+	if err := CreateReport(db, userReport); err != nil {
+		t.Fatalf("CreateReport() for a user error = %v", err)
+	}
+
+	got, err := GetReportByID(db, chatReport.ID)
+	if err != nil {
+		t.Fatalf("GetReportByID() error = %v", err)
+	}
+	if !got.IsOpen() || got.ReporterEmail != reporter.Email || got.ReportedUserEmail != troll.Email ||
+		got.GameTitle != "Reported Table" || got.MessageContent != "you all suck" || got.Note != "Second time tonight" {
+		t.Errorf("GetReportByID() = %+v", got)
+	}
+
+	// GMs only see chat reports about their game; admins see everything.
+	gameReports, _ := GetChatReportsForGame(db, game.ID, models.ReportStatusOpen)
+	if len(gameReports) != 1 || gameReports[0].ID != chatReport.ID {
+		t.Errorf("GetChatReportsForGame() = %v, want the chat report", gameReports)
+	}
+	if n, _ := CountOpenReports(db); n != 2 {
+		t.Errorf("CountOpenReports() = %d, want 2", n)
+	}
+
+	if err := CloseReport(db, chatReport.ID, gm.ID, models.ReportStatusResolved); err != nil {
+		t.Fatalf("CloseReport() error = %v", err)
+	}
+	if err := CloseReport(db, chatReport.ID, gm.ID, models.ReportStatusDismissed); err != sql.ErrNoRows {
+		t.Errorf("closing twice error = %v, want sql.ErrNoRows", err)
+	}
+	if err := AdminCloseReport(db, admin.ID, userReport.ID, models.ReportStatusDismissed); err != nil {
+		t.Fatalf("AdminCloseReport() error = %v", err)
+	}
+	if open, _ := GetReports(db, models.ReportStatusOpen, 10); len(open) != 0 {
+		t.Errorf("open reports after closing = %v", open)
+	}
+	all, _ := GetReports(db, "", 10)
+	if len(all) != 2 || all[0].Status != models.ReportStatusResolved || all[0].ResolvedBy != gm.ID || all[0].ResolvedAt.IsZero() || all[1].Status != models.ReportStatusDismissed {
+		t.Errorf("all reports = %+v", all)
+	}
+	if entries, _ := GetAuditLog(db, 10); len(entries) != 1 || entries[0].Action != models.AuditDismissReport || entries[0].TargetID != userReport.ID {
+		t.Errorf("audit log = %+v, want only the admin's dismissal", entries)
+	}
+
+	// Once the old report is closed, the same user can report again.
+	if err := CreateReport(db, &again); err != nil {
+		t.Errorf("reporting again after closing error = %v", err)
+	}
+}
+
+func TestUserBlocks(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	alice := createTestUserForRSVPs(t, db, "blocks_alice@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "blocks_bob@example.com", "password")
+
+	for i := 0; i < 2; i++ {
+		if err := BlockUser(db, alice.ID, bob.ID); err != nil {
+			t.Fatalf("BlockUser() error = %v", err)
+		}
+	}
+	if blocked, _ := IsUserBlocked(db, alice.ID, bob.ID); !blocked {
+		t.Errorf("bob is not blocked by alice")
+	}
+	if blocked, _ := IsUserBlocked(db, bob.ID, alice.ID); blocked {
+		t.Errorf("blocking is not one-way")
+	}
+	if ids, _ := GetBlockedUserIDs(db, alice.ID); len(ids) != 1 || !ids[bob.ID] {
+		t.Errorf("GetBlockedUserIDs() = %v, want bob", ids)
+	}
+	if err := UnblockUser(db, alice.ID, bob.ID); err != nil {
+		t.Fatalf("UnblockUser() error = %v", err)
+	}
+	if ids, _ := GetBlockedUserIDs(db, alice.ID); len(ids) != 0 {
+		t.Errorf("GetBlockedUserIDs() after unblocking = %v", ids)
+	}
+}
@@ -2,38 +2,182 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/gamemaster-scheduling/app/internal/models"
 )
 
+// ErrRSVPNotReviewable is returned by ReviewRSVP when the RSVP is not waiting for a decision.
+var ErrRSVPNotReviewable = errors.New("rsvp is not pending approval")
+
+// ErrGameFull is returned by CreateOrUpdateRSVP and ReviewRSVP when a player and
+// their guests would exceed the game's seats.
+var ErrGameFull = errors.New("game has no seats left")
+
+// ErrSlotTaken is returned by CreateOrUpdateRSVP when a player joining an event's
+// table is already running, attending or waiting for approval at another table
+// in the same slot.
+var ErrSlotTaken = errors.New("player is already at a table in this slot")
+
 // CreateOrUpdateRSVP inserts a new RSVP or updates an existing one.
-// It uses SQLite's "ON CONFLICT" clause to handle the upsert.
+// It uses SQLite's "ON CONFLICT" clause to handle the upsert. A change of status
+// clears the GM's review of the previous one. Status changes, and new comments,
+// are appended to rsvp_events in the same transaction.
+//
+// An attending RSVP from anyone but the game's GMs needs a seat for the player and
+// each guest; it fails with ErrGameFull if the game doesn't have them. Players
+// already attending keep their seats, so only extra guests need free ones. At an
+// event's table, an attending or pending RSVP from a player fails with
+// ErrSlotTaken if they are already at another table in the same slot.
 func CreateOrUpdateRSVP(db *sql.DB, rsvp *models.RSVP) error {
-	stmt, err := db.Prepare(`
-		INSERT INTO rsvps (user_id, game_id, status, created_at, updated_at)
-		VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldStatus, oldComment sql.NullString
+	err = tx.QueryRow("SELECT status, comment FROM rsvps WHERE user_id = ? AND game_id = ?", rsvp.UserID, rsvp.GameID).Scan(&oldStatus, &oldComment)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if rsvp.Status == models.RSVPStatusAttending || rsvp.Status == models.RSVPStatusPending {
+		var maxPlayers, slotID sql.NullInt64
+		var isGM bool
+		err := tx.QueryRow(`
+			SELECT max_players, event_slot_id, gm_id = ? OR EXISTS (SELECT 1 FROM game_staff WHERE game_id = games.id AND user_id = ?)
+			FROM games WHERE id = ?
+		`, rsvp.UserID, rsvp.UserID, rsvp.GameID).Scan(&maxPlayers, &slotID, &isGM)
+		if err != nil {
+			return err
+		}
+		if slotID.Valid && !isGM {
+			var taken bool
+			err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM games WHERE "+slotSignupWhere+")",
+				slotSignupArgs(rsvp.UserID, slotID.Int64, rsvp.GameID)...).Scan(&taken)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return ErrSlotTaken
+			}
+		}
+		if rsvp.Status == models.RSVPStatusAttending && maxPlayers.Valid && !isGM {
+			taken, err := seatsTaken(tx, rsvp.GameID, rsvp.UserID)
+			if err != nil {
+				return err
+			}
+			if taken+1+int64(rsvp.Guests) > maxPlayers.Int64 {
+				return ErrGameFull
+			}
+		}
+	}
+
+	var characterID sql.NullInt64
+	if rsvp.CharacterID != 0 {
+		characterID = sql.NullInt64{Int64: rsvp.CharacterID, Valid: true}
+	}
+	_, err = tx.Exec(`
+		INSERT INTO rsvps (user_id, game_id, status, character_id, comment, guests, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		ON CONFLICT(user_id, game_id) DO UPDATE SET
 			status = excluded.status,
+			character_id = excluded.character_id,
+			comment = excluded.comment,
+			guests = excluded.guests,
+			reviewed_by = CASE WHEN status = excluded.status THEN reviewed_by END,
+			reviewed_at = CASE WHEN status = excluded.status THEN reviewed_at END,
+			review_message = CASE WHEN status = excluded.status THEN review_message END,
 			updated_at = CURRENT_TIMESTAMP
-	`)
+	`, rsvp.UserID, rsvp.GameID, rsvp.Status, characterID, nullIfEmpty(rsvp.Comment), rsvp.Guests)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(rsvp.UserID, rsvp.GameID, rsvp.Status)
+	if oldStatus.String != rsvp.Status || (rsvp.Comment != "" && rsvp.Comment != oldComment.String) {
+		var rsvpID int64
+		if err := tx.QueryRow("SELECT id FROM rsvps WHERE user_id = ? AND game_id = ?", rsvp.UserID, rsvp.GameID).Scan(&rsvpID); err != nil {
+			return err
+		}
+		if err := insertRSVPEvent(tx, rsvpID, rsvp.UserID, oldStatus.String, rsvp.Status, rsvp.Comment); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// seatsTaken counts the seats taken by a game's attending players and their guests,
+// leaving out the GMs and the given player.
+func seatsTaken(tx *sql.Tx, gameID, exceptUserID int64) (int64, error) {
+	var taken int64
+	err := tx.QueryRow(`
+		SELECT COALESCE(SUM(1 + r.guests), 0) FROM rsvps r JOIN games g ON g.id = r.game_id
+		WHERE r.game_id = ? AND r.status = ? AND r.user_id != ? AND r.user_id != g.gm_id
+			AND r.user_id NOT IN (SELECT user_id FROM game_staff WHERE game_id = r.game_id)
+	`, gameID, models.RSVPStatusAttending, exceptUserID).Scan(&taken)
+	return taken, err
+}
+
+// insertRSVPEvent appends a change to an RSVP's history. The game and player are
+// taken from the RSVP.
+func insertRSVPEvent(tx *sql.Tx, rsvpID, actorID int64, oldStatus, newStatus, comment string) error {
+	_, err := tx.Exec(`
+		INSERT INTO rsvp_events (rsvp_id, game_id, user_id, actor_id, old_status, new_status, comment)
+		SELECT id, game_id, user_id, ?, ?, ?, ? FROM rsvps WHERE id = ?
+	`, actorID, nullIfEmpty(oldStatus), newStatus, nullIfEmpty(comment), rsvpID)
 	return err
 }
 
-// GetRSVPsForGame retrieves all RSVPs for a given game, including the user's email.
+// rsvpSelect selects an RSVP with the user's email and the character they are
+// bringing, if any. Use with scanRSVP.
+const rsvpSelect = `
+	SELECT r.id, r.user_id, r.game_id, r.status, r.created_at, r.updated_at, u.email,
+		r.reviewed_by, r.reviewed_at, r.review_message, rv.email, r.comment, r.guests,
+		` + characterColumns + `
+	FROM rsvps r
+	JOIN users u ON r.user_id = u.id
+	LEFT JOIN users rv ON rv.id = r.reviewed_by
+	LEFT JOIN characters c ON c.id = r.character_id
+`
+
+// scanRSVP scans a row produced by rsvpSelect.
+func scanRSVP(row rowScanner) (*models.RSVP, error) {
+	rsvp := &models.RSVP{}
+	var (
+		charID, charUserID, level            sql.NullInt64
+		name, system, class, sheetURL, notes sql.NullString
+		charCreatedAt, reviewedAt            sql.NullTime
+		reviewedBy                           sql.NullInt64
+		reviewMessage, reviewerEmail         sql.NullString
+		comment                              sql.NullString
+	)
+	err := row.Scan(&rsvp.ID, &rsvp.UserID, &rsvp.GameID, &rsvp.Status, &rsvp.CreatedAt, &rsvp.UpdatedAt, &rsvp.UserEmail,
+		&reviewedBy, &reviewedAt, &reviewMessage, &reviewerEmail, &comment, &rsvp.Guests,
+		&charID, &charUserID, &name, &system, &class, &level, &sheetURL, &notes, &charCreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rsvp.ReviewedBy, rsvp.ReviewedAt = reviewedBy.Int64, reviewedAt.Time
+	rsvp.ReviewMessage, rsvp.ReviewerEmail = reviewMessage.String, reviewerEmail.String
+	rsvp.Comment = comment.String
+	if charID.Valid {
+		rsvp.CharacterID = charID.Int64
+		rsvp.Character = &models.Character{
+			ID: charID.Int64, UserID: charUserID.Int64, Name: name.String, System: system.String, Class: class.String,
+			Level: int(level.Int64), SheetURL: sheetURL.String, Notes: notes.String, CreatedAt: charCreatedAt.Time,
+		}
+	}
+	return rsvp, nil
+}
+
+// GetRSVPsForGame retrieves all RSVPs for a given game, including the user's email
+// and the character they are bringing.
 func GetRSVPsForGame(db *sql.DB, gameID int64) ([]*models.RSVP, error) {
-	rows, err := db.Query(`
-		SELECT r.id, r.user_id, r.game_id, r.status, r.created_at, r.updated_at, u.email
-		FROM rsvps r
-		JOIN users u ON r.user_id = u.id
+	rows, err := db.Query(rsvpSelect+`
 		WHERE r.game_id = ?
-		ORDER BY r.updated_at DESC
+		ORDER BY r.updated_at DESC, r.id DESC
 	`, gameID)
 	if err != nil {
 		return nil, err
@@ -42,8 +186,7 @@ func GetRSVPsForGame(db *sql.DB, gameID int64) ([]*models.RSVP, error) {
 
 	var rsvps []*models.RSVP
 	for rows.Next() {
-		rsvp := &models.RSVP{}
-		err := rows.Scan(&rsvp.ID, &rsvp.UserID, &rsvp.GameID, &rsvp.Status, &rsvp.CreatedAt, &rsvp.UpdatedAt, &rsvp.UserEmail)
+		rsvp, err := scanRSVP(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -56,22 +199,120 @@ func GetRSVPsForGame(db *sql.DB, gameID int64) ([]*models.RSVP, error) {
 	return rsvps, nil
 }
 
+// GetRSVPStatusesBetween maps the IDs of games starting at or after from and
+// before to, as in GetGamesBetween, to the user's RSVP status for them. Games
+// the user hasn't RSVP'd to are left out.
+func GetRSVPStatusesBetween(db *sql.DB, userID int64, from, to time.Time) (map[int64]string, error) {
+	rows, err := db.Query(`
+		SELECT r.game_id, r.status FROM rsvps r JOIN games g ON g.id = r.game_id
+		WHERE r.user_id = ? AND julianday(g.game_datetime) >= julianday(?) AND julianday(g.game_datetime) < julianday(?)
+	`, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := make(map[int64]string)
+	for rows.Next() {
+		var gameID int64
+		var status string
+		if err := rows.Scan(&gameID, &status); err != nil {
+			return nil, err
+		}
+		statuses[gameID] = status
+	}
+	return statuses, rows.Err()
+}
+
 // GetRSVPByUserForGame retrieves a specific user's RSVP for a specific game.
 func GetRSVPByUserForGame(db *sql.DB, userID int64, gameID int64) (*models.RSVP, error) {
-	rsvp := &models.RSVP{}
-	// We can also join with users table here if UserEmail is needed, though it's less critical for this specific function
-	// if its primary use is just to check status for the current user.
-	// For consistency and if UserEmail might be useful on the RSVP object returned, let's include it.
-	row := db.QueryRow(`
-		SELECT r.id, r.user_id, r.game_id, r.status, r.created_at, r.updated_at, u.email
-		FROM rsvps r
-		JOIN users u ON r.user_id = u.id
-		WHERE r.user_id = ? AND r.game_id = ?
-	`, userID, gameID)
-
-	err := row.Scan(&rsvp.ID, &rsvp.UserID, &rsvp.GameID, &rsvp.Status, &rsvp.CreatedAt, &rsvp.UpdatedAt, &rsvp.UserEmail)
+	rsvp, err := scanRSVP(db.QueryRow(rsvpSelect+" WHERE r.user_id = ? AND r.game_id = ?", userID, gameID))
 	if err != nil {
 		return nil, err // This will include sql.ErrNoRows if not found
 	}
 	return rsvp, nil
 }
+
+// GetRSVPByID retrieves an RSVP by its ID.
+func GetRSVPByID(db *sql.DB, id int64) (*models.RSVP, error) {
+	return scanRSVP(db.QueryRow(rsvpSelect+" WHERE r.id = ?", id))
+}
+
+// ReviewRSVP records the GM's decision on a pending RSVP: approved players become
+// attending, others declined. A declined RSVP can still be approved later. Approval
+// fails with ErrGameFull if the game's seats are all taken.
+func ReviewRSVP(db *sql.DB, rsvpID int64, reviewerID int64, approve bool, message string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var gameID, userID, guests int64
+	var maxPlayers sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT r.status, r.game_id, r.user_id, r.guests, g.max_players FROM rsvps r JOIN games g ON g.id = r.game_id WHERE r.id = ?
+	`, rsvpID).Scan(&status, &gameID, &userID, &guests, &maxPlayers)
+	if err != nil {
+		return err
+	}
+	if status != models.RSVPStatusPending && !(approve && status == models.RSVPStatusDeclined) {
+		return ErrRSVPNotReviewable
+	}
+
+	newStatus := models.RSVPStatusDeclined
+	if approve {
+		newStatus = models.RSVPStatusAttending
+		if maxPlayers.Valid {
+			taken, err := seatsTaken(tx, gameID, userID)
+			if err != nil {
+				return err
+			}
+			if taken+1+guests > maxPlayers.Int64 {
+				return ErrGameFull
+			}
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE rsvps SET status = ?, reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP, review_message = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, newStatus, reviewerID, nullIfEmpty(message), rsvpID)
+	if err != nil {
+		return err
+	}
+	if err := insertRSVPEvent(tx, rsvpID, reviewerID, status, newStatus, message); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetRSVPEventsForGame retrieves a game's RSVP history, newest first.
+func GetRSVPEventsForGame(db *sql.DB, gameID int64) ([]*models.RSVPEvent, error) {
+	rows, err := db.Query(`
+		SELECT e.id, e.rsvp_id, e.game_id, e.user_id, u.email, e.actor_id, a.email, e.old_status, e.new_status, e.comment, e.created_at
+		FROM rsvp_events e
+		JOIN users u ON u.id = e.user_id
+		JOIN users a ON a.id = e.actor_id
+		WHERE e.game_id = ?
+		ORDER BY e.created_at DESC, e.id DESC
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.RSVPEvent
+	for rows.Next() {
+		e := &models.RSVPEvent{}
+		var oldStatus, comment sql.NullString
+		err := rows.Scan(&e.ID, &e.RSVPID, &e.GameID, &e.UserID, &e.UserEmail, &e.ActorID, &e.ActorEmail, &oldStatus, &e.NewStatus, &comment, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.OldStatus, e.Comment = oldStatus.String, comment.String
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
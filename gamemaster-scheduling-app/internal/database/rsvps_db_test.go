@@ -2,7 +2,8 @@ package database
 
 import (
 	"database/sql"
-	"reflect"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -188,3 +189,234 @@ func TestGetRSVPsForGame(t *testing.T) {
 		}
 	}
 }
+
+func TestReviewRSVP(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "review_gm@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "review_alice@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "review_bob@example.com", "password")
+	game, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Vetted", GameDateTime: time.Now(), Location: "Table", RequiresApproval: true, MaxPlayers: 1})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+	if !game.RequiresApproval || game.MaxPlayers != 1 {
+		t.Fatalf("game = %+v, want approval required with 1 seat", game)
+	}
+
+	for _, u := range []*models.User{alice, bob} {
+		if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: u.ID, Status: models.RSVPStatusPending}); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+	aliceRSVP, _ := GetRSVPByUserForGame(db, alice.ID, game.ID)
+	bobRSVP, _ := GetRSVPByUserForGame(db, bob.ID, game.ID)
+
+	if err := ReviewRSVP(db, aliceRSVP.ID, gm.ID, true, "Welcome aboard"); err != nil {
+		t.Fatalf("ReviewRSVP() approve error = %v", err)
+	}
+	aliceRSVP, _ = GetRSVPByID(db, aliceRSVP.ID)
+	if aliceRSVP.Status != models.RSVPStatusAttending || aliceRSVP.ReviewedBy != gm.ID || aliceRSVP.ReviewedAt.IsZero() ||
+		aliceRSVP.ReviewMessage != "Welcome aboard" || aliceRSVP.ReviewerEmail != gm.Email {
+		t.Errorf("approved RSVP = %+v", aliceRSVP)
+	}
+	if err := ReviewRSVP(db, aliceRSVP.ID, gm.ID, false, ""); err != ErrRSVPNotReviewable {
+		t.Errorf("reviewing an attending RSVP error = %v, want ErrRSVPNotReviewable", err)
+	}
+
+	if err := ReviewRSVP(db, bobRSVP.ID, gm.ID, true, ""); err != ErrGameFull {
+		t.Errorf("approving past the seat limit error = %v, want ErrGameFull", err)
+	}
+	if err := ReviewRSVP(db, bobRSVP.ID, gm.ID, false, "Table is full"); err != nil {
+		t.Fatalf("ReviewRSVP() decline error = %v", err)
+	}
+	bobRSVP, _ = GetRSVPByID(db, bobRSVP.ID)
+	if bobRSVP.Status != models.RSVPStatusDeclined || bobRSVP.ReviewMessage != "Table is full" {
+		t.Errorf("declined RSVP = %+v", bobRSVP)
+	}
+
+	// Re-submitting the same status (e.g. changing character) keeps the review;
+	// changing status clears it.
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	if r, _ := GetRSVPByID(db, aliceRSVP.ID); !r.IsReviewed() {
+		t.Errorf("review was cleared without a status change: %+v", r)
+	}
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusMaybe}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	if r, _ := GetRSVPByID(db, aliceRSVP.ID); r.IsReviewed() || r.ReviewMessage != "" {
+		t.Errorf("review kept after a status change: %+v", r)
+	}
+
+	// With Alice's seat free, the GM can still approve Bob.
+	if err := ReviewRSVP(db, bobRSVP.ID, gm.ID, true, ""); err != nil {
+		t.Errorf("approving a declined RSVP error = %v", err)
+	}
+}
+
+func TestRSVPEvents(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "events_gm@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "events_alice@example.com", "password")
+	game := createTestGameForRSVPs(t, db, gm, "Flaky")
+
+	changes := []struct {
+		status, comment string
+	}{
+		{models.RSVPStatusAttending, ""},
+		{models.RSVPStatusAttending, ""}, // No change, no comment: not logged
+		{models.RSVPStatusNotAttending, "Sorry, work came up"},
+		{models.RSVPStatusNotAttending, "Still can't make it"}, // New comment only: logged
+		{models.RSVPStatusPending, ""},
+	}
+	for _, c := range changes {
+		if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: c.status, Comment: c.comment}); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP(%s) error = %v", c.status, err)
+		}
+	}
+	rsvp, _ := GetRSVPByUserForGame(db, alice.ID, game.ID)
+	if err := ReviewRSVP(db, rsvp.ID, gm.ID, true, "Glad you're back"); err != nil {
+		t.Fatalf("ReviewRSVP() error = %v", err)
+	}
+
+	events, err := GetRSVPEventsForGame(db, game.ID)
+	if err != nil {
+		t.Fatalf("GetRSVPEventsForGame() error = %v", err)
+	}
+	want := []struct {
+		old, new, comment string
+		byGM              bool
+	}{
+		{models.RSVPStatusPending, models.RSVPStatusAttending, "Glad you're back", true},
+		{models.RSVPStatusNotAttending, models.RSVPStatusPending, "", false},
+		{models.RSVPStatusNotAttending, models.RSVPStatusNotAttending, "Still can't make it", false},
+		{models.RSVPStatusAttending, models.RSVPStatusNotAttending, "Sorry, work came up", false},
+		{"", models.RSVPStatusAttending, "", false},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.OldStatus != w.old || e.NewStatus != w.new || e.Comment != w.comment || e.ByGM() != w.byGM {
+			t.Errorf("event %d = %s -> %s %q (by GM %v), want %s -> %s %q (by GM %v)",
+				i, e.OldStatus, e.NewStatus, e.Comment, e.ByGM(), w.old, w.new, w.comment, w.byGM)
+		}
+		if e.RSVPID != rsvp.ID || e.UserEmail != alice.Email || e.CreatedAt.IsZero() {
+			t.Errorf("event %d = %+v", i, e)
+		}
+	}
+	if events[0].ActorEmail != gm.Email {
+		t.Errorf("approval actor = %q, want the GM", events[0].ActorEmail)
+	}
+}
+
+func TestRSVPCommentAndGuests(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "guests_gm@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "guests_alice@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "guests_bob@example.com", "password")
+	game, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Crowded", GameDateTime: time.Now(), Location: "Table", RequiresApproval: true, MaxPlayers: 3})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusPending, Comment: "Bringing snacks", Guests: 2}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: bob.ID, Status: models.RSVPStatusPending, Guests: 0}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	aliceRSVP, _ := GetRSVPByUserForGame(db, alice.ID, game.ID)
+	if aliceRSVP.Comment != "Bringing snacks" || aliceRSVP.Guests != 2 || aliceRSVP.PartySize() != 3 {
+		t.Errorf("RSVP = %+v, want the comment and 2 guests", aliceRSVP)
+	}
+
+	// Alice and her guests fill the table, so there's no seat left for Bob.
+	if err := ReviewRSVP(db, aliceRSVP.ID, gm.ID, true, ""); err != nil {
+		t.Fatalf("ReviewRSVP() error = %v", err)
+	}
+	bobRSVP, _ := GetRSVPByUserForGame(db, bob.ID, game.ID)
+	if err := ReviewRSVP(db, bobRSVP.ID, gm.ID, true, ""); err != ErrGameFull {
+		t.Errorf("approving past the seat limit error = %v, want ErrGameFull", err)
+	}
+	rsvps, _ := GetRSVPsForGame(db, game.ID)
+	if taken := game.SeatsTaken(rsvps); taken != 3 {
+		t.Errorf("SeatsTaken() = %d, want 3", taken)
+	}
+
+	// Clearing the comment and guests frees the seats.
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	aliceRSVP, _ = GetRSVPByID(db, aliceRSVP.ID)
+	if aliceRSVP.Comment != "" || aliceRSVP.Guests != 0 {
+		t.Errorf("RSVP = %+v, want the comment and guests cleared", aliceRSVP)
+	}
+	if err := ReviewRSVP(db, bobRSVP.ID, gm.ID, true, ""); err != nil {
+		t.Errorf("ReviewRSVP() after freeing seats error = %v", err)
+	}
+}
+
+func TestCreateOrUpdateRSVPSeatLimit(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "seats_gm@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "seats_alice@example.com", "password")
+	game, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Two Seats", GameDateTime: time.Now(), Location: "Table", MaxPlayers: 2})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending, Guests: 2}); err != ErrGameFull {
+		t.Errorf("attending with too many guests error = %v, want ErrGameFull", err)
+	}
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: gm.ID, Status: models.RSVPStatusAttending}); err != nil {
+		t.Errorf("GM attending error = %v, want no seat needed", err)
+	}
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	// Alice keeps her own seat when she brings a guest along.
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending, Guests: 1}); err != nil {
+		t.Fatalf("adding a guest to an attending RSVP error = %v", err)
+	}
+
+	// Players racing for the last seat can't overbook the game.
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		player := createTestUserForRSVPs(t, db, fmt.Sprintf("seats_racer%d@example.com", i), "password")
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: player.ID, Status: models.RSVPStatusAttending})
+		}(i)
+	}
+	wg.Wait()
+	seated := 0
+	for _, err := range errs {
+		switch err {
+		case nil:
+			seated++
+		case ErrGameFull:
+		default:
+			t.Errorf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+	rsvps, _ := GetRSVPsForGame(db, game.ID)
+	if taken := game.SeatsTaken(rsvps); seated != 1 || taken != 2 {
+		t.Errorf("%d racers seated and %d seats taken, want 1 and 2", seated, taken)
+	}
+}
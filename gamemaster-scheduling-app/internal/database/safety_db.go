@@ -0,0 +1,158 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// SetGameContentTags replaces a game's content warnings with topics.
+func SetGameContentTags(db *sql.DB, gameID int64, topics []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM game_content_tags WHERE game_id = ?", gameID); err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		_, err := tx.Exec("INSERT INTO game_content_tags (game_id, topic) VALUES (?, ?) ON CONFLICT DO NOTHING", gameID, topic)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetContentLimits returns a user's lines and veils as a map from topic to
+// models.LimitLine or models.LimitVeil.
+func GetContentLimits(db *sql.DB, userID int64) (map[string]string, error) {
+	rows, err := db.Query("SELECT topic, kind FROM user_content_limits WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make(map[string]string)
+	for rows.Next() {
+		var topic, kind string
+		if err := rows.Scan(&topic, &kind); err != nil {
+			return nil, err
+		}
+		limits[topic] = kind
+	}
+	return limits, rows.Err()
+}
+
+// SetContentLimits replaces a user's lines and veils with limits, a map from topic
+// to models.LimitLine or models.LimitVeil.
+func SetContentLimits(db *sql.DB, userID int64, limits map[string]string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_content_limits WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for topic, kind := range limits {
+		_, err := tx.Exec("INSERT INTO user_content_limits (user_id, topic, kind) VALUES (?, ?, ?)", userID, topic, kind)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// limitPlayers selects the players whose lines and veils count toward a game's
+// summary: those who RSVP'd (attending, maybe or waiting for approval), leaving
+// out the game's GMs. It takes the game ID and the three statuses.
+const limitPlayers = `
+	SELECT r.user_id FROM rsvps r
+	WHERE r.game_id = ? AND r.status IN (?, ?, ?)
+		AND r.user_id NOT IN (SELECT gm_id FROM games WHERE id = r.game_id)
+		AND r.user_id NOT IN (SELECT user_id FROM game_staff WHERE game_id = r.game_id)
+`
+
+// GetGameLimitSummary counts the lines and veils of the players who RSVP'd to a game
+// (attending, maybe or waiting for approval), per topic and without names. The
+// game's GMs are left out. Topics nobody limited are omitted, and so is the whole
+// summary unless models.MinLimitSummaryPlayers of the players have set limits, so
+// the GM can't tell whose they are.
+//
+// The counts are taken on the first call and kept: later RSVPs and changes to
+// players' limits don't show, so the GM can't learn a new player's limits by
+// comparing the counts before and after they RSVP. Callers should wait until
+// Game.LimitSummaryAt. Only which topics are tagged in the game is current.
+// This still leaves the GM what the final counts say about the group, which
+// with few players can be a lot, and anything they know of some players'
+// limits from elsewhere narrows down the rest.
+func GetGameLimitSummary(db *sql.DB, gameID int64) ([]*models.LimitSummary, error) {
+	if err := takeGameLimitSummary(db, gameID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT s.topic, s.line_count, s.veil_count,
+			EXISTS (SELECT 1 FROM game_content_tags t WHERE t.game_id = s.game_id AND t.topic = s.topic)
+		FROM game_limit_summary_topics s
+		WHERE s.game_id = ?
+		ORDER BY s.topic
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summary []*models.LimitSummary
+	for rows.Next() {
+		s := &models.LimitSummary{}
+		if err := rows.Scan(&s.Topic, &s.Lines, &s.Veils, &s.InGame); err != nil {
+			return nil, err
+		}
+		summary = append(summary, s)
+	}
+	return summary, rows.Err()
+}
+
+// takeGameLimitSummary stores the counts GetGameLimitSummary returns, unless they
+// were taken already.
+func takeGameLimitSummary(db *sql.DB, gameID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO game_limit_summaries (game_id) VALUES (?) ON CONFLICT DO NOTHING", gameID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err // Taken already
+	}
+
+	args := []interface{}{gameID, models.RSVPStatusAttending, models.RSVPStatusMaybe, models.RSVPStatusPending}
+	var players int
+	err = tx.QueryRow("SELECT COUNT(DISTINCT user_id) FROM user_content_limits WHERE user_id IN ("+limitPlayers+")", args...).Scan(&players)
+	if err != nil {
+		return err
+	}
+	if players >= models.MinLimitSummaryPlayers {
+		_, err := tx.Exec(`
+			INSERT INTO game_limit_summary_topics (game_id, topic, line_count, veil_count)
+			SELECT ?, l.topic,
+				SUM(CASE WHEN l.kind = ? THEN 1 ELSE 0 END),
+				SUM(CASE WHEN l.kind = ? THEN 1 ELSE 0 END)
+			FROM user_content_limits l
+			WHERE l.user_id IN (`+limitPlayers+`)
+			GROUP BY l.topic
+		`, append([]interface{}{gameID, models.LimitLine, models.LimitVeil}, args...)...)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
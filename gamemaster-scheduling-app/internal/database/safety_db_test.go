@@ -0,0 +1,133 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestContentTagsAndLimits(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "safety_gm@example.com", "password")
+	cogm := createTestUserForRSVPs(t, db, "safety_cogm@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "safety_alice@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "safety_bob@example.com", "password")
+	carol := createTestUserForRSVPs(t, db, "safety_carol@example.com", "password")
+	dan := createTestUserForRSVPs(t, db, "safety_dan@example.com", "password")
+
+	horror, err := CreateGame(db, &models.Game{
+		GMID: gm.ID, Title: "Haunted Manor", GameDateTime: time.Now().Add(24 * time.Hour), Location: "Test Location",
+		ContentTags: []string{"spiders", "gore"}, ContentNotes: "Lots of jump scares",
+	})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+	if !reflect.DeepEqual(horror.ContentTags, []string{"gore", "spiders"}) || horror.ContentNotes != "Lots of jump scares" {
+		t.Errorf("CreateGame() tags = %v, notes = %q", horror.ContentTags, horror.ContentNotes)
+	}
+	cozy := createTestGameForRSVPs(t, db, gm, "Tea Party")
+	if cozy.ContentTags != nil {
+		t.Errorf("untagged game ContentTags = %v, want nil", cozy.ContentTags)
+	}
+
+	if err := SetContentLimits(db, alice.ID, map[string]string{"spiders": models.LimitLine, "gore": models.LimitVeil}); err != nil {
+		t.Fatalf("SetContentLimits() error = %v", err)
+	}
+	if err := SetContentLimits(db, bob.ID, map[string]string{"spiders": models.LimitVeil, "death": models.LimitLine}); err != nil {
+		t.Fatalf("SetContentLimits() error = %v", err)
+	}
+	// Saving replaces the previous limits.
+	if err := SetContentLimits(db, bob.ID, map[string]string{"spiders": models.LimitLine}); err != nil {
+		t.Fatalf("SetContentLimits() error = %v", err)
+	}
+	if limits, _ := GetContentLimits(db, bob.ID); !reflect.DeepEqual(limits, map[string]string{"spiders": models.LimitLine}) {
+		t.Errorf("GetContentLimits() = %v, want only the spiders line", limits)
+	}
+	// Carol's limits don't count: she isn't coming. Nor do the co-GM's.
+	SetContentLimits(db, carol.ID, map[string]string{"gore": models.LimitLine})
+	SetContentLimits(db, cogm.ID, map[string]string{"gore": models.LimitLine})
+	if err := AddGameStaff(db, horror.ID, cogm.ID, gm.ID); err != nil {
+		t.Fatalf("AddGameStaff() error = %v", err)
+	}
+
+	for _, rsvp := range []*models.RSVP{
+		{GameID: horror.ID, UserID: alice.ID, Status: models.RSVPStatusAttending},
+		{GameID: horror.ID, UserID: bob.ID, Status: models.RSVPStatusMaybe},
+		{GameID: horror.ID, UserID: carol.ID, Status: models.RSVPStatusNotAttending},
+		{GameID: horror.ID, UserID: cogm.ID, Status: models.RSVPStatusAttending},
+	} {
+		if err := CreateOrUpdateRSVP(db, rsvp); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+
+	SetContentLimits(db, dan.ID, map[string]string{"insects": models.LimitVeil})
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: horror.ID, UserID: dan.ID, Status: models.RSVPStatusPending}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+
+	summary, err := GetGameLimitSummary(db, horror.ID)
+	if err != nil {
+		t.Fatalf("GetGameLimitSummary() error = %v", err)
+	}
+	want := []*models.LimitSummary{
+		{Topic: "gore", Veils: 1, InGame: true},
+		{Topic: "insects", Veils: 1},
+		{Topic: "spiders", Lines: 2, InGame: true},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("GetGameLimitSummary() = %+v, want %+v", summary, want)
+	}
+	if summary[0].Conflicts() || summary[1].Conflicts() || !summary[2].Conflicts() {
+		t.Errorf("Conflicts() = %v, %v, %v; want only spiders", summary[0].Conflicts(), summary[1].Conflicts(), summary[2].Conflicts())
+	}
+
+	// The counts are kept as they were taken: one more RSVP doesn't change them,
+	// so the GM can't read the newcomer's limits off the difference.
+	eve := createTestUserForRSVPs(t, db, "safety_eve@example.com", "password")
+	SetContentLimits(db, eve.ID, map[string]string{"spiders": models.LimitLine, "death": models.LimitVeil})
+	if err := CreateOrUpdateRSVP(db, &models.RSVP{GameID: horror.ID, UserID: eve.ID, Status: models.RSVPStatusAttending}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	if again, err := GetGameLimitSummary(db, horror.ID); err != nil || !reflect.DeepEqual(again, want) {
+		t.Errorf("GetGameLimitSummary() after one more RSVP = %+v, %v; want %+v", again, err, want)
+	}
+
+	games, err := FilterGames(db, models.GameFilter{ExcludeLinesOf: alice.ID})
+	if err != nil {
+		t.Fatalf("FilterGames() error = %v", err)
+	}
+	if len(games) != 1 || games[0].ID != cozy.ID {
+		t.Errorf("FilterGames() = %v, want only the untagged game", games)
+	}
+	if games, _ := FilterGames(db, models.GameFilter{ExcludeLinesOf: cogm.ID}); len(games) != 1 {
+		t.Errorf("FilterGames() for a gore line = %d games, want 1", len(games))
+	}
+	// A veil doesn't hide a game.
+	SetContentLimits(db, alice.ID, map[string]string{"spiders": models.LimitVeil})
+	if games, _ := FilterGames(db, models.GameFilter{ExcludeLinesOf: alice.ID}); len(games) != 2 {
+		t.Errorf("FilterGames() with only veils = %d games, want 2", len(games))
+	}
+}
+
+func TestGameLimitSummaryHidesASinglePlayer(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	gm := createTestUserForRSVPs(t, db, "lone_gm@example.com", "password")
+	alice := createTestUserForRSVPs(t, db, "lone_alice@example.com", "password")
+	bob := createTestUserForRSVPs(t, db, "lone_bob@example.com", "password")
+	game := createTestGameForRSVPs(t, db, gm, "One Player")
+	SetContentLimits(db, alice.ID, map[string]string{"spiders": models.LimitLine})
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: alice.ID, Status: models.RSVPStatusAttending})
+	// Players without limits don't make the others anonymous.
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: bob.ID, Status: models.RSVPStatusAttending})
+
+	if summary, err := GetGameLimitSummary(db, game.ID); err != nil || summary != nil {
+		t.Errorf("GetGameLimitSummary() with one player setting limits = %+v, %v; want nothing", summary, err)
+	}
+}
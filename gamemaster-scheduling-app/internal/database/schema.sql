@@ -1,10 +1,43 @@
 CREATE TABLE IF NOT EXISTS users (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     email TEXT UNIQUE NOT NULL,
+    username TEXT, -- Optional handle for @mentions; unique ignoring case (idx_users_username)
     password_hash TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'user', -- 'user' or 'admin' (site operator)
+    suspended_at TIMESTAMP, -- Set while an admin has suspended the account
+    suspended_reason TEXT,
+    timezone TEXT, -- IANA name, e.g. 'Europe/Berlin'; NULL means UTC
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Every login attempt, for admins reviewing account activity.
+CREATE TABLE IF NOT EXISTS login_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id), -- NULL when the email isn't registered
+    email TEXT NOT NULL, -- As entered
+    success BOOLEAN NOT NULL,
+    reason TEXT, -- Why a failed attempt failed: 'unknown_email', 'bad_password' or 'suspended'
+    ip_address TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events (user_id, created_at);
+
+-- Append-only record of actions taken by site admins.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin_id INTEGER NOT NULL,
+    action TEXT NOT NULL, -- e.g. 'suspend_user', 'cancel_game'
+    target_type TEXT NOT NULL, -- 'user', 'game', 'chat_message' or 'report'
+    target_id INTEGER NOT NULL,
+    details TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (admin_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log (created_at);
+
 CREATE TABLE IF NOT EXISTS games (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     gm_id INTEGER NOT NULL,
@@ -12,15 +45,87 @@ CREATE TABLE IF NOT EXISTS games (
     description TEXT,
     game_datetime TIMESTAMP,
     location TEXT, -- Could be physical address or virtual link
+    campaign_id INTEGER REFERENCES campaigns(id), -- NULL for one-shots
+    min_level INTEGER, -- Suggested character level range; NULL for no bound
+    max_level INTEGER,
+    requires_approval BOOLEAN NOT NULL DEFAULT 0, -- Attending RSVPs wait for the GM's approval
+    max_players INTEGER, -- Seats for approved attendees; NULL for unlimited
+    rsvp_deadline TIMESTAMP, -- RSVPs lock and the quorum is checked at this time
+    min_players INTEGER, -- Quorum; NULL for none
+    quorum_status TEXT, -- NULL until decided, then 'confirmed' or 'cancelled_quorum'
+    quorum_decided_at TIMESTAMP,
+    rsvps_reopened BOOLEAN NOT NULL DEFAULT 0, -- GM allowed RSVP changes after the deadline
+    transfer_to_id INTEGER REFERENCES users(id), -- Pending ownership transfer, until the recipient accepts
+    cancelled_at TIMESTAMP, -- Set when a site admin force-cancels the game
+    cancel_reason TEXT,
+    content_notes TEXT, -- Free-text content warning beyond game_content_tags
+    system_id INTEGER REFERENCES game_systems(id),
+    experience_level TEXT, -- One of models.ExperienceLevels
+    format TEXT, -- 'one_shot' or 'campaign'
+    play_mode TEXT, -- 'in_person' or 'online'
+    event_slot_id INTEGER REFERENCES event_slots(id), -- Set on the tables of an event
+    event_venue_id INTEGER REFERENCES event_venues(id),
+    venue_id INTEGER REFERENCES venues(id), -- At most one of venue_id and virtual_table_id is set
+    virtual_table_id INTEGER REFERENCES virtual_tables(id),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (gm_id) REFERENCES users(id)
 );
 
+-- Game systems, e.g. "D&D 5e", managed by site admins at /admin/systems.
+CREATE TABLE IF NOT EXISTS game_systems (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A fresh install starts with some popular systems; admins' later changes stick.
+INSERT INTO game_systems (name)
+SELECT column1 FROM (VALUES ('D&D 5e'), ('Pathfinder 2e'), ('Call of Cthulhu'), ('Blades in the Dark'),
+    ('Vampire: The Masquerade'), ('Shadowrun'), ('Starfinder'), ('Mothership'))
+WHERE NOT EXISTS (SELECT 1 FROM game_systems);
+
+-- The game systems a player likes to play, shown on their profile.
+CREATE TABLE IF NOT EXISTS user_game_systems (
+    user_id INTEGER NOT NULL,
+    system_id INTEGER NOT NULL,
+    PRIMARY KEY (user_id, system_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (system_id) REFERENCES game_systems(id)
+);
+
+-- Content warnings on a game, from the fixed vocabulary in models.ContentTopics.
+CREATE TABLE IF NOT EXISTS game_content_tags (
+    game_id INTEGER NOT NULL,
+    topic TEXT NOT NULL,
+    PRIMARY KEY (game_id, topic),
+    FOREIGN KEY (game_id) REFERENCES games(id)
+);
+
+-- Co-GMs, with the same rights as the owner (games.gm_id) except managing staff.
+CREATE TABLE IF NOT EXISTS game_staff (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL, -- 'co_gm'
+    added_by INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (added_by) REFERENCES users(id),
+    UNIQUE (game_id, user_id)
+);
+
 CREATE TABLE IF NOT EXISTS rsvps (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     user_id INTEGER NOT NULL,
     game_id INTEGER NOT NULL,
-    status TEXT NOT NULL, -- e.g., 'attending', 'not_attending', 'maybe'
+    status TEXT NOT NULL, -- e.g., 'attending', 'not_attending', 'maybe', 'pending', 'declined'
+    character_id INTEGER REFERENCES characters(id), -- The character the player is bringing
+    reviewed_by INTEGER REFERENCES users(id), -- GM who approved or declined a pending RSVP
+    reviewed_at TIMESTAMP,
+    review_message TEXT, -- Optional note from the GM to the player
+    comment TEXT, -- Optional note from the player, e.g. 'arriving late'
+    guests INTEGER NOT NULL DEFAULT 0, -- Plus-ones, who take seats too
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(id),
@@ -28,12 +133,511 @@ CREATE TABLE IF NOT EXISTS rsvps (
     UNIQUE (user_id, game_id)
 );
 
+-- Append-only history of RSVP changes, written in the same transaction as the change.
+CREATE TABLE IF NOT EXISTS rsvp_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rsvp_id INTEGER NOT NULL,
+    game_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL, -- The player whose RSVP changed
+    actor_id INTEGER NOT NULL, -- The player, or the GM approving or declining them
+    old_status TEXT, -- NULL for a first RSVP
+    new_status TEXT NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (rsvp_id) REFERENCES rsvps(id),
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (actor_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rsvp_events_game ON rsvp_events (game_id, created_at);
+
 CREATE TABLE IF NOT EXISTS chat_messages (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     game_id INTEGER NOT NULL,
     user_id INTEGER NOT NULL,
     message_content TEXT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP, -- Set when the author last edited the message
+    deleted_at TIMESTAMP, -- Soft delete; content is kept for moderation but not shown
+    deleted_by INTEGER REFERENCES users(id), -- Author, or the GM when moderated
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Chat is read a page at a time, newest first, per game.
+CREATE INDEX IF NOT EXISTS idx_chat_messages_game_created ON chat_messages (game_id, created_at);
+
+-- Previous versions of edited chat messages, oldest first.
+CREATE TABLE IF NOT EXISTS chat_message_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id INTEGER NOT NULL,
+    previous_content TEXT NOT NULL,
+    edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES chat_messages(id)
+);
+
+-- Users muted by the GM in a game's chat.
+CREATE TABLE IF NOT EXISTS chat_mutes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    muted_by INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (muted_by) REFERENCES users(id),
+    UNIQUE (game_id, user_id)
+);
+
+-- Server-generated results of /roll chat commands. Kept apart from
+-- message_content so a roll cannot be faked by typing it into chat.
+CREATE TABLE IF NOT EXISTS chat_rolls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id INTEGER NOT NULL UNIQUE,
+    expression TEXT NOT NULL, -- Normalized notation, e.g. '2d20kh1+5'
+    label TEXT,
+    total INTEGER NOT NULL,
+    groups_json TEXT NOT NULL, -- JSON array of models.DiceGroup, every die rolled
+    FOREIGN KEY (message_id) REFERENCES chat_messages(id)
+);
+
+-- Users @mentioned in a chat message, resolved when it was posted or edited.
+CREATE TABLE IF NOT EXISTS chat_mentions (
+    message_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (message_id, user_id),
+    FOREIGN KEY (message_id) REFERENCES chat_messages(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Per-user notifications, e.g. chat @mentions.
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL, -- e.g. 'mention'
+    message TEXT NOT NULL,
+    link TEXT,
+    read_at TIMESTAMP, -- NULL while unread
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications (user_id, created_at);
+
+-- A GM's recurring sessions, grouped by name.
+CREATE TABLE IF NOT EXISTS campaigns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gm_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (gm_id) REFERENCES users(id),
+    UNIQUE (gm_id, name)
+);
+
+-- Who actually showed up to a past game, recorded by the GM.
+CREATE TABLE IF NOT EXISTS attendance (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    status TEXT NOT NULL, -- 'present', 'late' or 'no_show'
+    recorded_by INTEGER NOT NULL,
+    recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (recorded_by) REFERENCES users(id),
+    UNIQUE (game_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_user ON attendance (user_id);
+
+-- Per-game session notes: a recap shared with participants and the GM's private prep.
+CREATE TABLE IF NOT EXISTS session_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL,
+    kind TEXT NOT NULL, -- 'recap' or 'gm_prep'
+    content TEXT NOT NULL,
+    updated_by INTEGER NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (updated_by) REFERENCES users(id),
+    UNIQUE (game_id, kind)
+);
+
+-- Every saved version of a session note. Autosaves by the same author within
+-- models.NoteRevisionMergeWindow update the latest revision instead of adding one.
+CREATE TABLE IF NOT EXISTS session_note_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    note_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    author_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (note_id) REFERENCES session_notes(id),
+    FOREIGN KEY (author_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_note_revisions_note ON session_note_revisions (note_id);
+
+-- Files the GM shared with a game. Contents live in a storage.Store, not here.
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL,
+    uploader_id INTEGER NOT NULL,
+    filename TEXT NOT NULL, -- As uploaded, for display and downloads
+    content_type TEXT NOT NULL, -- Sniffed from the contents, not taken from the client
+    size_bytes INTEGER NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT, -- NULL unless a preview image was generated
+    restricted INTEGER NOT NULL DEFAULT 0, -- 1 when only revealed to the players in attachment_reveals
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (uploader_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_game ON attachments (game_id);
+
+-- Players a restricted attachment has been revealed to.
+CREATE TABLE IF NOT EXISTS attachment_reveals (
+    attachment_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (attachment_id, user_id),
+    FOREIGN KEY (attachment_id) REFERENCES attachments(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Player characters, owned by users and brought to games through RSVPs.
+CREATE TABLE IF NOT EXISTS characters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    system TEXT, -- e.g. 'D&D 5e'
+    class TEXT,
+    level INTEGER, -- NULL if not tracked
+    sheet_url TEXT,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_characters_user ON characters (user_id);
+
+-- Users' reports of games, chat messages and other users, for moderators to review.
+CREATE TABLE IF NOT EXISTS reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL, -- 'game', 'chat_message' or 'user'
+    target_id INTEGER NOT NULL,
+    game_id INTEGER REFERENCES games(id), -- The game a game or chat report is about; NULL for users
+    reported_user_id INTEGER REFERENCES users(id), -- The profile, the message's author or the game's owner
+    reason TEXT NOT NULL, -- 'spam', 'harassment', 'scam', 'inappropriate' or 'other'
+    note TEXT,
+    status TEXT NOT NULL DEFAULT 'open', -- 'open', 'resolved' or 'dismissed'
+    resolved_by INTEGER REFERENCES users(id),
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reporter_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_reports_game ON reports (game_id);
+
+-- Users whose chat messages a user has chosen not to see.
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id INTEGER NOT NULL,
+    blocked_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users(id),
+    FOREIGN KEY (blocked_id) REFERENCES users(id)
+);
+
+-- Players' private lines (no such content) and veils (off-screen only), by topic.
+-- GMs only ever see anonymized counts.
+CREATE TABLE IF NOT EXISTS user_content_limits (
+    user_id INTEGER NOT NULL,
+    topic TEXT NOT NULL,
+    kind TEXT NOT NULL, -- 'line' or 'veil'
+    PRIMARY KEY (user_id, topic),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- The anonymized counts of a game's players' lines and veils, taken once when
+-- its RSVPs close so later RSVPs can't be told apart by how the counts change.
+-- A game has a row here once the counts are taken; too few players with limits
+-- leaves it without topics.
+CREATE TABLE IF NOT EXISTS game_limit_summaries (
+    game_id INTEGER PRIMARY KEY,
+    taken_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES games(id)
+);
+
+CREATE TABLE IF NOT EXISTS game_limit_summary_topics (
+    game_id INTEGER NOT NULL,
+    topic TEXT NOT NULL,
+    line_count INTEGER NOT NULL,
+    veil_count INTEGER NOT NULL,
+    PRIMARY KEY (game_id, topic),
+    FOREIGN KEY (game_id) REFERENCES game_limit_summaries(game_id)
+);
+
+-- Looking-for-group posts: players saying what they want to play, and GMs
+-- advertising open seats in a game. Posts stay up until closed_at is set.
+CREATE TABLE IF NOT EXISTS lfg_posts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL, -- 'player' or 'gm'
+    system_id INTEGER REFERENCES game_systems(id), -- NULL if any system will do
+    play_mode TEXT, -- 'in_person' or 'online'; NULL if either
+    experience_level TEXT, -- One of models.ExperienceLevels; NULL if unspecified
+    game_id INTEGER REFERENCES games(id), -- The game a GM post recruits for
+    note TEXT,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lfg_posts_open ON lfg_posts (kind, closed_at);
+
+-- The weekly times a player post is available, in minutes after midnight.
+CREATE TABLE IF NOT EXISTS lfg_post_availability (
+    post_id INTEGER NOT NULL,
+    weekday INTEGER NOT NULL, -- 0 is Sunday, as in time.Weekday
+    start_minute INTEGER NOT NULL,
+    end_minute INTEGER NOT NULL,
+    PRIMARY KEY (post_id, weekday, start_minute),
+    FOREIGN KEY (post_id) REFERENCES lfg_posts(id)
+);
+
+-- Users' recurring weekly availability, in their own timezone (users.timezone).
+CREATE TABLE IF NOT EXISTS user_availability (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    weekday INTEGER NOT NULL, -- 0 is Sunday, as in time.Weekday
+    start_minute INTEGER NOT NULL,
+    end_minute INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_availability_user ON user_availability (user_id);
+
+-- One-off changes to a user's weekly availability on a date in their timezone.
+CREATE TABLE IF NOT EXISTS user_availability_exceptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    date TEXT NOT NULL, -- YYYY-MM-DD
+    start_minute INTEGER NOT NULL,
+    end_minute INTEGER NOT NULL,
+    free BOOLEAN NOT NULL, -- 1 for extra free time, 0 for busy
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_availability_exceptions_user ON user_availability_exceptions (user_id, date);
+
+-- Conventions and other events with many tables. Each table is a game in one of
+-- the event's slots (games.event_slot_id).
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    organizer_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    starts_on TEXT NOT NULL, -- YYYY-MM-DD
+    max_attendees INTEGER, -- Badges to hand out; NULL for unlimited
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (organizer_id) REFERENCES users(id)
+);
+
+-- The rooms or halls of an event.
+CREATE TABLE IF NOT EXISTS event_venues (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    details TEXT,
+    FOREIGN KEY (event_id) REFERENCES events(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_venues_event ON event_venues (event_id);
+
+-- The blocks of time tables run in.
+CREATE TABLE IF NOT EXISTS event_slots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    ranked BOOLEAN NOT NULL DEFAULT 0, -- Players rank tables, and the organizer publishes the seating
+    assignment_seed INTEGER NOT NULL DEFAULT 0, -- Draws the lottery that breaks ties
+    assignments_published_at TIMESTAMP,
+    FOREIGN KEY (event_id) REFERENCES events(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_slots_event ON event_slots (event_id);
+
+-- Players' badges. Only registered players can sign up for tables.
+CREATE TABLE IF NOT EXISTS event_registrations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    badge_number INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, user_id),
+    UNIQUE (event_id, badge_number),
+    FOREIGN KEY (event_id) REFERENCES events(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Players' ranked table choices in a ranked slot, best first (rank 1).
+CREATE TABLE IF NOT EXISTS event_choices (
+    slot_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    game_id INTEGER NOT NULL,
+    rank INTEGER NOT NULL,
+    PRIMARY KEY (slot_id, user_id, rank),
+    UNIQUE (slot_id, user_id, game_id),
+    FOREIGN KEY (slot_id) REFERENCES event_slots(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (game_id) REFERENCES games(id)
+);
+
+-- The organizer's tweaks to a ranked slot's seating: the table a player is
+-- seated at whatever their choices, or NULL to leave them without one.
+CREATE TABLE IF NOT EXISTS event_assignment_pins (
+    slot_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    game_id INTEGER,
+    PRIMARY KEY (slot_id, user_id),
+    FOREIGN KEY (slot_id) REFERENCES event_slots(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (game_id) REFERENCES games(id)
+);
+
+-- Physical places games are played at.
+CREATE TABLE IF NOT EXISTS venues (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL,
+    capacity INTEGER, -- People it holds; NULL if unknown
+    accessibility TEXT,
+    house_rules TEXT,
+    host_contact TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id)
+);
+
+-- Where GMs run games online. The links are only shown to a game's GMs and
+-- attending players.
+CREATE TABLE IF NOT EXISTS virtual_tables (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    vtt_url TEXT,
+    voice_url TEXT,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_virtual_tables_owner ON virtual_tables (owner_id);
+
+-- The order a campaign's players take turns hosting its sessions.
+CREATE TABLE IF NOT EXISTS campaign_host_rotation (
+    campaign_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (campaign_id, user_id),
+    FOREIGN KEY (campaign_id) REFERENCES campaigns(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Who hosts a campaign session, assigned from the rotation.
+CREATE TABLE IF NOT EXISTS session_hosts (
+    game_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    assigned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (game_id) REFERENCES games(id),
     FOREIGN KEY (user_id) REFERENCES users(id)
 );
+
+-- A host's request to trade sessions with the host of another session.
+CREATE TABLE IF NOT EXISTS host_swaps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    campaign_id INTEGER NOT NULL,
+    from_game_id INTEGER NOT NULL, -- The requester's session
+    to_game_id INTEGER NOT NULL,
+    requester_id INTEGER NOT NULL,
+    responder_id INTEGER NOT NULL, -- The host of to_game_id when asked
+    status TEXT NOT NULL DEFAULT 'pending', -- 'pending', 'accepted' or 'declined'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP,
+    FOREIGN KEY (campaign_id) REFERENCES campaigns(id),
+    FOREIGN KEY (from_game_id) REFERENCES games(id),
+    FOREIGN KEY (to_game_id) REFERENCES games(id),
+    FOREIGN KEY (requester_id) REFERENCES users(id),
+    FOREIGN KEY (responder_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_host_swaps_campaign ON host_swaps (campaign_id, status);
+
+-- A campaign's shared costs as a double-entry ledger. Each entry is a balanced
+-- transaction: its postings add up to zero. A player's balance is the sum of
+-- their postings; positive means the others owe them.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    campaign_id INTEGER NOT NULL,
+    game_id INTEGER, -- The session an expense was for; NULL for payments
+    kind TEXT NOT NULL, -- 'expense', 'payment' or 'reversal'
+    description TEXT NOT NULL,
+    created_by INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    reverses_entry_id INTEGER, -- The entry a reversal undoes, each at most once; NULL for other kinds
+    FOREIGN KEY (campaign_id) REFERENCES campaigns(id),
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (created_by) REFERENCES users(id),
+    FOREIGN KEY (reverses_entry_id) REFERENCES ledger_entries(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_campaign ON ledger_entries (campaign_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_game ON ledger_entries (game_id);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    amount_cents INTEGER NOT NULL CHECK (amount_cents <> 0),
+    FOREIGN KEY (entry_id) REFERENCES ledger_entries(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_entry ON ledger_postings (entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_user ON ledger_postings (user_id);
+
+-- Attendees' ratings of a session they were at, from 1 to 5 stars, with a
+-- comment only the GM sees. One response per attendee per game.
+CREATE TABLE IF NOT EXISTS session_feedback (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    game_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    fun INTEGER NOT NULL CHECK (fun BETWEEN 1 AND 5),
+    pacing INTEGER NOT NULL CHECK (pacing BETWEEN 1 AND 5),
+    inclusivity INTEGER NOT NULL CHECK (inclusivity BETWEEN 1 AND 5),
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES games(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    UNIQUE (game_id, user_id)
+);
+
+-- The GMs a session's feedback counts for: the owner and co-GMs when it was given,
+-- so later staff changes and transfers don't move ratings.
+CREATE TABLE IF NOT EXISTS session_feedback_gms (
+    feedback_id INTEGER NOT NULL,
+    gm_id INTEGER NOT NULL,
+    PRIMARY KEY (feedback_id, gm_id),
+    FOREIGN KEY (feedback_id) REFERENCES session_feedback(id),
+    FOREIGN KEY (gm_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_feedback_gms_gm ON session_feedback_gms (gm_id);
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// ErrNoteConflict is returned by SaveSessionNote when someone else saved the note
+// since the editor loaded it.
+var ErrNoteConflict = errors.New("session note was changed by someone else")
+
+const sessionNoteSelect = `
+	SELECT n.id, n.game_id, n.kind, n.content, n.updated_by, n.updated_at,
+		(SELECT MAX(r.id) FROM session_note_revisions r WHERE r.note_id = n.id)
+	FROM session_notes n`
+
+func scanSessionNote(row rowScanner) (*models.SessionNote, error) {
+	note := &models.SessionNote{}
+	var revisionID sql.NullInt64
+	if err := row.Scan(&note.ID, &note.GameID, &note.Kind, &note.Content, &note.UpdatedBy, &note.UpdatedAt, &revisionID); err != nil {
+		return nil, err
+	}
+	note.RevisionID = revisionID.Int64
+	return note, nil
+}
+
+// GetSessionNote retrieves a game's note of the given kind (models.NoteKind*).
+// It returns sql.ErrNoRows if nothing has been written yet.
+func GetSessionNote(db *sql.DB, gameID int64, kind string) (*models.SessionNote, error) {
+	return scanSessionNote(db.QueryRow(sessionNoteSelect+" WHERE n.game_id = ? AND n.kind = ?", gameID, kind))
+}
+
+// SaveSessionNote stores new content for a game's note and records the revision, all in
+// one transaction. baseRevisionID is the revision the editor started from (0 for a new
+// note); if the note has moved on since, nothing is saved and ErrNoteConflict is returned.
+// Consecutive saves by the same author within models.NoteRevisionMergeWindow update the
+// latest revision rather than adding one. Permission checks are the caller's responsibility.
+func SaveSessionNote(db *sql.DB, gameID int64, kind string, authorID int64, content string, baseRevisionID int64) (*models.SessionNote, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		noteID         int64
+		latestID       sql.NullInt64
+		latestAuthor   sql.NullInt64
+		latestCreated  sql.NullTime
+		currentContent string
+	)
+	err = tx.QueryRow(`
+		SELECT n.id, n.content, r.id, r.author_id, r.created_at
+		FROM session_notes n
+		LEFT JOIN session_note_revisions r ON r.note_id = n.id
+		WHERE n.game_id = ? AND n.kind = ?
+		ORDER BY r.id DESC
+		LIMIT 1
+	`, gameID, kind).Scan(&noteID, &currentContent, &latestID, &latestAuthor, &latestCreated)
+	switch {
+	case err == sql.ErrNoRows:
+		if baseRevisionID != 0 {
+			return nil, ErrNoteConflict
+		}
+		res, err := tx.Exec("INSERT INTO session_notes (game_id, kind, content, updated_by) VALUES (?, ?, ?, ?)", gameID, kind, content, authorID)
+		if err != nil {
+			return nil, err
+		}
+		if noteID, err = res.LastInsertId(); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("INSERT INTO session_note_revisions (note_id, content, author_id) VALUES (?, ?, ?)", noteID, content, authorID); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case latestID.Int64 != baseRevisionID:
+		return nil, ErrNoteConflict
+	case content == currentContent:
+		// Nothing changed, e.g. an autosave fired on a keystroke that was undone.
+	default:
+		if _, err := tx.Exec("UPDATE session_notes SET content = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", content, authorID, noteID); err != nil {
+			return nil, err
+		}
+		merge := latestAuthor.Int64 == authorID && time.Since(latestCreated.Time) < models.NoteRevisionMergeWindow
+		if merge {
+			_, err = tx.Exec("UPDATE session_note_revisions SET content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", content, latestID.Int64)
+		} else {
+			_, err = tx.Exec("INSERT INTO session_note_revisions (note_id, content, author_id) VALUES (?, ?, ?)", noteID, content, authorID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetSessionNote(db, gameID, kind)
+}
+
+// GetSessionNoteRevisions retrieves every saved version of a note, newest first.
+func GetSessionNoteRevisions(db *sql.DB, noteID int64) ([]*models.SessionNoteRevision, error) {
+	rows, err := db.Query(`
+		SELECT r.id, r.note_id, r.content, r.author_id, COALESCE(u.username, u.email), r.created_at, r.updated_at
+		FROM session_note_revisions r
+		JOIN users u ON u.id = r.author_id
+		WHERE r.note_id = ?
+		ORDER BY r.id DESC
+	`, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*models.SessionNoteRevision
+	for rows.Next() {
+		rev := &models.SessionNoteRevision{}
+		if err := rows.Scan(&rev.ID, &rev.NoteID, &rev.Content, &rev.AuthorID, &rev.AuthorName, &rev.CreatedAt, &rev.UpdatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetCampaignRecaps returns the recap of each of a campaign's games that has one, by game ID.
+func GetCampaignRecaps(db *sql.DB, campaignID int64) (map[int64]*models.SessionNote, error) {
+	rows, err := db.Query(sessionNoteSelect+`
+		JOIN games g ON g.id = n.game_id
+		WHERE g.campaign_id = ? AND n.kind = ?
+	`, campaignID, models.NoteKindRecap)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recaps := map[int64]*models.SessionNote{}
+	for rows.Next() {
+		note, err := scanSessionNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		recaps[note.GameID] = note
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return recaps, nil
+}
+
+// IsGameParticipant reports whether a user takes part in a game: one of its GMs, or a player
+// the GM recorded as present or late. Until attendance is recorded for them, players
+// who RSVP'd attending count too.
+func IsGameParticipant(db *sql.DB, gameID, userID int64) (bool, error) {
+	var participant bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM games WHERE id = ? AND gm_id = ?)
+			OR EXISTS (SELECT 1 FROM game_staff WHERE game_id = ? AND user_id = ?)
+			OR COALESCE(
+				(SELECT status IN (?, ?) FROM attendance WHERE game_id = ? AND user_id = ?),
+				EXISTS (SELECT 1 FROM rsvps WHERE game_id = ? AND user_id = ? AND status = ?)
+			)
+	`, gameID, userID, gameID, userID,
+		models.AttendancePresent, models.AttendanceLate, gameID, userID,
+		gameID, userID, models.RSVPStatusAttending).Scan(&participant)
+	return participant, err
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSessionNotes(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+
+	gm, _ := CreateUser(db, "notes_gm@example.com", "password")
+	player, _ := CreateUser(db, "notes_player@example.com", "password")
+	campaign, _ := GetOrCreateCampaign(db, gm.ID, "Notes Campaign")
+	game, err := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Notes Game", GameDateTime: time.Now().Add(-time.Hour), Location: "Table", CampaignID: campaign.ID})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+
+	if _, err := GetSessionNote(db, game.ID, models.NoteKindRecap); err != sql.ErrNoRows {
+		t.Fatalf("GetSessionNote() before writing error = %v, want sql.ErrNoRows", err)
+	}
+
+	note, err := SaveSessionNote(db, game.ID, models.NoteKindRecap, gm.ID, "We met in a tavern.", 0)
+	if err != nil {
+		t.Fatalf("SaveSessionNote() new note error = %v", err)
+	}
+	if note.Content != "We met in a tavern." || note.RevisionID == 0 || note.UpdatedBy != gm.ID {
+		t.Fatalf("new note = %+v", note)
+	}
+	if _, err := SaveSessionNote(db, game.ID, models.NoteKindRecap, player.ID, "Stale", 0); err != ErrNoteConflict {
+		t.Errorf("saving over an existing note from revision 0: error = %v, want ErrNoteConflict", err)
+	}
+
+	// An autosave by the same author shortly after folds into the same revision.
+	merged, err := SaveSessionNote(db, game.ID, models.NoteKindRecap, gm.ID, "We met in a tavern. It burned down.", note.RevisionID)
+	if err != nil {
+		t.Fatalf("SaveSessionNote() autosave error = %v", err)
+	}
+	if merged.RevisionID != note.RevisionID {
+		t.Errorf("autosave revision = %d, want merged into %d", merged.RevisionID, note.RevisionID)
+	}
+
+	// Another author always starts a new revision, and must start from the latest one.
+	byPlayer, err := SaveSessionNote(db, game.ID, models.NoteKindRecap, player.ID, "We met in a tavern. It burned down. Not my fault.", merged.RevisionID)
+	if err != nil {
+		t.Fatalf("SaveSessionNote() by player error = %v", err)
+	}
+	if byPlayer.RevisionID == merged.RevisionID || byPlayer.UpdatedBy != player.ID {
+		t.Errorf("player save = %+v, want a new revision", byPlayer)
+	}
+	if _, err := SaveSessionNote(db, game.ID, models.NoteKindRecap, gm.ID, "Overwrite", merged.RevisionID); err != ErrNoteConflict {
+		t.Errorf("saving from a stale revision: error = %v, want ErrNoteConflict", err)
+	}
+
+	// Once the merge window has passed, the same author gets a new revision too.
+	if _, err := db.Exec("UPDATE session_note_revisions SET created_at = ? WHERE id = ?", time.Now().Add(-2*models.NoteRevisionMergeWindow), byPlayer.RevisionID); err != nil {
+		t.Fatalf("ageing revision: %v", err)
+	}
+	later, err := SaveSessionNote(db, game.ID, models.NoteKindRecap, player.ID, "Final recap.", byPlayer.RevisionID)
+	if err != nil {
+		t.Fatalf("SaveSessionNote() after window error = %v", err)
+	}
+	if later.RevisionID == byPlayer.RevisionID {
+		t.Errorf("save after merge window reused revision %d", later.RevisionID)
+	}
+	if unchanged, err := SaveSessionNote(db, game.ID, models.NoteKindRecap, gm.ID, "Final recap.", later.RevisionID); err != nil || unchanged.RevisionID != later.RevisionID {
+		t.Errorf("unchanged save = %+v (err %v), want no new revision", unchanged, err)
+	}
+
+	revisions, err := GetSessionNoteRevisions(db, note.ID)
+	if err != nil {
+		t.Fatalf("GetSessionNoteRevisions() error = %v", err)
+	}
+	wantContent := []string{"Final recap.", "We met in a tavern. It burned down. Not my fault.", "We met in a tavern. It burned down."}
+	if len(revisions) != len(wantContent) {
+		t.Fatalf("revisions = %d, want %d", len(revisions), len(wantContent))
+	}
+	for i, want := range wantContent {
+		if revisions[i].Content != want {
+			t.Errorf("revision %d content = %q, want %q", i, revisions[i].Content, want)
+		}
+	}
+	if revisions[2].AuthorID != gm.ID || revisions[2].AuthorName != "notes_gm@example.com" {
+		t.Errorf("oldest revision author = %d %q, want the GM", revisions[2].AuthorID, revisions[2].AuthorName)
+	}
+
+	// Prep notes are a separate note on the same game.
+	if _, err := SaveSessionNote(db, game.ID, models.NoteKindGMPrep, gm.ID, "The innkeeper is a vampire.", 0); err != nil {
+		t.Fatalf("SaveSessionNote() prep error = %v", err)
+	}
+	recaps, err := GetCampaignRecaps(db, campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignRecaps() error = %v", err)
+	}
+	if len(recaps) != 1 || recaps[game.ID].Content != "Final recap." {
+		t.Errorf("campaign recaps = %v, want only the recap", recaps)
+	}
+}
+
+func TestIsGameParticipant(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+
+	gm, _ := CreateUser(db, "participant_gm@example.com", "password")
+	attending, _ := CreateUser(db, "participant_a@example.com", "password")
+	noShow, _ := CreateUser(db, "participant_b@example.com", "password")
+	walkIn, _ := CreateUser(db, "participant_c@example.com", "password")
+	maybe, _ := CreateUser(db, "participant_d@example.com", "password")
+	game, _ := CreateGame(db, &models.Game{GMID: gm.ID, Title: "Who Played", GameDateTime: time.Now().Add(-time.Hour), Location: "Table"})
+
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: attending.ID, Status: models.RSVPStatusAttending})
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: noShow.ID, Status: models.RSVPStatusAttending})
+	CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: maybe.ID, Status: models.RSVPStatusMaybe})
+	SetAttendance(db, game.ID, gm.ID, map[int64]string{noShow.ID: models.AttendanceNoShow, walkIn.ID: models.AttendanceLate})
+
+	tests := []struct {
+		name string
+		user *models.User
+		want bool
+	}{
+		{"GM", gm, true},
+		{"RSVP'd attending, not recorded", attending, true},
+		{"RSVP'd attending, recorded no-show", noShow, false},
+		{"recorded late without RSVP", walkIn, true},
+		{"RSVP'd maybe", maybe, false},
+	}
+	for _, tt := range tests {
+		got, err := IsGameParticipant(db, game.ID, tt.user.ID)
+		if err != nil {
+			t.Fatalf("%s: IsGameParticipant() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: IsGameParticipant() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
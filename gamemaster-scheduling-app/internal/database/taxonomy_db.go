@@ -0,0 +1,163 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// ErrGameSystemExists is returned by AddGameSystem when a system has the name already.
+var ErrGameSystemExists = errors.New("game system already exists")
+
+// ErrGameSystemInUse is returned by DeleteGameSystem while games still use the system.
+var ErrGameSystemInUse = errors.New("game system is used by games")
+
+// GetGameSystems retrieves every game system by name, with how many games use each.
+func GetGameSystems(db *sql.DB) ([]*models.GameSystem, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.name, (SELECT COUNT(*) FROM games g WHERE g.system_id = s.id), s.created_at
+		FROM game_systems s
+		ORDER BY s.name COLLATE NOCASE
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var systems []*models.GameSystem
+	for rows.Next() {
+		s := &models.GameSystem{}
+		if err := rows.Scan(&s.ID, &s.Name, &s.GameCount, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		systems = append(systems, s)
+	}
+	return systems, rows.Err()
+}
+
+// GetGameSystemByID retrieves a game system. It returns sql.ErrNoRows if there is none.
+func GetGameSystemByID(db *sql.DB, id int64) (*models.GameSystem, error) {
+	s := &models.GameSystem{}
+	err := db.QueryRow("SELECT id, name, created_at FROM game_systems WHERE id = ?", id).Scan(&s.ID, &s.Name, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AddGameSystem adds a game system on behalf of a site admin and returns its ID.
+// Names are unique ignoring case; a duplicate returns ErrGameSystemExists.
+func AddGameSystem(db *sql.DB, adminID int64, name string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO game_systems (name) VALUES (?) ON CONFLICT DO NOTHING", name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrGameSystemExists
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := insertAuditEntry(tx, adminID, models.AuditAddGameSystem, models.AuditTargetGameSystem, id, name); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// DeleteGameSystem removes a game system on behalf of a site admin, along with
+// players' preferences for it; LFG posts asking for it then take any system.
+// It returns ErrGameSystemInUse if any game uses it, and sql.ErrNoRows if it
+// doesn't exist.
+func DeleteGameSystem(db *sql.DB, adminID, systemID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var name string
+	if err := tx.QueryRow("SELECT name FROM game_systems WHERE id = ?", systemID).Scan(&name); err != nil {
+		return err
+	}
+	var inUse bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM games WHERE system_id = ?)", systemID).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse {
+		return ErrGameSystemInUse
+	}
+	if _, err := tx.Exec("DELETE FROM user_game_systems WHERE system_id = ?", systemID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE lfg_posts SET system_id = NULL WHERE system_id = ?", systemID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM game_systems WHERE id = ?", systemID); err != nil {
+		return err
+	}
+	if err := insertAuditEntry(tx, adminID, models.AuditDeleteGameSystem, models.AuditTargetGameSystem, systemID, name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPreferredSystems retrieves the game systems a user likes to play, by name.
+func GetPreferredSystems(db *sql.DB, userID int64) ([]*models.GameSystem, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.name, s.created_at
+		FROM user_game_systems p JOIN game_systems s ON s.id = p.system_id
+		WHERE p.user_id = ?
+		ORDER BY s.name COLLATE NOCASE
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var systems []*models.GameSystem
+	for rows.Next() {
+		s := &models.GameSystem{}
+		if err := rows.Scan(&s.ID, &s.Name, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		systems = append(systems, s)
+	}
+	return systems, rows.Err()
+}
+
+// SetPreferredSystems replaces the game systems a user likes to play. IDs that
+// aren't game systems are ignored.
+func SetPreferredSystems(db *sql.DB, userID int64, systemIDs []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_game_systems WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, id := range systemIDs {
+		_, err := tx.Exec(`
+			INSERT INTO user_game_systems (user_id, system_id)
+			SELECT ?, id FROM game_systems WHERE id = ?
+			ON CONFLICT DO NOTHING
+		`, userID, id)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
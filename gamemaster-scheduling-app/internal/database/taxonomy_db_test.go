@@ -0,0 +1,116 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestGameSystemsAndFilters(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	admin := createTestUserForRSVPs(t, db, "taxonomy_admin@example.com", "password")
+	gm := createTestUserForRSVPs(t, db, "taxonomy_gm@example.com", "password")
+	player := createTestUserForRSVPs(t, db, "taxonomy_player@example.com", "password")
+
+	// A fresh database comes with the popular systems.
+	systems, err := GetGameSystems(db)
+	if err != nil || len(systems) == 0 {
+		t.Fatalf("GetGameSystems() = %v, %v; want the seeded systems", systems, err)
+	}
+	byName := make(map[string]int64)
+	for _, s := range systems {
+		byName[s.Name] = s.ID
+	}
+	dnd, coc := byName["D&D 5e"], byName["Call of Cthulhu"]
+	if dnd == 0 || coc == 0 {
+		t.Fatalf("seeded systems = %v, want D&D 5e and Call of Cthulhu", byName)
+	}
+
+	dw, err := AddGameSystem(db, admin.ID, "Dungeon World")
+	if err != nil {
+		t.Fatalf("AddGameSystem() error = %v", err)
+	}
+	if _, err := AddGameSystem(db, admin.ID, "dungeon world"); err != ErrGameSystemExists {
+		t.Errorf("adding a duplicate ignoring case error = %v, want ErrGameSystemExists", err)
+	}
+
+	newGame := func(title string, systemID int64, experience, format, mode string) *models.Game {
+		t.Helper()
+		g, err := CreateGame(db, &models.Game{
+			GMID: gm.ID, Title: title, GameDateTime: time.Now().Add(24 * time.Hour), Location: "Test Location",
+			SystemID: systemID, ExperienceLevel: experience, Format: format, PlayMode: mode,
+		})
+		if err != nil {
+			t.Fatalf("CreateGame(%s) error = %v", title, err)
+		}
+		return g
+	}
+	intro := newGame("Intro to 5e", dnd, models.ExperienceNewPlayerFriendly, models.FormatOneShot, models.PlayModeInPerson)
+	horror := newGame("Masks", coc, models.ExperienceExperienced, models.FormatCampaign, models.PlayModeOnline)
+	newGame("Mystery Game", 0, "", "", "")
+	if intro.SystemName != "D&D 5e" || intro.Format != models.FormatOneShot || intro.PlayMode != models.PlayModeInPerson {
+		t.Errorf("CreateGame() = %+v, want the taxonomy back", intro)
+	}
+
+	titles := func(filter models.GameFilter) []string {
+		t.Helper()
+		games, err := FilterGames(db, filter)
+		if err != nil {
+			t.Fatalf("FilterGames(%+v) error = %v", filter, err)
+		}
+		var out []string
+		for _, g := range games {
+			out = append(out, g.Title)
+		}
+		return out
+	}
+	if got := titles(models.GameFilter{}); len(got) != 3 {
+		t.Errorf("unfiltered games = %v, want all 3", got)
+	}
+	if got := titles(models.GameFilter{SystemID: coc}); len(got) != 1 || got[0] != horror.Title {
+		t.Errorf("Call of Cthulhu games = %v", got)
+	}
+	if got := titles(models.GameFilter{ExperienceLevel: models.ExperienceNewPlayerFriendly, PlayMode: models.PlayModeInPerson}); len(got) != 1 || got[0] != intro.Title {
+		t.Errorf("new-player friendly in person games = %v", got)
+	}
+	if got := titles(models.GameFilter{Format: models.FormatCampaign, PlayMode: models.PlayModeInPerson}); len(got) != 0 {
+		t.Errorf("in person campaigns = %v, want none", got)
+	}
+
+	// Preferred systems narrow the list down to matching games.
+	if got := titles(models.GameFilter{PreferredBy: player.ID}); len(got) != 0 {
+		t.Errorf("games in no preferred systems = %v, want none", got)
+	}
+	if err := SetPreferredSystems(db, player.ID, []int64{coc, dw, 9999}); err != nil {
+		t.Fatalf("SetPreferredSystems() error = %v", err)
+	}
+	preferred, err := GetPreferredSystems(db, player.ID)
+	if err != nil || len(preferred) != 2 || preferred[0].Name != "Call of Cthulhu" || preferred[1].Name != "Dungeon World" {
+		t.Errorf("GetPreferredSystems() = %v, %v; want Call of Cthulhu and Dungeon World", preferred, err)
+	}
+	if got := titles(models.GameFilter{PreferredBy: player.ID}); len(got) != 1 || got[0] != horror.Title {
+		t.Errorf("games in preferred systems = %v", got)
+	}
+
+	// Systems in use can't be removed; unused ones go, with players' picks.
+	if err := DeleteGameSystem(db, admin.ID, coc); err != ErrGameSystemInUse {
+		t.Errorf("deleting a system in use error = %v, want ErrGameSystemInUse", err)
+	}
+	if err := DeleteGameSystem(db, admin.ID, dw); err != nil {
+		t.Fatalf("DeleteGameSystem() error = %v", err)
+	}
+	if err := DeleteGameSystem(db, admin.ID, dw); err != sql.ErrNoRows {
+		t.Errorf("deleting twice error = %v, want sql.ErrNoRows", err)
+	}
+	if preferred, _ := GetPreferredSystems(db, player.ID); len(preferred) != 1 {
+		t.Errorf("preferred systems after deletion = %v, want only Call of Cthulhu", preferred)
+	}
+	entries, _ := GetAuditLog(db, 10)
+	if len(entries) != 2 || entries[0].Action != models.AuditDeleteGameSystem || entries[1].Details != "Dungeon World" {
+		t.Errorf("audit log = %v, want the addition and removal", entries)
+	}
+}
@@ -2,7 +2,8 @@ package database
 
 import (
 	"database/sql"
-	"time"
+	"errors"
+	"strings"
 
 	"github.com/gamemaster-scheduling/app/internal/models"
 	"golang.org/x/crypto/bcrypt"
@@ -37,26 +38,111 @@ func CreateUser(db *sql.DB, email string, password string) (*models.User, error)
 	return GetUserByID(db, id)
 }
 
-// GetUserByEmail retrieves a user by their email address.
-func GetUserByEmail(db *sql.DB, email string) (*models.User, error) {
+// userColumns are the users columns read by scanUser.
+const userColumns = "id, email, username, password_hash, role, suspended_at, suspended_reason, timezone, created_at"
+
+// ErrUsernameTaken is returned by SetUsername when another user has the username
+// (compared ignoring case).
+var ErrUsernameTaken = errors.New("username is already taken")
+
+// scanUser scans a row selected with userColumns.
+func scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-	row := db.QueryRow("SELECT id, email, password_hash, created_at FROM users WHERE email = ?", email)
-	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	var username, suspendedReason, timezone sql.NullString
+	var suspendedAt sql.NullTime
+	err := row.Scan(&user.ID, &user.Email, &username, &user.PasswordHash, &user.Role, &suspendedAt, &suspendedReason, &timezone, &user.CreatedAt)
 	if err != nil {
 		return nil, err // This will include sql.ErrNoRows if not found
 	}
+	user.Username = username.String
+	user.SuspendedAt, user.SuspendedReason = suspendedAt.Time, suspendedReason.String
+	user.Timezone = timezone.String
 	return user, nil
 }
 
+// GetUserByEmail retrieves a user by their email address.
+func GetUserByEmail(db *sql.DB, email string) (*models.User, error) {
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE email = ?", email))
+}
+
 // GetUserByID retrieves a user by their ID.
 func GetUserByID(db *sql.DB, id int64) (*models.User, error) {
-	user := &models.User{}
-	row := db.QueryRow("SELECT id, email, password_hash, created_at FROM users WHERE id = ?", id)
-	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
+
+// GetUserByUsername retrieves a user by their username, ignoring case.
+func GetUserByUsername(db *sql.DB, username string) (*models.User, error) {
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE username = ? COLLATE NOCASE", username))
+}
+
+// GetUsersByUsernames retrieves the users with any of the given usernames, ignoring case.
+// Usernames that don't exist are skipped.
+func GetUsersByUsernames(db *sql.DB, usernames []string) ([]*models.User, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(usernames)), ", ")
+	args := make([]interface{}, len(usernames))
+	for i, name := range usernames {
+		args[i] = name
+	}
+	return queryUsers(db, "SELECT "+userColumns+" FROM users WHERE username COLLATE NOCASE IN ("+placeholders+") ORDER BY id", args...)
+}
+
+// SearchUsers finds users whose email or username contains query (ignoring case),
+// newest first. An empty query lists the newest users.
+func SearchUsers(db *sql.DB, query string, limit int) ([]*models.User, error) {
+	return queryUsers(db, "SELECT "+userColumns+` FROM users
+		WHERE email LIKE '%' || ? || '%' OR username LIKE '%' || ? || '%'
+		ORDER BY created_at DESC, id DESC LIMIT ?`, query, query, limit)
+}
+
+// queryUsers runs a query selecting userColumns and scans every row.
+func queryUsers(db *sql.DB, query string, args ...interface{}) ([]*models.User, error) {
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, err // This will include sql.ErrNoRows if not found
+		return nil, err
 	}
-	return user, nil
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// SetUsername sets or, if username is empty, clears a user's username.
+// Validation (models.ValidUsername) is the caller's responsibility.
+// Returns ErrUsernameTaken if another user already has it.
+func SetUsername(db *sql.DB, userID int64, username string) error {
+	if username == "" {
+		_, err := db.Exec("UPDATE users SET username = NULL WHERE id = ?", userID)
+		return err
+	}
+	existing, err := GetUserByUsername(db, username)
+	if err == nil && existing.ID != userID {
+		return ErrUsernameTaken
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	_, err = db.Exec("UPDATE users SET username = ? WHERE id = ?", username, userID)
+	return err
+}
+
+// SetTimezone sets or, if timezone is empty, clears a user's timezone.
+// Validation (time.LoadLocation) is the caller's responsibility.
+func SetTimezone(db *sql.DB, userID int64, timezone string) error {
+	_, err := db.Exec("UPDATE users SET timezone = ? WHERE id = ?", nullIfEmpty(timezone), userID)
+	return err
 }
 
 // VerifyPassword compares a stored hashed password with a plaintext password.
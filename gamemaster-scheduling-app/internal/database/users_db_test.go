@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"reflect"
 	"testing"
-	"time"
 
 	"github.com/gamemaster-scheduling/app/internal/models"
 	// Ensure sqlite3 driver is registered
@@ -139,3 +138,41 @@ func createTestUser(t *testing.T, db *sql.DB, email, password string) *models.Us
 	}
 	return user
 }
+
+func TestUsernames(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+
+	alice, _ := CreateUser(db, "alice_u@example.com", "password")
+	bob, _ := CreateUser(db, "bob_u@example.com", "password")
+
+	if err := SetUsername(db, alice.ID, "Alice"); err != nil {
+		t.Fatalf("SetUsername() error = %v", err)
+	}
+	if err := SetUsername(db, alice.ID, "Alice"); err != nil {
+		t.Errorf("SetUsername() to own username error = %v", err)
+	}
+	if err := SetUsername(db, bob.ID, "alice"); err != ErrUsernameTaken {
+		t.Errorf("SetUsername() with taken username (other case) error = %v, want ErrUsernameTaken", err)
+	}
+
+	got, err := GetUserByUsername(db, "ALICE")
+	if err != nil || got.ID != alice.ID {
+		t.Fatalf("GetUserByUsername() = %+v, %v; want alice", got, err)
+	}
+	users, err := GetUsersByUsernames(db, []string{"alice", "nobody"})
+	if err != nil || len(users) != 1 || users[0].ID != alice.ID {
+		t.Errorf("GetUsersByUsernames() = %+v, %v; want only alice", users, err)
+	}
+
+	if err := SetUsername(db, alice.ID, ""); err != nil {
+		t.Fatalf("SetUsername(\"\") error = %v", err)
+	}
+	if got, _ := GetUserByID(db, alice.ID); got.Username != "" || got.DisplayName() != "alice_u@example.com" {
+		t.Errorf("after clearing, Username = %q, DisplayName = %q", got.Username, got.DisplayName())
+	}
+	// Cleared usernames are free again, and several users may have none.
+	if err := SetUsername(db, bob.ID, "alice"); err != nil {
+		t.Errorf("SetUsername() with freed username error = %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// venueSelect selects the columns read by scanVenue.
+const venueSelect = "SELECT id, owner_id, name, address, capacity, accessibility, house_rules, host_contact, created_at FROM venues"
+
+// scanVenue scans a row selected with venueSelect.
+func scanVenue(row rowScanner) (*models.Venue, error) {
+	v := &models.Venue{}
+	var capacity sql.NullInt64
+	var accessibility, houseRules, hostContact sql.NullString
+	if err := row.Scan(&v.ID, &v.OwnerID, &v.Name, &v.Address, &capacity, &accessibility, &houseRules, &hostContact, &v.CreatedAt); err != nil {
+		return nil, err
+	}
+	v.Capacity = int(capacity.Int64)
+	v.Accessibility, v.HouseRules, v.HostContact = accessibility.String, houseRules.String, hostContact.String
+	return v, nil
+}
+
+// CreateVenue saves a new venue and sets its ID.
+func CreateVenue(db *sql.DB, v *models.Venue) error {
+	res, err := db.Exec("INSERT INTO venues (owner_id, name, address, capacity, accessibility, house_rules, host_contact) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		v.OwnerID, v.Name, v.Address, nullIfZero(v.Capacity), nullIfEmpty(v.Accessibility), nullIfEmpty(v.HouseRules), nullIfEmpty(v.HostContact))
+	if err != nil {
+		return err
+	}
+	v.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateVenue saves the details of an existing venue. Its owner doesn't change.
+func UpdateVenue(db *sql.DB, v *models.Venue) error {
+	_, err := db.Exec("UPDATE venues SET name = ?, address = ?, capacity = ?, accessibility = ?, house_rules = ?, host_contact = ? WHERE id = ?",
+		v.Name, v.Address, nullIfZero(v.Capacity), nullIfEmpty(v.Accessibility), nullIfEmpty(v.HouseRules), nullIfEmpty(v.HostContact), v.ID)
+	return err
+}
+
+// GetVenueByID retrieves a venue by its ID.
+func GetVenueByID(db *sql.DB, id int64) (*models.Venue, error) {
+	return scanVenue(db.QueryRow(venueSelect+" WHERE id = ?", id)) // sql.ErrNoRows if not found
+}
+
+// GetVenues retrieves every venue, by name.
+func GetVenues(db *sql.DB) ([]*models.Venue, error) {
+	rows, err := db.Query(venueSelect + " ORDER BY name COLLATE NOCASE, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var venues []*models.Venue
+	for rows.Next() {
+		v, err := scanVenue(rows)
+		if err != nil {
+			return nil, err
+		}
+		venues = append(venues, v)
+	}
+	return venues, rows.Err()
+}
+
+// GetUpcomingGamesAtVenue retrieves the games at a venue starting at or after
+// now, soonest first.
+func GetUpcomingGamesAtVenue(db *sql.DB, venueID int64, now time.Time) ([]*models.Game, error) {
+	return queryGames(db, "SELECT "+gameColumns+" FROM games WHERE venue_id = ? AND julianday(game_datetime) >= julianday(?) ORDER BY julianday(game_datetime) ASC, id ASC",
+		venueID, now.UTC())
+}
+
+// virtualTableSelect selects the columns read by scanVirtualTable.
+const virtualTableSelect = "SELECT id, owner_id, name, vtt_url, voice_url, notes, created_at FROM virtual_tables"
+
+// scanVirtualTable scans a row selected with virtualTableSelect.
+func scanVirtualTable(row rowScanner) (*models.VirtualTable, error) {
+	t := &models.VirtualTable{}
+	var vttURL, voiceURL, notes sql.NullString
+	if err := row.Scan(&t.ID, &t.OwnerID, &t.Name, &vttURL, &voiceURL, &notes, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	t.VTTURL, t.VoiceURL, t.Notes = vttURL.String, voiceURL.String, notes.String
+	return t, nil
+}
+
+// CreateVirtualTable saves a new virtual table and sets its ID.
+func CreateVirtualTable(db *sql.DB, t *models.VirtualTable) error {
+	res, err := db.Exec("INSERT INTO virtual_tables (owner_id, name, vtt_url, voice_url, notes) VALUES (?, ?, ?, ?, ?)",
+		t.OwnerID, t.Name, nullIfEmpty(t.VTTURL), nullIfEmpty(t.VoiceURL), nullIfEmpty(t.Notes))
+	if err != nil {
+		return err
+	}
+	t.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateVirtualTable saves the details of an existing virtual table. Its owner
+// doesn't change.
+func UpdateVirtualTable(db *sql.DB, t *models.VirtualTable) error {
+	_, err := db.Exec("UPDATE virtual_tables SET name = ?, vtt_url = ?, voice_url = ?, notes = ? WHERE id = ?",
+		t.Name, nullIfEmpty(t.VTTURL), nullIfEmpty(t.VoiceURL), nullIfEmpty(t.Notes), t.ID)
+	return err
+}
+
+// GetVirtualTableByID retrieves a virtual table by its ID.
+func GetVirtualTableByID(db *sql.DB, id int64) (*models.VirtualTable, error) {
+	return scanVirtualTable(db.QueryRow(virtualTableSelect+" WHERE id = ?", id)) // sql.ErrNoRows if not found
+}
+
+// GetVirtualTablesByOwner retrieves a user's virtual tables, by name.
+func GetVirtualTablesByOwner(db *sql.DB, ownerID int64) ([]*models.VirtualTable, error) {
+	rows, err := db.Query(virtualTableSelect+" WHERE owner_id = ? ORDER BY name COLLATE NOCASE, id", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []*models.VirtualTable
+	for rows.Next() {
+		t, err := scanVirtualTable(rows)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
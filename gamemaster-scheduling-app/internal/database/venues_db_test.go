@@ -0,0 +1,81 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestVenuesAndVirtualTables(t *testing.T) {
+	db, teardown := setupTestDBForRSVPs(t)
+	defer teardown()
+
+	host := createTestUserForRSVPs(t, db, "venue_host@example.com", "password")
+	gm := createTestUserForRSVPs(t, db, "venue_gm@example.com", "password")
+
+	venue := &models.Venue{OwnerID: host.ID, Name: "The Dragon's Den", Address: "1 Main St", Capacity: 4, HouseRules: "No dice towers"}
+	if err := CreateVenue(db, venue); err != nil {
+		t.Fatalf("CreateVenue() error = %v", err)
+	}
+	other := &models.Venue{OwnerID: host.ID, Name: "attic", Address: "2 Side St"}
+	if err := CreateVenue(db, other); err != nil {
+		t.Fatalf("CreateVenue() error = %v", err)
+	}
+	venue.Capacity, venue.Accessibility = 6, "Step-free entrance"
+	if err := UpdateVenue(db, venue); err != nil {
+		t.Fatalf("UpdateVenue() error = %v", err)
+	}
+	got, err := GetVenueByID(db, venue.ID)
+	if err != nil || got.Capacity != 6 || got.Accessibility != "Step-free entrance" || got.HouseRules != "No dice towers" || got.HostContact != "" {
+		t.Errorf("GetVenueByID() = %+v, %v; want the updated venue", got, err)
+	}
+	if venues, _ := GetVenues(db); len(venues) != 2 || venues[0].ID != other.ID {
+		t.Errorf("GetVenues() = %v, want the attic first", venues)
+	}
+
+	now := time.Date(2030, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := createGameAtVenue(t, db, gm.ID, "Last Week", now.AddDate(0, 0, -7), venue.ID, 4)
+	big := createGameAtVenue(t, db, gm.ID, "Big Table", now.AddDate(0, 0, 2), venue.ID, 8)
+	small := createGameAtVenue(t, db, gm.ID, "Small Table", now.AddDate(0, 0, 1), venue.ID, 5)
+	createGameAtVenue(t, db, gm.ID, "Elsewhere", now.AddDate(0, 0, 1), other.ID, 8)
+
+	games, err := GetUpcomingGamesAtVenue(db, venue.ID, now)
+	if err != nil || len(games) != 2 || games[0].ID != small.ID || games[1].ID != big.ID {
+		t.Fatalf("GetUpcomingGamesAtVenue() = %v, %v; want Small Table then Big Table", games, err)
+	}
+	if games[0].OverCapacity() || !games[1].OverCapacity() || games[1].VenueName != "The Dragon's Den" || games[1].VenueCapacity != 6 {
+		t.Errorf("upcoming games = %+v, %+v; want only Big Table over the capacity of 6", games[0], games[1])
+	}
+	if g, _ := GetGameByID(db, past.ID); g.VenueID != venue.ID {
+		t.Errorf("GetGameByID().VenueID = %d, want %d", g.VenueID, venue.ID)
+	}
+
+	table := &models.VirtualTable{OwnerID: gm.ID, Name: "Tuesday Foundry", VTTURL: "https://vtt.example.com/t"}
+	if err := CreateVirtualTable(db, table); err != nil {
+		t.Fatalf("CreateVirtualTable() error = %v", err)
+	}
+	table.VoiceURL = "https://voice.example.com/c"
+	if err := UpdateVirtualTable(db, table); err != nil {
+		t.Fatalf("UpdateVirtualTable() error = %v", err)
+	}
+	if got, err := GetVirtualTableByID(db, table.ID); err != nil || got.VoiceURL != "https://voice.example.com/c" || got.VTTURL != "https://vtt.example.com/t" {
+		t.Errorf("GetVirtualTableByID() = %+v, %v; want both links", got, err)
+	}
+	if tables, _ := GetVirtualTablesByOwner(db, gm.ID); len(tables) != 1 {
+		t.Errorf("GetVirtualTablesByOwner(gm) = %v, want one table", tables)
+	}
+	if tables, _ := GetVirtualTablesByOwner(db, host.ID); len(tables) != 0 {
+		t.Errorf("GetVirtualTablesByOwner(host) = %v, want none", tables)
+	}
+}
+
+func createGameAtVenue(t *testing.T, db *sql.DB, gmID int64, title string, at time.Time, venueID int64, maxPlayers int) *models.Game {
+	t.Helper()
+	game, err := CreateGame(db, &models.Game{GMID: gmID, Title: title, GameDateTime: at, Location: "Venue", VenueID: venueID, MaxPlayers: maxPlayers})
+	if err != nil {
+		t.Fatalf("CreateGame(%s) error = %v", title, err)
+	}
+	return game
+}
@@ -0,0 +1,518 @@
+// Package dice parses and rolls standard tabletop dice notation, e.g. "4d6kh3+2".
+//
+// Supported notation, per dice term:
+//   - NdS       roll N dice with S sides (N defaults to 1, "d%" is d100)
+//   - khX / klX keep the highest / lowest X dice ("kX" is "khX")
+//   - dhX / dlX drop the highest / lowest X dice ("dX" is "dlX")
+//   - !         explode: roll an extra die on the maximum face
+//   - !>X, !<X, !=X, !X  explode on a compare point instead of the maximum
+//   - rX, r<X, r>X  reroll matching dice until they no longer match
+//   - roX, ro<X  reroll matching dice once
+//
+// Terms are combined with + and -, and may be flat numbers. In compare
+// points ">X" means "X or higher" and "<X" means "X or lower".
+package dice
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// Limits that keep a single roll cheap to compute and render.
+const (
+	MaxDice       = 100
+	MaxSides      = 1000
+	MaxTerms      = 20
+	maxExtraDice  = 100 // Extra dice a single term may add by exploding
+	maxRerollRuns = 100 // Rerolls of a single die before giving up
+)
+
+// compare is a compare point such as ">5" used by explode and reroll modifiers.
+type compare struct {
+	op byte // '=', '>' (or higher) or '<' (or lower)
+	n  int
+}
+
+func (c compare) matches(v int) bool {
+	switch c.op {
+	case '>':
+		return v >= c.n
+	case '<':
+		return v <= c.n
+	default:
+		return v == c.n
+	}
+}
+
+func (c compare) String() string {
+	if c.op == '=' {
+		return strconv.Itoa(c.n)
+	}
+	return string(c.op) + strconv.Itoa(c.n)
+}
+
+// term is a single dice group or constant within an expression.
+type term struct {
+	negative bool
+	constant int
+
+	count, sides int
+	keepDrop     string // "", "kh", "kl", "dh" or "dl"
+	keepDropN    int
+	explode      *compare
+	reroll       *compare
+	rerollOnce   bool
+}
+
+func (t term) isConstant() bool {
+	return t.sides == 0
+}
+
+func (t term) String() string {
+	if t.isConstant() {
+		return strconv.Itoa(t.constant)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%dd%d", t.count, t.sides)
+	if t.keepDrop != "" {
+		fmt.Fprintf(&b, "%s%d", t.keepDrop, t.keepDropN)
+	}
+	if t.explode != nil {
+		b.WriteString("!")
+		if !(t.explode.op == '=' && t.explode.n == t.sides) {
+			b.WriteString(t.explode.String())
+		}
+	}
+	if t.reroll != nil {
+		b.WriteString("r")
+		if t.rerollOnce {
+			b.WriteString("o")
+		}
+		b.WriteString(t.reroll.String())
+	}
+	return b.String()
+}
+
+// Expression is a parsed dice expression, ready to be rolled.
+type Expression struct {
+	terms []term
+}
+
+// String returns the normalized notation of the expression, e.g. "2d20kh1+5".
+func (e *Expression) String() string {
+	var b strings.Builder
+	for i, t := range e.terms {
+		if t.negative {
+			b.WriteString("-")
+		} else if i > 0 {
+			b.WriteString("+")
+		}
+		b.WriteString(t.String())
+	}
+	return b.String()
+}
+
+// WithAdvantage rewrites the first single-die term as two dice keeping the
+// highest (advantage) or lowest (disadvantage), so "1d20+5" becomes "2d20kh1+5".
+func (e *Expression) WithAdvantage(advantage bool) error {
+	for i := range e.terms {
+		t := &e.terms[i]
+		if t.isConstant() {
+			continue
+		}
+		if t.count != 1 || t.keepDrop != "" {
+			return fmt.Errorf("advantage and disadvantage only apply to a single die, not %q", t.String())
+		}
+		t.count = 2
+		t.keepDropN = 1
+		if advantage {
+			t.keepDrop = "kh"
+		} else {
+			t.keepDrop = "kl"
+		}
+		return nil
+	}
+	return fmt.Errorf("advantage and disadvantage need a die to roll")
+}
+
+// Parse parses dice notation such as "4d6kh3+2" or "1d20!-1".
+// Whitespace is not allowed inside an expression.
+func Parse(s string) (*Expression, error) {
+	p := &parser{src: strings.ToLower(strings.TrimSpace(s))}
+	if p.src == "" {
+		return nil, fmt.Errorf("empty dice expression")
+	}
+
+	expr := &Expression{}
+	for first := true; !p.done(); first = false {
+		t := term{}
+		switch {
+		case p.accept('+'):
+		case p.accept('-'):
+			t.negative = true
+		case !first:
+			return nil, p.errorf("expected + or -")
+		}
+		if err := p.parseTerm(&t); err != nil {
+			return nil, err
+		}
+		expr.terms = append(expr.terms, t)
+		if len(expr.terms) > MaxTerms {
+			return nil, fmt.Errorf("too many terms (max %d)", MaxTerms)
+		}
+	}
+
+	hasDice := false
+	for _, t := range expr.terms {
+		if !t.isConstant() {
+			hasDice = true
+		}
+	}
+	if !hasDice {
+		return nil, fmt.Errorf("%q does not roll any dice", s)
+	}
+	return expr, nil
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.src) }
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) accept(c byte) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) acceptPrefix(prefix string) bool {
+	if strings.HasPrefix(p.src[p.pos:], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid dice expression %q at position %d: %s", p.src, p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// number reads an unsigned integer. ok is false if no digits are present.
+func (p *parser) number() (n int, ok bool, err error) {
+	start := p.pos
+	for !p.done() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, false, nil
+	}
+	n, err = strconv.Atoi(p.src[start:p.pos])
+	if err != nil || n > 100000 {
+		return 0, false, p.errorf("number too large")
+	}
+	return n, true, nil
+}
+
+// comparePoint reads an optional ">X", "<X", "=X" or "X".
+func (p *parser) comparePoint() (*compare, error) {
+	op, hasOp := byte('='), true
+	switch {
+	case p.accept('>'):
+		op = '>'
+	case p.accept('<'):
+		op = '<'
+	case p.accept('='):
+	default:
+		hasOp = false
+	}
+	n, ok, err := p.number()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if hasOp {
+			return nil, p.errorf("expected a number after compare point")
+		}
+		return nil, nil
+	}
+	return &compare{op: op, n: n}, nil
+}
+
+func (p *parser) parseTerm(t *term) error {
+	count, hasCount, err := p.number()
+	if err != nil {
+		return err
+	}
+	if !p.accept('d') {
+		if !hasCount {
+			return p.errorf("expected a number or dice")
+		}
+		t.constant = count
+		return nil
+	}
+
+	t.count = 1
+	if hasCount {
+		t.count = count
+	}
+	if p.accept('%') {
+		t.sides = 100
+	} else {
+		sides, ok, err := p.number()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return p.errorf("expected number of sides")
+		}
+		t.sides = sides
+	}
+	if t.count < 1 || t.count > MaxDice {
+		return fmt.Errorf("number of dice must be between 1 and %d", MaxDice)
+	}
+	if t.sides < 1 || t.sides > MaxSides {
+		return fmt.Errorf("number of sides must be between 1 and %d", MaxSides)
+	}
+
+	for !p.done() {
+		switch {
+		case p.acceptPrefix("kh"), p.acceptPrefix("kl"), p.acceptPrefix("dh"), p.acceptPrefix("dl"):
+			if err := p.setKeepDrop(t, p.src[p.pos-2:p.pos]); err != nil {
+				return err
+			}
+		case p.accept('k'):
+			if err := p.setKeepDrop(t, "kh"); err != nil {
+				return err
+			}
+		case p.accept('d'):
+			if err := p.setKeepDrop(t, "dl"); err != nil {
+				return err
+			}
+		case p.accept('!'):
+			if t.explode != nil {
+				return p.errorf("only one exploding modifier is allowed")
+			}
+			c, err := p.comparePoint()
+			if err != nil {
+				return err
+			}
+			if c == nil {
+				c = &compare{op: '=', n: t.sides}
+			}
+			if matchesAllFaces(*c, t.sides) {
+				return fmt.Errorf("%q would explode forever", c.String())
+			}
+			t.explode = c
+		case p.accept('r'):
+			if t.reroll != nil {
+				return p.errorf("only one reroll modifier is allowed")
+			}
+			t.rerollOnce = p.accept('o')
+			c, err := p.comparePoint()
+			if err != nil {
+				return err
+			}
+			if c == nil {
+				return p.errorf("reroll needs a value, e.g. r1")
+			}
+			if matchesAllFaces(*c, t.sides) {
+				return fmt.Errorf("reroll %q matches every face of a d%d", c.String(), t.sides)
+			}
+			t.reroll = c
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *parser) setKeepDrop(t *term, mode string) error {
+	if t.keepDrop != "" {
+		return p.errorf("only one keep or drop modifier is allowed")
+	}
+	n, ok, err := p.number()
+	if err != nil {
+		return err
+	}
+	if !ok || n < 1 {
+		return p.errorf("%s needs a positive number of dice", mode)
+	}
+	if mode[0] == 'd' && n >= t.count {
+		return fmt.Errorf("cannot drop %d of %d dice", n, t.count)
+	}
+	t.keepDrop = mode
+	t.keepDropN = n
+	return nil
+}
+
+func matchesAllFaces(c compare, sides int) bool {
+	for v := 1; v <= sides; v++ {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Roller rolls parsed expressions. It is safe for concurrent use.
+// Two rollers created with the same seed produce the same sequence of results.
+type Roller struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewRoller returns a Roller seeded with seed.
+func NewRoller(seed int64) *Roller {
+	return &Roller{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Roll rolls expr and returns the full result with every die.
+// label is an optional name for the roll, e.g. "Stealth".
+func (r *Roller) Roll(expr *Expression, label string) *models.DiceRoll {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	roll := &models.DiceRoll{Expression: expr.String(), Label: label}
+	for _, t := range expr.terms {
+		group := r.rollTerm(t)
+		if group.Negative {
+			roll.Total -= group.Subtotal
+		} else {
+			roll.Total += group.Subtotal
+		}
+		roll.Groups = append(roll.Groups, group)
+	}
+	return roll
+}
+
+func (r *Roller) face(sides int) int {
+	return r.rng.Intn(sides) + 1
+}
+
+func (r *Roller) rollDie(t term) models.Die {
+	die := models.Die{Sides: t.sides, Value: r.face(t.sides)}
+	if t.reroll == nil {
+		return die
+	}
+	for runs := 0; t.reroll.matches(die.Value) && runs < maxRerollRuns; runs++ {
+		die.Rerolls = append(die.Rerolls, die.Value)
+		die.Value = r.face(t.sides)
+		if t.rerollOnce {
+			break
+		}
+	}
+	return die
+}
+
+func (r *Roller) rollTerm(t term) models.DiceGroup {
+	group := models.DiceGroup{Notation: t.String(), Negative: t.negative}
+	if t.isConstant() {
+		group.Constant = true
+		group.Subtotal = t.constant
+		return group
+	}
+
+	extra := 0
+	for i := 0; i < t.count; i++ {
+		die := r.rollDie(t)
+		group.Dice = append(group.Dice, die)
+		for t.explode != nil && t.explode.matches(die.Value) && extra < maxExtraDice {
+			group.Dice[len(group.Dice)-1].Exploded = true
+			die = models.Die{Sides: t.sides, Value: r.face(t.sides)}
+			group.Dice = append(group.Dice, die)
+			extra++
+		}
+	}
+
+	if t.keepDrop != "" {
+		markDropped(group.Dice, t.keepDrop, t.keepDropN)
+	}
+	for _, d := range group.Dice {
+		if !d.Dropped {
+			group.Subtotal += d.Value
+		}
+	}
+	return group
+}
+
+// markDropped flags the dice excluded by a keep/drop modifier. Ties are broken
+// by roll order so results are stable.
+func markDropped(dice []models.Die, mode string, n int) {
+	order := make([]int, len(dice))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return dice[order[a]].Value < dice[order[b]].Value
+	})
+
+	// Work out how many of the lowest dice to drop.
+	var dropLow, dropHigh int
+	switch mode {
+	case "kh":
+		dropLow = len(dice) - n
+	case "kl":
+		dropHigh = len(dice) - n
+	case "dl":
+		dropLow = n
+	case "dh":
+		dropHigh = n
+	}
+	for i := 0; i < dropLow && i < len(order); i++ {
+		dice[order[i]].Dropped = true
+	}
+	for i := 0; i < dropHigh && i < len(order); i++ {
+		dice[order[len(order)-1-i]].Dropped = true
+	}
+}
+
+// ParseRollArgs parses the arguments of a /roll command:
+//
+//	/roll 4d6kh3+2
+//	/roll 1d20 adv
+//	/roll 1d20+5 Stealth check
+//
+// The first word is the expression, an optional "adv"/"dis" follows, and any
+// remaining text names the roll.
+func ParseRollArgs(args string) (*Expression, string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return nil, "", fmt.Errorf("usage: /roll <dice> [adv|dis] [name], e.g. /roll 1d20+5 Stealth")
+	}
+	expr, err := Parse(fields[0])
+	if err != nil {
+		return nil, "", err
+	}
+	rest := fields[1:]
+	if len(rest) > 0 {
+		switch strings.ToLower(rest[0]) {
+		case "adv", "advantage":
+			err = expr.WithAdvantage(true)
+			rest = rest[1:]
+		case "dis", "disadv", "disadvantage":
+			err = expr.WithAdvantage(false)
+			rest = rest[1:]
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return expr, strings.Join(rest, " "), nil
+}
@@ -0,0 +1,195 @@
+package dice
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNormalizesNotation(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"4d6kh3+2", "4d6kh3+2"},
+		{"d20", "1d20"},
+		{"1D20-1", "1d20-1"},
+		{"4d6k3", "4d6kh3"},
+		{"4d6d1", "4d6dl1"},
+		{"2d20kl1", "2d20kl1"},
+		{"3d6!", "3d6!"},
+		{"3d6!>5", "3d6!>5"},
+		{"2d10r1", "2d10r1"},
+		{"2d10ro<2", "2d10ro<2"},
+		{"d%+5", "1d100+5"},
+		{"1d8+1d6+3", "1d8+1d6+3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if got := expr.String(); got != tt.want {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidNotation(t *testing.T) {
+	inputs := []string{
+		"",
+		"5",          // no dice
+		"d",          // no sides
+		"1d20+",      // dangling operator
+		"1d20 + 5",   // whitespace inside expression
+		"0d6",        // zero dice
+		"101d6",      // too many dice
+		"1d1001",     // too many sides
+		"4d6kh3kl1",  // two keep modifiers
+		"4d6d4",      // drops every die
+		"1d6!>1",     // explodes forever
+		"1d1!",       // explodes forever
+		"1d6r<6",     // rerolls every face
+		"1d6r",       // reroll without value
+		"2d6x",       // unknown modifier
+		"99999999d6", // number overflow
+	}
+	for _, input := range inputs {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) error = nil, want error", input)
+		}
+	}
+}
+
+func TestRollerIsReproducibleWithSeed(t *testing.T) {
+	expr, err := Parse("10d20!+3")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	a := NewRoller(42).Roll(expr, "")
+	b := NewRoller(42).Roll(expr, "")
+	if a.Total != b.Total || len(a.Groups[0].Dice) != len(b.Groups[0].Dice) {
+		t.Fatalf("same seed gave different rolls: %+v vs %+v", a, b)
+	}
+	for i := range a.Groups[0].Dice {
+		if a.Groups[0].Dice[i].Value != b.Groups[0].Dice[i].Value {
+			t.Errorf("die %d = %d vs %d with the same seed", i, a.Groups[0].Dice[i].Value, b.Groups[0].Dice[i].Value)
+		}
+	}
+}
+
+func TestRollKeepHighest(t *testing.T) {
+	expr, _ := Parse("4d6kh3+2")
+	roller := NewRoller(1)
+	for i := 0; i < 200; i++ {
+		roll := roller.Roll(expr, "")
+		dice := roll.Groups[0].Dice
+		if len(dice) != 4 {
+			t.Fatalf("rolled %d dice, want 4", len(dice))
+		}
+		dropped, lowest, kept := 0, 7, 0
+		for _, d := range dice {
+			if d.Value < 1 || d.Value > 6 {
+				t.Fatalf("die value %d out of range", d.Value)
+			}
+			if d.Value < lowest {
+				lowest = d.Value
+			}
+			if d.Dropped {
+				dropped++
+			} else {
+				kept += d.Value
+			}
+		}
+		if dropped != 1 {
+			t.Fatalf("dropped %d dice, want 1", dropped)
+		}
+		for _, d := range dice {
+			if d.Dropped && d.Value != lowest {
+				t.Fatalf("dropped a %d but lowest was %d: %+v", d.Value, lowest, dice)
+			}
+		}
+		if roll.Total != kept+2 || roll.Groups[0].Subtotal != kept {
+			t.Fatalf("total = %d, subtotal = %d, want %d and %d", roll.Total, roll.Groups[0].Subtotal, kept+2, kept)
+		}
+	}
+}
+
+func TestRollExplodingAndRerolls(t *testing.T) {
+	roller := NewRoller(7)
+
+	explode, _ := Parse("20d6!")
+	sawExplosion := false
+	for i := 0; i < 50; i++ {
+		dice := roller.Roll(explode, "").Groups[0].Dice
+		if len(dice) < 20 {
+			t.Fatalf("rolled %d dice, want at least 20", len(dice))
+		}
+		for _, d := range dice {
+			if d.Exploded != (d.Value == 6) {
+				t.Fatalf("die %+v: Exploded should be set exactly on a 6", d)
+			}
+			if d.Exploded {
+				sawExplosion = true
+			}
+		}
+	}
+	if !sawExplosion {
+		t.Errorf("no die exploded across 1000 d6 rolls")
+	}
+
+	reroll, _ := Parse("20d6r<2")
+	for i := 0; i < 50; i++ {
+		for _, d := range roller.Roll(reroll, "").Groups[0].Dice {
+			if d.Value <= 2 {
+				t.Fatalf("die %+v kept a value that should have been rerolled", d)
+			}
+			for _, prev := range d.Rerolls {
+				if prev > 2 {
+					t.Fatalf("die %+v recorded a reroll of %d", d, prev)
+				}
+			}
+		}
+	}
+
+	once, _ := Parse("20d6ro1")
+	for i := 0; i < 50; i++ {
+		for _, d := range roller.Roll(once, "").Groups[0].Dice {
+			if len(d.Rerolls) > 1 {
+				t.Fatalf("die %+v rerolled more than once", d)
+			}
+		}
+	}
+}
+
+func TestParseRollArgs(t *testing.T) {
+	tests := []struct {
+		args      string
+		wantExpr  string
+		wantLabel string
+	}{
+		{"4d6kh3+2", "4d6kh3+2", ""},
+		{"1d20 adv", "2d20kh1", ""},
+		{"1d20+5 dis", "2d20kl1+5", ""},
+		{"1d20+5 Stealth check", "1d20+5", "Stealth check"},
+		{"1d20+3 advantage Perception", "2d20kh1+3", "Perception"},
+	}
+	for _, tt := range tests {
+		expr, label, err := ParseRollArgs(tt.args)
+		if err != nil {
+			t.Errorf("ParseRollArgs(%q) error = %v", tt.args, err)
+			continue
+		}
+		if expr.String() != tt.wantExpr || label != tt.wantLabel {
+			t.Errorf("ParseRollArgs(%q) = %q, %q; want %q, %q", tt.args, expr.String(), label, tt.wantExpr, tt.wantLabel)
+		}
+	}
+
+	if _, _, err := ParseRollArgs("4d6 adv"); err == nil || !strings.Contains(err.Error(), "single die") {
+		t.Errorf("ParseRollArgs(\"4d6 adv\") error = %v, want single die error", err)
+	}
+	if _, _, err := ParseRollArgs("   "); err == nil {
+		t.Errorf("ParseRollArgs(empty) error = nil, want usage error")
+	}
+}
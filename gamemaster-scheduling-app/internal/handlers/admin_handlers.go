@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// adminPageSize is how many rows the admin lists and searches show.
+const adminPageSize = 50
+
+// AdminMiddleware protects the /admin area: it sends anonymous users to the login
+// page and refuses everyone who isn't a site admin.
+func AdminMiddleware(db *sql.DB, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if !currentUser.IsAdmin() {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only site administrators can see this page.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// renderAdminPage renders an admin template with the current user and page title set.
+func renderAdminPage(w http.ResponseWriter, r *http.Request, db *sql.DB, name, title string, data map[string]interface{}) {
+	currentUser, err := GetCurrentUser(r, db)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	data["Title"] = "Admin - " + title
+	data["User"] = currentUser
+	RenderTemplate(w, name, data)
+}
+
+// AdminDashboard shows the open report count and the latest admin actions and login
+// attempts: GET /admin.
+// This handler should be wrapped by AdminMiddleware.
+func AdminDashboard(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, err := database.GetAuditLog(db, 10)
+		if err != nil {
+			fmt.Printf("Error fetching audit log: %v\n", err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the audit log.")
+			return
+		}
+		logins, err := database.GetLoginEvents(db, 0, 10)
+		if err != nil {
+			fmt.Printf("Error fetching login activity: %v\n", err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load login activity.")
+			return
+		}
+		openReports, err := database.CountOpenReports(db)
+		if err != nil {
+			fmt.Printf("Error counting open reports: %v\n", err)
+		}
+		renderAdminPage(w, r, db, "admin/dashboard.html", "Dashboard", map[string]interface{}{
+			"AuditEntries": entries,
+			"LoginEvents":  logins,
+			"OpenReports":  openReports,
+		})
+	}
+}
+
+// AdminUsersPage lists and searches users: GET /admin/users?q=.
+// This handler should be wrapped by AdminMiddleware.
+func AdminUsersPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		users, err := database.SearchUsers(db, query, adminPageSize)
+		if err != nil {
+			fmt.Printf("Error searching users for %q: %v\n", query, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not search users.")
+			return
+		}
+		renderAdminPage(w, r, db, "admin/users.html", "Users", map[string]interface{}{
+			"Query": query,
+			"Users": users,
+		})
+	}
+}
+
+// AdminUserPage shows a user's account, suspension and login activity: GET /admin/users/{id}.
+// This handler should be wrapped by AdminMiddleware.
+func AdminUserPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := pathInt64(r, "/admin/users/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid user ID format.")
+			return
+		}
+		user, err := database.GetUserByID(db, userID)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "User not found.")
+			return
+		}
+		logins, err := database.GetLoginEvents(db, userID, adminPageSize)
+		if err != nil {
+			fmt.Printf("Error fetching login activity for user %d: %v\n", userID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load login activity.")
+			return
+		}
+		renderAdminPage(w, r, db, "admin/user.html", user.Email, map[string]interface{}{
+			"Account":     user,
+			"LoginEvents": logins,
+			"Roles":       []string{models.UserRoleUser, models.UserRoleAdmin},
+		})
+	}
+}
+
+// adminTarget loads the current admin and the numeric ID that follows prefix in the
+// path, and parses the form, writing an error page if any of it fails.
+func adminTarget(w http.ResponseWriter, r *http.Request, db *sql.DB, prefix string) (*models.User, int64, bool) {
+	currentUser, err := GetCurrentUser(r, db)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, 0, false
+	}
+	id, err := pathInt64(r, prefix, 0)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid ID format.")
+		return nil, 0, false
+	}
+	if err := r.ParseForm(); err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Error parsing form data.")
+		return nil, 0, false
+	}
+	return currentUser, id, true
+}
+
+// AdminSetSuspended suspends a user, logging them out everywhere, or lifts the
+// suspension: POST /admin/users/{id}/suspend (with a reason) or /unsuspend.
+// This handler should be wrapped by AdminMiddleware.
+func AdminSetSuspended(db *sql.DB, suspend bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, userID, ok := adminTarget(w, r, db, "/admin/users/")
+		if !ok {
+			return
+		}
+		if userID == admin.ID {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "You cannot suspend your own account.")
+			return
+		}
+		var err error
+		if suspend {
+			err = database.SuspendUser(db, admin.ID, userID, strings.TrimSpace(r.FormValue("reason")))
+		} else {
+			err = database.UnsuspendUser(db, admin.ID, userID)
+		}
+		if err != nil && err != sql.ErrNoRows { // ErrNoRows: already in that state, nothing to do
+			fmt.Printf("Error updating suspension of user %d: %v\n", userID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not update the account.")
+			return
+		}
+		if suspend {
+			SessionStore.DeleteUser(userID)
+		}
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", userID), http.StatusSeeOther)
+	}
+}
+
+// AdminSetUserRole changes a user's site role: POST /admin/users/{id}/role.
+// This handler should be wrapped by AdminMiddleware.
+func AdminSetUserRole(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, userID, ok := adminTarget(w, r, db, "/admin/users/")
+		if !ok {
+			return
+		}
+		role := r.FormValue("role")
+		if role != models.UserRoleUser && role != models.UserRoleAdmin {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid role.")
+			return
+		}
+		if userID == admin.ID {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "You cannot change your own role.")
+			return
+		}
+		if err := database.SetUserRole(db, admin.ID, userID, role); err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Error setting role of user %d: %v\n", userID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not update the account.")
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", userID), http.StatusSeeOther)
+	}
+}
+
+// AdminGamesPage lists and searches games: GET /admin/games?q=.
+// This handler should be wrapped by AdminMiddleware.
+func AdminGamesPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		games, err := database.SearchGames(db, query, adminPageSize)
+		if err != nil {
+			fmt.Printf("Error searching games for %q: %v\n", query, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not search games.")
+			return
+		}
+		renderAdminPage(w, r, db, "admin/games.html", "Games", map[string]interface{}{
+			"Query": query,
+			"Games": games,
+		})
+	}
+}
+
+// AdminCancelGame force-cancels a game and tells its GMs and players:
+// POST /admin/games/{id}/cancel with a reason. This handler should be wrapped by AdminMiddleware.
+func AdminCancelGame(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, gameID, ok := adminTarget(w, r, db, "/admin/games/")
+		if !ok {
+			return
+		}
+		reason := strings.TrimSpace(r.FormValue("reason"))
+		err := database.ForceCancelGame(db, admin.ID, gameID, reason)
+		if err == sql.ErrNoRows {
+			http.Redirect(w, r, "/admin/games", http.StatusSeeOther) // Already cancelled
+			return
+		} else if err != nil {
+			fmt.Printf("Error cancelling game %d: %v\n", gameID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not cancel the game.")
+			return
+		}
+		if err := notifyAdminCancellation(db, gameID, reason); err != nil {
+			fmt.Printf("Error notifying players of cancelled game %d: %v\n", gameID, err)
+		}
+		http.Redirect(w, r, "/admin/games", http.StatusSeeOther)
+	}
+}
+
+// notifyAdminCancellation tells a force-cancelled game's GMs and everyone still
+// interested in it that it won't go ahead.
+func notifyAdminCancellation(db *sql.DB, gameID int64, reason string) error {
+	game, err := database.GetGameByID(db, gameID)
+	if err != nil {
+		return err
+	}
+	rsvps, err := database.GetRSVPsForGame(db, gameID)
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("%s was cancelled by the site administrators", game.Title)
+	if reason != "" {
+		message += ": " + reason
+	}
+	recipients := game.GMIDs()
+	for _, rsvp := range rsvps {
+		switch rsvp.Status {
+		case models.RSVPStatusAttending, models.RSVPStatusMaybe, models.RSVPStatusPending:
+			if !game.IsGM(rsvp.UserID) {
+				recipients = append(recipients, rsvp.UserID)
+			}
+		}
+	}
+	for _, userID := range recipients {
+		_, err := database.CreateNotification(db, &models.Notification{
+			UserID:  userID,
+			Kind:    models.NotificationKindGameDecision,
+			Message: message,
+			Link:    fmt.Sprintf("/games/%d", game.ID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdminSystemsPage lists the game systems players and GMs pick from, with a form
+// to add one: GET /admin/systems. This handler should be wrapped by AdminMiddleware.
+func AdminSystemsPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderAdminSystems(w, r, db, "", "")
+	}
+}
+
+// renderAdminSystems renders the game systems page, re-filling the add form on error.
+func renderAdminSystems(w http.ResponseWriter, r *http.Request, db *sql.DB, name, errMsg string) {
+	systems, err := database.GetGameSystems(db)
+	if err != nil {
+		fmt.Printf("Error fetching game systems: %v\n", err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the game systems.")
+		return
+	}
+	renderAdminPage(w, r, db, "admin/systems.html", "Game systems", map[string]interface{}{
+		"Systems":   systems,
+		"Name":      name,
+		"Error":     errMsg,
+		"MaxLength": models.MaxGameSystemNameLength,
+	})
+}
+
+// AdminAddGameSystem adds a game system: POST /admin/systems with a name.
+// This handler should be wrapped by AdminMiddleware.
+func AdminAddGameSystem(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Error parsing form data.")
+			return
+		}
+		name := strings.Join(strings.Fields(r.FormValue("name")), " ")
+		if name == "" || utf8.RuneCountInString(name) > models.MaxGameSystemNameLength {
+			renderAdminSystems(w, r, db, name, fmt.Sprintf("System names are 1-%d characters.", models.MaxGameSystemNameLength))
+			return
+		}
+		_, err = database.AddGameSystem(db, admin.ID, name)
+		if err == database.ErrGameSystemExists {
+			renderAdminSystems(w, r, db, name, "That game system is already listed.")
+			return
+		} else if err != nil {
+			fmt.Printf("Error adding game system %q: %v\n", name, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not add the game system.")
+			return
+		}
+		http.Redirect(w, r, "/admin/systems", http.StatusSeeOther)
+	}
+}
+
+// AdminDeleteGameSystem removes a game system no game uses: POST /admin/systems/{id}/delete.
+// This handler should be wrapped by AdminMiddleware.
+func AdminDeleteGameSystem(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, systemID, ok := adminTarget(w, r, db, "/admin/systems/")
+		if !ok {
+			return
+		}
+		err := database.DeleteGameSystem(db, admin.ID, systemID)
+		if err == database.ErrGameSystemInUse {
+			renderAdminSystems(w, r, db, "", "Games use that system, so it can't be removed.")
+			return
+		} else if err != nil && err != sql.ErrNoRows { // ErrNoRows: already removed, nothing to do
+			fmt.Printf("Error deleting game system %d: %v\n", systemID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not remove the game system.")
+			return
+		}
+		http.Redirect(w, r, "/admin/systems", http.StatusSeeOther)
+	}
+}
+
+// AdminChatPage searches chat messages across all games: GET /admin/chat?q=.
+// This handler should be wrapped by AdminMiddleware.
+func AdminChatPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		messages, err := database.SearchChatMessages(db, query, adminPageSize)
+		if err != nil {
+			fmt.Printf("Error searching chat for %q: %v\n", query, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not search chat messages.")
+			return
+		}
+		renderAdminPage(w, r, db, "admin/chat.html", "Chat", map[string]interface{}{
+			"Query":    query,
+			"Messages": messages,
+		})
+	}
+}
+
+// AdminDeleteChatMessage deletes any chat message: POST /admin/chat/{id}/delete.
+// This handler should be wrapped by AdminMiddleware.
+func AdminDeleteChatMessage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, messageID, ok := adminTarget(w, r, db, "/admin/chat/")
+		if !ok {
+			return
+		}
+		err := database.AdminDeleteChatMessage(db, admin.ID, messageID)
+		if err != nil && err != sql.ErrNoRows { // ErrNoRows: already deleted, nothing to do
+			fmt.Printf("Error deleting chat message %d: %v\n", messageID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not delete the message.")
+			return
+		}
+		redirect := "/admin/chat"
+		if q := r.FormValue("q"); q != "" {
+			redirect += "?q=" + url.QueryEscape(q)
+		}
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+	}
+}
+
+// AdminLoginsPage lists the latest login attempts across the site: GET /admin/logins.
+// This handler should be wrapped by AdminMiddleware.
+func AdminLoginsPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logins, err := database.GetLoginEvents(db, 0, adminPageSize)
+		if err != nil {
+			fmt.Printf("Error fetching login activity: %v\n", err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load login activity.")
+			return
+		}
+		renderAdminPage(w, r, db, "admin/logins.html", "Login activity", map[string]interface{}{
+			"LoginEvents": logins,
+		})
+	}
+}
+
+// AdminAuditLogPage lists the latest admin actions: GET /admin/audit.
+// This handler should be wrapped by AdminMiddleware.
+func AdminAuditLogPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, err := database.GetAuditLog(db, adminPageSize)
+		if err != nil {
+			fmt.Printf("Error fetching audit log: %v\n", err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the audit log.")
+			return
+		}
+		renderAdminPage(w, r, db, "admin/audit.html", "Audit log", map[string]interface{}{
+			"AuditEntries": entries,
+		})
+	}
+}
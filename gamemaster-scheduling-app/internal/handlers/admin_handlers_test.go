@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+)
+
+func TestAdminPanel(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	adminClient, admin := ts.newUserClient(t, "admin_site@example.com", "adminpass")
+	gmClient, gm := ts.newUserClient(t, "admin_gm@example.com", "password")
+	playerClient, player := ts.newUserClient(t, "admin_player@example.com", "password")
+	if err := database.GrantAdminRole(ts.db, []string{admin.Email}); err != nil {
+		t.Fatalf("GrantAdminRole() error = %v", err)
+	}
+
+	// Only admins get in.
+	if status, _ := getBody(t, playerClient, ts.server.URL+"/admin/users"); status != http.StatusForbidden {
+		t.Errorf("player opening /admin/users status = %d, want %d", status, http.StatusForbidden)
+	}
+	userURL := ts.server.URL + "/admin/users/" + strconv.FormatInt(player.ID, 10)
+	if status, _ := postForm(t, playerClient, userURL+"/suspend", url.Values{}); status != http.StatusForbidden {
+		t.Errorf("player suspending status = %d, want %d", status, http.StatusForbidden)
+	}
+	status, body := getBody(t, adminClient, ts.server.URL+"/admin/users?q=admin_player")
+	if status != http.StatusOK || !strings.Contains(body, "admin_player@example.com") || strings.Contains(body, "admin_gm@example.com") {
+		t.Errorf("user search status = %d, body: %s", status, body)
+	}
+	if _, body := getBody(t, adminClient, ts.server.URL+"/games"); !strings.Contains(body, `href="/admin"`) {
+		t.Errorf("admin nav link missing for an admin")
+	}
+
+	// Suspending ends the user's sessions and blocks logging in again.
+	if status, _ := postForm(t, adminClient, userURL+"/suspend", url.Values{"reason": {"Spamming invites"}}); status != http.StatusSeeOther {
+		t.Errorf("suspend status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if status, _ := getBody(t, playerClient, ts.server.URL+"/characters"); status != http.StatusSeeOther {
+		t.Errorf("suspended user's session still works: status = %d", status)
+	}
+	_, body = postForm(t, playerClient, ts.server.URL+"/login", url.Values{"email": {player.Email}, "password": {"password"}})
+	if !strings.Contains(body, "This account has been suspended") {
+		t.Errorf("suspended user was not refused: %s", body)
+	}
+	// A session that outlives the suspension is refused too, and dropped.
+	SessionStore.Set("surviving-session", player.ID)
+	req, _ := http.NewRequest(http.MethodGet, ts.server.URL+"/characters", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "surviving-session"})
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		t.Fatalf("request with a surviving session: %v", err)
+	}
+	resp.Body.Close()
+	if _, ok := SessionStore.Get("surviving-session"); resp.StatusCode != http.StatusSeeOther || ok {
+		t.Errorf("surviving session status = %d, kept = %v; want a redirect to log in and the session dropped", resp.StatusCode, ok)
+	}
+	_, body = getBody(t, adminClient, userURL)
+	if !strings.Contains(body, "Spamming invites") || !strings.Contains(body, "Failed (Suspended)") {
+		t.Errorf("user page missing suspension or login activity: %s", body)
+	}
+	postForm(t, adminClient, userURL+"/unsuspend", url.Values{})
+	if status, _ := postForm(t, playerClient, ts.server.URL+"/login", url.Values{"email": {player.Email}, "password": {"password"}}); status != http.StatusSeeOther {
+		t.Errorf("login after unsuspending status = %d, want %d", status, http.StatusSeeOther)
+	}
+
+	// Force-cancelling a game tells its players and closes RSVPs.
+	game := ts.createTestGameDirectly(t, gm.ID, "Troubled Table")
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+	postForm(t, playerClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	postForm(t, gmClient, gameURL+"/chat", url.Values{"message_content": {"Something awful"}})
+	if status, _ := postForm(t, adminClient, ts.server.URL+"/admin/games/"+strconv.FormatInt(game.ID, 10)+"/cancel", url.Values{"reason": {"Reported content"}}); status != http.StatusSeeOther {
+		t.Errorf("cancel status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if notes, _ := database.GetNotificationsForUser(ts.db, player.ID, 10); len(notes) == 0 || !strings.Contains(notes[0].Message, "Reported content") {
+		t.Errorf("player was not told about the cancellation: %v", notes)
+	}
+	_, body = getBody(t, playerClient, gameURL)
+	if !strings.Contains(body, "Cancelled by the site administrators: Reported content") {
+		t.Errorf("game page missing the cancellation banner")
+	}
+	_, body = postForm(t, playerClient, gameURL+"/rsvp", url.Values{"status": {"maybe"}})
+	if !strings.Contains(body, "This game has been cancelled.") {
+		t.Errorf("RSVP on a cancelled game was not refused: %s", body)
+	}
+
+	// Chat messages can be found and removed.
+	messages, _ := database.SearchChatMessages(ts.db, "Something awful", 10)
+	if len(messages) != 1 {
+		t.Fatalf("chat search found %d messages, want 1", len(messages))
+	}
+	_, body = getBody(t, adminClient, ts.server.URL+"/admin/chat?q=awful")
+	if !strings.Contains(body, "Something awful") {
+		t.Errorf("admin chat search missing the message: %s", body)
+	}
+	status, _ = postForm(t, adminClient, ts.server.URL+"/admin/chat/"+strconv.FormatInt(messages[0].ID, 10)+"/delete", url.Values{"q": {"awful"}})
+	if status != http.StatusSeeOther {
+		t.Errorf("chat delete status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if messages, _ := database.SearchChatMessages(ts.db, "Something awful", 10); len(messages) != 1 || !messages[0].IsDeleted() {
+		t.Errorf("message was not deleted: %+v", messages)
+	}
+
+	// Every action is in the audit log.
+	_, body = getBody(t, adminClient, ts.server.URL+"/admin/audit")
+	for _, want := range []string{"Suspend User", "Unsuspend User", "Cancel Game", "Delete Chat Message", "Reported content"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("audit log missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+	"github.com/gamemaster-scheduling/app/internal/storage"
+	"github.com/gamemaster-scheduling/app/internal/thumbnail"
+	"github.com/google/uuid"
+)
+
+// canViewAttachment reports whether user may see and download an attachment:
+// the GM always, participants unless it is restricted to specific players.
+func canViewAttachment(db *sql.DB, game *models.Game, a *models.Attachment, user *models.User) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	if game.IsGM(user.ID) {
+		return true, nil
+	}
+	if a != nil && a.Restricted && !a.IsRevealedTo(user.ID) {
+		return false, nil
+	}
+	return database.IsGameParticipant(db, game.ID, user.ID)
+}
+
+// attachmentSectionData builds the template data for _attachments_section.html, with
+// only the attachments user may see. It returns nil if user can see none at all.
+func attachmentSectionData(db *sql.DB, game *models.Game, user *models.User) (map[string]interface{}, error) {
+	ok, err := canViewAttachment(db, game, nil, user)
+	if err != nil || !ok {
+		return nil, err
+	}
+	all, err := database.GetAttachmentsForGame(db, game.ID)
+	if err != nil {
+		return nil, err
+	}
+	isGM := game.IsGM(user.ID)
+	var visible []*models.Attachment
+	for _, a := range all {
+		if isGM || !a.Restricted || a.IsRevealedTo(user.ID) {
+			visible = append(visible, a)
+		}
+	}
+	data := map[string]interface{}{
+		"Game":        game,
+		"Attachments": visible,
+		"IsGM":        isGM,
+		"MaxSize":     fmt.Sprintf("%d MB", models.MaxAttachmentBytes>>20),
+	}
+	if isGM {
+		// The GM picks who to reveal restricted handouts to from the players who RSVP'd.
+		rsvps, err := database.GetRSVPsForGame(db, game.ID)
+		if err != nil {
+			return nil, err
+		}
+		data["Players"] = rsvps
+	}
+	return data, nil
+}
+
+// renderAttachmentSection re-renders the attachments partial after a change, with an
+// optional error message.
+func renderAttachmentSection(w http.ResponseWriter, db *sql.DB, game *models.Game, user *models.User, errMsg string) {
+	data, err := attachmentSectionData(db, game, user)
+	if err != nil || data == nil {
+		fmt.Printf("Error reloading attachments for game %d: %v\n", game.ID, err)
+		http.Error(w, "Failed to refresh attachments.", http.StatusInternalServerError)
+		return
+	}
+	data["Error"] = errMsg
+	RenderTemplate(w, "games/_attachments_section.html", data)
+}
+
+// gameForUser loads the game in the request path and the current user, writing an
+// error response if either is missing.
+func gameForUser(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.Game, *models.User, bool) {
+	currentUser, err := GetCurrentUser(r, db)
+	if err != nil {
+		w.Header().Set("HX-Redirect", "/login")
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	gameID, err := pathInt64(r, "/games/", 0)
+	if err != nil {
+		http.Error(w, "Invalid Game ID format", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	game, err := database.GetGameByID(db, gameID)
+	if err != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	return game, currentUser, true
+}
+
+// gameForGM loads the game in the request path and checks the current user is one of
+// its GMs (the owner or a co-GM), writing an error response if not.
+func gameForGM(w http.ResponseWriter, r *http.Request, db *sql.DB, action string) (*models.Game, *models.User, bool) {
+	game, currentUser, ok := gameForUser(w, r, db)
+	if !ok {
+		return nil, nil, false
+	}
+	if !game.IsGM(currentUser.ID) {
+		http.Error(w, "Only the GM can "+action+" for this game.", http.StatusForbidden)
+		return nil, nil, false
+	}
+	return game, currentUser, true
+}
+
+// attachmentFromPath loads the attachment /games/{id}/attachments/{attachmentID}[/...]
+// and checks it belongs to the game.
+func attachmentFromPath(r *http.Request, db *sql.DB, gameID int64) (*models.Attachment, error) {
+	attachmentID, err := pathInt64(r, "/games/", 2)
+	if err != nil {
+		return nil, err
+	}
+	a, err := database.GetAttachmentByID(db, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.GameID != gameID {
+		return nil, sql.ErrNoRows
+	}
+	return a, nil
+}
+
+// cleanFilename keeps the base name of an uploaded file, without control characters,
+// for display and the download's Content-Disposition.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > 200 {
+		name = name[:200]
+	}
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	return name
+}
+
+// revealForm reads the 'audience' (everyone or selected) and 'reveal_to' user ID fields.
+// Only players who RSVP'd may be picked.
+func revealForm(r *http.Request, db *sql.DB, gameID int64) (restricted bool, userIDs []int64, err error) {
+	if r.FormValue("audience") != "selected" {
+		return false, nil, nil
+	}
+	rsvps, err := database.GetRSVPsForGame(db, gameID)
+	if err != nil {
+		return false, nil, err
+	}
+	players := map[int64]bool{}
+	for _, rsvp := range rsvps {
+		players[rsvp.UserID] = true
+	}
+	for _, v := range r.Form["reveal_to"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && players[id] {
+			userIDs = append(userIDs, id)
+		}
+	}
+	return true, userIDs, nil
+}
+
+// UploadAttachment stores a file the GM shares with a game: POST /games/{id}/attachments
+// as multipart form data with a 'file' field, plus the reveal fields read by revealForm.
+// The type is sniffed from the contents and must be an allowed image, PDF or text file.
+// This handler should be wrapped by AuthMiddleware.
+func UploadAttachment(db *sql.DB, store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "share files")
+		if !ok {
+			return
+		}
+
+		// Leave room for the other form fields and multipart framing.
+		r.Body = http.MaxBytesReader(w, r.Body, models.MaxAttachmentBytes+64<<10)
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				renderAttachmentSection(w, db, game, currentUser, "That file is too large.")
+			} else {
+				renderAttachmentSection(w, db, game, currentUser, "Choose a file to upload.")
+			}
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			renderAttachmentSection(w, db, game, currentUser, "Choose a file to upload.")
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, models.MaxAttachmentBytes+1))
+		if err != nil {
+			http.Error(w, "Error reading upload", http.StatusBadRequest)
+			return
+		}
+		if len(data) > models.MaxAttachmentBytes {
+			renderAttachmentSection(w, db, game, currentUser, "That file is too large.")
+			return
+		}
+		if len(data) == 0 {
+			renderAttachmentSection(w, db, game, currentUser, "That file is empty.")
+			return
+		}
+		contentType := http.DetectContentType(data)
+		if !models.AllowedAttachmentType(contentType) {
+			renderAttachmentSection(w, db, game, currentUser, "Only images (PNG, JPEG, GIF, WebP), PDFs and plain text files can be shared.")
+			return
+		}
+		restricted, revealTo, err := revealForm(r, db, game.ID)
+		if err != nil {
+			fmt.Printf("Error reading players for game %d: %v\n", game.ID, err)
+			http.Error(w, "Failed to upload. Please try again.", http.StatusInternalServerError)
+			return
+		}
+
+		attachment := &models.Attachment{
+			GameID:      game.ID,
+			UploaderID:  currentUser.ID,
+			Filename:    cleanFilename(header.Filename),
+			ContentType: contentType,
+			SizeBytes:   int64(len(data)),
+			StorageKey:  fmt.Sprintf("games/%d/%s", game.ID, uuid.NewString()),
+			Restricted:  restricted,
+			RevealedTo:  revealTo,
+		}
+		if err := store.Put(attachment.StorageKey, bytes.NewReader(data)); err != nil {
+			fmt.Printf("Error storing attachment for game %d: %v\n", game.ID, err)
+			http.Error(w, "Failed to upload. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		if attachment.IsImage() {
+			// WebP and odd images just go without a preview.
+			if thumb, err := thumbnail.Generate(data, models.ThumbnailSize); err == nil {
+				thumbKey := attachment.StorageKey + ".thumb.jpg"
+				if err := store.Put(thumbKey, bytes.NewReader(thumb)); err != nil {
+					fmt.Printf("Error storing thumbnail for game %d: %v\n", game.ID, err)
+				} else {
+					attachment.ThumbnailKey = thumbKey
+				}
+			}
+		}
+		if _, err := database.CreateAttachment(db, attachment); err != nil {
+			fmt.Printf("Error recording attachment for game %d: %v\n", game.ID, err)
+			deleteStoredAttachment(store, attachment)
+			http.Error(w, "Failed to upload. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		renderAttachmentSection(w, db, game, currentUser, "")
+	}
+}
+
+// deleteStoredAttachment removes an attachment's file and thumbnail from storage,
+// logging failures: a leftover file is harmless once its record is gone.
+func deleteStoredAttachment(store storage.Store, a *models.Attachment) {
+	for _, key := range []string{a.StorageKey, a.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := store.Delete(key); err != nil {
+			fmt.Printf("Error deleting stored file %s: %v\n", key, err)
+		}
+	}
+}
+
+// ServeAttachment downloads an attachment, or its thumbnail when thumb is set:
+// GET /games/{id}/attachments/{attachmentID}[/thumbnail]. Attachments the user may
+// not see are reported as not found, so restricted handouts don't leak their existence.
+func ServeAttachment(db *sql.DB, store storage.Store, thumb bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID, err := pathInt64(r, "/games/", 0)
+		if err != nil {
+			http.Error(w, "Invalid Game ID format", http.StatusBadRequest)
+			return
+		}
+		game, err := database.GetGameByID(db, gameID)
+		if err != nil {
+			http.Error(w, "Game not found", http.StatusNotFound)
+			return
+		}
+		attachment, err := attachmentFromPath(r, db, gameID)
+		if err != nil {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		currentUser, _ := GetCurrentUser(r, db) // Nil user is refused below
+		allowed, err := canViewAttachment(db, game, attachment, currentUser)
+		if err != nil {
+			fmt.Printf("Error checking access to attachment %d: %v\n", attachment.ID, err)
+			http.Error(w, "Failed to load attachment.", http.StatusInternalServerError)
+			return
+		}
+		if !allowed || (thumb && attachment.ThumbnailKey == "") {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+
+		key, contentType, disposition := attachment.StorageKey, attachment.ContentType, "attachment"
+		if thumb {
+			key, contentType, disposition = attachment.ThumbnailKey, "image/jpeg", "inline"
+		} else if attachment.IsImage() || contentType == "application/pdf" {
+			disposition = "inline"
+		}
+		file, err := store.Open(key)
+		if err != nil {
+			fmt.Printf("Error opening stored file for attachment %d: %v\n", attachment.ID, err)
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "sandbox") // Uploaded files never run scripts on our origin
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+		if !thumb {
+			w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+		}
+		io.Copy(w, file)
+	}
+}
+
+// UpdateAttachmentReveal changes who may see an attachment:
+// POST /games/{id}/attachments/{attachmentID}/reveal with the fields read by revealForm.
+// Only the GM may do this. This handler should be wrapped by AuthMiddleware.
+func UpdateAttachmentReveal(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "change who sees files")
+		if !ok {
+			return
+		}
+		attachment, err := attachmentFromPath(r, db, game.ID)
+		if err != nil {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+		restricted, revealTo, err := revealForm(r, db, game.ID)
+		if err == nil {
+			err = database.SetAttachmentReveals(db, attachment.ID, restricted, revealTo)
+		}
+		if err != nil {
+			fmt.Printf("Error updating reveals for attachment %d: %v\n", attachment.ID, err)
+			http.Error(w, "Failed to update attachment. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		renderAttachmentSection(w, db, game, currentUser, "")
+	}
+}
+
+// DeleteAttachment removes an attachment and its stored files:
+// POST /games/{id}/attachments/{attachmentID}/delete. Only the GM may do this.
+// This handler should be wrapped by AuthMiddleware.
+func DeleteAttachment(db *sql.DB, store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "remove files")
+		if !ok {
+			return
+		}
+		attachment, err := attachmentFromPath(r, db, game.ID)
+		if err != nil {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		if err := database.DeleteAttachment(db, attachment.ID); err != nil {
+			fmt.Printf("Error deleting attachment %d: %v\n", attachment.ID, err)
+			http.Error(w, "Failed to remove attachment. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		deleteStoredAttachment(store, attachment)
+		renderAttachmentSection(w, db, game, currentUser, "")
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+	"github.com/gamemaster-scheduling/app/internal/storage"
+)
+
+// postFile uploads a file as multipart form data, with extra form fields.
+func postFile(t *testing.T, client *http.Client, rawURL, filename string, content []byte, fields url.Values) (int, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, vs := range fields {
+		for _, v := range vs {
+			mw.WriteField(k, v)
+		}
+	}
+	fw, _ := mw.CreateFormFile("file", filename)
+	fw.Write(content)
+	mw.Close()
+
+	resp, err := client.Post(rawURL, mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("upload to %s failed: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestAttachments(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "files_gm@example.com", "gmpass")
+	aliceClient, alice := ts.newUserClient(t, "files_alice@example.com", "password")
+	bobClient, bob := ts.newUserClient(t, "files_bob@example.com", "password")
+	outsiderClient, _ := ts.newUserClient(t, "files_outsider@example.com", "password")
+
+	game := ts.createTestGameDirectly(t, gm.ID, "Dungeon Crawl")
+	for _, u := range []*models.User{alice, bob} {
+		if err := database.CreateOrUpdateRSVP(ts.db, &models.RSVP{GameID: game.ID, UserID: u.ID, Status: models.RSVPStatusAttending}); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+
+	var mapPNG bytes.Buffer
+	png.Encode(&mapPNG, image.NewGray(image.Rect(0, 0, 600, 300)))
+
+	if status, _ := postFile(t, aliceClient, gameURL+"/attachments", "cheat.txt", []byte("hi"), nil); status != http.StatusForbidden {
+		t.Errorf("player upload status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body := postFile(t, gmClient, gameURL+"/attachments", "evil.html", []byte("<html><script>alert(1)</script></html>"), nil)
+	if !strings.Contains(body, "Only images") {
+		t.Errorf("HTML upload should be refused: %s", body)
+	}
+	_, body = postFile(t, gmClient, gameURL+"/attachments", "huge.txt", bytes.Repeat([]byte("a"), models.MaxAttachmentBytes+1), nil)
+	if !strings.Contains(body, "too large") {
+		t.Errorf("oversized upload should be refused: %s", body)
+	}
+
+	status, body := postFile(t, gmClient, gameURL+"/attachments", "../../World Map.png", mapPNG.Bytes(), url.Values{"audience": {"everyone"}})
+	if status != http.StatusOK || !strings.Contains(body, "World Map.png") || !strings.Contains(body, "/thumbnail") {
+		t.Fatalf("map upload status = %d, body: %s", status, body)
+	}
+	secret := url.Values{"audience": {"selected"}, "reveal_to": {strconv.FormatInt(alice.ID, 10)}}
+	if _, body := postFile(t, gmClient, gameURL+"/attachments", "letter.txt", []byte("The duke is the traitor."), secret); !strings.Contains(body, "Revealed to 1 player(s)") {
+		t.Fatalf("restricted upload missing from GM list: %s", body)
+	}
+
+	attachments, _ := database.GetAttachmentsForGame(ts.db, game.ID)
+	if len(attachments) != 2 {
+		t.Fatalf("attachments = %d, want 2", len(attachments))
+	}
+	letter, worldMap := attachments[0], attachments[1]
+	if worldMap.Filename != "World Map.png" || worldMap.ContentType != "image/png" || worldMap.ThumbnailKey == "" {
+		t.Errorf("map attachment = %+v", worldMap)
+	}
+	letterURL := gameURL + "/attachments/" + strconv.FormatInt(letter.ID, 10)
+	mapURL := gameURL + "/attachments/" + strconv.FormatInt(worldMap.ID, 10)
+
+	resp, err := bobClient.Get(mapURL)
+	if err != nil {
+		t.Fatalf("GET map failed: %v", err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, mapPNG.Bytes()) {
+		t.Errorf("map download status = %d, %d bytes", resp.StatusCode, len(got))
+	}
+	if resp.Header.Get("Content-Type") != "image/png" || resp.Header.Get("X-Content-Type-Options") != "nosniff" || !strings.Contains(resp.Header.Get("Content-Disposition"), `filename="World Map.png"`) {
+		t.Errorf("map download headers = %v", resp.Header)
+	}
+	resp, err = bobClient.Get(mapURL + "/thumbnail")
+	if err != nil {
+		t.Fatalf("GET thumbnail failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/jpeg" {
+		t.Errorf("thumbnail status = %d, type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// The letter is only revealed to Alice.
+	if status, body := getBody(t, aliceClient, letterURL); status != http.StatusOK || body != "The duke is the traitor." {
+		t.Errorf("revealed download status = %d, body %q", status, body)
+	}
+	if status, _ := getBody(t, bobClient, letterURL); status != http.StatusNotFound {
+		t.Errorf("unrevealed download status = %d, want %d", status, http.StatusNotFound)
+	}
+	if _, body := getBody(t, bobClient, gameURL); strings.Contains(body, "letter.txt") || !strings.Contains(body, "World Map.png") {
+		t.Errorf("bob's game page should list the map but not the letter")
+	}
+	if status, _ := getBody(t, outsiderClient, mapURL); status != http.StatusNotFound {
+		t.Errorf("outsider download status = %d, want %d", status, http.StatusNotFound)
+	}
+	if _, body := getBody(t, outsiderClient, gameURL); strings.Contains(body, "attachments-section") {
+		t.Errorf("outsider game page should not show attachments")
+	}
+
+	// Revealing the letter to everyone, then removing it.
+	if status, _ := postForm(t, aliceClient, letterURL+"/reveal", url.Values{"audience": {"everyone"}}); status != http.StatusForbidden {
+		t.Errorf("player reveal status = %d, want %d", status, http.StatusForbidden)
+	}
+	postForm(t, gmClient, letterURL+"/reveal", url.Values{"audience": {"everyone"}})
+	if status, _ := getBody(t, bobClient, letterURL); status != http.StatusOK {
+		t.Errorf("download after reveal status = %d, want %d", status, http.StatusOK)
+	}
+	if status, body := postForm(t, gmClient, letterURL+"/delete", nil); status != http.StatusOK || strings.Contains(body, "letter.txt") {
+		t.Errorf("delete status = %d, body: %s", status, body)
+	}
+	if _, err := ts.store.Open(letter.StorageKey); err != storage.ErrNotFound {
+		t.Errorf("stored file after delete: error = %v, want storage.ErrNotFound", err)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// SubmitAttendance records who showed up to a past game: POST /games/{id}/attendance
+// with a 'status_{userID}' field per RSVP'd player (present, late, no_show, or empty
+// to clear). Only the GM may record attendance, and only once the game has started.
+// This handler should be wrapped by AuthMiddleware.
+func SubmitAttendance(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			w.Header().Set("HX-Redirect", "/login")
+			http.Error(w, "User not authenticated", http.StatusUnauthorized)
+			return
+		}
+		gameID, err := pathInt64(r, "/games/", 0)
+		if err != nil {
+			http.Error(w, "Invalid Game ID format", http.StatusBadRequest)
+			return
+		}
+		game, err := database.GetGameByID(db, gameID)
+		if err != nil {
+			http.Error(w, "Game not found", http.StatusNotFound)
+			return
+		}
+		if !game.IsGM(currentUser.ID) {
+			http.Error(w, "Only the GM can record attendance for this game.", http.StatusForbidden)
+			return
+		}
+		if !game.HasHappened(time.Now()) {
+			http.Error(w, "Attendance can only be recorded after the game has started.", http.StatusBadRequest)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+		rsvps, err := database.GetRSVPsForGame(db, gameID)
+		if err != nil {
+			fmt.Printf("Error fetching RSVPs for game %d: %v\n", gameID, err)
+			http.Error(w, "Failed to record attendance. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		// Only RSVP'd players are on the form; fields for anyone else are ignored.
+		statuses := make(map[int64]string, len(rsvps))
+		for _, rsvp := range rsvps {
+			status := r.FormValue(fmt.Sprintf("status_%d", rsvp.UserID))
+			if status != "" && !models.ValidAttendanceStatus(status) {
+				http.Error(w, "Invalid attendance status value", http.StatusBadRequest)
+				return
+			}
+			statuses[rsvp.UserID] = status
+		}
+
+		if err := database.SetAttendance(db, gameID, currentUser.ID, statuses); err != nil {
+			fmt.Printf("Error recording attendance for game %d: %v\n", gameID, err)
+			http.Error(w, "Failed to record attendance. Please try again.", http.StatusInternalServerError)
+			return
+		}
+
+		data, err := attendanceSectionData(db, game, rsvps)
+		if err != nil {
+			fmt.Printf("Error reloading attendance for game %d: %v\n", gameID, err)
+			http.Error(w, "Failed to refresh attendance.", http.StatusInternalServerError)
+			return
+		}
+		data["AttendanceSaved"] = true
+		RenderTemplate(w, "games/_attendance_section.html", data)
+	}
+}
+
+// attendanceSectionData builds the template data for _attendance_section.html.
+func attendanceSectionData(db *sql.DB, game *models.Game, rsvps []*models.RSVP) (map[string]interface{}, error) {
+	attendance, err := database.GetAttendanceForGame(db, game.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Players the GM turned away weren't at the table.
+	var players []*models.RSVP
+	for _, rsvp := range rsvps {
+		if rsvp.Status != models.RSVPStatusDeclined {
+			players = append(players, rsvp)
+		}
+	}
+	return map[string]interface{}{
+		"Game":               game,
+		"AllGameRSVPs":       players,
+		"Attendance":         attendance,
+		"AttendanceStatuses": models.AttendanceStatuses,
+	}, nil
+}
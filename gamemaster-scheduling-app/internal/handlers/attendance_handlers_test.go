@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestAttendance(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "attendance_gm@example.com", "gmpass")
+	aliceClient, alice := ts.newUserClient(t, "attendance_alice@example.com", "password")
+	_, bob := ts.newUserClient(t, "attendance_bob@example.com", "password")
+
+	campaign, err := database.GetOrCreateCampaign(ts.db, gm.ID, "Night Below")
+	if err != nil {
+		t.Fatalf("GetOrCreateCampaign() error = %v", err)
+	}
+	past, err := database.CreateGame(ts.db, &models.Game{GMID: gm.ID, Title: "Session One", GameDateTime: time.Now().Add(-48 * time.Hour), Location: "Table", CampaignID: campaign.ID})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+	future := ts.createTestGameDirectly(t, gm.ID, "Session Two")
+	for _, u := range []*models.User{alice, bob} {
+		if err := database.CreateOrUpdateRSVP(ts.db, &models.RSVP{GameID: past.ID, UserID: u.ID, Status: models.RSVPStatusAttending}); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+
+	pastURL := ts.server.URL + "/games/" + strconv.FormatInt(past.ID, 10)
+	form := url.Values{
+		"status_" + strconv.FormatInt(alice.ID, 10): {models.AttendanceLate},
+		"status_" + strconv.FormatInt(bob.ID, 10):   {models.AttendanceNoShow},
+	}
+
+	if status, _ := postForm(t, aliceClient, pastURL+"/attendance", form); status != http.StatusForbidden {
+		t.Errorf("player attendance status = %d, want %d", status, http.StatusForbidden)
+	}
+	futureURL := ts.server.URL + "/games/" + strconv.FormatInt(future.ID, 10) + "/attendance"
+	if status, _ := postForm(t, gmClient, futureURL, form); status != http.StatusBadRequest {
+		t.Errorf("future game attendance status = %d, want %d", status, http.StatusBadRequest)
+	}
+	bad := url.Values{"status_" + strconv.FormatInt(alice.ID, 10): {"asleep"}}
+	if status, _ := postForm(t, gmClient, pastURL+"/attendance", bad); status != http.StatusBadRequest {
+		t.Errorf("invalid status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	status, body := postForm(t, gmClient, pastURL+"/attendance", form)
+	if status != http.StatusOK {
+		t.Fatalf("GM attendance status = %d, body: %s", status, body)
+	}
+	if !strings.Contains(body, `value="no_show" selected`) || !strings.Contains(body, "Saved.") {
+		t.Errorf("attendance partial missing saved state: %s", body)
+	}
+	recorded, _ := database.GetAttendanceForGame(ts.db, past.ID)
+	if recorded[alice.ID] != models.AttendanceLate || recorded[bob.ID] != models.AttendanceNoShow {
+		t.Errorf("recorded attendance = %v", recorded)
+	}
+
+	if _, body := getBody(t, gmClient, pastURL); !strings.Contains(body, `id="attendance-section"`) {
+		t.Errorf("GM game page missing attendance form")
+	}
+	if _, body := getBody(t, aliceClient, pastURL); strings.Contains(body, `id="attendance-section"`) {
+		t.Errorf("player game page should not show the attendance form")
+	}
+
+	_, body = getBody(t, aliceClient, ts.server.URL+"/users/"+strconv.FormatInt(bob.ID, 10))
+	if !strings.Contains(body, "100% (1 of 1 recorded sessions)") {
+		t.Errorf("profile missing no-show rate: %s", body)
+	}
+
+	_, body = getBody(t, aliceClient, ts.server.URL+"/campaigns/"+strconv.FormatInt(campaign.ID, 10))
+	if !strings.Contains(body, "Night Below") || !strings.Contains(body, `class="attendance-late"`) || !strings.Contains(body, `class="attendance-no_show"`) {
+		t.Errorf("campaign page missing attendance matrix: %s", body)
+	}
+}
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gamemaster-scheduling/app/internal/database"
@@ -11,9 +15,57 @@ import (
 	"github.com/google/uuid"
 )
 
-// SessionStore holds active session IDs and their corresponding user IDs.
+// Sessions maps session tokens to the IDs of logged-in users. It is safe for
+// concurrent use by request goroutines.
 // For POC only. In production, use a persistent store like Redis.
-var SessionStore = make(map[string]int64) // Made public
+type Sessions struct {
+	mu     sync.RWMutex
+	tokens map[string]int64
+}
+
+// NewSessions returns an empty session store.
+func NewSessions() *Sessions {
+	return &Sessions{tokens: make(map[string]int64)}
+}
+
+// Get returns the ID of the user logged in with token.
+func (s *Sessions) Get(token string) (int64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	userID, ok := s.tokens[token]
+	return userID, ok
+}
+
+// Set logs userID in with token.
+func (s *Sessions) Set(token string, userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = userID
+}
+
+// Delete ends the session with token.
+func (s *Sessions) Delete(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
+
+// DeleteUser logs a user out everywhere, e.g. when their account is suspended.
+func (s *Sessions) DeleteUser(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, id := range s.tokens {
+		if id == userID {
+			delete(s.tokens, token)
+		}
+	}
+}
+
+// SessionStore holds active session IDs and their corresponding user IDs.
+var SessionStore = NewSessions()
+
+// errAccountSuspended is returned by GetCurrentUser for suspended accounts.
+var errAccountSuspended = errors.New("account suspended")
 
 const sessionCookieName = "session_token"
 
@@ -60,6 +112,20 @@ func Register(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		username := strings.TrimPrefix(strings.TrimSpace(r.FormValue("username")), "@")
+		if username != "" {
+			if !models.ValidUsername(username) {
+				data := map[string]interface{}{"Error": "Usernames are 3-30 letters, digits or underscores."}
+				RenderTemplate(w, "auth/register.html", data)
+				return
+			}
+			if _, err := database.GetUserByUsername(db, username); err == nil {
+				data := map[string]interface{}{"Error": "That username is already taken."}
+				RenderTemplate(w, "auth/register.html", data)
+				return
+			}
+		}
+
 		// Check if user already exists
 		_, err = database.GetUserByEmail(db, email)
 		if err == nil { // If err is nil, user was found
@@ -73,11 +139,17 @@ func Register(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Create user
-		_, err = database.CreateUser(db, email, password)
+		user, err := database.CreateUser(db, email, password)
 		if err != nil {
 			http.Error(w, "Could not create user: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if username != "" {
+			if err := database.SetUsername(db, user.ID, username); err != nil {
+				// The account exists; the username can still be set from the profile page.
+				fmt.Printf("Error setting username for new user %d: %v\n", user.ID, err)
+			}
+		}
 
 		// For HTMX, if successful, you might want to redirect via a special HTMX header,
 		// or return a snippet that indicates success and then the client-side JS redirects.
@@ -122,6 +194,7 @@ func Login(db *sql.DB) http.HandlerFunc {
 		user, err := database.GetUserByEmail(db, email)
 		if err != nil {
 			if err == sql.ErrNoRows {
+				recordLogin(db, r, &models.LoginEvent{Email: email, Reason: models.LoginFailedUnknownEmail})
 				data := map[string]interface{}{"Error": "Invalid email or password."}
 				RenderTemplate(w, "auth/login.html", data)
 			} else {
@@ -132,10 +205,18 @@ func Login(db *sql.DB) http.HandlerFunc {
 
 		err = database.VerifyPassword(user.PasswordHash, password)
 		if err != nil { // Password mismatch
+			recordLogin(db, r, &models.LoginEvent{UserID: user.ID, Email: email, Reason: models.LoginFailedBadPassword})
 			data := map[string]interface{}{"Error": "Invalid email or password."}
 			RenderTemplate(w, "auth/login.html", data)
 			return
 		}
+		if user.IsSuspended() {
+			recordLogin(db, r, &models.LoginEvent{UserID: user.ID, Email: email, Reason: models.LoginFailedSuspended})
+			data := map[string]interface{}{"Error": "This account has been suspended. Contact the site administrators if you think this is a mistake."}
+			RenderTemplate(w, "auth/login.html", data)
+			return
+		}
+		recordLogin(db, r, &models.LoginEvent{UserID: user.ID, Email: email, Success: true})
 
 		// Create session
 		sessionID, err := uuid.NewRandom()
@@ -144,7 +225,7 @@ func Login(db *sql.DB) http.HandlerFunc {
 			return
 		}
 		sessionToken := sessionID.String()
-		SessionStore[sessionToken] = user.ID // Use public SessionStore
+		SessionStore.Set(sessionToken, user.ID)
 
 		http.SetCookie(w, &http.Cookie{
 			Name:     sessionCookieName,
@@ -169,7 +250,7 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie(sessionCookieName)
 	if err == nil { // Cookie exists
 		sessionToken := cookie.Value
-		delete(SessionStore, sessionToken) // Use public SessionStore
+		SessionStore.Delete(sessionToken)
 
 		// Expire the cookie
 		http.SetCookie(w, &http.Cookie{
@@ -186,14 +267,27 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
-// Middleware to protect routes that require authentication.
-func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// recordLogin stores a login attempt with the client's address for the admin activity
+// log. Failing to record it doesn't stop the login.
+func recordLogin(db *sql.DB, r *http.Request, event *models.LoginEvent) {
+	event.IPAddress = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		event.IPAddress = host
+	}
+	event.UserAgent = r.UserAgent()
+	if err := database.RecordLogin(db, event); err != nil {
+		fmt.Printf("Error recording login for %s: %v\n", event.Email, err)
+	}
+}
+
+// Middleware to protect routes that require authentication. Suspended users are
+// sent to the login page, which refuses them.
+func AuthMiddleware(db *sql.DB, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !IsAuthenticated(r) {
+		if _, err := GetCurrentUser(r, db); err != nil {
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
-		// If IsAuthenticated, proceed. We could also fetch the user and add to context here.
 		next.ServeHTTP(w, r)
 	}
 }
@@ -205,13 +299,15 @@ func IsAuthenticated(r *http.Request) bool {
 		return false
 	}
 	sessionToken := cookie.Value
-	_, ok := SessionStore[sessionToken] // Check if session token is valid in our store
+	_, ok := SessionStore.Get(sessionToken) // Check if session token is valid in our store
 	return ok
 }
 
 
 // GetCurrentUser retrieves the currently authenticated user from the session.
 // Returns the User object or an error if not authenticated or user not found.
+// A suspended user is logged out everywhere and gets an error too, however their
+// session survived.
 // db can be nil if only checking authentication status without fetching user details,
 // but for GetCurrentUser, db is required.
 func GetCurrentUser(r *http.Request, db *sql.DB) (*models.User, error) {
@@ -223,9 +319,17 @@ func GetCurrentUser(r *http.Request, db *sql.DB) (*models.User, error) {
 		return nil, fmt.Errorf("no session cookie: %w", err)
 	}
 	sessionToken := cookie.Value
-	userID, ok := SessionStore[sessionToken]
+	userID, ok := SessionStore.Get(sessionToken)
 	if !ok {
 		return nil, fmt.Errorf("invalid session token")
 	}
-	return database.GetUserByID(db, userID)
+	user, err := database.GetUserByID(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsSuspended() {
+		SessionStore.DeleteUser(user.ID)
+		return nil, errAccountSuspended
+	}
+	return user, nil
 }
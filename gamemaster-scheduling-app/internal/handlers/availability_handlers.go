@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gamemaster-scheduling/app/internal/availability"
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// maxExceptionNoteLength caps the note on an availability exception.
+const maxExceptionNoteLength = 200
+
+// AvailabilityPage shows the current user's timezone, weekly availability and
+// upcoming exceptions, with forms to change them: GET /availability.
+// This handler should be wrapped by AuthMiddleware.
+func AvailabilityPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		renderAvailabilityPage(w, r, db, currentUser, "")
+	}
+}
+
+func renderAvailabilityPage(w http.ResponseWriter, r *http.Request, db *sql.DB, currentUser *models.User, errMsg string) {
+	today := time.Now().In(currentUser.Location()).Format(models.DateLayout)
+	profile, err := database.GetAvailabilityProfile(db, currentUser, today)
+	if err != nil {
+		fmt.Printf("Error fetching availability for user %d: %v\n", currentUser.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load your availability.")
+		return
+	}
+	RenderTemplate(w, "users/availability.html", map[string]interface{}{
+		"Title":           "Availability",
+		"User":            currentUser,
+		"Profile":         profile,
+		"Timezone":        currentUser.Location().String(),
+		"CommonTimezones": models.CommonTimezones,
+		"Weekdays":        models.Weekdays,
+		"Today":           today,
+		"Error":           errMsg,
+	})
+}
+
+// UpdateTimezone sets the current user's timezone: POST /availability/timezone
+// with an IANA timezone name, or an empty one for UTC.
+// This handler should be wrapped by AuthMiddleware.
+func UpdateTimezone(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		timezone := strings.TrimSpace(r.FormValue("timezone"))
+		if timezone == "UTC" {
+			timezone = ""
+		}
+		if timezone != "" {
+			if _, err := time.LoadLocation(timezone); err != nil || strings.EqualFold(timezone, "Local") {
+				renderAvailabilityPage(w, r, db, currentUser, fmt.Sprintf("Unknown timezone %q. Use a name such as Europe/Berlin.", timezone))
+				return
+			}
+		}
+		if err := database.SetTimezone(db, currentUser.ID, timezone); err != nil {
+			fmt.Printf("Error setting timezone for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to save your timezone. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/availability", http.StatusSeeOther)
+	}
+}
+
+// parseClock parses a time of day "HH:MM" as minutes after midnight.
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// clockRange parses the start and end form values of a span within a day. An
+// end of 00:00 means midnight at the end of the day.
+func clockRange(startValue, endValue string) (start, end int, ok bool) {
+	start, okStart := parseClock(startValue)
+	end, okEnd := parseClock(endValue)
+	if end == 0 {
+		end = 24 * 60
+	}
+	return start, end, okStart && okEnd && start < end
+}
+
+// AddWeeklyAvailability adds a weekly window for the current user:
+// POST /availability/weekly with weekday (0 for Sunday to 6), start and end ("HH:MM").
+// This handler should be wrapped by AuthMiddleware.
+func AddWeeklyAvailability(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		weekday, err := strconv.Atoi(r.FormValue("weekday"))
+		if err != nil || weekday < 0 || weekday > 6 {
+			renderAvailabilityPage(w, r, db, currentUser, "Choose a day of the week.")
+			return
+		}
+		start, end, ok := clockRange(r.FormValue("start"), r.FormValue("end"))
+		if !ok {
+			renderAvailabilityPage(w, r, db, currentUser, "The window must end after it starts. Split windows that run past midnight in two.")
+			return
+		}
+		window := &models.WeeklyAvailability{
+			UserID:             currentUser.ID,
+			AvailabilityWindow: models.AvailabilityWindow{Weekday: time.Weekday(weekday), Start: start, End: end},
+		}
+		if err := database.AddWeeklyAvailability(db, window); err != nil {
+			fmt.Printf("Error adding availability for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to save your availability. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/availability", http.StatusSeeOther)
+	}
+}
+
+// AddAvailabilityException adds a one-off exception for the current user:
+// POST /availability/exceptions with a date, "free" or "busy" as kind, optional
+// start and end ("HH:MM"; both empty for the whole day) and an optional note.
+// This handler should be wrapped by AuthMiddleware.
+func AddAvailabilityException(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		date, err := time.Parse(models.DateLayout, r.FormValue("date"))
+		if err != nil {
+			renderAvailabilityPage(w, r, db, currentUser, "Choose a date.")
+			return
+		}
+		exception := &models.AvailabilityException{
+			UserID: currentUser.ID,
+			Date:   date.Format(models.DateLayout),
+			Start:  0,
+			End:    24 * 60,
+			Note:   strings.TrimSpace(r.FormValue("note")),
+		}
+		switch r.FormValue("kind") {
+		case "free":
+			exception.Free = true
+		case "busy":
+		default:
+			renderAvailabilityPage(w, r, db, currentUser, "Say whether you're free or busy.")
+			return
+		}
+		if r.FormValue("start") != "" || r.FormValue("end") != "" {
+			start, end, ok := clockRange(r.FormValue("start"), r.FormValue("end"))
+			if !ok {
+				renderAvailabilityPage(w, r, db, currentUser, "The exception must end after it starts, or leave both times empty for the whole day.")
+				return
+			}
+			exception.Start, exception.End = start, end
+		}
+		if utf8.RuneCountInString(exception.Note) > maxExceptionNoteLength {
+			renderAvailabilityPage(w, r, db, currentUser, fmt.Sprintf("Notes can be at most %d characters.", maxExceptionNoteLength))
+			return
+		}
+		if err := database.AddAvailabilityException(db, exception); err != nil {
+			fmt.Printf("Error adding availability exception for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to save the exception. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/availability", http.StatusSeeOther)
+	}
+}
+
+// DeleteAvailability removes one of the current user's weekly windows or
+// exceptions: POST /availability/weekly/{id}/delete or /availability/exceptions/{id}/delete.
+// This handler should be wrapped by AuthMiddleware.
+func DeleteAvailability(db *sql.DB, exception bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		id, err := pathInt64(r, "/availability/", 1)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid ID format.")
+			return
+		}
+		deleteFunc := database.DeleteWeeklyAvailability
+		if exception {
+			deleteFunc = database.DeleteAvailabilityException
+		}
+		deleted, err := deleteFunc(db, currentUser.ID, id)
+		if err != nil {
+			fmt.Printf("Error deleting availability %d for user %d: %v\n", id, currentUser.ID, err)
+			http.Error(w, "Failed to delete. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "You have no such availability.")
+			return
+		}
+		http.Redirect(w, r, "/availability", http.StatusSeeOther)
+	}
+}
+
+// CampaignAvailability shows a heatmap of when a campaign's roster is free, for
+// one week in the viewer's timezone: GET /campaigns/{id}/availability, with
+// an optional week=YYYY-MM-DD (any date in the week). Only people in the roster
+// can see it; the campaign's GM can click a slot to schedule a session then.
+// This handler should be wrapped by AuthMiddleware.
+func CampaignAvailability(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		campaignID, err := pathInt64(r, "/campaigns/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid campaign ID format.")
+			return
+		}
+		campaign, err := database.GetCampaignByID(db, campaignID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Campaign not found.")
+			} else {
+				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		roster, err := database.GetCampaignRoster(db, campaign.ID)
+		if err != nil {
+			fmt.Printf("Error fetching roster of campaign %d: %v\n", campaign.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the campaign's roster.")
+			return
+		}
+		inRoster := false
+		for _, u := range roster {
+			inRoster = inRoster || u.ID == currentUser.ID
+		}
+		if !inRoster {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the campaign's GM and players can see its availability.")
+			return
+		}
+
+		loc := currentUser.Location()
+		weekOf := time.Now()
+		if v := r.URL.Query().Get("week"); v != "" {
+			if weekOf, err = time.ParseInLocation(models.DateLayout, v, loc); err != nil {
+				RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid week. Use YYYY-MM-DD.")
+				return
+			}
+		}
+		weekStart := availability.WeekStart(weekOf, loc)
+
+		// Members' local dates can start a day before the viewer's week does.
+		from := weekStart.AddDate(0, 0, -1).Format(models.DateLayout)
+		var profiles []*models.AvailabilityProfile
+		for _, u := range roster {
+			profile, err := database.GetAvailabilityProfile(db, u, from)
+			if err != nil {
+				fmt.Printf("Error fetching availability for user %d: %v\n", u.ID, err)
+				RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the roster's availability.")
+				return
+			}
+			profiles = append(profiles, profile)
+		}
+
+		RenderTemplate(w, "campaigns/availability.html", map[string]interface{}{
+			"Title":    campaign.Name + " availability",
+			"User":     currentUser,
+			"Campaign": campaign,
+			"Roster":   roster,
+			"Heatmap":  availability.Build(profiles, weekStart, loc),
+			"PrevWeek": weekStart.AddDate(0, 0, -7).Format(models.DateLayout),
+			"NextWeek": weekStart.AddDate(0, 0, 7).Format(models.DateLayout),
+			"IsGM":     campaign.IsGM(currentUser.ID),
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestAvailabilityHeatmap(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "heat_gm@example.com", "gmpass")
+	aliceClient, alice := ts.newUserClient(t, "heat_alice@example.com", "password")
+	outsiderClient, _ := ts.newUserClient(t, "heat_outsider@example.com", "password")
+
+	// Players keep their availability in their own timezone.
+	if _, body := postForm(t, aliceClient, ts.server.URL+"/availability/timezone", url.Values{"timezone": {"Mars/Olympus_Mons"}}); !strings.Contains(body, "Unknown timezone") {
+		t.Errorf("invalid timezone was not refused: %s", body)
+	}
+	if status, _ := postForm(t, aliceClient, ts.server.URL+"/availability/timezone", url.Values{"timezone": {"Europe/Berlin"}}); status != http.StatusSeeOther {
+		t.Errorf("setting timezone status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if _, body := postForm(t, aliceClient, ts.server.URL+"/availability/weekly", url.Values{"weekday": {"1"}, "start": {"23:00"}, "end": {"19:00"}}); !strings.Contains(body, "must end after it starts") {
+		t.Errorf("backwards window was not refused: %s", body)
+	}
+	postForm(t, aliceClient, ts.server.URL+"/availability/weekly", url.Values{"weekday": {"1"}, "start": {"19:00"}, "end": {"23:00"}})
+	postForm(t, aliceClient, ts.server.URL+"/availability/exceptions", url.Values{"date": {"2030-01-07"}, "kind": {"busy"}, "start": {"19:00"}, "end": {"20:00"}, "note": {"Dentist"}})
+	_, body := getBody(t, aliceClient, ts.server.URL+"/availability")
+	if !strings.Contains(body, `value="Europe/Berlin"`) || !strings.Contains(body, "Monday 19:00-23:00") || !strings.Contains(body, "2030-01-07, 19:00-20:00: <strong>busy</strong> (Dentist)") {
+		t.Errorf("availability page does not show what was saved: %s", body)
+	}
+	postForm(t, gmClient, ts.server.URL+"/availability/weekly", url.Values{"weekday": {"1"}, "start": {"18:00"}, "end": {"22:00"}})
+
+	campaign, _ := database.GetOrCreateCampaign(ts.db, gm.ID, "Tomb of Horrors")
+	session, _ := database.CreateGame(ts.db, &models.Game{GMID: gm.ID, Title: "Session 1", GameDateTime: time.Now().Add(24 * time.Hour), Location: "Online", CampaignID: campaign.ID})
+	database.CreateOrUpdateRSVP(ts.db, &models.RSVP{GameID: session.ID, UserID: alice.ID, Status: models.RSVPStatusAttending})
+
+	// Only the roster sees the heatmap; in UTC, Alice is free 18:00-22:00 except for the dentist.
+	heatmapURL := ts.server.URL + "/campaigns/" + strconv.FormatInt(campaign.ID, 10) + "/availability?week=2030-01-09"
+	if status, _ := getBody(t, outsiderClient, heatmapURL); status != http.StatusForbidden {
+		t.Errorf("outsider heatmap status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body = getBody(t, aliceClient, heatmapURL)
+	if !strings.Contains(body, "Mon Jan 7") || strings.Contains(body, "/games/new?") {
+		t.Errorf("player's heatmap is wrong or offers scheduling: %s", body)
+	}
+	_, body = getBody(t, gmClient, heatmapURL)
+	for _, want := range []string{
+		`<td class="heat-2" title="Mon 18:00: heat_gm@example.com">`,
+		`<td class="heat-4" title="Mon 19:00: heat_alice@example.com, heat_gm@example.com">`,
+		`<a href="/games/new?game_datetime=2030-01-07T19%3a00&amp;campaign=Tomb%20of%20Horrors&amp;invite_roster=1">2</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GM's heatmap does not contain %s: %s", want, body)
+		}
+	}
+
+	// Clicking a slot prefills a new session, which invites the roster.
+	_, body = getBody(t, gmClient, ts.server.URL+"/games/new?game_datetime=2030-01-07T19:00&campaign=Tomb%20of%20Horrors&invite_roster=1")
+	if !strings.Contains(body, `value="2030-01-07T19:00"`) || !strings.Contains(body, `value="Tomb of Horrors"`) || !strings.Contains(body, `name="invite_roster" value="1" checked`) {
+		t.Errorf("new game form was not prefilled: %s", body)
+	}
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{
+		"title": {"Session 2"}, "game_datetime": {"2030-01-07T19:00"}, "location": {"Online"}, "campaign": {"Tomb of Horrors"}, "invite_roster": {"1"},
+	})
+	notifications, _ := database.GetNotificationsForUser(ts.db, alice.ID, 10)
+	if len(notifications) != 1 || notifications[0].Kind != models.NotificationKindGameInvite || !strings.Contains(notifications[0].Message, "Session 2") {
+		t.Errorf("roster invitations = %+v, want one for Session 2", notifications)
+	}
+	if gmNotes, _ := database.GetNotificationsForUser(ts.db, gm.ID, 10); len(gmNotes) != 0 {
+		t.Errorf("GM invited themselves: %+v", gmNotes)
+	}
+}
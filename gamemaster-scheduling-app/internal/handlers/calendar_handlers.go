@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/calendar"
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// CalendarPage shows games on a calendar: GET /calendar, with an optional
+// view (month, week or agenda) and date=YYYY-MM-DD (today if not given).
+// Signed-in viewers see it in their timezone, with their part in each game
+// color-coded. Requests from htmx get just the calendar, to swap in place.
+func CalendarPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, _ := GetCurrentUser(r, db) // Template handles nil user
+
+		loc := time.UTC
+		if currentUser != nil {
+			loc = currentUser.Location()
+		}
+		view := r.URL.Query().Get("view")
+		if !calendar.ValidView(view) {
+			view = calendar.ViewMonth
+		}
+		now := time.Now()
+		date := now
+		if v := r.URL.Query().Get("date"); v != "" {
+			var err error
+			if date, err = time.ParseInLocation(models.DateLayout, v, loc); err != nil {
+				RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid date. Use YYYY-MM-DD.")
+				return
+			}
+		}
+
+		from, to := calendar.Range(view, date, loc)
+		games, err := database.GetGamesBetween(db, from, to)
+		if err != nil {
+			fmt.Printf("Error fetching games from %v to %v: %v\n", from, to, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the calendar.")
+			return
+		}
+		statuses := map[int64]string{}
+		if currentUser != nil {
+			if statuses, err = database.GetRSVPStatusesBetween(db, currentUser.ID, from, to); err != nil {
+				fmt.Printf("Error fetching calendar RSVPs for user %d: %v\n", currentUser.ID, err)
+				RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the calendar.")
+				return
+			}
+		}
+		entries := make([]*calendar.Entry, len(games))
+		for i, g := range games {
+			entries[i] = &calendar.Entry{Game: g, RSVPStatus: statuses[g.ID]}
+			if currentUser == nil {
+				continue
+			}
+			if err := database.LoadGameStaff(db, g); err != nil {
+				fmt.Printf("Error fetching staff for game %d: %v\n", g.ID, err)
+				RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the calendar.")
+				return
+			}
+			entries[i].IsGM = g.IsGM(currentUser.ID)
+		}
+
+		data := map[string]interface{}{
+			"Title":    "Calendar",
+			"User":     currentUser,
+			"Calendar": calendar.New(view, date, now, loc, entries),
+			"Views":    calendar.Views,
+		}
+		if r.Header.Get("HX-Request") == "true" {
+			RenderTemplate(w, "calendar/_calendar.html", data)
+			return
+		}
+		RenderTemplate(w, "calendar/calendar.html", data)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestCalendar(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "cal_gm@example.com", "gmpass")
+	playerClient, player := ts.newUserClient(t, "cal_player@example.com", "password")
+	session, _ := database.CreateGame(ts.db, &models.Game{GMID: gm.ID, Title: "Session Zero", GameDateTime: time.Date(2030, 3, 13, 19, 0, 0, 0, time.UTC), Location: "Online"})
+	cancelled, _ := database.CreateGame(ts.db, &models.Game{GMID: gm.ID, Title: "Called Off", GameDateTime: time.Date(2030, 3, 20, 19, 0, 0, 0, time.UTC), Location: "Online"})
+	database.CreateGame(ts.db, &models.Game{GMID: gm.ID, Title: "Next Month", GameDateTime: time.Date(2030, 4, 20, 19, 0, 0, 0, time.UTC), Location: "Online"})
+	database.ForceCancelGame(ts.db, gm.ID, cancelled.ID, "Venue closed")
+	database.CreateOrUpdateRSVP(ts.db, &models.RSVP{GameID: session.ID, UserID: player.ID, Status: models.RSVPStatusAttending})
+
+	monthURL := ts.server.URL + "/calendar?view=month&date=2030-03-13"
+	_, body := getBody(t, ts.client, monthURL)
+	if !strings.Contains(body, "March 2030") || !strings.Contains(body, "19:00 Session Zero") || strings.Contains(body, "Next Month") || strings.Contains(body, "calendar-legend") {
+		t.Errorf("anonymous month view is wrong: %s", body)
+	}
+	_, body = getBody(t, playerClient, monthURL)
+	if !strings.Contains(body, `class="calendar-game calendar-rsvp-attending"`) || !strings.Contains(body, "<s>Called Off</s>") {
+		t.Errorf("player's month view does not color their RSVP or strike the cancelled game: %s", body)
+	}
+	if _, body = getBody(t, gmClient, monthURL); !strings.Contains(body, `class="calendar-game calendar-gm"`) {
+		t.Errorf("GM's month view does not mark their game: %s", body)
+	}
+	coGMClient, coGM := ts.newUserClient(t, "cal_cogm@example.com", "password")
+	database.AddGameStaff(ts.db, session.ID, coGM.ID, gm.ID)
+	if _, body = getBody(t, coGMClient, monthURL); !strings.Contains(body, `class="calendar-game calendar-gm"`) {
+		t.Errorf("co-GM's month view does not mark the game they run: %s", body)
+	}
+
+	// In Tokyo, the session is on Thursday morning; htmx gets just the calendar.
+	postForm(t, playerClient, ts.server.URL+"/availability/timezone", url.Values{"timezone": {"Asia/Tokyo"}})
+	req, _ := http.NewRequest(http.MethodGet, ts.server.URL+"/calendar?view=week&date=2030-03-14", nil)
+	req.Header.Set("HX-Request", "true")
+	resp, err := playerClient.Do(req)
+	if err != nil {
+		t.Fatalf("htmx calendar request failed: %v", err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	body = string(raw)
+	if strings.Contains(body, "<nav>") || !strings.Contains(body, `<div id="calendar">`) || !strings.Contains(body, "Mar 11 &ndash; Mar 17, 2030") || !strings.Contains(body, "Asia/Tokyo") {
+		t.Errorf("htmx week view is not a bare calendar in the viewer's timezone: %s", body)
+	}
+	if i := strings.Index(body, "Mar 14</div>"); i < 0 || !strings.Contains(body[i:], "04:00 Session Zero") {
+		t.Errorf("session is not on Thursday at 04:00: %s", body)
+	}
+	if !strings.Contains(body, `hx-get="/calendar?view=week&amp;date=2030-03-07"`) || !strings.Contains(body, `hx-get="/calendar?view=agenda&amp;date=2030-03-14"`) {
+		t.Errorf("week view is missing navigation: %s", body)
+	}
+
+	_, body = getBody(t, playerClient, ts.server.URL+"/calendar?view=agenda&date=2030-03-01")
+	if !strings.Contains(body, "Thursday, March 14") || !strings.Contains(body, "Thursday, March 21") || strings.Contains(body, "Next Month") {
+		t.Errorf("agenda is wrong: %s", body)
+	}
+	if status, _ := getBody(t, playerClient, ts.server.URL+"/calendar?date=13/03/2030"); status != http.StatusBadRequest {
+		t.Errorf("invalid date status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// CampaignPage shows a campaign's sessions and its attendance matrix: GET /campaigns/{id}.
+func CampaignPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaignID, err := pathInt64(r, "/campaigns/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid campaign ID format.")
+			return
+		}
+		campaign, err := database.GetCampaignByID(db, campaignID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Campaign not found.")
+			} else {
+				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		matrix, err := database.GetCampaignAttendanceMatrix(db, campaignID)
+		if err != nil {
+			fmt.Printf("Error building attendance matrix for campaign %d: %v\n", campaignID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load campaign attendance.")
+			return
+		}
+		currentUser, _ := GetCurrentUser(r, db) // Template handles nil user
+
+		data := map[string]interface{}{
+			"Title":    campaign.Name,
+			"User":     currentUser,
+			"Campaign": campaign,
+			"Matrix":   matrix,
+		}
+		RenderTemplate(w, "campaigns/campaign.html", data)
+	}
+}
+
+// campaignForMember loads the campaign /campaigns/{id} for the current user with
+// its roster, rendering an error page unless the user is in the roster. what
+// names the page for the error, e.g. "its hosting rotation".
+func campaignForMember(w http.ResponseWriter, r *http.Request, db *sql.DB, what string) (*models.Campaign, *models.User, []*models.User, bool) {
+	currentUser, err := GetCurrentUser(r, db)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, nil, nil, false
+	}
+	campaignID, err := pathInt64(r, "/campaigns/", 0)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid campaign ID format.")
+		return nil, nil, nil, false
+	}
+	campaign, err := database.GetCampaignByID(db, campaignID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Campaign not found.")
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return nil, nil, nil, false
+	}
+	roster, err := database.GetCampaignRoster(db, campaign.ID)
+	if err != nil {
+		fmt.Printf("Error fetching roster of campaign %d: %v\n", campaign.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the campaign's roster.")
+		return nil, nil, nil, false
+	}
+	for _, u := range roster {
+		if u.ID == currentUser.ID {
+			return campaign, currentUser, roster, true
+		}
+	}
+	RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the campaign's GM and players can see "+what+".")
+	return nil, nil, nil, false
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// characterFromForm reads and validates the character form fields. It returns a
+// message for the user if the input is invalid.
+func characterFromForm(r *http.Request) (*models.Character, string) {
+	c := &models.Character{
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		System:   strings.TrimSpace(r.FormValue("system")),
+		Class:    strings.TrimSpace(r.FormValue("class")),
+		SheetURL: strings.TrimSpace(r.FormValue("sheet_url")),
+		Notes:    strings.TrimSpace(r.FormValue("notes")),
+	}
+	if c.Name == "" {
+		return c, "Every character needs a name."
+	}
+	if len(c.Name) > 100 || len(c.System) > 100 || len(c.Class) > 100 {
+		return c, "Name, system and class are limited to 100 characters."
+	}
+	if level := strings.TrimSpace(r.FormValue("level")); level != "" {
+		n, err := strconv.Atoi(level)
+		if err != nil || n < 1 || n > models.MaxCharacterLevel {
+			return c, fmt.Sprintf("Level must be a number from 1 to %d.", models.MaxCharacterLevel)
+		}
+		c.Level = n
+	}
+	if c.SheetURL != "" {
+		u, err := url.Parse(c.SheetURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return c, "The character sheet link must be an http(s) URL."
+		}
+	}
+	return c, ""
+}
+
+// ownCharacter loads the character in the path /characters/{id}[/...] and checks it
+// belongs to the current user, writing an error page if not.
+func ownCharacter(w http.ResponseWriter, r *http.Request, db *sql.DB, currentUser *models.User) (*models.Character, bool) {
+	characterID, err := pathInt64(r, "/characters/", 0)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid character ID format.")
+		return nil, false
+	}
+	character, err := database.GetCharacterByID(db, characterID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Character not found.")
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	if character.UserID != currentUser.ID {
+		RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "You can only change your own characters.")
+		return nil, false
+	}
+	return character, true
+}
+
+// CharactersPage lists the current user's characters with a form to add one: GET /characters.
+// This handler should be wrapped by AuthMiddleware.
+func CharactersPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		renderCharactersPage(w, r, db, currentUser, nil, "")
+	}
+}
+
+// renderCharactersPage renders the roster, re-filling the new character form on error.
+func renderCharactersPage(w http.ResponseWriter, r *http.Request, db *sql.DB, currentUser *models.User, form *models.Character, errMsg string) {
+	characters, err := database.GetCharactersForUser(db, currentUser.ID)
+	if err != nil {
+		fmt.Printf("Error fetching characters for user %d: %v\n", currentUser.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load your characters.")
+		return
+	}
+	RenderTemplate(w, "characters/characters.html", map[string]interface{}{
+		"Title":      "Your characters",
+		"User":       currentUser,
+		"Characters": characters,
+		"Form":       form,
+		"Error":      errMsg,
+		"MaxLevel":   models.MaxCharacterLevel,
+	})
+}
+
+// CreateCharacter adds a character to the current user's roster: POST /characters.
+// This handler should be wrapped by AuthMiddleware.
+func CreateCharacter(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		character, errMsg := characterFromForm(r)
+		if errMsg != "" {
+			renderCharactersPage(w, r, db, currentUser, character, errMsg)
+			return
+		}
+		character.UserID = currentUser.ID
+		if _, err := database.CreateCharacter(db, character); err != nil {
+			fmt.Printf("Error creating character for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to create character. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/characters", http.StatusSeeOther)
+	}
+}
+
+// EditCharacterPage shows the form to edit one of the current user's characters:
+// GET /characters/{id}. This handler should be wrapped by AuthMiddleware.
+func EditCharacterPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		character, ok := ownCharacter(w, r, db, currentUser)
+		if !ok {
+			return
+		}
+		renderEditCharacter(w, currentUser, character, "")
+	}
+}
+
+func renderEditCharacter(w http.ResponseWriter, currentUser *models.User, character *models.Character, errMsg string) {
+	RenderTemplate(w, "characters/edit_character.html", map[string]interface{}{
+		"Title":    "Edit " + character.Name,
+		"User":     currentUser,
+		"Form":     character,
+		"Error":    errMsg,
+		"MaxLevel": models.MaxCharacterLevel,
+	})
+}
+
+// UpdateCharacter saves changes to one of the current user's characters: POST /characters/{id}.
+// This handler should be wrapped by AuthMiddleware.
+func UpdateCharacter(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		existing, ok := ownCharacter(w, r, db, currentUser)
+		if !ok {
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		character, errMsg := characterFromForm(r)
+		character.ID, character.UserID = existing.ID, existing.UserID
+		if errMsg != "" {
+			renderEditCharacter(w, currentUser, character, errMsg)
+			return
+		}
+		if err := database.UpdateCharacter(db, character); err != nil {
+			fmt.Printf("Error updating character %d: %v\n", character.ID, err)
+			http.Error(w, "Failed to save character. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/characters", http.StatusSeeOther)
+	}
+}
+
+// DeleteCharacter removes one of the current user's characters: POST /characters/{id}/delete.
+// RSVPs that brought it are kept, without a character. This handler should be wrapped by AuthMiddleware.
+func DeleteCharacter(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		character, ok := ownCharacter(w, r, db, currentUser)
+		if !ok {
+			return
+		}
+		if err := database.DeleteCharacter(db, character.ID); err != nil {
+			fmt.Printf("Error deleting character %d: %v\n", character.ID, err)
+			http.Error(w, "Failed to delete character. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/characters", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+)
+
+func TestCharacterRSVPs(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "party_gm@example.com", "gmpass")
+	aliceClient, alice := ts.newUserClient(t, "party_alice@example.com", "password")
+	bobClient, bob := ts.newUserClient(t, "party_bob@example.com", "password")
+
+	_, body := postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{
+		"title": {"Sunless Citadel"}, "game_datetime": {"2030-05-01T19:00"}, "location": {"Table"},
+		"min_level": {"5"}, "max_level": {"3"},
+	})
+	if !strings.Contains(body, "minimum level cannot be above") {
+		t.Errorf("inverted level range was not rejected: %s", body)
+	}
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{
+		"title": {"Sunless Citadel"}, "game_datetime": {"2030-05-01T19:00"}, "location": {"Table"},
+		"min_level": {"1"}, "max_level": {"3"},
+	})
+	games, err := database.GetGamesByGM(ts.db, gm.ID)
+	if err != nil || len(games) != 1 || games[0].LevelRangeLabel() != "1-3" {
+		t.Fatalf("GetGamesByGM() = %v, %v; want one game for levels 1-3", games, err)
+	}
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(games[0].ID, 10)
+
+	_, body = postForm(t, aliceClient, ts.server.URL+"/characters", url.Values{"name": {"Zanna"}, "level": {"99"}})
+	if !strings.Contains(body, "Level must be a number from 1 to 30") {
+		t.Errorf("invalid level was not rejected: %s", body)
+	}
+	_, body = postForm(t, aliceClient, ts.server.URL+"/characters", url.Values{"name": {"Zanna"}, "sheet_url": {"javascript:alert(1)"}})
+	if !strings.Contains(body, "must be an http(s) URL") {
+		t.Errorf("unsafe sheet link was not rejected: %s", body)
+	}
+	if status, _ := postForm(t, aliceClient, ts.server.URL+"/characters", url.Values{
+		"name": {"Zanna"}, "system": {"D&D 5e"}, "class": {"Wizard"}, "level": {"6"}, "sheet_url": {"https://example.com/zanna"},
+	}); status != http.StatusSeeOther {
+		t.Fatalf("create character status = %d, want %d", status, http.StatusSeeOther)
+	}
+	postForm(t, bobClient, ts.server.URL+"/characters", url.Values{"name": {"Brom"}, "class": {"Fighter"}, "level": {"2"}})
+	aliceRoster, _ := database.GetCharactersForUser(ts.db, alice.ID)
+	bobRoster, _ := database.GetCharactersForUser(ts.db, bob.ID)
+	if len(aliceRoster) != 1 || len(bobRoster) != 1 {
+		t.Fatalf("rosters = %v / %v, want one character each", aliceRoster, bobRoster)
+	}
+	zanna, brom := aliceRoster[0], bobRoster[0]
+	zannaURL := ts.server.URL + "/characters/" + strconv.FormatInt(zanna.ID, 10)
+
+	if status, _ := getBody(t, bobClient, zannaURL); status != http.StatusForbidden {
+		t.Errorf("editing another player's character status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status, _ := postForm(t, bobClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "character_id": {strconv.FormatInt(zanna.ID, 10)}}); status != http.StatusBadRequest {
+		t.Errorf("RSVP with another player's character status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	_, body = getBody(t, aliceClient, gameURL)
+	if !strings.Contains(body, `id="rsvp-character"`) || !strings.Contains(body, "Zanna (Level 6 Wizard)") || !strings.Contains(body, "Character levels:</strong> 1-3") {
+		t.Errorf("game page is missing the character picker or level range")
+	}
+	_, body = postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "character_id": {strconv.FormatInt(zanna.ID, 10)}})
+	if !strings.Contains(body, "Party Composition") || !strings.Contains(body, "<strong>Zanna</strong> &mdash; Level 6 Wizard") {
+		t.Errorf("party composition does not list Zanna: %s", body)
+	}
+	if !strings.Contains(body, "Outside the recommended levels (1-3)") {
+		t.Errorf("level 6 character in a 1-3 game has no warning")
+	}
+	_, body = postForm(t, bobClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "character_id": {strconv.FormatInt(brom.ID, 10)}})
+	if !strings.Contains(body, "<strong>Brom</strong>") || strings.Count(body, "level-warning") != 1 {
+		t.Errorf("party composition should list Brom without a warning: %s", body)
+	}
+
+	postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"not_attending"}, "character_id": {strconv.FormatInt(zanna.ID, 10)}})
+	if rsvp, _ := database.GetRSVPByUserForGame(ts.db, alice.ID, games[0].ID); rsvp.CharacterID != 0 {
+		t.Errorf("not attending RSVP kept character %d", rsvp.CharacterID)
+	}
+
+	postForm(t, aliceClient, zannaURL, url.Values{"name": {"Zanna the Bold"}, "level": {"3"}})
+	_, body = getBody(t, gmClient, ts.server.URL+"/users/"+strconv.FormatInt(alice.ID, 10))
+	if !strings.Contains(body, "Zanna the Bold") || !strings.Contains(body, "Level 3") {
+		t.Errorf("profile does not show the updated character")
+	}
+	if status, _ := postForm(t, bobClient, zannaURL+"/delete", nil); status != http.StatusForbidden {
+		t.Errorf("deleting another player's character status = %d, want %d", status, http.StatusForbidden)
+	}
+	postForm(t, aliceClient, zannaURL+"/delete", nil)
+	if roster, _ := database.GetCharactersForUser(ts.db, alice.ID); len(roster) != 0 {
+		t.Errorf("roster after delete = %v, want empty", roster)
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/dice"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// DiceRoller rolls the dice for /roll chat commands.
+// Tests can replace it with dice.NewRoller(seed) for reproducible results.
+var DiceRoller = dice.NewRoller(time.Now().UnixNano())
+
+// applyChatCommand interprets a chat message that starts with "/" as a slash command
+// and attaches the command's server-side result to the message. Plain messages are left untouched.
+//
+// Supported commands:
+//
+//	/roll <dice> [adv|dis] [name]   (alias /r), e.g. "/roll 4d6kh3+2" or "/roll 1d20+5 adv Stealth"
+func applyChatCommand(message *models.ChatMessage) error {
+	content := strings.TrimSpace(message.MessageContent)
+	if !strings.HasPrefix(content, "/") {
+		return nil
+	}
+
+	name, args, _ := strings.Cut(content[1:], " ")
+	switch strings.ToLower(name) {
+	case "roll", "r":
+		expr, label, err := dice.ParseRollArgs(args)
+		if err != nil {
+			return err
+		}
+		message.MessageContent = content
+		message.Roll = DiceRoller.Roll(expr, label)
+		return nil
+	default:
+		return fmt.Errorf("unknown command /%s. Try /roll 1d20", name)
+	}
+}
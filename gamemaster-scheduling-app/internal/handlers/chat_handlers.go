@@ -6,8 +6,11 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/markdown"
 	"github.com/gamemaster-scheduling/app/internal/models"
 )
 
@@ -53,30 +56,26 @@ func PostChatMessage(db *sql.DB) http.HandlerFunc {
 			return
 		}
 		messageContent := r.FormValue("message_content")
+		// Newest message the client already shows; only messages after it are returned.
+		afterID, _ := strconv.ParseInt(r.FormValue("after"), 10, 64)
 
 		if strings.TrimSpace(messageContent) == "" {
-			// Re-render chat section with an error message.
-			// This requires fetching existing messages and adding an error to the template data.
-			// For simplicity here, we'll return a Bad Request.
-			// A more user-friendly approach would be to return the chat partial with an error message.
-			// To do that, we'd fetch existing messages, then pass an error string to the partial.
-			
-			// Fetch existing messages to re-render the chat area
-			chatMessages, fetchErr := database.GetChatMessagesForGame(db, gameID)
-			if fetchErr != nil {
-				fmt.Printf("Error fetching chat messages for game %d: %v\n", gameID, fetchErr)
-				http.Error(w, "Failed to refresh chat messages after validation error.", http.StatusInternalServerError)
-				return
-			}
-			data := map[string]interface{}{
-				"ChatMessages": chatMessages,
-				"GameID": gameID, // For the form action URL in the partial
-				"User": currentUser, // For form display logic
-				"Error": "Message content cannot be empty.",
-			}
-			// It's important that the client-side target for this error is correct.
-			// If the form itself is inside the hx-target, this will replace the form and messages.
-			RenderTemplate(w, "games/_chat_messages.html", data)
+			renderNewChatMessages(w, db, gameID, currentUser, nil, afterID, "Message content cannot be empty.")
+			return
+		}
+		if utf8.RuneCountInString(messageContent) > models.MaxChatMessageLength {
+			renderNewChatMessages(w, db, gameID, currentUser, nil, afterID, fmt.Sprintf("Messages can be at most %d characters.", models.MaxChatMessageLength))
+			return
+		}
+
+		muted, err := database.IsUserMutedInGame(db, gameID, currentUser.ID)
+		if err != nil {
+			fmt.Printf("Error checking chat mute for user %d in game %d: %v\n", currentUser.ID, gameID, err)
+			http.Error(w, "Failed to post message. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		if muted {
+			renderNewChatMessages(w, db, gameID, currentUser, nil, afterID, "You have been muted in this game's chat by the GM.")
 			return
 		}
 
@@ -87,7 +86,13 @@ func PostChatMessage(db *sql.DB) http.HandlerFunc {
 			MessageContent: messageContent,
 		}
 
-		_, err = database.CreateChatMessage(db, chatMessage)
+		// Slash commands such as /roll are resolved server-side before the message is stored.
+		if err := applyChatCommand(chatMessage); err != nil {
+			renderNewChatMessages(w, db, gameID, currentUser, nil, afterID, err.Error())
+			return
+		}
+
+		createdMessage, err := database.CreateChatMessage(db, chatMessage)
 		if err != nil {
 			fmt.Printf("Error creating chat message: %v\n", err)
 			// In a real app, you might want to return a more user-friendly error
@@ -95,20 +100,472 @@ func PostChatMessage(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Failed to post message. Please try again.", http.StatusInternalServerError)
 			return
 		}
+		// The message is posted either way; a failure here only loses mention links and notifications.
+		if err := recordChatMentions(db, createdMessage, currentUser); err != nil {
+			fmt.Printf("Error recording mentions for chat message %d: %v\n", createdMessage.ID, err)
+		}
+
+		// Successfully posted. Append the messages the client hasn't seen yet (including
+		// any posted by others meanwhile) rather than re-rendering the whole list.
+		// Clients that don't send "after" just get the new message.
+		var newMessages []*models.ChatMessage
+		if afterID > 0 {
+			newMessages, err = database.GetChatMessagesSince(db, gameID, afterID, ChatPageSize)
+			if err != nil {
+				fmt.Printf("Error fetching chat messages for game %d after post: %v\n", gameID, err)
+				http.Error(w, "Failed to refresh chat messages.", http.StatusInternalServerError)
+				return
+			}
+		} else {
+			// Re-read so the message includes its mentions.
+			createdMessage, err = database.GetChatMessageByID(db, createdMessage.ID)
+			if err != nil {
+				fmt.Printf("Error fetching chat message after post: %v\n", err)
+				http.Error(w, "Failed to refresh chat messages.", http.StatusInternalServerError)
+				return
+			}
+			newMessages = []*models.ChatMessage{createdMessage}
+		}
+		renderNewChatMessages(w, db, gameID, currentUser, newMessages, afterID, "")
+	}
+}
 
-		// Successfully posted. Re-render the chat messages section.
-		updatedChatMessages, err := database.GetChatMessagesForGame(db, gameID)
+// ChatMessages serves chat history for htmx: GET /games/{id}/chat?before={messageID}
+// returns the page of older messages for the "load older" button, and ?after={messageID}
+// returns messages posted since (used for polling).
+func ChatMessages(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID, err := pathInt64(r, "/games/", 0)
 		if err != nil {
-			fmt.Printf("Error fetching chat messages for game %d after post: %v\n", gameID, err)
+			http.Error(w, "Invalid Game ID format", http.StatusBadRequest)
+			return
+		}
+		currentUser, _ := GetCurrentUser(r, db) // Anonymous viewers can read chat, as on the game page
+
+		if before := r.URL.Query().Get("before"); before != "" {
+			beforeID, err := strconv.ParseInt(before, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid message ID", http.StatusBadRequest)
+				return
+			}
+			data, err := chatViewerData(db, gameID, currentUser)
+			if err == nil {
+				err = addChatPage(db, data, gameID, beforeID)
+			}
+			if err != nil {
+				fmt.Printf("Error fetching older chat messages for game %d: %v\n", gameID, err)
+				http.Error(w, "Failed to load older messages.", http.StatusInternalServerError)
+				return
+			}
+			RenderTemplate(w, "games/_chat_older.html", data)
+			return
+		}
+
+		afterID, err := strconv.ParseInt(r.URL.Query().Get("after"), 10, 64)
+		if err != nil {
+			http.Error(w, "Missing or invalid 'before' or 'after' message ID", http.StatusBadRequest)
+			return
+		}
+		newMessages, err := database.GetChatMessagesSince(db, gameID, afterID, ChatPageSize)
+		if err != nil {
+			fmt.Printf("Error fetching new chat messages for game %d: %v\n", gameID, err)
 			http.Error(w, "Failed to refresh chat messages.", http.StatusInternalServerError)
 			return
 		}
+		renderNewChatMessages(w, db, gameID, currentUser, newMessages, afterID, "")
+	}
+}
+
+// EditChatMessage handles an author's edit of their own message: POST /games/{id}/chat/{messageID}/edit.
+// Edits are only allowed within models.ChatEditWindow of posting; the previous content is kept
+// as a revision. This handler should be wrapped by AuthMiddleware.
+func EditChatMessage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, message, ok := loadChatMessageForAction(w, r, db)
+		if !ok {
+			return
+		}
+
+		if message.UserID != currentUser.ID {
+			http.Error(w, "You can only edit your own messages.", http.StatusForbidden)
+			return
+		}
+		if !message.EditableAt(time.Now()) {
+			renderChatError(w, db, message.GameID, currentUser, "This message can no longer be edited.")
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+		newContent := r.FormValue("message_content")
+		if strings.TrimSpace(newContent) == "" {
+			renderChatError(w, db, message.GameID, currentUser, "Message content cannot be empty.")
+			return
+		}
+		if utf8.RuneCountInString(newContent) > models.MaxChatMessageLength {
+			renderChatError(w, db, message.GameID, currentUser, fmt.Sprintf("Messages can be at most %d characters.", models.MaxChatMessageLength))
+			return
+		}
+		// A muted user could otherwise keep talking by rewriting recent messages.
+		muted, err := database.IsUserMutedInGame(db, message.GameID, currentUser.ID)
+		if err != nil {
+			fmt.Printf("Error checking chat mute for user %d in game %d: %v\n", currentUser.ID, message.GameID, err)
+			http.Error(w, "Failed to edit message. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		if muted {
+			renderChatError(w, db, message.GameID, currentUser, "You have been muted in this game's chat by the GM.")
+			return
+		}
+
+		if newContent != message.MessageContent {
+			edited, err := database.UpdateChatMessageContent(db, message.ID, newContent)
+			if err != nil {
+				fmt.Printf("Error editing chat message %d: %v\n", message.ID, err)
+				http.Error(w, "Failed to edit message. Please try again.", http.StatusInternalServerError)
+				return
+			}
+			if err := recordChatMentions(db, edited, currentUser); err != nil {
+				fmt.Printf("Error recording mentions for chat message %d: %v\n", edited.ID, err)
+			}
+		}
+		renderChatSection(w, db, message.GameID, currentUser)
+	}
+}
+
+// DeleteChatMessage soft-deletes a message: POST /games/{id}/chat/{messageID}/delete.
+// Authors may delete their own messages; the GM may delete any message in their game.
+// This handler should be wrapped by AuthMiddleware.
+func DeleteChatMessage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, message, ok := loadChatMessageForAction(w, r, db)
+		if !ok {
+			return
+		}
+
+		if message.UserID != currentUser.ID {
+			game, err := database.GetGameByID(db, message.GameID)
+			if err != nil {
+				http.Error(w, "Game not found", http.StatusNotFound)
+				return
+			}
+			if !game.IsGM(currentUser.ID) {
+				http.Error(w, "Only the author or the GM can delete this message.", http.StatusForbidden)
+				return
+			}
+		}
+
+		err := database.SoftDeleteChatMessage(db, message.ID, currentUser.ID)
+		if err != nil && err != sql.ErrNoRows { // ErrNoRows: already deleted, nothing to do
+			fmt.Printf("Error deleting chat message %d: %v\n", message.ID, err)
+			http.Error(w, "Failed to delete message. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		renderChatSection(w, db, message.GameID, currentUser)
+	}
+}
+
+// ChatMessageHistory renders the previous versions of an edited message:
+// GET /games/{id}/chat/{messageID}/history.
+func ChatMessageHistory(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID, err := pathInt64(r, "/games/", 0)
+		if err != nil {
+			http.Error(w, "Invalid Game ID format", http.StatusBadRequest)
+			return
+		}
+		messageID, err := pathInt64(r, "/games/", 2)
+		if err != nil {
+			http.Error(w, "Invalid message ID format", http.StatusBadRequest)
+			return
+		}
+		message, err := database.GetChatMessageByID(db, messageID)
+		if err != nil || message.GameID != gameID || message.IsDeleted() {
+			http.Error(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		revisions, err := database.GetChatMessageRevisions(db, messageID)
+		if err != nil {
+			fmt.Printf("Error fetching revisions for chat message %d: %v\n", messageID, err)
+			http.Error(w, "Failed to load message history.", http.StatusInternalServerError)
+			return
+		}
+		RenderTemplate(w, "games/_chat_history.html", map[string]interface{}{
+			"Message":   message,
+			"Revisions": revisions,
+		})
+	}
+}
 
-		data := map[string]interface{}{
-			"ChatMessages": updatedChatMessages,
-			"GameID": gameID, // For the form action URL in the partial, if form is part of it
-			"User": currentUser, // For conditional rendering in the partial (e.g. showing form)
+// SetChatMute mutes or unmutes a user in a game's chat:
+// POST /games/{id}/chat/mute or /games/{id}/chat/unmute with a 'user_id' field.
+// Only the game's GM may do this. This handler should be wrapped by AuthMiddleware.
+func SetChatMute(db *sql.DB, mute bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			w.Header().Set("HX-Redirect", "/login")
+			http.Error(w, "User not authenticated", http.StatusUnauthorized)
+			return
 		}
-		RenderTemplate(w, "games/_chat_messages.html", data)
+		gameID, err := pathInt64(r, "/games/", 0)
+		if err != nil {
+			http.Error(w, "Invalid Game ID format", http.StatusBadRequest)
+			return
+		}
+		game, err := database.GetGameByID(db, gameID)
+		if err != nil {
+			http.Error(w, "Game not found", http.StatusNotFound)
+			return
+		}
+		if !game.IsGM(currentUser.ID) {
+			http.Error(w, "Only the GM can mute players in this game.", http.StatusForbidden)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+		userID, err := strconv.ParseInt(r.FormValue("user_id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		if userID == currentUser.ID {
+			renderChatError(w, db, gameID, currentUser, "You cannot mute yourself.")
+			return
+		}
+		if mute && game.IsGM(userID) {
+			renderChatError(w, db, gameID, currentUser, "You cannot mute a GM of this game.")
+			return
+		}
+
+		if mute {
+			err = database.MuteUserInGame(db, gameID, userID, currentUser.ID)
+		} else {
+			err = database.UnmuteUserInGame(db, gameID, userID)
+		}
+		if err != nil {
+			fmt.Printf("Error updating chat mute for user %d in game %d: %v\n", userID, gameID, err)
+			http.Error(w, "Failed to update mute. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		renderChatSection(w, db, gameID, currentUser)
+	}
+}
+
+// recordChatMentions resolves the @mentions in a message to users and stores them, so
+// they render as profile links. Users mentioned for the first time in this message
+// (on posting, or added by an edit) get a notification; authors aren't notified of
+// their own mentions, and users who blocked the author aren't notified at all.
+func recordChatMentions(db *sql.DB, message *models.ChatMessage, author *models.User) error {
+	if message.Roll != nil {
+		return nil // Roll labels are plain text
+	}
+	users, err := database.GetUsersByUsernames(db, markdown.Mentions(message.MessageContent))
+	if err != nil {
+		return err
+	}
+	userIDs := make([]int64, len(users))
+	for i, u := range users {
+		userIDs[i] = u.ID
+	}
+	added, err := database.SetChatMentions(db, message.ID, userIDs)
+	if err != nil || len(added) == 0 {
+		return err
+	}
+
+	game, err := database.GetGameByID(db, message.GameID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range added {
+		if userID == author.ID {
+			continue
+		}
+		blocked, err := database.IsUserBlocked(db, userID, author.ID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			continue
+		}
+		_, err = database.CreateNotification(db, &models.Notification{
+			UserID:  userID,
+			Kind:    models.NotificationKindMention,
+			Message: fmt.Sprintf("%s mentioned you in the chat for %s", author.DisplayName(), game.Title),
+			Link:    fmt.Sprintf("/games/%d#chat-message-%d", game.ID, message.ID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadChatMessageForAction resolves the current user and the message addressed by
+// /games/{id}/chat/{messageID}/..., writing an error response and returning ok=false on failure.
+func loadChatMessageForAction(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.User, *models.ChatMessage, bool) {
+	currentUser, err := GetCurrentUser(r, db)
+	if err != nil {
+		w.Header().Set("HX-Redirect", "/login")
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	gameID, err := pathInt64(r, "/games/", 0)
+	if err != nil {
+		http.Error(w, "Invalid Game ID format", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	messageID, err := pathInt64(r, "/games/", 2)
+	if err != nil {
+		http.Error(w, "Invalid message ID format", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	message, err := database.GetChatMessageByID(db, messageID)
+	if err != nil || message.GameID != gameID {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if message.IsDeleted() {
+		renderChatError(w, db, gameID, currentUser, "This message has been deleted.")
+		return nil, nil, false
+	}
+	return currentUser, message, true
+}
+
+// ChatPageSize is how many messages the chat shows at once, and how many
+// "load older" fetches per click.
+const ChatPageSize = 50
+
+// chatViewerData builds the template data shared by the chat partials: who is viewing
+// and what they may do (edit window, GM moderation, mute state), and whose messages
+// they have blocked.
+func chatViewerData(db *sql.DB, gameID int64, currentUser *models.User) (map[string]interface{}, error) {
+	data := map[string]interface{}{
+		"GameID":  gameID,      // For the form action URL in the partial
+		"User":    currentUser, // For conditional rendering in the partial (e.g. showing form)
+		"Now":     time.Now(),  // For the edit window
+		"IsGM":    false,
+		"IsMuted": false,
+	}
+	if currentUser == nil {
+		return data, nil
+	}
+	blocked, err := database.GetBlockedUserIDs(db, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	data["BlockedUserIDs"] = blocked
+
+	game, err := database.GetGameByID(db, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game.IsGM(currentUser.ID) {
+		data["IsGM"] = true
+		mutes, err := database.GetChatMutesForGame(db, gameID)
+		if err != nil {
+			return nil, err
+		}
+		data["ChatMutes"] = mutes
+	} else {
+		muted, err := database.IsUserMutedInGame(db, gameID, currentUser.ID)
+		if err != nil {
+			return nil, err
+		}
+		data["IsMuted"] = muted
+	}
+	return data, nil
+}
+
+// addChatPage adds the page of messages before beforeID (0 for the latest) to data,
+// with the cursors the partials need to load older or newer messages.
+func addChatPage(db *sql.DB, data map[string]interface{}, gameID int64, beforeID int64) error {
+	messages, hasOlder, err := database.GetChatMessagesPage(db, gameID, beforeID, ChatPageSize)
+	if err != nil {
+		return err
+	}
+	data["ChatMessages"] = withoutBlockedAuthors(data, messages)
+	data["HasOlder"] = hasOlder
+	// The cursors come from the whole page, so hidden messages aren't fetched again.
+	data["OldestID"], data["NewestID"] = int64(0), int64(0)
+	if len(messages) > 0 {
+		data["OldestID"] = messages[0].ID
+		data["NewestID"] = messages[len(messages)-1].ID
+	}
+	return nil
+}
+
+// withoutBlockedAuthors drops the messages by users the viewer has blocked, as
+// loaded into data by chatViewerData.
+func withoutBlockedAuthors(data map[string]interface{}, messages []*models.ChatMessage) []*models.ChatMessage {
+	blocked, _ := data["BlockedUserIDs"].(map[int64]bool)
+	if len(blocked) == 0 {
+		return messages
+	}
+	var visible []*models.ChatMessage
+	for _, m := range messages {
+		if !blocked[m.UserID] {
+			visible = append(visible, m)
+		}
+	}
+	return visible
+}
+
+// chatSectionData builds the template data used by the _chat_messages.html partial:
+// the latest page of messages plus the viewer's permissions.
+func chatSectionData(db *sql.DB, gameID int64, currentUser *models.User) (map[string]interface{}, error) {
+	data, err := chatViewerData(db, gameID, currentUser)
+	if err != nil {
+		return nil, err
+	}
+	if err := addChatPage(db, data, gameID, 0); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// renderChatSection renders the chat messages partial for an htmx swap.
+func renderChatSection(w http.ResponseWriter, db *sql.DB, gameID int64, currentUser *models.User) {
+	renderChatError(w, db, gameID, currentUser, "")
+}
+
+// renderChatError re-renders the chat messages partial with a validation error (if errMsg is set).
+// It's important that the client-side target for this error is correct.
+// If the form itself is inside the hx-target, this will replace the form and messages.
+func renderChatError(w http.ResponseWriter, db *sql.DB, gameID int64, currentUser *models.User, errMsg string) {
+	data, err := chatSectionData(db, gameID, currentUser)
+	if err != nil {
+		fmt.Printf("Error fetching chat messages for game %d: %v\n", gameID, err)
+		http.Error(w, "Failed to refresh chat messages.", http.StatusInternalServerError)
+		return
+	}
+	if errMsg != "" {
+		data["Error"] = errMsg
+	}
+	RenderTemplate(w, "games/_chat_messages.html", data)
+}
+
+// renderNewChatMessages renders messages to append to the chat list, moving the
+// client's "after" cursor forward and showing errMsg (if set) in #chat-error.
+func renderNewChatMessages(w http.ResponseWriter, db *sql.DB, gameID int64, currentUser *models.User, messages []*models.ChatMessage, afterID int64, errMsg string) {
+	data, err := chatViewerData(db, gameID, currentUser)
+	if err != nil {
+		fmt.Printf("Error loading chat permissions for game %d: %v\n", gameID, err)
+		http.Error(w, "Failed to refresh chat messages.", http.StatusInternalServerError)
+		return
+	}
+	data["ChatMessages"] = withoutBlockedAuthors(data, messages)
+	data["NewestID"] = afterID
+	if len(messages) > 0 && messages[len(messages)-1].ID > afterID {
+		data["NewestID"] = messages[len(messages)-1].ID
+	}
+	if errMsg != "" {
+		data["Error"] = errMsg
 	}
+	RenderTemplate(w, "games/_chat_new_messages.html", data)
 }
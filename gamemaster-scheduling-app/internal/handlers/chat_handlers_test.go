@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/dice"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestPostChatMessageRollCommand(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	DiceRoller = dice.NewRoller(1) // Reproducible rolls for this test
+
+	authedClient, user := ts.registerAndLoginUser(t, "roller@example.com", "password")
+	testGame := ts.createTestGameDirectly(t, user.ID, "Dice Game")
+	chatURL := ts.server.URL + "/games/" + strconv.FormatInt(testGame.ID, 10) + "/chat"
+
+	t.Run("POST /roll stores a structured roll", func(t *testing.T) {
+		resp, err := authedClient.PostForm(chatURL, url.Values{"message_content": {"/roll 1d20+5 adv Stealth"}})
+		if err != nil {
+			t.Fatalf("POST roll failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST roll status = %d; want %d. Body: %s", resp.StatusCode, http.StatusOK, string(body))
+		}
+		for _, want := range []string{`class="dice-roll"`, "2d20kh1", "Stealth"} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("POST roll response missing %q. Body: %s", want, string(body))
+			}
+		}
+
+		messages, _ := database.GetChatMessagesForGame(ts.db, testGame.ID)
+		if len(messages) != 1 || messages[0].Roll == nil {
+			t.Fatalf("expected one message with a stored roll, got %+v", messages)
+		}
+		roll := messages[0].Roll
+		if roll.Label != "Stealth" || len(roll.Groups) != 2 || len(roll.Groups[0].Dice) != 2 {
+			t.Errorf("stored roll = %+v, want 2d20kh1+5 labelled Stealth", roll)
+		}
+		if roll.Total < 6 || roll.Total > 25 {
+			t.Errorf("roll total = %d, want between 6 and 25", roll.Total)
+		}
+	})
+
+	t.Run("typed fake roll is rendered as text", func(t *testing.T) {
+		fake := `<div class="dice-roll">rolled a 20</div>`
+		resp, err := authedClient.PostForm(chatURL, url.Values{"message_content": {fake}})
+		if err != nil {
+			t.Fatalf("POST fake roll failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if strings.Contains(string(body), fake) {
+			t.Errorf("fake roll markup was rendered unescaped. Body: %s", string(body))
+		}
+		messages, _ := database.GetChatMessagesForGame(ts.db, testGame.ID)
+		if last := messages[len(messages)-1]; last.Roll != nil {
+			t.Errorf("plain text message stored a roll: %+v", last.Roll)
+		}
+	})
+
+	t.Run("invalid and unknown commands show an error", func(t *testing.T) {
+		for content, want := range map[string]string{
+			"/roll 1d0":  "number of sides",
+			"/dance now": "unknown command /dance",
+		} {
+			resp, err := authedClient.PostForm(chatURL, url.Values{"message_content": {content}})
+			if err != nil {
+				t.Fatalf("POST %q failed: %v", content, err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if !strings.Contains(string(body), want) {
+				t.Errorf("POST %q response missing %q. Body: %s", content, want, string(body))
+			}
+		}
+		messages, _ := database.GetChatMessagesForGame(ts.db, testGame.ID)
+		if len(messages) != 2 {
+			t.Errorf("rejected commands were stored: %d messages, want 2", len(messages))
+		}
+	})
+}
+
+func TestChatEditDeleteAndModeration(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "mod_gm@example.com", "gmpass")
+	playerClient, player := ts.newUserClient(t, "mod_player@example.com", "password")
+	otherClient, _ := ts.newUserClient(t, "mod_other@example.com", "password")
+
+	game := ts.createTestGameDirectly(t, gm.ID, "Moderated Game")
+	chatURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10) + "/chat"
+
+	newMessage := func(content string) *models.ChatMessage {
+		msg, err := database.CreateChatMessage(ts.db, &models.ChatMessage{GameID: game.ID, UserID: player.ID, MessageContent: content})
+		if err != nil {
+			t.Fatalf("CreateChatMessage() error = %v", err)
+		}
+		return msg
+	}
+	msgURL := func(msg *models.ChatMessage, action string) string {
+		return chatURL + "/" + strconv.FormatInt(msg.ID, 10) + "/" + action
+	}
+
+	t.Run("author edits within window", func(t *testing.T) {
+		msg := newMessage("Se you at 7")
+		status, body := postForm(t, playerClient, msgURL(msg, "edit"), url.Values{"message_content": {"See you at 7"}})
+		if status != http.StatusOK || !strings.Contains(body, "See you at 7") || !strings.Contains(body, "(edited)") {
+			t.Fatalf("edit status = %d, body missing new content or edited marker: %s", status, body)
+		}
+		revisions, _ := database.GetChatMessageRevisions(ts.db, msg.ID)
+		if len(revisions) != 1 || revisions[0].PreviousContent != "Se you at 7" {
+			t.Errorf("revisions = %+v, want the original content", revisions)
+		}
+
+		resp, err := playerClient.Get(msgURL(msg, "history"))
+		if err != nil {
+			t.Fatalf("GET history failed: %v", err)
+		}
+		historyBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(historyBody), "Se you at 7") {
+			t.Errorf("history does not show the previous version: %s", historyBody)
+		}
+
+		// The history is only served under the message's own game.
+		otherGame := ts.createTestGameDirectly(t, gm.ID, "Unrelated Game")
+		otherURL := ts.server.URL + "/games/" + strconv.FormatInt(otherGame.ID, 10) + "/chat/" + strconv.FormatInt(msg.ID, 10) + "/history"
+		if status, body := getBody(t, otherClient, otherURL); status != http.StatusNotFound || strings.Contains(body, "Se you at 7") {
+			t.Errorf("history under another game status = %d, want %d", status, http.StatusNotFound)
+		}
+	})
+
+	t.Run("edits are enforced server-side", func(t *testing.T) {
+		msg := newMessage("original")
+		if status, _ := postForm(t, otherClient, msgURL(msg, "edit"), url.Values{"message_content": {"hijacked"}}); status != http.StatusForbidden {
+			t.Errorf("editing someone else's message status = %d, want %d", status, http.StatusForbidden)
+		}
+		if status, _ := postForm(t, gmClient, msgURL(msg, "edit"), url.Values{"message_content": {"gm rewrite"}}); status != http.StatusForbidden {
+			t.Errorf("GM editing a player's message status = %d, want %d", status, http.StatusForbidden)
+		}
+
+		if _, err := ts.db.Exec("UPDATE chat_messages SET created_at = datetime('now', '-1 hour') WHERE id = ?", msg.ID); err != nil {
+			t.Fatalf("backdating message failed: %v", err)
+		}
+		_, body := postForm(t, playerClient, msgURL(msg, "edit"), url.Values{"message_content": {"too late"}})
+		if !strings.Contains(body, "can no longer be edited") {
+			t.Errorf("edit after window was not rejected: %s", body)
+		}
+		stored, _ := database.GetChatMessageByID(ts.db, msg.ID)
+		if stored.MessageContent != "original" {
+			t.Errorf("message content = %q after rejected edits, want %q", stored.MessageContent, "original")
+		}
+	})
+
+	t.Run("overlong messages are rejected", func(t *testing.T) {
+		long := strings.Repeat("é", models.MaxChatMessageLength+1)
+		_, body := postForm(t, playerClient, chatURL, url.Values{"message_content": {long}})
+		if !strings.Contains(body, "Messages can be at most") {
+			t.Errorf("overlong post was not rejected: %.200s", body)
+		}
+		msg := newMessage("short")
+		_, body = postForm(t, playerClient, msgURL(msg, "edit"), url.Values{"message_content": {long}})
+		if !strings.Contains(body, "Messages can be at most") {
+			t.Errorf("overlong edit was not rejected: %.200s", body)
+		}
+		messages, _ := database.GetChatMessagesForGame(ts.db, game.ID)
+		for _, m := range messages {
+			if m.MessageContent == long {
+				t.Errorf("overlong message %d was stored", m.ID)
+			}
+		}
+
+		if status, body := postForm(t, playerClient, chatURL, url.Values{"message_content": {strings.Repeat("é", models.MaxChatMessageLength)}}); status != http.StatusOK || strings.Contains(body, "Messages can be at most") {
+			t.Errorf("message at the limit was rejected: %d", status)
+		}
+	})
+
+	t.Run("author and GM delete, others cannot", func(t *testing.T) {
+		own := newMessage("oops, wrong game")
+		status, body := postForm(t, playerClient, msgURL(own, "delete"), nil)
+		if status != http.StatusOK || !strings.Contains(body, "Message deleted by its author.") || strings.Contains(body, "oops, wrong game") {
+			t.Errorf("author delete status = %d, body should show placeholder without content: %s", status, body)
+		}
+
+		abusive := newMessage("something abusive")
+		if status, _ := postForm(t, otherClient, msgURL(abusive, "delete"), nil); status != http.StatusForbidden {
+			t.Errorf("non-GM deleting another's message status = %d, want %d", status, http.StatusForbidden)
+		}
+		status, body = postForm(t, gmClient, msgURL(abusive, "delete"), nil)
+		if status != http.StatusOK || !strings.Contains(body, "Message removed by the GM.") || strings.Contains(body, "something abusive") {
+			t.Errorf("GM delete status = %d, body should show moderation placeholder: %s", status, body)
+		}
+	})
+
+	t.Run("GM mutes a player", func(t *testing.T) {
+		if status, _ := postForm(t, otherClient, chatURL+"/mute", url.Values{"user_id": {strconv.FormatInt(player.ID, 10)}}); status != http.StatusForbidden {
+			t.Errorf("non-GM mute status = %d, want %d", status, http.StatusForbidden)
+		}
+		status, body := postForm(t, gmClient, chatURL+"/mute", url.Values{"user_id": {strconv.FormatInt(player.ID, 10)}})
+		if status != http.StatusOK || !strings.Contains(body, "Muted in this chat") {
+			t.Fatalf("GM mute status = %d, body should list muted users: %s", status, body)
+		}
+
+		_, body = postForm(t, playerClient, chatURL, url.Values{"message_content": {"can anyone hear me"}})
+		if !strings.Contains(body, "You have been muted") {
+			t.Errorf("muted player's post was not rejected: %s", body)
+		}
+		messages, _ := database.GetChatMessagesForGame(ts.db, game.ID)
+		for _, m := range messages {
+			if m.MessageContent == "can anyone hear me" {
+				t.Errorf("muted player's message was stored")
+			}
+		}
+
+		// Nor can they keep talking by editing a recent message.
+		recent := newMessage("before the mute")
+		gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+		if _, body := getBody(t, playerClient, gameURL); strings.Contains(body, "<summary>Edit</summary>") {
+			t.Errorf("muted player is offered to edit: %s", body)
+		}
+		_, body = postForm(t, playerClient, msgURL(recent, "edit"), url.Values{"message_content": {"edited while muted"}})
+		if !strings.Contains(body, "You have been muted") {
+			t.Errorf("muted player's edit was not rejected: %s", body)
+		}
+		if stored, _ := database.GetChatMessageByID(ts.db, recent.ID); stored.MessageContent != "before the mute" {
+			t.Errorf("message content = %q after a muted edit, want it unchanged", stored.MessageContent)
+		}
+
+		postForm(t, gmClient, chatURL+"/unmute", url.Values{"user_id": {strconv.FormatInt(player.ID, 10)}})
+		if _, body := getBody(t, playerClient, gameURL); !strings.Contains(body, "<summary>Edit</summary>") {
+			t.Errorf("unmuted player is not offered to edit their recent message: %s", body)
+		}
+		if status, body := postForm(t, playerClient, chatURL, url.Values{"message_content": {"back again"}}); status != http.StatusOK || !strings.Contains(body, "back again") {
+			t.Errorf("unmuted player could not post: %d %s", status, body)
+		}
+	})
+}
+
+func TestChatPaginationAndIncrementalPosts(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	client, user := ts.newUserClient(t, "pager@example.com", "password")
+	game := ts.createTestGameDirectly(t, user.ID, "Long Campaign")
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+
+	var ids []int64
+	for i := 0; i < ChatPageSize+5; i++ {
+		msg, err := database.CreateChatMessage(ts.db, &models.ChatMessage{GameID: game.ID, UserID: user.ID, MessageContent: "message #" + strconv.Itoa(i) + "."})
+		if err != nil {
+			t.Fatalf("CreateChatMessage() error = %v", err)
+		}
+		ids = append(ids, msg.ID)
+	}
+
+	get := func(rawURL string) string {
+		resp, err := client.Get(rawURL)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", rawURL, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s status = %d. Body: %s", rawURL, resp.StatusCode, body)
+		}
+		return string(body)
+	}
+
+	t.Run("game page shows only the latest page", func(t *testing.T) {
+		body := get(gameURL)
+		if strings.Contains(body, "message #4.") || !strings.Contains(body, "message #5.") {
+			t.Errorf("game page should show messages 5..%d only", ChatPageSize+4)
+		}
+		wantOlder := "/chat?before=" + strconv.FormatInt(ids[5], 10)
+		if !strings.Contains(body, wantOlder) {
+			t.Errorf("game page missing load older trigger %q", wantOlder)
+		}
+	})
+
+	t.Run("load older returns the previous page", func(t *testing.T) {
+		body := get(gameURL + "/chat?before=" + strconv.FormatInt(ids[5], 10))
+		if !strings.Contains(body, "message #0.") || !strings.Contains(body, "message #4.") || strings.Contains(body, "message #5.") {
+			t.Errorf("older page should hold messages 0..4. Body: %s", body)
+		}
+		if strings.Contains(body, "Load older messages") {
+			t.Errorf("oldest page should not offer to load more")
+		}
+	})
+
+	t.Run("post returns only messages since the client's newest", func(t *testing.T) {
+		last := ids[len(ids)-1]
+		fromOther, _ := database.CreateChatMessage(ts.db, &models.ChatMessage{GameID: game.ID, UserID: user.ID, MessageContent: "posted meanwhile"})
+		status, body := postForm(t, client, gameURL+"/chat", url.Values{
+			"message_content": {"my new message"},
+			"after":           {strconv.FormatInt(last, 10)},
+		})
+		if status != http.StatusOK {
+			t.Fatalf("POST chat status = %d. Body: %s", status, body)
+		}
+		if !strings.Contains(body, "posted meanwhile") || !strings.Contains(body, "my new message") {
+			t.Errorf("POST should return both new messages. Body: %s", body)
+		}
+		if strings.Contains(body, "message #") {
+			t.Errorf("POST re-rendered already shown messages. Body: %s", body)
+		}
+		if !strings.Contains(body, `id="chat-after"`) || strings.Contains(body, `value="`+strconv.FormatInt(fromOther.ID, 10)+`" hx-swap-oob`) {
+			t.Errorf("POST should move the chat-after cursor to the posted message. Body: %s", body)
+		}
+	})
+
+	t.Run("polling with nothing new returns no messages", func(t *testing.T) {
+		messages, _ := database.GetChatMessagesForGame(ts.db, game.ID)
+		newest := messages[len(messages)-1].ID
+		body := get(gameURL + "/chat?after=" + strconv.FormatInt(newest, 10))
+		if strings.Contains(body, `class="chat-message`) {
+			t.Errorf("poll returned messages although nothing is new. Body: %s", body)
+		}
+	})
+}
+
+func TestChatMentionsAndNotifications(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "mention_gm@example.com", "gmpass")
+	aliceClient, alice := ts.newUserClient(t, "alice@example.com", "password")
+	_, bob := ts.newUserClient(t, "bob@example.com", "password")
+
+	// Alice picks a username from her profile; Bob has none, so he can't be mentioned.
+	status, _ := postForm(t, aliceClient, ts.server.URL+"/users/"+strconv.FormatInt(alice.ID, 10)+"/username", url.Values{"username": {"@Alice_W"}})
+	if status != http.StatusSeeOther {
+		t.Fatalf("setting username status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if u, _ := database.GetUserByID(ts.db, alice.ID); u.Username != "Alice_W" {
+		t.Fatalf("username = %q, want %q", u.Username, "Alice_W")
+	}
+
+	game := ts.createTestGameDirectly(t, gm.ID, "Mention Game")
+	chatURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10) + "/chat"
+
+	status, body := postForm(t, gmClient, chatURL, url.Values{"message_content": {"@alice_w bring **snacks**, @bob_nobody too"}})
+	if status != http.StatusOK {
+		t.Fatalf("post status = %d, body: %s", status, body)
+	}
+	profileLink := `<a class="mention" href="/users/` + strconv.FormatInt(alice.ID, 10) + `">@alice_w</a>`
+	if !strings.Contains(body, profileLink) || !strings.Contains(body, "<strong>snacks</strong>") {
+		t.Errorf("posted message missing mention link or formatting: %s", body)
+	}
+	if !strings.Contains(body, "@bob_nobody too") || strings.Contains(body, `href="/users/`+strconv.FormatInt(bob.ID, 10)+`"`) {
+		t.Errorf("unresolved mention should stay plain text: %s", body)
+	}
+
+	notifications, err := database.GetNotificationsForUser(ts.db, alice.ID, 10)
+	if err != nil || len(notifications) != 1 {
+		t.Fatalf("alice notifications = %v (err %v), want 1", notifications, err)
+	}
+	if n := notifications[0]; n.Kind != models.NotificationKindMention || !strings.Contains(n.Link, "/games/"+strconv.FormatInt(game.ID, 10)) {
+		t.Errorf("notification = %+v, want a mention linking to the game", n)
+	}
+	if gmNotes, _ := database.GetNotificationsForUser(ts.db, gm.ID, 10); len(gmNotes) != 0 {
+		t.Errorf("author got %d notifications, want 0", len(gmNotes))
+	}
+
+	t.Run("edits notify only newly mentioned users", func(t *testing.T) {
+		msgs, _ := database.GetChatMessagesForGame(ts.db, game.ID)
+		msg := msgs[len(msgs)-1]
+		editURL := chatURL + "/" + strconv.FormatInt(msg.ID, 10) + "/edit"
+		postForm(t, gmClient, editURL, url.Values{"message_content": {"@Alice_W bring snacks please"}})
+		if count, _ := database.CountUnreadNotifications(ts.db, alice.ID); count != 1 {
+			t.Errorf("unread after edit keeping the mention = %d, want 1", count)
+		}
+	})
+
+	t.Run("notification page and badge", func(t *testing.T) {
+		_, badge := getBody(t, aliceClient, ts.server.URL+"/notifications/count")
+		if !strings.Contains(badge, `<span class="badge">1</span>`) {
+			t.Errorf("badge = %s, want unread count 1", badge)
+		}
+		_, page := getBody(t, aliceClient, ts.server.URL+"/notifications")
+		if !strings.Contains(page, "mentioned you in the chat for Mention Game") {
+			t.Errorf("notifications page missing mention: %s", page)
+		}
+		if status, _ := postForm(t, aliceClient, ts.server.URL+"/notifications/read", nil); status != http.StatusSeeOther {
+			t.Errorf("mark read status = %d, want %d", status, http.StatusSeeOther)
+		}
+		if count, _ := database.CountUnreadNotifications(ts.db, alice.ID); count != 0 {
+			t.Errorf("unread after mark read = %d, want 0", count)
+		}
+	})
+
+	t.Run("blocked authors don't notify", func(t *testing.T) {
+		if err := database.BlockUser(ts.db, alice.ID, gm.ID); err != nil {
+			t.Fatalf("BlockUser() error = %v", err)
+		}
+		postForm(t, gmClient, chatURL, url.Values{"message_content": {"@alice_w are you there?"}})
+		if count, _ := database.CountUnreadNotifications(ts.db, alice.ID); count != 0 {
+			t.Errorf("unread after a mention by a blocked user = %d, want 0", count)
+		}
+		database.UnblockUser(ts.db, alice.ID, gm.ID)
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		status, page := getBody(t, gmClient, ts.server.URL+"/users/"+strconv.FormatInt(alice.ID, 10))
+		if status != http.StatusOK || !strings.Contains(page, "@Alice_W") {
+			t.Errorf("profile status = %d, missing username: %s", status, page)
+		}
+		if strings.Contains(page, `/username" method="POST"`) {
+			t.Errorf("another user's profile shows the username form")
+		}
+		if status, _ := postForm(t, gmClient, ts.server.URL+"/users/"+strconv.FormatInt(alice.ID, 10)+"/username", url.Values{"username": {"hijack"}}); status != http.StatusForbidden {
+			t.Errorf("changing someone else's username status = %d, want %d", status, http.StatusForbidden)
+		}
+		_, page = postForm(t, gmClient, ts.server.URL+"/users/"+strconv.FormatInt(gm.ID, 10)+"/username", url.Values{"username": {"alice_w"}})
+		if !strings.Contains(page, "already taken") {
+			t.Errorf("duplicate username (different case) was not rejected: %s", page)
+		}
+		_, page = postForm(t, gmClient, ts.server.URL+"/users/"+strconv.FormatInt(gm.ID, 10)+"/username", url.Values{"username": {"<b>x</b>"}})
+		if !strings.Contains(page, "3-30 letters") {
+			t.Errorf("invalid username was not rejected: %s", page)
+		}
+	})
+}
+
+func TestChatMarkdownEscapesHostileInput(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	client, user := ts.newUserClient(t, "xss@example.com", "password")
+	game := ts.createTestGameDirectly(t, user.ID, "XSS Game")
+	chatURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10) + "/chat"
+
+	hostile := `<script>alert(1)</script> [x](javascript:alert(1)) <img src=x onerror=alert(1)>`
+	_, body := postForm(t, client, chatURL, url.Values{"message_content": {hostile}})
+	for _, bad := range []string{"<script>alert", "<img", `href="javascript:`} {
+		if strings.Contains(body, bad) {
+			t.Errorf("chat response contains %q: %s", bad, body)
+		}
+	}
+	if !strings.Contains(body, "&lt;script&gt;") {
+		t.Errorf("hostile markup was not shown escaped: %s", body)
+	}
+}
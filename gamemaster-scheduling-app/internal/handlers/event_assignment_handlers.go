@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gamemaster-scheduling/app/internal/assignment"
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// seatingTable is a table in the preview of a ranked slot's seating, with the
+// players seated at it.
+type seatingTable struct {
+	Table   *models.EventTable
+	Players []*seatingPlayer
+}
+
+// Overbooked reports whether more players are seated than the table has free seats.
+func (t *seatingTable) Overbooked() bool {
+	return len(t.Players) > t.Table.SeatsLeft()
+}
+
+// seatingPlayer is a player in the preview of a ranked slot's seating.
+type seatingPlayer struct {
+	Choices *models.EventChoices
+	Rank    int  // Of their table among their choices, from 1; 0 if it isn't one or they have no table
+	Pinned  bool // The organizer placed them by hand
+}
+
+// slotSeating is the seating of a ranked slot as it would be published now.
+type slotSeating struct {
+	Tables   []*seatingTable
+	Unseated []*seatingPlayer
+	Result   *assignment.Result
+}
+
+// slotFromPath loads the slot /events/{id}/slots/{slotID}/... of the event,
+// rendering an error page if there is no such slot.
+func slotFromPath(w http.ResponseWriter, r *http.Request, db *sql.DB, event *models.Event) (*models.EventSlot, bool) {
+	slotID, err := pathInt64(r, "/events/", 2)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid slot ID format.")
+		return nil, false
+	}
+	slot, err := database.GetEventSlotByID(db, slotID)
+	if err != nil || slot.EventID != event.ID {
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		} else {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Slot not found.")
+		}
+		return nil, false
+	}
+	return slot, true
+}
+
+// rankedSlotForOrganizer loads the event and slot in the path, and checks that
+// the current user organizes the event and that the slot's seating is still open.
+func rankedSlotForOrganizer(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.Event, *models.EventSlot, *models.User, bool) {
+	event, currentUser, ok := eventForOrganizer(w, r, db)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	slot, ok := slotFromPath(w, r, db, event)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	if !slot.TakesChoices() {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "This slot's seating isn't open for changes.")
+		return nil, nil, nil, false
+	}
+	return event, slot, currentUser, true
+}
+
+// slotTables retrieves the tables of a slot that players can be seated at,
+// leaving out cancelled ones.
+func slotTables(db *sql.DB, slot *models.EventSlot) ([]*models.EventTable, error) {
+	tables, err := database.GetEventTables(db, slot.EventID)
+	if err != nil {
+		return nil, err
+	}
+	var inSlot []*models.EventTable
+	for _, t := range tables {
+		if t.Game.EventSlotID == slot.ID && !t.Game.IsCancelled() {
+			inSlot = append(inSlot, t)
+		}
+	}
+	return inSlot, nil
+}
+
+// solveSlot seats the players who ranked a slot's tables, with the organizer's
+// pins and the slot's lottery seed.
+func solveSlot(db *sql.DB, slot *models.EventSlot) (*slotSeating, error) {
+	tables, err := slotTables(db, slot)
+	if err != nil {
+		return nil, err
+	}
+	choices, err := database.GetEventChoices(db, slot.ID)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := database.GetEventAssignmentPins(db, slot.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	problem := assignment.Problem{Pins: pins, Seed: slot.Seed}
+	seating := &slotSeating{}
+	byGame := make(map[int64]*seatingTable, len(tables))
+	for _, t := range tables {
+		problem.Tables = append(problem.Tables, assignment.Table{ID: t.Game.ID, Seats: t.SeatsLeft()})
+		byGame[t.Game.ID] = &seatingTable{Table: t}
+		seating.Tables = append(seating.Tables, byGame[t.Game.ID])
+	}
+	for _, c := range choices {
+		problem.Players = append(problem.Players, assignment.Player{ID: c.UserID, Choices: c.GameIDs})
+	}
+	seating.Result = assignment.Solve(problem)
+
+	for _, c := range choices {
+		_, pinned := pins[c.UserID]
+		player := &seatingPlayer{Choices: c, Rank: seating.Result.Ranks[c.UserID], Pinned: pinned}
+		if gameID, ok := seating.Result.Tables[c.UserID]; ok {
+			byGame[gameID].Players = append(byGame[gameID].Players, player)
+		} else {
+			seating.Unseated = append(seating.Unseated, player)
+		}
+	}
+	for _, t := range seating.Tables {
+		sort.Slice(t.Players, func(i, j int) bool { return t.Players[i].Choices.UserEmail < t.Players[j].Choices.UserEmail })
+	}
+	return seating, nil
+}
+
+// SubmitEventChoices saves the current user's ranked choices in a ranked slot:
+// POST /events/{id}/slots/{slotID}/choices with choice_1 to choice_3, the game
+// IDs of their first to third choices. Empty choices are skipped, and no
+// choices at all withdraws the user from the slot's seating.
+// This handler should be wrapped by AuthMiddleware.
+func SubmitEventChoices(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		event, ok := eventFromPath(w, r, db)
+		if !ok {
+			return
+		}
+		slot, ok := slotFromPath(w, r, db, event)
+		if !ok {
+			return
+		}
+		fail := func(msg string) { renderEventPage(w, r, db, event, currentUser, nil, msg) }
+
+		if !slot.TakesChoices() {
+			fail(fmt.Sprintf("The %s slot isn't taking ranked choices.", slot.Name))
+			return
+		}
+		if _, err := database.GetEventRegistration(db, event.ID, currentUser.ID); err != nil {
+			if err == sql.ErrNoRows {
+				fail("Register for a badge before ranking tables.")
+				return
+			}
+			fmt.Printf("Error fetching registration of user %d for event %d: %v\n", currentUser.ID, event.ID, err)
+			http.Error(w, "Failed to save your choices. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		if other, err := database.GetSlotSignup(db, currentUser.ID, slot.ID, 0); err == nil {
+			fail(fmt.Sprintf("You're already at %s in the %s slot.", other.Title, slot.Name))
+			return
+		} else if err != sql.ErrNoRows {
+			fmt.Printf("Error checking slot %d sign-up of user %d: %v\n", slot.ID, currentUser.ID, err)
+			http.Error(w, "Failed to save your choices. Please try again.", http.StatusInternalServerError)
+			return
+		}
+
+		tables, err := slotTables(db, slot)
+		if err != nil {
+			fmt.Printf("Error fetching tables of slot %d: %v\n", slot.ID, err)
+			http.Error(w, "Failed to save your choices. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		inSlot := make(map[int64]bool, len(tables))
+		for _, t := range tables {
+			inSlot[t.Game.ID] = true
+		}
+		var gameIDs []int64
+		chosen := make(map[int64]bool)
+		for i := 1; i <= models.MaxEventChoices; i++ {
+			v := r.FormValue(fmt.Sprintf("choice_%d", i))
+			if v == "" {
+				continue
+			}
+			gameID, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || !inSlot[gameID] {
+				fail(fmt.Sprintf("Choose among the tables of the %s slot.", slot.Name))
+				return
+			}
+			if chosen[gameID] {
+				fail("Rank each table only once.")
+				return
+			}
+			chosen[gameID] = true
+			gameIDs = append(gameIDs, gameID)
+		}
+
+		if err := database.SetEventChoices(db, slot.ID, currentUser.ID, gameIDs); err != nil {
+			fmt.Printf("Error saving choices of user %d in slot %d: %v\n", currentUser.ID, slot.ID, err)
+			http.Error(w, "Failed to save your choices. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d", event.ID), http.StatusSeeOther)
+	}
+}
+
+// EventAssignmentsPage previews the seating of a ranked slot for the organizer:
+// GET /events/{id}/slots/{slotID}/assignments. This handler should be wrapped
+// by AuthMiddleware.
+func EventAssignmentsPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, slot, currentUser, ok := rankedSlotForOrganizer(w, r, db)
+		if !ok {
+			return
+		}
+		seating, err := solveSlot(db, slot)
+		if err != nil {
+			fmt.Printf("Error seating slot %d: %v\n", slot.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not work out the seating.")
+			return
+		}
+		RenderTemplate(w, "events/assignments.html", map[string]interface{}{
+			"Title":   slot.Name + " seating",
+			"User":    currentUser,
+			"Event":   event,
+			"Slot":    slot,
+			"Seating": seating,
+			"Ranks":   choiceRanks(),
+		})
+	}
+}
+
+// PinEventAssignment tweaks the seating of a ranked slot:
+// POST /events/{id}/slots/{slotID}/assignments with a user_id and the game_id
+// of the table to seat them at, 0 to leave them without one, or empty to let
+// their choices decide again. This handler should be wrapped by AuthMiddleware.
+func PinEventAssignment(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, slot, _, ok := rankedSlotForOrganizer(w, r, db)
+		if !ok {
+			return
+		}
+		userID, err := strconv.ParseInt(r.FormValue("user_id"), 10, 64)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid user ID format.")
+			return
+		}
+
+		if v := r.FormValue("game_id"); v == "" {
+			err = database.ClearEventAssignmentPin(db, slot.ID, userID)
+		} else {
+			gameID, perr := strconv.ParseInt(v, 10, 64)
+			if perr != nil {
+				RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid table ID format.")
+				return
+			}
+			if gameID != 0 {
+				game, gerr := database.GetGameByID(db, gameID)
+				if gerr != nil || game.EventSlotID != slot.ID {
+					RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Choose one of the slot's tables.")
+					return
+				}
+			}
+			err = database.SetEventAssignmentPin(db, slot.ID, userID, gameID)
+		}
+		if err != nil {
+			fmt.Printf("Error pinning user %d in slot %d: %v\n", userID, slot.ID, err)
+			http.Error(w, "Failed to change the seating. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d/slots/%d/assignments", event.ID, slot.ID), http.StatusSeeOther)
+	}
+}
+
+// SetEventSlotSeed redraws the lottery of a ranked slot:
+// POST /events/{id}/slots/{slotID}/seed with the new seed.
+// This handler should be wrapped by AuthMiddleware.
+func SetEventSlotSeed(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, slot, _, ok := rankedSlotForOrganizer(w, r, db)
+		if !ok {
+			return
+		}
+		seed, err := strconv.ParseInt(r.FormValue("seed"), 10, 64)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "The seed must be a whole number.")
+			return
+		}
+		if err := database.SetEventSlotSeed(db, slot.ID, seed); err != nil {
+			fmt.Printf("Error setting seed of slot %d: %v\n", slot.ID, err)
+			http.Error(w, "Failed to change the seed. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d/slots/%d/assignments", event.ID, slot.ID), http.StatusSeeOther)
+	}
+}
+
+// PublishEventAssignments makes a ranked slot's previewed seating final:
+// POST /events/{id}/slots/{slotID}/publish. Seated players get an attending
+// RSVP for their table, and everyone who ranked tables is notified.
+// This handler should be wrapped by AuthMiddleware.
+func PublishEventAssignments(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, slot, currentUser, ok := rankedSlotForOrganizer(w, r, db)
+		if !ok {
+			return
+		}
+		seating, err := solveSlot(db, slot)
+		if err == nil {
+			err = database.PublishEventAssignments(db, slot.ID, currentUser.ID, seating.Result.Tables)
+		}
+		if err != nil {
+			if err == database.ErrAssignmentsPublished {
+				RenderErrorPage(w, r, db, http.StatusConflict, "Conflict", "This slot's seating was already published.")
+				return
+			}
+			fmt.Printf("Error publishing seating of slot %d: %v\n", slot.ID, err)
+			http.Error(w, "Failed to publish the seating. Please try again.", http.StatusInternalServerError)
+			return
+		}
+
+		notify := func(userID int64, message, link string) {
+			_, err := database.CreateNotification(db, &models.Notification{
+				UserID: userID, Kind: models.NotificationKindEventSeating, Message: message, Link: link,
+			})
+			if err != nil {
+				fmt.Printf("Error notifying user %d of slot %d seating: %v\n", userID, slot.ID, err)
+			}
+		}
+		for _, t := range seating.Tables {
+			for _, p := range t.Players {
+				notify(p.Choices.UserID, fmt.Sprintf("You have a seat at %s in the %s slot of %s.", t.Table.Game.Title, slot.Name, event.Name), fmt.Sprintf("/games/%d", t.Table.Game.ID))
+			}
+		}
+		for _, p := range seating.Unseated {
+			notify(p.Choices.UserID, fmt.Sprintf("There was no seat for you at your choices in the %s slot of %s. Any seats left are now first come, first served.", slot.Name, event.Name), fmt.Sprintf("/events/%d", event.ID))
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d", event.ID), http.StatusSeeOther)
+	}
+}
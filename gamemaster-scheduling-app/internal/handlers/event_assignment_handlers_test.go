@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestEventRankedSeating(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	orgClient, org := ts.newUserClient(t, "ranked_org@example.com", "orgpass")
+	aliceClient, alice := ts.newUserClient(t, "ranked_alice@example.com", "password")
+	bobClient, bob := ts.newUserClient(t, "ranked_bob@example.com", "password")
+	carolClient, carol := ts.newUserClient(t, "ranked_carol@example.com", "password")
+
+	event := &models.Event{OrganizerID: org.ID, Name: "RankCon", StartsOn: "2030-06-01"}
+	database.CreateEvent(ts.db, event)
+	eventURL := ts.server.URL + "/events/" + strconv.FormatInt(event.ID, 10)
+	postForm(t, orgClient, eventURL+"/slots", url.Values{"name": {"Morning"}, "starts_at": {"2030-06-01T09:00"}, "ends_at": {"2030-06-01T13:00"}, "ranked": {"1"}})
+	slots, _ := database.GetEventSlots(ts.db, event.ID)
+	if len(slots) != 1 || !slots[0].Ranked {
+		t.Fatalf("slots = %v, want one ranked slot", slots)
+	}
+	slot := strconv.FormatInt(slots[0].ID, 10)
+	slotURL := eventURL + "/slots/" + slot
+	postForm(t, orgClient, eventURL+"/tables", url.Values{"title": {"Dragons"}, "slot_id": {slot}, "max_players": {"1"}})
+	postForm(t, orgClient, eventURL+"/tables", url.Values{"title": {"Goblins"}, "slot_id": {slot}, "max_players": {"2"}})
+	tables, _ := database.GetEventTables(ts.db, event.ID)
+	dragons, goblins := strconv.FormatInt(tables[0].Game.ID, 10), strconv.FormatInt(tables[1].Game.ID, 10)
+	for _, c := range []*http.Client{aliceClient, bobClient} {
+		postForm(t, c, eventURL+"/register", nil)
+	}
+
+	if _, body := postForm(t, aliceClient, ts.server.URL+"/games/"+dragons+"/rsvp", url.Values{"status": {"attending"}}); !strings.Contains(body, "Seats in the Morning slot are assigned from ranked choices") {
+		t.Errorf("direct sign-up in a ranked slot was not refused: %s", body)
+	}
+	if _, body := postForm(t, carolClient, slotURL+"/choices", url.Values{"choice_1": {dragons}}); !strings.Contains(body, "Register for a badge before ranking tables.") {
+		t.Errorf("ranking without a badge was not refused: %s", body)
+	}
+	if _, body := postForm(t, aliceClient, slotURL+"/choices", url.Values{"choice_1": {dragons}, "choice_2": {dragons}}); !strings.Contains(body, "Rank each table only once.") {
+		t.Errorf("ranking a table twice was not refused: %s", body)
+	}
+	for _, c := range []*http.Client{aliceClient, bobClient} {
+		if status, _ := postForm(t, c, slotURL+"/choices", url.Values{"choice_1": {dragons}, "choice_2": {goblins}}); status != http.StatusSeeOther {
+			t.Errorf("ranking status = %d, want %d", status, http.StatusSeeOther)
+		}
+	}
+	if _, body := getBody(t, aliceClient, eventURL); !strings.Contains(body, `<option value="`+dragons+`" selected>Dragons</option>`) || !strings.Contains(body, "Ranked choice:") {
+		t.Errorf("event page does not show Alice's choices: %s", body)
+	}
+
+	if status, _ := getBody(t, aliceClient, slotURL+"/assignments"); status != http.StatusForbidden {
+		t.Errorf("non-organizer preview status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body := getBody(t, orgClient, slotURL+"/assignments")
+	if !strings.Contains(body, "2 of 2 players seated.") || !strings.Contains(body, "1 got their 1st choice.") || !strings.Contains(body, "1 got their 2nd choice.") {
+		t.Errorf("preview is wrong: %s", body)
+	}
+
+	// The organizer seats Bob at Dragons by hand, which moves Alice to Goblins.
+	postForm(t, orgClient, slotURL+"/assignments", url.Values{"user_id": {strconv.FormatInt(bob.ID, 10)}, "game_id": {dragons}})
+	if _, body = getBody(t, orgClient, slotURL+"/assignments"); !strings.Contains(body, "placed by hand") {
+		t.Errorf("preview does not show the pin: %s", body)
+	}
+	if status, _ := postForm(t, orgClient, slotURL+"/seed", url.Values{"seed": {"1234"}}); status != http.StatusSeeOther {
+		t.Errorf("seed status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if status, _ := postForm(t, orgClient, slotURL+"/publish", nil); status != http.StatusSeeOther {
+		t.Fatalf("publish status = %d, want %d", status, http.StatusSeeOther)
+	}
+	for _, seat := range []struct {
+		user *models.User
+		game string
+	}{{bob, dragons}, {alice, goblins}} {
+		gameID, _ := strconv.ParseInt(seat.game, 10, 64)
+		if rsvp, err := database.GetRSVPByUserForGame(ts.db, seat.user.ID, gameID); err != nil || rsvp.Status != models.RSVPStatusAttending {
+			t.Errorf("RSVP of %s = %+v, %v; want attending", seat.user.Email, rsvp, err)
+		}
+	}
+	notes, _ := database.GetNotificationsForUser(ts.db, alice.ID, 10)
+	if len(notes) != 1 || notes[0].Kind != models.NotificationKindEventSeating || !strings.Contains(notes[0].Message, "a seat at Goblins") {
+		t.Errorf("Alice's notifications = %+v, want her seat at Goblins", notes)
+	}
+	if status, _ := postForm(t, orgClient, slotURL+"/publish", nil); status != http.StatusBadRequest {
+		t.Errorf("publishing again: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	// Seats left are first come, first served.
+	postForm(t, carolClient, eventURL+"/register", nil)
+	postForm(t, carolClient, ts.server.URL+"/games/"+goblins+"/rsvp", url.Values{"status": {"attending"}})
+	if rsvp, err := database.GetRSVPByUserForGame(ts.db, carol.ID, tables[1].Game.ID); err != nil || rsvp.Status != models.RSVPStatusAttending {
+		t.Errorf("Carol's RSVP after publishing = %+v, %v; want attending", rsvp, err)
+	}
+}
@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// maxEventNameLength caps the names of events, and of their venues, slots and tables.
+const maxEventNameLength = 100
+
+// EventsPage lists events, with a form to create one: GET /events.
+func EventsPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, _ := GetCurrentUser(r, db) // Template handles nil user
+		renderEventsPage(w, r, db, currentUser, nil, "")
+	}
+}
+
+func renderEventsPage(w http.ResponseWriter, r *http.Request, db *sql.DB, currentUser *models.User, form map[string]string, errMsg string) {
+	events, err := database.GetEvents(db)
+	if err != nil {
+		fmt.Printf("Error fetching events: %v\n", err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load events.")
+		return
+	}
+	RenderTemplate(w, "events/events.html", map[string]interface{}{
+		"Title":  "Events",
+		"User":   currentUser,
+		"Events": events,
+		"Form":   form,
+		"Error":  errMsg,
+	})
+}
+
+// CreateEvent creates an event organized by the current user: POST /events with
+// a name, the date it starts on, and an optional description and badge limit.
+// This handler should be wrapped by AuthMiddleware.
+func CreateEvent(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		form := map[string]string{
+			"name":          strings.TrimSpace(r.FormValue("name")),
+			"description":   strings.TrimSpace(r.FormValue("description")),
+			"starts_on":     r.FormValue("starts_on"),
+			"max_attendees": strings.TrimSpace(r.FormValue("max_attendees")),
+		}
+		event := &models.Event{OrganizerID: currentUser.ID, Name: form["name"], Description: form["description"], StartsOn: form["starts_on"]}
+		errMsg := ""
+		switch {
+		case event.Name == "" || utf8.RuneCountInString(event.Name) > maxEventNameLength:
+			errMsg = fmt.Sprintf("Give the event a name of at most %d characters.", maxEventNameLength)
+		case utf8.RuneCountInString(event.Description) > models.MaxEventDescriptionLength:
+			errMsg = fmt.Sprintf("The description can be at most %d characters.", models.MaxEventDescriptionLength)
+		default:
+			if _, err := time.Parse(models.DateLayout, event.StartsOn); err != nil {
+				errMsg = "Choose the date the event starts on."
+			} else if form["max_attendees"] != "" {
+				event.MaxAttendees, err = strconv.Atoi(form["max_attendees"])
+				if err != nil || event.MaxAttendees < 0 {
+					errMsg = "The badge limit must be a whole number, or empty for no limit."
+				}
+			}
+		}
+		if errMsg != "" {
+			renderEventsPage(w, r, db, currentUser, form, errMsg)
+			return
+		}
+		if err := database.CreateEvent(db, event); err != nil {
+			fmt.Printf("Error creating event: %v\n", err)
+			http.Error(w, "Failed to create the event. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d", event.ID), http.StatusSeeOther)
+	}
+}
+
+// eventFromPath loads the event /events/{id}[/...], rendering an error page if
+// the ID is invalid or there is no such event.
+func eventFromPath(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.Event, bool) {
+	eventID, err := pathInt64(r, "/events/", 0)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid event ID format.")
+		return nil, false
+	}
+	event, err := database.GetEventByID(db, eventID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Event not found.")
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return event, true
+}
+
+// eventForOrganizer loads the event in the path like eventFromPath, and checks
+// that the current user organizes it.
+func eventForOrganizer(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.Event, *models.User, bool) {
+	currentUser, err := GetCurrentUser(r, db)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, nil, false
+	}
+	event, ok := eventFromPath(w, r, db)
+	if !ok {
+		return nil, nil, false
+	}
+	if event.OrganizerID != currentUser.ID {
+		RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the event's organizer can do that.")
+		return nil, nil, false
+	}
+	return event, currentUser, true
+}
+
+// EventPage shows an event's slots and tables, with the viewer's badge and
+// sign-ups, and the organizer's forms: GET /events/{id}.
+func EventPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, ok := eventFromPath(w, r, db)
+		if !ok {
+			return
+		}
+		currentUser, _ := GetCurrentUser(r, db) // Template handles nil user
+		renderEventPage(w, r, db, event, currentUser, nil, "")
+	}
+}
+
+func renderEventPage(w http.ResponseWriter, r *http.Request, db *sql.DB, event *models.Event, currentUser *models.User, form map[string]string, errMsg string) {
+	slots, err := database.GetEventSlots(db, event.ID)
+	if err != nil {
+		fmt.Printf("Error fetching slots of event %d: %v\n", event.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the event.")
+		return
+	}
+	venues, err := database.GetEventVenues(db, event.ID)
+	if err != nil {
+		fmt.Printf("Error fetching venues of event %d: %v\n", event.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the event.")
+		return
+	}
+	tables, err := database.GetEventTables(db, event.ID)
+	if err != nil {
+		fmt.Printf("Error fetching tables of event %d: %v\n", event.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the event.")
+		return
+	}
+	venueNames := make(map[int64]string, len(venues))
+	for _, v := range venues {
+		venueNames[v.ID] = v.Name
+	}
+
+	data := map[string]interface{}{
+		"Title":       event.Name,
+		"User":        currentUser,
+		"Event":       event,
+		"Slots":       slots,
+		"Venues":      venues,
+		"VenueNames":  venueNames,
+		"Tables":      tables,
+		"IsOrganizer": currentUser != nil && currentUser.ID == event.OrganizerID,
+		"Form":        form,
+		"Error":       errMsg,
+	}
+	if currentUser != nil {
+		reg, err := database.GetEventRegistration(db, event.ID, currentUser.ID)
+		if err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Error fetching registration of user %d for event %d: %v\n", currentUser.ID, event.ID, err)
+		}
+		signups, err := database.GetEventSignups(db, event.ID, currentUser.ID)
+		if err != nil {
+			fmt.Printf("Error fetching sign-ups of user %d for event %d: %v\n", currentUser.ID, event.ID, err)
+		}
+		choices, err := database.GetUserEventChoices(db, event.ID, currentUser.ID)
+		if err != nil {
+			fmt.Printf("Error fetching choices of user %d for event %d: %v\n", currentUser.ID, event.ID, err)
+			choices = map[int64][]int64{}
+		}
+		// One entry per rank for the ranking forms, 0 where nothing is chosen.
+		for _, s := range slots {
+			if s.TakesChoices() {
+				ranked := make([]int64, models.MaxEventChoices)
+				copy(ranked, choices[s.ID])
+				choices[s.ID] = ranked
+			}
+		}
+		data["Registration"] = reg
+		data["Signups"] = signups
+		data["Choices"] = choices
+		data["ChoiceRanks"] = choiceRanks()
+	}
+	RenderTemplate(w, "events/event.html", data)
+}
+
+// RegisterForEvent gives the current user a badge for the event:
+// POST /events/{id}/register. This handler should be wrapped by AuthMiddleware.
+func RegisterForEvent(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		event, ok := eventFromPath(w, r, db)
+		if !ok {
+			return
+		}
+		if _, err := database.RegisterForEvent(db, event.ID, currentUser.ID); err != nil {
+			if err == database.ErrEventFull {
+				renderEventPage(w, r, db, event, currentUser, nil, "Sorry, every badge for this event has been handed out.")
+				return
+			}
+			fmt.Printf("Error registering user %d for event %d: %v\n", currentUser.ID, event.ID, err)
+			http.Error(w, "Failed to register. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d", event.ID), http.StatusSeeOther)
+	}
+}
+
+// AddEventVenue adds a venue to the event: POST /events/{id}/venues with a name
+// and optional details. Only the organizer can add venues.
+// This handler should be wrapped by AuthMiddleware.
+func AddEventVenue(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, currentUser, ok := eventForOrganizer(w, r, db)
+		if !ok {
+			return
+		}
+		venue := &models.EventVenue{EventID: event.ID, Name: strings.TrimSpace(r.FormValue("name")), Details: strings.TrimSpace(r.FormValue("details"))}
+		switch {
+		case venue.Name == "" || utf8.RuneCountInString(venue.Name) > maxEventNameLength:
+			renderEventPage(w, r, db, event, currentUser, nil, fmt.Sprintf("Give the venue a name of at most %d characters.", maxEventNameLength))
+			return
+		case utf8.RuneCountInString(venue.Details) > models.MaxEventDescriptionLength:
+			renderEventPage(w, r, db, event, currentUser, nil, fmt.Sprintf("Venue details can be at most %d characters.", models.MaxEventDescriptionLength))
+			return
+		}
+		if err := database.AddEventVenue(db, venue); err != nil {
+			fmt.Printf("Error adding venue to event %d: %v\n", event.ID, err)
+			http.Error(w, "Failed to add the venue. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d", event.ID), http.StatusSeeOther)
+	}
+}
+
+// AddEventSlot adds a time slot to the event: POST /events/{id}/slots with a
+// name and its starts_at and ends_at ("YYYY-MM-DDTHH:MM", UTC like game times).
+// If ranked is set, players rank the slot's tables instead of signing up, and
+// the slot gets a random lottery seed. Only the organizer can add slots. This handler should be wrapped by AuthMiddleware.
+func AddEventSlot(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, currentUser, ok := eventForOrganizer(w, r, db)
+		if !ok {
+			return
+		}
+		slot := &models.EventSlot{EventID: event.ID, Name: strings.TrimSpace(r.FormValue("name")), Ranked: r.FormValue("ranked") != ""}
+		startsAt, errStart := time.Parse("2006-01-02T15:04", r.FormValue("starts_at"))
+		endsAt, errEnd := time.Parse("2006-01-02T15:04", r.FormValue("ends_at"))
+		switch {
+		case slot.Name == "" || utf8.RuneCountInString(slot.Name) > maxEventNameLength:
+			renderEventPage(w, r, db, event, currentUser, nil, fmt.Sprintf("Give the slot a name of at most %d characters.", maxEventNameLength))
+			return
+		case errStart != nil || errEnd != nil || !endsAt.After(startsAt):
+			renderEventPage(w, r, db, event, currentUser, nil, "The slot must end after it starts.")
+			return
+		}
+		slot.StartsAt, slot.EndsAt = startsAt, endsAt
+		slot.Seed = rand.Int63n(1000000)
+		if err := database.AddEventSlot(db, slot); err != nil {
+			fmt.Printf("Error adding slot to event %d: %v\n", event.ID, err)
+			http.Error(w, "Failed to add the slot. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d", event.ID), http.StatusSeeOther)
+	}
+}
+
+// AddEventTable adds a table to the event: POST /events/{id}/tables with a
+// title, the slot_id it runs in, its seats (max_players), and optionally a
+// venue_id, a description and the email of the GM running it (the organizer if
+// empty), who can't already be at another table in the slot. The table is a
+// game starting when its slot does.
+// Only the organizer can add tables. This handler should be wrapped by AuthMiddleware.
+func AddEventTable(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, currentUser, ok := eventForOrganizer(w, r, db)
+		if !ok {
+			return
+		}
+		form := map[string]string{
+			"title":       strings.TrimSpace(r.FormValue("title")),
+			"description": strings.TrimSpace(r.FormValue("description")),
+			"slot_id":     r.FormValue("slot_id"),
+			"venue_id":    r.FormValue("venue_id"),
+			"max_players": strings.TrimSpace(r.FormValue("max_players")),
+			"gm_email":    strings.TrimSpace(r.FormValue("gm_email")),
+		}
+		fail := func(msg string) { renderEventPage(w, r, db, event, currentUser, form, msg) }
+
+		if form["title"] == "" || utf8.RuneCountInString(form["title"]) > maxEventNameLength {
+			fail(fmt.Sprintf("Give the table a title of at most %d characters.", maxEventNameLength))
+			return
+		}
+		seats, err := strconv.Atoi(form["max_players"])
+		if err != nil || seats < 1 || seats > models.MaxGameSeats {
+			fail(fmt.Sprintf("A table needs between 1 and %d seats.", models.MaxGameSeats))
+			return
+		}
+		slotID, _ := strconv.ParseInt(form["slot_id"], 10, 64)
+		slot, err := database.GetEventSlotByID(db, slotID)
+		if err != nil || slot.EventID != event.ID {
+			fail("Choose one of the event's slots.")
+			return
+		}
+		game := &models.Game{
+			GMID:         currentUser.ID,
+			Title:        form["title"],
+			Description:  form["description"],
+			GameDateTime: slot.StartsAt,
+			Location:     event.Name,
+			MaxPlayers:   seats,
+			Format:       models.FormatOneShot,
+			PlayMode:     models.PlayModeInPerson,
+			EventSlotID:  slot.ID,
+		}
+		if form["venue_id"] != "" {
+			venueID, _ := strconv.ParseInt(form["venue_id"], 10, 64)
+			venues, err := database.GetEventVenues(db, event.ID)
+			if err != nil {
+				fmt.Printf("Error fetching venues of event %d: %v\n", event.ID, err)
+				http.Error(w, "Failed to add the table. Please try again.", http.StatusInternalServerError)
+				return
+			}
+			for _, v := range venues {
+				if v.ID == venueID {
+					game.EventVenueID = v.ID
+					game.Location = v.Name + ", " + event.Name
+				}
+			}
+			if game.EventVenueID == 0 {
+				fail("Choose one of the event's venues.")
+				return
+			}
+		}
+		if form["gm_email"] != "" {
+			gm, err := database.GetUserByEmail(db, form["gm_email"])
+			if err != nil || gm.IsSuspended() {
+				fail("There is no user with that email to run the table.")
+				return
+			}
+			other, err := database.GetSlotSignup(db, gm.ID, slot.ID, 0)
+			if err == nil {
+				fail(fmt.Sprintf("%s is already at %s in the %s slot.", gm.DisplayName(), other.Title, slot.Name))
+				return
+			}
+			if err != sql.ErrNoRows {
+				fmt.Printf("Error checking the slot sign-ups of user %d: %v\n", gm.ID, err)
+				http.Error(w, "Failed to add the table. Please try again.", http.StatusInternalServerError)
+				return
+			}
+			game.GMID = gm.ID
+		}
+
+		if _, err := database.CreateGame(db, game); err != nil {
+			fmt.Printf("Error adding table to event %d: %v\n", event.ID, err)
+			http.Error(w, "Failed to add the table. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/events/%d", event.ID), http.StatusSeeOther)
+	}
+}
+
+// EventOverview shows the organizer how full each slot's tables are:
+// GET /events/{id}/overview. This handler should be wrapped by AuthMiddleware.
+func EventOverview(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event, currentUser, ok := eventForOrganizer(w, r, db)
+		if !ok {
+			return
+		}
+		overview, err := database.GetEventOverview(db, event.ID)
+		if err != nil {
+			fmt.Printf("Error building overview of event %d: %v\n", event.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the event's overview.")
+			return
+		}
+		RenderTemplate(w, "events/overview.html", map[string]interface{}{
+			"Title":    event.Name + " overview",
+			"User":     currentUser,
+			"Event":    event,
+			"Overview": overview,
+		})
+	}
+}
+
+// eventSignupProblem checks that the user can take a seat at an event's table:
+// the slot can't be waiting for its ranked seating, and they need a badge.
+// It returns a message for the user if they can't. That they aren't already at
+// another table in the same slot is checked as the RSVP is saved; see
+// slotTakenMessage.
+func eventSignupProblem(db *sql.DB, game *models.Game, user *models.User) (string, error) {
+	slot, err := database.GetEventSlotByID(db, game.EventSlotID)
+	if err != nil {
+		return "", err
+	}
+	if slot.TakesChoices() {
+		return fmt.Sprintf("Seats in the %s slot are assigned from ranked choices. Rank its tables on the event's page.", slot.Name), nil
+	}
+	if _, err := database.GetEventRegistration(db, game.EventID, user.ID); err != nil {
+		if err == sql.ErrNoRows {
+			return "Register for a badge on the event's page before signing up for its tables.", nil
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+// slotTakenMessage explains a sign-up refused with database.ErrSlotTaken,
+// naming the table the user is already at in the game's slot.
+func slotTakenMessage(db *sql.DB, game *models.Game, user *models.User) string {
+	other, err := database.GetSlotSignup(db, user.ID, game.EventSlotID, game.ID)
+	if err != nil {
+		return "You're already at another table in this slot. Leave it before joining another."
+	}
+	return fmt.Sprintf("You're already at %s in this slot. Leave that table before joining another.", other.Title)
+}
+
+// choiceRanks lists the ranks of a player's choices, 1 to MaxEventChoices, for
+// templates to range over.
+func choiceRanks() []int {
+	ranks := make([]int, models.MaxEventChoices)
+	for i := range ranks {
+		ranks[i] = i + 1
+	}
+	return ranks
+}
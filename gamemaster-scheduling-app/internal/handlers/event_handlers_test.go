@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestEventSignups(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	orgClient, _ := ts.newUserClient(t, "event_org@example.com", "orgpass")
+	playerClient, player := ts.newUserClient(t, "event_player@example.com", "password")
+
+	if status, _ := postForm(t, orgClient, ts.server.URL+"/events", url.Values{"name": {"DiceCon"}, "starts_on": {"2030-05-04"}, "max_attendees": {"10"}}); status != http.StatusSeeOther {
+		t.Fatalf("create event status = %d, want %d", status, http.StatusSeeOther)
+	}
+	events, _ := database.GetEvents(ts.db)
+	if len(events) != 1 {
+		t.Fatalf("events = %v, want DiceCon", events)
+	}
+	eventURL := ts.server.URL + "/events/" + strconv.FormatInt(events[0].ID, 10)
+
+	if status, _ := postForm(t, playerClient, eventURL+"/slots", url.Values{"name": {"Morning"}, "starts_at": {"2030-05-04T09:00"}, "ends_at": {"2030-05-04T13:00"}}); status != http.StatusForbidden {
+		t.Errorf("non-organizer adding a slot: status = %d, want %d", status, http.StatusForbidden)
+	}
+	if _, body := postForm(t, orgClient, eventURL+"/slots", url.Values{"name": {"Morning"}, "starts_at": {"2030-05-04T13:00"}, "ends_at": {"2030-05-04T09:00"}}); !strings.Contains(body, "The slot must end after it starts.") {
+		t.Errorf("backwards slot was not refused: %s", body)
+	}
+	postForm(t, orgClient, eventURL+"/venues", url.Values{"name": {"Main hall"}})
+	postForm(t, orgClient, eventURL+"/slots", url.Values{"name": {"Morning"}, "starts_at": {"2030-05-04T09:00"}, "ends_at": {"2030-05-04T13:00"}})
+	postForm(t, orgClient, eventURL+"/slots", url.Values{"name": {"Evening"}, "starts_at": {"2030-05-04T18:00"}, "ends_at": {"2030-05-04T22:00"}})
+	slots, _ := database.GetEventSlots(ts.db, events[0].ID)
+	venues, _ := database.GetEventVenues(ts.db, events[0].ID)
+	if len(slots) != 2 || len(venues) != 1 {
+		t.Fatalf("slots = %v, venues = %v; want 2 and 1", slots, venues)
+	}
+	morning, evening := strconv.FormatInt(slots[0].ID, 10), strconv.FormatInt(slots[1].ID, 10)
+	hall := strconv.FormatInt(venues[0].ID, 10)
+	for _, table := range []url.Values{
+		{"title": {"Dragons"}, "slot_id": {morning}, "venue_id": {hall}, "max_players": {"4"}},
+		{"title": {"Goblins"}, "slot_id": {morning}, "max_players": {"4"}},
+		{"title": {"Vampires"}, "slot_id": {evening}, "venue_id": {hall}, "max_players": {"5"}},
+	} {
+		if status, body := postForm(t, orgClient, eventURL+"/tables", table); status != http.StatusSeeOther {
+			t.Fatalf("add table %s: status = %d, body = %s", table.Get("title"), status, body)
+		}
+	}
+	if _, body := postForm(t, orgClient, eventURL+"/tables", url.Values{"title": {"Ghosts"}, "slot_id": {morning}, "max_players": {"4"}, "gm_email": {"nobody@example.com"}}); !strings.Contains(body, "There is no user with that email to run the table.") {
+		t.Errorf("table with an unknown GM was not refused: %s", body)
+	}
+	tables, _ := database.GetEventTables(ts.db, events[0].ID)
+	if len(tables) != 3 {
+		t.Fatalf("tables = %v, want 3", tables)
+	}
+	tableURL := func(i int) string { return ts.server.URL + "/games/" + strconv.FormatInt(tables[i].Game.ID, 10) }
+	if _, body := getBody(t, ts.client, tableURL(0)); !strings.Contains(body, `a table at <a href="`+strings.TrimPrefix(eventURL, ts.server.URL)+`">DiceCon</a>, in the Morning slot`) || !strings.Contains(body, "Main hall, DiceCon") {
+		t.Errorf("table page does not link its event: %s", body)
+	}
+
+	if _, body := getBody(t, ts.client, eventURL); !strings.Contains(body, "0/4 seats taken") || strings.Contains(body, "You:") {
+		t.Errorf("anonymous event page is wrong: %s", body)
+	}
+
+	attend := url.Values{"status": {"attending"}}
+	if _, body := postForm(t, playerClient, tableURL(0)+"/rsvp", attend); !strings.Contains(body, "Register for a badge on the event") {
+		t.Errorf("sign-up without a badge was not refused: %s", body)
+	}
+	if status, _ := postForm(t, playerClient, eventURL+"/register", nil); status != http.StatusSeeOther {
+		t.Errorf("register status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if _, body := getBody(t, playerClient, eventURL); !strings.Contains(body, "<strong>#1</strong>") {
+		t.Errorf("event page does not show the badge: %s", body)
+	}
+	postForm(t, playerClient, tableURL(0)+"/rsvp", attend)
+	if _, body := postForm(t, playerClient, tableURL(1)+"/rsvp", attend); !strings.Contains(body, "already at Dragons in this slot") {
+		t.Errorf("second table in the same slot was not refused: %s", body)
+	}
+	postForm(t, playerClient, tableURL(2)+"/rsvp", attend)
+	signups, _ := database.GetEventSignups(ts.db, events[0].ID, player.ID)
+	if len(signups) != 2 || signups[tables[0].Game.ID] != models.RSVPStatusAttending || signups[tables[2].Game.ID] != models.RSVPStatusAttending {
+		t.Errorf("sign-ups = %v, want Dragons and Vampires", signups)
+	}
+	if _, body := postForm(t, orgClient, eventURL+"/tables", url.Values{"title": {"Ghosts"}, "slot_id": {morning}, "max_players": {"4"}, "gm_email": {player.Email}}); !strings.Contains(body, "is already at Dragons in the Morning slot") {
+		t.Errorf("table run by a GM seated elsewhere in the slot was not refused: %s", body)
+	}
+	if _, body := getBody(t, playerClient, eventURL); !strings.Contains(body, "1/4 seats taken") || !strings.Contains(body, "You: Attending") {
+		t.Errorf("event page does not show the sign-up: %s", body)
+	}
+
+	if status, _ := getBody(t, playerClient, eventURL+"/overview"); status != http.StatusForbidden {
+		t.Errorf("non-organizer overview status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body := getBody(t, orgClient, eventURL+"/overview")
+	if !strings.Contains(body, `title="25% full"`) || !strings.Contains(body, "<strong>12% full</strong>") || !strings.Contains(body, "<em>No venue</em>") || !strings.Contains(body, "0 registered players have no table") {
+		t.Errorf("overview is wrong: %s", body)
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// feedbackRequest loads the game /games/{id} for the current user, rendering an
+// error page if there is no such game.
+func feedbackRequest(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.Game, *models.User, bool) {
+	currentUser, err := GetCurrentUser(r, db)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, nil, false
+	}
+	gameID, err := pathInt64(r, "/games/", 0)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid game ID format.")
+		return nil, nil, false
+	}
+	game, err := database.GetGameByID(db, gameID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Game not found.")
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return nil, nil, false
+	}
+	return game, currentUser, true
+}
+
+// canGiveFeedback reports whether the user may rate the game: it has happened,
+// they aren't one of its GMs, and the GM marked them present (or late).
+func canGiveFeedback(db *sql.DB, game *models.Game, user *models.User) (bool, error) {
+	if user == nil || game.IsGM(user.ID) || !game.HasHappened(time.Now()) {
+		return false, nil
+	}
+	attendance, err := database.GetAttendanceForGame(db, game.ID)
+	if err != nil {
+		return false, err
+	}
+	status := attendance[user.ID]
+	return status == models.AttendancePresent || status == models.AttendanceLate, nil
+}
+
+// GameFeedbackPage shows the feedback on a game to its GMs, and the feedback
+// form to attendees marked present: GET /games/{id}/feedback. This handler
+// should be wrapped by AuthMiddleware.
+func GameFeedbackPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := feedbackRequest(w, r, db)
+		if !ok {
+			return
+		}
+		renderFeedbackPage(w, r, db, game, currentUser, &models.SessionFeedback{}, "")
+	}
+}
+
+func renderFeedbackPage(w http.ResponseWriter, r *http.Request, db *sql.DB, game *models.Game, currentUser *models.User, form *models.SessionFeedback, errMsg string) {
+	data := map[string]interface{}{
+		"Title":     game.Title + " feedback",
+		"User":      currentUser,
+		"Game":      game,
+		"IsGM":      game.IsGM(currentUser.ID),
+		"Form":      form,
+		"Stars":     []int{1, 2, 3, 4, 5},
+		"MaxLength": models.MaxFeedbackCommentLength,
+		"Threshold": models.FeedbackPublicThreshold,
+		"Error":     errMsg,
+	}
+	if game.IsGM(currentUser.ID) {
+		feedback, err := database.GetFeedbackForGame(db, game.ID)
+		if err != nil {
+			fmt.Printf("Error fetching feedback for game %d: %v\n", game.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the feedback.")
+			return
+		}
+		data["Feedback"], data["Ratings"] = feedback, averageFeedback(feedback)
+		RenderTemplate(w, "games/feedback.html", data)
+		return
+	}
+
+	eligible, err := canGiveFeedback(db, game, currentUser)
+	if err != nil {
+		fmt.Printf("Error checking feedback eligibility of user %d for game %d: %v\n", currentUser.ID, game.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the feedback form.")
+		return
+	}
+	if !eligible {
+		RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only players the GM marked present can give feedback on this session.")
+		return
+	}
+	submitted, err := database.HasSubmittedFeedback(db, game.ID, currentUser.ID)
+	if err != nil {
+		fmt.Printf("Error checking feedback of user %d for game %d: %v\n", currentUser.ID, game.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the feedback form.")
+		return
+	}
+	data["Submitted"] = submitted
+	RenderTemplate(w, "games/feedback.html", data)
+}
+
+// averageFeedback averages the ratings of one game's feedback.
+func averageFeedback(feedback []*models.SessionFeedback) *models.GMRatings {
+	r := &models.GMRatings{Responses: len(feedback)}
+	if len(feedback) == 0 {
+		return r
+	}
+	for _, f := range feedback {
+		r.Fun += float64(f.Fun)
+		r.Pacing += float64(f.Pacing)
+		r.Inclusivity += float64(f.Inclusivity)
+	}
+	n := float64(len(feedback))
+	r.Fun, r.Pacing, r.Inclusivity = r.Fun/n, r.Pacing/n, r.Inclusivity/n
+	return r
+}
+
+// SubmitGameFeedback saves an attendee's ratings of a game and their comment to
+// the GM: POST /games/{id}/feedback, with fun, pacing and inclusivity from 1 to
+// 5 and an optional comment. Each attendee marked present can respond once.
+// This handler should be wrapped by AuthMiddleware.
+func SubmitGameFeedback(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := feedbackRequest(w, r, db)
+		if !ok {
+			return
+		}
+		eligible, err := canGiveFeedback(db, game, currentUser)
+		if err != nil {
+			fmt.Printf("Error checking feedback eligibility of user %d for game %d: %v\n", currentUser.ID, game.ID, err)
+			http.Error(w, "Failed to send feedback. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		if !eligible {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only players the GM marked present can give feedback on this session.")
+			return
+		}
+
+		feedback := &models.SessionFeedback{
+			GameID:  game.ID,
+			UserID:  currentUser.ID,
+			GMIDs:   game.GMIDs(),
+			Comment: strings.TrimSpace(r.FormValue("comment")),
+		}
+		ratings := []*int{&feedback.Fun, &feedback.Pacing, &feedback.Inclusivity}
+		for i, field := range []string{"fun", "pacing", "inclusivity"} {
+			stars, err := strconv.Atoi(r.FormValue(field))
+			if err != nil || stars < 1 || stars > models.MaxRating {
+				renderFeedbackPage(w, r, db, game, currentUser, feedback, fmt.Sprintf("Rate fun, pacing and inclusivity from 1 to %d stars.", models.MaxRating))
+				return
+			}
+			*ratings[i] = stars
+		}
+		if utf8.RuneCountInString(feedback.Comment) > models.MaxFeedbackCommentLength {
+			renderFeedbackPage(w, r, db, game, currentUser, feedback, fmt.Sprintf("The comment can be at most %d characters.", models.MaxFeedbackCommentLength))
+			return
+		}
+
+		if err := database.SubmitSessionFeedback(db, feedback); err == database.ErrFeedbackSubmitted {
+			renderFeedbackPage(w, r, db, game, currentUser, feedback, "You already gave feedback on this session.")
+			return
+		} else if err != nil {
+			fmt.Printf("Error saving feedback of user %d for game %d: %v\n", currentUser.ID, game.ID, err)
+			http.Error(w, "Failed to send feedback. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		link := fmt.Sprintf("/games/%d/feedback", game.ID)
+		for _, gmID := range game.GMIDs() {
+			_, err := database.CreateNotification(db, &models.Notification{
+				UserID: gmID, Kind: models.NotificationKindFeedback, Message: fmt.Sprintf("A player gave feedback on %s.", game.Title), Link: link,
+			})
+			if err != nil {
+				fmt.Printf("Error notifying GM %d of feedback on game %d: %v\n", gmID, game.ID, err)
+			}
+		}
+		http.Redirect(w, r, link, http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestSessionFeedback(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "stars_gm@example.com", "gmpass")
+	annClient, ann := ts.newUserClient(t, "stars_ann@example.com", "password")
+	bobClient, bob := ts.newUserClient(t, "stars_bob@example.com", "password")
+
+	game, err := database.CreateGame(ts.db, &models.Game{GMID: gm.ID, Title: "Heist Night", GameDateTime: time.Now().Add(-3 * time.Hour), Location: "Online"})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+	database.SetAttendance(ts.db, game.ID, gm.ID, map[int64]string{ann.ID: models.AttendancePresent, bob.ID: models.AttendanceNoShow})
+	coGMClient, coGM := ts.newUserClient(t, "stars_cogm@example.com", "password")
+	database.AddGameStaff(ts.db, game.ID, coGM.ID, gm.ID)
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+	feedbackURL := gameURL + "/feedback"
+
+	if status, _ := getBody(t, bobClient, feedbackURL); status != http.StatusForbidden {
+		t.Errorf("no-show feedback page status = %d, want %d", status, http.StatusForbidden)
+	}
+	if _, body := getBody(t, annClient, gameURL); !strings.Contains(body, "Rate it for the GM") {
+		t.Errorf("game page does not invite an attendee to give feedback: %s", body)
+	}
+	if _, body := postForm(t, annClient, feedbackURL, url.Values{"fun": {"6"}, "pacing": {"3"}, "inclusivity": {"4"}}); !strings.Contains(body, "Rate fun, pacing and inclusivity from 1 to 5 stars.") {
+		t.Errorf("a 6 star rating was not refused: %s", body)
+	}
+	form := url.Values{"fun": {"5"}, "pacing": {"3"}, "inclusivity": {"4"}, "comment": {"Loved the vault scene"}}
+	if status, _ := postForm(t, annClient, feedbackURL, form); status != http.StatusSeeOther {
+		t.Fatalf("feedback status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if _, body := postForm(t, annClient, feedbackURL, form); !strings.Contains(body, "You already gave feedback on this session.") {
+		t.Errorf("second feedback was not refused: %s", body)
+	}
+	if _, body := getBody(t, annClient, feedbackURL); !strings.Contains(body, "Thanks for your feedback!") || strings.Contains(body, "<form action=\"/games/") {
+		t.Errorf("attendee still sees the feedback form: %s", body)
+	}
+	for _, u := range []*models.User{gm, coGM} {
+		if notes, _ := database.GetNotificationsForUser(ts.db, u.ID, 10); len(notes) == 0 || notes[0].Kind != models.NotificationKindFeedback {
+			t.Errorf("GM %s was not told about the feedback: %v", u.Email, notes)
+		}
+	}
+	if _, body := getBody(t, coGMClient, ts.server.URL+"/users/"+strconv.FormatInt(coGM.ID, 10)); !strings.Contains(body, "Player feedback as GM") {
+		t.Errorf("co-GM profile does not count the session they ran: %s", body)
+	}
+	if _, body := getBody(t, gmClient, feedbackURL); !strings.Contains(body, "Loved the vault scene") || !strings.Contains(body, ann.DisplayName()) {
+		t.Errorf("GM feedback page does not show the response: %s", body)
+	}
+
+	// Below the threshold only the GM sees their ratings.
+	profileURL := ts.server.URL + "/users/" + strconv.FormatInt(gm.ID, 10)
+	if _, body := getBody(t, gmClient, profileURL); !strings.Contains(body, "Player feedback as GM") || !strings.Contains(body, "Only you can see these") {
+		t.Errorf("GM profile does not show their own ratings: %s", body)
+	}
+	if _, body := getBody(t, annClient, profileURL); strings.Contains(body, "Player feedback as GM") || strings.Contains(body, "Loved the vault scene") {
+		t.Errorf("GM ratings are public below the threshold: %s", body)
+	}
+	for i := 1; i < models.FeedbackPublicThreshold; i++ {
+		player, _ := database.CreateUser(ts.db, "stars_player"+strconv.Itoa(i)+"@example.com", "password")
+		database.SubmitSessionFeedback(ts.db, &models.SessionFeedback{GameID: game.ID, UserID: player.ID, GMIDs: []int64{gm.ID}, Fun: 4, Pacing: 4, Inclusivity: 4})
+	}
+	if _, body := getBody(t, annClient, profileURL); !strings.Contains(body, "Player feedback as GM") || strings.Contains(body, "Loved the vault scene") {
+		t.Errorf("GM ratings are not public at the threshold: %s", body)
+	}
+}
@@ -4,35 +4,89 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gamemaster-scheduling/app/internal/database"
 	"github.com/gamemaster-scheduling/app/internal/models"
 	// "github.com/gorilla/mux" // Or use net/http path parsing
 )
 
-// GamesListPage displays all available games.
+// GamesListPage displays all available games, narrowed down by the filters in the
+// query string: system, experience, format and mode from the game taxonomy, plus
+// my_systems (only the viewer's preferred systems) and exclude_lines (hide games
+// tagged with content the viewer marked as a line) for logged-in players.
 func GamesListPage(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		games, err := database.GetAllGames(db)
+		// For now, we'll pass the games directly.
+		// Later, we might add user login status to the data for conditional rendering in template.
+		currentUser, _ := GetCurrentUser(r, db) // Ignore error for now, template will handle nil user
+
+		filter := gameFilterFromQuery(r.URL.Query(), currentUser)
+		games, err := database.FilterGames(db, filter)
 		if err != nil {
 			http.Error(w, "Failed to retrieve games: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		systems, err := database.GetGameSystems(db)
+		if err != nil {
+			fmt.Printf("Error fetching game systems: %v\n", err)
+		}
 
-		// For now, we'll pass the games directly.
-		// Later, we might add user login status to the data for conditional rendering in template.
-		currentUser, _ := GetCurrentUser(r, db) // Ignore error for now, template will handle nil user
+		// Games in the viewer's preferred systems are highlighted.
+		preferred := make(map[int64]bool)
+		if currentUser != nil {
+			mine, err := database.GetPreferredSystems(db, currentUser.ID)
+			if err != nil {
+				fmt.Printf("Error fetching preferred systems for user %d: %v\n", currentUser.ID, err)
+			}
+			for _, s := range mine {
+				preferred[s.ID] = true
+			}
+		}
 
 		data := map[string]interface{}{
-			"Games": games,
-			"User": currentUser,
+			"Games":            games,
+			"User":             currentUser,
+			"Filter":           filter,
+			"Systems":          systems,
+			"PreferredSystems": preferred,
+			"ExperienceLevels": models.ExperienceLevels,
+			"GameFormats":      models.GameFormats,
+			"PlayModes":        models.PlayModes,
 		}
 		RenderTemplate(w, "games/games_list.html", data)
 	}
 }
 
+// gameFilterFromQuery reads the games list filters. Unknown values are ignored
+// rather than refused, so a stale link still lists games.
+func gameFilterFromQuery(q url.Values, currentUser *models.User) models.GameFilter {
+	var filter models.GameFilter
+	filter.SystemID, _ = strconv.ParseInt(q.Get("system"), 10, 64)
+	if v := q.Get("experience"); models.ValidExperienceLevel(v) {
+		filter.ExperienceLevel = v
+	}
+	if v := q.Get("format"); models.ValidGameFormat(v) {
+		filter.Format = v
+	}
+	if v := q.Get("mode"); models.ValidPlayMode(v) {
+		filter.PlayMode = v
+	}
+	if currentUser != nil {
+		if q.Get("my_systems") != "" {
+			filter.PreferredBy = currentUser.ID
+		}
+		if q.Get("exclude_lines") != "" {
+			filter.ExcludeLinesOf = currentUser.ID
+		}
+	}
+	return filter
+}
+
 // GameDetailPage displays details for a specific game.
 func GameDetailPage(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -78,7 +132,7 @@ func GameDetailPage(db *sql.DB) http.HandlerFunc {
 		game, err := database.GetGameByID(db, gameID)
 		if err != nil {
 			if err == sql.ErrNoRows {
-				http.Error(w, "Game not found", http.StatusNotFound)
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Game not found.")
 			} else {
 				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 			}
@@ -87,45 +141,132 @@ func GameDetailPage(db *sql.DB) http.HandlerFunc {
 
 		currentUser, _ := GetCurrentUser(r, db) // Error ignored for now, template handles nil user
 
-		allGameRSVPs, err := database.GetRSVPsForGame(db, gameID)
+		data, err := rsvpSectionData(db, game, currentUser)
 		if err != nil {
 			// Log this error but don't necessarily fail the whole page load
-			fmt.Printf("Error fetching all RSVPs for game %d: %v\n", gameID, err)
-			// allGameRSVPs will be nil, template should handle this
+			fmt.Printf("Error fetching RSVPs for game %d: %v\n", gameID, err)
+			// The RSVP lists will be empty, template should handle this
+			data = map[string]interface{}{"Game": game, "User": currentUser}
 		}
+		allGameRSVPs, _ := data["AllGameRSVPs"].([]*models.RSVP)
 
-		var currentUserRSVP *models.RSVP
-		if currentUser != nil {
-			currentUserRSVP, err = database.GetRSVPByUserForGame(db, currentUser.ID, gameID)
-			if err != nil && err != sql.ErrNoRows {
-				// Log this error but don't necessarily fail the whole page load
-				fmt.Printf("Error fetching current user's RSVP for game %d: %v\n", gameID, err)
-				// currentUserRSVP will be nil, template should handle this
+		chatData, err := chatSectionData(db, gameID, currentUser)
+		if err != nil {
+			// Log this error but don't necessarily fail the whole page load
+			fmt.Printf("Error fetching chat messages for game %d: %v\n", gameID, err)
+			// ChatMessages will be missing, template should handle this
+		}
+		for k, v := range chatData {
+			data[k] = v // Messages, GameID, edit window and moderation flags for the chat partial
+		}
+
+		if currentUser != nil && game.IsGM(currentUser.ID) {
+			reports, err := database.GetChatReportsForGame(db, gameID, models.ReportStatusOpen)
+			if err != nil {
+				fmt.Printf("Error fetching reports for game %d: %v\n", gameID, err)
 			}
-			// If err is sql.ErrNoRows, currentUserRSVP remains nil, which is correct (user hasn't RSVP'd)
+			data["OpenReportCount"] = len(reports)
 		}
-		
-		// Constants for RSVP status, to be used in templates if needed
-		// Though for the current _rsvp_section.html, these are not directly used in hx-vals
-		// as strings are directly embedded. But good to have if template logic changes.
-		data := map[string]interface{}{
-			"Game":              game,
-			"User":              currentUser, // Renamed from LoggedInUser for consistency with other templates
-			"CurrentUserRSVP":   currentUserRSVP, // Renamed from UserRSVP
-			"AllGameRSVPs":      allGameRSVPs,
-			"RSVPStatusAttending": models.RSVPStatusAttending,
-			"RSVPStatusMaybe":     models.RSVPStatusMaybe,
-			"RSVPStatusNotAttending": models.RSVPStatusNotAttending,
+
+		// GMs see how many of their players ruled out or veiled each topic, but not who,
+		// once RSVPs close.
+		if currentUser != nil && game.IsGM(currentUser.ID) {
+			if at := game.LimitSummaryAt(); time.Now().Before(at) {
+				data["LimitSummaryAt"] = at
+			} else {
+				limits, err := database.GetGameLimitSummary(db, gameID)
+				if err != nil {
+					fmt.Printf("Error fetching content limits for game %d: %v\n", gameID, err)
+				}
+				data["LimitSummary"] = limits
+			}
+			data["LimitMinPlayers"] = models.MinLimitSummaryPlayers
 		}
 
-		chatMessages, err := database.GetChatMessagesForGame(db, gameID)
+		if game.CampaignID != 0 {
+			campaign, err := database.GetCampaignByID(db, game.CampaignID)
+			if err != nil {
+				fmt.Printf("Error fetching campaign %d for game %d: %v\n", game.CampaignID, gameID, err)
+			}
+			data["Campaign"] = campaign
+			if host, err := database.GetSessionHost(db, game.ID); err == nil {
+				data["SessionHost"] = host
+			} else if err != sql.ErrNoRows {
+				fmt.Printf("Error fetching the host of game %d: %v\n", gameID, err)
+			}
+		}
+		if game.EventID != 0 {
+			event, err := database.GetEventByID(db, game.EventID)
+			if err == nil {
+				data["EventSlot"], err = database.GetEventSlotByID(db, game.EventSlotID)
+			}
+			if err != nil {
+				fmt.Printf("Error fetching event %d for game %d: %v\n", game.EventID, gameID, err)
+			}
+			data["Event"] = event
+		}
+		if game.VenueID != 0 {
+			venue, err := database.GetVenueByID(db, game.VenueID)
+			if err != nil {
+				fmt.Printf("Error fetching venue %d for game %d: %v\n", game.VenueID, gameID, err)
+			}
+			data["Venue"] = venue
+		}
+		// A virtual table's links are for the GMs and attending players only.
+		if game.VirtualTableID != 0 {
+			rsvp, _ := data["CurrentUserRSVP"].(*models.RSVP)
+			if currentUser != nil && (game.IsGM(currentUser.ID) || (rsvp != nil && rsvp.Status == models.RSVPStatusAttending)) {
+				table, err := database.GetVirtualTableByID(db, game.VirtualTableID)
+				if err != nil {
+					fmt.Printf("Error fetching virtual table %d for game %d: %v\n", game.VirtualTableID, gameID, err)
+				}
+				data["VirtualTable"] = table
+			}
+		}
+
+		// After the session, the GM records who actually showed up.
+		if currentUser != nil && game.IsGM(currentUser.ID) && game.HasHappened(time.Now()) {
+			attendanceData, err := attendanceSectionData(db, game, allGameRSVPs)
+			if err != nil {
+				fmt.Printf("Error fetching attendance for game %d: %v\n", gameID, err)
+			} else {
+				data["CanRecordAttendance"] = true
+				for k, v := range attendanceData {
+					data[k] = v
+				}
+			}
+		}
+
+		// After the session, attendees marked present can rate it.
+		if canRate, err := canGiveFeedback(db, game, currentUser); err != nil {
+			fmt.Printf("Error checking feedback eligibility for game %d: %v\n", gameID, err)
+		} else {
+			data["CanGiveFeedback"] = canRate
+		}
+
+		staffData, err := staffSectionData(db, game, currentUser)
 		if err != nil {
-			// Log this error but don't necessarily fail the whole page load
-			fmt.Printf("Error fetching chat messages for game %d: %v\n", gameID, err)
-			// chatMessages will be nil or empty, template should handle this
+			fmt.Printf("Error fetching staff for game %d: %v\n", gameID, err)
+		} else if staffData != nil {
+			data["StaffSection"] = staffData
+		}
+
+		attachmentData, err := attachmentSectionData(db, game, currentUser)
+		if err != nil {
+			fmt.Printf("Error fetching attachments for game %d: %v\n", gameID, err)
+		} else if attachmentData != nil {
+			data["AttachmentSection"] = attachmentData
+		}
+
+		// Participants share the recap; prep notes are for the GM's eyes only.
+		for key, kind := range map[string]string{"RecapNote": models.NoteKindRecap, "GMPrepNote": models.NoteKindGMPrep} {
+			noteData, err := sessionNoteData(db, game, kind, currentUser)
+			if err != nil {
+				fmt.Printf("Error fetching %s note for game %d: %v\n", kind, gameID, err)
+			} else if noteData != nil {
+				data[key] = noteData
+			}
 		}
-		data["ChatMessages"] = chatMessages
-		data["GameID"] = gameID // Already part of 'game' object, but explicit for chat form if needed
 
 		RenderTemplate(w, "games/game_detail.html", data)
 	}
@@ -133,10 +274,219 @@ func GameDetailPage(db *sql.DB) http.HandlerFunc {
 
 // CreateGamePage renders the form for creating a new game.
 // This handler should be wrapped by AuthMiddleware.
-func CreateGamePage(w http.ResponseWriter, r *http.Request) {
-	// Pass nil data if the form doesn't need any initial data.
-	// If re-rendering with errors, this data object would contain error messages.
-	RenderTemplate(w, "games/new_game.html", nil)
+func CreateGamePage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The availability heatmap links here with a time and campaign filled in.
+		form := make(map[string]string)
+		for _, field := range []string{"game_datetime", "campaign", "invite_roster"} {
+			form[field] = r.URL.Query().Get(field)
+		}
+		currentUser, _ := GetCurrentUser(r, db)
+		renderNewGameForm(w, db, currentUser, form, "")
+	}
+}
+
+// renderNewGameForm renders the new game form, repopulated from form and showing
+// errMsg if set. The GM can pick any venue, or one of their own virtual tables.
+func renderNewGameForm(w http.ResponseWriter, db *sql.DB, currentUser *models.User, form map[string]string, errMsg string) {
+	contentTags := make(map[string]bool)
+	for _, topic := range strings.Split(form["content_tags"], ",") {
+		contentTags[topic] = true
+	}
+	systems, err := database.GetGameSystems(db)
+	if err != nil {
+		fmt.Printf("Error fetching game systems: %v\n", err)
+	}
+	venues, err := database.GetVenues(db)
+	if err != nil {
+		fmt.Printf("Error fetching venues: %v\n", err)
+	}
+	var virtualTables []*models.VirtualTable
+	if currentUser != nil {
+		if virtualTables, err = database.GetVirtualTablesByOwner(db, currentUser.ID); err != nil {
+			fmt.Printf("Error fetching virtual tables of user %d: %v\n", currentUser.ID, err)
+		}
+	}
+	RenderTemplate(w, "games/new_game.html", map[string]interface{}{
+		"Error":            errMsg,
+		"Form":             form,
+		"ContentTopics":    models.ContentTopics,
+		"ContentTags":      contentTags,
+		"Systems":          systems,
+		"ExperienceLevels": models.ExperienceLevels,
+		"GameFormats":      models.GameFormats,
+		"PlayModes":        models.PlayModes,
+		"Venues":           venues,
+		"VirtualTables":    virtualTables,
+	})
+}
+
+// inviteCampaignRoster notifies everyone in the campaign's roster, other than the
+// GM who created it, of a new session.
+func inviteCampaignRoster(db *sql.DB, game *models.Game, gm *models.User) {
+	roster, err := database.GetCampaignRoster(db, game.CampaignID)
+	if err != nil {
+		fmt.Printf("Error fetching roster of campaign %d: %v\n", game.CampaignID, err)
+		return
+	}
+	for _, u := range roster {
+		if u.ID == gm.ID {
+			continue
+		}
+		_, err := database.CreateNotification(db, &models.Notification{
+			UserID:  u.ID,
+			Kind:    models.NotificationKindGameInvite,
+			Message: fmt.Sprintf("%s invited you to %s on %s (UTC)", gm.DisplayName(), game.Title, FormatDateTime(game.GameDateTime)),
+			Link:    fmt.Sprintf("/games/%d#rsvp-section", game.ID),
+		})
+		if err != nil {
+			fmt.Printf("Error inviting user %d to game %d: %v\n", u.ID, game.ID, err)
+		}
+	}
+}
+
+// gameOptionFields are the optional new game form fields read by gameOptionsFromForm.
+// The content_tags checkboxes are joined with commas.
+var gameOptionFields = []string{"min_level", "max_level", "max_players", "min_players", "rsvp_deadline", "requires_approval", "content_notes",
+	"system_id", "experience_level", "format", "play_mode", "venue_id", "virtual_table_id"}
+
+// gameOptionsFromForm validates the optional settings of a new game and sets them on
+// game, whose GameDateTime must already be set. It returns a message for the user if
+// a setting is invalid.
+func gameOptionsFromForm(form map[string]string, game *models.Game) string {
+	// Optional recommended character levels; either bound may be empty.
+	levels := [2]int{}
+	for i, field := range []string{"min_level", "max_level"} {
+		if form[field] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(form[field])
+		if err != nil || n < 1 || n > models.MaxCharacterLevel {
+			return fmt.Sprintf("Levels must be numbers from 1 to %d.", models.MaxCharacterLevel)
+		}
+		levels[i] = n
+	}
+	if levels[0] != 0 && levels[1] != 0 && levels[0] > levels[1] {
+		return "The minimum level cannot be above the maximum level."
+	}
+	game.MinLevel, game.MaxLevel = levels[0], levels[1]
+
+	// Optional seat limit and quorum.
+	players := [2]int{}
+	for i, field := range []string{"max_players", "min_players"} {
+		if form[field] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(form[field])
+		if err != nil || n < 1 || n > models.MaxGameSeats {
+			return fmt.Sprintf("Player counts must be from 1 to %d.", models.MaxGameSeats)
+		}
+		players[i] = n
+	}
+	if players[0] != 0 && players[1] > players[0] {
+		return "The minimum number of players cannot be above the number of seats."
+	}
+	game.MaxPlayers, game.MinPlayers = players[0], players[1]
+
+	if form["rsvp_deadline"] != "" {
+		deadline, err := time.Parse("2006-01-02T15:04", form["rsvp_deadline"])
+		if err != nil {
+			return "Invalid RSVP deadline format. Use YYYY-MM-DDTHH:MM."
+		}
+		if !deadline.Before(game.GameDateTime) {
+			return "The RSVP deadline must be before the game starts."
+		}
+		game.RSVPDeadline = deadline
+	}
+	if game.MinPlayers > 0 && game.RSVPDeadline.IsZero() {
+		return "Set an RSVP deadline so the minimum number of players can be checked."
+	}
+
+	game.RequiresApproval = form["requires_approval"] != ""
+
+	// Optional content warnings.
+	tags, errMsg := contentTagsFromForm(form["content_tags"], form["content_notes"])
+	if errMsg != "" {
+		return errMsg
+	}
+	game.ContentTags, game.ContentNotes = tags, form["content_notes"]
+
+	// Optional taxonomy. The caller checks that the system exists.
+	if form["system_id"] != "" {
+		id, err := strconv.ParseInt(form["system_id"], 10, 64)
+		if err != nil || id < 1 {
+			return "Choose a game system from the list."
+		}
+		game.SystemID = id
+	}
+	if v := form["experience_level"]; v != "" && !models.ValidExperienceLevel(v) {
+		return "Choose an experience level from the list."
+	}
+	if v := form["format"]; v != "" && !models.ValidGameFormat(v) {
+		return "Choose a format from the list."
+	}
+	if v := form["play_mode"]; v != "" && !models.ValidPlayMode(v) {
+		return "Choose in person or online."
+	}
+	game.ExperienceLevel, game.Format, game.PlayMode = form["experience_level"], form["format"], form["play_mode"]
+	return ""
+}
+
+// gamePlaceFromForm sets the venue_id or virtual_table_id of the new game form on
+// game: any venue, or one of the GM's own virtual tables, but not both. An empty
+// location is filled in with the venue's address or the virtual table's name.
+// It returns a message for the user if the choice is invalid.
+func gamePlaceFromForm(db *sql.DB, form map[string]string, game *models.Game, gm *models.User) (string, error) {
+	if form["venue_id"] != "" && form["virtual_table_id"] != "" {
+		return "Choose a venue or a virtual table, not both.", nil
+	}
+	if v := form["venue_id"]; v != "" {
+		id, _ := strconv.ParseInt(v, 10, 64)
+		venue, err := database.GetVenueByID(db, id)
+		if err == sql.ErrNoRows {
+			return "Choose a venue from the list.", nil
+		} else if err != nil {
+			return "", err
+		}
+		game.VenueID = venue.ID
+		if game.Location == "" {
+			game.Location = venue.Name + ", " + venue.Address
+		}
+	}
+	if v := form["virtual_table_id"]; v != "" {
+		id, _ := strconv.ParseInt(v, 10, 64)
+		table, err := database.GetVirtualTableByID(db, id)
+		if err == sql.ErrNoRows || (err == nil && table.OwnerID != gm.ID) {
+			return "Choose one of your virtual tables.", nil
+		} else if err != nil {
+			return "", err
+		}
+		game.VirtualTableID = table.ID
+		if game.Location == "" {
+			game.Location = table.Name
+		}
+	}
+	return "", nil
+}
+
+// contentTagsFromForm validates a game's content warnings: comma-separated topics
+// from models.ContentTopics and a free-text note. It returns a message for the user
+// if either is invalid.
+func contentTagsFromForm(topics, notes string) ([]string, string) {
+	var tags []string
+	for _, topic := range strings.Split(topics, ",") {
+		if topic == "" {
+			continue
+		}
+		if !models.ValidContentTopic(topic) {
+			return nil, "Unknown content warning: " + topic
+		}
+		tags = append(tags, topic)
+	}
+	if utf8.RuneCountInString(notes) > models.MaxContentNotesLength {
+		return nil, fmt.Sprintf("Content notes can be at most %d characters.", models.MaxContentNotesLength)
+	}
+	return tags, ""
 }
 
 // CreateGame handles the submission of the new game form.
@@ -154,15 +504,38 @@ func CreateGame(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			// This should ideally not happen if AuthMiddleware is working correctly
+			http.Error(w, "User not authenticated: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
 		title := r.FormValue("title")
 		description := r.FormValue("description")
 		gameDateTimeStr := r.FormValue("game_datetime") // Format: "YYYY-MM-DDTHH:MM"
 		location := r.FormValue("location")
+		campaignName := strings.TrimSpace(r.FormValue("campaign")) // Optional; sessions with the same name form a campaign
+
+		// Keep submitted values to repopulate the form on errors, including the
+		// optional settings parsed by gameOptionsFromForm.
+		form := map[string]string{
+			"title": title, "description": description, "game_datetime": gameDateTimeStr, "location": location, "campaign": campaignName,
+		}
+		for _, field := range gameOptionFields {
+			form[field] = strings.TrimSpace(r.FormValue(field))
+		}
+		form["content_tags"] = strings.Join(r.Form["content_tags"], ",")
+		form["invite_roster"] = r.FormValue("invite_roster")
 
 		// Validation
-		if title == "" || gameDateTimeStr == "" || location == "" {
-			data := map[string]interface{}{"Error": "Title, Game Date/Time, and Location are required."}
-			RenderTemplate(w, "games/new_game.html", data) // Re-render form with error
+		// A venue or virtual table stands in for the location.
+		if title == "" || gameDateTimeStr == "" || (location == "" && form["venue_id"] == "" && form["virtual_table_id"] == "") {
+			renderNewGameForm(w, db, currentUser, form, "Title, Game Date/Time, and Location are required.") // Re-render form with error
+			return
+		}
+		if utf8.RuneCountInString(description) > models.MaxGameDescriptionLength {
+			renderNewGameForm(w, db, currentUser, form, fmt.Sprintf("The description can be at most %d characters.", models.MaxGameDescriptionLength))
 			return
 		}
 
@@ -170,20 +543,7 @@ func CreateGame(db *sql.DB) http.HandlerFunc {
 		// HTML input type="datetime-local" sends data in "YYYY-MM-DDTHH:MM" format
 		gameDateTime, err := time.Parse("2006-01-02T15:04", gameDateTimeStr)
 		if err != nil {
-			data := map[string]interface{}{
-				"Error": "Invalid date/time format. Use YYYY-MM-DDTHH:MM.",
-				"Form": map[string]string{ // Keep submitted values to repopulate form
-					"title": title, "description": description, "game_datetime": gameDateTimeStr, "location": location,
-				},
-			}
-			RenderTemplate(w, "games/new_game.html", data)
-			return
-		}
-
-		currentUser, err := GetCurrentUser(r, db)
-		if err != nil {
-			// This should ideally not happen if AuthMiddleware is working correctly
-			http.Error(w, "User not authenticated: "+err.Error(), http.StatusUnauthorized)
+			renderNewGameForm(w, db, currentUser, form, "Invalid date/time format. Use YYYY-MM-DDTHH:MM.")
 			return
 		}
 
@@ -194,19 +554,49 @@ func CreateGame(db *sql.DB) http.HandlerFunc {
 			GameDateTime: gameDateTime,
 			Location:     location,
 		}
+		if errMsg := gameOptionsFromForm(form, game); errMsg != "" {
+			renderNewGameForm(w, db, currentUser, form, errMsg)
+			return
+		}
+		if errMsg, err := gamePlaceFromForm(db, form, game, currentUser); err != nil {
+			fmt.Printf("Error checking the venue of a new game: %v\n", err)
+			http.Error(w, "Failed to create game. Please try again.", http.StatusInternalServerError)
+			return
+		} else if errMsg != "" {
+			renderNewGameForm(w, db, currentUser, form, errMsg)
+			return
+		}
+		if game.SystemID != 0 {
+			if _, err := database.GetGameSystemByID(db, game.SystemID); err == sql.ErrNoRows {
+				renderNewGameForm(w, db, currentUser, form, "Choose a game system from the list.")
+				return
+			} else if err != nil {
+				fmt.Printf("Error fetching game system %d: %v\n", game.SystemID, err)
+				http.Error(w, "Failed to create game. Please try again.", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if campaignName != "" {
+			campaign, err := database.GetOrCreateCampaign(db, currentUser.ID, campaignName)
+			if err != nil {
+				fmt.Printf("Error finding or creating campaign %q: %v\n", campaignName, err)
+				http.Error(w, "Failed to create game. Please try again.", http.StatusInternalServerError)
+				return
+			}
+			game.CampaignID = campaign.ID
+		}
 
 		createdGame, err := database.CreateGame(db, game)
 		if err != nil {
-			data := map[string]interface{}{
-				"Error": "Failed to create game: " + err.Error(),
-				"Form": map[string]string{
-					"title": title, "description": description, "game_datetime": gameDateTimeStr, "location": location,
-				},
-			}
-			RenderTemplate(w, "games/new_game.html", data)
+			renderNewGameForm(w, db, currentUser, form, "Failed to create game: "+err.Error())
 			return
 		}
 
+		if form["invite_roster"] != "" && createdGame.CampaignID != 0 {
+			inviteCampaignRoster(db, createdGame, currentUser)
+		}
+
 		// Successful creation, redirect to the game's detail page.
 		// For HTMX, a redirect can be triggered by HX-Redirect header.
 		redirectURL := fmt.Sprintf("/games/%d", createdGame.ID)
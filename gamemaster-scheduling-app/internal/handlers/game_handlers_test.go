@@ -1,13 +1,9 @@
 package handlers
 
 import (
-	"database/sql"
 	"io"
 	"net/http"
-	"net/http/cookiejar"
-	"net/http/httptest"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"testing"
@@ -18,184 +14,8 @@ import (
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
 
-// testServerGame struct holds a test server and its dependencies for game tests
-type testServerGame struct {
-	server *httptest.Server
-	db     *sql.DB
-	client *http.Client // HTTP client that can handle cookies
-	mux    *http.ServeMux // Expose mux to add more routes if needed
-}
-
-// setupTestServerForGames initializes an in-memory SQLite database, loads templates,
-// sets up the application router (including dynamic game paths), and starts an httptest.Server.
-func setupTestServerForGames(t *testing.T) *testServerGame {
-	t.Helper()
-
-	db, err := database.InitDB(":memory:")
-	if err != nil {
-		t.Fatalf("Failed to initialize test database: %v", err)
-	}
-
-	templatePath := "../../web/templates"
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		templatePath = "web/templates"
-	}
-	err = LoadTemplates(templatePath)
-	if err != nil {
-		t.Fatalf("Error loading templates from %s: %v", templatePath, err)
-	}
-
-	mux := http.NewServeMux()
-
-	// Static File Server
-	// fs := http.FileServer(http.Dir("../../web/static"))
-	// mux.Handle("/static/", http.StripPrefix("/static/", fs))
-
-	// Root Handler
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/" { http.Redirect(w, r, "/games", http.StatusSeeOther) } else 
-		{ RenderErrorPage(w, r, db, http.StatusNotFound, "Page Not Found", "Test: Page not found.")}
-	})
-
-	// Auth Routes (needed for testing auth-protected game routes)
-	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet { RegisterPage(w, r) } else 
-		if r.Method == http.MethodPost { Register(db)(w, r) } else 
-		{ RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "")}
-	})
-	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet { LoginPage(w, r) } else 
-		if r.Method == http.MethodPost { Login(db)(w, r) } else
-		{ RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "")}
-	})
-	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost { Logout(w, r) } else
-		{ RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "")}
-	})
-
-	// Game Routes
-	mux.HandleFunc("/games", GamesListPage(db))
-	mux.HandleFunc("/games/new", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet { AuthMiddleware(CreateGamePage)(w,r) } else
-		if r.Method == http.MethodPost { AuthMiddleware(CreateGame(db))(w,r) } else
-		{ RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "") }
-	})
-	
-	// Dynamic Game Path Router (simplified from main.go for test focus)
-	// This needs to be robust enough for the tests.
-	mux.HandleFunc("/games/", func(w http.ResponseWriter, r *http.Request) {
-		path := r.URL.Path
-		parts := strings.Split(strings.TrimPrefix(path, "/games/"), "/")
-
-		if len(parts) == 0 || parts[0] == "" {
-			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Game ID missing or invalid path.")
-			return
-		}
-		gameIDStr := parts[0]
-		if _, err := strconv.ParseInt(gameIDStr, 10, 64); err != nil && gameIDStr != "new" { 
-			// "new" is handled by its own more specific mux.HandleFunc
-			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid Game ID format.")
-			return
-		}
-
-		if len(parts) == 1 { // Path is /games/{id}
-			if r.Method == http.MethodGet { GameDetailPage(db)(w, r) } else 
-			{ RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "") }
-		} else if len(parts) == 2 { // Path is /games/{id}/action
-			action := parts[1]
-			// RSVP and Chat handlers will be tested in their own file,
-			// but the routing logic needs to be aware of them.
-			// For now, just a placeholder or error if not testing those actions here.
-			switch action {
-			case "rsvp": // Placeholder for RSVP
-				if r.Method == http.MethodPost { AuthMiddleware(SubmitRSVP(db))(w,r) } else
-				{ RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "RSVP requires POST") }
-			case "chat": // Placeholder for Chat
-				if r.Method == http.MethodPost { AuthMiddleware(PostChatMessage(db))(w,r) } else
-				{ RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Chat requires POST") }
-			default:
-				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid game action.")
-			}
-		} else {
-			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid game path structure.")
-		}
-	})
-
-
-	ts := httptest.NewServer(mux)
-	jar, _ := cookiejar.New(nil)
-	client := &http.Client{
-		Jar: jar,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse 
-		},
-	}
-
-	return &testServerGame{
-		server: ts,
-		db:     db,
-		client: client,
-		mux:    mux,
-	}
-}
-
-func (ts *testServerGame) Teardown() {
-	ts.server.Close()
-	ts.db.Close()
-}
-
-// Helper to register and login a user, returns the authenticated client and user model
-func (ts *testServerGame) registerAndLoginUser(t *testing.T, email, password string) (*http.Client, *models.User) {
-	t.Helper()
-	// Register
-	regData := url.Values{"email": {email}, "password": {password}, "confirm_password": {password}}
-	resp, err := ts.client.PostForm(ts.server.URL+"/register", regData)
-	if err != nil {
-		t.Fatalf("Helper register failed: %v", err)
-	}
-	resp.Body.Close()
-	if resp.StatusCode != http.StatusSeeOther { // Should redirect to /login
-		t.Fatalf("Helper register expected redirect, got %d", resp.StatusCode)
-	}
-
-	// Login
-	loginData := url.Values{"email": {email}, "password": {password}}
-	resp, err = ts.client.PostForm(ts.server.URL+"/login", loginData)
-	if err != nil {
-		t.Fatalf("Helper login failed: %v", err)
-	}
-	resp.Body.Close()
-	if resp.StatusCode != http.StatusSeeOther { // Should redirect to /games
-		t.Fatalf("Helper login expected redirect, got %d", resp.StatusCode)
-	}
-	
-	user, dbErr := database.GetUserByEmail(ts.db, email)
-	if dbErr != nil {
-		t.Fatalf("Helper: Failed to get user from DB after login: %v", dbErr)
-	}
-	return ts.client, user // client now has session cookie
-}
-
-// Helper to create a game directly in DB for testing reads
-func (ts *testServerGame) createTestGameDirectly(t *testing.T, gmID int64, title string) *models.Game {
-	t.Helper()
-	game := &models.Game{
-		GMID:         gmID,
-		Title:        title,
-		Description:  "Test Desc",
-		GameDateTime: time.Now().Add(7 * 24 * time.Hour),
-		Location:     "Test Location",
-	}
-	createdGame, err := database.CreateGame(ts.db, game)
-	if err != nil {
-		t.Fatalf("Failed to create test game directly: %v", err)
-	}
-	return createdGame
-}
-
-
 func TestGamesListAndDetailPages(t *testing.T) {
-	ts := setupTestServerForGames(t)
+	ts := setupTestServer(t)
 	defer ts.Teardown()
 
 	// Register a GM user to create games
@@ -277,7 +97,7 @@ func TestGamesListAndDetailPages(t *testing.T) {
 
 
 func TestCreateGame(t *testing.T) {
-	ts := setupTestServerForGames(t)
+	ts := setupTestServer(t)
 	defer ts.Teardown()
 
 	// Unauthenticated client
@@ -385,4 +205,112 @@ func TestCreateGame(t *testing.T) {
 			t.Errorf("Game in DB title = %s; want %s", dbGame.Title, gameTitle)
 		}
 	})
+
+	t.Run("POST /games/new description too long", func(t *testing.T) {
+		formData := url.Values{
+			"title":         {"Wall of Text"},
+			"description":   {strings.Repeat("a", models.MaxGameDescriptionLength+1)},
+			"game_datetime": {time.Now().Add(72 * time.Hour).Format("2006-01-02T15:04")},
+			"location":      {"The Test Server"},
+		}
+		_, body := postForm(t, authedClient, ts.server.URL+"/games/new", formData)
+		if !strings.Contains(body, "The description can be at most") {
+			t.Errorf("overlong description was not refused: %.200s", body)
+		}
+		games, _ := database.GetAllGames(ts.db)
+		for _, g := range games {
+			if g.Title == "Wall of Text" {
+				t.Errorf("game with an overlong description was created")
+			}
+		}
+	})
+}
+
+func TestGameTaxonomyAndFilters(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, _ := ts.newUserClient(t, "taxonomy_gm@example.com", "gmpass")
+	playerClient, player := ts.newUserClient(t, "taxonomy_player@example.com", "password")
+	adminClient, admin := ts.newUserClient(t, "taxonomy_admin@example.com", "password")
+	database.GrantAdminRole(ts.db, []string{admin.Email})
+
+	// Admins manage the list of systems.
+	if status, _ := postForm(t, playerClient, ts.server.URL+"/admin/systems", url.Values{"name": {"Homebrew"}}); status != http.StatusForbidden {
+		t.Errorf("player adding a system status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status, _ := postForm(t, adminClient, ts.server.URL+"/admin/systems", url.Values{"name": {"  Dungeon   World "}}); status != http.StatusSeeOther {
+		t.Errorf("adding a system status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if _, body := postForm(t, adminClient, ts.server.URL+"/admin/systems", url.Values{"name": {"dungeon world"}}); !strings.Contains(body, "already listed") {
+		t.Errorf("duplicate system was not refused: %s", body)
+	}
+	systemIDs := make(map[string]string)
+	systems, _ := database.GetGameSystems(ts.db)
+	for _, s := range systems {
+		systemIDs[s.Name] = strconv.FormatInt(s.ID, 10)
+	}
+	if systemIDs["Dungeon World"] == "" {
+		t.Fatalf("systems = %v, want Dungeon World", systemIDs)
+	}
+
+	// GMs pick from the taxonomy when creating a game.
+	gameTime := time.Now().Add(48 * time.Hour).Format("2006-01-02T15:04")
+	newGame := url.Values{
+		"title": {"Intro One-Shot"}, "game_datetime": {gameTime}, "location": {"Game store"},
+		"system_id": {systemIDs["D&D 5e"]}, "experience_level": {models.ExperienceNewPlayerFriendly}, "format": {models.FormatOneShot}, "play_mode": {"hovercraft"},
+	}
+	if _, body := postForm(t, gmClient, ts.server.URL+"/games/new", newGame); !strings.Contains(body, "Choose in person or online") {
+		t.Errorf("unknown play mode was not refused: %s", body)
+	}
+	newGame.Set("play_mode", models.PlayModeInPerson)
+	newGame.Set("system_id", "9999")
+	if _, body := postForm(t, gmClient, ts.server.URL+"/games/new", newGame); !strings.Contains(body, "Choose a game system from the list") {
+		t.Errorf("unknown system was not refused: %s", body)
+	}
+	newGame.Set("system_id", systemIDs["D&D 5e"])
+	postForm(t, gmClient, ts.server.URL+"/games/new", newGame)
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{
+		"title": {"Online Dungeon Crawl"}, "game_datetime": {gameTime}, "location": {"Discord"},
+		"system_id": {systemIDs["Dungeon World"]}, "format": {models.FormatCampaign}, "play_mode": {models.PlayModeOnline},
+	})
+	if games, _ := database.GetAllGames(ts.db); len(games) != 2 {
+		t.Fatalf("games = %d, want 2", len(games))
+	}
+
+	// Players filter the list by the taxonomy.
+	_, body := getBody(t, playerClient, ts.server.URL+"/games?experience=new_player_friendly&mode=in_person")
+	if !strings.Contains(body, "Intro One-Shot") || strings.Contains(body, "Online Dungeon Crawl") || !strings.Contains(body, "New Player Friendly") {
+		t.Errorf("filtered games list = %s", body)
+	}
+	_, body = getBody(t, playerClient, ts.server.URL+"/games?system="+systemIDs["Dungeon World"])
+	if strings.Contains(body, "Intro One-Shot") || !strings.Contains(body, "Online Dungeon Crawl") {
+		t.Errorf("games list filtered by system = %s", body)
+	}
+
+	// Preferred systems are set on the player's own profile and matched in the list.
+	profileURL := ts.server.URL + "/users/" + strconv.FormatInt(player.ID, 10)
+	if status, _ := postForm(t, gmClient, profileURL+"/systems", url.Values{"system_id": {systemIDs["Dungeon World"]}}); status != http.StatusForbidden {
+		t.Errorf("changing someone else's systems status = %d, want %d", status, http.StatusForbidden)
+	}
+	postForm(t, playerClient, profileURL+"/systems", url.Values{"system_id": {systemIDs["Dungeon World"]}})
+	if _, body := getBody(t, gmClient, profileURL); !strings.Contains(body, "Dungeon World</a>") {
+		t.Errorf("profile does not list the preferred system")
+	}
+	_, body = getBody(t, playerClient, ts.server.URL+"/games")
+	if strings.Count(body, "One of your systems") != 1 {
+		t.Errorf("games list should mark one game as matching the player's systems: %s", body)
+	}
+	_, body = getBody(t, playerClient, ts.server.URL+"/games?my_systems=1")
+	if strings.Contains(body, "Intro One-Shot") || !strings.Contains(body, "Online Dungeon Crawl") {
+		t.Errorf("games list limited to my systems = %s", body)
+	}
+
+	// Systems in use stay; the audit log records the admin's changes.
+	if _, body := postForm(t, adminClient, ts.server.URL+"/admin/systems/"+systemIDs["Dungeon World"]+"/delete", nil); !strings.Contains(body, "Games use that system") {
+		t.Errorf("deleting a system in use was not refused: %s", body)
+	}
+	if entries, _ := database.GetAuditLog(ts.db, 10); len(entries) != 1 || entries[0].Action != models.AuditAddGameSystem {
+		t.Errorf("audit log = %v, want the addition", entries)
+	}
 }
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// CampaignHostingPage shows a campaign's hosting rotation, who hosts each
+// upcoming session and the swaps awaiting an answer: GET /campaigns/{id}/hosting.
+// This handler should be wrapped by AuthMiddleware.
+func CampaignHostingPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, roster, ok := campaignForMember(w, r, db, "its hosting rotation")
+		if !ok {
+			return
+		}
+		renderHostingPage(w, r, db, campaign, currentUser, roster, "")
+	}
+}
+
+func renderHostingPage(w http.ResponseWriter, r *http.Request, db *sql.DB, campaign *models.Campaign, currentUser *models.User, roster []*models.User, errMsg string) {
+	rotation, err := database.GetHostRotation(db, campaign.ID)
+	if err != nil {
+		fmt.Printf("Error fetching hosting rotation of campaign %d: %v\n", campaign.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the hosting rotation.")
+		return
+	}
+	sessions, err := database.GetUpcomingSessionHosts(db, campaign.ID, time.Now())
+	if err != nil {
+		fmt.Printf("Error fetching session hosts of campaign %d: %v\n", campaign.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the hosting rotation.")
+		return
+	}
+	swaps, err := database.GetPendingHostSwaps(db, campaign.ID)
+	if err != nil {
+		fmt.Printf("Error fetching host swaps of campaign %d: %v\n", campaign.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the hosting rotation.")
+		return
+	}
+
+	inRotation := make(map[int64]bool, len(rotation))
+	for _, u := range rotation {
+		inRotation[u.ID] = true
+	}
+	var candidates []*models.User // Roster members the GM can add to the rotation
+	for _, u := range roster {
+		if !inRotation[u.ID] {
+			candidates = append(candidates, u)
+		}
+	}
+	var mine, others []*models.SessionHost // For the swap form
+	for _, s := range sessions {
+		switch s.UserID {
+		case 0:
+		case currentUser.ID:
+			mine = append(mine, s)
+		default:
+			others = append(others, s)
+		}
+	}
+
+	RenderTemplate(w, "campaigns/hosting.html", map[string]interface{}{
+		"Title":         campaign.Name + " hosting",
+		"User":          currentUser,
+		"Campaign":      campaign,
+		"IsGM":          campaign.IsGM(currentUser.ID),
+		"Rotation":      rotation,
+		"Candidates":    candidates,
+		"Sessions":      sessions,
+		"MySessions":    mine,
+		"OtherSessions": others,
+		"Swaps":         swaps,
+		"Error":         errMsg,
+	})
+}
+
+// hostingURL is where hosting changes redirect to.
+func hostingURL(campaign *models.Campaign) string {
+	return fmt.Sprintf("/campaigns/%d/hosting", campaign.ID)
+}
+
+// notifyHosting tells a user about a hosting change in a campaign.
+func notifyHosting(db *sql.DB, campaign *models.Campaign, userID int64, message string) {
+	_, err := database.CreateNotification(db, &models.Notification{
+		UserID: userID, Kind: models.NotificationKindHosting, Message: message, Link: hostingURL(campaign),
+	})
+	if err != nil {
+		fmt.Printf("Error notifying user %d of hosting in campaign %d: %v\n", userID, campaign.ID, err)
+	}
+}
+
+// AddCampaignHost adds a roster member to the end of the hosting rotation:
+// POST /campaigns/{id}/hosting/rotation. Only the campaign's GM can change the
+// rotation. This handler should be wrapped by AuthMiddleware.
+func AddCampaignHost(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, roster, ok := campaignForMember(w, r, db, "its hosting rotation")
+		if !ok {
+			return
+		}
+		if !campaign.IsGM(currentUser.ID) {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the campaign's GM can change the hosting rotation.")
+			return
+		}
+		userID, _ := strconv.ParseInt(r.FormValue("user_id"), 10, 64)
+		inRoster := false
+		for _, u := range roster {
+			inRoster = inRoster || u.ID == userID
+		}
+		if !inRoster {
+			renderHostingPage(w, r, db, campaign, currentUser, roster, "Choose one of the campaign's players.")
+			return
+		}
+		if err := database.AddToHostRotation(db, campaign.ID, userID); err != nil {
+			fmt.Printf("Error adding user %d to the hosting rotation of campaign %d: %v\n", userID, campaign.ID, err)
+			http.Error(w, "Failed to update the hosting rotation. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, hostingURL(campaign), http.StatusSeeOther)
+	}
+}
+
+// RemoveCampaignHost takes a player out of the hosting rotation:
+// POST /campaigns/{id}/hosting/rotation/{userID}/remove. Only the campaign's GM
+// can change the rotation. This handler should be wrapped by AuthMiddleware.
+func RemoveCampaignHost(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, _, ok := campaignForMember(w, r, db, "its hosting rotation")
+		if !ok {
+			return
+		}
+		if !campaign.IsGM(currentUser.ID) {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the campaign's GM can change the hosting rotation.")
+			return
+		}
+		userID, err := pathInt64(r, "/campaigns/", 3)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid user ID format.")
+			return
+		}
+		if err := database.RemoveFromHostRotation(db, campaign.ID, userID); err != nil {
+			fmt.Printf("Error removing user %d from the hosting rotation of campaign %d: %v\n", userID, campaign.ID, err)
+			http.Error(w, "Failed to update the hosting rotation. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, hostingURL(campaign), http.StatusSeeOther)
+	}
+}
+
+// AssignNextHost gives the next upcoming session without a host to the next
+// player in the rotation, and tells them: POST /campaigns/{id}/hosting/assign.
+// Only the campaign's GM can assign hosts. This handler should be wrapped by
+// AuthMiddleware.
+func AssignNextHost(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, roster, ok := campaignForMember(w, r, db, "its hosting rotation")
+		if !ok {
+			return
+		}
+		if !campaign.IsGM(currentUser.ID) {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the campaign's GM can assign hosts.")
+			return
+		}
+		session, err := database.AssignNextHost(db, campaign.ID, time.Now())
+		switch err {
+		case nil:
+		case database.ErrNoHostRotation:
+			renderHostingPage(w, r, db, campaign, currentUser, roster, "Add players to the rotation before assigning hosts.")
+			return
+		case database.ErrNoSessionToHost:
+			renderHostingPage(w, r, db, campaign, currentUser, roster, "Every upcoming session already has a host.")
+			return
+		default:
+			fmt.Printf("Error assigning the next host of campaign %d: %v\n", campaign.ID, err)
+			http.Error(w, "Failed to assign a host. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		notifyHosting(db, campaign, session.UserID, fmt.Sprintf("You're hosting %s on %s.", session.GameTitle, session.GameDateTime.Format("Jan 2")))
+		http.Redirect(w, r, hostingURL(campaign), http.StatusSeeOther)
+	}
+}
+
+// RequestHostSwap asks another host to trade sessions with the current user:
+// POST /campaigns/{id}/hosting/swaps, with the user's session in from_game_id
+// and the other in to_game_id. This handler should be wrapped by AuthMiddleware.
+func RequestHostSwap(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, roster, ok := campaignForMember(w, r, db, "its hosting rotation")
+		if !ok {
+			return
+		}
+		fromGameID, _ := strconv.ParseInt(r.FormValue("from_game_id"), 10, 64)
+		toGameID, _ := strconv.ParseInt(r.FormValue("to_game_id"), 10, 64)
+		swap, err := database.RequestHostSwap(db, campaign.ID, fromGameID, toGameID, currentUser.ID, time.Now())
+		if err == database.ErrInvalidHostSwap {
+			renderHostingPage(w, r, db, campaign, currentUser, roster, "Choose a session you host and an upcoming session someone else hosts.")
+			return
+		} else if err != nil {
+			fmt.Printf("Error requesting a host swap in campaign %d: %v\n", campaign.ID, err)
+			http.Error(w, "Failed to ask for the swap. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		notifyHosting(db, campaign, swap.ResponderID, fmt.Sprintf("%s asks to swap hosting %s for %s.", currentUser.DisplayName(), swap.FromTitle, swap.ToTitle))
+		http.Redirect(w, r, hostingURL(campaign), http.StatusSeeOther)
+	}
+}
+
+// RespondToHostSwap accepts or declines a swap asked of the current user:
+// POST /campaigns/{id}/hosting/swaps/{swapID}, with decision "accept" or
+// "decline". This handler should be wrapped by AuthMiddleware.
+func RespondToHostSwap(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, roster, ok := campaignForMember(w, r, db, "its hosting rotation")
+		if !ok {
+			return
+		}
+		swapID, err := pathInt64(r, "/campaigns/", 3)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid swap ID format.")
+			return
+		}
+		swap, err := database.GetHostSwapByID(db, swapID)
+		if err != nil || swap.CampaignID != campaign.ID || swap.ResponderID != currentUser.ID || swap.Status != models.HostSwapPending {
+			if err != nil && err != sql.ErrNoRows {
+				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			} else {
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Swap not found.")
+			}
+			return
+		}
+		accept := r.FormValue("decision") == "accept"
+		err = database.RespondToHostSwap(db, swap, accept)
+		switch {
+		case err == database.ErrHostSwapOutdated:
+			renderHostingPage(w, r, db, campaign, currentUser, roster, "Those sessions have changed hosts since the swap was asked for, so it was declined.")
+			return
+		case err == sql.ErrNoRows:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Swap not found.")
+			return
+		case err != nil:
+			fmt.Printf("Error answering host swap %d: %v\n", swap.ID, err)
+			http.Error(w, "Failed to answer the swap. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		outcome := "declined"
+		if accept {
+			outcome = "accepted"
+		}
+		notifyHosting(db, campaign, swap.RequesterID, fmt.Sprintf("%s %s your swap of %s for %s.", currentUser.DisplayName(), outcome, swap.FromTitle, swap.ToTitle))
+		http.Redirect(w, r, hostingURL(campaign), http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestHostingRotationAndExpenses(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "rota_gm@example.com", "gmpass")
+	annClient, ann := ts.newUserClient(t, "rota_ann@example.com", "password")
+	bobClient, bob := ts.newUserClient(t, "rota_bob@example.com", "password")
+	outsiderClient, _ := ts.newUserClient(t, "rota_outsider@example.com", "password")
+
+	campaign, _ := database.GetOrCreateCampaign(ts.db, gm.ID, "Homebrew")
+	var sessions []*models.Game
+	for i := 1; i <= 2; i++ {
+		game, _ := database.CreateGame(ts.db, &models.Game{GMID: gm.ID, Title: "Session " + strconv.Itoa(i), GameDateTime: time.Now().AddDate(0, 0, 7*i), Location: "Rotating", CampaignID: campaign.ID})
+		sessions = append(sessions, game)
+		for _, u := range []*models.User{ann, bob} {
+			database.CreateOrUpdateRSVP(ts.db, &models.RSVP{UserID: u.ID, GameID: game.ID, Status: models.RSVPStatusAttending})
+		}
+	}
+	campaignURL := ts.server.URL + "/campaigns/" + strconv.FormatInt(campaign.ID, 10)
+
+	if status, _ := getBody(t, outsiderClient, campaignURL+"/hosting"); status != http.StatusForbidden {
+		t.Errorf("outsider hosting page status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status, _ := postForm(t, annClient, campaignURL+"/hosting/rotation", url.Values{"user_id": {strconv.FormatInt(ann.ID, 10)}}); status != http.StatusForbidden {
+		t.Errorf("player changing the rotation status = %d, want %d", status, http.StatusForbidden)
+	}
+	if _, body := postForm(t, gmClient, campaignURL+"/hosting/assign", nil); !strings.Contains(body, "Add players to the rotation before assigning hosts.") {
+		t.Errorf("assigning without a rotation was not refused: %s", body)
+	}
+	for _, u := range []*models.User{ann, bob} {
+		postForm(t, gmClient, campaignURL+"/hosting/rotation", url.Values{"user_id": {strconv.FormatInt(u.ID, 10)}})
+	}
+	for range sessions {
+		if status, _ := postForm(t, gmClient, campaignURL+"/hosting/assign", nil); status != http.StatusSeeOther {
+			t.Fatalf("assign host status = %d, want %d", status, http.StatusSeeOther)
+		}
+	}
+	if host, _ := database.GetSessionHost(ts.db, sessions[0].ID); host == nil || host.ID != ann.ID {
+		t.Fatalf("host of session 1 = %v, want ann", host)
+	}
+	if notes, _ := database.GetNotificationsForUser(ts.db, ann.ID, 10); len(notes) == 0 || notes[0].Kind != models.NotificationKindHosting {
+		t.Errorf("ann was not told she hosts: %v", notes)
+	}
+	if _, body := getBody(t, bobClient, ts.server.URL+"/games/"+strconv.FormatInt(sessions[1].ID, 10)); !strings.Contains(body, "Hosted by:") || !strings.Contains(body, bob.DisplayName()) {
+		t.Errorf("game page does not show the host: %s", body)
+	}
+
+	// Ann asks Bob to swap; Bob accepts.
+	first, second := strconv.FormatInt(sessions[0].ID, 10), strconv.FormatInt(sessions[1].ID, 10)
+	if _, body := postForm(t, annClient, campaignURL+"/hosting/swaps", url.Values{"from_game_id": {second}, "to_game_id": {first}}); !strings.Contains(body, "Choose a session you host") {
+		t.Errorf("offering someone else's session was not refused: %s", body)
+	}
+	postForm(t, annClient, campaignURL+"/hosting/swaps", url.Values{"from_game_id": {first}, "to_game_id": {second}})
+	swaps, _ := database.GetPendingHostSwaps(ts.db, campaign.ID)
+	if len(swaps) != 1 {
+		t.Fatalf("pending swaps = %v, want one", swaps)
+	}
+	swapURL := campaignURL + "/hosting/swaps/" + strconv.FormatInt(swaps[0].ID, 10)
+	if status, _ := postForm(t, annClient, swapURL, url.Values{"decision": {"accept"}}); status != http.StatusNotFound {
+		t.Errorf("requester answering her own swap status = %d, want %d", status, http.StatusNotFound)
+	}
+	if _, body := getBody(t, bobClient, campaignURL+"/hosting"); !strings.Contains(body, `value="accept"`) {
+		t.Errorf("bob is not offered to answer the swap: %s", body)
+	}
+	if status, _ := postForm(t, bobClient, swapURL, url.Values{"decision": {"accept"}}); status != http.StatusSeeOther {
+		t.Fatalf("accept swap status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if host, _ := database.GetSessionHost(ts.db, sessions[0].ID); host.ID != bob.ID {
+		t.Errorf("host of session 1 after the swap = %s, want bob", host.Email)
+	}
+
+	// Expenses: Ann fronts pizza for everyone, Bob pays drinks by custom shares.
+	expensesURL := ts.server.URL + "/games/" + first + "/expenses"
+	if status, _ := getBody(t, outsiderClient, expensesURL); status != http.StatusForbidden {
+		t.Errorf("outsider expenses status = %d, want %d", status, http.StatusForbidden)
+	}
+	everyone := []string{strconv.FormatInt(gm.ID, 10), strconv.FormatInt(ann.ID, 10), strconv.FormatInt(bob.ID, 10)}
+	if _, body := postForm(t, annClient, expensesURL, url.Values{"description": {"Pizza"}, "amount": {"thirty"}, "split": {"even"}, "participant": everyone}); !strings.Contains(body, "Enter the amount paid as a number") {
+		t.Errorf("invalid amount was not rejected: %s", body)
+	}
+	if _, body := postForm(t, annClient, expensesURL, url.Values{"description": {"Pizza"}, "amount": {"30"}, "split": {"even"}, "participant": {"999999"}}); !strings.Contains(body, "Only the session") {
+		t.Errorf("a non-attendee share was not rejected: %s", body)
+	}
+	if status, _ := postForm(t, annClient, expensesURL, url.Values{"description": {"Pizza"}, "amount": {"30.00"}, "split": {"even"}, "participant": everyone}); status != http.StatusSeeOther {
+		t.Fatalf("record expense status = %d, want %d", status, http.StatusSeeOther)
+	}
+	drinks := url.Values{"description": {"Drinks"}, "amount": {"8"}, "split": {"custom"}, "share_" + everyone[1]: {"3"}, "share_" + everyone[2]: {"4"}}
+	if _, body := postForm(t, bobClient, expensesURL, drinks); !strings.Contains(body, "The shares add up to 7.00, but 8.00 was paid.") {
+		t.Errorf("unbalanced shares were not rejected: %s", body)
+	}
+	drinks.Set("share_"+everyone[2], "5")
+	postForm(t, bobClient, expensesURL, drinks)
+	if _, body := getBody(t, gmClient, expensesURL); !strings.Contains(body, "30.00 paid by") || !strings.Contains(body, "8.00 paid by") {
+		t.Errorf("expenses page does not list both expenses: %s", body)
+	}
+
+	// Running balances: Ann +30 -10 -3 = 17, Bob +8 -10 -5 = -7, GM -10. Only
+	// whoever was paid records a payment, for no more than is left to settle.
+	paymentsURL := campaignURL + "/ledger/payments"
+	if _, body := postForm(t, gmClient, paymentsURL, url.Values{"from_user_id": {everyone[1]}, "amount": {"4"}}); !strings.Contains(body, "only record a payment from someone who owes") {
+		t.Errorf("a debtor recording their own payment was not refused: %s", body)
+	}
+	if _, body := postForm(t, annClient, paymentsURL, url.Values{"from_user_id": {everyone[0]}, "amount": {"10.01"}}); !strings.Contains(body, "record at most 10.00") {
+		t.Errorf("overpayment was not refused: %s", body)
+	}
+	if _, body := postForm(t, annClient, paymentsURL, url.Values{"from_user_id": {everyone[1]}, "amount": {"1"}}); !strings.Contains(body, "Choose who paid you.") {
+		t.Errorf("paying yourself was not refused: %s", body)
+	}
+	if status, _ := postForm(t, annClient, paymentsURL, url.Values{"from_user_id": {everyone[0]}, "amount": {"4"}}); status != http.StatusSeeOther {
+		t.Fatalf("record payment status = %d, want %d", status, http.StatusSeeOther)
+	}
+	_, body := getBody(t, annClient, campaignURL+"/ledger")
+	for _, want := range []string{"is owed 13.00", "owes 7.00", "owes 6.00", "Paid back"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("ledger page is missing %q: %s", want, body)
+		}
+	}
+
+	// A mistaken payment is reversed rather than deleted.
+	entries, _ := database.GetCampaignLedger(ts.db, campaign.ID)
+	if len(entries) == 0 || entries[0].Kind != models.LedgerPayment {
+		t.Fatalf("ledger = %v, want the payment first", entries)
+	}
+	reverseURL := campaignURL + "/ledger/" + strconv.FormatInt(entries[0].ID, 10) + "/reverse"
+	if status, _ := postForm(t, bobClient, reverseURL, nil); status != http.StatusForbidden {
+		t.Errorf("bob reversing ann's payment status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status, _ := postForm(t, annClient, reverseURL, nil); status != http.StatusSeeOther {
+		t.Fatalf("reverse payment status = %d, want %d", status, http.StatusSeeOther)
+	}
+	_, body = getBody(t, annClient, campaignURL+"/ledger")
+	for _, want := range []string{"is owed 17.00", "owes 10.00", "reversed Paid back, 4.00"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("ledger page after the reversal is missing %q: %s", want, body)
+		}
+	}
+	if _, body := postForm(t, gmClient, reverseURL, nil); !strings.Contains(body, "already been reversed") {
+		t.Errorf("reversing twice was not refused: %s", body)
+	}
+	if status, _ := getBody(t, outsiderClient, campaignURL+"/ledger"); status != http.StatusForbidden {
+		t.Errorf("outsider ledger status = %d, want %d", status, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/ledger"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// expenseForm is what the expense form was filled in with, to show it again
+// next to an error.
+type expenseForm struct {
+	Description  string
+	Amount       string
+	Split        string // "even" or "custom"
+	Participants map[int64]bool
+	Shares       map[int64]string
+}
+
+// sessionForExpenses loads the session /games/{id} for the current user with
+// its attendees, rendering an error page unless it belongs to a campaign and
+// the user attended it or is in the campaign's roster.
+func sessionForExpenses(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.Game, *models.User, []*models.User, bool) {
+	currentUser, err := GetCurrentUser(r, db)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, nil, nil, false
+	}
+	gameID, err := pathInt64(r, "/games/", 0)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid game ID format.")
+		return nil, nil, nil, false
+	}
+	game, err := database.GetGameByID(db, gameID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Game not found.")
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return nil, nil, nil, false
+	}
+	if game.CampaignID == 0 {
+		RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Only campaign sessions keep an expense ledger.")
+		return nil, nil, nil, false
+	}
+	attendees, err := database.GetSessionAttendees(db, game.ID)
+	if err != nil {
+		fmt.Printf("Error fetching attendees of game %d: %v\n", game.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the session's expenses.")
+		return nil, nil, nil, false
+	}
+	if userIn(attendees, currentUser.ID) {
+		return game, currentUser, attendees, true
+	}
+	roster, err := database.GetCampaignRoster(db, game.CampaignID)
+	if err != nil {
+		fmt.Printf("Error fetching roster of campaign %d: %v\n", game.CampaignID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the session's expenses.")
+		return nil, nil, nil, false
+	}
+	if !userIn(roster, currentUser.ID) {
+		RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the campaign's GM and players can see its expenses.")
+		return nil, nil, nil, false
+	}
+	return game, currentUser, attendees, true
+}
+
+// userIn reports whether userID is one of users.
+func userIn(users []*models.User, userID int64) bool {
+	for _, u := range users {
+		if u.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionExpensesPage lists the expenses recorded for a campaign session, with
+// a form for its attendees to add one: GET /games/{id}/expenses. This handler
+// should be wrapped by AuthMiddleware.
+func SessionExpensesPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, attendees, ok := sessionForExpenses(w, r, db)
+		if !ok {
+			return
+		}
+		form := &expenseForm{Split: "even", Participants: make(map[int64]bool), Shares: make(map[int64]string)}
+		for _, u := range attendees {
+			form.Participants[u.ID] = true
+		}
+		renderSessionExpenses(w, r, db, game, currentUser, attendees, form, "")
+	}
+}
+
+func renderSessionExpenses(w http.ResponseWriter, r *http.Request, db *sql.DB, game *models.Game, currentUser *models.User, attendees []*models.User, form *expenseForm, errMsg string) {
+	entries, err := database.GetSessionExpenses(db, game.ID)
+	if err != nil {
+		fmt.Printf("Error fetching expenses of game %d: %v\n", game.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the session's expenses.")
+		return
+	}
+	RenderTemplate(w, "games/expenses.html", map[string]interface{}{
+		"Title":     game.Title + " expenses",
+		"User":      currentUser,
+		"Game":      game,
+		"Attendees": attendees,
+		"CanAdd":    userIn(attendees, currentUser.ID),
+		"Entries":   entries,
+		"Form":      form,
+		"MaxLength": models.MaxLedgerDescriptionLength,
+		"Error":     errMsg,
+	})
+}
+
+// AddSessionExpense records an expense the current user paid for a session,
+// split evenly between the checked attendees or by custom shares:
+// POST /games/{id}/expenses. Only the session's attendees can record its
+// expenses. This handler should be wrapped by AuthMiddleware.
+func AddSessionExpense(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, attendees, ok := sessionForExpenses(w, r, db)
+		if !ok {
+			return
+		}
+		if !userIn(attendees, currentUser.ID) {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the session's attendees can record its expenses.")
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+
+		form := &expenseForm{
+			Description:  strings.TrimSpace(r.FormValue("description")),
+			Amount:       strings.TrimSpace(r.FormValue("amount")),
+			Split:        r.FormValue("split"),
+			Participants: make(map[int64]bool),
+			Shares:       make(map[int64]string),
+		}
+		for _, v := range r.Form["participant"] {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				form.Participants[id] = true
+			}
+		}
+		// Only attendees are on the form; shares for anyone else are ignored.
+		for _, u := range attendees {
+			form.Shares[u.ID] = strings.TrimSpace(r.FormValue(fmt.Sprintf("share_%d", u.ID)))
+		}
+		shares, total, errMsg := expenseShares(form, attendees)
+		if errMsg != "" {
+			renderSessionExpenses(w, r, db, game, currentUser, attendees, form, errMsg)
+			return
+		}
+
+		entry := &models.LedgerEntry{
+			CampaignID:  game.CampaignID,
+			GameID:      game.ID,
+			Kind:        models.LedgerExpense,
+			Description: form.Description,
+			CreatedBy:   currentUser.ID,
+			Postings:    ledger.ExpensePostings(currentUser.ID, total, shares),
+		}
+		if err := database.AddLedgerEntry(db, entry); err != nil {
+			fmt.Printf("Error recording an expense for game %d: %v\n", game.ID, err)
+			http.Error(w, "Failed to record the expense. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/games/%d/expenses", game.ID), http.StatusSeeOther)
+	}
+}
+
+// expenseShares validates the expense form and works out each participant's
+// share. It returns a message for the user if the input is invalid.
+func expenseShares(form *expenseForm, attendees []*models.User) ([]ledger.Share, models.Cents, string) {
+	if form.Description == "" || utf8.RuneCountInString(form.Description) > models.MaxLedgerDescriptionLength {
+		return nil, 0, fmt.Sprintf("Say what the expense was for, in at most %d characters.", models.MaxLedgerDescriptionLength)
+	}
+	total, err := ledger.ParseAmount(form.Amount)
+	if err != nil || total == 0 {
+		return nil, 0, "Enter the amount paid as a number such as 12.50."
+	}
+
+	var shares []ledger.Share
+	switch form.Split {
+	case "even":
+		var participants []int64
+		for _, u := range attendees { // In the attendees' order, so the leftover cents go the same way every time
+			if form.Participants[u.ID] {
+				participants = append(participants, u.ID)
+			}
+		}
+		if len(participants) != len(form.Participants) {
+			return nil, 0, "Only the session's attendees can share its expenses."
+		}
+		if len(participants) == 0 {
+			return nil, 0, "Choose who shares the expense."
+		}
+		for i, amount := range ledger.SplitEvenly(total, len(participants)) {
+			shares = append(shares, ledger.Share{UserID: participants[i], Amount: amount})
+		}
+	case "custom":
+		var sum models.Cents
+		for _, u := range attendees {
+			if form.Shares[u.ID] == "" {
+				continue
+			}
+			amount, err := ledger.ParseAmount(form.Shares[u.ID])
+			if err != nil {
+				return nil, 0, "Enter each share as a number such as 4.50, or leave it empty."
+			}
+			shares = append(shares, ledger.Share{UserID: u.ID, Amount: amount})
+			sum += amount
+		}
+		if sum != total {
+			return nil, 0, fmt.Sprintf("The shares add up to %s, but %s was paid.", sum, total)
+		}
+	default:
+		return nil, 0, "Choose how to split the expense."
+	}
+	return shares, total, ""
+}
+
+// CampaignLedgerPage shows everyone's running balance across a campaign, the
+// payments that would settle them, and every expense and payment:
+// GET /campaigns/{id}/ledger. This handler should be wrapped by AuthMiddleware.
+func CampaignLedgerPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, roster, ok := campaignForMember(w, r, db, "its expenses")
+		if !ok {
+			return
+		}
+		renderCampaignLedger(w, r, db, campaign, currentUser, roster, "")
+	}
+}
+
+func renderCampaignLedger(w http.ResponseWriter, r *http.Request, db *sql.DB, campaign *models.Campaign, currentUser *models.User, roster []*models.User, errMsg string) {
+	balances, err := database.GetLedgerBalances(db, campaign.ID)
+	if err != nil {
+		fmt.Printf("Error fetching ledger balances of campaign %d: %v\n", campaign.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the campaign's expenses.")
+		return
+	}
+	entries, err := database.GetCampaignLedger(db, campaign.ID)
+	if err != nil {
+		fmt.Printf("Error fetching the ledger of campaign %d: %v\n", campaign.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the campaign's expenses.")
+		return
+	}
+	// Players record payments they received, so only a player who is owed can
+	// record one, from someone who owes.
+	var payers []*models.User
+	if ledgerBalance(balances, currentUser.ID) > 0 {
+		for _, u := range roster {
+			if ledgerBalance(balances, u.ID) < 0 {
+				payers = append(payers, u)
+			}
+		}
+	}
+	reversible := make(map[int64]bool)
+	for _, e := range entries {
+		reversible[e.ID] = e.Reversible() && (e.CreatedBy == currentUser.ID || campaign.IsGM(currentUser.ID))
+	}
+	RenderTemplate(w, "campaigns/ledger.html", map[string]interface{}{
+		"Title":      campaign.Name + " expenses",
+		"User":       currentUser,
+		"Campaign":   campaign,
+		"Balances":   balances,
+		"SettleUp":   ledger.SettleUp(balances),
+		"Entries":    entries,
+		"Reversible": reversible,
+		"Payers":     payers,
+		"MaxLength":  models.MaxLedgerDescriptionLength,
+		"Error":      errMsg,
+	})
+}
+
+// ledgerBalance is userID's amount in balances, or 0 if they have none.
+func ledgerBalance(balances []*models.LedgerBalance, userID int64) models.Cents {
+	for _, b := range balances {
+		if b.UserID == userID {
+			return b.Amount
+		}
+	}
+	return 0
+}
+
+// RecordLedgerPayment records that another player paid the current user back:
+// POST /campaigns/{id}/ledger/payments, with from_user_id, amount and an
+// optional description. Only the player paid records a payment, so nobody can
+// clear their own debt by claiming they paid. The amount can't be more than the
+// payer owes, or more than the current user is owed. This handler should be
+// wrapped by AuthMiddleware.
+func RecordLedgerPayment(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, roster, ok := campaignForMember(w, r, db, "its expenses")
+		if !ok {
+			return
+		}
+		fromUserID, _ := strconv.ParseInt(r.FormValue("from_user_id"), 10, 64)
+		if fromUserID == currentUser.ID || !userIn(roster, fromUserID) {
+			renderCampaignLedger(w, r, db, campaign, currentUser, roster, "Choose who paid you.")
+			return
+		}
+		amount, err := ledger.ParseAmount(r.FormValue("amount"))
+		if err != nil || amount == 0 {
+			renderCampaignLedger(w, r, db, campaign, currentUser, roster, "Enter the amount paid as a number such as 12.50.")
+			return
+		}
+		description := strings.TrimSpace(r.FormValue("description"))
+		if description == "" {
+			description = "Paid back"
+		}
+		if utf8.RuneCountInString(description) > models.MaxLedgerDescriptionLength {
+			renderCampaignLedger(w, r, db, campaign, currentUser, roster, fmt.Sprintf("The note can be at most %d characters.", models.MaxLedgerDescriptionLength))
+			return
+		}
+
+		balances, err := database.GetLedgerBalances(db, campaign.ID)
+		if err != nil {
+			fmt.Printf("Error fetching ledger balances of campaign %d: %v\n", campaign.ID, err)
+			http.Error(w, "Failed to record the payment. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		limit := min(-ledgerBalance(balances, fromUserID), ledgerBalance(balances, currentUser.ID))
+		if limit <= 0 {
+			renderCampaignLedger(w, r, db, campaign, currentUser, roster, "You can only record a payment from someone who owes money while you are owed.")
+			return
+		}
+		if amount > limit {
+			renderCampaignLedger(w, r, db, campaign, currentUser, roster, fmt.Sprintf("That's more than is left to settle: record at most %s.", limit))
+			return
+		}
+
+		entry := &models.LedgerEntry{
+			CampaignID:  campaign.ID,
+			Kind:        models.LedgerPayment,
+			Description: description,
+			CreatedBy:   currentUser.ID,
+			Postings:    ledger.PaymentPostings(fromUserID, currentUser.ID, amount),
+		}
+		if err := database.AddLedgerEntry(db, entry); err != nil {
+			fmt.Printf("Error recording a payment in campaign %d: %v\n", campaign.ID, err)
+			http.Error(w, "Failed to record the payment. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/campaigns/%d/ledger", campaign.ID), http.StatusSeeOther)
+	}
+}
+
+// ReverseLedgerEntry undoes a mistaken expense or payment with a reversal entry
+// that negates its postings: POST /campaigns/{id}/ledger/{entryID}/reverse.
+// Only whoever recorded the entry and the campaign's GM can reverse it. This
+// handler should be wrapped by AuthMiddleware.
+func ReverseLedgerEntry(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaign, currentUser, roster, ok := campaignForMember(w, r, db, "its expenses")
+		if !ok {
+			return
+		}
+		entryID, err := pathInt64(r, "/campaigns/", 2)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid entry ID format.")
+			return
+		}
+		entry, err := database.GetLedgerEntry(db, entryID)
+		if err != nil || entry.CampaignID != campaign.ID {
+			if err != nil && err != sql.ErrNoRows {
+				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			} else {
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Ledger entry not found.")
+			}
+			return
+		}
+		if entry.CreatedBy != currentUser.ID && !campaign.IsGM(currentUser.ID) {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only whoever recorded an entry and the GM can reverse it.")
+			return
+		}
+		if _, err := database.ReverseLedgerEntry(db, entry.ID, currentUser.ID); err != nil {
+			if err == database.ErrEntryNotReversible {
+				renderCampaignLedger(w, r, db, campaign, currentUser, roster, "That entry has already been reversed.")
+				return
+			}
+			fmt.Printf("Error reversing ledger entry %d: %v\n", entry.ID, err)
+			http.Error(w, "Failed to reverse the entry. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/campaigns/%d/ledger", campaign.ID), http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/lfg"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// maxLFGSuggestions caps the matches suggested for each of the viewer's posts.
+const maxLFGSuggestions = 5
+
+// lfgOwnPost is one of the viewer's open posts with the matches suggested for it.
+type lfgOwnPost struct {
+	Post    *models.LFGPost
+	Matches []*lfg.Match
+	// Full is set for a GM post whose game has no open seats or has started.
+	Full bool
+}
+
+// LFGBoardPage shows the looking-for-group board: GET /lfg. Players' and GMs'
+// open posts are listed, and each of the viewer's own posts comes with its best
+// matches: games for a player post, players for a GM post.
+// This handler should be wrapped by AuthMiddleware.
+func LFGBoardPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		renderLFGBoard(w, r, db, currentUser, nil, "")
+	}
+}
+
+func renderLFGBoard(w http.ResponseWriter, r *http.Request, db *sql.DB, currentUser *models.User, form map[string]string, errMsg string) {
+	now := time.Now()
+	playerPosts, err := database.GetOpenLFGPosts(db, models.LFGKindPlayer)
+	if err != nil {
+		fmt.Printf("Error fetching LFG player posts: %v\n", err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the board.")
+		return
+	}
+	gmPosts, err := database.GetOpenLFGPosts(db, models.LFGKindGM)
+	if err != nil {
+		fmt.Printf("Error fetching LFG GM posts: %v\n", err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the board.")
+		return
+	}
+	openings, err := database.GetGameOpenings(db, now)
+	if err != nil {
+		fmt.Printf("Error fetching game openings: %v\n", err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the board.")
+		return
+	}
+	systems, err := database.GetGameSystems(db)
+	if err != nil {
+		fmt.Printf("Error fetching game systems: %v\n", err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the board.")
+		return
+	}
+	mine, err := database.GetOpenLFGPostsByUser(db, currentUser.ID)
+	if err != nil {
+		fmt.Printf("Error fetching LFG posts for user %d: %v\n", currentUser.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the board.")
+		return
+	}
+
+	openingsByGame := make(map[int64]*models.GameOpening)
+	var myGames []*models.Game // Games the viewer can recruit for
+	for _, o := range openings {
+		openingsByGame[o.Game.ID] = o
+		if !o.Involved[currentUser.ID] {
+			continue
+		}
+		if err := database.LoadGameStaff(db, o.Game); err != nil {
+			fmt.Printf("Error fetching staff for game %d: %v\n", o.Game.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the board.")
+			return
+		}
+		if o.Game.IsGM(currentUser.ID) {
+			myGames = append(myGames, o.Game)
+		}
+	}
+	var own []*lfgOwnPost
+	for _, p := range mine {
+		op := &lfgOwnPost{Post: p}
+		if p.Kind == models.LFGKindPlayer {
+			op.Matches = lfg.GamesForPlayer(p, openings, now, maxLFGSuggestions)
+		} else if o := openingsByGame[p.GameID]; o != nil {
+			op.Matches = lfg.PlayersForGame(o, playerPosts, now, maxLFGSuggestions)
+		} else {
+			op.Full = true
+		}
+		own = append(own, op)
+	}
+
+	if form == nil {
+		form = map[string]string{"kind": models.LFGKindPlayer}
+	}
+	RenderTemplate(w, "lfg/board.html", map[string]interface{}{
+		"Title":            "Looking for Group",
+		"User":             currentUser,
+		"PlayerPosts":      playerPosts,
+		"GMPosts":          gmPosts,
+		"Openings":         openingsByGame,
+		"MyPosts":          own,
+		"MyGames":          myGames,
+		"Systems":          systems,
+		"ExperienceLevels": models.ExperienceLevels,
+		"PlayModes":        models.PlayModes,
+		"Weekdays":         models.Weekdays,
+		"DayParts":         models.DayParts,
+		"Form":             form,
+		"Error":            errMsg,
+	})
+}
+
+// CreateLFGPost puts a post on the board: POST /lfg. Players ("kind" player) may
+// give a system_id, play_mode, experience_level and any number of availability
+// values of the form "weekday:part", e.g. "6:evening" for Saturday evening. GMs
+// ("kind" gm) give the game_id of an upcoming game they run or co-GM. Both may add a note.
+// Users have at most one open player post, and one open post per game.
+// This handler should be wrapped by AuthMiddleware.
+func CreateLFGPost(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+
+		form := map[string]string{"kind": r.FormValue("kind"), "note": strings.TrimSpace(r.FormValue("note"))}
+		post := &models.LFGPost{UserID: currentUser.ID, Kind: form["kind"], Note: form["note"]}
+		fail := func(msg string) {
+			renderLFGBoard(w, r, db, currentUser, form, msg)
+		}
+		if utf8.RuneCountInString(post.Note) > models.MaxLFGNoteLength {
+			fail(fmt.Sprintf("Notes can be at most %d characters.", models.MaxLFGNoteLength))
+			return
+		}
+
+		mine, err := database.GetOpenLFGPostsByUser(db, currentUser.ID)
+		if err != nil {
+			fmt.Printf("Error fetching LFG posts for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to create the post. Please try again.", http.StatusInternalServerError)
+			return
+		}
+
+		switch post.Kind {
+		case models.LFGKindPlayer:
+			for _, p := range mine {
+				if p.Kind == models.LFGKindPlayer {
+					fail("You already have a post looking for a game. Close it before posting another.")
+					return
+				}
+			}
+			if msg := lfgPlayerFromForm(db, r, post); msg != "" {
+				fail(msg)
+				return
+			}
+		case models.LFGKindGM:
+			gameID, err := strconv.ParseInt(r.FormValue("game_id"), 10, 64)
+			if err != nil {
+				fail("Choose one of your upcoming games.")
+				return
+			}
+			game, err := database.GetGameByID(db, gameID)
+			if err == sql.ErrNoRows || (err == nil && (!game.IsGM(currentUser.ID) || !game.GameDateTime.After(time.Now()) || game.IsCancelled())) {
+				fail("Choose one of your upcoming games.")
+				return
+			} else if err != nil {
+				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, p := range mine {
+				if p.GameID == game.ID {
+					fail("You're already recruiting for that game.")
+					return
+				}
+			}
+			post.GameID = game.ID
+		default:
+			fail("Say whether you're looking for a game or for players.")
+			return
+		}
+
+		if err := database.CreateLFGPost(db, post); err != nil {
+			fmt.Printf("Error creating LFG post for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to create the post. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/lfg", http.StatusSeeOther)
+	}
+}
+
+// lfgPlayerFromForm validates what a player is looking for and sets it on post.
+// It returns a message for the user if a field is invalid.
+func lfgPlayerFromForm(db *sql.DB, r *http.Request, post *models.LFGPost) string {
+	if v := r.FormValue("system_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "Choose a game system from the list."
+		}
+		if _, err := database.GetGameSystemByID(db, id); err != nil {
+			return "Choose a game system from the list."
+		}
+		post.SystemID = id
+	}
+	if v := r.FormValue("play_mode"); v != "" && !models.ValidPlayMode(v) {
+		return "Choose in person or online."
+	}
+	if v := r.FormValue("experience_level"); v != "" && !models.ValidExperienceLevel(v) {
+		return "Choose an experience level from the list."
+	}
+	post.PlayMode, post.ExperienceLevel = r.FormValue("play_mode"), r.FormValue("experience_level")
+
+	for _, v := range r.Form["availability"] {
+		day, partName, _ := strings.Cut(v, ":")
+		weekday, err := strconv.Atoi(day)
+		if err != nil || weekday < 0 || weekday > 6 {
+			return "Pick your availability from the grid."
+		}
+		found := false
+		for _, part := range models.DayParts {
+			if part.Name == partName {
+				post.Availability = append(post.Availability, models.AvailabilityWindow{Weekday: time.Weekday(weekday), Start: part.Start, End: part.End})
+				found = true
+			}
+		}
+		if !found {
+			return "Pick your availability from the grid."
+		}
+	}
+	return ""
+}
+
+// CloseLFGPost takes one of the current user's posts off the board: POST /lfg/{id}/close.
+// This handler should be wrapped by AuthMiddleware.
+func CloseLFGPost(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		postID, err := pathInt64(r, "/lfg/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid post ID format.")
+			return
+		}
+		post, err := database.GetLFGPostByID(db, postID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Post not found.")
+			} else {
+				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		if post.UserID != currentUser.ID {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "You can only close your own posts.")
+			return
+		}
+		if _, err := database.CloseLFGPost(db, post.ID); err != nil {
+			fmt.Printf("Error closing LFG post %d: %v\n", post.ID, err)
+			http.Error(w, "Failed to close the post. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/lfg", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestLookingForGroupBoard(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, _ := ts.newUserClient(t, "lfg_gm@example.com", "gmpass")
+	seekerClient, seeker := ts.newUserClient(t, "lfg_seeker@example.com", "password")
+
+	systems, _ := database.GetGameSystems(ts.db)
+	dnd := ""
+	for _, s := range systems {
+		if s.Name == "D&D 5e" {
+			dnd = strconv.FormatInt(s.ID, 10)
+		}
+	}
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{
+		"title": {"Open Table"}, "game_datetime": {time.Now().Add(48 * time.Hour).Format("2006-01-02T15:04")}, "location": {"Discord"},
+		"system_id": {dnd}, "play_mode": {models.PlayModeOnline}, "max_players": {"3"},
+	})
+	games, _ := database.GetAllGames(ts.db)
+	if len(games) != 1 {
+		t.Fatalf("games = %d, want 1", len(games))
+	}
+	gameID := strconv.FormatInt(games[0].ID, 10)
+
+	// Players say what they're looking for; the grid only offers real days and times.
+	seek := url.Values{"kind": {models.LFGKindPlayer}, "system_id": {dnd}, "play_mode": {models.PlayModeOnline}, "availability": {"8:evening"}, "note": {"Fan of dungeon crawls"}}
+	if _, body := postForm(t, seekerClient, ts.server.URL+"/lfg", seek); !strings.Contains(body, "Pick your availability from the grid") {
+		t.Errorf("invalid availability was not refused: %s", body)
+	}
+	seek.Del("availability")
+	if status, _ := postForm(t, seekerClient, ts.server.URL+"/lfg", seek); status != http.StatusSeeOther {
+		t.Errorf("posting as a player status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if _, body := postForm(t, seekerClient, ts.server.URL+"/lfg", seek); !strings.Contains(body, "already have a post looking for a game") {
+		t.Errorf("second player post was not refused: %s", body)
+	}
+	if _, body := getBody(t, seekerClient, ts.server.URL+"/lfg"); !strings.Contains(body, "Games for you") || !strings.Contains(body, `<a href="/games/`+gameID+`">Open Table</a>`) || !strings.Contains(body, "Plays D&amp;D 5e") {
+		t.Errorf("player's suggestions do not include the open game: %s", body)
+	}
+
+	// Only the game's GMs can recruit for it, and they're offered the player.
+	if _, body := postForm(t, seekerClient, ts.server.URL+"/lfg", url.Values{"kind": {models.LFGKindGM}, "game_id": {gameID}}); !strings.Contains(body, "Choose one of your upcoming games") {
+		t.Errorf("recruiting for someone else's game was not refused: %s", body)
+	}
+	if status, _ := postForm(t, gmClient, ts.server.URL+"/lfg", url.Values{"kind": {models.LFGKindGM}, "game_id": {gameID}}); status != http.StatusSeeOther {
+		t.Errorf("posting as a GM status = %d, want %d", status, http.StatusSeeOther)
+	}
+	_, body := getBody(t, gmClient, ts.server.URL+"/lfg")
+	if !strings.Contains(body, "Players for Open Table") || !strings.Contains(body, seeker.Email+"</a>") || !strings.Contains(body, "3 seats left") {
+		t.Errorf("GM's suggestions do not include the player: %s", body)
+	}
+
+	// Posts are closed by their authors only.
+	posts, _ := database.GetOpenLFGPostsByUser(ts.db, seeker.ID)
+	if len(posts) != 1 {
+		t.Fatalf("seeker's open posts = %d, want 1", len(posts))
+	}
+	closeURL := ts.server.URL + "/lfg/" + strconv.FormatInt(posts[0].ID, 10) + "/close"
+	if status, _ := postForm(t, gmClient, closeURL, nil); status != http.StatusForbidden {
+		t.Errorf("closing someone else's post status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status, _ := postForm(t, seekerClient, closeURL, nil); status != http.StatusSeeOther {
+		t.Errorf("closing own post status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if _, body := getBody(t, gmClient, ts.server.URL+"/lfg"); !strings.Contains(body, "No players looking for a group fit this game yet") {
+		t.Errorf("closed post is still suggested: %s", body)
+	}
+}
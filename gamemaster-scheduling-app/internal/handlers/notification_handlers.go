@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+)
+
+// notificationsPageSize is how many recent notifications the notifications page lists.
+const notificationsPageSize = 100
+
+// NotificationsPage lists the current user's recent notifications: GET /notifications.
+// This handler should be wrapped by AuthMiddleware.
+func NotificationsPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		notifications, err := database.GetNotificationsForUser(db, currentUser.ID, notificationsPageSize)
+		if err != nil {
+			fmt.Printf("Error fetching notifications for user %d: %v\n", currentUser.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load notifications.")
+			return
+		}
+		data := map[string]interface{}{
+			"Title":         "Notifications",
+			"User":          currentUser,
+			"Notifications": notifications,
+		}
+		RenderTemplate(w, "notifications/notifications.html", data)
+	}
+}
+
+// MarkNotificationsRead marks all of the current user's notifications as read:
+// POST /notifications/read. This handler should be wrapped by AuthMiddleware.
+func MarkNotificationsRead(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err := database.MarkNotificationsRead(db, currentUser.ID); err != nil {
+			fmt.Printf("Error marking notifications read for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to update notifications. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/notifications", http.StatusSeeOther)
+	}
+}
+
+// NotificationCount renders the unread count badge shown in the navigation bar:
+// GET /notifications/count. The layout loads it with htmx, so pages don't each
+// need to look the count up.
+func NotificationCount(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count := 0
+		if currentUser, err := GetCurrentUser(r, db); err == nil {
+			count, err = database.CountUnreadNotifications(db, currentUser.ID)
+			if err != nil {
+				fmt.Printf("Error counting notifications for user %d: %v\n", currentUser.ID, err)
+			}
+		}
+		RenderTemplate(w, "notifications/_notification_count.html", map[string]interface{}{"Count": count})
+	}
+}
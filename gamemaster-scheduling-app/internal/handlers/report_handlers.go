@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// reportTarget looks up what a report is about and fills in the report's game and
+// reported user. It returns a description of the target for the report form.
+func reportTarget(db *sql.DB, report *models.Report) (string, error) {
+	switch report.TargetType {
+	case models.ReportTargetGame:
+		game, err := database.GetGameByID(db, report.TargetID)
+		if err != nil {
+			return "", err
+		}
+		report.GameID, report.ReportedUserID = game.ID, game.GMID
+		return fmt.Sprintf("the game %q", game.Title), nil
+	case models.ReportTargetChatMessage:
+		message, err := database.GetChatMessageByID(db, report.TargetID)
+		if err != nil {
+			return "", err
+		}
+		report.GameID, report.ReportedUserID = message.GameID, message.UserID
+		return "a chat message by " + message.UserEmail, nil
+	case models.ReportTargetUser:
+		user, err := database.GetUserByID(db, report.TargetID)
+		if err != nil {
+			return "", err
+		}
+		report.ReportedUserID = user.ID
+		return "the user " + user.DisplayName(), nil
+	default:
+		return "", sql.ErrNoRows
+	}
+}
+
+// renderReportForm renders the report form for report's target, with an optional
+// error or the confirmation once the report is filed.
+func renderReportForm(w http.ResponseWriter, currentUser *models.User, report *models.Report, target string, errMsg string, submitted bool) {
+	RenderTemplate(w, "reports/new.html", map[string]interface{}{
+		"Title":     "Report",
+		"User":      currentUser,
+		"Report":    report,
+		"Target":    target,
+		"Reasons":   models.ReportReasons,
+		"Error":     errMsg,
+		"Submitted": submitted,
+	})
+}
+
+// reportFromRequest reads the report target from the form or query string and loads it.
+func reportFromRequest(w http.ResponseWriter, r *http.Request, db *sql.DB, currentUser *models.User) (*models.Report, string, bool) {
+	targetID, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid report target.")
+		return nil, "", false
+	}
+	report := &models.Report{ReporterID: currentUser.ID, TargetType: r.FormValue("type"), TargetID: targetID}
+	target, err := reportTarget(db, report)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error loading %s %d to report: %v\n", report.TargetType, targetID, err)
+		}
+		RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "There is nothing to report there.")
+		return nil, "", false
+	}
+	if report.ReportedUserID == currentUser.ID {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "You can't report yourself or your own content.")
+		return nil, "", false
+	}
+	return report, target, true
+}
+
+// ReportPage shows the form for reporting a game, chat message or user:
+// GET /reports/new?type={game|chat_message|user}&id={id}. This handler should be wrapped by AuthMiddleware.
+func ReportPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		report, target, ok := reportFromRequest(w, r, db, currentUser)
+		if !ok {
+			return
+		}
+		renderReportForm(w, currentUser, report, target, "", false)
+	}
+}
+
+// SubmitReport files a report: POST /reports with type, id, reason and an optional note.
+// Reports about chat messages are also sent to the game's GMs.
+// This handler should be wrapped by AuthMiddleware.
+func SubmitReport(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Error parsing form data.")
+			return
+		}
+		report, target, ok := reportFromRequest(w, r, db, currentUser)
+		if !ok {
+			return
+		}
+		report.Reason = r.FormValue("reason")
+		report.Note = strings.TrimSpace(r.FormValue("note"))
+		if !models.ValidReportReason(report.Reason) {
+			renderReportForm(w, currentUser, report, target, "Choose a reason for your report.", false)
+			return
+		}
+		if utf8.RuneCountInString(report.Note) > models.MaxReportNoteLength {
+			renderReportForm(w, currentUser, report, target, fmt.Sprintf("Notes can be at most %d characters.", models.MaxReportNoteLength), false)
+			return
+		}
+
+		err = database.CreateReport(db, report)
+		if err == database.ErrAlreadyReported {
+			renderReportForm(w, currentUser, report, target, "You have already reported this. The moderators will look at it.", false)
+			return
+		} else if err != nil {
+			fmt.Printf("Error filing report on %s %d: %v\n", report.TargetType, report.TargetID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not file your report. Please try again.")
+			return
+		}
+		if report.TargetType == models.ReportTargetChatMessage {
+			notifyGMsOfReport(db, report)
+		}
+		renderReportForm(w, currentUser, report, target, "", true)
+	}
+}
+
+// notifyGMsOfReport tells the GMs of a game that one of its chat messages was reported.
+func notifyGMsOfReport(db *sql.DB, report *models.Report) {
+	game, err := database.GetGameByID(db, report.GameID)
+	if err != nil {
+		fmt.Printf("Error loading game %d to notify of report %d: %v\n", report.GameID, report.ID, err)
+		return
+	}
+	for _, gmID := range game.GMIDs() {
+		if gmID == report.ReportedUserID {
+			continue // A GM reported for their own message finds out from the admins
+		}
+		_, err := database.CreateNotification(db, &models.Notification{
+			UserID:  gmID,
+			Kind:    models.NotificationKindReport,
+			Message: fmt.Sprintf("A chat message in %s was reported for %s", game.Title, report.Reason),
+			Link:    fmt.Sprintf("/games/%d/reports", game.ID),
+		})
+		if err != nil {
+			fmt.Printf("Error notifying GM %d of report %d: %v\n", gmID, report.ID, err)
+		}
+	}
+}
+
+// reportStatusFilter reads the queue's ?status= filter: open reports by default, or
+// every report for "all".
+func reportStatusFilter(r *http.Request) string {
+	switch status := r.URL.Query().Get("status"); status {
+	case "all":
+		return ""
+	case models.ReportStatusResolved, models.ReportStatusDismissed:
+		return status
+	default:
+		return models.ReportStatusOpen
+	}
+}
+
+// GameReportsPage is the GMs' queue of reported chat messages in their game:
+// GET /games/{id}/reports[?status=all]. This handler should be wrapped by AuthMiddleware.
+func GameReportsPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "review reports")
+		if !ok {
+			return
+		}
+		status := reportStatusFilter(r)
+		reports, err := database.GetChatReportsForGame(db, game.ID, status)
+		if err != nil {
+			fmt.Printf("Error fetching reports for game %d: %v\n", game.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load reports.")
+			return
+		}
+		RenderTemplate(w, "games/reports.html", map[string]interface{}{
+			"Title":      "Reports for " + game.Title,
+			"User":       currentUser,
+			"Game":       game,
+			"Reports":    reports,
+			"Status":     status,
+			"ActionBase": fmt.Sprintf("/games/%d/reports", game.ID),
+		})
+	}
+}
+
+// CloseGameReport resolves or dismisses a report about a chat message in the GM's game:
+// POST /games/{id}/reports/{reportID}/{resolve|dismiss}. This handler should be wrapped by AuthMiddleware.
+func CloseGameReport(db *sql.DB, status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "review reports")
+		if !ok {
+			return
+		}
+		reportID, err := pathInt64(r, "/games/", 2)
+		if err != nil {
+			http.Error(w, "Invalid report ID format", http.StatusBadRequest)
+			return
+		}
+		report, err := database.GetReportByID(db, reportID)
+		if err != nil || report.GameID != game.ID || report.TargetType != models.ReportTargetChatMessage {
+			http.Error(w, "Report not found", http.StatusNotFound)
+			return
+		}
+		err = database.CloseReport(db, reportID, currentUser.ID, status)
+		if err != nil && err != sql.ErrNoRows { // ErrNoRows: already closed, nothing to do
+			fmt.Printf("Error closing report %d: %v\n", reportID, err)
+			http.Error(w, "Failed to update the report. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/games/%d/reports", game.ID), http.StatusSeeOther)
+	}
+}
+
+// AdminReportsPage is the site-wide moderation queue: GET /admin/reports[?status=all].
+// This handler should be wrapped by AdminMiddleware.
+func AdminReportsPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := reportStatusFilter(r)
+		reports, err := database.GetReports(db, status, adminPageSize)
+		if err != nil {
+			fmt.Printf("Error fetching reports: %v\n", err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load reports.")
+			return
+		}
+		renderAdminPage(w, r, db, "admin/reports.html", "Reports", map[string]interface{}{
+			"Reports":    reports,
+			"Status":     status,
+			"ActionBase": "/admin/reports",
+		})
+	}
+}
+
+// AdminCloseReport resolves or dismisses any report: POST /admin/reports/{id}/{resolve|dismiss}.
+// This handler should be wrapped by AdminMiddleware.
+func AdminCloseReport(db *sql.DB, status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, reportID, ok := adminTarget(w, r, db, "/admin/reports/")
+		if !ok {
+			return
+		}
+		err := database.AdminCloseReport(db, admin.ID, reportID, status)
+		if err != nil && err != sql.ErrNoRows { // ErrNoRows: already closed, nothing to do
+			fmt.Printf("Error closing report %d: %v\n", reportID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not update the report.")
+			return
+		}
+		http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+	}
+}
+
+// SetUserBlock blocks or unblocks a user for the current user, hiding or showing
+// their chat messages: POST /users/{id}/block or /users/{id}/unblock.
+// This handler should be wrapped by AuthMiddleware.
+func SetUserBlock(db *sql.DB, block bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		userID, err := pathInt64(r, "/users/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid user ID format.")
+			return
+		}
+		if userID == currentUser.ID {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "You can't block yourself.")
+			return
+		}
+		if _, err := database.GetUserByID(db, userID); err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "User not found.")
+			return
+		}
+		if block {
+			err = database.BlockUser(db, currentUser.ID, userID)
+		} else {
+			err = database.UnblockUser(db, currentUser.ID, userID)
+		}
+		if err != nil {
+			fmt.Printf("Error updating block of user %d by user %d: %v\n", userID, currentUser.ID, err)
+			http.Error(w, "Failed to update the block. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", userID), http.StatusSeeOther)
+	}
+}
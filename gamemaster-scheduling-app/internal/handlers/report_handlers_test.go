@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestReportsAndBlocking(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "report_gm@example.com", "gmpass")
+	trollClient, troll := ts.newUserClient(t, "report_troll@example.com", "password")
+	playerClient, player := ts.newUserClient(t, "report_player@example.com", "password")
+	adminClient, admin := ts.newUserClient(t, "report_admin@example.com", "password")
+	database.GrantAdminRole(ts.db, []string{admin.Email})
+
+	game := ts.createTestGameDirectly(t, gm.ID, "Flagged Table")
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+	postForm(t, trollClient, gameURL+"/chat", url.Values{"message_content": {"Nobody wants you here"}})
+	messages, _ := database.SearchChatMessages(ts.db, "Nobody wants you here", 10)
+	if len(messages) != 1 {
+		t.Fatalf("chat search found %d messages, want 1", len(messages))
+	}
+	messageID := strconv.FormatInt(messages[0].ID, 10)
+
+	// Reporting a chat message.
+	_, body := getBody(t, playerClient, gameURL)
+	if !strings.Contains(body, "/reports/new?type=chat_message&id="+messageID) || !strings.Contains(body, "Report this game") {
+		t.Errorf("game page missing report links")
+	}
+	status, body := getBody(t, playerClient, ts.server.URL+"/reports/new?type=chat_message&id="+messageID)
+	if status != http.StatusOK || !strings.Contains(body, "a chat message by report_troll@example.com") {
+		t.Errorf("report form status = %d, body: %s", status, body)
+	}
+	if status, _ := getBody(t, trollClient, ts.server.URL+"/reports/new?type=chat_message&id="+messageID); status != http.StatusBadRequest {
+		t.Errorf("reporting your own message status = %d, want %d", status, http.StatusBadRequest)
+	}
+	report := url.Values{"type": {"chat_message"}, "id": {messageID}, "reason": {"harassment"}, "note": {"Aimed at a new player"}}
+	_, body = postForm(t, playerClient, ts.server.URL+"/reports", url.Values{"type": {"chat_message"}, "id": {messageID}, "reason": {"boredom"}})
+	if !strings.Contains(body, "Choose a reason") {
+		t.Errorf("invalid reason was not refused: %s", body)
+	}
+	_, body = postForm(t, playerClient, ts.server.URL+"/reports", report)
+	if !strings.Contains(body, "Thanks for your report") {
+		t.Errorf("report was not filed: %s", body)
+	}
+	_, body = postForm(t, playerClient, ts.server.URL+"/reports", report)
+	if !strings.Contains(body, "You have already reported this") {
+		t.Errorf("duplicate report was not refused: %s", body)
+	}
+	if notes, _ := database.GetNotificationsForUser(ts.db, gm.ID, 10); len(notes) != 1 || notes[0].Kind != models.NotificationKindReport {
+		t.Errorf("GM notifications = %v, want one report", notes)
+	}
+
+	// The GM's queue shows chat reports for their game only; players can't see it.
+	postForm(t, playerClient, ts.server.URL+"/reports", url.Values{"type": {"game"}, "id": {strconv.FormatInt(game.ID, 10)}, "reason": {"scam"}})
+	if status, _ := getBody(t, playerClient, gameURL+"/reports"); status != http.StatusForbidden {
+		t.Errorf("player opening the GM queue status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body = getBody(t, gmClient, gameURL+"/reports")
+	if !strings.Contains(body, "Nobody wants you here") || !strings.Contains(body, "Aimed at a new player") || strings.Contains(body, "Scam") {
+		t.Errorf("GM queue = %s", body)
+	}
+	reports, _ := database.GetChatReportsForGame(ts.db, game.ID, models.ReportStatusOpen)
+	if len(reports) != 1 {
+		t.Fatalf("open chat reports = %d, want 1", len(reports))
+	}
+	if status, _ := postForm(t, gmClient, gameURL+"/reports/"+strconv.FormatInt(reports[0].ID, 10)+"/resolve", nil); status != http.StatusSeeOther {
+		t.Errorf("resolve status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if r, _ := database.GetReportByID(ts.db, reports[0].ID); r.Status != models.ReportStatusResolved || r.ResolvedBy != gm.ID {
+		t.Errorf("report after resolving = %+v", r)
+	}
+
+	// Admins see every report and their decisions are audited.
+	_, body = getBody(t, adminClient, ts.server.URL+"/admin")
+	if !strings.Contains(body, "1 open report(s)") {
+		t.Errorf("admin dashboard missing the open report count")
+	}
+	open, _ := database.GetReports(ts.db, models.ReportStatusOpen, 10)
+	if len(open) != 1 || open[0].TargetType != models.ReportTargetGame {
+		t.Fatalf("open reports = %v, want the game report", open)
+	}
+	postForm(t, adminClient, ts.server.URL+"/admin/reports/"+strconv.FormatInt(open[0].ID, 10)+"/dismiss", nil)
+	if entries, _ := database.GetAuditLog(ts.db, 10); len(entries) != 1 || entries[0].Action != models.AuditDismissReport {
+		t.Errorf("audit log = %v, want the dismissal", entries)
+	}
+
+	// Blocking hides the troll's messages from the player only.
+	trollURL := ts.server.URL + "/users/" + strconv.FormatInt(troll.ID, 10)
+	if status, _ := postForm(t, playerClient, trollURL+"/block", nil); status != http.StatusSeeOther {
+		t.Errorf("block status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if _, body := getBody(t, playerClient, trollURL); !strings.Contains(body, "You have blocked this user") {
+		t.Errorf("profile does not show the block")
+	}
+	if _, body := getBody(t, playerClient, gameURL); strings.Contains(body, "Nobody wants you here") {
+		t.Errorf("blocked user's message still shown to the blocker")
+	}
+	if _, body := getBody(t, gmClient, gameURL); !strings.Contains(body, "Nobody wants you here") {
+		t.Errorf("blocked user's message hidden from others")
+	}
+	postForm(t, trollClient, gameURL+"/chat", url.Values{"message_content": {"Still here"}})
+	if _, body := getBody(t, playerClient, gameURL+"/chat?after="+messageID); strings.Contains(body, "Still here") {
+		t.Errorf("polling shows the blocked user's new message")
+	}
+	postForm(t, playerClient, trollURL+"/unblock", nil)
+	if _, body := getBody(t, playerClient, gameURL); !strings.Contains(body, "Nobody wants you here") {
+		t.Errorf("message still hidden after unblocking")
+	}
+	if status, _ := postForm(t, playerClient, ts.server.URL+"/users/"+strconv.FormatInt(player.ID, 10)+"/block", nil); status != http.StatusBadRequest {
+		t.Errorf("blocking yourself status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,767 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+	"github.com/gamemaster-scheduling/app/internal/storage"
+)
+
+// NewRouter returns the application's route table. The server adds static
+// files on top of it; handler tests serve it as is.
+func NewRouter(db *sql.DB, store storage.Store) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Root Handler
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/games", http.StatusSeeOther)
+		} else {
+			// Pass db to RenderErrorPage
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Page Not Found", "The page you are looking for does not exist.")
+		}
+	})
+
+	// Authentication Routes
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			RegisterPage(w, r)
+		case http.MethodPost:
+			Register(db)(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "This method is not supported for /register.")
+		}
+	})
+
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			LoginPage(w, r)
+		case http.MethodPost:
+			// Login handler uses the global SessionStore
+			Login(db)(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "This method is not supported for /login.")
+		}
+	})
+
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			// Logout handler uses the global SessionStore
+			Logout(w, r)
+		} else {
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Logout requires POST method.")
+		}
+	})
+
+	// Game Routes
+	mux.HandleFunc("/games", GamesListPage(db)) // Handles only "/games", not "/games/"
+	mux.HandleFunc("/calendar", CalendarPage(db))
+
+	mux.HandleFunc("/games/new", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			AuthMiddleware(db, CreateGamePage(db))(w, r)
+		case http.MethodPost:
+			AuthMiddleware(db, CreateGame(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "This method is not supported for /games/new.")
+		}
+	})
+
+	// Dynamic Game Path Router
+	// This needs to be specific enough not to overlap with /games or /games/new if they were also handled by it.
+	// Since /games and /games/new are handled above, this will catch /games/{id} and /games/{id}/action
+	mux.HandleFunc("/games/", routeDynamicGamePaths(db, store))
+
+	// Campaign Routes
+	mux.HandleFunc("/campaigns/", routeDynamicCampaignPaths(db))
+
+	// User Profile Routes
+	mux.HandleFunc("/users/", routeDynamicUserPaths(db))
+
+	// Character Roster Routes
+	mux.HandleFunc("/characters", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			AuthMiddleware(db, CharactersPage(db))(w, r)
+		case http.MethodPost:
+			AuthMiddleware(db, CreateCharacter(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for /characters.")
+		}
+	})
+	mux.HandleFunc("/characters/", routeDynamicCharacterPaths(db))
+
+	// Lines and Veils Routes
+	mux.HandleFunc("/safety", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			AuthMiddleware(db, SafetyPage(db))(w, r)
+		case http.MethodPost:
+			AuthMiddleware(db, UpdateSafetyLimits(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for /safety.")
+		}
+	})
+
+	// Looking for Group Routes
+	mux.HandleFunc("/lfg", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			AuthMiddleware(db, LFGBoardPage(db))(w, r)
+		case http.MethodPost:
+			AuthMiddleware(db, CreateLFGPost(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for /lfg.")
+		}
+	})
+	mux.HandleFunc("/lfg/", routeDynamicLFGPaths(db))
+
+	// Availability Routes
+	mux.HandleFunc("/availability", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			AuthMiddleware(db, AvailabilityPage(db))(w, r)
+		} else {
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET is allowed for /availability.")
+		}
+	})
+	mux.HandleFunc("/availability/", routeDynamicAvailabilityPaths(db))
+
+	// Event Routes
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			EventsPage(db)(w, r)
+		case http.MethodPost:
+			AuthMiddleware(db, CreateEvent(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for /events.")
+		}
+	})
+	mux.HandleFunc("/events/", routeDynamicEventPaths(db))
+
+	// Venue Routes
+	mux.HandleFunc("/venues", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			VenuesPage(db)(w, r)
+		case http.MethodPost:
+			AuthMiddleware(db, CreateVenue(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for /venues.")
+		}
+	})
+	mux.HandleFunc("/venues/", routeDynamicVenuePaths(db))
+	mux.HandleFunc("/virtual-tables", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			AuthMiddleware(db, VirtualTablesPage(db))(w, r)
+		case http.MethodPost:
+			AuthMiddleware(db, CreateVirtualTable(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for /virtual-tables.")
+		}
+	})
+	mux.HandleFunc("/virtual-tables/", routeDynamicVirtualTablePaths(db))
+
+	// Notification Routes
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			AuthMiddleware(db, NotificationsPage(db))(w, r)
+		} else {
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET is allowed for /notifications.")
+		}
+	})
+	mux.HandleFunc("/notifications/read", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			AuthMiddleware(db, MarkNotificationsRead(db))(w, r)
+		} else {
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for /notifications/read.")
+		}
+	})
+	mux.HandleFunc("/notifications/count", NotificationCount(db)) // Empty badge when logged out
+
+	// Report Routes
+	mux.HandleFunc("/reports/new", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			AuthMiddleware(db, ReportPage(db))(w, r)
+		} else {
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET is allowed for /reports/new.")
+		}
+	})
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			AuthMiddleware(db, SubmitReport(db))(w, r)
+		} else {
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for /reports.")
+		}
+	})
+
+	// Admin Routes
+	mux.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			AdminMiddleware(db, AdminDashboard(db))(w, r)
+		} else {
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET is allowed for /admin.")
+		}
+	})
+	mux.HandleFunc("/admin/", routeDynamicAdminPaths(db))
+
+	return mux
+}
+
+func routeDynamicGamePaths(db *sql.DB, store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		parts := strings.Split(strings.TrimPrefix(path, "/games/"), "/")
+		// Expected parts:
+		// /games/{id} -> ["{id}"] -> len 1
+		// /games/{id}/rsvp -> ["{id}", "rsvp"] -> len 2
+		// /games/{id}/attendance -> ["{id}", "attendance"] -> len 2
+		// /games/{id}/chat -> ["{id}", "chat"] -> len 2
+		// /games/{id}/chat/mute -> ["{id}", "chat", "mute"] -> len 3
+		// /games/{id}/attachments -> ["{id}", "attachments"] -> len 2
+		// /games/{id}/attachments/{attachmentID} -> ["{id}", "attachments", "{attachmentID}"] -> len 3
+		// /games/{id}/attachments/{attachmentID}/thumbnail -> ["{id}", "attachments", "{attachmentID}", "thumbnail"] -> len 4
+		// /games/{id}/notes/{kind} -> ["{id}", "notes", "{kind}"] -> len 3
+		// /games/{id}/notes/{kind}/history -> ["{id}", "notes", "{kind}", "history"] -> len 4
+		// /games/{id}/chat/{messageID}/edit -> ["{id}", "chat", "{messageID}", "edit"] -> len 4
+		// /games/{id}/rsvps/reopen -> ["{id}", "rsvps", "reopen"] -> len 3
+		// /games/{id}/rsvps/history -> ["{id}", "rsvps", "history"] -> len 3
+		// /games/{id}/rsvps/{rsvpID}/approve -> ["{id}", "rsvps", "{rsvpID}", "approve"] -> len 4
+		// /games/{id}/staff -> ["{id}", "staff"] -> len 2
+		// /games/{id}/staff/{userID}/remove -> ["{id}", "staff", "{userID}", "remove"] -> len 4
+		// /games/{id}/transfer -> ["{id}", "transfer"] -> len 2
+		// /games/{id}/expenses -> ["{id}", "expenses"] -> len 2
+		// /games/{id}/feedback -> ["{id}", "feedback"] -> len 2
+		// /games/{id}/transfer/accept -> ["{id}", "transfer", "accept"] -> len 3
+
+		if len(parts) == 0 || parts[0] == "" {
+			// This case might occur if path is just "/games/" with trailing slash and no ID
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Game ID missing or invalid path.")
+			return
+		}
+
+		gameIDStr := parts[0]
+		_, err := strconv.ParseInt(gameIDStr, 10, 64) // Validate gameID format
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid Game ID format.")
+			return
+		}
+
+		// Route based on number of parts and the action part
+		if len(parts) == 1 { // Path is /games/{id}
+			if r.Method == http.MethodGet {
+				GameDetailPage(db)(w, r)
+			} else {
+				RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET is allowed for game details.")
+			}
+		} else if len(parts) == 2 { // Path is /games/{id}/action
+			action := parts[1]
+			switch action {
+			case "rsvp":
+				if r.Method == http.MethodPost {
+					AuthMiddleware(db, SubmitRSVP(db))(w, r)
+				} else {
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for RSVP.")
+				}
+			case "attendance":
+				if r.Method == http.MethodPost {
+					AuthMiddleware(db, SubmitAttendance(db))(w, r)
+				} else {
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for attendance.")
+				}
+			case "attachments":
+				if r.Method == http.MethodPost {
+					AuthMiddleware(db, UploadAttachment(db, store))(w, r)
+				} else {
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for uploading attachments.")
+				}
+			case "staff":
+				if r.Method == http.MethodPost {
+					AuthMiddleware(db, AddCoGM(db))(w, r)
+				} else {
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for adding co-GMs.")
+				}
+			case "expenses":
+				switch r.Method {
+				case http.MethodGet:
+					AuthMiddleware(db, SessionExpensesPage(db))(w, r)
+				case http.MethodPost:
+					AuthMiddleware(db, AddSessionExpense(db))(w, r)
+				default:
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for expenses.")
+				}
+			case "feedback":
+				switch r.Method {
+				case http.MethodGet:
+					AuthMiddleware(db, GameFeedbackPage(db))(w, r)
+				case http.MethodPost:
+					AuthMiddleware(db, SubmitGameFeedback(db))(w, r)
+				default:
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for feedback.")
+				}
+			case "transfer":
+				if r.Method == http.MethodPost {
+					AuthMiddleware(db, OfferGameTransfer(db))(w, r)
+				} else {
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for transferring games.")
+				}
+			case "reports":
+				if r.Method == http.MethodGet {
+					AuthMiddleware(db, GameReportsPage(db))(w, r)
+				} else {
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET is allowed for reports.")
+				}
+			case "chat":
+				switch r.Method {
+				case http.MethodGet: // Older pages and polling for new messages
+					ChatMessages(db)(w, r)
+				case http.MethodPost:
+					AuthMiddleware(db, PostChatMessage(db))(w, r)
+				default:
+					RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET and POST are allowed for chat.")
+				}
+			default:
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid action for game.")
+			}
+		} else if len(parts) == 3 && parts[1] == "chat" { // Path is /games/{id}/chat/{mute|unmute}
+			if r.Method != http.MethodPost {
+				RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for chat moderation.")
+				return
+			}
+			switch parts[2] {
+			case "mute":
+				AuthMiddleware(db, SetChatMute(db, true))(w, r)
+			case "unmute":
+				AuthMiddleware(db, SetChatMute(db, false))(w, r)
+			default:
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid chat action.")
+			}
+		} else if (len(parts) == 3 || len(parts) == 4) && parts[1] == "attachments" { // Path is /games/{id}/attachments/{attachmentID}[/action]
+			if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+				RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid attachment ID format.")
+				return
+			}
+			action := ""
+			if len(parts) == 4 {
+				action = parts[3]
+			}
+			switch {
+			case action == "" && r.Method == http.MethodGet:
+				ServeAttachment(db, store, false)(w, r)
+			case action == "thumbnail" && r.Method == http.MethodGet:
+				ServeAttachment(db, store, true)(w, r)
+			case action == "reveal" && r.Method == http.MethodPost:
+				AuthMiddleware(db, UpdateAttachmentReveal(db))(w, r)
+			case action == "delete" && r.Method == http.MethodPost:
+				AuthMiddleware(db, DeleteAttachment(db, store))(w, r)
+			default:
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid attachment action.")
+			}
+		} else if len(parts) == 3 && parts[1] == "notes" { // Path is /games/{id}/notes/{kind}
+			if r.Method == http.MethodPost {
+				AuthMiddleware(db, SaveSessionNote(db))(w, r)
+			} else {
+				RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for session notes.")
+			}
+		} else if len(parts) == 4 && parts[1] == "notes" && parts[3] == "history" { // Path is /games/{id}/notes/{kind}/history
+			if r.Method == http.MethodGet {
+				AuthMiddleware(db, SessionNoteHistory(db))(w, r)
+			} else {
+				RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET is allowed for note history.")
+			}
+		} else if len(parts) == 3 && parts[1] == "rsvps" && parts[2] == "history" { // Path is /games/{id}/rsvps/history
+			if r.Method == http.MethodGet {
+				AuthMiddleware(db, RSVPHistory(db))(w, r)
+			} else {
+				RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only GET is allowed for RSVP history.")
+			}
+		} else if len(parts) == 3 && parts[1] == "rsvps" { // Path is /games/{id}/rsvps/{reopen|lock}
+			if r.Method != http.MethodPost {
+				RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for RSVP settings.")
+				return
+			}
+			switch parts[2] {
+			case "reopen":
+				AuthMiddleware(db, SetRSVPsReopened(db, true))(w, r)
+			case "lock":
+				AuthMiddleware(db, SetRSVPsReopened(db, false))(w, r)
+			default:
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid RSVP action.")
+			}
+		} else if len(parts) == 4 && parts[1] == "rsvps" { // Path is /games/{id}/rsvps/{rsvpID}/{approve|decline}
+			if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+				RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid RSVP ID format.")
+				return
+			}
+			switch {
+			case parts[3] == "approve" && r.Method == http.MethodPost:
+				AuthMiddleware(db, ReviewRSVP(db, true))(w, r)
+			case parts[3] == "decline" && r.Method == http.MethodPost:
+				AuthMiddleware(db, ReviewRSVP(db, false))(w, r)
+			default:
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid RSVP action.")
+			}
+		} else if len(parts) == 3 && parts[1] == "transfer" { // Path is /games/{id}/transfer/{accept|cancel}
+			if r.Method != http.MethodPost {
+				RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for transferring games.")
+				return
+			}
+			switch parts[2] {
+			case "accept":
+				AuthMiddleware(db, AcceptGameTransfer(db))(w, r)
+			case "cancel":
+				AuthMiddleware(db, CancelGameTransfer(db))(w, r)
+			default:
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid transfer action.")
+			}
+		} else if len(parts) == 4 && parts[1] == "staff" && parts[3] == "remove" { // Path is /games/{id}/staff/{userID}/remove
+			if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+				RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid user ID format.")
+				return
+			}
+			if r.Method == http.MethodPost {
+				AuthMiddleware(db, RemoveCoGM(db))(w, r)
+			} else {
+				RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for removing co-GMs.")
+			}
+		} else if len(parts) == 4 && parts[1] == "reports" { // Path is /games/{id}/reports/{reportID}/{resolve|dismiss}
+			if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+				RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid report ID format.")
+				return
+			}
+			switch {
+			case parts[3] == "resolve" && r.Method == http.MethodPost:
+				AuthMiddleware(db, CloseGameReport(db, models.ReportStatusResolved))(w, r)
+			case parts[3] == "dismiss" && r.Method == http.MethodPost:
+				AuthMiddleware(db, CloseGameReport(db, models.ReportStatusDismissed))(w, r)
+			default:
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid report action.")
+			}
+		} else if len(parts) == 4 && parts[1] == "chat" { // Path is /games/{id}/chat/{messageID}/action
+			if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+				RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid message ID format.")
+				return
+			}
+			switch {
+			case parts[3] == "history" && r.Method == http.MethodGet:
+				ChatMessageHistory(db)(w, r)
+			case parts[3] == "edit" && r.Method == http.MethodPost:
+				AuthMiddleware(db, EditChatMessage(db))(w, r)
+			case parts[3] == "delete" && r.Method == http.MethodPost:
+				AuthMiddleware(db, DeleteChatMessage(db))(w, r)
+			default:
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid chat message action.")
+			}
+		} else {
+			// Path is too long or malformed, e.g., /games/{id}/action/extra
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid game path structure.")
+		}
+	}
+}
+
+func routeDynamicUserPaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/users/"), "/")
+		// Expected parts:
+		// /users/{id} -> ["{id}"] -> len 1
+		// /users/{id}/username -> ["{id}", "username"] -> len 2
+		// /users/{id}/block -> ["{id}", "block"] -> len 2
+		if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "User ID missing or invalid.")
+			return
+		}
+
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			UserProfilePage(db)(w, r)
+		case len(parts) == 2 && parts[1] == "username" && r.Method == http.MethodPost:
+			AuthMiddleware(db, UpdateUsername(db))(w, r)
+		case len(parts) == 2 && parts[1] == "systems" && r.Method == http.MethodPost:
+			AuthMiddleware(db, UpdatePreferredSystems(db))(w, r)
+		case len(parts) == 2 && parts[1] == "block" && r.Method == http.MethodPost:
+			AuthMiddleware(db, SetUserBlock(db, true))(w, r)
+		case len(parts) == 2 && parts[1] == "unblock" && r.Method == http.MethodPost:
+			AuthMiddleware(db, SetUserBlock(db, false))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid user path.")
+		}
+	}
+}
+
+func routeDynamicCharacterPaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/characters/"), "/")
+		// Expected parts:
+		// /characters/{id} -> ["{id}"] -> len 1
+		// /characters/{id}/delete -> ["{id}", "delete"] -> len 2
+		if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Character ID missing or invalid.")
+			return
+		}
+
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			AuthMiddleware(db, EditCharacterPage(db))(w, r)
+		case len(parts) == 1 && r.Method == http.MethodPost:
+			AuthMiddleware(db, UpdateCharacter(db))(w, r)
+		case len(parts) == 2 && parts[1] == "delete" && r.Method == http.MethodPost:
+			AuthMiddleware(db, DeleteCharacter(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid character path.")
+		}
+	}
+}
+
+func routeDynamicAvailabilityPaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/availability/"), "/")
+		// Expected parts:
+		// /availability/timezone -> ["timezone"] -> len 1
+		// /availability/weekly -> ["weekly"] -> len 1
+		// /availability/exceptions/{id}/delete -> ["exceptions", "{id}", "delete"] -> len 3
+		if r.Method != http.MethodPost {
+			RenderErrorPage(w, r, db, http.StatusMethodNotAllowed, "Method Not Allowed", "Only POST is allowed for availability changes.")
+			return
+		}
+
+		switch {
+		case len(parts) == 1 && parts[0] == "timezone":
+			AuthMiddleware(db, UpdateTimezone(db))(w, r)
+		case len(parts) == 1 && parts[0] == "weekly":
+			AuthMiddleware(db, AddWeeklyAvailability(db))(w, r)
+		case len(parts) == 1 && parts[0] == "exceptions":
+			AuthMiddleware(db, AddAvailabilityException(db))(w, r)
+		case len(parts) == 3 && parts[0] == "weekly" && parts[2] == "delete":
+			AuthMiddleware(db, DeleteAvailability(db, false))(w, r)
+		case len(parts) == 3 && parts[0] == "exceptions" && parts[2] == "delete":
+			AuthMiddleware(db, DeleteAvailability(db, true))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid availability path.")
+		}
+	}
+}
+
+func routeDynamicEventPaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/events/"), "/")
+		// Expected parts:
+		// /events/{id} -> ["{id}"] -> len 1
+		// /events/{id}/register -> ["{id}", "register"] -> len 2
+		// /events/{id}/venues -> ["{id}", "venues"] -> len 2
+		// /events/{id}/slots -> ["{id}", "slots"] -> len 2
+		// /events/{id}/tables -> ["{id}", "tables"] -> len 2
+		// /events/{id}/overview -> ["{id}", "overview"] -> len 2
+		// /events/{id}/slots/{slotID}/choices -> ["{id}", "slots", "{slotID}", "choices"] -> len 4
+		// /events/{id}/slots/{slotID}/assignments -> ["{id}", "slots", "{slotID}", "assignments"] -> len 4
+		// /events/{id}/slots/{slotID}/seed -> ["{id}", "slots", "{slotID}", "seed"] -> len 4
+		// /events/{id}/slots/{slotID}/publish -> ["{id}", "slots", "{slotID}", "publish"] -> len 4
+		if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Event ID missing or invalid.")
+			return
+		}
+
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			EventPage(db)(w, r)
+		case len(parts) == 2 && parts[1] == "register" && r.Method == http.MethodPost:
+			AuthMiddleware(db, RegisterForEvent(db))(w, r)
+		case len(parts) == 2 && parts[1] == "venues" && r.Method == http.MethodPost:
+			AuthMiddleware(db, AddEventVenue(db))(w, r)
+		case len(parts) == 2 && parts[1] == "slots" && r.Method == http.MethodPost:
+			AuthMiddleware(db, AddEventSlot(db))(w, r)
+		case len(parts) == 2 && parts[1] == "tables" && r.Method == http.MethodPost:
+			AuthMiddleware(db, AddEventTable(db))(w, r)
+		case len(parts) == 2 && parts[1] == "overview" && r.Method == http.MethodGet:
+			AuthMiddleware(db, EventOverview(db))(w, r)
+		case len(parts) == 4 && parts[1] == "slots" && parts[3] == "choices" && r.Method == http.MethodPost:
+			AuthMiddleware(db, SubmitEventChoices(db))(w, r)
+		case len(parts) == 4 && parts[1] == "slots" && parts[3] == "assignments" && r.Method == http.MethodGet:
+			AuthMiddleware(db, EventAssignmentsPage(db))(w, r)
+		case len(parts) == 4 && parts[1] == "slots" && parts[3] == "assignments" && r.Method == http.MethodPost:
+			AuthMiddleware(db, PinEventAssignment(db))(w, r)
+		case len(parts) == 4 && parts[1] == "slots" && parts[3] == "seed" && r.Method == http.MethodPost:
+			AuthMiddleware(db, SetEventSlotSeed(db))(w, r)
+		case len(parts) == 4 && parts[1] == "slots" && parts[3] == "publish" && r.Method == http.MethodPost:
+			AuthMiddleware(db, PublishEventAssignments(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid event path.")
+		}
+	}
+}
+
+func routeDynamicVenuePaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/venues/"), "/")
+		// Expected parts:
+		// /venues/{id} -> ["{id}"] -> len 1
+		if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Venue ID missing or invalid.")
+			return
+		}
+
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			VenuePage(db)(w, r)
+		case len(parts) == 1 && r.Method == http.MethodPost:
+			AuthMiddleware(db, UpdateVenue(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid venue path.")
+		}
+	}
+}
+
+func routeDynamicVirtualTablePaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/virtual-tables/"), "/")
+		// Expected parts:
+		// /virtual-tables/{id} -> ["{id}"] -> len 1
+		if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Virtual table ID missing or invalid.")
+			return
+		}
+
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodPost:
+			AuthMiddleware(db, UpdateVirtualTable(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid virtual table path.")
+		}
+	}
+}
+
+func routeDynamicLFGPaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/lfg/"), "/")
+		// Expected parts:
+		// /lfg/{id}/close -> ["{id}", "close"] -> len 2
+		if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Post ID missing or invalid.")
+			return
+		}
+
+		switch {
+		case len(parts) == 2 && parts[1] == "close" && r.Method == http.MethodPost:
+			AuthMiddleware(db, CloseLFGPost(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid LFG path.")
+		}
+	}
+}
+
+func routeDynamicCampaignPaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/campaigns/"), "/")
+		// Expected parts:
+		// /campaigns/{id} -> ["{id}"] -> len 1
+		// /campaigns/{id}/journal -> ["{id}", "journal"] -> len 2
+		// /campaigns/{id}/availability -> ["{id}", "availability"] -> len 2
+		// /campaigns/{id}/hosting -> ["{id}", "hosting"] -> len 2
+		// /campaigns/{id}/hosting/rotation -> ["{id}", "hosting", "rotation"] -> len 3
+		// /campaigns/{id}/hosting/assign -> ["{id}", "hosting", "assign"] -> len 3
+		// /campaigns/{id}/hosting/swaps -> ["{id}", "hosting", "swaps"] -> len 3
+		// /campaigns/{id}/hosting/swaps/{swapID} -> ["{id}", "hosting", "swaps", "{swapID}"] -> len 4
+		// /campaigns/{id}/hosting/rotation/{userID}/remove -> ["{id}", "hosting", "rotation", "{userID}", "remove"] -> len 5
+		// /campaigns/{id}/ledger -> ["{id}", "ledger"] -> len 2
+		// /campaigns/{id}/ledger/payments -> ["{id}", "ledger", "payments"] -> len 3
+		// /campaigns/{id}/ledger/{entryID}/reverse -> ["{id}", "ledger", "{entryID}", "reverse"] -> len 4
+		if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Campaign ID missing or invalid.")
+			return
+		}
+		get, post := r.Method == http.MethodGet, r.Method == http.MethodPost
+
+		switch {
+		case len(parts) == 1 && get:
+			CampaignPage(db)(w, r)
+		case len(parts) == 2 && parts[1] == "journal" && get:
+			CampaignJournal(db)(w, r)
+		case len(parts) == 2 && parts[1] == "availability" && get:
+			AuthMiddleware(db, CampaignAvailability(db))(w, r)
+		case len(parts) == 2 && parts[1] == "hosting" && get:
+			AuthMiddleware(db, CampaignHostingPage(db))(w, r)
+		case len(parts) == 3 && parts[1] == "hosting" && parts[2] == "rotation" && post:
+			AuthMiddleware(db, AddCampaignHost(db))(w, r)
+		case len(parts) == 3 && parts[1] == "hosting" && parts[2] == "assign" && post:
+			AuthMiddleware(db, AssignNextHost(db))(w, r)
+		case len(parts) == 3 && parts[1] == "hosting" && parts[2] == "swaps" && post:
+			AuthMiddleware(db, RequestHostSwap(db))(w, r)
+		case len(parts) == 4 && parts[1] == "hosting" && parts[2] == "swaps" && post:
+			AuthMiddleware(db, RespondToHostSwap(db))(w, r)
+		case len(parts) == 5 && parts[1] == "hosting" && parts[2] == "rotation" && parts[4] == "remove" && post:
+			AuthMiddleware(db, RemoveCampaignHost(db))(w, r)
+		case len(parts) == 2 && parts[1] == "ledger" && get:
+			AuthMiddleware(db, CampaignLedgerPage(db))(w, r)
+		case len(parts) == 3 && parts[1] == "ledger" && parts[2] == "payments" && post:
+			AuthMiddleware(db, RecordLedgerPayment(db))(w, r)
+		case len(parts) == 4 && parts[1] == "ledger" && parts[3] == "reverse" && post:
+			AuthMiddleware(db, ReverseLedgerEntry(db))(w, r)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid campaign path.")
+		}
+	}
+}
+
+func routeDynamicAdminPaths(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/"), "/")
+		// Expected parts:
+		// /admin/users -> ["users"] -> len 1
+		// /admin/users/{id} -> ["users", "{id}"] -> len 2
+		// /admin/users/{id}/suspend -> ["users", "{id}", "suspend"] -> len 3
+		if len(parts) > 1 {
+			if _, err := strconv.ParseInt(parts[1], 10, 64); err != nil {
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "ID missing or invalid.")
+				return
+			}
+		}
+
+		var handler http.HandlerFunc
+		switch {
+		case len(parts) == 1 && parts[0] == "reports" && r.Method == http.MethodGet:
+			handler = AdminReportsPage(db)
+		case len(parts) == 3 && parts[0] == "reports" && parts[2] == "resolve" && r.Method == http.MethodPost:
+			handler = AdminCloseReport(db, models.ReportStatusResolved)
+		case len(parts) == 3 && parts[0] == "reports" && parts[2] == "dismiss" && r.Method == http.MethodPost:
+			handler = AdminCloseReport(db, models.ReportStatusDismissed)
+		case len(parts) == 1 && parts[0] == "users" && r.Method == http.MethodGet:
+			handler = AdminUsersPage(db)
+		case len(parts) == 2 && parts[0] == "users" && r.Method == http.MethodGet:
+			handler = AdminUserPage(db)
+		case len(parts) == 3 && parts[0] == "users" && parts[2] == "suspend" && r.Method == http.MethodPost:
+			handler = AdminSetSuspended(db, true)
+		case len(parts) == 3 && parts[0] == "users" && parts[2] == "unsuspend" && r.Method == http.MethodPost:
+			handler = AdminSetSuspended(db, false)
+		case len(parts) == 3 && parts[0] == "users" && parts[2] == "role" && r.Method == http.MethodPost:
+			handler = AdminSetUserRole(db)
+		case len(parts) == 1 && parts[0] == "games" && r.Method == http.MethodGet:
+			handler = AdminGamesPage(db)
+		case len(parts) == 3 && parts[0] == "games" && parts[2] == "cancel" && r.Method == http.MethodPost:
+			handler = AdminCancelGame(db)
+		case len(parts) == 1 && parts[0] == "systems" && r.Method == http.MethodGet:
+			handler = AdminSystemsPage(db)
+		case len(parts) == 1 && parts[0] == "systems" && r.Method == http.MethodPost:
+			handler = AdminAddGameSystem(db)
+		case len(parts) == 3 && parts[0] == "systems" && parts[2] == "delete" && r.Method == http.MethodPost:
+			handler = AdminDeleteGameSystem(db)
+		case len(parts) == 1 && parts[0] == "chat" && r.Method == http.MethodGet:
+			handler = AdminChatPage(db)
+		case len(parts) == 3 && parts[0] == "chat" && parts[2] == "delete" && r.Method == http.MethodPost:
+			handler = AdminDeleteChatMessage(db)
+		case len(parts) == 1 && parts[0] == "logins" && r.Method == http.MethodGet:
+			handler = AdminLoginsPage(db)
+		case len(parts) == 1 && parts[0] == "audit" && r.Method == http.MethodGet:
+			handler = AdminAuditLogPage(db)
+		default:
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Invalid admin path.")
+			return
+		}
+		AdminMiddleware(db, handler)(w, r)
+	}
+}
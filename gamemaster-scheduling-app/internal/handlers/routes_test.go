@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+	"github.com/gamemaster-scheduling/app/internal/storage"
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+// testServer struct holds a test server and its dependencies
+type testServer struct {
+	server *httptest.Server
+	db     *sql.DB
+	client *http.Client        // HTTP client that can handle cookies
+	store  *storage.LocalStore // Attachment storage in a temp dir
+}
+
+// setupTestServer initializes an in-memory SQLite database, loads templates
+// and serves the application's router (NewRouter, as main.go does) from an
+// httptest.Server.
+func setupTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+
+	// Load HTML templates - path relative to this test file
+	templatePath := "../../web/templates"
+	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
+		// Fallback for different CWD (e.g. if running tests from project root)
+		templatePath = "web/templates"
+	}
+	err = LoadTemplates(templatePath)
+	if err != nil {
+		t.Fatalf("Error loading templates from %s: %v", templatePath, err)
+	}
+
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create attachment store: %v", err)
+	}
+
+	ts := httptest.NewServer(NewRouter(db, store))
+
+	// Create a client with a cookie jar to handle sessions
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		ts.Close()
+		db.Close()
+		t.Fatalf("Failed to create cookie jar: %v", err)
+	}
+	client := &http.Client{
+		Jar: jar,
+		// Prevent auto-redirects to inspect intermediate responses (e.g. 302 redirect from POST)
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &testServer{
+		server: ts,
+		db:     db,
+		client: client,
+		store:  store,
+	}
+}
+
+// Teardown closes the test server and database connection.
+func (ts *testServer) Teardown() {
+	ts.server.Close()
+	ts.db.Close()
+}
+
+// Helper to register and login a user, returns the authenticated client and user model
+func (ts *testServer) registerAndLoginUser(t *testing.T, email, password string) (*http.Client, *models.User) {
+	t.Helper()
+	// Register
+	regData := url.Values{"email": {email}, "password": {password}, "confirm_password": {password}}
+	resp, err := ts.client.PostForm(ts.server.URL+"/register", regData)
+	if err != nil {
+		t.Fatalf("Helper register failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther { // Should redirect to /login
+		t.Fatalf("Helper register expected redirect, got %d", resp.StatusCode)
+	}
+
+	// Login
+	loginData := url.Values{"email": {email}, "password": {password}}
+	resp, err = ts.client.PostForm(ts.server.URL+"/login", loginData)
+	if err != nil {
+		t.Fatalf("Helper login failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther { // Should redirect to /games
+		t.Fatalf("Helper login expected redirect, got %d", resp.StatusCode)
+	}
+
+	user, dbErr := database.GetUserByEmail(ts.db, email)
+	if dbErr != nil {
+		t.Fatalf("Helper: Failed to get user from DB after login: %v", dbErr)
+	}
+	return ts.client, user // client now has session cookie
+}
+
+// Helper to create a game directly in DB for testing reads
+func (ts *testServer) createTestGameDirectly(t *testing.T, gmID int64, title string) *models.Game {
+	t.Helper()
+	game := &models.Game{
+		GMID:         gmID,
+		Title:        title,
+		Description:  "Test Desc",
+		GameDateTime: time.Now().Add(7 * 24 * time.Hour),
+		Location:     "Test Location",
+	}
+	createdGame, err := database.CreateGame(ts.db, game)
+	if err != nil {
+		t.Fatalf("Failed to create test game directly: %v", err)
+	}
+	return createdGame
+}
+
+// newUserClient registers and logs in a user with its own cookie jar, so several
+// users can act in the same test without overwriting each other's session.
+func (ts *testServer) newUserClient(t *testing.T, email, password string) (*http.Client, *models.User) {
+	t.Helper()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar:           jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	regData := url.Values{"email": {email}, "password": {password}, "confirm_password": {password}}
+	if resp, err := client.PostForm(ts.server.URL+"/register", regData); err != nil {
+		t.Fatalf("register %s failed: %v", email, err)
+	} else {
+		resp.Body.Close()
+	}
+	resp, err := client.PostForm(ts.server.URL+"/login", url.Values{"email": {email}, "password": {password}})
+	if err != nil || resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("login %s failed: %v", email, err)
+	}
+	resp.Body.Close()
+	user, err := database.GetUserByEmail(ts.db, email)
+	if err != nil {
+		t.Fatalf("user %s not found: %v", email, err)
+	}
+	return client, user
+}
+
+// postForm posts to a path on the test server and returns the status and body.
+func postForm(t *testing.T, client *http.Client, rawURL string, data url.Values) (int, string) {
+	t.Helper()
+	resp, err := client.PostForm(rawURL, data)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+// getBody fetches a URL on the test server and returns the status and body.
+func getBody(t *testing.T, client *http.Client, rawURL string) (int, string) {
+	t.Helper()
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRouterRejectsUnknownPaths(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	client, user := ts.registerAndLoginUser(t, "router@example.com", "password")
+	game := ts.createTestGameDirectly(t, user.ID, "Routed Game")
+	gamePath := "/games/" + strconv.FormatInt(game.ID, 10)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/", http.StatusSeeOther},
+		{http.MethodGet, "/nowhere", http.StatusNotFound},
+		{http.MethodDelete, "/register", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/logout", http.StatusMethodNotAllowed},
+		{http.MethodGet, gamePath, http.StatusOK},
+		{http.MethodPost, gamePath, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/games/abc", http.StatusBadRequest},
+		{http.MethodGet, gamePath + "/rsvp", http.StatusMethodNotAllowed},
+		{http.MethodGet, gamePath + "/nonsense", http.StatusNotFound},
+		{http.MethodGet, gamePath + "/chat/1/2/3/4", http.StatusNotFound},
+		{http.MethodGet, "/users/abc", http.StatusNotFound},
+		{http.MethodGet, "/campaigns/abc", http.StatusNotFound},
+		{http.MethodGet, "/events/abc", http.StatusNotFound},
+		{http.MethodGet, "/admin", http.StatusForbidden},
+		{http.MethodGet, "/admin/users/abc", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, ts.server.URL+tt.path, nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", tt.method, tt.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, resp.StatusCode)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gamemaster-scheduling/app/internal/database"
 	"github.com/gamemaster-scheduling/app/internal/models"
@@ -63,13 +64,107 @@ func SubmitRSVP(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		comment := strings.TrimSpace(r.FormValue("comment"))
+		if len(comment) > models.MaxRSVPCommentLength {
+			http.Error(w, fmt.Sprintf("Comments are limited to %d characters", models.MaxRSVPCommentLength), http.StatusBadRequest)
+			return
+		}
+
+		// Guests come along with the player, so they are dropped if the player is not coming.
+		guests := 0
+		if v := strings.TrimSpace(r.FormValue("guests")); v != "" && status != models.RSVPStatusNotAttending {
+			guests, err = strconv.Atoi(v)
+			if err != nil || guests < 0 || guests > models.MaxRSVPGuests {
+				http.Error(w, fmt.Sprintf("Guests must be a number between 0 and %d", models.MaxRSVPGuests), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// The character the player brings is optional, must be one of their own,
+		// and is dropped if they are not coming.
+		var characterID int64
+		if v := r.FormValue("character_id"); v != "" && status != models.RSVPStatusNotAttending {
+			characterID, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid character ID format", http.StatusBadRequest)
+				return
+			}
+			character, err := database.GetCharacterByID(db, characterID)
+			if err != nil || character.UserID != currentUser.ID {
+				http.Error(w, "You can only bring your own characters", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Also need the Game itself for the context of the RSVP section (e.g. Game.ID for form posts)
+		game, err := database.GetGameByID(db, gameID)
+		if err != nil {
+			fmt.Printf("Error fetching game %d for RSVP partial: %v\n", gameID, err)
+			http.Error(w, "Failed to load game context for RSVP.", http.StatusInternalServerError)
+			return
+		}
+		existing, err := database.GetRSVPByUserForGame(db, currentUser.ID, gameID)
+		if err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Error fetching current user's RSVP for game %d: %v\n", gameID, err)
+			http.Error(w, "Failed to update RSVP status. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		previousStatus := ""
+		if existing != nil {
+			previousStatus = existing.Status
+		}
+		if game.CancelledByAdmin() {
+			renderRSVPSection(w, db, game, currentUser, "This game has been cancelled.")
+			return
+		}
+		if game.RSVPsLocked(time.Now()) && !game.IsGM(currentUser.ID) {
+			renderRSVPSection(w, db, game, currentUser, "RSVPs for this game closed on "+FormatDateTime(game.RSVPDeadline)+". Ask the GM if you need to change yours.")
+			return
+		}
+		if previousStatus == models.RSVPStatusDeclined {
+			renderRSVPSection(w, db, game, currentUser, "The GM declined your request to join this game.")
+			return
+		}
+		if game.EventSlotID != 0 && status == models.RSVPStatusAttending && !game.IsGM(currentUser.ID) {
+			problem, err := eventSignupProblem(db, game, currentUser)
+			if err != nil {
+				fmt.Printf("Error checking event sign-up of user %d for game %d: %v\n", currentUser.ID, gameID, err)
+				http.Error(w, "Failed to update RSVP status. Please try again.", http.StatusInternalServerError)
+				return
+			}
+			if problem != "" {
+				renderRSVPSection(w, db, game, currentUser, problem)
+				return
+			}
+		}
+
+		// Joining a game asks the GM for a seat if the game requires approval, and so
+		// does an approved player bringing more guests than the GM approved. Otherwise
+		// the database takes one for the player and each guest, if there are enough.
+		addsGuests := previousStatus == models.RSVPStatusAttending && guests > existing.Guests
+		if status == models.RSVPStatusAttending && !game.IsGM(currentUser.ID) &&
+			game.RequiresApproval && (previousStatus != models.RSVPStatusAttending || addsGuests) {
+			status = models.RSVPStatusPending
+		}
+
 		rsvp := &models.RSVP{
-			UserID: currentUser.ID,
-			GameID: gameID,
-			Status: status,
+			UserID:      currentUser.ID,
+			GameID:      gameID,
+			Status:      status,
+			CharacterID: characterID,
+			Comment:     comment,
+			Guests:      guests,
 		}
 
 		err = database.CreateOrUpdateRSVP(db, rsvp)
+		if err == database.ErrGameFull {
+			renderRSVPSection(w, db, game, currentUser, gameFullMessage(db, game, existing, guests))
+			return
+		}
+		if err == database.ErrSlotTaken {
+			renderRSVPSection(w, db, game, currentUser, slotTakenMessage(db, game, currentUser))
+			return
+		}
 		if err != nil {
 			// Log the error for server-side diagnosis
 			fmt.Printf("Error creating or updating RSVP: %v\n", err)
@@ -79,38 +174,216 @@ func SubmitRSVP(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Successfully updated RSVP. Re-render the RSVP section.
-		// Fetch updated data for the partial.
-		allGameRSVPs, err := database.GetRSVPsForGame(db, gameID)
+		if status == models.RSVPStatusPending && previousStatus != models.RSVPStatusPending {
+			request := fmt.Sprintf("%s asked to join %s", currentUser.DisplayName(), game.Title)
+			if addsGuests {
+				request = fmt.Sprintf("%s asked to bring %d guest(s) to %s", currentUser.DisplayName(), guests, game.Title)
+			}
+			for _, gmID := range game.GMIDs() {
+				_, err := database.CreateNotification(db, &models.Notification{
+					UserID:  gmID,
+					Kind:    models.NotificationKindRSVPRequest,
+					Message: request,
+					Link:    fmt.Sprintf("/games/%d#rsvp-section", game.ID),
+				})
+				if err != nil {
+					fmt.Printf("Error notifying GM %d of RSVP request for game %d: %v\n", gmID, gameID, err)
+				}
+			}
+		}
+
+		// Successfully updated RSVP. Re-render only the partial for the HTMX response.
+		renderRSVPSection(w, db, game, currentUser, "")
+	}
+}
+
+// gameFullMessage explains to a player why there was no seat for them and their
+// guests. Players already attending keep their own seats.
+func gameFullMessage(db *sql.DB, game *models.Game, existing *models.RSVP, guests int) string {
+	rsvps, err := database.GetRSVPsForGame(db, game.ID)
+	if err != nil {
+		fmt.Printf("Error fetching RSVPs for game %d: %v\n", game.ID, err)
+		return "Sorry, this game is full."
+	}
+	taken := game.SeatsTaken(rsvps)
+	if existing != nil && existing.Status == models.RSVPStatusAttending {
+		taken -= existing.PartySize()
+	}
+	if free := game.MaxPlayers - taken; free > 0 {
+		return fmt.Sprintf("Sorry, only %d seat(s) are left, not enough for you and %d guest(s).", free, guests)
+	}
+	return "Sorry, this game is full."
+}
+
+// ReviewRSVP approves or declines a player's pending RSVP:
+// POST /games/{id}/rsvps/{rsvpID}/approve or /decline, with an optional message
+// for the player. Only the GM can review RSVPs. This handler should be wrapped by AuthMiddleware.
+func ReviewRSVP(db *sql.DB, approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "review RSVPs")
+		if !ok {
+			return
+		}
+		rsvpID, err := pathInt64(r, "/games/", 2)
+		if err != nil {
+			http.Error(w, "Invalid RSVP ID format", http.StatusBadRequest)
+			return
+		}
+		rsvp, err := database.GetRSVPByID(db, rsvpID)
+		if err != nil || rsvp.GameID != game.ID {
+			http.Error(w, "RSVP not found", http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+		message := strings.TrimSpace(r.FormValue("message"))
+		if len(message) > 500 {
+			renderRSVPSection(w, db, game, currentUser, "Messages to players are limited to 500 characters.")
+			return
+		}
+
+		err = database.ReviewRSVP(db, rsvp.ID, currentUser.ID, approve, message)
+		switch {
+		case err == database.ErrGameFull:
+			renderRSVPSection(w, db, game, currentUser, "There are no seats left. Raise the seat limit or wait for a player to drop out.")
+			return
+		case err == database.ErrRSVPNotReviewable:
+			renderRSVPSection(w, db, game, currentUser, "That RSVP is no longer waiting for approval.")
+			return
+		case err != nil:
+			fmt.Printf("Error reviewing RSVP %d: %v\n", rsvp.ID, err)
+			http.Error(w, "Failed to review RSVP. Please try again.", http.StatusInternalServerError)
+			return
+		}
+
+		kind, verb := models.NotificationKindRSVPDeclined, "declined"
+		if approve {
+			kind, verb = models.NotificationKindRSVPApproved, "approved"
+		}
+		text := fmt.Sprintf("%s %s your request to join %s", currentUser.DisplayName(), verb, game.Title)
+		if message != "" {
+			text += ": " + message
+		}
+		_, err = database.CreateNotification(db, &models.Notification{
+			UserID:  rsvp.UserID,
+			Kind:    kind,
+			Message: text,
+			Link:    fmt.Sprintf("/games/%d", game.ID),
+		})
 		if err != nil {
-			fmt.Printf("Error fetching RSVPs for game %d after update: %v\n", gameID, err)
-			http.Error(w, "Failed to refresh RSVP list.", http.StatusInternalServerError)
+			fmt.Printf("Error notifying user %d of RSVP review: %v\n", rsvp.UserID, err)
+		}
+		renderRSVPSection(w, db, game, currentUser, "")
+	}
+}
+
+// SetRSVPsReopened lets players change their RSVPs after the deadline, or locks them
+// again: POST /games/{id}/rsvps/reopen or /games/{id}/rsvps/lock. Only the GM can do this.
+// This handler should be wrapped by AuthMiddleware.
+func SetRSVPsReopened(db *sql.DB, reopen bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "reopen RSVPs")
+		if !ok {
+			return
+		}
+		if err := database.SetRSVPsReopened(db, game.ID, reopen); err != nil {
+			fmt.Printf("Error setting RSVPs reopened for game %d: %v\n", game.ID, err)
+			http.Error(w, "Failed to update RSVPs. Please try again.", http.StatusInternalServerError)
 			return
 		}
+		game.RSVPsReopened = reopen
+		renderRSVPSection(w, db, game, currentUser, "")
+	}
+}
 
-		currentUserRSVP, err := database.GetRSVPByUserForGame(db, currentUser.ID, gameID)
-		if err != nil && err != sql.ErrNoRows {
-			fmt.Printf("Error fetching current user's RSVP for game %d after update: %v\n", gameID, err)
-			// Non-critical, can proceed without it if it fails, partial should handle nil
+// RSVPHistory shows the GM a game's RSVP changes, newest first: GET /games/{id}/rsvps/history.
+// This handler should be wrapped by AuthMiddleware.
+func RSVPHistory(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "see the RSVP history")
+		if !ok {
+			return
 		}
-		
-		// Also need the Game itself for the context of the RSVP section (e.g. Game.ID for form posts)
-		game, err := database.GetGameByID(db, gameID)
+		events, err := database.GetRSVPEventsForGame(db, game.ID)
 		if err != nil {
-			fmt.Printf("Error fetching game %d for RSVP partial: %v\n", gameID, err)
-			http.Error(w, "Failed to load game context for RSVP.", http.StatusInternalServerError)
+			fmt.Printf("Error fetching RSVP history for game %d: %v\n", game.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the RSVP history.")
 			return
 		}
+		RenderTemplate(w, "games/rsvp_history.html", map[string]interface{}{
+			"Title":  "RSVP history - " + game.Title,
+			"User":   currentUser,
+			"Game":   game,
+			"Events": events,
+		})
+	}
+}
 
+// rsvpSectionData gathers what _rsvp_section.html needs: everyone's RSVPs, the
+// viewer's own RSVP and characters, seats, and for the GM the approval queue.
+func rsvpSectionData(db *sql.DB, game *models.Game, currentUser *models.User) (map[string]interface{}, error) {
+	allGameRSVPs, err := database.GetRSVPsForGame(db, game.ID)
+	if err != nil {
+		return nil, err
+	}
 
-		data := map[string]interface{}{
-			"Game":            game, // Needed for forming hx-post URLs in the partial
-			"User":            currentUser, // For conditional rendering within the partial
-			"CurrentUserRSVP": currentUserRSVP,
-			"AllGameRSVPs":    allGameRSVPs,
+	var currentUserRSVP *models.RSVP
+	var pending []*models.RSVP
+	for _, rsvp := range allGameRSVPs {
+		if currentUser != nil && rsvp.UserID == currentUser.ID {
+			currentUserRSVP = rsvp
+		}
+		if rsvp.Status == models.RSVPStatusPending {
+			pending = append(pending, rsvp)
 		}
-		
-		// Render only the partial for the HTMX response
-		RenderTemplate(w, "games/_rsvp_section.html", data)
 	}
+	isGM := currentUser != nil && game.IsGM(currentUser.ID)
+	now := time.Now()
+	if !isGM {
+		pending = nil
+	}
+
+	return map[string]interface{}{
+		"Game":            game,        // Needed for forming hx-post URLs in the partial
+		"User":            currentUser, // For conditional rendering within the partial
+		"CurrentUserRSVP": currentUserRSVP,
+		"AllGameRSVPs":    allGameRSVPs,
+		"MyCharacters":    myCharacters(db, currentUser),
+		"IsGM":            isGM,
+		"PendingRSVPs":    pending,
+		"SeatsTaken":      game.SeatsTaken(allGameRSVPs),
+		"DeadlinePassed":  game.RSVPDeadlinePassed(now),
+		"RSVPsLocked":     game.RSVPsLocked(now) && !isGM,
+
+		// Status values for the buttons' hx-vals, so they keep working after a swap
+		"RSVPStatusAttending":    models.RSVPStatusAttending,
+		"RSVPStatusMaybe":        models.RSVPStatusMaybe,
+		"RSVPStatusNotAttending": models.RSVPStatusNotAttending,
+	}, nil
+}
+
+// renderRSVPSection renders the RSVP partial for an htmx swap, with an optional error.
+func renderRSVPSection(w http.ResponseWriter, db *sql.DB, game *models.Game, currentUser *models.User, errMsg string) {
+	data, err := rsvpSectionData(db, game, currentUser)
+	if err != nil {
+		fmt.Printf("Error fetching RSVPs for game %d: %v\n", game.ID, err)
+		http.Error(w, "Failed to refresh RSVP list.", http.StatusInternalServerError)
+		return
+	}
+	data["Error"] = errMsg
+	RenderTemplate(w, "games/_rsvp_section.html", data)
+}
+
+// myCharacters returns the roster the current user can pick from in the RSVP section.
+func myCharacters(db *sql.DB, currentUser *models.User) []*models.Character {
+	if currentUser == nil {
+		return nil
+	}
+	characters, err := database.GetCharactersForUser(db, currentUser.ID)
+	if err != nil {
+		fmt.Printf("Error fetching characters for user %d: %v\n", currentUser.ID, err)
+	}
+	return characters
 }
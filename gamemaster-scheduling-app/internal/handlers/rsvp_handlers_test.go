@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+	"github.com/gamemaster-scheduling/app/internal/quorum"
+)
+
+func TestRSVPApproval(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "vet_gm@example.com", "gmpass")
+	aliceClient, alice := ts.newUserClient(t, "vet_alice@example.com", "password")
+	bobClient, bob := ts.newUserClient(t, "vet_bob@example.com", "password")
+
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{
+		"title": {"Invite Only"}, "game_datetime": {"2030-05-01T19:00"}, "location": {"Table"},
+		"requires_approval": {"on"}, "max_players": {"1"},
+	})
+	games, _ := database.GetGamesByGM(ts.db, gm.ID)
+	if len(games) != 1 || !games[0].RequiresApproval || games[0].MaxPlayers != 1 {
+		t.Fatalf("games = %v, want one approval game with 1 seat", games)
+	}
+	game := games[0]
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+
+	_, body := postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	if !strings.Contains(body, "Waiting for the GM to approve your request") || !strings.Contains(body, "Seats: <strong>0 of 1</strong>") {
+		t.Errorf("pending RSVP response missing the waiting note or seat count: %s", body)
+	}
+	postForm(t, bobClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	gmNotes, _ := database.GetNotificationsForUser(ts.db, gm.ID, 10)
+	if len(gmNotes) != 2 || gmNotes[0].Kind != models.NotificationKindRSVPRequest {
+		t.Errorf("GM notifications = %v, want two join requests", gmNotes)
+	}
+
+	aliceRSVP, _ := database.GetRSVPByUserForGame(ts.db, alice.ID, game.ID)
+	bobRSVP, _ := database.GetRSVPByUserForGame(ts.db, bob.ID, game.ID)
+	aliceReview := gameURL + "/rsvps/" + strconv.FormatInt(aliceRSVP.ID, 10)
+	bobReview := gameURL + "/rsvps/" + strconv.FormatInt(bobRSVP.ID, 10)
+	if aliceRSVP.Status != models.RSVPStatusPending {
+		t.Errorf("alice's RSVP status = %q, want pending", aliceRSVP.Status)
+	}
+	if status, _ := postForm(t, aliceClient, aliceReview+"/approve", nil); status != http.StatusForbidden {
+		t.Errorf("player approving their own RSVP status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	_, body = getBody(t, gmClient, gameURL)
+	if !strings.Contains(body, "Waiting for Approval") || !strings.Contains(body, "rsvps/"+strconv.FormatInt(bobRSVP.ID, 10)+"/decline") {
+		t.Errorf("GM's game page is missing the approval queue")
+	}
+	_, body = getBody(t, aliceClient, gameURL)
+	if strings.Contains(body, "Waiting for Approval") {
+		t.Errorf("players should not see the approval queue")
+	}
+
+	_, body = postForm(t, gmClient, aliceReview+"/approve", url.Values{"message": {"See you there!"}})
+	if !strings.Contains(body, "Approved by vet_gm@example.com") || !strings.Contains(body, "1 of 1") {
+		t.Errorf("approval response missing the review or seat count: %s", body)
+	}
+	aliceNotes, _ := database.GetNotificationsForUser(ts.db, alice.ID, 10)
+	if len(aliceNotes) != 1 || aliceNotes[0].Kind != models.NotificationKindRSVPApproved || !strings.Contains(aliceNotes[0].Message, "See you there!") {
+		t.Errorf("alice notifications = %v, want an approval with the GM's message", aliceNotes)
+	}
+
+	_, body = postForm(t, gmClient, bobReview+"/approve", nil)
+	if !strings.Contains(body, "There are no seats left") {
+		t.Errorf("approving past the seat limit was not refused: %s", body)
+	}
+	postForm(t, gmClient, bobReview+"/decline", url.Values{"message": {"Full this time, sorry"}})
+	bobNotes, _ := database.GetNotificationsForUser(ts.db, bob.ID, 10)
+	if len(bobNotes) != 1 || bobNotes[0].Kind != models.NotificationKindRSVPDeclined {
+		t.Errorf("bob notifications = %v, want a decline", bobNotes)
+	}
+	_, body = postForm(t, bobClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	if !strings.Contains(body, "The GM declined your request") || !strings.Contains(body, "Full this time, sorry") {
+		t.Errorf("declined player could re-request or did not see the GM's message: %s", body)
+	}
+
+	// An approved player changing character keeps their seat.
+	postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	if r, _ := database.GetRSVPByUserForGame(ts.db, alice.ID, game.ID); r.Status != models.RSVPStatusAttending {
+		t.Errorf("approved player re-submitting attending became %q", r.Status)
+	}
+
+	// Bringing guests the GM didn't approve asks again.
+	postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "guests": {"1"}})
+	if r, _ := database.GetRSVPByUserForGame(ts.db, alice.ID, game.ID); r.Status != models.RSVPStatusPending || r.Guests != 1 {
+		t.Errorf("approved player adding a guest = %q with %d guest(s), want pending with 1", r.Status, r.Guests)
+	}
+	gmNotes, _ = database.GetNotificationsForUser(ts.db, gm.ID, 10)
+	if len(gmNotes) != 3 || !strings.Contains(gmNotes[0].Message, "asked to bring 1 guest(s) to Invite Only") {
+		t.Errorf("GM notifications = %v, want a request for alice's guest", gmNotes)
+	}
+
+	// Without approval, the seat limit still applies.
+	open := ts.createTestGameDirectly(t, gm.ID, "Open Table")
+	database.CreateOrUpdateRSVP(ts.db, &models.RSVP{GameID: open.ID, UserID: alice.ID, Status: models.RSVPStatusAttending})
+	ts.db.Exec("UPDATE games SET max_players = 1 WHERE id = ?", open.ID)
+	_, body = postForm(t, bobClient, ts.server.URL+"/games/"+strconv.FormatInt(open.ID, 10)+"/rsvp", url.Values{"status": {"attending"}})
+	if !strings.Contains(body, "Sorry, this game is full.") {
+		t.Errorf("RSVP to a full game was not refused: %s", body)
+	}
+}
+
+func TestRSVPDeadlineAndQuorum(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "quorum_gm@example.com", "gmpass")
+	aliceClient, alice := ts.newUserClient(t, "quorum_alice@example.com", "password")
+	_, bob := ts.newUserClient(t, "quorum_bob@example.com", "password")
+
+	for _, tc := range []struct {
+		fields url.Values
+		want   string
+	}{
+		{url.Values{"rsvp_deadline": {"2030-05-02T19:00"}}, "must be before the game starts"},
+		{url.Values{"min_players": {"3"}}, "Set an RSVP deadline"},
+		{url.Values{"rsvp_deadline": {"2030-04-30T19:00"}, "min_players": {"5"}, "max_players": {"4"}}, "cannot be above the number of seats"},
+	} {
+		tc.fields.Set("title", "Tentative")
+		tc.fields.Set("game_datetime", "2030-05-01T19:00")
+		tc.fields.Set("location", "Table")
+		if _, body := postForm(t, gmClient, ts.server.URL+"/games/new", tc.fields); !strings.Contains(body, tc.want) {
+			t.Errorf("creating a game with %v: response missing %q", tc.fields, tc.want)
+		}
+	}
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{
+		"title": {"Tentative"}, "game_datetime": {"2030-05-01T19:00"}, "location": {"Table"},
+		"rsvp_deadline": {"2030-04-30T19:00"}, "min_players": {"2"},
+	})
+	games, _ := database.GetGamesByGM(ts.db, gm.ID)
+	if len(games) != 1 || games[0].MinPlayers != 2 || !games[0].RSVPDeadline.Equal(time.Date(2030, 4, 30, 19, 0, 0, 0, time.UTC)) {
+		t.Fatalf("games = %v, want one game needing 2 players by the deadline", games)
+	}
+
+	// A game whose deadline has already passed.
+	game, err := database.CreateGame(ts.db, &models.Game{
+		GMID: gm.ID, Title: "Last Call", GameDateTime: time.Now().Add(24 * time.Hour), Location: "Table",
+		RSVPDeadline: time.Now().Add(-time.Hour), MinPlayers: 2,
+	})
+	if err != nil {
+		t.Fatalf("CreateGame() error = %v", err)
+	}
+	database.CreateOrUpdateRSVP(ts.db, &models.RSVP{GameID: game.ID, UserID: bob.ID, Status: models.RSVPStatusAttending})
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+
+	_, body := postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	if !strings.Contains(body, "RSVPs for this game closed on") || !strings.Contains(body, "RSVPs are closed.") {
+		t.Errorf("RSVP after the deadline was not refused: %s", body)
+	}
+	if _, err := database.GetRSVPByUserForGame(ts.db, alice.ID, game.ID); err != sql.ErrNoRows {
+		t.Errorf("locked RSVP was saved: %v", err)
+	}
+	if status, _ := postForm(t, aliceClient, gameURL+"/rsvps/reopen", nil); status != http.StatusForbidden {
+		t.Errorf("player reopening RSVPs status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	_, body = getBody(t, gmClient, gameURL)
+	if !strings.Contains(body, "Reopen RSVPs") || !strings.Contains(body, "needs at least 2 players (1 so far)") {
+		t.Errorf("GM's game page is missing the reopen button or quorum count")
+	}
+	postForm(t, gmClient, gameURL+"/rsvps/reopen", nil)
+	_, body = postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	if !strings.Contains(body, "(reopened by the GM)") || !strings.Contains(body, "2 players (2 so far)") {
+		t.Errorf("RSVP after the GM reopened was not accepted: %s", body)
+	}
+	_, body = postForm(t, gmClient, gameURL+"/rsvps/lock", nil)
+	if !strings.Contains(body, "Reopen RSVPs") {
+		t.Errorf("locking RSVPs again did not offer to reopen them: %s", body)
+	}
+
+	checker := &quorum.Checker{DB: ts.db, Now: time.Now}
+	if n, err := checker.RunOnce(); err != nil || n != 1 {
+		t.Fatalf("RunOnce() = %d, %v; want the past-deadline game decided", n, err)
+	}
+	_, body = getBody(t, aliceClient, gameURL)
+	if !strings.Contains(body, "Confirmed: enough players signed up") {
+		t.Errorf("game page does not show the confirmation")
+	}
+	notes, _ := database.GetNotificationsForUser(ts.db, alice.ID, 10)
+	if len(notes) != 1 || !strings.Contains(notes[0].Message, "Last Call is on") {
+		t.Errorf("alice notifications = %v, want the game confirmation", notes)
+	}
+}
+
+func TestRSVPHistory(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "history_gm@example.com", "gmpass")
+	aliceClient, _ := ts.newUserClient(t, "history_alice@example.com", "password")
+	game := ts.createTestGameDirectly(t, gm.ID, "Midnight Run")
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+
+	postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"not_attending"}, "comment": {"Kid is sick <sorry>"}})
+	if status, _ := postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"maybe"}, "comment": {strings.Repeat("x", models.MaxRSVPCommentLength+1)}}); status != http.StatusBadRequest {
+		t.Errorf("over-long comment status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	if status, _ := getBody(t, aliceClient, gameURL+"/rsvps/history"); status != http.StatusForbidden {
+		t.Errorf("player viewing RSVP history status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body := getBody(t, aliceClient, gameURL)
+	if strings.Contains(body, "/rsvps/history") || !strings.Contains(body, `id="rsvp-comment"`) {
+		t.Errorf("player game page should have the comment field but no history link")
+	}
+
+	_, body = getBody(t, gmClient, gameURL)
+	if !strings.Contains(body, "/rsvps/history") {
+		t.Errorf("GM game page is missing the RSVP history link")
+	}
+	status, body := getBody(t, gmClient, gameURL+"/rsvps/history")
+	if status != http.StatusOK {
+		t.Fatalf("RSVP history status = %d", status)
+	}
+	if !strings.Contains(body, "Attending &rarr; <strong>Not Attending</strong>") || !strings.Contains(body, "Kid is sick &lt;sorry&gt;") {
+		t.Errorf("RSVP history is missing the change or escaped comment: %s", body)
+	}
+	if strings.Index(body, "Not Attending</strong>") > strings.Index(body, "<strong>Attending</strong>") {
+		t.Errorf("RSVP history should list the newest change first")
+	}
+}
+
+func TestRSVPCommentsAndGuests(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "plus_gm@example.com", "gmpass")
+	aliceClient, alice := ts.newUserClient(t, "plus_alice@example.com", "password")
+	bobClient, _ := ts.newUserClient(t, "plus_bob@example.com", "password")
+
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{
+		"title": {"Game Night"}, "game_datetime": {"2030-05-01T19:00"}, "location": {"Table"}, "max_players": {"3"},
+	})
+	games, _ := database.GetGamesByGM(ts.db, gm.ID)
+	if len(games) != 1 {
+		t.Fatalf("games = %v, want one", games)
+	}
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(games[0].ID, 10)
+
+	if status, _ := postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "guests": {"9"}}); status != http.StatusBadRequest {
+		t.Errorf("too many guests status = %d, want %d", status, http.StatusBadRequest)
+	}
+	_, body := postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "guests": {"1"}, "comment": {"Arriving 30 min late"}})
+	if !strings.Contains(body, "Seats: <strong>2 of 3</strong>") || !strings.Contains(body, "+1 guest(s)") || !strings.Contains(body, "Arriving 30 min late") {
+		t.Errorf("RSVP response missing the guest, comment or seat count: %s", body)
+	}
+	// The partial swapped in must carry working buttons.
+	if !strings.Contains(body, `"status": "attending"`) || !strings.Contains(body, `"status": "not_attending"`) {
+		t.Errorf("swapped RSVP buttons have no status values: %s", body)
+	}
+	if r, _ := database.GetRSVPByUserForGame(ts.db, alice.ID, games[0].ID); r.Comment != "Arriving 30 min late" || r.Guests != 1 {
+		t.Errorf("stored RSVP = %+v", r)
+	}
+
+	_, body = postForm(t, bobClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "guests": {"1"}})
+	if !strings.Contains(body, "only 1 seat(s) are left") {
+		t.Errorf("guests past the seat limit were not refused: %s", body)
+	}
+	postForm(t, bobClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+
+	// Alice can't add a guest to a full table, but can drop hers.
+	_, body = postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "guests": {"2"}})
+	if !strings.Contains(body, "only 2 seat(s) are left, not enough for you and 2 guest(s)") {
+		t.Errorf("adding a guest to a full game was not refused: %s", body)
+	}
+	_, body = postForm(t, aliceClient, gameURL+"/rsvp", url.Values{"status": {"attending"}, "guests": {"0"}})
+	if !strings.Contains(body, "Seats: <strong>2 of 3</strong>") {
+		t.Errorf("dropping a guest did not free the seat: %s", body)
+	}
+
+	_, body = getBody(t, gmClient, gameURL)
+	if !strings.Contains(body, "plus_bob@example.com") || strings.Contains(body, "Arriving 30 min late") {
+		t.Errorf("game page shows a stale comment or misses players")
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// SafetyPage shows the form where players set their lines and veils: GET /safety.
+// Limits are private; GMs only see anonymized counts for their games.
+// This handler should be wrapped by AuthMiddleware.
+func SafetyPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		limits, err := database.GetContentLimits(db, currentUser.ID)
+		if err != nil {
+			fmt.Printf("Error fetching content limits for user %d: %v\n", currentUser.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load your lines and veils.")
+			return
+		}
+		renderSafetyPage(w, currentUser, limits, r.URL.Query().Get("saved") != "", "")
+	}
+}
+
+func renderSafetyPage(w http.ResponseWriter, currentUser *models.User, limits map[string]string, saved bool, errMsg string) {
+	RenderTemplate(w, "users/safety.html", map[string]interface{}{
+		"Title":         "Lines and veils",
+		"User":          currentUser,
+		"ContentTopics": models.ContentTopics,
+		"Limits":        limits,
+		"Saved":         saved,
+		"Error":         errMsg,
+	})
+}
+
+// UpdateSafetyLimits saves the current user's lines and veils: POST /safety.
+// Each topic's form value is "line", "veil" or empty for no limit.
+// This handler should be wrapped by AuthMiddleware.
+func UpdateSafetyLimits(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+
+		limits := make(map[string]string)
+		for _, topic := range models.ContentTopics {
+			switch kind := r.FormValue(topic); kind {
+			case "":
+			case models.LimitLine, models.LimitVeil:
+				limits[topic] = kind
+			default:
+				renderSafetyPage(w, currentUser, limits, false, "Each topic must be a line, a veil or nothing.")
+				return
+			}
+		}
+
+		if err := database.SetContentLimits(db, currentUser.ID, limits); err != nil {
+			fmt.Printf("Error saving content limits for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to save your lines and veils. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/safety?saved=1", http.StatusSeeOther)
+	}
+}
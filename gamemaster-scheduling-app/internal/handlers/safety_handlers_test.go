@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestContentWarningsAndLimits(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, _ := ts.newUserClient(t, "safety_gm@example.com", "gmpass")
+	playerClient, player := ts.newUserClient(t, "safety_player@example.com", "password")
+	otherClient, _ := ts.newUserClient(t, "safety_other@example.com", "password")
+
+	// The GM tags a new game; unknown topics are refused.
+	newGame := url.Values{
+		"title": {"Arachnophobia"}, "game_datetime": {time.Now().Add(48 * time.Hour).Format("2006-01-02T15:04")}, "location": {"Online"},
+		"content_tags": {"spiders", "made_up"},
+	}
+	if _, body := postForm(t, gmClient, ts.server.URL+"/games/new", newGame); !strings.Contains(body, "Unknown content warning: made_up") {
+		t.Errorf("unknown content tag was not refused: %s", body)
+	}
+	newGame["content_tags"] = []string{"spiders", "death"}
+	newGame.Set("content_notes", "A giant spider eats an NPC")
+	postForm(t, gmClient, ts.server.URL+"/games/new", newGame)
+	games, _ := database.GetAllGames(ts.db)
+	if len(games) != 1 || !reflect.DeepEqual(games[0].ContentTags, []string{"death", "spiders"}) {
+		t.Fatalf("games = %v, want one tagged with death and spiders", games)
+	}
+	game := games[0]
+	ts.createTestGameDirectly(t, game.GMID, "Untagged Table")
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+	if _, body := getBody(t, playerClient, gameURL); !strings.Contains(body, "Content warnings:") || !strings.Contains(body, "A giant spider eats an NPC") {
+		t.Errorf("game page missing content warnings")
+	}
+
+	// Players set lines and veils privately.
+	if _, body := postForm(t, playerClient, ts.server.URL+"/safety", url.Values{"spiders": {"forbidden"}}); !strings.Contains(body, "must be a line, a veil or nothing") {
+		t.Errorf("invalid limit kind was not refused: %s", body)
+	}
+	if status, _ := postForm(t, playerClient, ts.server.URL+"/safety", url.Values{"spiders": {"line"}, "death": {"veil"}}); status != http.StatusSeeOther {
+		t.Errorf("saving limits status = %d, want %d", status, http.StatusSeeOther)
+	}
+	if limits, _ := database.GetContentLimits(ts.db, player.ID); len(limits) != 2 || limits["spiders"] != models.LimitLine {
+		t.Errorf("limits = %v, want a spiders line and a death veil", limits)
+	}
+	if _, body := getBody(t, playerClient, ts.server.URL+"/safety"); !strings.Contains(body, `value="line" aria-label="Spiders: line" checked`) {
+		t.Errorf("safety page does not show the saved line")
+	}
+
+	// The games list can hide games with the player's lines.
+	if _, body := getBody(t, playerClient, ts.server.URL+"/games?exclude_lines=1"); strings.Contains(body, "Arachnophobia") || !strings.Contains(body, "Untagged Table") {
+		t.Errorf("filtered games list = %s", body)
+	}
+	if _, body := getBody(t, otherClient, ts.server.URL+"/games?exclude_lines=1"); !strings.Contains(body, "Arachnophobia") {
+		t.Errorf("games list hides games from a player without lines")
+	}
+
+	// The GM sees anonymized counts once RSVPs close, not as each player RSVPs.
+	// Other players see nothing.
+	postForm(t, playerClient, gameURL+"/rsvp", url.Values{"status": {models.RSVPStatusAttending}})
+	for _, email := range []string{"safety_second@example.com", "safety_third@example.com"} {
+		client, _ := ts.newUserClient(t, email, "password")
+		postForm(t, client, ts.server.URL+"/safety", url.Values{"gore": {"veil"}})
+		postForm(t, client, gameURL+"/rsvp", url.Values{"status": {models.RSVPStatusMaybe}})
+	}
+	_, body := getBody(t, gmClient, gameURL)
+	if !strings.Contains(body, "Counts appear when RSVPs close") || strings.Contains(body, "Spiders <strong>") {
+		t.Errorf("GM sees limits before RSVPs close: %s", body)
+	}
+	if _, err := ts.db.Exec("UPDATE games SET rsvp_deadline = ? WHERE id = ?", time.Now().Add(-time.Minute).UTC(), game.ID); err != nil {
+		t.Fatalf("closing RSVPs failed: %v", err)
+	}
+	_, body = getBody(t, gmClient, gameURL)
+	if !strings.Contains(body, "Players' lines and veils") || !strings.Contains(body, "Spiders <strong>(tagged in this game)</strong>") {
+		t.Errorf("GM limit summary = %s", body)
+	}
+	if _, body := getBody(t, otherClient, gameURL); strings.Contains(body, "Players' lines and veils") {
+		t.Errorf("limit summary shown to a player")
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// noteLabels are the headings shown for each kind of session note.
+var noteLabels = map[string]string{
+	models.NoteKindRecap:  "Session Recap",
+	models.NoteKindGMPrep: "GM Prep Notes (only you can see these)",
+}
+
+// canAccessNote reports whether user may read and edit a game's note of the given kind.
+// Recaps are open to the game's participants; prep notes only to the GM.
+func canAccessNote(db *sql.DB, game *models.Game, kind string, user *models.User) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	if kind == models.NoteKindGMPrep {
+		return game.IsGM(user.ID), nil
+	}
+	return database.IsGameParticipant(db, game.ID, user.ID)
+}
+
+// sessionNoteData builds the template data for _session_note.html. It returns nil
+// if the user may not see the note.
+func sessionNoteData(db *sql.DB, game *models.Game, kind string, user *models.User) (map[string]interface{}, error) {
+	ok, err := canAccessNote(db, game, kind, user)
+	if err != nil || !ok {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"Game":     game,
+		"Kind":     kind,
+		"Label":    noteLabels[kind],
+		"Revision": int64(0),
+	}
+	note, err := database.GetSessionNote(db, game.ID, kind)
+	if err == nil {
+		data["Note"] = note
+		data["Revision"] = note.RevisionID
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+	return data, nil
+}
+
+// sessionNoteRequest loads the game and note kind from /games/{id}/notes/{kind}[/...]
+// and checks the current user may access it, writing an error response if not.
+func sessionNoteRequest(w http.ResponseWriter, r *http.Request, db *sql.DB) (game *models.Game, kind string, user *models.User, ok bool) {
+	gameID, err := pathInt64(r, "/games/", 0)
+	if err != nil {
+		http.Error(w, "Invalid Game ID format", http.StatusBadRequest)
+		return nil, "", nil, false
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/games/"), "/")
+	if len(parts) < 3 || !models.ValidNoteKind(parts[2]) {
+		http.Error(w, "Unknown kind of session note", http.StatusNotFound)
+		return nil, "", nil, false
+	}
+	kind = parts[2]
+	game, err = database.GetGameByID(db, gameID)
+	if err != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return nil, "", nil, false
+	}
+	user, _ = GetCurrentUser(r, db) // Nil user is refused below
+	allowed, err := canAccessNote(db, game, kind, user)
+	if err != nil {
+		fmt.Printf("Error checking note access for game %d: %v\n", gameID, err)
+		http.Error(w, "Failed to load session notes.", http.StatusInternalServerError)
+		return nil, "", nil, false
+	}
+	if !allowed {
+		if kind == models.NoteKindGMPrep {
+			http.Error(w, "Only the GM can see prep notes for this game.", http.StatusForbidden)
+		} else {
+			http.Error(w, "Only the game's participants can see its recap.", http.StatusForbidden)
+		}
+		return nil, "", nil, false
+	}
+	return game, kind, user, true
+}
+
+// SaveSessionNote saves a game's recap or GM prep notes: POST /games/{id}/notes/{kind}
+// with 'content' and 'revision', the revision the editor started from. The editor
+// autosaves through this as the user types. It renders _session_note_status.html.
+// This handler should be wrapped by AuthMiddleware.
+func SaveSessionNote(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, kind, currentUser, ok := sessionNoteRequest(w, r, db)
+		if !ok {
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+		baseRevision, _ := strconv.ParseInt(r.FormValue("revision"), 10, 64) // Empty for a new note
+
+		data := map[string]interface{}{"Game": game, "Kind": kind}
+		note, err := database.SaveSessionNote(db, game.ID, kind, currentUser.ID, r.FormValue("content"), baseRevision)
+		switch {
+		case err == database.ErrNoteConflict:
+			data["Error"] = "Someone else changed these notes while you were editing. Copy your text and reload the page to see their version."
+			data["Revision"] = baseRevision // Keep failing until the editor reloads
+		case err != nil:
+			fmt.Printf("Error saving %s note for game %d: %v\n", kind, game.ID, err)
+			data["Error"] = "Failed to save. Your changes will be retried as you type."
+			data["Revision"] = baseRevision
+		default:
+			data["Note"] = note
+			data["Revision"] = note.RevisionID
+			data["Saved"] = true
+		}
+		RenderTemplate(w, "games/_session_note_status.html", data)
+	}
+}
+
+// SessionNoteHistory lists every saved version of a note: GET /games/{id}/notes/{kind}/history.
+func SessionNoteHistory(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, kind, currentUser, ok := sessionNoteRequest(w, r, db)
+		if !ok {
+			return
+		}
+		var revisions []*models.SessionNoteRevision
+		note, err := database.GetSessionNote(db, game.ID, kind)
+		if err == nil {
+			revisions, err = database.GetSessionNoteRevisions(db, note.ID)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Error fetching %s note history for game %d: %v\n", kind, game.ID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the note history.")
+			return
+		}
+		RenderTemplate(w, "games/note_history.html", map[string]interface{}{
+			"Title":     noteLabels[kind] + " history - " + game.Title,
+			"User":      currentUser,
+			"Game":      game,
+			"Label":     noteLabels[kind],
+			"Revisions": revisions,
+		})
+	}
+}
+
+// CampaignJournal strings together the recaps of a campaign's past sessions, oldest
+// first: GET /campaigns/{id}/journal. Recaps are shown only for sessions the viewer
+// took part in.
+func CampaignJournal(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaignID, err := pathInt64(r, "/campaigns/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid campaign ID format.")
+			return
+		}
+		campaign, err := database.GetCampaignByID(db, campaignID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Campaign not found.")
+			} else {
+				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		currentUser, _ := GetCurrentUser(r, db) // Logged-out visitors see session titles only
+		entries, err := campaignJournalEntries(db, campaignID, currentUser)
+		if err != nil {
+			fmt.Printf("Error building journal for campaign %d: %v\n", campaignID, err)
+			RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the campaign journal.")
+			return
+		}
+		RenderTemplate(w, "campaigns/journal.html", map[string]interface{}{
+			"Title":    campaign.Name + " journal",
+			"User":     currentUser,
+			"Campaign": campaign,
+			"Entries":  entries,
+		})
+	}
+}
+
+// campaignJournalEntries lists a campaign's past sessions, oldest first, with the
+// recaps user may read.
+func campaignJournalEntries(db *sql.DB, campaignID int64, user *models.User) ([]*models.JournalEntry, error) {
+	games, err := database.GetGamesForCampaign(db, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	recaps, err := database.GetCampaignRecaps(db, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*models.JournalEntry
+	now := time.Now()
+	for _, game := range games {
+		if !game.HasHappened(now) {
+			continue
+		}
+		entry := &models.JournalEntry{Game: game}
+		if entry.CanRead, err = canAccessNote(db, game, models.NoteKindRecap, user); err != nil {
+			return nil, err
+		}
+		if entry.CanRead {
+			entry.Recap = recaps[game.ID]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestSessionNotesAndJournal(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	gmClient, gm := ts.newUserClient(t, "notes_gm@example.com", "gmpass")
+	playerClient, player := ts.newUserClient(t, "notes_player@example.com", "password")
+	outsiderClient, _ := ts.newUserClient(t, "notes_outsider@example.com", "password")
+
+	campaign, _ := database.GetOrCreateCampaign(ts.db, gm.ID, "Journal Campaign")
+	var sessions []*models.Game
+	for i, title := range []string{"Chapter One", "Chapter Two"} {
+		game, err := database.CreateGame(ts.db, &models.Game{GMID: gm.ID, Title: title, GameDateTime: time.Now().Add(time.Duration(i-2) * 24 * time.Hour), Location: "Table", CampaignID: campaign.ID})
+		if err != nil {
+			t.Fatalf("CreateGame() error = %v", err)
+		}
+		sessions = append(sessions, game)
+	}
+	if err := database.CreateOrUpdateRSVP(ts.db, &models.RSVP{GameID: sessions[0].ID, UserID: player.ID, Status: models.RSVPStatusAttending}); err != nil {
+		t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+	}
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(sessions[0].ID, 10)
+
+	status, body := postForm(t, gmClient, gameURL+"/notes/gm_prep", url.Values{"content": {"The **lich** is behind it all."}, "revision": {"0"}})
+	if status != http.StatusOK || !strings.Contains(body, "Saved") || !strings.Contains(body, "<strong>lich</strong>") {
+		t.Fatalf("GM prep save status = %d, body: %s", status, body)
+	}
+	if status, _ := postForm(t, playerClient, gameURL+"/notes/gm_prep", url.Values{"content": {"peek"}}); status != http.StatusForbidden {
+		t.Errorf("player prep save status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status, _ := getBody(t, playerClient, gameURL+"/notes/gm_prep/history"); status != http.StatusForbidden {
+		t.Errorf("player prep history status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	// The player writes the recap; the GM's editor, still on revision 0, must not clobber it.
+	status, body = postForm(t, playerClient, gameURL+"/notes/recap", url.Values{"content": {"We found the phylactery."}, "revision": {"0"}})
+	if status != http.StatusOK || !strings.Contains(body, "Saved") {
+		t.Fatalf("player recap save status = %d, body: %s", status, body)
+	}
+	if _, body := postForm(t, gmClient, gameURL+"/notes/recap", url.Values{"content": {"Stale"}, "revision": {"0"}}); !strings.Contains(body, "Someone else changed these notes") {
+		t.Errorf("stale save should report a conflict: %s", body)
+	}
+	if status, _ := postForm(t, outsiderClient, gameURL+"/notes/recap", url.Values{"content": {"Vandalism"}}); status != http.StatusForbidden {
+		t.Errorf("outsider recap save status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	_, body = getBody(t, playerClient, gameURL)
+	if !strings.Contains(body, "We found the phylactery.") || strings.Contains(body, "lich") {
+		t.Errorf("player game page should show the recap but not the prep notes: %s", body)
+	}
+	_, body = getBody(t, gmClient, gameURL)
+	if !strings.Contains(body, "We found the phylactery.") || !strings.Contains(body, "<strong>lich</strong>") {
+		t.Errorf("GM game page should show recap and prep notes: %s", body)
+	}
+	if _, body := getBody(t, outsiderClient, gameURL); strings.Contains(body, "phylactery") {
+		t.Errorf("outsider game page should not show the recap")
+	}
+
+	_, body = getBody(t, gmClient, gameURL+"/notes/recap/history")
+	if !strings.Contains(body, "notes_player@example.com") || !strings.Contains(body, "We found the phylactery.") {
+		t.Errorf("recap history missing the player's revision: %s", body)
+	}
+
+	journalURL := ts.server.URL + "/campaigns/" + strconv.FormatInt(campaign.ID, 10) + "/journal"
+	_, body = getBody(t, playerClient, journalURL)
+	if !strings.Contains(body, "Chapter One") || !strings.Contains(body, "We found the phylactery.") {
+		t.Errorf("journal missing the recap: %s", body)
+	}
+	if !strings.Contains(body, "Chapter Two") || !strings.Contains(body, "Recaps are visible to the session") {
+		t.Errorf("journal should list sessions the player missed without their recap: %s", body)
+	}
+	if strings.Index(body, "Chapter One") > strings.Index(body, "Chapter Two") {
+		t.Errorf("journal should list sessions oldest first")
+	}
+	if _, body := getBody(t, outsiderClient, journalURL); strings.Contains(body, "phylactery") {
+		t.Errorf("outsider journal should not show recaps")
+	}
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// staffSectionData builds the template data for _staff_section.html: the owner, the
+// co-GMs and any pending ownership transfer. It returns nil if user is neither a GM
+// of the game nor the recipient of a transfer, who don't see the section.
+func staffSectionData(db *sql.DB, game *models.Game, user *models.User) (map[string]interface{}, error) {
+	if user == nil {
+		return nil, nil
+	}
+	isRecipient := game.HasPendingTransfer() && game.TransferToID == user.ID
+	if !game.IsGM(user.ID) && !isRecipient {
+		return nil, nil
+	}
+	owner, err := database.GetUserByID(db, game.GMID)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"Game":                game,
+		"User":                user,
+		"Owner":               owner,
+		"Staff":               game.Staff,
+		"IsOwner":             game.IsOwner(user.ID),
+		"IsTransferRecipient": isRecipient,
+	}
+	if game.HasPendingTransfer() {
+		recipient, err := database.GetUserByID(db, game.TransferToID)
+		if err != nil {
+			return nil, err
+		}
+		data["TransferTo"] = recipient
+	}
+	return data, nil
+}
+
+// renderStaffSection re-renders the staff partial after a change, with an optional
+// error message. game must be freshly loaded so its staff and transfer are current.
+func renderStaffSection(w http.ResponseWriter, db *sql.DB, game *models.Game, user *models.User, errMsg string) {
+	data, err := staffSectionData(db, game, user)
+	if err != nil {
+		fmt.Printf("Error loading staff for game %d: %v\n", game.ID, err)
+		http.Error(w, "Failed to refresh the game's GMs.", http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		// The user just stepped down or declined, so the section is gone for them.
+		data = map[string]interface{}{"Game": game}
+	}
+	data["Error"] = errMsg
+	RenderTemplate(w, "games/_staff_section.html", data)
+}
+
+// reloadAndRenderStaffSection reloads the game after a staff change and re-renders the partial.
+func reloadAndRenderStaffSection(w http.ResponseWriter, db *sql.DB, gameID int64, user *models.User) {
+	game, err := database.GetGameByID(db, gameID)
+	if err != nil {
+		fmt.Printf("Error reloading game %d: %v\n", gameID, err)
+		http.Error(w, "Failed to refresh the game's GMs.", http.StatusInternalServerError)
+		return
+	}
+	renderStaffSection(w, db, game, user, "")
+}
+
+// gameForOwner is gameForGM for actions only the game's owner may take.
+func gameForOwner(w http.ResponseWriter, r *http.Request, db *sql.DB, action string) (*models.Game, *models.User, bool) {
+	game, currentUser, ok := gameForGM(w, r, db, action)
+	if !ok {
+		return nil, nil, false
+	}
+	if !game.IsOwner(currentUser.ID) {
+		http.Error(w, "Only the game's owner can "+action+".", http.StatusForbidden)
+		return nil, nil, false
+	}
+	return game, currentUser, true
+}
+
+// userFromEmailField looks up the user named in the form's "email" field. It returns
+// a message for the user if there is none.
+func userFromEmailField(r *http.Request, db *sql.DB) (*models.User, string) {
+	email := strings.TrimSpace(r.FormValue("email"))
+	if email == "" {
+		return nil, "Enter the email address of a registered user."
+	}
+	user, err := database.GetUserByEmail(db, email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error looking up user %q: %v\n", email, err)
+		}
+		return nil, "No user is registered with " + email + "."
+	}
+	return user, ""
+}
+
+// notifyStaffChange sends a game_staff notification linking to the game's staff section.
+func notifyStaffChange(db *sql.DB, game *models.Game, userID int64, message string) {
+	_, err := database.CreateNotification(db, &models.Notification{
+		UserID:  userID,
+		Kind:    models.NotificationKindGameStaff,
+		Message: message,
+		Link:    fmt.Sprintf("/games/%d#staff-section", game.ID),
+	})
+	if err != nil {
+		fmt.Printf("Error notifying user %d about staff of game %d: %v\n", userID, game.ID, err)
+	}
+}
+
+// AddCoGM makes another user a co-GM of the game: POST /games/{id}/staff with their email.
+// Only the owner manages staff. This handler should be wrapped by AuthMiddleware.
+func AddCoGM(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForOwner(w, r, db, "manage its GMs")
+		if !ok {
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+		coGM, errMsg := userFromEmailField(r, db)
+		if errMsg != "" {
+			renderStaffSection(w, db, game, currentUser, errMsg)
+			return
+		}
+		err := database.AddGameStaff(db, game.ID, coGM.ID, currentUser.ID)
+		if err == database.ErrAlreadyStaff {
+			renderStaffSection(w, db, game, currentUser, coGM.Email+" already runs this game.")
+			return
+		} else if err != nil {
+			fmt.Printf("Error adding co-GM %d to game %d: %v\n", coGM.ID, game.ID, err)
+			http.Error(w, "Failed to add the co-GM. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		notifyStaffChange(db, game, coGM.ID, fmt.Sprintf("%s made you a co-GM of %s", currentUser.DisplayName(), game.Title))
+		reloadAndRenderStaffSection(w, db, game.ID, currentUser)
+	}
+}
+
+// RemoveCoGM takes a co-GM off the game: POST /games/{id}/staff/{userID}/remove.
+// The owner can remove anyone; co-GMs can only step down themselves.
+// This handler should be wrapped by AuthMiddleware.
+func RemoveCoGM(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForGM(w, r, db, "manage its GMs")
+		if !ok {
+			return
+		}
+		userID, err := pathInt64(r, "/games/", 2)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+		if !game.IsOwner(currentUser.ID) && userID != currentUser.ID {
+			http.Error(w, "Only the game's owner can remove other GMs.", http.StatusForbidden)
+			return
+		}
+		if game.RoleOf(userID) != models.GameRoleCoGM {
+			renderStaffSection(w, db, game, currentUser, "That user is not a co-GM of this game.")
+			return
+		}
+		if err := database.RemoveGameStaff(db, game.ID, userID); err != nil {
+			fmt.Printf("Error removing co-GM %d from game %d: %v\n", userID, game.ID, err)
+			http.Error(w, "Failed to remove the co-GM. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		if userID == currentUser.ID {
+			notifyStaffChange(db, game, game.GMID, fmt.Sprintf("%s stepped down as co-GM of %s", currentUser.DisplayName(), game.Title))
+		} else {
+			notifyStaffChange(db, game, userID, fmt.Sprintf("%s removed you as co-GM of %s", currentUser.DisplayName(), game.Title))
+		}
+		reloadAndRenderStaffSection(w, db, game.ID, currentUser)
+	}
+}
+
+// OfferGameTransfer offers ownership of the game to another user: POST /games/{id}/transfer
+// with their email. Nothing changes until they accept. This handler should be wrapped by AuthMiddleware.
+func OfferGameTransfer(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForOwner(w, r, db, "hand it over")
+		if !ok {
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form data", http.StatusBadRequest)
+			return
+		}
+		recipient, errMsg := userFromEmailField(r, db)
+		if errMsg == "" && recipient.ID == currentUser.ID {
+			errMsg = "You already own this game."
+		}
+		if errMsg != "" {
+			renderStaffSection(w, db, game, currentUser, errMsg)
+			return
+		}
+		if err := database.SetGameTransfer(db, game.ID, recipient.ID); err != nil {
+			fmt.Printf("Error offering game %d to user %d: %v\n", game.ID, recipient.ID, err)
+			http.Error(w, "Failed to offer the game. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		notifyStaffChange(db, game, recipient.ID, fmt.Sprintf("%s wants to hand %s over to you", currentUser.DisplayName(), game.Title))
+		reloadAndRenderStaffSection(w, db, game.ID, currentUser)
+	}
+}
+
+// AcceptGameTransfer makes the current user the owner of a game offered to them:
+// POST /games/{id}/transfer/accept. The previous owner stays on as a co-GM.
+// This handler should be wrapped by AuthMiddleware.
+func AcceptGameTransfer(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForUser(w, r, db)
+		if !ok {
+			return
+		}
+		previousOwnerID, err := database.AcceptGameTransfer(db, game.ID, currentUser.ID)
+		if err == database.ErrNoTransfer {
+			http.Error(w, "This game has not been offered to you.", http.StatusForbidden)
+			return
+		} else if err != nil {
+			fmt.Printf("Error transferring game %d to user %d: %v\n", game.ID, currentUser.ID, err)
+			http.Error(w, "Failed to take over the game. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		notifyStaffChange(db, game, previousOwnerID, fmt.Sprintf("%s accepted %s; you are now a co-GM", currentUser.DisplayName(), game.Title))
+		reloadAndRenderStaffSection(w, db, game.ID, currentUser)
+	}
+}
+
+// CancelGameTransfer withdraws a pending transfer (by the owner) or declines it (by the
+// recipient): POST /games/{id}/transfer/cancel. This handler should be wrapped by AuthMiddleware.
+func CancelGameTransfer(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, currentUser, ok := gameForUser(w, r, db)
+		if !ok {
+			return
+		}
+		isRecipient := game.HasPendingTransfer() && game.TransferToID == currentUser.ID
+		if !game.IsOwner(currentUser.ID) && !isRecipient {
+			http.Error(w, "Only the game's owner or the person it was offered to can cancel a transfer.", http.StatusForbidden)
+			return
+		}
+		if !game.HasPendingTransfer() {
+			renderStaffSection(w, db, game, currentUser, "There is no pending transfer.")
+			return
+		}
+		if err := database.SetGameTransfer(db, game.ID, 0); err != nil {
+			fmt.Printf("Error cancelling transfer of game %d: %v\n", game.ID, err)
+			http.Error(w, "Failed to cancel the transfer. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		if isRecipient {
+			notifyStaffChange(db, game, game.GMID, fmt.Sprintf("%s declined to take over %s", currentUser.DisplayName(), game.Title))
+		} else {
+			notifyStaffChange(db, game, game.TransferToID, fmt.Sprintf("%s withdrew the offer of %s", currentUser.DisplayName(), game.Title))
+		}
+		reloadAndRenderStaffSection(w, db, game.ID, currentUser)
+	}
+}
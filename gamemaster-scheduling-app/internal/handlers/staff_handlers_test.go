@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestCoGMsAndTransfer(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	ownerClient, owner := ts.newUserClient(t, "staff_owner@example.com", "gmpass")
+	coClient, coGM := ts.newUserClient(t, "staff_co@example.com", "password")
+	playerClient, player := ts.newUserClient(t, "staff_player@example.com", "password")
+
+	game := ts.createTestGameDirectly(t, owner.ID, "Shared Table")
+	if _, err := ts.db.Exec("UPDATE games SET requires_approval = 1 WHERE id = ?", game.ID); err != nil {
+		t.Fatalf("enabling approval: %v", err)
+	}
+	gameURL := ts.server.URL + "/games/" + strconv.FormatInt(game.ID, 10)
+
+	// Only the owner manages staff, and co-GMs must be registered.
+	if status, _ := postForm(t, playerClient, gameURL+"/staff", url.Values{"email": {player.Email}}); status != http.StatusForbidden {
+		t.Errorf("player adding a co-GM status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body := postForm(t, ownerClient, gameURL+"/staff", url.Values{"email": {"nobody@example.com"}})
+	if !strings.Contains(body, "No user is registered with nobody@example.com") {
+		t.Errorf("unknown co-GM was not refused: %s", body)
+	}
+	_, body = postForm(t, ownerClient, gameURL+"/staff", url.Values{"email": {coGM.Email}})
+	if !strings.Contains(body, "staff_co@example.com</strong> &mdash; co-GM") {
+		t.Errorf("staff section missing the new co-GM: %s", body)
+	}
+	_, body = postForm(t, ownerClient, gameURL+"/staff", url.Values{"email": {coGM.Email}})
+	if !strings.Contains(body, "already runs this game") {
+		t.Errorf("adding a co-GM twice was not refused: %s", body)
+	}
+	if notes, _ := database.GetNotificationsForUser(ts.db, coGM.ID, 10); len(notes) != 1 || notes[0].Kind != models.NotificationKindGameStaff {
+		t.Errorf("co-GM notifications = %v, want one", notes)
+	}
+
+	// The co-GM gets the GM's rights: the approval queue, reviews and moderation.
+	postForm(t, playerClient, gameURL+"/rsvp", url.Values{"status": {"attending"}})
+	if notes, _ := database.GetNotificationsForUser(ts.db, coGM.ID, 10); len(notes) != 2 || notes[0].Kind != models.NotificationKindRSVPRequest {
+		t.Errorf("co-GM was not told about the join request: %v", notes)
+	}
+	_, body = getBody(t, coClient, gameURL)
+	if !strings.Contains(body, "Waiting for Approval") || !strings.Contains(body, `id="staff-section"`) {
+		t.Errorf("co-GM's game page is missing the approval queue or staff section")
+	}
+	rsvp, _ := database.GetRSVPByUserForGame(ts.db, player.ID, game.ID)
+	postForm(t, coClient, gameURL+"/rsvps/"+strconv.FormatInt(rsvp.ID, 10)+"/approve", nil)
+	if r, _ := database.GetRSVPByID(ts.db, rsvp.ID); r.Status != models.RSVPStatusAttending || r.ReviewedBy != coGM.ID {
+		t.Errorf("co-GM approval = %+v", r)
+	}
+	if status, _ := getBody(t, coClient, gameURL+"/rsvps/history"); status != http.StatusOK {
+		t.Errorf("co-GM RSVP history status = %d, want %d", status, http.StatusOK)
+	}
+	if status, _ := postForm(t, playerClient, gameURL+"/chat/mute", url.Values{"user_id": {strconv.FormatInt(coGM.ID, 10)}}); status != http.StatusForbidden {
+		t.Errorf("player muting status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body = postForm(t, ownerClient, gameURL+"/chat/mute", url.Values{"user_id": {strconv.FormatInt(coGM.ID, 10)}})
+	if !strings.Contains(body, "You cannot mute a GM of this game.") {
+		t.Errorf("owner could mute a co-GM: %s", body)
+	}
+	_, body = getBody(t, playerClient, gameURL)
+	if strings.Contains(body, `id="staff-section"`) {
+		t.Errorf("players should not see the staff section")
+	}
+
+	// Co-GMs can't hand the game over; the owner can, but only on acceptance.
+	if status, _ := postForm(t, coClient, gameURL+"/transfer", url.Values{"email": {coGM.Email}}); status != http.StatusForbidden {
+		t.Errorf("co-GM offering the game status = %d, want %d", status, http.StatusForbidden)
+	}
+	_, body = postForm(t, ownerClient, gameURL+"/transfer", url.Values{"email": {coGM.Email}})
+	if !strings.Contains(body, "Waiting for staff_co@example.com to accept") {
+		t.Errorf("pending transfer not shown: %s", body)
+	}
+	if status, _ := postForm(t, playerClient, gameURL+"/transfer/accept", nil); status != http.StatusForbidden {
+		t.Errorf("accepting someone else's transfer status = %d, want %d", status, http.StatusForbidden)
+	}
+	if g, _ := database.GetGameByID(ts.db, game.ID); g.GMID != owner.ID {
+		t.Fatalf("game changed hands before acceptance: %+v", g)
+	}
+	_, body = postForm(t, coClient, gameURL+"/transfer/accept", nil)
+	if !strings.Contains(body, "staff_co@example.com</strong> &mdash; owner") || !strings.Contains(body, "staff_owner@example.com</strong> &mdash; co-GM") {
+		t.Errorf("accepted transfer not reflected: %s", body)
+	}
+	g, _ := database.GetGameByID(ts.db, game.ID)
+	if g.GMID != coGM.ID || !g.IsGM(owner.ID) || g.IsOwner(owner.ID) || g.HasPendingTransfer() {
+		t.Errorf("game after transfer = %+v", g)
+	}
+
+	// The previous owner, now a co-GM, can step down but not remove others.
+	ownerID := strconv.FormatInt(owner.ID, 10)
+	postForm(t, ownerClient, gameURL+"/staff", url.Values{"email": {player.Email}})
+	if g, _ := database.GetGameByID(ts.db, game.ID); g.IsGM(player.ID) {
+		t.Errorf("a co-GM could add staff")
+	}
+	postForm(t, ownerClient, gameURL+"/staff/"+ownerID+"/remove", nil)
+	if g, _ := database.GetGameByID(ts.db, game.ID); g.IsGM(owner.ID) {
+		t.Errorf("previous owner could not step down")
+	}
+	if status, _ := postForm(t, ownerClient, gameURL+"/rsvps/lock", nil); status != http.StatusForbidden {
+		t.Errorf("former GM status = %d, want %d", status, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// UserProfilePage displays a user's public profile: GET /users/{id}.
+// On their own profile, users can also set the username others @mention them by.
+func UserProfilePage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := pathInt64(r, "/users/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid user ID format.")
+			return
+		}
+		currentUser, _ := GetCurrentUser(r, db) // Profiles are public; template handles nil user
+		renderUserProfile(w, r, db, userID, currentUser, "")
+	}
+}
+
+// UpdateUsername sets or clears the current user's username: POST /users/{id}/username.
+// Users can only change their own username. This handler should be wrapped by AuthMiddleware.
+func UpdateUsername(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		userID, err := pathInt64(r, "/users/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid user ID format.")
+			return
+		}
+		if userID != currentUser.ID {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "You can only change your own username.")
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		username := strings.TrimPrefix(strings.TrimSpace(r.FormValue("username")), "@")
+		if username != "" && !models.ValidUsername(username) {
+			renderUserProfile(w, r, db, userID, currentUser, "Usernames are 3-30 letters, digits or underscores.")
+			return
+		}
+
+		err = database.SetUsername(db, currentUser.ID, username)
+		if err == database.ErrUsernameTaken {
+			renderUserProfile(w, r, db, userID, currentUser, "That username is already taken.")
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error setting username for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to update username. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", currentUser.ID), http.StatusSeeOther)
+	}
+}
+
+// UpdatePreferredSystems saves the game systems the current user likes to play:
+// POST /users/{id}/systems with a system_id value per checked system. Users can only
+// change their own. This handler should be wrapped by AuthMiddleware.
+func UpdatePreferredSystems(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		userID, err := pathInt64(r, "/users/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid user ID format.")
+			return
+		}
+		if userID != currentUser.ID {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "You can only change your own game systems.")
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		var systemIDs []int64
+		for _, v := range r.Form["system_id"] {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				renderUserProfile(w, r, db, userID, currentUser, "Choose game systems from the list.")
+				return
+			}
+			systemIDs = append(systemIDs, id)
+		}
+
+		if err := database.SetPreferredSystems(db, currentUser.ID, systemIDs); err != nil {
+			fmt.Printf("Error setting preferred systems for user %d: %v\n", currentUser.ID, err)
+			http.Error(w, "Failed to save your game systems. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", currentUser.ID), http.StatusSeeOther)
+	}
+}
+
+// renderUserProfile renders the profile page for userID, with an optional form error.
+func renderUserProfile(w http.ResponseWriter, r *http.Request, db *sql.DB, userID int64, currentUser *models.User, errMsg string) {
+	profileUser, err := database.GetUserByID(db, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "User not found.")
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	hostedGames, err := database.GetGamesByGM(db, userID)
+	if err != nil {
+		// Log this error but don't fail the whole page load
+		fmt.Printf("Error fetching games hosted by user %d: %v\n", userID, err)
+	}
+
+	stats, err := database.GetPlayerStats(db, userID)
+	if err != nil {
+		fmt.Printf("Error fetching attendance stats for user %d: %v\n", userID, err)
+	}
+
+	characters, err := database.GetCharactersForUser(db, userID)
+	if err != nil {
+		fmt.Printf("Error fetching characters for user %d: %v\n", userID, err)
+	}
+
+	preferredSystems, err := database.GetPreferredSystems(db, userID)
+	if err != nil {
+		fmt.Printf("Error fetching preferred systems for user %d: %v\n", userID, err)
+	}
+
+	gmRatings, err := database.GetGMRatings(db, userID)
+	if err != nil {
+		fmt.Printf("Error fetching GM ratings for user %d: %v\n", userID, err)
+	}
+
+	isBlocked := false
+	if currentUser != nil && currentUser.ID != profileUser.ID {
+		isBlocked, err = database.IsUserBlocked(db, currentUser.ID, profileUser.ID)
+		if err != nil {
+			fmt.Printf("Error checking block of user %d by user %d: %v\n", userID, currentUser.ID, err)
+		}
+	}
+
+	data := map[string]interface{}{
+		"Title":       profileUser.DisplayName(),
+		"Stats":       stats,
+		"User":        currentUser,
+		"ProfileUser": profileUser,
+		"IsOwn":       currentUser != nil && currentUser.ID == profileUser.ID,
+		"IsBlocked":   isBlocked,
+		"HostedGames": hostedGames,
+		"GMRatings":   gmRatings,
+		"Threshold":   models.FeedbackPublicThreshold,
+		"Characters":  characters,
+		"Systems":     preferredSystems,
+		"Error":       errMsg,
+	}
+	// On their own profile, users pick their systems from the full list.
+	if currentUser != nil && currentUser.ID == profileUser.ID {
+		systems, err := database.GetGameSystems(db)
+		if err != nil {
+			fmt.Printf("Error fetching game systems: %v\n", err)
+		}
+		chosen := make(map[int64]bool)
+		for _, s := range preferredSystems {
+			chosen[s.ID] = true
+		}
+		data["AllSystems"], data["ChosenSystems"] = systems, chosen
+	}
+	RenderTemplate(w, "users/profile.html", data)
+}
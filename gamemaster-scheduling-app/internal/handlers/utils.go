@@ -1,20 +1,63 @@
 package handlers
 
 import (
+	"database/sql"
 	"fmt"
 	"html/template"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/markdown"
+	"github.com/gamemaster-scheduling/app/internal/models"
 )
 
 // Template helper functions
 var funcMap = template.FuncMap{
 	"FormatDateTime": FormatDateTime,
-	"Nl2br":          Nl2br,
+	"Markdown":       Markdown,
 	"TitleCase":      TitleCase,
+	"default":        Default,
+	"dict":           Dict,
+}
+
+// Dict builds a map from alternating keys and values, so a template can pass
+// several values to a sub-template: {{template "chat_message" (dict "Message" . "User" $.User)}}
+func Dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict needs an even number of arguments, got %d", len(pairs))
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// Default returns value unless it is empty (nil, "", 0), in which case it returns def.
+// Used as a pipeline: {{.Title | default "Fallback"}}
+func Default(def interface{}, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return def
+	case string:
+		if v == "" {
+			return def
+		}
+	case int:
+		if v == 0 {
+			return def
+		}
+	}
+	return value
 }
 
 // TitleCase converts a string to title case.
@@ -34,11 +77,34 @@ func FormatDateTime(t time.Time) string {
 	return t.Format("January 2, 2006 at 3:04 PM")
 }
 
-// Nl2br replaces newline characters with <br> tags.
-func Nl2br(s string) template.HTML {
-	return template.HTML(strings.ReplaceAll(s, "\n", "<br>"))
+// Markdown renders user-written text (game descriptions, chat) as sanitized HTML;
+// see package markdown for the supported syntax. Pass a chat message's Mentions to
+// link its @mentions to the mentioned users' profiles:
+// {{Markdown .MessageContent .Mentions}}
+func Markdown(s string, mentions ...[]models.Mention) template.HTML {
+	var opts markdown.Options
+	if len(mentions) > 0 {
+		opts.Mention = func(username string) (string, bool) {
+			for _, m := range mentions[0] {
+				if strings.EqualFold(m.Username, username) {
+					return fmt.Sprintf("/users/%d", m.UserID), true
+				}
+			}
+			return "", false
+		}
+	}
+	return markdown.Render(s, opts)
 }
 
+// pathInt64 parses the numeric path segment at index after prefix.
+// e.g. pathInt64(r, "/games/", 2) returns 7 for "/games/3/chat/7/edit".
+func pathInt64(r *http.Request, prefix string, index int) (int64, error) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if index >= len(parts) || parts[index] == "" {
+		return 0, fmt.Errorf("path segment %d missing in %s", index, r.URL.Path)
+	}
+	return strconv.ParseInt(parts[index], 10, 64)
+}
 
 // templates holds all parsed templates.
 // The key is the template name relative to the templates directory
@@ -71,12 +137,19 @@ func LoadTemplates(dir string) error {
 			return
 		}
 
-		// Find all page template files (excluding layout and partials)
-		allFiles, err := filepath.Glob(filepath.Join(dir, "**/*.html"))
+		// Find all page template files (excluding layout and partials).
+		// Top-level pages such as error.html sit next to layout.html, so glob both levels.
+		rootFiles, err := filepath.Glob(filepath.Join(dir, "*.html"))
+		if err != nil {
+			loadErr = fmt.Errorf("error globbing root templates: %w", err)
+			return
+		}
+		nestedFiles, err := filepath.Glob(filepath.Join(dir, "**/*.html"))
 		if err != nil {
 			loadErr = fmt.Errorf("error globbing all templates: %w", err)
 			return
 		}
+		allFiles := append(rootFiles, nestedFiles...)
 
 		pageFiles := []string{}
 		for _, file := range allFiles {
@@ -122,7 +195,8 @@ func LoadTemplates(dir string) error {
 			// fmt.Printf("Loaded page template: %s\n", name)
 		}
 
-		// Parse partial templates standalone (they don't use the main layout)
+		// Parse partial templates standalone (they don't use the main layout).
+		// Each is parsed together with the other partials so partials can include one another.
 		for _, partialFile := range partialFiles {
 			name := strings.TrimPrefix(partialFile, dir+string(filepath.Separator))
 			name = filepath.ToSlash(name) // Use relative path as template name
@@ -132,7 +206,13 @@ func LoadTemplates(dir string) error {
 			// template.New() here should ideally use the base name if ExecuteTemplate is to be called with it.
 			// Or, the template name for Execute() should be the relative path.
 			// Let's assume templates[name].Execute(w, data) will execute the primary definition in the partial.
-			tmpl, parseErr := template.New(name).Funcs(funcMap).ParseFiles(partialFile)
+			filesToParse := []string{partialFile}
+			for _, other := range partialFiles {
+				if other != partialFile {
+					filesToParse = append(filesToParse, other)
+				}
+			}
+			tmpl, parseErr := template.New(name).Funcs(funcMap).ParseFiles(filesToParse...)
 			if parseErr != nil {
 				loadErr = fmt.Errorf("error parsing partial template %s: %w", name, parseErr)
 				return
@@ -203,10 +283,10 @@ func RenderTemplate(w http.ResponseWriter, name string, data interface{}) {
 		return
 	}
 
-	// For full page templates, Execute() will render the template named after the page file (e.g. "auth/login.html"),
-	// which then calls {{template "layout" .}}.
-	// For partials, Execute() will render the primary template defined in that partial file.
-	err := tmpl.Execute(w, data)
+	// ParseFiles names each file's template after its base name, so the entry point for
+	// "auth/login.html" is "login.html". For full pages that template calls {{template "layout" .}};
+	// for partials it is the partial's own body.
+	err := tmpl.ExecuteTemplate(w, filepath.Base(name), data)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error executing template %s: %s", name, err.Error()), http.StatusInternalServerError)
 	}
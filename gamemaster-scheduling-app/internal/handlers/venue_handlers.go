@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// maxVenueNameLength caps the names of venues and virtual tables.
+const maxVenueNameLength = 100
+
+// venueFromForm reads and validates the venue form fields. It returns a message
+// for the user if the input is invalid.
+func venueFromForm(r *http.Request) (*models.Venue, string) {
+	v := &models.Venue{
+		Name:          strings.TrimSpace(r.FormValue("name")),
+		Address:       strings.TrimSpace(r.FormValue("address")),
+		Accessibility: strings.TrimSpace(r.FormValue("accessibility")),
+		HouseRules:    strings.TrimSpace(r.FormValue("house_rules")),
+		HostContact:   strings.TrimSpace(r.FormValue("host_contact")),
+	}
+	if v.Name == "" || utf8.RuneCountInString(v.Name) > maxVenueNameLength {
+		return v, fmt.Sprintf("Give the venue a name of at most %d characters.", maxVenueNameLength)
+	}
+	if v.Address == "" {
+		return v, "Every venue needs an address."
+	}
+	for _, text := range []string{v.Address, v.Accessibility, v.HouseRules, v.HostContact} {
+		if utf8.RuneCountInString(text) > models.MaxVenueTextLength {
+			return v, fmt.Sprintf("Each detail can be at most %d characters.", models.MaxVenueTextLength)
+		}
+	}
+	if capacity := strings.TrimSpace(r.FormValue("capacity")); capacity != "" {
+		n, err := strconv.Atoi(capacity)
+		if err != nil || n < 1 {
+			return v, "The capacity must be a number of people, or empty if you don't know."
+		}
+		v.Capacity = n
+	}
+	return v, ""
+}
+
+// virtualTableFromForm reads and validates the virtual table form fields. It
+// returns a message for the user if the input is invalid.
+func virtualTableFromForm(r *http.Request) (*models.VirtualTable, string) {
+	t := &models.VirtualTable{
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		VTTURL:   strings.TrimSpace(r.FormValue("vtt_url")),
+		VoiceURL: strings.TrimSpace(r.FormValue("voice_url")),
+		Notes:    strings.TrimSpace(r.FormValue("notes")),
+	}
+	if t.Name == "" || utf8.RuneCountInString(t.Name) > maxVenueNameLength {
+		return t, fmt.Sprintf("Give the virtual table a name of at most %d characters.", maxVenueNameLength)
+	}
+	if t.VTTURL == "" && t.VoiceURL == "" {
+		return t, "Add a virtual tabletop link, a voice link, or both."
+	}
+	for _, link := range []string{t.VTTURL, t.VoiceURL} {
+		if link == "" {
+			continue
+		}
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return t, "Links must be http(s) URLs."
+		}
+	}
+	if utf8.RuneCountInString(t.Notes) > models.MaxVenueTextLength {
+		return t, fmt.Sprintf("Notes can be at most %d characters.", models.MaxVenueTextLength)
+	}
+	return t, ""
+}
+
+// VenuesPage lists the venues, with a form to add one: GET /venues.
+func VenuesPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, _ := GetCurrentUser(r, db) // Template handles nil user
+		renderVenuesPage(w, r, db, currentUser, nil, "")
+	}
+}
+
+func renderVenuesPage(w http.ResponseWriter, r *http.Request, db *sql.DB, currentUser *models.User, form *models.Venue, errMsg string) {
+	venues, err := database.GetVenues(db)
+	if err != nil {
+		fmt.Printf("Error fetching venues: %v\n", err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load venues.")
+		return
+	}
+	if form == nil {
+		form = &models.Venue{}
+	}
+	RenderTemplate(w, "venues/venues.html", map[string]interface{}{
+		"Title":  "Venues",
+		"User":   currentUser,
+		"Venues": venues,
+		"Form":   form,
+		"Error":  errMsg,
+	})
+}
+
+// CreateVenue adds a venue owned by the current user: POST /venues.
+// This handler should be wrapped by AuthMiddleware.
+func CreateVenue(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		venue, errMsg := venueFromForm(r)
+		if errMsg != "" {
+			renderVenuesPage(w, r, db, currentUser, venue, errMsg)
+			return
+		}
+		venue.OwnerID = currentUser.ID
+		if err := database.CreateVenue(db, venue); err != nil {
+			fmt.Printf("Error creating venue: %v\n", err)
+			http.Error(w, "Failed to add the venue. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/venues/%d", venue.ID), http.StatusSeeOther)
+	}
+}
+
+// venueFromPath loads the venue /venues/{id}, rendering an error page if the ID
+// is invalid or there is no such venue.
+func venueFromPath(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.Venue, bool) {
+	venueID, err := pathInt64(r, "/venues/", 0)
+	if err != nil {
+		RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid venue ID format.")
+		return nil, false
+	}
+	venue, err := database.GetVenueByID(db, venueID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Venue not found.")
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return venue, true
+}
+
+// VenuePage shows a venue and its upcoming games, flagging games with more seats
+// than the venue holds: GET /venues/{id}. Its owner gets a form to edit it.
+func VenuePage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		venue, ok := venueFromPath(w, r, db)
+		if !ok {
+			return
+		}
+		currentUser, _ := GetCurrentUser(r, db) // Template handles nil user
+		renderVenuePage(w, r, db, venue, currentUser, venue, "")
+	}
+}
+
+func renderVenuePage(w http.ResponseWriter, r *http.Request, db *sql.DB, venue *models.Venue, currentUser *models.User, form *models.Venue, errMsg string) {
+	games, err := database.GetUpcomingGamesAtVenue(db, venue.ID, time.Now())
+	if err != nil {
+		fmt.Printf("Error fetching games at venue %d: %v\n", venue.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load the venue.")
+		return
+	}
+	RenderTemplate(w, "venues/venue.html", map[string]interface{}{
+		"Title":   venue.Name,
+		"User":    currentUser,
+		"Venue":   venue,
+		"Games":   games,
+		"IsOwner": currentUser != nil && currentUser.ID == venue.OwnerID,
+		"Form":    form,
+		"Error":   errMsg,
+	})
+}
+
+// UpdateVenue saves changes to a venue: POST /venues/{id}. Only its owner can
+// edit it. This handler should be wrapped by AuthMiddleware.
+func UpdateVenue(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		venue, ok := venueFromPath(w, r, db)
+		if !ok {
+			return
+		}
+		if venue.OwnerID != currentUser.ID {
+			RenderErrorPage(w, r, db, http.StatusForbidden, "Forbidden", "Only the venue's owner can edit it.")
+			return
+		}
+		updated, errMsg := venueFromForm(r)
+		if errMsg != "" {
+			renderVenuePage(w, r, db, venue, currentUser, updated, errMsg)
+			return
+		}
+		updated.ID, updated.OwnerID = venue.ID, venue.OwnerID
+		if err := database.UpdateVenue(db, updated); err != nil {
+			fmt.Printf("Error updating venue %d: %v\n", venue.ID, err)
+			http.Error(w, "Failed to save the venue. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/venues/%d", venue.ID), http.StatusSeeOther)
+	}
+}
+
+// VirtualTablesPage lists the current user's virtual tables, with forms to add
+// and edit them: GET /virtual-tables. This handler should be wrapped by AuthMiddleware.
+func VirtualTablesPage(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		renderVirtualTablesPage(w, r, db, currentUser, nil, "")
+	}
+}
+
+func renderVirtualTablesPage(w http.ResponseWriter, r *http.Request, db *sql.DB, currentUser *models.User, form *models.VirtualTable, errMsg string) {
+	tables, err := database.GetVirtualTablesByOwner(db, currentUser.ID)
+	if err != nil {
+		fmt.Printf("Error fetching virtual tables of user %d: %v\n", currentUser.ID, err)
+		RenderErrorPage(w, r, db, http.StatusInternalServerError, "Server Error", "Could not load your virtual tables.")
+		return
+	}
+	if form == nil {
+		form = &models.VirtualTable{}
+	}
+	RenderTemplate(w, "venues/virtual_tables.html", map[string]interface{}{
+		"Title":         "Virtual Tables",
+		"User":          currentUser,
+		"VirtualTables": tables,
+		"Form":          form,
+		"Error":         errMsg,
+	})
+}
+
+// CreateVirtualTable adds a virtual table owned by the current user:
+// POST /virtual-tables. This handler should be wrapped by AuthMiddleware.
+func CreateVirtualTable(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		table, errMsg := virtualTableFromForm(r)
+		if errMsg != "" {
+			renderVirtualTablesPage(w, r, db, currentUser, table, errMsg)
+			return
+		}
+		table.OwnerID = currentUser.ID
+		if err := database.CreateVirtualTable(db, table); err != nil {
+			fmt.Printf("Error creating virtual table: %v\n", err)
+			http.Error(w, "Failed to add the virtual table. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/virtual-tables", http.StatusSeeOther)
+	}
+}
+
+// UpdateVirtualTable saves changes to one of the current user's virtual tables:
+// POST /virtual-tables/{id}. This handler should be wrapped by AuthMiddleware.
+func UpdateVirtualTable(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser, err := GetCurrentUser(r, db)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		tableID, err := pathInt64(r, "/virtual-tables/", 0)
+		if err != nil {
+			RenderErrorPage(w, r, db, http.StatusBadRequest, "Bad Request", "Invalid virtual table ID format.")
+			return
+		}
+		table, err := database.GetVirtualTableByID(db, tableID)
+		if err != nil || table.OwnerID != currentUser.ID {
+			if err != nil && err != sql.ErrNoRows {
+				http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			} else {
+				RenderErrorPage(w, r, db, http.StatusNotFound, "Not Found", "Virtual table not found.")
+			}
+			return
+		}
+		updated, errMsg := virtualTableFromForm(r)
+		if errMsg != "" {
+			renderVirtualTablesPage(w, r, db, currentUser, nil, errMsg)
+			return
+		}
+		updated.ID, updated.OwnerID = table.ID, table.OwnerID
+		if err := database.UpdateVirtualTable(db, updated); err != nil {
+			fmt.Printf("Error updating virtual table %d: %v\n", table.ID, err)
+			http.Error(w, "Failed to save the virtual table. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/virtual-tables", http.StatusSeeOther)
+	}
+}
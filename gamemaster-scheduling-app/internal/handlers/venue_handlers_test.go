@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestVenuesAndVirtualTables(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Teardown()
+
+	hostClient, _ := ts.newUserClient(t, "venue_host@example.com", "hostpass")
+	gmClient, gm := ts.newUserClient(t, "venue_gm@example.com", "gmpass")
+	aliceClient, _ := ts.newUserClient(t, "venue_alice@example.com", "password")
+	bobClient, _ := ts.newUserClient(t, "venue_bob@example.com", "password")
+
+	if _, body := postForm(t, hostClient, ts.server.URL+"/venues", url.Values{"name": {"Den"}, "address": {"1 Main St"}, "capacity": {"-2"}}); !strings.Contains(body, "The capacity must be a number of people") {
+		t.Errorf("negative capacity was not rejected: %s", body)
+	}
+	if status, _ := postForm(t, hostClient, ts.server.URL+"/venues", url.Values{
+		"name": {"Dragon Den"}, "address": {"1 Main St"}, "capacity": {"4"}, "accessibility": {"Step-free entrance"}, "host_contact": {"Ring the bell"},
+	}); status != http.StatusSeeOther {
+		t.Fatalf("create venue status = %d, want %d", status, http.StatusSeeOther)
+	}
+	venues, _ := database.GetVenues(ts.db)
+	if len(venues) != 1 {
+		t.Fatalf("venues = %v, want Dragon Den", venues)
+	}
+	venue := strconv.FormatInt(venues[0].ID, 10)
+	venueURL := ts.server.URL + "/venues/" + venue
+	if _, body := getBody(t, ts.client, venueURL); !strings.Contains(body, "Step-free entrance") || strings.Contains(body, "Ring the bell") {
+		t.Errorf("anonymous venue page should show accessibility but not the host contact: %s", body)
+	}
+	if _, body := getBody(t, aliceClient, venueURL); !strings.Contains(body, "Ring the bell") {
+		t.Errorf("signed-in venue page does not show the host contact: %s", body)
+	}
+	if status, _ := postForm(t, gmClient, venueURL, url.Values{"name": {"Mine"}, "address": {"2 Side St"}}); status != http.StatusForbidden {
+		t.Errorf("non-owner venue update status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	if _, body := postForm(t, gmClient, ts.server.URL+"/virtual-tables", url.Values{"name": {"Foundry"}, "vtt_url": {"javascript:alert(1)"}}); !strings.Contains(body, "Links must be http(s) URLs.") {
+		t.Errorf("unsafe tabletop link was not rejected: %s", body)
+	}
+	postForm(t, gmClient, ts.server.URL+"/virtual-tables", url.Values{"name": {"Foundry"}, "vtt_url": {"https://vtt.example.com/room"}, "voice_url": {"https://voice.example.com/chan"}})
+	postForm(t, hostClient, ts.server.URL+"/virtual-tables", url.Values{"name": {"Host Table"}, "voice_url": {"https://voice.example.com/host"}})
+	gmTables, _ := database.GetVirtualTablesByOwner(ts.db, gm.ID)
+	if len(gmTables) != 1 {
+		t.Fatalf("GM virtual tables = %v, want Foundry", gmTables)
+	}
+	hostTables, _ := database.GetVirtualTablesByOwner(ts.db, venues[0].OwnerID)
+	gmTable, hostTable := strconv.FormatInt(gmTables[0].ID, 10), strconv.FormatInt(hostTables[0].ID, 10)
+	if status, _ := postForm(t, gmClient, ts.server.URL+"/virtual-tables/"+hostTable, url.Values{"name": {"Stolen"}}); status != http.StatusNotFound {
+		t.Errorf("editing another user's virtual table status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	newGame := url.Values{"title": {"Crowded"}, "game_datetime": {"2030-05-01T19:00"}, "venue_id": {venue}, "virtual_table_id": {gmTable}}
+	if _, body := postForm(t, gmClient, ts.server.URL+"/games/new", newGame); !strings.Contains(body, "Choose a venue or a virtual table, not both.") {
+		t.Errorf("venue and virtual table together were not rejected: %s", body)
+	}
+	newGame.Set("venue_id", "")
+	newGame.Set("virtual_table_id", hostTable)
+	if _, body := postForm(t, gmClient, ts.server.URL+"/games/new", newGame); !strings.Contains(body, "Choose one of your virtual tables.") {
+		t.Errorf("another user's virtual table was not rejected: %s", body)
+	}
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{"title": {"Crowded"}, "game_datetime": {"2030-05-01T19:00"}, "venue_id": {venue}, "max_players": {"6"}})
+	postForm(t, gmClient, ts.server.URL+"/games/new", url.Values{"title": {"Online"}, "game_datetime": {"2030-05-02T19:00"}, "virtual_table_id": {gmTable}})
+	games, _ := database.GetGamesByGM(ts.db, gm.ID)
+	if len(games) != 2 {
+		t.Fatalf("GetGamesByGM() = %v, want two games", games)
+	}
+	var crowded, online *models.Game
+	for _, g := range games {
+		if g.VenueID != 0 {
+			crowded = g
+		} else {
+			online = g
+		}
+	}
+	if crowded == nil || online == nil || crowded.Location != "Dragon Den, 1 Main St" || online.Location != "Foundry" {
+		t.Fatalf("games = %+v, %+v; want locations filled in from the venue and the virtual table", crowded, online)
+	}
+
+	crowdedURL := ts.server.URL + "/games/" + strconv.FormatInt(crowded.ID, 10)
+	if _, body := getBody(t, gmClient, crowdedURL); !strings.Contains(body, "This game has 6 seats, but Dragon Den only holds 4 people.") {
+		t.Errorf("GM does not see the capacity warning: %s", body)
+	}
+	if _, body := getBody(t, aliceClient, crowdedURL); strings.Contains(body, "capacity-warning") || !strings.Contains(body, "/venues/"+venue) {
+		t.Errorf("player should see the venue but not the capacity warning: %s", body)
+	}
+	if _, body := getBody(t, ts.client, venueURL); !strings.Contains(body, "Crowded") || !strings.Contains(body, "More seats than the venue holds") {
+		t.Errorf("venue page does not flag the crowded game: %s", body)
+	}
+
+	onlineURL := ts.server.URL + "/games/" + strconv.FormatInt(online.ID, 10)
+	postForm(t, aliceClient, onlineURL+"/rsvp", url.Values{"status": {"attending"}})
+	postForm(t, bobClient, onlineURL+"/rsvp", url.Values{"status": {"maybe"}})
+	for _, viewer := range []struct {
+		name      string
+		client    *http.Client
+		seesLinks bool
+	}{{"GM", gmClient, true}, {"attending player", aliceClient, true}, {"maybe player", bobClient, false}, {"anonymous", ts.client, false}} {
+		_, body := getBody(t, viewer.client, onlineURL)
+		if strings.Contains(body, "https://vtt.example.com/room") != viewer.seesLinks {
+			t.Errorf("%s sees the tabletop link = %v, want %v", viewer.name, !viewer.seesLinks, viewer.seesLinks)
+		}
+		if !viewer.seesLinks && !strings.Contains(body, "The links are shown to attending players.") {
+			t.Errorf("%s is not told the game is online: %s", viewer.name, body)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package ledger does the arithmetic of a campaign's shared expenses: reading
+// amounts, splitting an expense into balanced postings, and suggesting the
+// payments that settle everyone's balances.
+//
+// Everything here is a pure function of its arguments, in whole cents, so the
+// rounding can be tested on its own; the database stores what it returns.
+package ledger
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// MaxAmount is the largest amount ParseAmount accepts, 1,000,000.00.
+const MaxAmount models.Cents = 100000000
+
+// ErrInvalidAmount is returned by ParseAmount for anything but a plain
+// non-negative amount with at most two decimals.
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// ParseAmount reads an amount such as "12", "12.5" or "12.50" into cents.
+func ParseAmount(s string) (models.Cents, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || len(frac) > 2 || (hasFrac && frac == "") || !digits(whole) || !digits(frac) {
+		return 0, ErrInvalidAmount
+	}
+	if len(whole) > 7 {
+		return 0, ErrInvalidAmount
+	}
+	units, _ := strconv.ParseInt(whole, 10, 64)
+	cents := units * 100
+	if frac != "" {
+		f, _ := strconv.ParseInt(frac, 10, 64)
+		if len(frac) == 1 {
+			f *= 10
+		}
+		cents += f
+	}
+	if models.Cents(cents) > MaxAmount {
+		return 0, ErrInvalidAmount
+	}
+	return models.Cents(cents), nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitEvenly splits total into n shares that differ by at most a cent and add
+// up to total. The leftover cents go to the first shares.
+func SplitEvenly(total models.Cents, n int) []models.Cents {
+	if n <= 0 {
+		return nil
+	}
+	shares := make([]models.Cents, n)
+	each, left := total/models.Cents(n), total%models.Cents(n)
+	for i := range shares {
+		shares[i] = each
+		if models.Cents(i) < left {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// Share is a participant's part of an expense.
+type Share struct {
+	UserID int64
+	Amount models.Cents
+}
+
+// ExpensePostings turns an expense the payer fronted into balanced postings:
+// the payer is owed the total, and each participant owes their share. Shares of
+// zero are left out. The postings add up to zero only if the shares add up to
+// the total, which Balanced checks.
+func ExpensePostings(payerID int64, total models.Cents, shares []Share) []*models.LedgerPosting {
+	postings := []*models.LedgerPosting{{UserID: payerID, Amount: total}}
+	for _, s := range shares {
+		if s.Amount != 0 {
+			postings = append(postings, &models.LedgerPosting{UserID: s.UserID, Amount: -s.Amount})
+		}
+	}
+	return postings
+}
+
+// PaymentPostings records that from paid amount to to: from is owed it back
+// out of their balance, and to's balance goes down by as much.
+func PaymentPostings(fromID, toID int64, amount models.Cents) []*models.LedgerPosting {
+	return []*models.LedgerPosting{{UserID: fromID, Amount: amount}, {UserID: toID, Amount: -amount}}
+}
+
+// ReversalPostings undoes an entry: its postings with their amounts negated,
+// so that the two entries together leave every balance as it was.
+func ReversalPostings(postings []*models.LedgerPosting) []*models.LedgerPosting {
+	reversed := make([]*models.LedgerPosting, len(postings))
+	for i, p := range postings {
+		reversed[i] = &models.LedgerPosting{UserID: p.UserID, Amount: -p.Amount}
+	}
+	return reversed
+}
+
+// Balanced reports whether postings make a valid ledger entry: at least two of
+// them, none zero, adding up to zero.
+func Balanced(postings []*models.LedgerPosting) bool {
+	if len(postings) < 2 {
+		return false
+	}
+	var sum models.Cents
+	for _, p := range postings {
+		if p.Amount == 0 {
+			return false
+		}
+		sum += p.Amount
+	}
+	return sum == 0
+}
+
+// Transfer is a payment that helps settle balances.
+type Transfer struct {
+	FromID, ToID     int64
+	FromName, ToName string
+	Amount           models.Cents
+}
+
+// SettleUp suggests payments that bring every balance to zero, with at most
+// one payment fewer than the players whose balance isn't zero. The largest
+// debtor pays the largest creditor first; ties go to the lower user ID. The
+// balances must add up to zero, as a ledger's do.
+func SettleUp(balances []*models.LedgerBalance) []Transfer {
+	var debtors, creditors []models.LedgerBalance
+	for _, b := range balances {
+		switch {
+		case b.Amount < 0:
+			debtors = append(debtors, models.LedgerBalance{UserID: b.UserID, UserName: b.UserName, Amount: -b.Amount})
+		case b.Amount > 0:
+			creditors = append(creditors, *b)
+		}
+	}
+	largestFirst := func(bs []models.LedgerBalance) {
+		sort.Slice(bs, func(i, j int) bool {
+			if bs[i].Amount != bs[j].Amount {
+				return bs[i].Amount > bs[j].Amount
+			}
+			return bs[i].UserID < bs[j].UserID
+		})
+	}
+	largestFirst(debtors)
+	largestFirst(creditors)
+
+	var transfers []Transfer
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		d, c := &debtors[i], &creditors[j]
+		amount := min(d.Amount, c.Amount)
+		transfers = append(transfers, Transfer{FromID: d.UserID, FromName: d.UserName, ToID: c.UserID, ToName: c.UserName, Amount: amount})
+		d.Amount -= amount
+		c.Amount -= amount
+		if d.Amount == 0 {
+			i++
+		}
+		if c.Amount == 0 {
+			j++
+		}
+	}
+	return transfers
+}
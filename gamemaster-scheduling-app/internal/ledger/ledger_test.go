@@ -0,0 +1,121 @@
+package ledger
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    models.Cents
+		wantErr bool
+	}{
+		{"12", 1200, false},
+		{" 12.5 ", 1250, false},
+		{"12.05", 1205, false},
+		{"0.99", 99, false},
+		{"0", 0, false},
+		{"1000000", 100000000, false},
+		{"1000000.01", 0, true},
+		{"12.", 0, true},
+		{".5", 0, true},
+		{"12.345", 0, true},
+		{"-3", 0, true},
+		{"1e3", 0, true},
+		{"12,50", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAmount(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCentsString(t *testing.T) {
+	for c, want := range map[models.Cents]string{0: "0.00", 5: "0.05", 1250: "12.50", -1205: "-12.05"} {
+		if got := c.String(); got != want {
+			t.Errorf("Cents(%d).String() = %q, want %q", int64(c), got, want)
+		}
+	}
+}
+
+func TestSplitEvenly(t *testing.T) {
+	tests := []struct {
+		total models.Cents
+		n     int
+		want  []models.Cents
+	}{
+		{3000, 3, []models.Cents{1000, 1000, 1000}},
+		{1000, 3, []models.Cents{334, 333, 333}},
+		{1001, 4, []models.Cents{251, 250, 250, 250}},
+		{2, 3, []models.Cents{1, 1, 0}},
+		{500, 0, nil},
+	}
+	for _, tt := range tests {
+		if got := SplitEvenly(tt.total, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitEvenly(%v, %d) = %v, want %v", tt.total, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestExpensePostingsBalance(t *testing.T) {
+	shares := []Share{{UserID: 1, Amount: 334}, {UserID: 2, Amount: 333}, {UserID: 3, Amount: 333}}
+	postings := ExpensePostings(1, 1000, shares)
+	if !Balanced(postings) {
+		t.Errorf("ExpensePostings() = %v, want balanced postings", postings)
+	}
+	if len(postings) != 4 || postings[0].UserID != 1 || postings[0].Amount != 1000 || postings[1].Amount != -334 {
+		t.Errorf("ExpensePostings() = %v, want the payer owed 10.00 and three shares", postings)
+	}
+
+	// Custom shares that don't add up to the total don't balance.
+	if Balanced(ExpensePostings(1, 1000, []Share{{UserID: 2, Amount: 600}, {UserID: 3, Amount: 300}})) {
+		t.Error("shares of 9.00 for a 10.00 expense balanced, want unbalanced")
+	}
+	// A zero share is left out rather than posted.
+	if got := ExpensePostings(1, 500, []Share{{UserID: 2, Amount: 500}, {UserID: 3, Amount: 0}}); len(got) != 2 || !Balanced(got) {
+		t.Errorf("ExpensePostings() with a zero share = %v, want two balanced postings", got)
+	}
+	if Balanced(PaymentPostings(1, 2, 0)) || !Balanced(PaymentPostings(1, 2, 250)) {
+		t.Error("Balanced() of payments: want a zero payment refused and 2.50 accepted")
+	}
+	expense := ExpensePostings(1, 900, []Share{{UserID: 1, Amount: 300}, {UserID: 2, Amount: 600}})
+	reversal := ReversalPostings(expense)
+	if !Balanced(reversal) || len(reversal) != len(expense) {
+		t.Fatalf("ReversalPostings() = %v, want balanced postings", reversal)
+	}
+	for i, p := range reversal {
+		if p.UserID != expense[i].UserID || p.Amount != -expense[i].Amount {
+			t.Errorf("reversal posting %d = %+v, want %+v negated", i, p, expense[i])
+		}
+	}
+}
+
+func TestSettleUp(t *testing.T) {
+	balances := []*models.LedgerBalance{
+		{UserID: 1, UserName: "ann", Amount: 2000},
+		{UserID: 2, UserName: "bob", Amount: -1500},
+		{UserID: 3, UserName: "cat", Amount: -1000},
+		{UserID: 4, UserName: "dan", Amount: 500},
+		{UserID: 5, UserName: "eve", Amount: 0},
+	}
+	want := []Transfer{
+		{FromID: 2, FromName: "bob", ToID: 1, ToName: "ann", Amount: 1500},
+		{FromID: 3, FromName: "cat", ToID: 1, ToName: "ann", Amount: 500},
+		{FromID: 3, FromName: "cat", ToID: 4, ToName: "dan", Amount: 500},
+	}
+	if got := SettleUp(balances); !reflect.DeepEqual(got, want) {
+		t.Errorf("SettleUp() = %+v, want %+v", got, want)
+	}
+	if balances[0].Amount != 2000 {
+		t.Errorf("SettleUp() changed the balances it was given")
+	}
+	if got := SettleUp(nil); got != nil {
+		t.Errorf("SettleUp(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,169 @@
+// Package lfg matches looking-for-group posts from players with upcoming games
+// that have free seats, for suggestions on both sides of the /lfg board.
+//
+// Score, GamesForPlayer and PlayersForGame are pure functions of the posts, the
+// game openings and the current time, so the ranking can be tested on its own.
+package lfg
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// Points a match earns for each criterion. A post that doesn't mind, or a game
+// that doesn't say, earns the smaller "unknown" amount, so games that are known
+// to fit rank above games that might.
+const (
+	pointsSystem        = 40
+	pointsSystemUnknown = 10
+	pointsMode          = 20
+	pointsModeUnknown   = 5
+	pointsLevel         = 15
+	pointsLevelAll      = 10
+	pointsLevelUnknown  = 5
+	pointsTime          = 25
+	pointsTimeUnknown   = 5
+	pointsRecruiting    = 5
+)
+
+// Match is a player's post paired with a game opening, with the reasons it fits.
+type Match struct {
+	Post    *models.LFGPost
+	Opening *models.GameOpening
+	Score   int
+	Reasons []string
+}
+
+// Score rates how well a game opening fits a player's post. ok is false if the
+// game can't be offered at all: the post isn't an open player post, the game has
+// started, was cancelled or is full, the player is already involved in it, or the
+// system, play mode, experience level or availability rule it out.
+func Score(post *models.LFGPost, opening *models.GameOpening, now time.Time) (score int, reasons []string, ok bool) {
+	game := opening.Game
+	if post.Kind != models.LFGKindPlayer || !post.IsOpen() {
+		return 0, nil, false
+	}
+	if !game.GameDateTime.After(now) || game.IsCancelled() || opening.OpenSeats() == 0 {
+		return 0, nil, false
+	}
+	if game.IsGM(post.UserID) || opening.Involved[post.UserID] {
+		return 0, nil, false
+	}
+
+	switch {
+	case post.SystemID == 0 || game.SystemID == 0:
+		score += pointsSystemUnknown
+	case post.SystemID == game.SystemID:
+		score += pointsSystem
+		reasons = append(reasons, "Plays "+game.SystemName)
+	default:
+		return 0, nil, false
+	}
+
+	switch {
+	case post.PlayMode == "" || game.PlayMode == "":
+		score += pointsModeUnknown
+	case post.PlayMode == game.PlayMode:
+		score += pointsMode
+		if game.PlayMode == models.PlayModeOnline {
+			reasons = append(reasons, "Online")
+		} else {
+			reasons = append(reasons, "In person")
+		}
+	default:
+		return 0, nil, false
+	}
+
+	switch {
+	case post.ExperienceLevel == "" || game.ExperienceLevel == "":
+		score += pointsLevelUnknown
+	case post.ExperienceLevel == game.ExperienceLevel:
+		score += pointsLevel
+		if game.ExperienceLevel == models.ExperienceNewPlayerFriendly {
+			reasons = append(reasons, "New-player friendly")
+		}
+	case game.ExperienceLevel == models.ExperienceAllLevels:
+		score += pointsLevelAll
+	case post.ExperienceLevel == models.ExperienceNewPlayerFriendly:
+		return 0, nil, false // A newcomer at a table for experienced players
+	default:
+		score += pointsLevelUnknown // Experienced players at a beginner table may still enjoy it
+	}
+
+	if len(post.Availability) == 0 {
+		score += pointsTimeUnknown
+	} else {
+		fits := false
+		for _, w := range post.Availability {
+			if w.Contains(game.GameDateTime) {
+				fits = true
+				reasons = append(reasons, "Fits "+w.Label())
+				break
+			}
+		}
+		if !fits {
+			return 0, nil, false
+		}
+		score += pointsTime
+	}
+
+	if opening.Recruiting {
+		score += pointsRecruiting
+		reasons = append(reasons, "GM is recruiting")
+	}
+	return score, reasons, true
+}
+
+// GamesForPlayer ranks the game openings that fit a player's post, best first.
+// Ties go to the sooner game. At most limit matches are returned; 0 means no limit.
+func GamesForPlayer(post *models.LFGPost, openings []*models.GameOpening, now time.Time, limit int) []*Match {
+	var matches []*Match
+	for _, o := range openings {
+		if score, reasons, ok := Score(post, o, now); ok {
+			matches = append(matches, &Match{Post: post, Opening: o, Score: score, Reasons: reasons})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Opening.Game.GameDateTime.Equal(b.Opening.Game.GameDateTime) {
+			return a.Opening.Game.GameDateTime.Before(b.Opening.Game.GameDateTime)
+		}
+		return a.Opening.Game.ID < b.Opening.Game.ID
+	})
+	return truncate(matches, limit)
+}
+
+// PlayersForGame ranks the player posts that fit a game opening, best first.
+// Ties go to the player who has been waiting longest. At most limit matches are
+// returned; 0 means no limit.
+func PlayersForGame(opening *models.GameOpening, posts []*models.LFGPost, now time.Time, limit int) []*Match {
+	var matches []*Match
+	for _, p := range posts {
+		if score, reasons, ok := Score(p, opening, now); ok {
+			matches = append(matches, &Match{Post: p, Opening: opening, Score: score, Reasons: reasons})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Post.CreatedAt.Equal(b.Post.CreatedAt) {
+			return a.Post.CreatedAt.Before(b.Post.CreatedAt)
+		}
+		return a.Post.ID < b.Post.ID
+	})
+	return truncate(matches, limit)
+}
+
+func truncate(matches []*Match, limit int) []*Match {
+	if limit > 0 && len(matches) > limit {
+		return matches[:limit]
+	}
+	return matches
+}
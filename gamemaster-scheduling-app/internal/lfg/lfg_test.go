@@ -0,0 +1,129 @@
+package lfg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// now is a Wednesday; saturday is 19:00 three days later.
+var (
+	now      = time.Date(2030, 5, 1, 12, 0, 0, 0, time.UTC)
+	saturday = time.Date(2030, 5, 4, 19, 0, 0, 0, time.UTC)
+)
+
+const dnd, coc = 1, 2
+
+var saturdayEvening = models.AvailabilityWindow{Weekday: time.Saturday, Start: 17 * 60, End: 21 * 60}
+
+func opening(game models.Game) *models.GameOpening {
+	if game.GMID == 0 {
+		game.GMID = 100
+	}
+	if game.GameDateTime.IsZero() {
+		game.GameDateTime = saturday
+	}
+	return &models.GameOpening{Game: &game}
+}
+
+func TestScore(t *testing.T) {
+	player := &models.LFGPost{ID: 1, UserID: 7, Kind: models.LFGKindPlayer, SystemID: dnd, PlayMode: models.PlayModeOnline,
+		ExperienceLevel: models.ExperienceNewPlayerFriendly, Availability: []models.AvailabilityWindow{saturdayEvening}}
+
+	tests := []struct {
+		name        string
+		post        *models.LFGPost
+		opening     *models.GameOpening
+		wantScore   int
+		wantReasons []string
+		wantOK      bool
+	}{
+		{"perfect fit", player,
+			opening(models.Game{SystemID: dnd, SystemName: "D&D 5e", PlayMode: models.PlayModeOnline, ExperienceLevel: models.ExperienceNewPlayerFriendly}),
+			pointsSystem + pointsMode + pointsLevel + pointsTime, []string{"Plays D&D 5e", "Online", "New-player friendly", "Fits Saturday evening"}, true},
+		{"game says nothing", player, opening(models.Game{}),
+			pointsSystemUnknown + pointsModeUnknown + pointsLevelUnknown + pointsTime, []string{"Fits Saturday evening"}, true},
+		{"open to all levels", player, opening(models.Game{ExperienceLevel: models.ExperienceAllLevels}),
+			pointsSystemUnknown + pointsModeUnknown + pointsLevelAll + pointsTime, []string{"Fits Saturday evening"}, true},
+		{"recruiting", &models.LFGPost{UserID: 7, Kind: models.LFGKindPlayer}, &models.GameOpening{Game: opening(models.Game{}).Game, Recruiting: true},
+			pointsSystemUnknown + pointsModeUnknown + pointsLevelUnknown + pointsTimeUnknown + pointsRecruiting, []string{"GM is recruiting"}, true},
+		{"other system", player, opening(models.Game{SystemID: coc}), 0, nil, false},
+		{"in person", player, opening(models.Game{PlayMode: models.PlayModeInPerson}), 0, nil, false},
+		{"too advanced for a newcomer", player, opening(models.Game{ExperienceLevel: models.ExperienceExperienced}), 0, nil, false},
+		{"outside availability", player, opening(models.Game{GameDateTime: saturday.Add(3 * time.Hour)}), 0, nil, false},
+		{"full", player, &models.GameOpening{Game: opening(models.Game{MaxPlayers: 4}).Game, SeatsTaken: 4}, 0, nil, false},
+		{"already started", player, opening(models.Game{GameDateTime: now.Add(-time.Minute)}), 0, nil, false},
+		{"cancelled", player, opening(models.Game{QuorumStatus: models.QuorumCancelled}), 0, nil, false},
+		{"own game", player, opening(models.Game{GMID: 7}), 0, nil, false},
+		{"already involved", player, &models.GameOpening{Game: opening(models.Game{}).Game, Involved: map[int64]bool{7: true}}, 0, nil, false},
+		{"closed post", &models.LFGPost{UserID: 7, Kind: models.LFGKindPlayer, ClosedAt: now}, opening(models.Game{}), 0, nil, false},
+		{"GM post", &models.LFGPost{UserID: 7, Kind: models.LFGKindGM}, opening(models.Game{}), 0, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons, ok := Score(tt.post, tt.opening, now)
+			if score != tt.wantScore || !reflect.DeepEqual(reasons, tt.wantReasons) || ok != tt.wantOK {
+				t.Errorf("Score() = %d, %q, %v; want %d, %q, %v", score, reasons, ok, tt.wantScore, tt.wantReasons, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGamesForPlayer(t *testing.T) {
+	post := &models.LFGPost{UserID: 7, Kind: models.LFGKindPlayer, SystemID: dnd}
+	openings := []*models.GameOpening{
+		opening(models.Game{ID: 1, Title: "Unknown system"}),
+		opening(models.Game{ID: 2, Title: "Later D&D", SystemID: dnd, GameDateTime: saturday.Add(24 * time.Hour)}),
+		opening(models.Game{ID: 3, Title: "Cthulhu", SystemID: coc}),
+		opening(models.Game{ID: 4, Title: "Sooner D&D", SystemID: dnd}),
+		opening(models.Game{ID: 5, Title: "Same time D&D", SystemID: dnd}),
+	}
+
+	var titles []string
+	for _, m := range GamesForPlayer(post, openings, now, 0) {
+		titles = append(titles, m.Opening.Game.Title)
+	}
+	want := []string{"Sooner D&D", "Same time D&D", "Later D&D", "Unknown system"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("GamesForPlayer() = %v, want %v", titles, want)
+	}
+	if got := GamesForPlayer(post, openings, now, 2); len(got) != 2 {
+		t.Errorf("GamesForPlayer() with limit 2 returned %d matches", len(got))
+	}
+}
+
+func TestPlayersForGame(t *testing.T) {
+	game := opening(models.Game{ID: 1, SystemID: dnd, PlayMode: models.PlayModeInPerson})
+	posts := []*models.LFGPost{
+		{ID: 1, UserID: 11, Kind: models.LFGKindPlayer, CreatedAt: now.Add(-time.Hour)},
+		{ID: 2, UserID: 12, Kind: models.LFGKindPlayer, SystemID: dnd, CreatedAt: now.Add(-time.Hour)},
+		{ID: 3, UserID: 13, Kind: models.LFGKindPlayer, SystemID: dnd, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: 4, UserID: 14, Kind: models.LFGKindPlayer, PlayMode: models.PlayModeOnline},
+		{ID: 5, UserID: 15, Kind: models.LFGKindPlayer, SystemID: dnd, PlayMode: models.PlayModeInPerson, CreatedAt: now},
+	}
+
+	var ids []int64
+	for _, m := range PlayersForGame(game, posts, now, 0) {
+		ids = append(ids, m.Post.ID)
+	}
+	// The best fit first, then equal scores by who has waited longest.
+	want := []int64{5, 3, 2, 1}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("PlayersForGame() = %v, want %v", ids, want)
+	}
+}
+
+func TestAvailabilityWindow(t *testing.T) {
+	if !saturdayEvening.Contains(saturday) || saturdayEvening.Contains(saturday.Add(2*time.Hour)) || saturdayEvening.Contains(saturday.Add(-24*time.Hour)) {
+		t.Errorf("Contains() is wrong around %v", saturday)
+	}
+	if got := saturdayEvening.Label(); got != "Saturday evening" {
+		t.Errorf("Label() = %q, want %q", got, "Saturday evening")
+	}
+	custom := models.AvailabilityWindow{Weekday: time.Monday, Start: 18*60 + 30, End: 20 * 60}
+	if got := custom.Label(); got != "Monday 18:30-20:00" {
+		t.Errorf("Label() = %q, want %q", got, "Monday 18:30-20:00")
+	}
+}
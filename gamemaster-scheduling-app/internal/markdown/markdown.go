@@ -0,0 +1,324 @@
+// Package markdown renders the small subset of Markdown used in game descriptions and
+// chat: paragraphs and line breaks, **bold**, *italic*, `code`, [links](https://...),
+// bare http(s) URLs, bulleted and numbered lists, and @mentions.
+//
+// The renderer is escape-first: every piece of user text is HTML-escaped, and the only
+// markup in the output is the fixed set of tags this package writes itself. Link targets
+// are limited to http, https, mailto and site-relative paths, so hostile input can't
+// produce script URLs, event handler attributes or raw tags.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Options configures Render.
+type Options struct {
+	// Mention resolves "@username" to the URL of that user's profile.
+	// Mentions it doesn't resolve (ok == false), or all mentions if Mention is nil,
+	// are rendered as plain text.
+	Mention func(username string) (href string, ok bool)
+}
+
+// Mentions are "@" followed by 3-30 letters, digits or underscores; this matches
+// models.ValidUsername.
+const (
+	minMentionLength = 3
+	maxMentionLength = 30
+)
+
+// maxNesting bounds how deeply emphasis and links may nest, so pathological input
+// can't recurse without limit.
+const maxNesting = 8
+
+var (
+	bulletItem  = regexp.MustCompile(`^ {0,3}[-*+][ \t]+(.*)$`)
+	orderedItem = regexp.MustCompile(`^ {0,3}\d{1,9}[.)][ \t]+(.*)$`)
+)
+
+// Render converts src to sanitized HTML.
+func Render(src string, opts Options) template.HTML {
+	r := &renderer{opts: opts}
+	return template.HTML(r.blocks(src))
+}
+
+// Mentions returns the distinct usernames mentioned in src, in order of first appearance.
+// It uses the same rules as Render, so "@name" inside code spans or link text isn't a mention.
+func Mentions(src string) []string {
+	var names []string
+	seen := map[string]bool{}
+	collect := func(username string) (string, bool) {
+		key := strings.ToLower(username)
+		if !seen[key] {
+			seen[key] = true
+			names = append(names, username)
+		}
+		return "", false
+	}
+	r := &renderer{opts: Options{Mention: collect}}
+	r.blocks(src)
+	return names
+}
+
+type renderer struct {
+	opts Options
+}
+
+// blocks splits src into paragraphs and lists. Blank lines end a block; consecutive
+// list items of the same kind form one list.
+func (r *renderer) blocks(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+
+	var (
+		out       strings.Builder
+		paragraph []string
+		listTag   string // "ul" or "ol" while a list is open
+	)
+	flushParagraph := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		out.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				out.WriteString("<br>\n")
+			}
+			out.WriteString(r.inline(line, false, 0))
+		}
+		out.WriteString("</p>\n")
+		paragraph = nil
+	}
+	closeList := func() {
+		if listTag != "" {
+			out.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+
+	for _, line := range strings.Split(src, "\n") {
+		if strings.TrimSpace(line) == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+
+		tag, item := "", ""
+		if m := bulletItem.FindStringSubmatch(line); m != nil {
+			tag, item = "ul", m[1]
+		} else if m := orderedItem.FindStringSubmatch(line); m != nil {
+			tag, item = "ol", m[1]
+		}
+		if tag == "" {
+			closeList()
+			paragraph = append(paragraph, strings.TrimSpace(line))
+			continue
+		}
+
+		flushParagraph()
+		if listTag != tag {
+			closeList()
+			out.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+		out.WriteString("<li>" + r.inline(strings.TrimSpace(item), false, 0) + "</li>\n")
+	}
+	flushParagraph()
+	closeList()
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// inline renders emphasis, code, links and mentions within a single line.
+// inLink is set while rendering link text, where nested links and mentions are not allowed.
+func (r *renderer) inline(s string, inLink bool, depth int) string {
+	var out strings.Builder
+	plainStart := 0
+	flushPlain := func(end int) {
+		out.WriteString(html.EscapeString(s[plainStart:end]))
+	}
+	noCloser := make(map[string]int) // See closingDelimiter
+
+	for i := 0; i < len(s); {
+		rendered, next := r.inlineAt(s, i, inLink, depth, noCloser)
+		if next == i {
+			i++
+			continue
+		}
+		flushPlain(i)
+		out.WriteString(rendered)
+		i = next
+		plainStart = i
+	}
+	flushPlain(len(s))
+	return out.String()
+}
+
+// inlineAt tries to render a construct starting at s[i]. It returns the rendered HTML and
+// the index just past the construct, or next == i if nothing starts there.
+// noCloser is shared by the calls for one string s; see closingDelimiter.
+func (r *renderer) inlineAt(s string, i int, inLink bool, depth int, noCloser map[string]int) (out string, next int) {
+	switch c := s[i]; {
+	case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+		return html.EscapeString(s[i+1 : i+2]), i + 2
+
+	case c == '`':
+		if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+			return "<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>", i + end + 2
+		}
+
+	case (c == '*' || c == '_') && depth < maxNesting:
+		if c == '_' && i > 0 && isWordChar(s[i-1]) {
+			return "", i // snake_case_names aren't emphasis
+		}
+		delim, tag := string(c), "em"
+		if strings.HasPrefix(s[i:], delim+delim) {
+			delim, tag = delim+delim, "strong"
+		}
+		start := i + len(delim)
+		end := closingDelimiter(s, start, delim, noCloser)
+		if end < 0 {
+			return "", i
+		}
+		inner := r.inline(s[start:end], inLink, depth+1)
+		return "<" + tag + ">" + inner + "</" + tag + ">", end + len(delim)
+
+	case c == '[' && !inLink && depth < maxNesting:
+		closeText := strings.IndexByte(s[i:], ']')
+		if closeText < 0 || i+closeText+1 >= len(s) || s[i+closeText+1] != '(' {
+			return "", i
+		}
+		closeText += i
+		// Link targets can't contain parentheses. This also keeps a line of "[a](" from
+		// scanning to the same far-off ")" for each of them.
+		closeURL := strings.IndexAny(s[closeText+2:], "()")
+		if closeURL < 0 || s[closeText+2+closeURL] == '(' {
+			return "", i
+		}
+		closeURL += closeText + 2
+		href, ok := safeURL(s[closeText+2 : closeURL])
+		text := s[i+1 : closeText]
+		if !ok || strings.TrimSpace(text) == "" {
+			return "", i
+		}
+		return link(href, r.inline(text, true, depth+1)), closeURL + 1
+
+	case c == 'h' && !inLink && (i == 0 || !isWordChar(s[i-1])):
+		if !strings.HasPrefix(s[i:], "http://") && !strings.HasPrefix(s[i:], "https://") {
+			return "", i
+		}
+		end := i
+		for end < len(s) && s[end] > ' ' && s[end] != '<' && s[end] != '>' && s[end] != '"' {
+			end++
+		}
+		// Trailing punctuation usually ends the sentence, not the URL.
+		for end > i && strings.IndexByte(".,:;!?')*_", s[end-1]) >= 0 {
+			end--
+		}
+		href, ok := safeURL(s[i:end])
+		if !ok {
+			return "", i
+		}
+		return link(href, html.EscapeString(s[i:end])), end
+
+	case c == '@' && !inLink && r.opts.Mention != nil && (i == 0 || !isWordChar(s[i-1]) && s[i-1] != '@'):
+		end := i + 1
+		for end < len(s) && isWordChar(s[end]) {
+			end++
+		}
+		name := s[i+1 : end]
+		if len(name) < minMentionLength || len(name) > maxMentionLength {
+			return "", i
+		}
+		href, ok := r.opts.Mention(name)
+		if !ok {
+			return "", i
+		}
+		if href, ok = safeURL(href); !ok {
+			return "", i
+		}
+		return `<a class="mention" href="` + html.EscapeString(href) + `">@` + html.EscapeString(name) + `</a>`, end
+	}
+	return "", i
+}
+
+// closingDelimiter finds the delimiter that closes emphasis opened just before s[start],
+// or -1. The emphasized text may not be empty or start or end with a space.
+//
+// noCloser remembers, per delimiter, a start from which the scan reached the end of s
+// without finding a closer. A later start can't find one either, so it isn't scanned
+// again; without this, a line of unclosed "*"s would take quadratic time.
+func closingDelimiter(s string, start int, delim string, noCloser map[string]int) int {
+	if start >= len(s) || s[start] == ' ' {
+		return -1
+	}
+	if from, ok := noCloser[delim]; ok && start >= from {
+		return -1
+	}
+	for j := start + 1; j+len(delim) <= len(s); j++ {
+		if s[j] == '\\' {
+			j++
+			continue
+		}
+		if !strings.HasPrefix(s[j:], delim) || s[j-1] == ' ' {
+			continue
+		}
+		if len(delim) == 1 && j+1 < len(s) && s[j+1] == delim[0] {
+			j++ // part of a "**" run, not our closer
+			continue
+		}
+		if delim[0] == '_' && j+len(delim) < len(s) && isWordChar(s[j+len(delim)]) {
+			continue
+		}
+		// In "**a *b***" the strong closes at the end of the run, after the em's closer.
+		for len(delim) == 2 && j+2 < len(s) && s[j+2] == delim[0] {
+			j++
+		}
+		return j
+	}
+	noCloser[delim] = start
+	return -1
+}
+
+func link(href, text string) string {
+	return `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">` + text + `</a>`
+}
+
+// safeURL reports whether raw is an acceptable link target: an absolute http, https or
+// mailto URL, or a path on this site. It returns the trimmed URL.
+func safeURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+	for i := 0; i < len(raw); i++ {
+		if raw[i] <= ' ' || raw[i] == 0x7f || raw[i] == '\\' {
+			return "", false
+		}
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return raw, u.Host != ""
+	case "mailto":
+		return raw, true
+	case "":
+		// Site-relative paths only; "//host" would be a protocol-relative external link.
+		return raw, strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//")
+	}
+	return "", false
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
@@ -0,0 +1,212 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func profileLinks(username string) (string, bool) {
+	if strings.EqualFold(username, "alice") {
+		return "/users/1", true
+	}
+	return "", false
+}
+
+func TestRenderFormatting(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "Hello table", "<p>Hello table</p>"},
+		{"line breaks", "line one\nline two", "<p>line one<br>\nline two</p>"},
+		{"paragraphs", "one\n\ntwo", "<p>one</p>\n<p>two</p>"},
+		{"bold and italic", "**Bring** *dice* and __snacks__", "<p><strong>Bring</strong> <em>dice</em> and <strong>snacks</strong></p>"},
+		{"nested emphasis", "**very *important***", "<p><strong>very <em>important</em></strong></p>"},
+		{"snake case", "use some_long_name here", "<p>use some_long_name here</p>"},
+		{"unmatched", "5 * 3 and **open", "<p>5 * 3 and **open</p>"},
+		{"code", "type `/roll 1d20`", "<p>type <code>/roll 1d20</code></p>"},
+		{"escaped", `not \*italic\*`, "<p>not *italic*</p>"},
+		{"link", "[Rules](https://example.com/rules)", `<p><a href="https://example.com/rules" rel="nofollow noopener noreferrer">Rules</a></p>`},
+		{"relative link", "[Game](/games/3)", `<p><a href="/games/3" rel="nofollow noopener noreferrer">Game</a></p>`},
+		{"autolink", "See https://example.com/a?b=1&c=2.", `<p>See <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">https://example.com/a?b=1&amp;c=2</a>.</p>`},
+		{"bullets", "Bring:\n- dice\n* pencils", "<p>Bring:</p>\n<ul>\n<li>dice</li>\n<li>pencils</li>\n</ul>"},
+		{"numbered", "1. arrive\n2) play", "<ol>\n<li>arrive</li>\n<li>play</li>\n</ol>"},
+		{"list then text", "- one\nafter", "<ul>\n<li>one</li>\n</ul>\n<p>after</p>"},
+		{"crlf", "a\r\nb", "<p>a<br>\nb</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Render(tt.input, Options{})); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMentions(t *testing.T) {
+	got := string(Render("@alice and @Alice, not @bob or bob@alice.com or `@alice`", Options{Mention: profileLinks}))
+	want := `<p><a class="mention" href="/users/1">@alice</a> and <a class="mention" href="/users/1">@Alice</a>, not @bob or bob@alice.com or <code>@alice</code></p>`
+	if got != want {
+		t.Errorf("Render mentions\n got %q\nwant %q", got, want)
+	}
+
+	if got := string(Render("@alice", Options{})); got != "<p>@alice</p>" {
+		t.Errorf("Render without a resolver = %q, want plain text", got)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	got := Mentions("@alice: ask @bob_2 and @ALICE. Mail x@carol.com, `@dave`, [@erin](/x), @ab, @" + strings.Repeat("a", 31))
+	want := []string{"alice", "bob_2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Mentions() = %q, want %q", got, want)
+	}
+}
+
+// TestRenderHostileInput is an XSS regression test: whatever the input, the output may
+// only contain markup written by the renderer, and links may only use safe schemes.
+func TestRenderHostileInput(t *testing.T) {
+	hostile := []string{
+		`<script>alert(1)</script>`,
+		`<img src=x onerror=alert(1)>`,
+		`"><svg onload=alert(1)>`,
+		`[click](javascript:alert(1))`,
+		`[click](JaVaScRiPt:alert(1))`,
+		`[click]( javascript:alert(1))`,
+		"[click](java\tscript:alert(1))",
+		`[click](&#106;avascript:alert(1))`,
+		`[click](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)`,
+		`[click](vbscript:msgbox(1))`,
+		`[click](//evil.example/x)`,
+		`[click](/\evil.example)`,
+		`[click](https://example.com/"onmouseover="alert(1))`,
+		`[click](https://example.com/'onmouseover='alert(1))`,
+		`[<img src=x onerror=alert(1)>](https://example.com)`,
+		`[**<b>x</b>**](https://example.com)`,
+		`https://example.com/"><script>alert(1)</script>`,
+		`https://example.com/<script>`,
+		"`</code><script>alert(1)</script>`",
+		`**<script>alert(1)</script>**`,
+		`*<iframe src=javascript:alert(1)>*`,
+		`- <script>alert(1)</script>`,
+		`1. <a href="javascript:alert(1)">x</a>`,
+		`@<script>alert(1)</script>`,
+		`@alice"onmouseover="alert(1)`,
+		`\<script>alert(1)\</script>`,
+		`&lt;script&gt;alert(1)&lt;/script&gt;`,
+		strings.Repeat("**[", 500) + "x" + strings.Repeat("](https://e.com)**", 500),
+	}
+	allowedTags := map[string]bool{
+		"p": true, "br": true, "strong": true, "em": true, "code": true,
+		"a": true, "ul": true, "ol": true, "li": true,
+	}
+
+	for _, input := range hostile {
+		got := string(Render(input, Options{Mention: profileLinks}))
+
+		lower := strings.ToLower(got)
+		for _, raw := range []string{"<script", "<img", "<svg", "<iframe", "<b>"} {
+			if strings.Contains(lower, raw) {
+				t.Errorf("Render(%q) contains raw %q: %s", input, raw, got)
+			}
+		}
+
+		for _, tag := range tagsIn(got) {
+			if !allowedTags[tag.name] {
+				t.Errorf("Render(%q) emitted tag <%s>: %s", input, tag.name, got)
+			}
+			for _, attr := range tag.attrs {
+				if attr != "href" && attr != "rel" && attr != "class" {
+					t.Errorf("Render(%q) emitted attribute %q: %s", input, attr, got)
+				}
+			}
+			if href, ok := tag.values["href"]; ok {
+				if _, safe := safeURL(href); !safe {
+					t.Errorf("Render(%q) emitted unsafe href %q", input, href)
+				}
+			}
+		}
+	}
+}
+
+// TestRenderUnclosedRunsAreFast guards against delimiter matching going quadratic: each
+// input used to take seconds at chat-message length times a few.
+func TestRenderUnclosedRunsAreFast(t *testing.T) {
+	inputs := map[string]string{
+		"emphasis": strings.Repeat("*a ", 20000),
+		"strong":   strings.Repeat("**a ", 15000),
+		"under":    strings.Repeat("_a ", 20000),
+		"brackets": strings.Repeat("[a", 30000),
+		"links":    strings.Repeat("[a](", 15000) + ")",
+	}
+	for name, input := range inputs {
+		start := time.Now()
+		Render(input, Options{Mention: profileLinks})
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: rendering %d bytes took %v", name, len(input), elapsed)
+		}
+	}
+}
+
+type tagInfo struct {
+	name   string
+	attrs  []string
+	values map[string]string
+}
+
+// tagsIn extracts the tags from rendered output. Because the renderer escapes all text,
+// every '<' in the output starts a tag the renderer wrote, with double-quoted attributes.
+func tagsIn(s string) []tagInfo {
+	var tags []tagInfo
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			return tags
+		}
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			return append(tags, tagInfo{name: "unterminated"})
+		}
+		body := strings.TrimPrefix(s[start+1:start+end], "/")
+		s = s[start+end+1:]
+
+		info := tagInfo{values: map[string]string{}}
+		nameEnd := strings.IndexByte(body, ' ')
+		if nameEnd < 0 {
+			info.name = body
+			tags = append(tags, info)
+			continue
+		}
+		info.name = body[:nameEnd]
+		rest := body[nameEnd:]
+		for {
+			rest = strings.TrimLeft(rest, " ")
+			if rest == "" {
+				break
+			}
+			eq := strings.Index(rest, `="`)
+			if eq < 0 {
+				info.attrs = append(info.attrs, "malformed:"+rest)
+				break
+			}
+			closeQuote := strings.IndexByte(rest[eq+2:], '"')
+			if closeQuote < 0 {
+				info.attrs = append(info.attrs, "malformed:"+rest)
+				break
+			}
+			name := rest[:eq]
+			info.attrs = append(info.attrs, name)
+			info.values[name] = unescapeAttr(rest[eq+2 : eq+2+closeQuote])
+			rest = rest[eq+2+closeQuote+1:]
+		}
+		tags = append(tags, info)
+	}
+}
+
+func unescapeAttr(s string) string {
+	r := strings.NewReplacer("&amp;", "&", "&#34;", `"`, "&#39;", "'", "&lt;", "<", "&gt;", ">")
+	return r.Replace(s)
+}
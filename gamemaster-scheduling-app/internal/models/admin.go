@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Admin actions recorded in the audit log.
+const (
+	AuditSuspendUser       = "suspend_user"
+	AuditUnsuspendUser     = "unsuspend_user"
+	AuditSetUserRole       = "set_user_role"
+	AuditCancelGame        = "cancel_game"
+	AuditDeleteChatMessage = "delete_chat_message"
+	AuditResolveReport     = "resolve_report"
+	AuditDismissReport     = "dismiss_report"
+	AuditAddGameSystem     = "add_game_system"
+	AuditDeleteGameSystem  = "delete_game_system"
+)
+
+// Kinds of record an audit entry can be about.
+const (
+	AuditTargetUser        = "user"
+	AuditTargetGame        = "game"
+	AuditTargetChatMessage = "chat_message"
+	AuditTargetReport      = "report"
+	AuditTargetGameSystem  = "game_system"
+)
+
+// AuditEntry records an action a site admin took. The log is append-only.
+type AuditEntry struct {
+	ID         int64
+	AdminID    int64
+	AdminEmail string // Populated by joining with users table
+	Action     string
+	TargetType string
+	TargetID   int64
+	Details    string // e.g. the reason given for a suspension
+	CreatedAt  time.Time
+}
+
+// TargetLink is the admin page for the entry's target, or "" if there is none.
+func (e *AuditEntry) TargetLink() string {
+	switch e.TargetType {
+	case AuditTargetUser:
+		return fmt.Sprintf("/admin/users/%d", e.TargetID)
+	case AuditTargetGame:
+		return fmt.Sprintf("/games/%d", e.TargetID)
+	case AuditTargetGameSystem:
+		return "/admin/systems"
+	default:
+		return ""
+	}
+}
+
+// LoginEvent is an attempt to log in, successful or not.
+type LoginEvent struct {
+	ID        int64
+	UserID    int64  // 0 when the email isn't registered
+	Email     string // As entered
+	Success   bool
+	Reason    string // Why a failed attempt failed, e.g. "bad_password"
+	IPAddress string
+	UserAgent string
+	CreatedAt time.Time
+}
+
+// Reasons a login attempt failed.
+const (
+	LoginFailedUnknownEmail = "unknown_email"
+	LoginFailedBadPassword  = "bad_password"
+	LoginFailedSuspended    = "suspended"
+)
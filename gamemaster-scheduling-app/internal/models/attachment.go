@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Upload limits for game attachments.
+const (
+	MaxAttachmentBytes = 10 << 20 // 10 MB
+	ThumbnailSize      = 256      // Longest side of image thumbnails, in pixels
+)
+
+// attachmentTypes are the content types we accept, as sniffed by http.DetectContentType.
+var attachmentTypes = map[string]bool{
+	"image/png":                 true,
+	"image/jpeg":                true,
+	"image/gif":                 true,
+	"image/webp":                true,
+	"application/pdf":           true,
+	"text/plain; charset=utf-8": true,
+}
+
+// AllowedAttachmentType reports whether files of the given sniffed content type may be uploaded.
+func AllowedAttachmentType(contentType string) bool {
+	return attachmentTypes[contentType]
+}
+
+// Attachment is a file (map, handout, PDF...) the GM shared with a game.
+// The file itself lives in a storage.Store under StorageKey.
+type Attachment struct {
+	ID           int64
+	GameID       int64
+	UploaderID   int64
+	Filename     string
+	ContentType  string
+	SizeBytes    int64
+	StorageKey   string
+	ThumbnailKey string  // Empty unless a preview could be made
+	Restricted   bool    // Only revealed to the players in RevealedTo, not every participant
+	RevealedTo   []int64 // User IDs; only meaningful when Restricted
+	CreatedAt    time.Time
+}
+
+// IsImage reports whether the attachment is an image browsers can show inline.
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// IsRevealedTo reports whether a restricted attachment was revealed to the user.
+func (a *Attachment) IsRevealedTo(userID int64) bool {
+	for _, id := range a.RevealedTo {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// SizeLabel formats the file size for display, e.g. "2.4 MB".
+func (a *Attachment) SizeLabel() string {
+	switch {
+	case a.SizeBytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(a.SizeBytes)/(1<<20))
+	case a.SizeBytes >= 1<<10:
+		return fmt.Sprintf("%.0f KB", float64(a.SizeBytes)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", a.SizeBytes)
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Attendance statuses the GM records after a session.
+const (
+	AttendancePresent = "present"
+	AttendanceLate    = "late"
+	AttendanceNoShow  = "no_show"
+)
+
+// AttendanceStatuses lists the valid statuses in the order the attendance form shows them.
+var AttendanceStatuses = []string{AttendancePresent, AttendanceLate, AttendanceNoShow}
+
+// ValidAttendanceStatus reports whether s is one of AttendanceStatuses.
+func ValidAttendanceStatus(s string) bool {
+	for _, status := range AttendanceStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Attendance records whether a player who RSVP'd actually came to a session.
+type Attendance struct {
+	ID         int64
+	GameID     int64
+	UserID     int64
+	Status     string
+	RecordedBy int64
+	RecordedAt time.Time
+}
+
+// PlayerStats summarizes a player's recorded attendance across all games.
+type PlayerStats struct {
+	UserID           int64
+	SessionsRecorded int // Sessions with any attendance recorded
+	SessionsPlayed   int // Present or late
+	LateCount        int
+	NoShowCount      int
+	NoShowPercent    float64   // Share of recorded sessions that were no-shows, 0-100
+	LastPlayed       time.Time // Zero if never played
+}
+
+// AttendanceMatrix is a campaign's attendance: one row per player, one column per session.
+type AttendanceMatrix struct {
+	Games []*Game
+	Rows  []*AttendanceRow
+}
+
+// AttendanceRow is one player's line in an AttendanceMatrix.
+type AttendanceRow struct {
+	UserID   int64
+	UserName string   // Username, or email if the player has none
+	Statuses []string // Aligned with AttendanceMatrix.Games; "" where nothing was recorded
+	Played   int
+	Late     int
+	NoShows  int
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// DateLayout is how calendar dates are written in forms, URLs and the database.
+const DateLayout = "2006-01-02"
+
+// CommonTimezones are suggested in the timezone field of the availability page;
+// any IANA timezone name is accepted.
+var CommonTimezones = []string{
+	"UTC",
+	"America/Los_Angeles", "America/Denver", "America/Chicago", "America/New_York", "America/Sao_Paulo",
+	"Europe/London", "Europe/Berlin", "Europe/Paris", "Europe/Helsinki", "Europe/Moscow",
+	"Africa/Johannesburg", "Asia/Kolkata", "Asia/Singapore", "Asia/Tokyo", "Australia/Sydney", "Pacific/Auckland",
+}
+
+// Location is the user's timezone, or UTC if they haven't set one.
+func (u *User) Location() *time.Location {
+	if u.Timezone != "" {
+		if loc, err := time.LoadLocation(u.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// WeeklyAvailability is a recurring window when a user is free to play, in the
+// user's own timezone.
+type WeeklyAvailability struct {
+	ID     int64
+	UserID int64
+	AvailabilityWindow
+}
+
+// AvailabilityException is a one-off change to a user's weekly availability on
+// a date in their timezone: busy when they'd usually be free, or free when they
+// usually aren't. Start and End are minutes after midnight; a whole day is 0 to 24*60.
+type AvailabilityException struct {
+	ID     int64
+	UserID int64
+	Date   string // DateLayout
+	Start  int
+	End    int
+	Free   bool
+	Note   string
+}
+
+// IsWholeDay reports whether the exception covers the whole date.
+func (e *AvailabilityException) IsWholeDay() bool {
+	return e.Start == 0 && e.End == 24*60
+}
+
+// TimeLabel describes the exception's times, e.g. "all day" or "18:00-22:00".
+func (e *AvailabilityException) TimeLabel() string {
+	if e.IsWholeDay() {
+		return "all day"
+	}
+	return formatMinutes(e.Start) + "-" + formatMinutes(e.End)
+}
+
+// AvailabilityProfile is everything known about when a user can play.
+type AvailabilityProfile struct {
+	User       *User
+	Weekly     []*WeeklyAvailability
+	Exceptions []*AvailabilityException
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Campaign groups a GM's recurring sessions (games) under one name.
+type Campaign struct {
+	ID        int64
+	GMID      int64
+	Name      string
+	CreatedAt time.Time
+}
+
+// IsGM reports whether the user runs the campaign. Campaign-wide permissions go
+// through it, as a game's go through Game.IsGM; co-GMs help run single sessions,
+// not the campaign.
+func (c *Campaign) IsGM(userID int64) bool {
+	return userID != 0 && userID == c.GMID
+}
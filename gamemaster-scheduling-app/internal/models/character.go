@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxCharacterLevel bounds character levels and game level ranges.
+const MaxCharacterLevel = 30
+
+// Character is a player character owned by a user, who can bring it to games they RSVP to.
+type Character struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	System    string // Game system, e.g. "D&D 5e"
+	Class     string
+	Level     int // 0 if not tracked
+	SheetURL  string
+	Notes     string
+	CreatedAt time.Time
+}
+
+// Summary describes the character's class and level, e.g. "Level 5 Wizard".
+func (c *Character) Summary() string {
+	switch {
+	case c.Level > 0 && c.Class != "":
+		return fmt.Sprintf("Level %d %s", c.Level, c.Class)
+	case c.Level > 0:
+		return fmt.Sprintf("Level %d", c.Level)
+	default:
+		return c.Class
+	}
+}
@@ -2,6 +2,12 @@ package models
 
 import "time"
 
+// ChatEditWindow is how long after posting an author may still edit a message.
+const ChatEditWindow = 15 * time.Minute
+
+// MaxChatMessageLength caps a chat message, in characters.
+const MaxChatMessageLength = 2000
+
 type ChatMessage struct {
 	ID             int64
 	GameID         int64
@@ -9,4 +15,54 @@ type ChatMessage struct {
 	UserEmail      string // For display
 	MessageContent string
 	CreatedAt      time.Time
+	EditedAt       time.Time // Zero if never edited
+	DeletedAt      time.Time // Zero unless soft-deleted
+	DeletedBy      int64     // Author or moderating GM; 0 unless deleted
+	Roll           *DiceRoll // Set when the message was a /roll command
+	Mentions       []Mention // Users @mentioned in the message
+}
+
+// Mention is a user @mentioned in a chat message.
+type Mention struct {
+	UserID   int64
+	Username string
+}
+
+// IsEdited reports whether the author has edited the message.
+func (m *ChatMessage) IsEdited() bool {
+	return !m.EditedAt.IsZero()
+}
+
+// IsDeleted reports whether the message has been soft-deleted.
+func (m *ChatMessage) IsDeleted() bool {
+	return !m.DeletedAt.IsZero()
+}
+
+// RemovedByModerator reports whether someone other than the author deleted the message.
+func (m *ChatMessage) RemovedByModerator() bool {
+	return m.IsDeleted() && m.DeletedBy != m.UserID
+}
+
+// EditableAt reports whether the author may still edit the message at the given time.
+// Deleted messages and dice rolls are never editable.
+func (m *ChatMessage) EditableAt(now time.Time) bool {
+	return !m.IsDeleted() && m.Roll == nil && now.Before(m.CreatedAt.Add(ChatEditWindow))
+}
+
+// ChatMessageRevision is a previous version of an edited chat message.
+type ChatMessageRevision struct {
+	ID              int64
+	MessageID       int64
+	PreviousContent string
+	EditedAt        time.Time // When this version was replaced
+}
+
+// ChatMute records that the GM has muted a user in a game's chat.
+type ChatMute struct {
+	ID        int64
+	GameID    int64
+	UserID    int64
+	UserEmail string // For display
+	MutedBy   int64
+	CreatedAt time.Time
 }
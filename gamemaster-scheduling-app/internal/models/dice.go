@@ -0,0 +1,30 @@
+package models
+
+// Die is a single die rolled as part of a DiceGroup.
+type Die struct {
+	Sides    int
+	Value    int
+	Dropped  bool  // Excluded from the total by a keep/drop modifier
+	Exploded bool  // Triggered an extra die via an exploding modifier
+	Rerolls  []int // Earlier values discarded by a reroll modifier, oldest first
+}
+
+// DiceGroup is one term of a dice expression, e.g. "4d6kh3" or the constant "+2".
+type DiceGroup struct {
+	Notation string
+	Negative bool // Term is subtracted from the total
+	Constant bool // Term is a flat modifier with no dice
+	Dice     []Die
+	Subtotal int // Unsigned sum of kept dice (or the constant value)
+}
+
+// DiceRoll is the server-generated result of a /roll chat command.
+// It is stored separately from the message text so results cannot be faked by typing.
+type DiceRoll struct {
+	ID         int64
+	MessageID  int64
+	Expression string // Normalized expression that was rolled, e.g. "2d20kh1+5"
+	Label      string // Optional name for the roll, e.g. "Stealth"
+	Total      int
+	Groups     []DiceGroup
+}
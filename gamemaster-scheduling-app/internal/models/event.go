@@ -0,0 +1,131 @@
+package models
+
+import "time"
+
+// MaxEventDescriptionLength caps an event's description.
+const MaxEventDescriptionLength = 5000
+
+// Event is a convention or other gathering with many tables: players register
+// for a badge, then sign up for at most one table in each time slot. Its
+// organizer sets up the venues, slots and tables.
+type Event struct {
+	ID          int64
+	OrganizerID int64
+	Name        string
+	Description string
+	StartsOn    string // DateLayout
+	// MaxAttendees caps the badges handed out; 0 for unlimited.
+	MaxAttendees int
+	Attendees    int // Badges handed out so far
+	CreatedAt    time.Time
+}
+
+// IsFull reports whether every badge has been handed out.
+func (e *Event) IsFull() bool {
+	return e.MaxAttendees > 0 && e.Attendees >= e.MaxAttendees
+}
+
+// EventVenue is a place at an event where tables are run, e.g. a room or a hall.
+type EventVenue struct {
+	ID      int64
+	EventID int64
+	Name    string
+	Details string // e.g. the floor, or how to find it
+}
+
+// MaxEventChoices is how many tables a player can rank in a ranked slot.
+const MaxEventChoices = 3
+
+// EventSlot is a block of time at an event. Every table runs in one slot.
+//
+// Players sign up for the tables of a slot first come, first served, unless it
+// is Ranked: then they rank the tables they want, and the organizer previews,
+// tweaks and publishes a seating that becomes their RSVPs.
+type EventSlot struct {
+	ID       int64
+	EventID  int64
+	Name     string // e.g. "Saturday morning"
+	StartsAt time.Time
+	EndsAt   time.Time
+	Ranked   bool
+	Seed     int64 // Draws the lottery that breaks ties in the seating
+	// PublishedAt is when a ranked slot's seating became RSVPs; zero until then.
+	// Any seats left afterwards are first come, first served.
+	PublishedAt time.Time
+}
+
+// TakesChoices reports whether players rank the slot's tables rather than
+// signing up for them: it is ranked and its seating isn't published yet.
+func (s *EventSlot) TakesChoices() bool {
+	return s.Ranked && s.PublishedAt.IsZero()
+}
+
+// EventChoices is a player's ranked choices in a slot: game IDs, best first.
+type EventChoices struct {
+	UserID    int64
+	UserEmail string
+	GameIDs   []int64
+}
+
+// EventRegistration is a player's badge for an event. Badge numbers count up
+// from 1 in the order players registered.
+type EventRegistration struct {
+	ID          int64
+	EventID     int64
+	UserID      int64
+	BadgeNumber int
+	CreatedAt   time.Time
+}
+
+// EventTable is a table at an event: a game in one of its slots, with the seats
+// taken by attending players and their guests.
+type EventTable struct {
+	Game  *Game
+	Taken int
+}
+
+// SeatsLeft is how many seats the table has free; it is never negative.
+func (t *EventTable) SeatsLeft() int {
+	return max(t.Game.MaxPlayers-t.Taken, 0)
+}
+
+// FillPercent is the share of the table's seats taken, from 0 to 100.
+func (t *EventTable) FillPercent() int {
+	return fillPercent(t.Taken, t.Game.MaxPlayers)
+}
+
+func fillPercent(taken, seats int) int {
+	if seats <= 0 {
+		return 0
+	}
+	return min(taken*100/seats, 100)
+}
+
+// EventOverview is an event's fill rates for its organizer: one row per venue,
+// one column per slot, plus the totals of each slot.
+type EventOverview struct {
+	Slots  []*EventSlot
+	Rows   []*EventOverviewRow
+	Totals []*SlotFill // Aligned with Slots
+}
+
+// EventOverviewRow is a venue's line in an EventOverview. Venue is nil for the
+// row of tables that have no venue yet.
+type EventOverviewRow struct {
+	Venue *EventVenue
+	Cells [][]*EventTable // Aligned with EventOverview.Slots
+}
+
+// SlotFill totals the seats of the tables in a slot, and counts the registered
+// players who aren't running or signed up for any of them.
+type SlotFill struct {
+	Tables   int
+	Taken    int
+	Seats    int
+	Unplaced int
+}
+
+// FillPercent is the share of the slot's seats taken, from 0 to 100.
+func (f *SlotFill) FillPercent() int {
+	return fillPercent(f.Taken, f.Seats)
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Limits on session feedback.
+const (
+	MaxRating                = 5 // Ratings are 1 to 5 stars
+	MaxFeedbackCommentLength = 2000
+	// FeedbackPublicThreshold is how many responses a GM needs before their
+	// ratings show on their profile to everyone; until then only they see them.
+	FeedbackPublicThreshold = 5
+)
+
+// SessionFeedback is an attendee's ratings of a session they were at, with a
+// comment only the GM sees.
+type SessionFeedback struct {
+	ID          int64
+	GameID      int64
+	UserID      int64
+	UserName    string
+	GMIDs       []int64 // The game's GMs when the feedback was given; not loaded by queries
+	Fun         int
+	Pacing      int
+	Inclusivity int
+	Comment     string
+	CreatedAt   time.Time
+}
+
+// GMRatings are a GM's average ratings over every feedback response to their
+// sessions.
+type GMRatings struct {
+	Responses   int
+	Fun         float64
+	Pacing      float64
+	Inclusivity float64
+}
+
+// Public reports whether there are enough responses to show the ratings to
+// everyone, so no single player's ratings can be picked out.
+func (r *GMRatings) Public() bool {
+	return r.Responses >= FeedbackPublicThreshold
+}
@@ -1,6 +1,21 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// MaxGameSeats bounds the seat limit a GM can set.
+const MaxGameSeats = 100
+
+// MaxGameDescriptionLength caps a game's description.
+const MaxGameDescriptionLength = 5000
+
+// Quorum decisions, made when a game's RSVP deadline passes.
+const (
+	QuorumConfirmed = "confirmed"
+	QuorumCancelled = "cancelled_quorum" // Fewer than MinPlayers were attending
+)
 
 type Game struct {
 	ID           int64
@@ -9,7 +24,147 @@ type Game struct {
 	Description  string
 	GameDateTime time.Time
 	Location     string
-	CreatedAt    time.Time
+	CampaignID   int64 // 0 for a one-shot outside any campaign
+	MinLevel     int   // Suggested character level range; 0 for no bound
+	MaxLevel     int
+	// RequiresApproval makes attending RSVPs pending until the GM approves them.
+	RequiresApproval bool
+	MaxPlayers       int // Seat limit for approved attendees; 0 for unlimited
+	// After RSVPDeadline, RSVPs are locked (unless the GM reopens them) and the game
+	// is confirmed or cancelled depending on whether MinPlayers are attending.
+	RSVPDeadline    time.Time // Zero for no deadline
+	MinPlayers      int       // 0 for no quorum
+	QuorumStatus    string    // "" until decided, then QuorumConfirmed or QuorumCancelled
+	QuorumDecidedAt time.Time
+	RSVPsReopened   bool
+	// Staff are the co-GMs, loaded by database.GetGameByID; games from list queries
+	// leave it empty.
+	Staff        []*GameStaff
+	TransferToID int64 // Recipient of a pending ownership transfer; 0 for none
+	// CancelledAt is set when a site admin force-cancels the game.
+	CancelledAt  time.Time
+	CancelReason string
+	// ContentTags are the ContentTopics the game may include, sorted; ContentNotes
+	// warns about anything the list doesn't cover.
+	ContentTags  []string
+	ContentNotes string
+	// SystemID is the GameSystem played, 0 if unset; SystemName is joined in.
+	SystemID   int64
+	SystemName string
+	// ExperienceLevel, Format and PlayMode come from ExperienceLevels, GameFormats
+	// and PlayModes; "" if the GM didn't say.
+	ExperienceLevel string
+	Format          string
+	PlayMode        string
+	// A table at an event runs in one of its slots, optionally at one of its
+	// venues; EventID is joined in. All are 0 for games outside events.
+	EventSlotID  int64
+	EventVenueID int64
+	EventID      int64
+	// A game is at a Venue or at a VirtualTable, or neither if its Location says
+	// it all. VenueName and VenueCapacity are joined in.
+	VenueID        int64
+	VenueName      string
+	VenueCapacity  int
+	VirtualTableID int64
+	CreatedAt      time.Time
 	// Optional: Add GMUsername string if you want to easily display it,
 	// otherwise you'll need to join or do a separate query. For POC, keep it simple.
 }
+
+// HasHappened reports whether the game's scheduled start is before now,
+// i.e. attendance can be recorded.
+func (g *Game) HasHappened(now time.Time) bool {
+	return g.GameDateTime.Before(now)
+}
+
+// HasLevelRange reports whether the GM set a suggested character level range.
+func (g *Game) HasLevelRange() bool {
+	return g.MinLevel > 0 || g.MaxLevel > 0
+}
+
+// LevelRangeLabel describes the level range, e.g. "3-5", "3+" or "up to 5".
+func (g *Game) LevelRangeLabel() string {
+	switch {
+	case g.MinLevel > 0 && g.MaxLevel > 0 && g.MinLevel == g.MaxLevel:
+		return fmt.Sprintf("%d", g.MinLevel)
+	case g.MinLevel > 0 && g.MaxLevel > 0:
+		return fmt.Sprintf("%d-%d", g.MinLevel, g.MaxLevel)
+	case g.MinLevel > 0:
+		return fmt.Sprintf("%d+", g.MinLevel)
+	case g.MaxLevel > 0:
+		return fmt.Sprintf("up to %d", g.MaxLevel)
+	default:
+		return ""
+	}
+}
+
+// LevelOutOfRange reports whether a character level falls outside the game's range.
+// Characters without a tracked level (0) are never out of range.
+func (g *Game) LevelOutOfRange(level int) bool {
+	if level <= 0 {
+		return false
+	}
+	return (g.MinLevel > 0 && level < g.MinLevel) || (g.MaxLevel > 0 && level > g.MaxLevel)
+}
+
+// SeatsTaken counts the players attending the game and their guests, not including
+// the GMs. Pending and declined requests don't take a seat.
+func (g *Game) SeatsTaken(rsvps []*RSVP) int {
+	taken := 0
+	for _, r := range rsvps {
+		if r.Status == RSVPStatusAttending && !g.IsGM(r.UserID) {
+			taken += r.PartySize()
+		}
+	}
+	return taken
+}
+
+// PlayersAttending counts the players attending the game, not including the GMs or
+// anyone's guests. It is what a game's minimum player count is checked against.
+func (g *Game) PlayersAttending(rsvps []*RSVP) int {
+	players := 0
+	for _, r := range rsvps {
+		if r.Status == RSVPStatusAttending && !g.IsGM(r.UserID) {
+			players++
+		}
+	}
+	return players
+}
+
+// IsFull reports whether a seat limit is set and taken seats have reached it.
+func (g *Game) IsFull(taken int) bool {
+	return g.MaxPlayers > 0 && taken >= g.MaxPlayers
+}
+
+// HasRSVPDeadline reports whether the GM set an RSVP deadline.
+func (g *Game) HasRSVPDeadline() bool {
+	return !g.RSVPDeadline.IsZero()
+}
+
+// RSVPDeadlinePassed reports whether the RSVP deadline is set and has passed at now.
+func (g *Game) RSVPDeadlinePassed(now time.Time) bool {
+	return g.HasRSVPDeadline() && !now.Before(g.RSVPDeadline)
+}
+
+// RSVPsLocked reports whether players can no longer change their RSVPs at now:
+// the deadline has passed and the GM hasn't reopened them.
+func (g *Game) RSVPsLocked(now time.Time) bool {
+	return g.RSVPDeadlinePassed(now) && !g.RSVPsReopened
+}
+
+// OverCapacity reports whether the game has more seats than its venue holds
+// people. Games with unlimited seats, or at a venue of unknown capacity, aren't.
+func (g *Game) OverCapacity() bool {
+	return g.VenueCapacity > 0 && g.MaxPlayers > g.VenueCapacity
+}
+
+// IsCancelled reports whether the game was called off, for lack of players or by a site admin.
+func (g *Game) IsCancelled() bool {
+	return g.QuorumStatus == QuorumCancelled || g.CancelledByAdmin()
+}
+
+// CancelledByAdmin reports whether a site admin force-cancelled the game.
+func (g *Game) CancelledByAdmin() bool {
+	return !g.CancelledAt.IsZero()
+}
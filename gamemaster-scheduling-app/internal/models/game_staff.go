@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Game staff roles. The owner is the game's GMID; co-GMs are listed in Game.Staff.
+const (
+	GameRoleOwner = "owner"
+	GameRoleCoGM  = "co_gm"
+)
+
+// GameStaff is a user who helps the owner run a game, with the same rights to
+// edit, moderate and approve.
+type GameStaff struct {
+	ID        int64
+	GameID    int64
+	UserID    int64
+	UserEmail string // Populated by joining with users table
+	Role      string
+	AddedBy   int64
+	CreatedAt time.Time
+}
+
+// RoleOf returns the user's staff role in the game, or "" for everyone else.
+// Every GM-only permission on a game goes through it, via IsGM or IsOwner.
+func (g *Game) RoleOf(userID int64) string {
+	if userID == 0 {
+		return ""
+	}
+	if userID == g.GMID {
+		return GameRoleOwner
+	}
+	for _, s := range g.Staff {
+		if s.UserID == userID {
+			return s.Role
+		}
+	}
+	return ""
+}
+
+// IsGM reports whether the user may run the game: its owner or a co-GM.
+func (g *Game) IsGM(userID int64) bool {
+	return g.RoleOf(userID) != ""
+}
+
+// IsOwner reports whether the user owns the game, and so manages its staff and
+// may hand it over to someone else.
+func (g *Game) IsOwner(userID int64) bool {
+	return g.RoleOf(userID) == GameRoleOwner
+}
+
+// GMIDs returns the owner and co-GMs, owner first, e.g. to notify them all.
+func (g *Game) GMIDs() []int64 {
+	ids := []int64{g.GMID}
+	for _, s := range g.Staff {
+		ids = append(ids, s.UserID)
+	}
+	return ids
+}
+
+// HasPendingTransfer reports whether the owner offered the game to someone who
+// hasn't accepted yet.
+func (g *Game) HasPendingTransfer() bool {
+	return g.TransferToID != 0
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Host swap statuses.
+const (
+	HostSwapPending  = "pending"
+	HostSwapAccepted = "accepted"
+	HostSwapDeclined = "declined"
+)
+
+// SessionHost is who hosts one of a campaign's sessions.
+type SessionHost struct {
+	GameID       int64
+	GameTitle    string
+	GameDateTime time.Time
+	UserID       int64 // 0 if no host is assigned yet
+	UserName     string
+}
+
+// HostSwap is a host's request to trade sessions with another host. Accepting
+// it swaps who hosts the two sessions.
+type HostSwap struct {
+	ID            int64
+	CampaignID    int64
+	FromGameID    int64 // The requester's session
+	FromTitle     string
+	FromDateTime  time.Time
+	ToGameID      int64 // The session the requester would rather host
+	ToTitle       string
+	ToDateTime    time.Time
+	RequesterID   int64
+	RequesterName string
+	ResponderID   int64 // The host of ToGameID when the swap was asked for
+	ResponderName string
+	Status        string
+	CreatedAt     time.Time
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ledger entry kinds.
+const (
+	LedgerExpense  = "expense"  // Someone paid for something a session shared
+	LedgerPayment  = "payment"  // One player paid another back, as recorded by the one paid
+	LedgerReversal = "reversal" // Undoes an earlier entry with its postings negated
+)
+
+// MaxLedgerDescriptionLength caps what an expense or payment is for.
+const MaxLedgerDescriptionLength = 200
+
+// Cents is an amount of money in hundredths of the currency, as the ledger
+// stores it. Its String is the amount with two decimals, e.g. "12.50".
+type Cents int64
+
+func (c Cents) String() string {
+	sign := ""
+	if c < 0 {
+		sign, c = "-", -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// Abs is the amount without its sign.
+func (c Cents) Abs() Cents {
+	if c < 0 {
+		return -c
+	}
+	return c
+}
+
+// LedgerEntry is one transaction in a campaign's double-entry ledger. Its
+// postings always add up to zero: whatever one player is owed, others owe.
+type LedgerEntry struct {
+	ID            int64
+	CampaignID    int64
+	GameID        int64 // The session an expense was for; 0 for payments
+	GameTitle     string
+	Kind          string
+	Description   string
+	CreatedBy     int64
+	CreatedByName string
+	CreatedAt     time.Time
+	Postings      []*LedgerPosting
+
+	ReversesEntryID int64 // The entry a reversal undoes; 0 for other kinds
+	Reversed        bool  // Whether a later reversal undid this entry
+}
+
+// Reversible reports whether the entry can still be reversed: it isn't a
+// reversal itself, and hasn't been reversed yet.
+func (e *LedgerEntry) Reversible() bool {
+	return e.Kind != LedgerReversal && !e.Reversed
+}
+
+// LedgerPosting is one side of a ledger entry. A positive amount is owed to
+// the user: they paid for an expense or paid someone back. A negative amount
+// is owed by them: their share of an expense, or a payment they received.
+type LedgerPosting struct {
+	UserID   int64
+	UserName string
+	Amount   Cents
+}
+
+// Total is how much money changed hands in the entry: the sum of its positive
+// postings.
+func (e *LedgerEntry) Total() Cents {
+	var total Cents
+	for _, p := range e.Postings {
+		if p.Amount > 0 {
+			total += p.Amount
+		}
+	}
+	return total
+}
+
+// Paid returns the entry's positive postings: who paid.
+func (e *LedgerEntry) Paid() []*LedgerPosting {
+	var paid []*LedgerPosting
+	for _, p := range e.Postings {
+		if p.Amount > 0 {
+			paid = append(paid, p)
+		}
+	}
+	return paid
+}
+
+// Owed returns the entry's negative postings: the shares of an expense, or
+// who received a payment.
+func (e *LedgerEntry) Owed() []*LedgerPosting {
+	var owed []*LedgerPosting
+	for _, p := range e.Postings {
+		if p.Amount < 0 {
+			owed = append(owed, p)
+		}
+	}
+	return owed
+}
+
+// LedgerBalance is a player's running balance across a campaign: the sum of
+// their postings. Positive means the others owe them.
+type LedgerBalance struct {
+	UserID   int64
+	UserName string
+	Amount   Cents
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of looking-for-group post.
+const (
+	LFGKindPlayer = "player" // A player looking for a table
+	LFGKindGM     = "gm"     // A GM with open seats in one of their games
+)
+
+// MaxLFGNoteLength caps the free-text note on a looking-for-group post.
+const MaxLFGNoteLength = 1000
+
+// LFGPost is a looking-for-group post on the /lfg board. Players say what they
+// want to play and when; GMs advertise the open seats of an upcoming game. Posts
+// stay up until their author closes them.
+type LFGPost struct {
+	ID        int64
+	UserID    int64
+	UserEmail string // For display
+	Username  string
+	Kind      string // LFGKindPlayer or LFGKindGM
+	// What a player is looking for; zero values mean they don't mind.
+	SystemID        int64
+	SystemName      string // Joined in
+	PlayMode        string
+	ExperienceLevel string
+	Availability    []AvailabilityWindow
+	// GameID is the game a GM post recruits for; 0 for player posts.
+	GameID    int64
+	GameTitle string
+	Note      string
+	ClosedAt  time.Time
+	CreatedAt time.Time
+}
+
+// AuthorName is how the post's author is shown: their username if set, else their email.
+func (p *LFGPost) AuthorName() string {
+	if p.Username != "" {
+		return p.Username
+	}
+	return p.UserEmail
+}
+
+// IsOpen reports whether the post is still on the board.
+func (p *LFGPost) IsOpen() bool {
+	return p.ClosedAt.IsZero()
+}
+
+// AvailabilityWindow is a weekly span of time, in the same wall-clock time as
+// game start times. Start and End are minutes after midnight; End may be 24*60.
+type AvailabilityWindow struct {
+	Weekday time.Weekday
+	Start   int
+	End     int
+}
+
+// Contains reports whether t falls within the window.
+func (w AvailabilityWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	return t.Weekday() == w.Weekday && minute >= w.Start && minute < w.End
+}
+
+// Label describes the window, e.g. "Saturday evening" or "Monday 18:30-20:00".
+func (w AvailabilityWindow) Label() string {
+	for _, part := range DayParts {
+		if part.Start == w.Start && part.End == w.End {
+			return w.Weekday.String() + " " + part.Name
+		}
+	}
+	return w.Weekday.String() + " " + formatMinutes(w.Start) + "-" + formatMinutes(w.End)
+}
+
+// formatMinutes writes minutes after midnight as a 24-hour clock time, e.g. "18:30".
+func formatMinutes(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+// DayPart is a block of the day offered by the availability grid on the LFG form.
+type DayPart struct {
+	Name       string
+	Start, End int // Minutes after midnight
+}
+
+// DayParts are the blocks of the day players can tick, in order.
+var DayParts = []DayPart{
+	{"morning", 8 * 60, 12 * 60},
+	{"afternoon", 12 * 60, 17 * 60},
+	{"evening", 17 * 60, 21 * 60},
+	{"night", 21 * 60, 24 * 60},
+}
+
+// Weekdays lists the days of the week starting on Monday, as the availability grid shows them.
+var Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// GameOpening is an upcoming game with free seats, as matched against LFG posts.
+type GameOpening struct {
+	Game       *Game
+	SeatsTaken int
+	// Involved are the game's GMs and everyone with an RSVP other than "not
+	// attending", who shouldn't be offered the game.
+	Involved map[int64]bool
+	// Recruiting is set when a GM has an open LFG post for the game.
+	Recruiting bool
+}
+
+// OpenSeats is the number of free seats, or -1 if the game has no seat limit.
+func (o *GameOpening) OpenSeats() int {
+	if o.Game.MaxPlayers == 0 {
+		return -1
+	}
+	if free := o.Game.MaxPlayers - o.SeatsTaken; free > 0 {
+		return free
+	}
+	return 0
+}
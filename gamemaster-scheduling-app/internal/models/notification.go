@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Notification kinds.
+const (
+	NotificationKindMention      = "mention"
+	NotificationKindRSVPRequest  = "rsvp_request"  // To the GM: a player asked to join
+	NotificationKindRSVPApproved = "rsvp_approved" // To the player
+	NotificationKindRSVPDeclined = "rsvp_declined" // To the player
+	NotificationKindGameDecision = "game_decision" // Confirmed or cancelled at the RSVP deadline
+	NotificationKindGameStaff    = "game_staff"    // Made a co-GM, or offered or handed a game
+	NotificationKindReport       = "report"        // To the GMs: a chat message in their game was reported
+	NotificationKindGameInvite   = "game_invite"   // To a campaign's roster: a new session was scheduled for them
+	NotificationKindEventSeating = "event_seating" // To players who ranked a slot's tables: the seating was published
+	NotificationKindHosting      = "hosting"       // To a campaign's players: picked to host a session, or a host swap asked or answered
+	NotificationKindFeedback     = "feedback"      // To the GM: an attendee rated a session
+)
+
+// Notification tells a user something happened that involves them, e.g. a chat @mention.
+type Notification struct {
+	ID        int64
+	UserID    int64
+	Kind      string
+	Message   string
+	Link      string    // Where the notification leads, e.g. the chat message
+	ReadAt    time.Time // Zero while unread
+	CreatedAt time.Time
+}
+
+// IsRead reports whether the user has seen the notification.
+func (n *Notification) IsRead() bool {
+	return !n.ReadAt.IsZero()
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of thing a user can report.
+const (
+	ReportTargetGame        = "game"
+	ReportTargetChatMessage = "chat_message"
+	ReportTargetUser        = "user"
+)
+
+// Why something was reported.
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonScam          = "scam"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonOther         = "other"
+)
+
+// ReportReasons lists the reasons in the order the report form offers them.
+var ReportReasons = []string{ReportReasonSpam, ReportReasonHarassment, ReportReasonScam, ReportReasonInappropriate, ReportReasonOther}
+
+// Report statuses. Reports start open and are closed by a moderator as either
+// resolved (action was taken) or dismissed (nothing to do).
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+// MaxReportNoteLength caps the reporter's free-text note.
+const MaxReportNoteLength = 2000
+
+// Report is a user's complaint about a game listing, a chat message or another user.
+// Chat reports also go to the GMs of the game; the rest only reach site admins.
+type Report struct {
+	ID             int64
+	ReporterID     int64
+	ReporterEmail  string // Populated by joining with users table
+	TargetType     string
+	TargetID       int64
+	GameID         int64 // The game a game or chat report is about; 0 for user reports
+	ReportedUserID int64 // The profile, the message's author or the game's owner
+	Reason         string
+	Note           string
+	Status         string
+	ResolvedBy     int64     // 0 while open
+	ResolvedAt     time.Time // Zero while open
+	CreatedAt      time.Time
+
+	// Populated by joining, for the moderation queue.
+	ReportedUserEmail string
+	GameTitle         string
+	MessageContent    string // The reported chat message, even if it was deleted since
+}
+
+// ValidReportReason reports whether reason is one of ReportReasons.
+func ValidReportReason(reason string) bool {
+	for _, r := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether the report still needs a moderator's decision.
+func (r *Report) IsOpen() bool {
+	return r.Status == ReportStatusOpen
+}
+
+// TargetLink is where a moderator can see the reported content.
+func (r *Report) TargetLink() string {
+	switch r.TargetType {
+	case ReportTargetGame:
+		return fmt.Sprintf("/games/%d", r.TargetID)
+	case ReportTargetChatMessage:
+		return fmt.Sprintf("/games/%d#chat-message-%d", r.GameID, r.TargetID)
+	default:
+		return fmt.Sprintf("/users/%d", r.TargetID)
+	}
+}
+
+// UserBlock records that Blocker doesn't want to see Blocked's chat messages.
+type UserBlock struct {
+	BlockerID int64
+	BlockedID int64
+	CreatedAt time.Time
+}
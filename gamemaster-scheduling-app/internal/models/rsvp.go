@@ -6,14 +6,66 @@ const (
 	RSVPStatusAttending    = "attending"
 	RSVPStatusNotAttending = "not_attending"
 	RSVPStatusMaybe        = "maybe"
+	// In games that require approval, an attending RSVP waits as pending until
+	// the GM approves it (attending) or declines it.
+	RSVPStatusPending  = "pending"
+	RSVPStatusDeclined = "declined"
 )
 
 type RSVP struct {
-	ID        int64
-	UserID    int64
-	GameID    int64
-	Status    string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserEmail string // Optional: For easier display in templates
+	ID          int64
+	UserID      int64
+	GameID      int64
+	Status      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserEmail   string     // Optional: For easier display in templates
+	CharacterID int64      // The character the player is bringing; 0 if none chosen
+	Character   *Character // Populated when reading RSVPs with a character
+	// The GM's decision on a pending RSVP. Cleared when the player changes their status.
+	ReviewedBy    int64 // 0 if not reviewed
+	ReviewedAt    time.Time
+	ReviewMessage string
+	ReviewerEmail string
+	// Comment is an optional note from the player, e.g. "arriving 30 min late".
+	// New comments are also written to the RSVP's event log.
+	Comment string
+	Guests  int // People the player is bringing along; they take seats too
+}
+
+// MaxRSVPCommentLength bounds the note a player can leave with an RSVP.
+const MaxRSVPCommentLength = 200
+
+// MaxRSVPGuests bounds how many guests a player can bring.
+const MaxRSVPGuests = 5
+
+// PartySize is the number of seats the RSVP takes when attending: the player and their guests.
+func (r *RSVP) PartySize() int {
+	return 1 + r.Guests
+}
+
+// RSVPEvent is one entry in a game's append-only RSVP history: a player's status
+// change, or the GM approving or declining them.
+type RSVPEvent struct {
+	ID         int64
+	RSVPID     int64
+	GameID     int64
+	UserID     int64 // The player whose RSVP changed
+	UserEmail  string
+	ActorID    int64 // Who made the change: the player, or the GM reviewing them
+	ActorEmail string
+	OldStatus  string // "" for a first RSVP
+	NewStatus  string
+	Comment    string
+	CreatedAt  time.Time
+}
+
+// ByGM reports whether someone other than the player made the change.
+func (e *RSVPEvent) ByGM() bool {
+	return e.ActorID != e.UserID
+}
+
+// IsReviewed reports whether the GM approved or declined this RSVP.
+func (r *RSVP) IsReviewed() bool {
+	return r.ReviewedBy != 0
 }
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// ContentTopics is the fixed vocabulary for content warnings on games and for
+// players' lines and veils, so the two can be matched.
+var ContentTopics = []string{
+	"violence", "gore", "body_horror", "death", "torture", "sexual_content",
+	"sexual_violence", "self_harm", "suicide", "abuse", "harm_to_children",
+	"harm_to_animals", "slavery", "racism", "drugs", "alcohol", "spiders",
+	"insects", "claustrophobia", "religion", "pregnancy",
+}
+
+// MaxContentNotesLength caps a game's free-text content warning.
+const MaxContentNotesLength = 1000
+
+// Kinds of content limit. A line is content the player doesn't want in the game at
+// all; a veil is content that may happen but only off-screen.
+const (
+	LimitLine = "line"
+	LimitVeil = "veil"
+)
+
+// ValidContentTopic reports whether topic is one of ContentTopics.
+func ValidContentTopic(topic string) bool {
+	return contains(ContentTopics, topic)
+}
+
+// HasContentTag reports whether the game is tagged with topic.
+func (g *Game) HasContentTag(topic string) bool {
+	for _, t := range g.ContentTags {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// MinLimitSummaryPlayers is how many of a game's players must have set lines or
+// veils before the GM sees the counts. Fewer would let the GM tell whose they are.
+const MinLimitSummaryPlayers = 3
+
+// LimitSummaryLead is how long before a game without an RSVP deadline the GM's
+// summary of its players' lines and veils is taken.
+const LimitSummaryLead = 24 * time.Hour
+
+// LimitSummaryAt is when the summary of the game's players' lines and veils is
+// taken, once: when RSVPs close, or LimitSummaryLead before a game without an
+// RSVP deadline. Counts that followed each RSVP would let the GM read a new
+// player's limits off the difference.
+func (g *Game) LimitSummaryAt() time.Time {
+	if g.HasRSVPDeadline() {
+		return g.RSVPDeadline
+	}
+	return g.GameDateTime.Add(-LimitSummaryLead)
+}
+
+// LimitSummary counts how many of a game's players marked a topic as a line or a
+// veil, without saying who. InGame is set when the game is tagged with the topic.
+type LimitSummary struct {
+	Topic  string
+	Lines  int
+	Veils  int
+	InGame bool
+}
+
+// Conflicts reports whether the game is tagged with content someone ruled out.
+func (s *LimitSummary) Conflicts() bool {
+	return s.InGame && s.Lines > 0
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Session note kinds. Recaps are shared with the game's participants; prep notes
+// are private to the GM.
+const (
+	NoteKindRecap  = "recap"
+	NoteKindGMPrep = "gm_prep"
+)
+
+// NoteRevisionMergeWindow is how long consecutive saves by the same author are
+// folded into one revision, so autosave doesn't record every pause in typing.
+const NoteRevisionMergeWindow = 10 * time.Minute
+
+// ValidNoteKind reports whether kind is one of the NoteKind* constants.
+func ValidNoteKind(kind string) bool {
+	return kind == NoteKindRecap || kind == NoteKindGMPrep
+}
+
+// SessionNote is the current text of a game's recap or GM prep notes.
+type SessionNote struct {
+	ID         int64
+	GameID     int64
+	Kind       string
+	Content    string
+	RevisionID int64 // Latest revision; editors send it back to detect conflicting saves
+	UpdatedBy  int64
+	UpdatedAt  time.Time
+}
+
+// SessionNoteRevision is one saved version of a session note.
+type SessionNoteRevision struct {
+	ID         int64
+	NoteID     int64
+	Content    string
+	AuthorID   int64
+	AuthorName string // For display
+	CreatedAt  time.Time
+	UpdatedAt  time.Time // Later than CreatedAt when autosaves were merged into it
+}
+
+// JournalEntry is one past session in a campaign journal. Recap is nil when
+// there is none yet or the viewer did not take part in the session.
+type JournalEntry struct {
+	Game    *Game
+	Recap   *SessionNote
+	CanRead bool
+}
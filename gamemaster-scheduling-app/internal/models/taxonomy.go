@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// MaxGameSystemNameLength caps the names admins give game systems.
+const MaxGameSystemNameLength = 60
+
+// GameSystem is a rules system, e.g. "D&D 5e", from the list site admins manage.
+// Games pick one and players list the ones they like to play.
+type GameSystem struct {
+	ID        int64
+	Name      string
+	GameCount int // Games using the system; only set by database.GetGameSystems
+	CreatedAt time.Time
+}
+
+// Experience levels a game is pitched at.
+const (
+	ExperienceNewPlayerFriendly = "new_player_friendly"
+	ExperienceAllLevels         = "all_levels"
+	ExperienceExperienced       = "experienced"
+)
+
+// ExperienceLevels lists the experience levels in the order forms show them.
+var ExperienceLevels = []string{ExperienceNewPlayerFriendly, ExperienceAllLevels, ExperienceExperienced}
+
+// Game formats.
+const (
+	FormatOneShot  = "one_shot"
+	FormatCampaign = "campaign"
+)
+
+// GameFormats lists the game formats in the order forms show them.
+var GameFormats = []string{FormatOneShot, FormatCampaign}
+
+// Play modes.
+const (
+	PlayModeInPerson = "in_person"
+	PlayModeOnline   = "online"
+)
+
+// PlayModes lists the play modes in the order forms show them.
+var PlayModes = []string{PlayModeInPerson, PlayModeOnline}
+
+// ValidExperienceLevel reports whether s is one of ExperienceLevels.
+func ValidExperienceLevel(s string) bool {
+	return contains(ExperienceLevels, s)
+}
+
+// ValidGameFormat reports whether s is one of GameFormats.
+func ValidGameFormat(s string) bool {
+	return contains(GameFormats, s)
+}
+
+// ValidPlayMode reports whether s is one of PlayModes.
+func ValidPlayMode(s string) bool {
+	return contains(PlayModes, s)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// GameFilter narrows down the games list. Zero fields don't filter.
+type GameFilter struct {
+	SystemID        int64
+	ExperienceLevel string
+	Format          string
+	PlayMode        string
+	// PreferredBy keeps only games in this user's preferred systems.
+	PreferredBy int64
+	// ExcludeLinesOf hides games tagged with content this user marked as a line.
+	ExcludeLinesOf int64
+}
@@ -1,11 +1,53 @@
 package models
 
-import "time"
+import (
+	"regexp"
+	"time"
+)
+
+// Site roles.
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin" // Site operator with access to /admin
+)
 
 // User represents a user in the system.
 type User struct {
 	ID           int64
 	Email        string
+	Username     string // Optional; used for @mentions. Empty if not set.
 	PasswordHash string
-	CreatedAt    time.Time
+	Role         string // UserRoleUser or UserRoleAdmin
+	Timezone     string // IANA name, e.g. "Europe/Berlin"; empty means UTC
+	// SuspendedAt is set while a site admin has suspended the account; suspended
+	// users can't log in.
+	SuspendedAt     time.Time
+	SuspendedReason string
+	CreatedAt       time.Time
+}
+
+// IsAdmin reports whether the user is a site admin.
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// IsSuspended reports whether the account is suspended.
+func (u *User) IsSuspended() bool {
+	return !u.SuspendedAt.IsZero()
+}
+
+// DisplayName is how the user is shown to others: their username if set, else their email.
+func (u *User) DisplayName() string {
+	if u.Username != "" {
+		return u.Username
+	}
+	return u.Email
+}
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// ValidUsername reports whether s can be used as a username:
+// 3-30 letters, digits or underscores, so it can be @mentioned.
+func ValidUsername(s string) bool {
+	return usernamePattern.MatchString(s)
 }
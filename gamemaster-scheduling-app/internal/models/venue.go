@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// MaxVenueTextLength caps the free-text fields of venues and virtual tables.
+const MaxVenueTextLength = 2000
+
+// Venue is a physical space games are played at, e.g. a game store or someone's
+// living room. Anyone can pick it for their games; only its owner edits it.
+type Venue struct {
+	ID            int64
+	OwnerID       int64
+	Name          string
+	Address       string
+	Capacity      int // People it holds; 0 if unknown
+	Accessibility string
+	HouseRules    string
+	HostContact   string // Shown to signed-in users only
+	CreatedAt     time.Time
+}
+
+// VirtualTable is where a GM runs games online: a virtual tabletop and a voice
+// channel. Its links are only shown to a game's GMs and attending players.
+type VirtualTable struct {
+	ID        int64
+	OwnerID   int64
+	Name      string
+	VTTURL    string
+	VoiceURL  string
+	Notes     string
+	CreatedAt time.Time
+}
@@ -0,0 +1,157 @@
+// Package quorum decides, when a game's RSVP deadline passes, whether enough players
+// are attending for it to go ahead, and notifies everyone of the outcome.
+//
+// Decide is a pure function of the game, its RSVPs and the current time. Checker
+// applies it to every undecided game on a schedule, with an injectable clock.
+package quorum
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+)
+
+// DefaultInterval is how often Checker.Run looks for games past their deadline.
+const DefaultInterval = time.Minute
+
+// Decide returns the quorum decision for a game at now. due is false if there is
+// nothing to decide yet: no deadline, the deadline hasn't passed, or the game was
+// already decided. Games without a minimum player count are always confirmed.
+// Guests take seats but don't count toward the minimum.
+func Decide(game *models.Game, rsvps []*models.RSVP, now time.Time) (status string, due bool) {
+	if game.QuorumStatus != "" || !game.RSVPDeadlinePassed(now) {
+		return "", false
+	}
+	if game.PlayersAttending(rsvps) < game.MinPlayers {
+		return models.QuorumCancelled, true
+	}
+	return models.QuorumConfirmed, true
+}
+
+// Checker decides the quorum of games whose RSVP deadline has passed.
+type Checker struct {
+	DB *sql.DB
+	// Now returns the current time; time.Now if nil.
+	Now func() time.Time
+	// Interval between checks in Run; DefaultInterval if zero.
+	Interval time.Duration
+}
+
+func (c *Checker) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Run checks for due games every Interval until ctx is cancelled. Errors are
+// logged and retried on the next tick.
+func (c *Checker) Run(ctx context.Context) {
+	interval := c.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := c.RunOnce(); err != nil {
+			log.Printf("Error checking game quorums: %v", err)
+		} else if n > 0 {
+			log.Printf("Decided the quorum of %d game(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce decides every game that is due, notifies its GMs and players, and returns
+// how many games were decided. A game that fails is logged and skipped, so it
+// doesn't hold up the others; the failures are returned together.
+func (c *Checker) RunOnce() (int, error) {
+	now := c.now()
+	games, err := database.GetGamesAwaitingQuorum(c.DB)
+	if err != nil {
+		return 0, err
+	}
+
+	decided := 0
+	var errs []error
+	for _, game := range games {
+		if !game.RSVPDeadlinePassed(now) {
+			continue
+		}
+		applied, err := c.decide(game, now)
+		if applied {
+			decided++
+		}
+		if err != nil {
+			err = fmt.Errorf("game %d: %w", game.ID, err)
+			log.Printf("Error deciding the quorum of %v", err)
+			errs = append(errs, err)
+		}
+	}
+	return decided, errors.Join(errs...)
+}
+
+// decide decides the quorum of one game past its deadline and notifies everyone.
+// applied reports whether the decision was saved, even if notifying failed.
+func (c *Checker) decide(game *models.Game, now time.Time) (applied bool, err error) {
+	if err := database.LoadGameStaff(c.DB, game); err != nil {
+		return false, err
+	}
+	rsvps, err := database.GetRSVPsForGame(c.DB, game.ID)
+	if err != nil {
+		return false, err
+	}
+	status, due := Decide(game, rsvps, now)
+	if !due {
+		return false, nil
+	}
+	applied, err = database.SetQuorumStatus(c.DB, game.ID, status, now)
+	if err != nil || !applied {
+		return false, err // !applied: decided concurrently
+	}
+	game.QuorumStatus = status
+	return true, notifyDecision(c.DB, game, rsvps)
+}
+
+// notifyDecision tells the GMs and everyone still interested in the game (attending,
+// maybe or awaiting approval) whether it is going ahead.
+func notifyDecision(db *sql.DB, game *models.Game, rsvps []*models.RSVP) error {
+	attending := game.PlayersAttending(rsvps)
+	message := fmt.Sprintf("%s is on: %d player(s) confirmed by the RSVP deadline", game.Title, attending)
+	if game.IsCancelled() {
+		message = fmt.Sprintf("%s is cancelled: only %d of the %d players needed RSVP'd by the deadline", game.Title, attending, game.MinPlayers)
+	}
+
+	recipients := game.GMIDs()
+	for _, rsvp := range rsvps {
+		switch rsvp.Status {
+		case models.RSVPStatusAttending, models.RSVPStatusMaybe, models.RSVPStatusPending:
+			if !game.IsGM(rsvp.UserID) {
+				recipients = append(recipients, rsvp.UserID)
+			}
+		}
+	}
+	for _, userID := range recipients {
+		_, err := database.CreateNotification(db, &models.Notification{
+			UserID:  userID,
+			Kind:    models.NotificationKindGameDecision,
+			Message: message,
+			Link:    fmt.Sprintf("/games/%d", game.ID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
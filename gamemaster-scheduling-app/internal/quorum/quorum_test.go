@@ -0,0 +1,163 @@
+package quorum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gamemaster-scheduling/app/internal/database"
+	"github.com/gamemaster-scheduling/app/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestDecide(t *testing.T) {
+	deadline := time.Date(2030, 5, 1, 18, 0, 0, 0, time.UTC)
+	attending := []*models.RSVP{
+		{UserID: 1, Status: models.RSVPStatusAttending}, // The GM doesn't count
+		{UserID: 2, Status: models.RSVPStatusAttending},
+		{UserID: 3, Status: models.RSVPStatusAttending, Guests: 2}, // Guests don't count
+		{UserID: 4, Status: models.RSVPStatusMaybe},
+		{UserID: 5, Status: models.RSVPStatusPending},
+	}
+
+	tests := []struct {
+		name       string
+		game       models.Game
+		now        time.Time
+		wantStatus string
+		wantDue    bool
+	}{
+		{"no deadline", models.Game{GMID: 1, MinPlayers: 3}, deadline, "", false},
+		{"before deadline", models.Game{GMID: 1, MinPlayers: 3, RSVPDeadline: deadline}, deadline.Add(-time.Second), "", false},
+		{"at deadline without quorum", models.Game{GMID: 1, MinPlayers: 3, RSVPDeadline: deadline}, deadline, models.QuorumCancelled, true},
+		{"quorum met", models.Game{GMID: 1, MinPlayers: 2, RSVPDeadline: deadline}, deadline.Add(time.Hour), models.QuorumConfirmed, true},
+		{"quorum met only with guests", models.Game{GMID: 1, MinPlayers: 4, RSVPDeadline: deadline}, deadline, models.QuorumCancelled, true},
+		{"no minimum", models.Game{GMID: 1, RSVPDeadline: deadline}, deadline, models.QuorumConfirmed, true},
+		{"already decided", models.Game{GMID: 1, MinPlayers: 3, RSVPDeadline: deadline, QuorumStatus: models.QuorumConfirmed}, deadline, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, due := Decide(&tt.game, attending, tt.now)
+			if status != tt.wantStatus || due != tt.wantDue {
+				t.Errorf("Decide() = %q, %v; want %q, %v", status, due, tt.wantStatus, tt.wantDue)
+			}
+		})
+	}
+}
+
+func TestCheckerRunOnce(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	defer db.Close()
+
+	gm, _ := database.CreateUser(db, "quorum_gm@example.com", "password")
+	alice, _ := database.CreateUser(db, "quorum_alice@example.com", "password")
+	bob, _ := database.CreateUser(db, "quorum_bob@example.com", "password")
+	carol, _ := database.CreateUser(db, "quorum_carol@example.com", "password")
+
+	now := time.Date(2030, 5, 1, 12, 0, 0, 0, time.UTC)
+	newGame := func(title string, deadline time.Time, minPlayers int) *models.Game {
+		game, err := database.CreateGame(db, &models.Game{
+			GMID: gm.ID, Title: title, GameDateTime: deadline.Add(24 * time.Hour), Location: "Table",
+			RSVPDeadline: deadline, MinPlayers: minPlayers,
+		})
+		if err != nil {
+			t.Fatalf("CreateGame() error = %v", err)
+		}
+		return game
+	}
+	onGame := newGame("Full House", now.Add(-time.Hour), 2)
+	offGame := newGame("Ghost Town", now.Add(-time.Minute), 2)
+	laterGame := newGame("Next Week", now.Add(time.Hour), 2)
+	rsvp := func(game *models.Game, user *models.User, status string) {
+		if err := database.CreateOrUpdateRSVP(db, &models.RSVP{GameID: game.ID, UserID: user.ID, Status: status}); err != nil {
+			t.Fatalf("CreateOrUpdateRSVP() error = %v", err)
+		}
+	}
+	rsvp(onGame, alice, models.RSVPStatusAttending)
+	rsvp(onGame, bob, models.RSVPStatusAttending)
+	rsvp(offGame, alice, models.RSVPStatusAttending)
+	rsvp(offGame, bob, models.RSVPStatusMaybe)
+	rsvp(offGame, carol, models.RSVPStatusNotAttending)
+
+	checker := &Checker{DB: db, Now: func() time.Time { return now }}
+	if n, err := checker.RunOnce(); err != nil || n != 2 {
+		t.Fatalf("RunOnce() = %d, %v; want 2 games decided", n, err)
+	}
+	for _, tc := range []struct {
+		game *models.Game
+		want string
+	}{{onGame, models.QuorumConfirmed}, {offGame, models.QuorumCancelled}, {laterGame, ""}} {
+		game, _ := database.GetGameByID(db, tc.game.ID)
+		if game.QuorumStatus != tc.want {
+			t.Errorf("%s quorum = %q, want %q", game.Title, game.QuorumStatus, tc.want)
+		}
+		if tc.want != "" && !game.QuorumDecidedAt.Equal(now) {
+			t.Errorf("%s decided at %v, want %v", game.Title, game.QuorumDecidedAt, now)
+		}
+	}
+
+	// The GM and interested players hear about both games; Carol declined and isn't told.
+	for user, want := range map[*models.User]int{gm: 2, alice: 2, bob: 2, carol: 0} {
+		notes, _ := database.GetNotificationsForUser(db, user.ID, 10)
+		if len(notes) != want {
+			t.Errorf("%s has %d notifications, want %d", user.Email, len(notes), want)
+		}
+	}
+	bobNotes, _ := database.GetNotificationsForUser(db, bob.ID, 10)
+	for _, n := range bobNotes {
+		if n.Kind != models.NotificationKindGameDecision {
+			t.Errorf("notification kind = %q", n.Kind)
+		}
+	}
+
+	// Running again decides nothing new until the clock reaches the next deadline.
+	if n, _ := checker.RunOnce(); n != 0 {
+		t.Errorf("second RunOnce() decided %d games, want 0", n)
+	}
+	now = now.Add(2 * time.Hour)
+	if n, _ := checker.RunOnce(); n != 1 {
+		t.Errorf("RunOnce() after the next deadline decided %d games, want 1", n)
+	}
+	if game, _ := database.GetGameByID(db, laterGame.ID); !game.IsCancelled() {
+		t.Errorf("game with no attendees quorum = %q, want cancelled", game.QuorumStatus)
+	}
+}
+
+func TestCheckerRunOnceSkipsFailingGames(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	defer db.Close()
+
+	gm, _ := database.CreateUser(db, "skip_gm@example.com", "password")
+	now := time.Date(2030, 5, 1, 12, 0, 0, 0, time.UTC)
+	var games []*models.Game
+	for i, title := range []string{"Broken", "Fine"} {
+		game, err := database.CreateGame(db, &models.Game{
+			GMID: gm.ID, Title: title, GameDateTime: now.Add(24 * time.Hour), Location: "Table",
+			RSVPDeadline: now.Add(time.Duration(i-2) * time.Hour), MinPlayers: 1,
+		})
+		if err != nil {
+			t.Fatalf("CreateGame() error = %v", err)
+		}
+		games = append(games, game)
+	}
+	// The first game checked can't be saved.
+	_, err = db.Exec(`CREATE TRIGGER fail_quorum BEFORE UPDATE OF quorum_status ON games
+		WHEN NEW.title = 'Broken' BEGIN SELECT RAISE(ABORT, 'broken game'); END`)
+	if err != nil {
+		t.Fatalf("creating trigger: %v", err)
+	}
+
+	checker := &Checker{DB: db, Now: func() time.Time { return now }}
+	n, err := checker.RunOnce()
+	if err == nil || n != 1 {
+		t.Fatalf("RunOnce() = %d, %v; want the other game decided and an error", n, err)
+	}
+	if game, _ := database.GetGameByID(db, games[1].ID); !game.IsCancelled() {
+		t.Errorf("game after the failing one quorum = %q, want cancelled", game.QuorumStatus)
+	}
+}
@@ -0,0 +1,44 @@
+// Package storage keeps uploaded files (game attachments and their thumbnails)
+// outside the database. Files are addressed by keys chosen by the application,
+// e.g. "games/12/3f2c...", and are never served directly: handlers check access
+// and stream them from a Store.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned by Open when no file is stored under the key.
+var ErrNotFound = errors.New("storage: file not found")
+
+// Store is where uploaded files live.
+type Store interface {
+	// Put stores the contents of r under key, replacing any existing file.
+	Put(key string, r io.Reader) error
+	// Open returns the file stored under key, or ErrNotFound.
+	Open(key string) (io.ReadCloser, error)
+	// Delete removes the file stored under key. Deleting a missing file is not an error.
+	Delete(key string) error
+}
+
+// validateKey accepts slash-separated keys of letters, digits, '-', '_' and '.',
+// with no empty, "." or ".." segments, so a key can never escape a Store's root.
+func validateKey(key string) error {
+	if key == "" || len(key) > 512 {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("storage: invalid key %q", key)
+		}
+		for _, c := range segment {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+				return fmt.Errorf("storage: invalid key %q", key)
+			}
+		}
+	}
+	return nil
+}
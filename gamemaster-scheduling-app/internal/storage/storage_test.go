@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStore checks the behaviour every Store must share.
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	if err := store.Put("games/1/handout.pdf", strings.NewReader("first")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put("games/1/handout.pdf", strings.NewReader("second")); err != nil {
+		t.Fatalf("Put() replace error = %v", err)
+	}
+	rc, err := store.Open("games/1/handout.pdf")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "second" {
+		t.Errorf("Open() contents = %q, want %q", got, "second")
+	}
+
+	if err := store.Delete("games/1/handout.pdf"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Open("games/1/handout.pdf"); err != ErrNotFound {
+		t.Errorf("Open() after delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete("games/1/handout.pdf"); err != nil {
+		t.Errorf("Delete() of a missing file error = %v", err)
+	}
+
+	for _, key := range []string{"", "../secret", "games/../../etc/passwd", "/abs", "games//x", "games/a b", `games\x`} {
+		if err := store.Put(key, strings.NewReader("x")); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	testStore(t, store)
+}
+
+// fakeS3 is an in-memory bucket that checks requests carry a SigV4 signature.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDTEST/20240102/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") ||
+		r.Header.Get("X-Amz-Date") != "20240102T030405Z" {
+		http.Error(w, "bad signature: "+auth, http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+			http.Error(w, "payload hash mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := &S3Store{
+		Endpoint:        server.URL,
+		Bucket:          "handouts",
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDTEST",
+		SecretAccessKey: "secret",
+		now:             func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+	testStore(t, store)
+
+	store.AccessKeyID = "WRONG"
+	if err := store.Put("games/1/x", strings.NewReader("x")); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put() with rejected credentials error = %v, want a 403 error", err)
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation.
+	got := hex.EncodeToString(signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam"))
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got != want {
+		t.Errorf("signingKey() = %s, want %s", got, want)
+	}
+}
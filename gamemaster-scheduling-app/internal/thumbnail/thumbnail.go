@@ -0,0 +1,92 @@
+// Package thumbnail makes small JPEG previews of uploaded PNG, JPEG and GIF images
+// using only the standard library.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	_ "image/gif" // Register decoders for image.Decode
+	_ "image/png"
+)
+
+// MaxSourcePixels bounds the images we will decode, so a small file that
+// decompresses into a huge bitmap can't exhaust memory.
+const MaxSourcePixels = 40_000_000
+
+// ErrTooLarge is returned for images over MaxSourcePixels.
+var ErrTooLarge = errors.New("thumbnail: image dimensions too large")
+
+// Generate decodes an image and returns a JPEG no larger than maxSize pixels on its
+// longest side, preserving the aspect ratio. Smaller images are not upscaled.
+// Transparent areas are drawn on white.
+func Generate(data []byte, maxSize int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxSourcePixels {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := fit(cfg.Width, cfg.Height, maxSize)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	scale(dst, src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fit returns the largest size within maxSize x maxSize with the aspect ratio of w x h.
+func fit(w, h, maxSize int) (int, int) {
+	if w <= maxSize && h <= maxSize {
+		return w, h
+	}
+	if w >= h {
+		return maxSize, max(1, h*maxSize/w)
+	}
+	return max(1, w*maxSize/h), maxSize
+}
+
+// scale draws src onto dst, on a white background, by averaging the block of source
+// pixels under each destination pixel.
+func scale(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	for y := 0; y < db.Dy(); y++ {
+		y0 := sb.Min.Y + y*sb.Dy()/db.Dy()
+		y1 := max(y0+1, sb.Min.Y+(y+1)*sb.Dy()/db.Dy())
+		for x := 0; x < db.Dx(); x++ {
+			x0 := sb.Min.X + x*sb.Dx()/db.Dx()
+			x1 := max(x0+1, sb.Min.X+(x+1)*sb.Dx()/db.Dx())
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA() // Alpha-premultiplied, 16 bits
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			// Composite the averaged pixel over white.
+			white := 0xffff * n
+			px := color.RGBA64{
+				R: uint16((r + white - a) / n),
+				G: uint16((g + white - a) / n),
+				B: uint16((b + white - a) / n),
+				A: 0xffff,
+			}
+			dst.Set(db.Min.X+x, db.Min.Y+y, px)
+		}
+	}
+}
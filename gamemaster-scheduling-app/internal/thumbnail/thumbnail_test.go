@@ -0,0 +1,91 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerate(t *testing.T) {
+	// Left half red, right half transparent.
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			src.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+
+	out, err := Generate(encodePNG(t, src), 100)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Fatalf("thumbnail size = %dx%d, want 100x50", b.Dx(), b.Dy())
+	}
+	near := func(got uint32, want uint8) bool {
+		g := int(got >> 8)
+		return g > int(want)-20 && g < int(want)+20
+	}
+	if r, g, b, _ := thumb.At(20, 25).RGBA(); !near(r, 255) || !near(g, 0) || !near(b, 0) {
+		t.Errorf("left pixel = %d,%d,%d, want red", r>>8, g>>8, b>>8)
+	}
+	if r, g, b, _ := thumb.At(80, 25).RGBA(); !near(r, 255) || !near(g, 255) || !near(b, 255) {
+		t.Errorf("transparent area = %d,%d,%d, want white", r>>8, g>>8, b>>8)
+	}
+
+	// Small images keep their size.
+	out, err = Generate(encodePNG(t, image.NewGray(image.Rect(0, 0, 30, 60))), 100)
+	if err != nil {
+		t.Fatalf("Generate() small error = %v", err)
+	}
+	if cfg, _ := jpeg.DecodeConfig(bytes.NewReader(out)); cfg.Width != 30 || cfg.Height != 60 {
+		t.Errorf("small thumbnail size = %dx%d, want 30x60", cfg.Width, cfg.Height)
+	}
+}
+
+func TestGenerateRejects(t *testing.T) {
+	if _, err := Generate([]byte("%PDF-1.4 not an image"), 100); err == nil {
+		t.Error("Generate() of a non-image succeeded")
+	}
+
+	// A PNG header claiming huge dimensions is refused before decoding.
+	huge := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	huge[16], huge[17], huge[18], huge[19] = 0, 0, 0x4e, 0x20 // width 20000
+	huge[20], huge[21], huge[22], huge[23] = 0, 0, 0x4e, 0x20 // height 20000
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+	if _, err := Generate(huge, 100); err != ErrTooLarge {
+		t.Errorf("Generate() of a 20000x20000 image error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct{ w, h, wantW, wantH int }{
+		{1000, 500, 200, 100},
+		{500, 1000, 100, 200},
+		{150, 80, 150, 80},
+		{5000, 1, 200, 1},
+	}
+	for _, tt := range tests {
+		if w, h := fit(tt.w, tt.h, 200); w != tt.wantW || h != tt.wantH {
+			t.Errorf("fit(%d, %d) = %d, %d, want %d, %d", tt.w, tt.h, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}